@@ -0,0 +1,289 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+// Package compat allows the API to evolve without breaking existing clients.
+//
+// The handlers in the controllers package implement version 1 of the API. Version 2
+// is served under /api/v2, or under /api/v1 to clients that send an Accept header
+// containing MediaTypeV2. Version 2 responses are derived from version 1 responses
+// by the middleware in this package, which currently:
+//
+//   - wraps errors in a structured envelope with a machine readable code
+//   - paginates the results of all list operations, unless the handler already
+//     returned one page of results
+//
+// Endpoints that need a different behavior in version 2 can get their own handler,
+// registered only on the v2 router, as the need arises.
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudbase/garm/apiserver/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const (
+	// MediaTypeV2 is the media type clients can use in the Accept header to
+	// request version 2 responses from /api/v1 endpoints.
+	MediaTypeV2 = "application/vnd.garm.v2+json"
+	// VersionHeader is set on every response and holds the version of the API
+	// that was used to generate the response.
+	VersionHeader = "Garm-Api-Version"
+
+	// DefaultPageSize is the number of items returned by list operations in
+	// version 2 of the API, if the page_size parameter is not set.
+	DefaultPageSize = appdefaults.DefaultListPageSize
+	// MaxPageSize is the maximum accepted value of the page_size parameter.
+	MaxPageSize = appdefaults.MaxListPageSize
+)
+
+// NegotiateVersion is the middleware used for /api/v1. Responses are translated to
+// version 2 if the client accepts MediaTypeV2. Otherwise they are left untouched.
+func NegotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if acceptsV2(r) {
+			translateToV2(next, w, r)
+			return
+		}
+		w.Header().Set(VersionHeader, "1")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// V2 is the middleware used for /api/v2. All responses are translated to version 2.
+func V2(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		translateToV2(next, w, r)
+	})
+}
+
+type v2ContextKey struct{}
+
+// IsV2 returns true if the request is served by version 2 of the API. List handlers
+// use it to paginate in the database, instead of returning all results for this
+// package to paginate.
+func IsV2(ctx context.Context) bool {
+	v2, _ := ctx.Value(v2ContextKey{}).(bool)
+	return v2
+}
+
+func acceptsV2(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			parsed, _, err := mime.ParseMediaType(strings.TrimSpace(mediaType))
+			if err == nil && parsed == MediaTypeV2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// responseRecorder buffers the response of a version 1 handler, so it can be
+// translated before being sent to the client.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(data)
+}
+
+func translateToV2(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), v2ContextKey{}, true))
+	if r.Header.Get("Upgrade") != "" {
+		// Websocket connections need the original response writer and don't have
+		// a body we could translate.
+		w.Header().Set(VersionHeader, "2")
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := &responseRecorder{
+		header: http.Header{},
+	}
+	next.ServeHTTP(rec, r)
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := rec.body.Bytes()
+
+	switch {
+	case status >= http.StatusBadRequest:
+		body = errorToV2(status, body)
+		rec.header.Set("Content-Type", "application/json")
+	case r.Method == http.MethodGet && status == http.StatusOK && isJSONArray(body):
+		var err error
+		body, err = paginate(r, body)
+		if err != nil {
+			status = http.StatusBadRequest
+			body = marshalV2Error(status, "bad_request", "Bad Request", err.Error())
+		}
+		rec.header.Set("Content-Type", "application/json")
+	}
+
+	for key, values := range rec.header {
+		if key == "Content-Length" {
+			continue
+		}
+		w.Header()[key] = values
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.Header().Set("Content-Type", MediaTypeV2)
+	}
+	w.Header().Set(VersionHeader, "2")
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(r.Context(), "failed to write response")
+	}
+}
+
+func isJSONArray(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+func errorCodeFromStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	default:
+		if status >= http.StatusInternalServerError {
+			return "server_error"
+		}
+		return "error"
+	}
+}
+
+// errorToV2 converts a version 1 error response to the version 2 error envelope.
+// Some middlewares reply with plain text errors, which are used as the message.
+func errorToV2(status int, body []byte) []byte {
+	var apiErr params.APIErrorResponse
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(status)
+		}
+		return marshalV2Error(status, errorCodeFromStatus(status), message, "")
+	}
+
+	code := errorCodeFromStatus(status)
+	switch apiErr.Error {
+	case params.InitializationRequired.Error, params.URLsRequired.Error:
+		// These are already machine readable.
+		code = apiErr.Error
+	}
+	return marshalV2Error(status, code, apiErr.Error, apiErr.Details)
+}
+
+func marshalV2Error(status int, code, message, details string) []byte {
+	asJs, err := json.Marshal(params.APIErrorResponseV2{
+		Error: params.APIErrorV2{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+	if err != nil {
+		// This should never happen.
+		return []byte(fmt.Sprintf(`{"error":{"code":%q,"message":%q}}`, code, http.StatusText(status)))
+	}
+	return asJs
+}
+
+func queryUint(r *http.Request, name string, defaultValue uint) (uint, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseUint(val, 10, 32)
+	if err != nil || parsed == 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, val)
+	}
+	return uint(parsed), nil
+}
+
+// paginate returns one page of the JSON array in body, using the page and page_size
+// query parameters.
+func paginate(r *http.Request, body []byte) ([]byte, error) {
+	page, err := queryUint(r, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := queryUint(r, "page_size", DefaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize > MaxPageSize {
+		return nil, fmt.Errorf("page_size may not exceed %d", MaxPageSize)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	total := uint(len(items))
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	pageItems := []json.RawMessage{}
+	if start < total {
+		if end > total {
+			end = total
+		}
+		pageItems = items[start:end]
+	}
+
+	return json.Marshal(params.PaginatedResponse[json.RawMessage]{
+		Items:      pageItems,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: total,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	})
+}
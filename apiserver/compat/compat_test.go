@@ -0,0 +1,159 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package compat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/apiserver/params"
+)
+
+func listHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`[1,2,3,4,5]`))
+}
+
+func notFoundHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(params.NotFoundResponse)
+}
+
+func TestNegotiateVersionKeepsV1Responses(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pools", nil)
+	rec := httptest.NewRecorder()
+	NegotiateVersion(http.HandlerFunc(listHandler)).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "1", rec.Header().Get(VersionHeader))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, `[1,2,3,4,5]`, rec.Body.String())
+}
+
+func TestNegotiateVersionWithAcceptHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pools?page=2&page_size=2", nil)
+	req.Header.Set("Accept", "application/json, "+MediaTypeV2)
+	rec := httptest.NewRecorder()
+	NegotiateVersion(http.HandlerFunc(listHandler)).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get(VersionHeader))
+	require.Equal(t, MediaTypeV2, rec.Header().Get("Content-Type"))
+
+	var page params.PaginatedResponse[int]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, []int{3, 4}, page.Items)
+	require.Equal(t, uint(2), page.Page)
+	require.Equal(t, uint(2), page.PageSize)
+	require.Equal(t, uint(5), page.TotalCount)
+	require.Equal(t, uint(3), page.TotalPages)
+}
+
+func TestV2PaginatesByDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/pools", nil)
+	rec := httptest.NewRecorder()
+	V2(http.HandlerFunc(listHandler)).ServeHTTP(rec, req)
+
+	var page params.PaginatedResponse[int]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, []int{1, 2, 3, 4, 5}, page.Items)
+	require.Equal(t, uint(1), page.Page)
+	require.Equal(t, uint(DefaultPageSize), page.PageSize)
+	require.Equal(t, uint(1), page.TotalPages)
+}
+
+func TestV2PageOutOfRange(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/pools?page=10", nil)
+	rec := httptest.NewRecorder()
+	V2(http.HandlerFunc(listHandler)).ServeHTTP(rec, req)
+
+	var page params.PaginatedResponse[int]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Empty(t, page.Items)
+	require.Equal(t, uint(5), page.TotalCount)
+}
+
+func TestV2InvalidPagination(t *testing.T) {
+	for _, query := range []string{"page=0", "page=abc", "page_size=1001"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/pools?"+query, nil)
+		rec := httptest.NewRecorder()
+		V2(http.HandlerFunc(listHandler)).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+		var apiErr params.APIErrorResponseV2
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+		require.Equal(t, "bad_request", apiErr.Error.Code)
+	}
+}
+
+func TestV2StructuredErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/pools/missing", nil)
+	rec := httptest.NewRecorder()
+	V2(http.HandlerFunc(notFoundHandler)).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	var apiErr params.APIErrorResponseV2
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+	require.Equal(t, "not_found", apiErr.Error.Code)
+	require.Equal(t, params.NotFoundResponse.Error, apiErr.Error.Message)
+	require.Equal(t, params.NotFoundResponse.Details, apiErr.Error.Details)
+}
+
+func TestV2PlainTextErrors(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/pools", nil)
+	rec := httptest.NewRecorder()
+	V2(handler).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, MediaTypeV2, rec.Header().Get("Content-Type"))
+	var apiErr params.APIErrorResponseV2
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+	require.Equal(t, "unauthorized", apiErr.Error.Code)
+	require.Equal(t, "Unauthorized", apiErr.Error.Message)
+}
+
+func TestV2LeavesPaginatedResponses(t *testing.T) {
+	var isV2 bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isV2 = IsV2(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(params.PaginatedResponse[int]{
+			Items: []int{7}, Page: 3, PageSize: 1, TotalCount: 9, TotalPages: 9,
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/pools?page=3&page_size=1", nil)
+	rec := httptest.NewRecorder()
+	V2(handler).ServeHTTP(rec, req)
+
+	require.True(t, isV2)
+	var page params.PaginatedResponse[int]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, []int{7}, page.Items)
+	require.Equal(t, uint(9), page.TotalCount)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/pools", nil)
+	NegotiateVersion(handler).ServeHTTP(httptest.NewRecorder(), req)
+	require.False(t, isV2)
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+)
+
+// defaultInstanceFailuresWindow is the time window used when the caller does not
+// specify one.
+const defaultInstanceFailuresWindow = 24 * time.Hour
+
+// swagger:route GET /analytics/instance-failures analytics InstanceFailureAnalytics
+//
+// Get provider faults of instances, grouped by provider, pool and error class.
+//
+//	Parameters:
+//	  + name: window
+//	    description: Time window to aggregate over, as a duration (eg: 1h, 30m). Defaults to 24h.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: InstanceFailureAnalytics
+//	  default: APIErrorResponse
+func (a *APIController) InstanceFailureAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	window := defaultInstanceFailuresWindow
+	if windowParam := r.URL.Query().Get("window"); windowParam != "" {
+		var err error
+		window, err = time.ParseDuration(windowParam)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid window %q: %s", windowParam, err))
+			return
+		}
+	}
+
+	analytics, err := a.r.GetInstanceFailureAnalytics(ctx, window)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching instance failure analytics")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(analytics); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /tokens tokens ListAPITokens
+//
+// List the API tokens of the current user. The admin gets the tokens of all users.
+//
+//	Responses:
+//	  200: APITokens
+//	  default: APIErrorResponse
+func (a *APIController) ListAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tokens, err := a.auth.ListAPITokens(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing API tokens")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /tokens tokens CreateAPIToken
+//
+// Create an API token that acts on behalf of the current user. The token is only
+// returned once, in the response.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Parameters used when creating the API token.
+//	    type: CreateAPITokenParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: APIToken
+//	  default: APIErrorResponse
+func (a *APIController) CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var param runnerParams.CreateAPITokenParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	token, err := a.auth.CreateAPIToken(ctx, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating API token")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route DELETE /tokens/{tokenID} tokens RevokeAPIToken
+//
+// Revoke an API token.
+//
+//	Parameters:
+//	  + name: tokenID
+//	    description: ID of the API token to revoke.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  default: APIErrorResponse
+func (a *APIController) RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	tokenID, ok := vars["tokenID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No API token ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	if err := a.auth.RevokeAPIToken(ctx, tokenID); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "revoking API token")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+// swagger:route POST /users/{username}/impersonate users ImpersonateUser
+//
+// Get a short lived token that acts on behalf of a user. Every request made with
+// the token is recorded in the audit log.
+//
+//	Parameters:
+//	  + name: username
+//	    description: The user to impersonate.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Impersonation parameters.
+//	    type: ImpersonateUserParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: JWTResponse
+//	  default: APIErrorResponse
+func (a *APIController) ImpersonateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	username, ok := vars["username"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No username specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var impersonateParams runnerParams.ImpersonateUserParams
+	if err := json.NewDecoder(r.Body).Decode(&impersonateParams); err != nil {
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	token, err := a.auth.GetImpersonationToken(ctx, username, impersonateParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "impersonating user")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runnerParams.JWTResponse{Token: token}); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /audit audit ListAuditRecords
+//
+// List audit records of user impersonations, of attempts to use denied images and flavors
+// and of resources created, updated or deleted through the API, newest first.
+//
+//	Parameters:
+//	  + name: action
+//	    description: Only return records of this action.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: resource_type
+//	    description: Only return records about this type of resource.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: page
+//	    description: The page to return, starting from 1. Defaults to 1.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of records in each page. Defaults to 50.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: AuditRecordsPage
+//	  default: APIErrorResponse
+func (a *APIController) ListAuditRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	listParams := runnerParams.ListAuditRecordsParams{
+		Action:       runnerParams.AuditAction(r.URL.Query().Get("action")),
+		ResourceType: runnerParams.AuditResourceType(r.URL.Query().Get("resource_type")),
+		Page:         1,
+		PageSize:     appdefaults.DefaultAuditRecordsPageSize,
+	}
+	for name, dest := range map[string]*uint{"page": &listParams.Page, "page_size": &listParams.PageSize} {
+		val := r.URL.Query().Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid %s %q", name, val))
+			return
+		}
+		*dest = uint(parsed)
+	}
+
+	records, err := a.r.ListAuditRecords(ctx, listParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing audit records")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /capacity-reservations capacityReservations ListCapacityReservations
+//
+// List the capacity reservations.
+//
+//	Responses:
+//	  200: CapacityReservations
+//	  default: APIErrorResponse
+func (a *APIController) ListCapacityReservationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reservations, err := a.r.ListCapacityReservations(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing capacity reservations")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reservations); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /capacity-reservations capacityReservations CreateCapacityReservation
+//
+// Reserve extra idle runners for a pool during a window of time.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Parameters used when creating the capacity reservation.
+//	    type: CreateCapacityReservationParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: CapacityReservation
+//	  default: APIErrorResponse
+func (a *APIController) CreateCapacityReservationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var param runnerParams.CreateCapacityReservationParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	reservation, err := a.r.CreateCapacityReservation(ctx, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating capacity reservation")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reservation); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route DELETE /capacity-reservations/{reservationID} capacityReservations DeleteCapacityReservation
+//
+// Delete a capacity reservation.
+//
+//	Parameters:
+//	  + name: reservationID
+//	    description: ID of the capacity reservation to delete.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  default: APIErrorResponse
+func (a *APIController) DeleteCapacityReservationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	reservationID, ok := vars["reservationID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No capacity reservation ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	if err := a.r.DeleteCapacityReservation(ctx, reservationID); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "deleting capacity reservation")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
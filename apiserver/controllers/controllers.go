@@ -19,8 +19,11 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -38,8 +41,7 @@ import (
 )
 
 func NewAPIController(r *runner.Runner, authenticator *auth.Authenticator, hub *wsWriter.Hub) (*APIController, error) {
-	controllerInfo, err := r.GetControllerInfo(auth.GetAdminContext(context.Background()))
-	if err != nil {
+	if _, err := r.GetControllerInfo(auth.GetAdminContext(context.Background())); err != nil {
 		return nil, errors.Wrap(err, "failed to get controller info")
 	}
 	return &APIController{
@@ -50,16 +52,14 @@ func NewAPIController(r *runner.Runner, authenticator *auth.Authenticator, hub *
 			ReadBufferSize:  1024,
 			WriteBufferSize: 16384,
 		},
-		controllerID: controllerInfo.ControllerID.String(),
 	}, nil
 }
 
 type APIController struct {
-	r            *runner.Runner
-	auth         *auth.Authenticator
-	hub          *wsWriter.Hub
-	upgrader     websocket.Upgrader
-	controllerID string
+	r        *runner.Runner
+	auth     *auth.Authenticator
+	hub      *wsWriter.Hub
+	upgrader websocket.Upgrader
 }
 
 func handleError(ctx context.Context, w http.ResponseWriter, err error) {
@@ -96,7 +96,7 @@ func handleError(ctx context.Context, w http.ResponseWriter, err error) {
 	}
 }
 
-func (a *APIController) handleWorkflowJobEvent(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+func (a *APIController) handleWorkflowJobEvent(ctx context.Context, w http.ResponseWriter, r *http.Request, entityID string) {
 	defer r.Body.Close()
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -104,17 +104,24 @@ func (a *APIController) handleWorkflowJobEvent(ctx context.Context, w http.Respo
 		return
 	}
 
-	signature := r.Header.Get("X-Hub-Signature-256")
-	hookType := r.Header.Get("X-Github-Hook-Installation-Target-Type")
+	started := time.Now()
+	err = a.r.HandleWebhookDelivery(ctx, entityID, r.Header, body)
+	valid := "true"
+	if err != nil {
+		valid = "false"
+	}
+	metrics.WebhookProcessingDuration.WithLabelValues(
+		valid, // label: valid
+	).Observe(time.Since(started).Seconds())
 
-	if err := a.r.DispatchWorkflowJob(hookType, signature, body); err != nil {
+	if err != nil {
 		switch {
 		case errors.Is(err, gErrors.ErrNotFound):
 			metrics.WebhooksReceived.WithLabelValues(
 				"false",         // label: valid
 				"owner_unknown", // label: reason
 			).Inc()
-			slog.With(slog.Any("error", err)).ErrorContext(ctx, "got not found error from DispatchWorkflowJob. webhook not meant for us?")
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "got not found error from HandleWebhookDelivery. webhook not meant for us?")
 			return
 		case strings.Contains(err.Error(), "signature"):
 			// nolint:golangci-lint,godox TODO: check error type
@@ -148,17 +155,21 @@ func (a *APIController) WebhookHandler(w http.ResponseWriter, r *http.Request) {
 	// The controllerID suffixed webhook URL is useful when configuring the webhook for an entity
 	// via garm. We cannot tag a webhook URL on github, so there is no way to determine ownership.
 	// Using a controllerID suffix is a simple way to denote ownership.
-	if ok && controllerID != a.controllerID {
+	if ok && !a.r.IsControllerWebhookID(ctx, controllerID) {
 		slog.InfoContext(ctx, "ignoring webhook meant for foreign controller", "req_controller_id", controllerID)
 		return
 	}
 
+	// Webhooks installed with a per-entity URL also carry the ID of the entity. Events received
+	// on such a URL are only handled by that entity.
+	entityID := vars["entityID"]
+
 	headers := r.Header.Clone()
 
 	event := runnerParams.Event(headers.Get("X-Github-Event"))
 	switch event {
 	case runnerParams.WorkflowJobEvent:
-		a.handleWorkflowJobEvent(ctx, w, r)
+		a.handleWorkflowJobEvent(ctx, w, r, entityID)
 	default:
 		slog.InfoContext(ctx, "ignoring unknown event", "gh_event", util.SanitizeLogEntry(string(event)))
 	}
@@ -188,7 +199,7 @@ func (a *APIController) EventsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer wsClient.Stop()
 
-	eventHandler, err := events.NewHandler(ctx, wsClient)
+	eventHandler, err := events.NewHandler(ctx, wsClient, a.r)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create new event handler")
 		return
@@ -257,6 +268,18 @@ func (a *APIController) NotFoundHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// ReadyzHandler reports that the API server is ready to serve requests. It does not
+// require authentication, so that it can be used as a readiness probe. The health of
+// the worker loops of each entity is only available to admins, on the workers endpoint.
+func (a *APIController) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(params.ReadyzResponse{Status: "ok"}); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route GET /metrics-token metrics-token GetMetricsToken
 //
 // Returns a JWT token that can be used to access the metrics endpoint.
@@ -298,6 +321,7 @@ func (a *APIController) MetricsTokenHandler(w http.ResponseWriter, r *http.Reque
 //	Responses:
 //	  200: JWTResponse
 //	  400: APIErrorResponse
+//	  429: APIErrorResponse
 //
 // LoginHandler returns a jwt token
 func (a *APIController) LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -313,8 +337,21 @@ func (a *APIController) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, err := a.auth.AuthenticateUser(ctx, loginInfo)
+	ctx, err := a.auth.AuthenticateUser(ctx, loginInfo, auth.SourceAddress(r))
 	if err != nil {
+		var rateLimited *auth.LoginRateLimitedError
+		if errors.As(err, &rateLimited) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+				Error:   "Too Many Requests",
+				Details: rateLimited.Error(),
+			}); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+			}
+			return
+		}
 		handleError(ctx, w, err)
 		return
 	}
@@ -394,11 +431,46 @@ func (a *APIController) ListProviders(w http.ResponseWriter, r *http.Request) {
 //
 // List all jobs.
 //
+//	Parameters:
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: id, name, status, started_at, completed_at, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Jobs
 //	  400: APIErrorResponse
 func (a *APIController) ListAllJobs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	if paginated {
+		page, err := a.r.ListAllJobsPage(ctx, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing jobs")
+			handleError(ctx, w, err)
+			return
+		}
+		writePaginated(ctx, w, page)
+		return
+	}
+
 	jobs, err := a.r.ListAllJobs(ctx)
 	if err != nil {
 		handleError(ctx, w, err)
@@ -411,6 +483,57 @@ func (a *APIController) ListAllJobs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// swagger:route PATCH /jobs/{jobID} jobs UpdateJobMetadata
+//
+// Set metadata keys on a job. The keys are merged into the existing metadata of
+// the job. Keys set to null are removed.
+//
+//	Parameters:
+//	  + name: jobID
+//	    description: The ID of the job.
+//	    type: integer
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: The metadata keys to set.
+//	    type: UpdateJobMetadataParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: Job
+//	  default: APIErrorResponse
+func (a *APIController) UpdateJobMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	jobID, err := strconv.ParseInt(vars["jobID"], 10, 64)
+	if err != nil {
+		handleError(ctx, w, gErrors.NewBadRequestError("invalid job ID %q", vars["jobID"]))
+		return
+	}
+
+	var metadataParams runnerParams.UpdateJobMetadataParams
+	if err := json.NewDecoder(r.Body).Decode(&metadataParams); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	job, err := a.r.UpdateJobMetadata(ctx, jobID, metadataParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "updating job metadata")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route GET /controller-info controllerInfo ControllerInfo
 //
 // Get controller info.
@@ -432,6 +555,27 @@ func (a *APIController) ControllerInfoHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// swagger:route GET /controller/nodes controller ListControllerNodes
+//
+// List the controllers that take part in the cluster, and the entities each of them manages.
+//
+//	Responses:
+//	  200: ControllerNodes
+//	  default: APIErrorResponse
+func (a *APIController) ListControllerNodesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	nodes, err := a.r.ListControllerNodes(ctx)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nodes); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route PUT /controller controller UpdateController
 //
 // Update controller.
@@ -470,3 +614,59 @@ func (a *APIController) UpdateControllerHandler(w http.ResponseWriter, r *http.R
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
 	}
 }
+
+// swagger:route POST /controller/id-migration controller StartControllerIDMigration
+//
+// Start changing the controller ID.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Parameters used when changing the controller ID.
+//	    type: StartControllerIDMigrationParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: ControllerIDMigration
+//	  400: APIErrorResponse
+//	  409: APIErrorResponse
+func (a *APIController) StartControllerIDMigrationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var migrationParams runnerParams.StartControllerIDMigrationParams
+	if err := json.NewDecoder(r.Body).Decode(&migrationParams); err != nil {
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	migration, err := a.r.StartControllerIDMigration(ctx, migrationParams)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(migration); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /controller/id-migration controller GetControllerIDMigration
+//
+// Get the most recent controller ID migration.
+//
+//	Responses:
+//	  200: ControllerIDMigration
+//	  404: APIErrorResponse
+func (a *APIController) GetControllerIDMigrationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	migration, err := a.r.GetControllerIDMigration(ctx)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(migration); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
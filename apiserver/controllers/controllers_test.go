@@ -0,0 +1,53 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/config"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestLoginHandlerRateLimited(t *testing.T) {
+	store := dbMocks.NewStore(t)
+	store.On("GetUser", mock.Anything, "admin").Return(params.User{}, nil).Once()
+	store.On("CreateAuditRecord", mock.Anything, mock.Anything).Return(params.AuditRecord{}, nil).Once()
+
+	a := &APIController{auth: auth.NewAuthenticator(config.JWTAuth{LoginRateLimit: 1}, store)}
+
+	login := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"username":"admin","password":"wrong-password"}`))
+		rec := httptest.NewRecorder()
+		a.LoginHandler(rec, req)
+		return rec
+	}
+
+	rec := login()
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = login()
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), "too many login attempts")
+}
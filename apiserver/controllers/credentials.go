@@ -227,3 +227,25 @@ func (a *APIController) UpdateGithubCredential(w http.ResponseWriter, r *http.Re
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
 	}
 }
+
+// swagger:route GET /github/rate-limits credentials ListRateLimitForecasts
+//
+// List the forecast of rate limit exhaustion of each set of GitHub credentials.
+//
+//	Responses:
+//	  200: RateLimitForecasts
+//	  400: APIErrorResponse
+func (a *APIController) ListRateLimitForecasts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	forecasts, err := a.r.ListRateLimitForecasts(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing rate limit forecasts")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(forecasts); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
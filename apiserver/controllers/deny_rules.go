@@ -0,0 +1,126 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /deny-rules denyRules ListDenyRules
+//
+// List the rules that ban image and flavor combinations.
+//
+//	Responses:
+//	  200: DenyRules
+//	  default: APIErrorResponse
+func (a *APIController) ListDenyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rules, err := a.r.ListDenyRules(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing deny rules")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /deny-rules denyRules CreateDenyRule
+//
+// Ban an image and flavor combination.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Parameters used when creating the deny rule.
+//	    type: CreateDenyRuleParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: DenyRule
+//	  default: APIErrorResponse
+func (a *APIController) CreateDenyRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var param runnerParams.CreateDenyRuleParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	rule, err := a.r.CreateDenyRule(ctx, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating deny rule")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route DELETE /deny-rules/{ruleID} denyRules DeleteDenyRule
+//
+// Delete a deny rule.
+//
+//	Parameters:
+//	  + name: ruleID
+//	    description: ID of the deny rule to delete.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  default: APIErrorResponse
+func (a *APIController) DeleteDenyRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	ruleID, ok := vars["ruleID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No deny rule ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	if err := a.r.DeleteDenyRule(ctx, ruleID); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "deleting deny rule")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
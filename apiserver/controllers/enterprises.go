@@ -18,12 +18,14 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	gErrors "github.com/cloudbase/garm-provider-common/errors"
 	"github.com/cloudbase/garm/apiserver/params"
 	runnerParams "github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
 )
 
 // swagger:route POST /enterprises enterprises CreateEnterprise
@@ -238,6 +240,12 @@ func (a *APIController) UpdateEnterpriseHandler(w http.ResponseWriter, r *http.R
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -264,6 +272,12 @@ func (a *APIController) CreateEnterprisePoolHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.CreateEnterprisePool(ctx, enterpriseID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating enterprise pool")
@@ -288,6 +302,24 @@ func (a *APIController) CreateEnterprisePoolHandler(w http.ResponseWriter, r *ht
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: provider_name, image, flavor, priority, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pools
 //	  default: APIErrorResponse
@@ -306,6 +338,22 @@ func (a *APIController) ListEnterprisePoolsHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	if paginated {
+		page, err := a.r.ListEntityPoolsPage(ctx, runnerParams.GithubEntity{ID: enterpriseID, EntityType: runnerParams.GithubEntityTypeEnterprise}, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
+			handleError(ctx, w, err)
+			return
+		}
+		writePaginated(ctx, w, page)
+		return
+	}
+
 	pools, err := a.r.ListEnterprisePools(ctx, enterpriseID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
@@ -437,6 +485,12 @@ func (a *APIController) DeleteEnterprisePoolHandler(w http.ResponseWriter, r *ht
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -464,6 +518,12 @@ func (a *APIController) UpdateEnterprisePoolHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.UpdateEnterprisePool(ctx, enterpriseID, poolID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating enterprise pool")
@@ -476,3 +536,62 @@ func (a *APIController) UpdateEnterprisePoolHandler(w http.ResponseWriter, r *ht
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
 	}
 }
+
+// swagger:route GET /enterprises/{enterpriseID}/runner-distribution enterprises GetEnterpriseRunnerDistribution
+//
+// Get the distribution of jobs that ran on the runners of the enterprise pools, across the
+// organizations and repositories of the enterprise.
+//
+//	Parameters:
+//	  + name: enterpriseID
+//	    description: The ID of the enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//	  + name: window
+//	    description: The time window of the report, as a duration (eg. 1h, 30m). Defaults to 24h.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: EnterpriseRunnerDistribution
+//	  default: APIErrorResponse
+func (a *APIController) GetEnterpriseRunnerDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	enterpriseID, ok := vars["enterpriseID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No enterprise ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	window := common.CompletedJobsRetention
+	if val := r.URL.Query().Get("window"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid window %q: %s", val, err))
+			return
+		}
+		window = parsed
+	}
+
+	distribution, err := a.r.GetEnterpriseRunnerDistribution(ctx, enterpriseID, window)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching enterprise runner distribution")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(distribution); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /entities/{entityID}/forge-runners entities ListEntityForgeRunners
+//
+// List the runners registered in GitHub for a repository, organization or enterprise,
+// correlated with the instances recorded by GARM.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the repository, organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: ForgeRunners
+//	  default: APIErrorResponse
+func (a *APIController) ListEntityForgeRunnersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	runners, err := a.r.ListEntityForgeRunners(ctx, entityID)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing forge runners")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runners); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /entities/{entityID}/pools/tags entities BulkUpdateEntityPoolTags
+//
+// Add, remove or rename a tag across the pools of a repository, organization or enterprise.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the repository, organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: The tag operation to apply.
+//	    type: BulkUpdatePoolTagsParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: BulkUpdatePoolTagsResult
+//	  default: APIErrorResponse
+func (a *APIController) BulkUpdateEntityPoolTagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var param runnerParams.BulkUpdatePoolTagsParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	result, err := a.r.BulkUpdatePoolTags(ctx, entityID, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "updating pool tags")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /entities/{entityID}/pools/match entities MatchEntityPools
+//
+// List the pools of a repository, organization or enterprise that would be tried, in order,
+// to create a runner for a job with the given labels.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the repository, organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: labels
+//	    description: Comma separated list of job labels.
+//	    type: string
+//	    in: query
+//	    required: true
+//
+//	Responses:
+//	  200: PoolMatchResult
+//	  default: APIErrorResponse
+func (a *APIController) MatchEntityPoolsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	labels := []string{}
+	for _, label := range strings.Split(r.URL.Query().Get("labels"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+
+	result, err := a.r.MatchEntityPools(ctx, entityID, labels)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "matching pools")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /entities/{entityID}/runner-groups entities ListEntityRunnerGroups
+//
+// List the GitHub runner groups of an organization or enterprise.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: RunnerGroups
+//	  default: APIErrorResponse
+func (a *APIController) ListEntityRunnerGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	groups, err := a.r.ListEntityRunnerGroups(ctx, entityID)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing runner groups")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /entities/{entityID}/runner-groups/{groupName} entities GetEntityRunnerGroup
+//
+// Get a GitHub runner group of an organization or enterprise by name. Returns a not found
+// error if the group does not exist.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: groupName
+//	    description: The name of the runner group.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: RunnerGroup
+//	  default: APIErrorResponse
+func (a *APIController) GetEntityRunnerGroupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+	groupName, ok := vars["groupName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No runner group name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	group, err := a.r.GetEntityRunnerGroup(ctx, entityID, groupName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching runner group")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(group); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /entities/{entityID}/runner-groups entities CreateEntityRunnerGroup
+//
+// Create a GitHub runner group in an organization or enterprise.
+//
+//	Parameters:
+//	  + name: entityID
+//	    description: The ID of the organization or enterprise.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Parameters used when creating the runner group.
+//	    type: CreateRunnerGroupParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: RunnerGroup
+//	  default: APIErrorResponse
+func (a *APIController) CreateEntityRunnerGroupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	entityID, ok := vars["entityID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var param runnerParams.CreateRunnerGroupParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	group, err := a.r.CreateEntityRunnerGroup(ctx, entityID, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating runner group")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(group); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /repositories/{repoID}/config repositories ExportRepoConfig
+//
+// Export the configuration of a repository and its pools as YAML. The webhook secret is
+// replaced with a placeholder.
+//
+//	Parameters:
+//	  + name: repoID
+//	    description: Repository ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Produces:
+//	- application/yaml
+//
+//	Responses:
+//	  200: EntityConfig
+//	  default: APIErrorResponse
+func (a *APIController) ExportRepoConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.exportEntityConfig(w, r, runnerParams.GithubEntityTypeRepository, "repoID")
+}
+
+// swagger:route POST /repositories/{repoID}/config repositories ImportRepoConfig
+//
+// Create the pools of an exported configuration in a repository. The settings of the
+// repository itself are left unchanged.
+//
+//	Parameters:
+//	  + name: repoID
+//	    description: Repository ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Configuration exported from a repository, organization or enterprise, as YAML.
+//	    type: EntityConfig
+//	    in: body
+//	    required: true
+//
+//	Consumes:
+//	- application/yaml
+//
+//	Responses:
+//	  200: Pools
+//	  default: APIErrorResponse
+func (a *APIController) ImportRepoConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.importEntityConfig(w, r, runnerParams.GithubEntityTypeRepository, "repoID")
+}
+
+// swagger:route GET /organizations/{orgID}/config organizations ExportOrgConfig
+//
+// Export the configuration of a organization and its pools as YAML. The webhook secret is
+// replaced with a placeholder.
+//
+//	Parameters:
+//	  + name: orgID
+//	    description: Organization ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Produces:
+//	- application/yaml
+//
+//	Responses:
+//	  200: EntityConfig
+//	  default: APIErrorResponse
+func (a *APIController) ExportOrgConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.exportEntityConfig(w, r, runnerParams.GithubEntityTypeOrganization, "orgID")
+}
+
+// swagger:route POST /organizations/{orgID}/config organizations ImportOrgConfig
+//
+// Create the pools of an exported configuration in a organization. The settings of the
+// organization itself are left unchanged.
+//
+//	Parameters:
+//	  + name: orgID
+//	    description: Organization ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Configuration exported from a repository, organization or enterprise, as YAML.
+//	    type: EntityConfig
+//	    in: body
+//	    required: true
+//
+//	Consumes:
+//	- application/yaml
+//
+//	Responses:
+//	  200: Pools
+//	  default: APIErrorResponse
+func (a *APIController) ImportOrgConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.importEntityConfig(w, r, runnerParams.GithubEntityTypeOrganization, "orgID")
+}
+
+// swagger:route GET /enterprises/{enterpriseID}/config enterprises ExportEnterpriseConfig
+//
+// Export the configuration of a enterprise and its pools as YAML. The webhook secret is
+// replaced with a placeholder.
+//
+//	Parameters:
+//	  + name: enterpriseID
+//	    description: Enterprise ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Produces:
+//	- application/yaml
+//
+//	Responses:
+//	  200: EntityConfig
+//	  default: APIErrorResponse
+func (a *APIController) ExportEnterpriseConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.exportEntityConfig(w, r, runnerParams.GithubEntityTypeEnterprise, "enterpriseID")
+}
+
+// swagger:route POST /enterprises/{enterpriseID}/config enterprises ImportEnterpriseConfig
+//
+// Create the pools of an exported configuration in a enterprise. The settings of the
+// enterprise itself are left unchanged.
+//
+//	Parameters:
+//	  + name: enterpriseID
+//	    description: Enterprise ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Configuration exported from a repository, organization or enterprise, as YAML.
+//	    type: EntityConfig
+//	    in: body
+//	    required: true
+//
+//	Consumes:
+//	- application/yaml
+//
+//	Responses:
+//	  200: Pools
+//	  default: APIErrorResponse
+func (a *APIController) ImportEnterpriseConfigHandler(w http.ResponseWriter, r *http.Request) {
+	a.importEntityConfig(w, r, runnerParams.GithubEntityTypeEnterprise, "enterpriseID")
+}
+
+// swagger:route POST /entities/import entities ImportEntity
+//
+// Create a repository, organization or enterprise and its pools from an exported
+// configuration. The webhook secret placeholder must be replaced with the secret of
+// the new entity.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Configuration exported from a repository, organization or enterprise, as YAML.
+//	    type: EntityConfig
+//	    in: body
+//	    required: true
+//
+//	Consumes:
+//	- application/yaml
+//
+//	Responses:
+//	  200: EntityConfigImport
+//	  default: APIErrorResponse
+func (a *APIController) ImportEntityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cfg, ok := decodeEntityConfig(w, r)
+	if !ok {
+		return
+	}
+
+	imported, err := a.r.ImportEntity(ctx, cfg)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "importing entity")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(imported); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /config config ExportControllerConfig
+//
+// Export the configuration of all repositories, organizations and enterprises, their
+// pools and the credentials they use, as YAML. Webhook secrets are replaced with a
+// placeholder, and the secrets of the credentials are left out.
+//
+//	Produces:
+//	- application/yaml
+//
+//	Responses:
+//	  200: ControllerConfig
+//	  default: APIErrorResponse
+func (a *APIController) ExportControllerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cfg, err := a.r.ExportControllerConfig(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "exporting controller config")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /config/apply config ApplyControllerConfig
+//
+// Apply a controller configuration. Entities and pools are created and updated until
+// they match the configuration.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Controller configuration, as YAML.
+//	    type: ControllerConfig
+//	    in: body
+//	    required: true
+//
+//	  + name: dry_run
+//	    description: Only list the changes that would be made.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
+//	  + name: prune
+//	    description: Remove the entities and pools that are not in the configuration.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
+//	Consumes:
+//	- application/yaml
+//
+//	Responses:
+//	  200: ControllerConfigApplyResult
+//	  default: APIErrorResponse
+func (a *APIController) ApplyControllerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var applyParams runnerParams.ApplyControllerConfigParams
+	query := r.URL.Query()
+	for name, dest := range map[string]*bool{"dry_run": &applyParams.DryRun, "prune": &applyParams.Prune} {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid %s %q", name, val))
+			return
+		}
+		*dest = parsed
+	}
+
+	var cfg runnerParams.ControllerConfig
+	if err := yaml.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.NewBadRequestError("invalid config: %s", err))
+		return
+	}
+
+	result, err := a.r.ApplyControllerConfig(ctx, cfg, applyParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "applying controller config")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func (a *APIController) exportEntityConfig(w http.ResponseWriter, r *http.Request, entityType runnerParams.GithubEntityType, idVar string) {
+	ctx := r.Context()
+
+	entityID, ok := entityIDFromRequest(w, r, idVar)
+	if !ok {
+		return
+	}
+
+	cfg, err := a.r.ExportEntityConfig(ctx, entityType, entityID)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "exporting entity config")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func (a *APIController) importEntityConfig(w http.ResponseWriter, r *http.Request, entityType runnerParams.GithubEntityType, idVar string) {
+	ctx := r.Context()
+
+	entityID, ok := entityIDFromRequest(w, r, idVar)
+	if !ok {
+		return
+	}
+
+	cfg, ok := decodeEntityConfig(w, r)
+	if !ok {
+		return
+	}
+
+	pools, err := a.r.ImportEntityConfig(ctx, entityType, entityID, cfg)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "importing entity config")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pools); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func entityIDFromRequest(w http.ResponseWriter, r *http.Request, idVar string) (string, bool) {
+	entityID, ok := mux.Vars(r)[idVar]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No entity ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.Context(), "failed to encode response")
+		}
+		return "", false
+	}
+	return entityID, true
+}
+
+// decodeEntityConfig reads an entity config from the request body. As YAML is a
+// superset of JSON, configs sent as JSON are accepted as well.
+func decodeEntityConfig(w http.ResponseWriter, r *http.Request) (runnerParams.EntityConfig, bool) {
+	var cfg runnerParams.EntityConfig
+	if err := yaml.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(r.Context(), "failed to decode")
+		handleError(r.Context(), w, gErrors.NewBadRequestError("invalid config: %s", err))
+		return runnerParams.EntityConfig{}, false
+	}
+	return cfg, true
+}
@@ -19,12 +19,16 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/apiserver/params"
 	runnerParams "github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
 )
 
 // swagger:route GET /pools/{poolID}/instances instances ListPoolInstances
@@ -38,6 +42,54 @@ import (
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: name, status, runner_status, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Comma separated list of instance statuses. Only instances with one of these statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: runner_status
+//	    description: Comma separated list of runner statuses. Only instances with one of these runner statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: provider
+//	    description: Only return instances created by this provider.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_before
+//	    description: Only return instances created before this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_after
+//	    description: Only return instances created after this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Instances
 //	  default: APIErrorResponse
@@ -56,6 +108,10 @@ func (a *APIController) ListPoolInstancesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if a.listFilteredInstances(w, r, nil, poolID) {
+		return
+	}
+
 	instances, err := a.r.ListPoolInstances(ctx, poolID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pool instances")
@@ -111,6 +167,126 @@ func (a *APIController) GetInstanceHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// swagger:route GET /instances/{instanceName}/provider-logs instances GetInstanceProviderLogs
+//
+// Get the log of provider operations (create, delete) performed for a runner instance.
+//
+//	Parameters:
+//	  + name: instanceName
+//	    description: Runner instance name.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: StatusMessages
+//	  default: APIErrorResponse
+func (a *APIController) GetInstanceProviderLogsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	instanceName, ok := vars["instanceName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No runner name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	logs, err := a.r.GetInstanceProviderLogs(ctx, instanceName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching instance provider logs")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logs); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /instances/{instanceName}/status-messages instances ListInstanceStatusMessages
+//
+// List the status messages of a runner instance, newest first.
+//
+//	Parameters:
+//	  + name: instanceName
+//	    description: Runner instance name.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: level
+//	    description: Only return messages with this level (info, warning or error).
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: page
+//	    description: The page to return, starting from 1. Defaults to 1.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of messages in each page. Defaults to 50.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: StatusMessagesPage
+//	  default: APIErrorResponse
+func (a *APIController) ListInstanceStatusMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	instanceName, ok := vars["instanceName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No runner name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	listParams := runnerParams.ListStatusMessagesParams{
+		EventLevel: runnerParams.EventLevel(r.URL.Query().Get("level")),
+		Page:       1,
+		PageSize:   appdefaults.DefaultStatusMessagesPageSize,
+	}
+	for name, dest := range map[string]*uint{"page": &listParams.Page, "page_size": &listParams.PageSize} {
+		val := r.URL.Query().Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid %s %q", name, val))
+			return
+		}
+		*dest = uint(parsed)
+	}
+
+	messages, err := a.r.ListInstanceStatusMessages(ctx, instanceName, listParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instance status messages")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(messages); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route DELETE /instances/{instanceName} instances DeleteInstance
 //
 // Delete runner instance by name.
@@ -164,6 +340,87 @@ func (a *APIController) DeleteInstanceHandler(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusOK)
 }
 
+// swagger:route DELETE /instances instances BulkDeleteInstances
+//
+// Mark all the runners matching the given filters for deletion. At least one filter
+// must be set. Runners that are running a job are only removed if force_remove is set.
+//
+//	Parameters:
+//	  + name: pool_id
+//	    description: Only remove runners of this pool.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Only remove runners in this status (running or error).
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: older_than
+//	    description: Only remove runners created more than this long ago, as a duration (eg: 1h, 30m).
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: force_remove
+//	    description: Mark runners as pending_force_delete, and also remove runners that are running a job.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
+//	  + name: dry_run
+//	    description: Only list the runners that would be removed.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: BulkDeleteInstancesResult
+//	  default: APIErrorResponse
+func (a *APIController) BulkDeleteInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	deleteParams := runnerParams.BulkDeleteInstancesParams{
+		PoolID: query.Get("pool_id"),
+		Status: commonParams.InstanceStatus(query.Get("status")),
+	}
+	if val := query.Get("older_than"); val != "" {
+		olderThan, err := time.ParseDuration(val)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid older_than %q: %s", val, err))
+			return
+		}
+		deleteParams.OlderThan = olderThan
+	}
+	for name, dest := range map[string]*bool{"force_remove": &deleteParams.ForceRemove, "dry_run": &deleteParams.DryRun} {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid %s %q", name, val))
+			return
+		}
+		*dest = parsed
+	}
+
+	result, err := a.r.BulkDeleteRunners(ctx, deleteParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "removing runners")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route GET /repositories/{repoID}/instances repositories instances ListRepoInstances
 //
 // List repository instances.
@@ -175,6 +432,60 @@ func (a *APIController) DeleteInstanceHandler(w http.ResponseWriter, r *http.Req
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: name, status, runner_status, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Comma separated list of instance statuses. Only instances with one of these statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: runner_status
+//	    description: Comma separated list of runner statuses. Only instances with one of these runner statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: provider
+//	    description: Only return instances created by this provider.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: pool_id
+//	    description: Only return instances in this pool.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_before
+//	    description: Only return instances created before this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_after
+//	    description: Only return instances created after this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Instances
 //	  default: APIErrorResponse
@@ -193,6 +504,10 @@ func (a *APIController) ListRepoInstancesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if a.listFilteredInstances(w, r, &runnerParams.GithubEntity{ID: repoID, EntityType: runnerParams.GithubEntityTypeRepository}, "") {
+		return
+	}
+
 	instances, err := a.r.ListRepoInstances(ctx, repoID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
@@ -217,6 +532,60 @@ func (a *APIController) ListRepoInstancesHandler(w http.ResponseWriter, r *http.
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: name, status, runner_status, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Comma separated list of instance statuses. Only instances with one of these statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: runner_status
+//	    description: Comma separated list of runner statuses. Only instances with one of these runner statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: provider
+//	    description: Only return instances created by this provider.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: pool_id
+//	    description: Only return instances in this pool.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_before
+//	    description: Only return instances created before this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_after
+//	    description: Only return instances created after this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Instances
 //	  default: APIErrorResponse
@@ -235,6 +604,10 @@ func (a *APIController) ListOrgInstancesHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if a.listFilteredInstances(w, r, &runnerParams.GithubEntity{ID: orgID, EntityType: runnerParams.GithubEntityTypeOrganization}, "") {
+		return
+	}
+
 	instances, err := a.r.ListOrgInstances(ctx, orgID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instances")
@@ -259,6 +632,60 @@ func (a *APIController) ListOrgInstancesHandler(w http.ResponseWriter, r *http.R
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: name, status, runner_status, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Comma separated list of instance statuses. Only instances with one of these statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: runner_status
+//	    description: Comma separated list of runner statuses. Only instances with one of these runner statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: provider
+//	    description: Only return instances created by this provider.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: pool_id
+//	    description: Only return instances in this pool.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_before
+//	    description: Only return instances created before this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_after
+//	    description: Only return instances created after this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Instances
 //	  default: APIErrorResponse
@@ -277,6 +704,10 @@ func (a *APIController) ListEnterpriseInstancesHandler(w http.ResponseWriter, r
 		return
 	}
 
+	if a.listFilteredInstances(w, r, &runnerParams.GithubEntity{ID: enterpriseID, EntityType: runnerParams.GithubEntityTypeEnterprise}, "") {
+		return
+	}
+
 	instances, err := a.r.ListEnterpriseInstances(ctx, enterpriseID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instances")
@@ -294,12 +725,71 @@ func (a *APIController) ListEnterpriseInstancesHandler(w http.ResponseWriter, r
 //
 // Get all runners' instances.
 //
+//	Parameters:
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: name, status, runner_status, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Comma separated list of instance statuses. Only instances with one of these statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: runner_status
+//	    description: Comma separated list of runner statuses. Only instances with one of these runner statuses are returned.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: provider
+//	    description: Only return instances created by this provider.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: pool_id
+//	    description: Only return instances in this pool.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_before
+//	    description: Only return instances created before this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: created_after
+//	    description: Only return instances created after this RFC 3339 timestamp.
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Instances
 //	  default: APIErrorResponse
 func (a *APIController) ListAllInstancesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if a.listFilteredInstances(w, r, nil, "") {
+		return
+	}
+
 	instances, err := a.r.ListAllInstances(ctx)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instances")
@@ -329,6 +819,9 @@ func (a *APIController) InstanceStatusMessageHandler(w http.ResponseWriter, r *h
 		return
 	}
 
+	if skew, skewed := a.r.InstanceClockSkew(updateMessage); skewed {
+		w.Header().Set(runnerParams.InstanceClockSkewHeader, strconv.FormatInt(int64(skew.Seconds()), 10))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
@@ -352,3 +845,83 @@ func (a *APIController) InstanceSystemInfoHandler(w http.ResponseWriter, r *http
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+// instanceFilterFromRequest parses the query parameters that filter instances. The
+// status and runner_status parameters accept a comma separated list of values.
+func instanceFilterFromRequest(r *http.Request) (runnerParams.InstanceFilter, error) {
+	query := r.URL.Query()
+	filter := runnerParams.InstanceFilter{
+		ProviderName: query.Get("provider"),
+		PoolID:       query.Get("pool_id"),
+	}
+	if val := query.Get("status"); val != "" {
+		for _, status := range strings.Split(val, ",") {
+			filter.Statuses = append(filter.Statuses, commonParams.InstanceStatus(strings.TrimSpace(status)))
+		}
+	}
+	if val := query.Get("runner_status"); val != "" {
+		for _, status := range strings.Split(val, ",") {
+			filter.RunnerStatuses = append(filter.RunnerStatuses, runnerParams.RunnerStatus(strings.TrimSpace(status)))
+		}
+	}
+	for name, dest := range map[string]**time.Time{"created_before": &filter.CreatedBefore, "created_after": &filter.CreatedAfter} {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return runnerParams.InstanceFilter{}, gErrors.NewBadRequestError("invalid %s %q: must be an RFC 3339 timestamp", name, val)
+		}
+		*dest = &parsed
+	}
+	return filter, nil
+}
+
+// listFilteredInstances writes the instances that match the filter in the query
+// parameters, if a filter or a page is requested. If entity is set, only instances in
+// the pools of that entity are listed. If poolID is set, only instances in that pool
+// are listed. It returns false if the handler should list all instances.
+func (a *APIController) listFilteredInstances(w http.ResponseWriter, r *http.Request, entity *runnerParams.GithubEntity, poolID string) bool {
+	ctx := r.Context()
+
+	filter, err := instanceFilterFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return true
+	}
+	filtered := !filter.IsEmpty()
+	if poolID != "" {
+		filter.PoolID = poolID
+	}
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return true
+	}
+
+	switch {
+	case paginated:
+		page, err := a.r.ListInstancesPage(ctx, entity, filter, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instances")
+			handleError(ctx, w, err)
+			return true
+		}
+		writePaginated(ctx, w, page)
+	case filtered:
+		instances, err := a.r.ListInstances(ctx, entity, filter)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instances")
+			handleError(ctx, w, err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(instances); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+	default:
+		return false
+	}
+	return true
+}
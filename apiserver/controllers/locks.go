@@ -0,0 +1,79 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+)
+
+// swagger:route GET /locks locks ListInstanceLocks
+//
+// List the locks currently held on runner instances.
+//
+//	Responses:
+//	  200: InstanceLocks
+//	  default: APIErrorResponse
+func (a *APIController) ListInstanceLocksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	locks, err := a.r.ListInstanceLocks(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing instance locks")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(locks); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route DELETE /locks/{instanceName} locks ReleaseInstanceLock
+//
+// Force release the lock held on a runner instance.
+//
+//	Parameters:
+//	  + name: instanceName
+//	    description: Runner instance name.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  default: APIErrorResponse
+func (a *APIController) ReleaseInstanceLockHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	instanceName, ok := vars["instanceName"]
+	if !ok {
+		handleError(ctx, w, gErrors.NewBadRequestError("no instance name specified"))
+		return
+	}
+
+	if err := a.r.ReleaseInstanceLock(ctx, instanceName); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "releasing instance lock")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -109,6 +109,51 @@ func (a *APIController) SystemdUnitFileHandler(w http.ResponseWriter, r *http.Re
 	}
 }
 
+func (a *APIController) InstanceToolsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tools, err := a.r.GetInstanceTools(ctx)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tools); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func (a *APIController) InstanceNetworkSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	settings, err := a.r.GetInstanceNetworkSettings(ctx)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func (a *APIController) InstanceMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metadata, err := a.r.GetInstanceMetadata(ctx)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metadata); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 func (a *APIController) RootCertificateBundleHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
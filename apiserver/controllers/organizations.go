@@ -248,6 +248,12 @@ func (a *APIController) UpdateOrgHandler(w http.ResponseWriter, r *http.Request)
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -274,6 +280,12 @@ func (a *APIController) CreateOrgPoolHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.CreateOrgPool(ctx, orgID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating organization pool")
@@ -298,6 +310,24 @@ func (a *APIController) CreateOrgPoolHandler(w http.ResponseWriter, r *http.Requ
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: provider_name, image, flavor, priority, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pools
 //	  default: APIErrorResponse
@@ -316,6 +346,22 @@ func (a *APIController) ListOrgPoolsHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	if paginated {
+		page, err := a.r.ListEntityPoolsPage(ctx, runnerParams.GithubEntity{ID: orgID, EntityType: runnerParams.GithubEntityTypeOrganization}, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
+			handleError(ctx, w, err)
+			return
+		}
+		writePaginated(ctx, w, page)
+		return
+	}
+
 	pools, err := a.r.ListOrgPools(ctx, orgID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
@@ -447,6 +493,12 @@ func (a *APIController) DeleteOrgPoolHandler(w http.ResponseWriter, r *http.Requ
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -474,6 +526,12 @@ func (a *APIController) UpdateOrgPoolHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.UpdateOrgPool(ctx, orgID, poolID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating organization pool")
@@ -0,0 +1,70 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/compat"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+// listOptionsFromRequest parses the page, page_size and sort query parameters of a
+// list operation. The results are paginated if any of them is set, or if the request
+// is served by version 2 of the API. Otherwise, version 1 handlers return all results,
+// as they always did.
+func listOptionsFromRequest(r *http.Request) (runnerParams.ListOptions, bool, error) {
+	query := r.URL.Query()
+	opts := runnerParams.ListOptions{
+		Page:     1,
+		PageSize: appdefaults.DefaultListPageSize,
+		Sort:     query.Get("sort"),
+	}
+	paginated := compat.IsV2(r.Context()) || opts.Sort != ""
+	for name, dest := range map[string]*uint{"page": &opts.Page, "page_size": &opts.PageSize} {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return runnerParams.ListOptions{}, false, gErrors.NewBadRequestError("invalid %s %q", name, val)
+		}
+		*dest = uint(parsed)
+		paginated = true
+	}
+	return opts, paginated, nil
+}
+
+// writePaginated writes one page of results.
+func writePaginated[T any](ctx context.Context, w http.ResponseWriter, result runnerParams.PaginatedResult[T]) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(params.PaginatedResponse[T]{
+		Items:      result.Items,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalCount: result.TotalCount,
+		TotalPages: result.TotalPages,
+	}); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 
@@ -30,12 +31,47 @@ import (
 //
 // List all pools.
 //
+//	Parameters:
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: provider_name, image, flavor, priority, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pools
 //	  default: APIErrorResponse
 func (a *APIController) ListAllPoolsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	if paginated {
+		page, err := a.r.ListAllPoolsPage(ctx, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
+			handleError(ctx, w, err)
+			return
+		}
+		writePaginated(ctx, w, page)
+		return
+	}
+
 	pools, err := a.r.ListAllPools(ctx)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
@@ -87,6 +123,11 @@ func (a *APIController) GetPoolByIDHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	pool.RunnerBootstrapTimeout = pool.RunnerTimeout()
+	pool.IdleDetectionWindow = pool.IdleWindow()
+	pool.ScaleDownGracePeriod = pool.ScaleDownGrace()
+	pool.ScaleDownFactor = pool.ScaleDownFraction()
+	pool.CapacityWarningThreshold = pool.CapacityThreshold()
+	pool.AutoscaleCooldown = pool.AutoscaleCooldownPeriod()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(pool); err != nil {
@@ -150,6 +191,12 @@ func (a *APIController) DeletePoolByIDHandler(w http.ResponseWriter, r *http.Req
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -176,6 +223,12 @@ func (a *APIController) UpdatePoolByIDHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.UpdatePoolByID(ctx, poolID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching pool")
@@ -188,3 +241,56 @@ func (a *APIController) UpdatePoolByIDHandler(w http.ResponseWriter, r *http.Req
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
 	}
 }
+
+// swagger:route POST /pools/tags pools BulkUpdatePoolTags
+//
+// Add, remove or rename a tag across all pools of the controller.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: The tag operation to apply.
+//	    type: BulkUpdatePoolTagsParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: BulkUpdatePoolTagsResult
+//	  default: APIErrorResponse
+func (a *APIController) BulkUpdatePoolTagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var param runnerParams.BulkUpdatePoolTagsParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	result, err := a.r.BulkUpdatePoolTags(ctx, "", param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "updating pool tags")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// dryRunFromRequest parses the dry_run query parameter of pool create and update
+// requests. If the value is invalid, an error is written to the response and false
+// is returned.
+func dryRunFromRequest(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	val := r.URL.Query().Get("dry_run")
+	if val == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(val)
+	if err != nil {
+		handleError(r.Context(), w, gErrors.NewBadRequestError("invalid dry_run %q", val))
+		return false, false
+	}
+	return dryRun, true
+}
@@ -0,0 +1,321 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route POST /providers/{providerName}/pause providers PauseProvider
+//
+// Pause a provider. Pools that use a paused provider don't create new instances.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider to pause.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Parameters used when pausing the provider.
+//	    type: PauseProviderParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: Provider
+//	  default: APIErrorResponse
+func (a *APIController) PauseProviderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var pauseParams runnerParams.PauseProviderParams
+	if err := json.NewDecoder(r.Body).Decode(&pauseParams); err != nil {
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	provider, err := a.r.PauseProvider(ctx, providerName, pauseParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "pausing provider")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(provider); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /providers/{providerName}/resume providers ResumeProvider
+//
+// Resume a paused provider.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider to resume.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: Provider
+//	  default: APIErrorResponse
+func (a *APIController) ResumeProviderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	provider, err := a.r.ResumeProvider(ctx, providerName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "resuming provider")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(provider); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /providers/{providerName}/environment providers GetProviderEnvironment
+//
+// Get the names of the environment variables stored for a provider.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: ProviderEnvironment
+//	  default: APIErrorResponse
+func (a *APIController) GetProviderEnvironmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	env, err := a.r.GetProviderEnvironment(ctx, providerName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching provider environment")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route PUT /providers/{providerName}/environment providers UpdateProviderEnvironment
+//
+// Set or unset environment variables passed to a provider.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Environment variables to set or unset.
+//	    type: UpdateProviderEnvironmentParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: ProviderEnvironment
+//	  default: APIErrorResponse
+func (a *APIController) UpdateProviderEnvironmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var updateParams runnerParams.UpdateProviderEnvironmentParams
+	if err := json.NewDecoder(r.Body).Decode(&updateParams); err != nil {
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	env, err := a.r.UpdateProviderEnvironment(ctx, providerName, updateParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "updating provider environment")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /providers/{providerName}/orphans providers ListOrphanedInstances
+//
+// List the instances of a provider that GARM has no record of. Nothing is removed.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: OrphanSweep
+//	  default: APIErrorResponse
+func (a *APIController) ListOrphanedInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	sweep, err := a.r.ListOrphanedInstances(ctx, providerName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing orphaned instances")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sweep); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /providers/{providerName}/orphans/cleanup providers CleanupOrphanedInstances
+//
+// Remove the instances of a provider that GARM has no record of.
+//
+//	Parameters:
+//	  + name: providerName
+//	    description: The name of the provider.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	  + name: Body
+//	    description: Parameters used when removing orphaned instances.
+//	    type: CleanupOrphanedInstancesParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: OrphanSweep
+//	  default: APIErrorResponse
+func (a *APIController) CleanupOrphanedInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	providerName, ok := vars["providerName"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No provider name specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var cleanupParams runnerParams.CleanupOrphanedInstancesParams
+	if err := json.NewDecoder(r.Body).Decode(&cleanupParams); err != nil {
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	sweep, err := a.r.CleanupOrphanedInstances(ctx, providerName, cleanupParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "removing orphaned instances")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sweep); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
@@ -247,6 +247,12 @@ func (a *APIController) UpdateRepoHandler(w http.ResponseWriter, r *http.Request
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -273,6 +279,12 @@ func (a *APIController) CreateRepoPoolHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.CreateRepoPool(ctx, repoID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating repository pool")
@@ -297,6 +309,24 @@ func (a *APIController) CreateRepoPoolHandler(w http.ResponseWriter, r *http.Req
 //	    in: path
 //	    required: true
 //
+//	  + name: page
+//	    description: The page to return, starting from 1. If page, page_size or sort is set, one page of results is returned.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of items in each page. Defaults to 100.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: sort
+//	    description: "The field by which results are sorted, prefixed with - for descending order. One of: provider_name, image, flavor, priority, created_at, updated_at."
+//	    type: string
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pools
 //	  default: APIErrorResponse
@@ -315,6 +345,22 @@ func (a *APIController) ListRepoPoolsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	opts, paginated, err := listOptionsFromRequest(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	if paginated {
+		page, err := a.r.ListEntityPoolsPage(ctx, runnerParams.GithubEntity{ID: repoID, EntityType: runnerParams.GithubEntityTypeRepository}, opts)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
+			handleError(ctx, w, err)
+			return
+		}
+		writePaginated(ctx, w, page)
+		return
+	}
+
 	pools, err := a.r.ListRepoPools(ctx, repoID)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing pools")
@@ -446,6 +492,12 @@ func (a *APIController) DeleteRepoPoolHandler(w http.ResponseWriter, r *http.Req
 //	    in: body
 //	    required: true
 //
+//	  + name: dry_run
+//	    description: Validate the pool and return it, without saving it.
+//	    type: boolean
+//	    in: query
+//	    required: false
+//
 //	Responses:
 //	  200: Pool
 //	  default: APIErrorResponse
@@ -473,6 +525,12 @@ func (a *APIController) UpdateRepoPoolHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	dryRun, ok := dryRunFromRequest(w, r)
+	if !ok {
+		return
+	}
+	poolData.DryRun = dryRun
+
 	pool, err := a.r.UpdateRepoPool(ctx, repoID, poolID, poolData)
 	if err != nil {
 		slog.With(slog.Any("error", err)).ErrorContext(ctx, "error creating repository pool")
@@ -543,6 +601,50 @@ func (a *APIController) InstallRepoWebhookHandler(w http.ResponseWriter, r *http
 	}
 }
 
+// swagger:route POST /repositories/{repoID}/jobs/sync repositories jobs SyncRepoJobs
+//
+// Record the jobs queued in GitHub for the repository that GARM missed, for example
+// because it was offline when the webhooks were sent.
+//
+//	Parameters:
+//	  + name: repoID
+//	    description: Repository ID.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: Jobs
+//	  default: APIErrorResponse
+func (a *APIController) SyncRepoJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	repoID, ok := vars["repoID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No repository ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	jobs, err := a.r.SyncRepoJobs(ctx, repoID)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "syncing jobs")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
 // swagger:route DELETE /repositories/{repoID}/webhook repositories hooks UninstallRepoWebhook
 //
 // Uninstall organization webhook.
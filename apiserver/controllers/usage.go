@@ -0,0 +1,125 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route GET /usage usage GetUsageReport
+//
+// Get the runtime of runners, aggregated per pool, provider and entity.
+//
+//	Parameters:
+//	  + name: since
+//	    description: Start of the reported period, in RFC3339 format. Defaults to the first recorded runner.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: until
+//	    description: End of the reported period, in RFC3339 format. Defaults to now.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: UsageReport
+//	  default: APIErrorResponse
+func (a *APIController) GetUsageReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reportParams, err := usageReportParamsFromQuery(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	report, err := a.r.GetUsageReport(ctx, reportParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching usage report")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /usage/efficiency usage GetEfficiencyReport
+//
+// Get the number of jobs served by runners, their first job latency and idle time, per pool.
+//
+//	Parameters:
+//	  + name: since
+//	    description: Start of the reported period, in RFC3339 format. Defaults to the first recorded runner.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: until
+//	    description: End of the reported period, in RFC3339 format. Defaults to now.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: EfficiencyReport
+//	  default: APIErrorResponse
+func (a *APIController) GetEfficiencyReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reportParams, err := usageReportParamsFromQuery(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	report, err := a.r.GetEfficiencyReport(ctx, reportParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching efficiency report")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+func usageReportParamsFromQuery(r *http.Request) (runnerParams.UsageReportParams, error) {
+	var reportParams runnerParams.UsageReportParams
+	for name, dest := range map[string]*time.Time{"since": &reportParams.Since, "until": &reportParams.Until} {
+		val := r.URL.Query().Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return runnerParams.UsageReportParams{}, gErrors.NewBadRequestError("invalid %s %q: %s", name, val, err)
+		}
+		*dest = parsed.UTC()
+	}
+	return reportParams, nil
+}
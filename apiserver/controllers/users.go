@@ -0,0 +1,252 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+)
+
+// swagger:route POST /users users CreateUser
+//
+// Create a user. Users are created with an optional role on all entities, and can
+// be given roles on single entities.
+//
+//	Parameters:
+//	  + name: Body
+//	    description: Parameters used when creating the user.
+//	    type: NewUserParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: User
+//	  default: APIErrorResponse
+func (a *APIController) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var param runnerParams.NewUserParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	user, err := a.auth.CreateUser(ctx, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating user")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route GET /users/{username} users GetUser
+//
+// Get a user, along with its lockout status.
+//
+//	Parameters:
+//	  + name: username
+//	    description: The username or email of the user.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: User
+//	  default: APIErrorResponse
+func (a *APIController) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	username, ok := vars["username"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No username specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	user, err := a.auth.GetUser(ctx, username)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "fetching user")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /users/{username}/unlock users UnlockUser
+//
+// Lift the lockout of a user and clear its failed logins.
+//
+//	Parameters:
+//	  + name: username
+//	    description: The username or email of the user.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: User
+//	  default: APIErrorResponse
+func (a *APIController) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	username, ok := vars["username"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No username specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	user, err := a.auth.UnlockUser(ctx, username)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "unlocking user")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /users/{username}/grants users CreateRoleGrant
+//
+// Give a user a role on a repository, organization or enterprise. An existing role
+// of the user on the same entity is replaced.
+//
+//	Parameters:
+//	  + name: username
+//	    description: The username or email of the user.
+//	    type: string
+//	    in: path
+//	    required: true
+//	  + name: Body
+//	    description: Parameters used when creating the role grant.
+//	    type: CreateRoleGrantParams
+//	    in: body
+//	    required: true
+//
+//	Responses:
+//	  200: RoleGrant
+//	  default: APIErrorResponse
+func (a *APIController) CreateRoleGrantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	username, ok := vars["username"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No username specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	var param runnerParams.CreateRoleGrantParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to decode")
+		handleError(ctx, w, gErrors.ErrBadRequest)
+		return
+	}
+
+	grant, err := a.auth.CreateRoleGrant(ctx, username, param)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "creating role grant")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(grant); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route DELETE /users/{username}/grants/{grantID} users DeleteRoleGrant
+//
+// Remove a role grant of a user.
+//
+//	Parameters:
+//	  + name: username
+//	    description: The username or email of the user.
+//	    type: string
+//	    in: path
+//	    required: true
+//	  + name: grantID
+//	    description: ID of the role grant to remove.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  default: APIErrorResponse
+func (a *APIController) DeleteRoleGrantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	username, ok := vars["username"]
+	grantID, grantOk := vars["grantID"]
+	if !ok || !grantOk {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No username or grant ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	if err := a.auth.DeleteRoleGrant(ctx, username, grantID); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "deleting role grant")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
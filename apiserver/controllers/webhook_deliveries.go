@@ -0,0 +1,139 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	gErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/apiserver/params"
+	runnerParams "github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+// swagger:route GET /webhook-deliveries webhooks ListWebhookDeliveries
+//
+// List the workflow job webhooks received by GARM, newest first.
+//
+//	Parameters:
+//	  + name: entity_id
+//	    description: Only return deliveries meant for this repository, organization or enterprise.
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: status
+//	    description: Only return deliveries with this status (processed or failed).
+//	    type: string
+//	    in: query
+//	    required: false
+//
+//	  + name: page
+//	    description: The page to return, starting from 1. Defaults to 1.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	  + name: page_size
+//	    description: The number of deliveries in each page. Defaults to 50.
+//	    type: integer
+//	    in: query
+//	    required: false
+//
+//	Responses:
+//	  200: WebhookDeliveriesPage
+//	  default: APIErrorResponse
+func (a *APIController) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	listParams := runnerParams.ListWebhookDeliveriesParams{
+		EntityID: r.URL.Query().Get("entity_id"),
+		Status:   runnerParams.WebhookDeliveryStatus(r.URL.Query().Get("status")),
+		Page:     1,
+		PageSize: appdefaults.DefaultWebhookDeliveriesPageSize,
+	}
+	for name, dest := range map[string]*uint{"page": &listParams.Page, "page_size": &listParams.PageSize} {
+		val := r.URL.Query().Get(name)
+		if val == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			handleError(ctx, w, gErrors.NewBadRequestError("invalid %s %q", name, val))
+			return
+		}
+		*dest = uint(parsed)
+	}
+
+	deliveries, err := a.r.ListWebhookDeliveries(ctx, listParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "listing webhook deliveries")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+// swagger:route POST /webhook-deliveries/{deliveryID}/redeliver webhooks RedeliverWebhook
+//
+// Process a failed webhook delivery again.
+//
+//	Parameters:
+//	  + name: deliveryID
+//	    description: The ID of the delivery.
+//	    type: string
+//	    in: path
+//	    required: true
+//
+//	Responses:
+//	  200: WebhookDelivery
+//	  default: APIErrorResponse
+func (a *APIController) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	deliveryID, ok := vars["deliveryID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(params.APIErrorResponse{
+			Error:   "Bad Request",
+			Details: "No delivery ID specified",
+		}); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+		}
+		return
+	}
+
+	delivery, err := a.r.RedeliverWebhook(ctx, deliveryID)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "processing webhook delivery again")
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
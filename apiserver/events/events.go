@@ -13,10 +13,17 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/database/watcher"
+	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/websocket"
 )
 
-func NewHandler(ctx context.Context, client *websocket.Client) (*EventHandler, error) {
+// poolLister is used to find the pools of an entity when clients filter instance
+// events by entity.
+type poolLister interface {
+	ListAllPools(ctx context.Context) ([]params.Pool, error)
+}
+
+func NewHandler(ctx context.Context, client *websocket.Client, pools poolLister) (*EventHandler, error) {
 	if client == nil {
 		return nil, runnerErrors.ErrUnauthorized
 	}
@@ -39,6 +46,7 @@ func NewHandler(ctx context.Context, client *websocket.Client) (*EventHandler, e
 		client:   client,
 		ctx:      ctx,
 		consumer: consumer,
+		pools:    pools,
 		done:     make(chan struct{}),
 	}
 	client.SetMessageHandler(handler.HandleClientMessages)
@@ -49,6 +57,7 @@ func NewHandler(ctx context.Context, client *websocket.Client) (*EventHandler, e
 type EventHandler struct {
 	client   *websocket.Client
 	consumer common.Consumer
+	pools    poolLister
 
 	ctx     context.Context
 	done    chan struct{}
@@ -126,16 +135,27 @@ func (e *EventHandler) Done() <-chan struct{} {
 // Which means that if any of the elements in the array match an event, it will be
 // sent to the websocket.
 // Alternatively, clients can choose to get everything.
-func (e *EventHandler) optionsToWatcherFilters(opt Options) common.PayloadFilterFunc {
+func (e *EventHandler) optionsToWatcherFilters(opt Options) (common.PayloadFilterFunc, error) {
 	if opt.SendEverything {
-		return watcher.WithEverything()
+		return watcher.WithEverything(), nil
 	}
 
+	// Filters that select instances by entity need to see pool events, in order to
+	// keep track of the pools of the entity. We place them first, so they are not
+	// short circuited by other filters.
+	var entityFuncs []common.PayloadFilterFunc
 	var funcs []common.PayloadFilterFunc
 	for _, filter := range opt.Filters {
 		var filterFunc []common.PayloadFilterFunc
 		if filter.EntityType == "" {
-			return watcher.WithNone()
+			return watcher.WithNone(), nil
+		}
+		if filter.Entity != nil {
+			entityFilter, err := e.entityFilter(filter.EntityType, *filter.Entity)
+			if err != nil {
+				return nil, err
+			}
+			filterFunc = append(filterFunc, entityFilter)
 		}
 		filterFunc = append(filterFunc, watcher.WithEntityTypeFilter(filter.EntityType))
 		if len(filter.Operations) > 0 {
@@ -145,9 +165,86 @@ func (e *EventHandler) optionsToWatcherFilters(opt Options) common.PayloadFilter
 			}
 			filterFunc = append(filterFunc, watcher.WithAny(opFunc...))
 		}
-		funcs = append(funcs, watcher.WithAll(filterFunc...))
+		if len(filter.PoolIDs) > 0 {
+			filterFunc = append(filterFunc, watcher.WithInstancePoolFilter(filter.PoolIDs...))
+		}
+		if len(filter.InstanceStatuses) > 0 {
+			// Delete payloads only hold the identity of the instance, not its status.
+			filterFunc = append(filterFunc, watcher.WithAny(
+				watcher.WithOperationTypeFilter(common.DeleteOperation),
+				watcher.WithInstanceStatusFilter(filter.InstanceStatuses...),
+			))
+		}
+		if len(filter.JobStatuses) > 0 {
+			// Delete payloads only hold the ID of the job, not its status.
+			filterFunc = append(filterFunc, watcher.WithAny(
+				watcher.WithOperationTypeFilter(common.DeleteOperation),
+				watcher.WithJobStatusFilter(filter.JobStatuses...),
+			))
+		}
+		if filter.Entity != nil && filter.EntityType == common.InstanceEntityType {
+			entityFuncs = append(entityFuncs, watcher.WithAll(filterFunc...))
+		} else {
+			funcs = append(funcs, watcher.WithAll(filterFunc...))
+		}
+	}
+	return watcher.WithAny(append(entityFuncs, funcs...)...), nil
+}
+
+// entityFilter returns a filter that matches the pools, jobs or instances of an entity.
+func (e *EventHandler) entityFilter(entityType common.DatabaseEntityType, entity EntityFilter) (common.PayloadFilterFunc, error) {
+	ghEntity := params.GithubEntity{
+		ID:         entity.ID,
+		EntityType: entity.Type,
+	}
+	switch entityType {
+	case common.PoolEntityType:
+		// Delete payloads only hold the ID of the pool, not the entity it belonged to.
+		return watcher.WithAny(
+			watcher.WithOperationTypeFilter(common.DeleteOperation),
+			watcher.WithEntityPoolFilter(ghEntity),
+		), nil
+	case common.JobEntityType:
+		return watcher.WithEntityJobFilter(ghEntity), nil
+	default:
+		return e.entityInstanceFilter(entity)
+	}
+}
+
+func (e *EventHandler) entityInstanceFilter(entity EntityFilter) (common.PayloadFilterFunc, error) {
+	if e.pools == nil {
+		return nil, fmt.Errorf("filtering by entity is not supported")
+	}
+	pools, err := e.pools.ListAllPools(e.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pools: %w", err)
+	}
+
+	ghEntity := params.GithubEntity{
+		ID:         entity.ID,
+		EntityType: entity.Type,
+	}
+	var poolIDs []string
+	for _, pool := range pools {
+		switch entity.Type {
+		case params.GithubEntityTypeRepository:
+			if pool.RepoID != entity.ID {
+				continue
+			}
+		case params.GithubEntityTypeOrganization:
+			if pool.OrgID != entity.ID {
+				continue
+			}
+		case params.GithubEntityTypeEnterprise:
+			if pool.EnterpriseID != entity.ID {
+				continue
+			}
+		default:
+			continue
+		}
+		poolIDs = append(poolIDs, pool.ID)
 	}
-	return watcher.WithAny(funcs...)
+	return watcher.WithEntityInstanceFilter(ghEntity, poolIDs...), nil
 }
 
 func (e *EventHandler) HandleClientMessages(message []byte) error {
@@ -175,7 +272,13 @@ func (e *EventHandler) HandleClientMessages(message []byte) error {
 		return nil
 	}
 
-	watcherFilters := e.optionsToWatcherFilters(opt)
+	watcherFilters, err := e.optionsToWatcherFilters(opt)
+	if err != nil {
+		slog.ErrorContext(e.ctx, "failed to set up filters", "error", err)
+		e.client.Write([]byte("failed to set up filters"))
+		e.Stop()
+		return nil
+	}
 	e.consumer.SetFilters(watcherFilters)
 	return nil
 }
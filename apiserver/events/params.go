@@ -1,12 +1,36 @@
 package events
 
 import (
+	"fmt"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
 )
 
+// EntityFilter identifies a repository, organization or enterprise.
+type EntityFilter struct {
+	Type params.GithubEntityType `json:"type" jsonschema:"title=type,description=The type of the entity,enum=repository,enum=organization,enum=enterprise"`
+	ID   string                  `json:"id" jsonschema:"title=id,description=The ID of the entity"`
+}
+
 type Filter struct {
 	Operations []common.OperationType    `json:"operations,omitempty" jsonschema:"title=operations,description=A list of operations to filter on,enum=create,enum=update,enum=delete"`
 	EntityType common.DatabaseEntityType `json:"entity-type,omitempty" jsonschema:"title=entity type,description=The type of entity to filter on,enum=repository,enum=organization,enum=enterprise,enum=pool,enum=user,enum=instance,enum=job,enum=controller,enum=github_credentials,enum=github_endpoint"`
+
+	// The following fields can only be used when the entity type is "instance".
+	PoolIDs          []string                      `json:"pool-ids,omitempty" jsonschema:"title=pool IDs,description=Only send events for instances that belong to one of these pools"`
+	InstanceStatuses []commonParams.InstanceStatus `json:"instance-statuses,omitempty" jsonschema:"title=instance statuses,description=Only send events for instances in one of these statuses. Delete events are always sent"`
+
+	// This field can only be used when the entity type is "job".
+	JobStatuses []params.JobStatus `json:"job-statuses,omitempty" jsonschema:"title=job statuses,description=Only send events for jobs in one of these statuses. Delete events are always sent"`
+
+	// This field can be used when the entity type is "instance", "pool" or "job".
+	Entity *EntityFilter `json:"entity,omitempty" jsonschema:"title=entity,description=Only send events for instances or pools or jobs that belong to this entity"`
+}
+
+func (f Filter) hasInstanceFilters() bool {
+	return len(f.PoolIDs) > 0 || len(f.InstanceStatuses) > 0
 }
 
 func (f Filter) Validate() error {
@@ -26,6 +50,45 @@ func (f Filter) Validate() error {
 			return common.ErrInvalidOperationType
 		}
 	}
+
+	if f.hasInstanceFilters() && f.EntityType != common.InstanceEntityType {
+		return fmt.Errorf("instance filters require the instance entity type: %w", common.ErrInvalidEntityType)
+	}
+	if len(f.JobStatuses) > 0 && f.EntityType != common.JobEntityType {
+		return fmt.Errorf("job filters require the job entity type: %w", common.ErrInvalidEntityType)
+	}
+	for _, status := range f.JobStatuses {
+		switch status {
+		case params.JobStatusQueued, params.JobStatusWaiting, params.JobStatusInProgress, params.JobStatusCompleted:
+		default:
+			return fmt.Errorf("invalid job status %q: %w", status, common.ErrInvalidEntityType)
+		}
+	}
+	if f.Entity != nil {
+		switch f.EntityType {
+		case common.InstanceEntityType, common.PoolEntityType, common.JobEntityType:
+		default:
+			return fmt.Errorf("entity filters require the instance, pool or job entity type: %w", common.ErrInvalidEntityType)
+		}
+		switch f.Entity.Type {
+		case params.GithubEntityTypeRepository, params.GithubEntityTypeOrganization, params.GithubEntityTypeEnterprise:
+		default:
+			return common.ErrInvalidEntityType
+		}
+		if f.Entity.ID == "" {
+			return fmt.Errorf("missing entity ID: %w", common.ErrInvalidEntityType)
+		}
+	}
+	for _, status := range f.InstanceStatuses {
+		switch status {
+		case commonParams.InstanceRunning, commonParams.InstanceStopped, commonParams.InstanceError,
+			commonParams.InstancePendingDelete, commonParams.InstancePendingForceDelete,
+			commonParams.InstanceDeleting, commonParams.InstancePendingCreate,
+			commonParams.InstanceCreating, commonParams.InstanceStatusUnknown:
+		default:
+			return fmt.Errorf("invalid instance status %q: %w", status, common.ErrInvalidEntityType)
+		}
+	}
 	return nil
 }
 
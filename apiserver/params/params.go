@@ -42,3 +42,31 @@ var (
 		Details: "Missing required URLs. Make sure you update the metadata, callback and webhook URLs",
 	}
 )
+
+// APIErrorV2 holds information about an error, as returned by version 2 of the API.
+type APIErrorV2 struct {
+	// Code is a machine readable error code (eg: not_found, bad_request).
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+// APIErrorResponseV2 is the error envelope returned by version 2 of the API.
+type APIErrorResponseV2 struct {
+	Error APIErrorV2 `json:"error"`
+}
+
+// PaginatedResponse is returned by list operations in version 2 of the API, and in
+// version 1 when a page is requested.
+type PaginatedResponse[T any] struct {
+	Items      []T  `json:"items"`
+	Page       uint `json:"page"`
+	PageSize   uint `json:"page_size"`
+	TotalCount uint `json:"total_count"`
+	TotalPages uint `json:"total_pages"`
+}
+
+// ReadyzResponse is returned by the readiness endpoint.
+type ReadyzResponse struct {
+	Status string `json:"status"`
+}
@@ -55,6 +55,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/cloudbase/garm/apiserver/compat"
 	"github.com/cloudbase/garm/apiserver/controllers"
 	"github.com/cloudbase/garm/auth"
 )
@@ -100,25 +101,20 @@ func requestLogger(h http.Handler) http.Handler {
 	})
 }
 
-func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware, urlsRequiredMiddleware, instanceMiddleware auth.Middleware, manageWebhooks bool) *mux.Router {
-	router := mux.NewRouter()
-	router.Use(requestLogger)
-
+// addInternalRoutes registers the routes that need to be reachable by GitHub (webhooks)
+// and by the runners (callbacks and metadata).
+func addInternalRoutes(router *mux.Router, han *controllers.APIController, instanceMiddleware auth.Middleware) {
 	// Handles github webhooks
 	webhookRouter := router.PathPrefix("/webhooks").Subrouter()
 	webhookRouter.Handle("/", http.HandlerFunc(han.WebhookHandler))
 	webhookRouter.Handle("", http.HandlerFunc(han.WebhookHandler))
 	webhookRouter.Handle("/{controllerID}/", http.HandlerFunc(han.WebhookHandler))
 	webhookRouter.Handle("/{controllerID}", http.HandlerFunc(han.WebhookHandler))
+	webhookRouter.Handle("/{controllerID}/{entityID}/", http.HandlerFunc(han.WebhookHandler))
+	webhookRouter.Handle("/{controllerID}/{entityID}", http.HandlerFunc(han.WebhookHandler))
 
-	// Handles API calls
 	apiSubRouter := router.PathPrefix("/api/v1").Subrouter()
 
-	// FirstRunHandler
-	firstRunRouter := apiSubRouter.PathPrefix("/first-run").Subrouter()
-	firstRunRouter.Handle("/", http.HandlerFunc(han.FirstRunHandler)).Methods("POST", "OPTIONS")
-	firstRunRouter.Handle("", http.HandlerFunc(han.FirstRunHandler)).Methods("POST", "OPTIONS")
-
 	// Instance URLs
 	callbackRouter := apiSubRouter.PathPrefix("/callbacks").Subrouter()
 	callbackRouter.Handle("/status/", http.HandlerFunc(han.InstanceStatusMessageHandler)).Methods("POST", "OPTIONS")
@@ -146,6 +142,59 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	metadataRouter.Handle("/systemd/unit-file", http.HandlerFunc(han.SystemdUnitFileHandler)).Methods("GET", "OPTIONS")
 	metadataRouter.Handle("/system/cert-bundle/", http.HandlerFunc(han.RootCertificateBundleHandler)).Methods("GET", "OPTIONS")
 	metadataRouter.Handle("/system/cert-bundle", http.HandlerFunc(han.RootCertificateBundleHandler)).Methods("GET", "OPTIONS")
+	metadataRouter.Handle("/system/network-settings/", http.HandlerFunc(han.InstanceNetworkSettingsHandler)).Methods("GET", "OPTIONS")
+	metadataRouter.Handle("/system/network-settings", http.HandlerFunc(han.InstanceNetworkSettingsHandler)).Methods("GET", "OPTIONS")
+	metadataRouter.Handle("/system/instance-metadata/", http.HandlerFunc(han.InstanceMetadataHandler)).Methods("GET", "OPTIONS")
+	metadataRouter.Handle("/system/instance-metadata", http.HandlerFunc(han.InstanceMetadataHandler)).Methods("GET", "OPTIONS")
+	// Runner tools
+	metadataRouter.Handle("/tools/", http.HandlerFunc(han.InstanceToolsHandler)).Methods("GET", "OPTIONS")
+	metadataRouter.Handle("/tools", http.HandlerFunc(han.InstanceToolsHandler)).Methods("GET", "OPTIONS")
+}
+
+// NewInternalRouter returns a router that only serves the webhook, callback and metadata
+// routes. It is meant to be used on a separate listener that is only reachable by GitHub
+// and by the runners.
+func NewInternalRouter(han *controllers.APIController, instanceMiddleware auth.Middleware) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(requestLogger)
+
+	addInternalRoutes(router, han, instanceMiddleware)
+	return router
+}
+
+// NewAPIRouter returns the router for the GARM API. If withInternalRoutes is false, the
+// webhook, callback and metadata routes are not registered and must be served using
+// NewInternalRouter.
+func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware, urlsRequiredMiddleware, instanceMiddleware, idempotencyMiddleware auth.Middleware, manageWebhooks, withInternalRoutes bool) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(requestLogger)
+
+	if withInternalRoutes {
+		addInternalRoutes(router, han, instanceMiddleware)
+	}
+
+	// Readiness probe
+	router.Handle("/readyz", http.HandlerFunc(han.ReadyzHandler)).Methods("GET")
+
+	// Handles API calls. Version 2 of the API uses the same handlers as version 1.
+	// The differences between versions are handled by the compat middleware.
+	apiV1Router := router.PathPrefix("/api/v1").Subrouter()
+	apiV1Router.Use(compat.NegotiateVersion)
+	addAPIRoutes(apiV1Router, han, authMiddleware, initMiddleware, urlsRequiredMiddleware, idempotencyMiddleware, manageWebhooks)
+
+	apiV2Router := router.PathPrefix("/api/v2").Subrouter()
+	apiV2Router.Use(compat.V2)
+	addAPIRoutes(apiV2Router, han, authMiddleware, initMiddleware, urlsRequiredMiddleware, idempotencyMiddleware, manageWebhooks)
+	return router
+}
+
+// addAPIRoutes registers the API endpoints on the versioned API subrouter.
+func addAPIRoutes(apiSubRouter *mux.Router, han *controllers.APIController, authMiddleware, initMiddleware, urlsRequiredMiddleware, idempotencyMiddleware auth.Middleware, manageWebhooks bool) {
+
+	// FirstRunHandler
+	firstRunRouter := apiSubRouter.PathPrefix("/first-run").Subrouter()
+	firstRunRouter.Handle("/", http.HandlerFunc(han.FirstRunHandler)).Methods("POST", "OPTIONS")
+	firstRunRouter.Handle("", http.HandlerFunc(han.FirstRunHandler)).Methods("POST", "OPTIONS")
 
 	// Login
 	authRouter := apiSubRouter.PathPrefix("/auth").Subrouter()
@@ -161,6 +210,7 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// updating the URLs.
 	controllerRouter.Use(initMiddleware.Middleware)
 	controllerRouter.Use(authMiddleware.Middleware)
+	controllerRouter.Use(auth.APITokenScopeMiddleware)
 	controllerRouter.Use(auth.AdminRequiredMiddleware)
 	// Get controller info
 	controllerRouter.Handle("/", http.HandlerFunc(han.ControllerInfoHandler)).Methods("GET", "OPTIONS")
@@ -168,6 +218,15 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// Update controller
 	controllerRouter.Handle("/", http.HandlerFunc(han.UpdateControllerHandler)).Methods("PUT", "OPTIONS")
 	controllerRouter.Handle("", http.HandlerFunc(han.UpdateControllerHandler)).Methods("PUT", "OPTIONS")
+	// Change the controller ID
+	controllerRouter.Handle("/id-migration/", http.HandlerFunc(han.StartControllerIDMigrationHandler)).Methods("POST", "OPTIONS")
+	controllerRouter.Handle("/id-migration", http.HandlerFunc(han.StartControllerIDMigrationHandler)).Methods("POST", "OPTIONS")
+	// Get the controller ID migration
+	controllerRouter.Handle("/id-migration/", http.HandlerFunc(han.GetControllerIDMigrationHandler)).Methods("GET", "OPTIONS")
+	controllerRouter.Handle("/id-migration", http.HandlerFunc(han.GetControllerIDMigrationHandler)).Methods("GET", "OPTIONS")
+	// List controller nodes
+	controllerRouter.Handle("/nodes/", http.HandlerFunc(han.ListControllerNodesHandler)).Methods("GET", "OPTIONS")
+	controllerRouter.Handle("/nodes", http.HandlerFunc(han.ListControllerNodesHandler)).Methods("GET", "OPTIONS")
 
 	////////////////////////////////////
 	// API router for everything else //
@@ -178,7 +237,14 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// if the required metadata, callback and webhook URLs are not set.
 	apiRouter.Use(urlsRequiredMiddleware.Middleware)
 	apiRouter.Use(authMiddleware.Middleware)
-	apiRouter.Use(auth.AdminRequiredMiddleware)
+	// requests made with API tokens are limited to the scopes of the token.
+	apiRouter.Use(auth.APITokenScopeMiddleware)
+	// users that are not admins are let through if they have a role. What they
+	// are allowed to do with their role is checked by the runner.
+	apiRouter.Use(auth.RoleRequiredMiddleware)
+	// idempotency keys are scoped to the user making the request, so this middleware
+	// must run after the auth middleware.
+	apiRouter.Use(idempotencyMiddleware.Middleware)
 
 	// Legacy controller path
 	apiRouter.Handle("/controller-info/", http.HandlerFunc(han.ControllerInfoHandler)).Methods("GET", "OPTIONS")
@@ -188,12 +254,94 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/metrics-token/", http.HandlerFunc(han.MetricsTokenHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/metrics-token", http.HandlerFunc(han.MetricsTokenHandler)).Methods("GET", "OPTIONS")
 
+	///////////
+	// Users //
+	///////////
+	// Create user
+	apiRouter.Handle("/users/", http.HandlerFunc(han.CreateUserHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/users", http.HandlerFunc(han.CreateUserHandler)).Methods("POST", "OPTIONS")
+	// Get user
+	apiRouter.Handle("/users/{username}/", http.HandlerFunc(han.GetUserHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/users/{username}", http.HandlerFunc(han.GetUserHandler)).Methods("GET", "OPTIONS")
+	// Unlock user
+	apiRouter.Handle("/users/{username}/unlock/", http.HandlerFunc(han.UnlockUserHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/users/{username}/unlock", http.HandlerFunc(han.UnlockUserHandler)).Methods("POST", "OPTIONS")
+	// Give a user a role on an entity
+	apiRouter.Handle("/users/{username}/grants/", http.HandlerFunc(han.CreateRoleGrantHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/users/{username}/grants", http.HandlerFunc(han.CreateRoleGrantHandler)).Methods("POST", "OPTIONS")
+	// Remove a role grant of a user
+	apiRouter.Handle("/users/{username}/grants/{grantID}/", http.HandlerFunc(han.DeleteRoleGrantHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/users/{username}/grants/{grantID}", http.HandlerFunc(han.DeleteRoleGrantHandler)).Methods("DELETE", "OPTIONS")
+
+	////////////////
+	// API tokens //
+	////////////////
+	// List API tokens
+	apiRouter.Handle("/tokens/", http.HandlerFunc(han.ListAPITokensHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/tokens", http.HandlerFunc(han.ListAPITokensHandler)).Methods("GET", "OPTIONS")
+	// Create API token. The response holds the token, so it is never recorded for idempotent retries.
+	apiRouter.Handle("/tokens/", auth.OmitIdempotentResponse(http.HandlerFunc(han.CreateAPITokenHandler))).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/tokens", auth.OmitIdempotentResponse(http.HandlerFunc(han.CreateAPITokenHandler))).Methods("POST", "OPTIONS")
+	// Revoke API token
+	apiRouter.Handle("/tokens/{tokenID}/", http.HandlerFunc(han.RevokeAPITokenHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/tokens/{tokenID}", http.HandlerFunc(han.RevokeAPITokenHandler)).Methods("DELETE", "OPTIONS")
+
+	///////////////////
+	// Impersonation //
+	///////////////////
+	// Get a token that impersonates a user. The response holds the token, so it is never recorded
+	// for idempotent retries.
+	apiRouter.Handle("/users/{username}/impersonate/", auth.OmitIdempotentResponse(http.HandlerFunc(han.ImpersonateUserHandler))).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/users/{username}/impersonate", auth.OmitIdempotentResponse(http.HandlerFunc(han.ImpersonateUserHandler))).Methods("POST", "OPTIONS")
+	// List audit records
+	apiRouter.Handle("/audit/", http.HandlerFunc(han.ListAuditRecordsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/audit", http.HandlerFunc(han.ListAuditRecordsHandler)).Methods("GET", "OPTIONS")
+
+	////////////////////////
+	// Webhook deliveries //
+	////////////////////////
+	// List webhook deliveries
+	apiRouter.Handle("/webhook-deliveries/", http.HandlerFunc(han.ListWebhookDeliveriesHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/webhook-deliveries", http.HandlerFunc(han.ListWebhookDeliveriesHandler)).Methods("GET", "OPTIONS")
+	// Process a failed webhook delivery again
+	apiRouter.Handle("/webhook-deliveries/{deliveryID}/redeliver/", http.HandlerFunc(han.RedeliverWebhookHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/webhook-deliveries/{deliveryID}/redeliver", http.HandlerFunc(han.RedeliverWebhookHandler)).Methods("POST", "OPTIONS")
+
+	////////////////
+	// Deny rules //
+	////////////////
+	// List deny rules
+	apiRouter.Handle("/deny-rules/", http.HandlerFunc(han.ListDenyRulesHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/deny-rules", http.HandlerFunc(han.ListDenyRulesHandler)).Methods("GET", "OPTIONS")
+	// Create deny rule
+	apiRouter.Handle("/deny-rules/", http.HandlerFunc(han.CreateDenyRuleHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/deny-rules", http.HandlerFunc(han.CreateDenyRuleHandler)).Methods("POST", "OPTIONS")
+	// Delete deny rule
+	apiRouter.Handle("/deny-rules/{ruleID}/", http.HandlerFunc(han.DeleteDenyRuleHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/deny-rules/{ruleID}", http.HandlerFunc(han.DeleteDenyRuleHandler)).Methods("DELETE", "OPTIONS")
+
+	///////////////////////////
+	// Capacity reservations //
+	///////////////////////////
+	// List capacity reservations
+	apiRouter.Handle("/capacity-reservations/", http.HandlerFunc(han.ListCapacityReservationsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/capacity-reservations", http.HandlerFunc(han.ListCapacityReservationsHandler)).Methods("GET", "OPTIONS")
+	// Create capacity reservation
+	apiRouter.Handle("/capacity-reservations/", http.HandlerFunc(han.CreateCapacityReservationHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/capacity-reservations", http.HandlerFunc(han.CreateCapacityReservationHandler)).Methods("POST", "OPTIONS")
+	// Delete capacity reservation
+	apiRouter.Handle("/capacity-reservations/{reservationID}/", http.HandlerFunc(han.DeleteCapacityReservationHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/capacity-reservations/{reservationID}", http.HandlerFunc(han.DeleteCapacityReservationHandler)).Methods("DELETE", "OPTIONS")
+
 	//////////
 	// Jobs //
 	//////////
 	// List all jobs
 	apiRouter.Handle("/jobs/", http.HandlerFunc(han.ListAllJobs)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/jobs", http.HandlerFunc(han.ListAllJobs)).Methods("GET", "OPTIONS")
+	// Set job metadata
+	apiRouter.Handle("/jobs/{jobID}/", http.HandlerFunc(han.UpdateJobMetadataHandler)).Methods("PATCH", "OPTIONS")
+	apiRouter.Handle("/jobs/{jobID}", http.HandlerFunc(han.UpdateJobMetadataHandler)).Methods("PATCH", "OPTIONS")
 
 	///////////
 	// Pools //
@@ -201,6 +349,9 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// List all pools
 	apiRouter.Handle("/pools/", http.HandlerFunc(han.ListAllPoolsHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/pools", http.HandlerFunc(han.ListAllPoolsHandler)).Methods("GET", "OPTIONS")
+	// Add, remove or rename a tag across all pools
+	apiRouter.Handle("/pools/tags/", http.HandlerFunc(han.BulkUpdatePoolTagsHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/pools/tags", http.HandlerFunc(han.BulkUpdatePoolTagsHandler)).Methods("POST", "OPTIONS")
 	// Get one pool
 	apiRouter.Handle("/pools/{poolID}/", http.HandlerFunc(han.GetPoolByIDHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/pools/{poolID}", http.HandlerFunc(han.GetPoolByIDHandler)).Methods("GET", "OPTIONS")
@@ -217,6 +368,12 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	/////////////
 	// Runners //
 	/////////////
+	// Get instance provider logs
+	apiRouter.Handle("/instances/{instanceName}/provider-logs/", http.HandlerFunc(han.GetInstanceProviderLogsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/instances/{instanceName}/provider-logs", http.HandlerFunc(han.GetInstanceProviderLogsHandler)).Methods("GET", "OPTIONS")
+	// List instance status messages
+	apiRouter.Handle("/instances/{instanceName}/status-messages/", http.HandlerFunc(han.ListInstanceStatusMessagesHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/instances/{instanceName}/status-messages", http.HandlerFunc(han.ListInstanceStatusMessagesHandler)).Methods("GET", "OPTIONS")
 	// Get instance
 	apiRouter.Handle("/instances/{instanceName}/", http.HandlerFunc(han.GetInstanceHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/instances/{instanceName}", http.HandlerFunc(han.GetInstanceHandler)).Methods("GET", "OPTIONS")
@@ -226,6 +383,60 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// List runners
 	apiRouter.Handle("/instances/", http.HandlerFunc(han.ListAllInstancesHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/instances", http.HandlerFunc(han.ListAllInstancesHandler)).Methods("GET", "OPTIONS")
+	// Delete runners in bulk
+	apiRouter.Handle("/instances/", http.HandlerFunc(han.BulkDeleteInstancesHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/instances", http.HandlerFunc(han.BulkDeleteInstancesHandler)).Methods("DELETE", "OPTIONS")
+
+	///////////////
+	// Analytics //
+	///////////////
+	// Instance failures
+	apiRouter.Handle("/analytics/instance-failures/", http.HandlerFunc(han.InstanceFailureAnalyticsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/analytics/instance-failures", http.HandlerFunc(han.InstanceFailureAnalyticsHandler)).Methods("GET", "OPTIONS")
+
+	///////////
+	// Usage //
+	///////////
+	apiRouter.Handle("/usage/", http.HandlerFunc(han.GetUsageReportHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/usage", http.HandlerFunc(han.GetUsageReportHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/usage/efficiency/", http.HandlerFunc(han.GetEfficiencyReportHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/usage/efficiency", http.HandlerFunc(han.GetEfficiencyReportHandler)).Methods("GET", "OPTIONS")
+
+	////////////
+	// Schema //
+	////////////
+	// Get pool schema
+	apiRouter.Handle("/schema/pool/", http.HandlerFunc(han.GetPoolSchemaHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/schema/pool", http.HandlerFunc(han.GetPoolSchemaHandler)).Methods("GET", "OPTIONS")
+
+	///////////
+	// Locks //
+	///////////
+	// List instance locks
+	apiRouter.Handle("/locks/", http.HandlerFunc(han.ListInstanceLocksHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/locks", http.HandlerFunc(han.ListInstanceLocksHandler)).Methods("GET", "OPTIONS")
+	// Release instance lock
+	apiRouter.Handle("/locks/{instanceName}/", http.HandlerFunc(han.ReleaseInstanceLockHandler)).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/locks/{instanceName}", http.HandlerFunc(han.ReleaseInstanceLockHandler)).Methods("DELETE", "OPTIONS")
+
+	/////////////
+	// Workers //
+	/////////////
+	// List the worker loops of all pool managers
+	apiRouter.Handle("/workers/", http.HandlerFunc(han.ListWorkersHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/workers", http.HandlerFunc(han.ListWorkersHandler)).Methods("GET", "OPTIONS")
+
+	////////////////////
+	// Entity configs //
+	////////////////////
+	apiRouter.Handle("/entities/import/", http.HandlerFunc(han.ImportEntityHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/entities/import", http.HandlerFunc(han.ImportEntityHandler)).Methods("POST", "OPTIONS")
+	// Export controller config
+	apiRouter.Handle("/config/", http.HandlerFunc(han.ExportControllerConfigHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/config", http.HandlerFunc(han.ExportControllerConfigHandler)).Methods("GET", "OPTIONS")
+	// Apply controller config
+	apiRouter.Handle("/config/apply/", http.HandlerFunc(han.ApplyControllerConfigHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/config/apply", http.HandlerFunc(han.ApplyControllerConfigHandler)).Methods("POST", "OPTIONS")
 
 	/////////////////////
 	// Repos and pools //
@@ -246,10 +457,21 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/repositories/{repoID}/pools/", http.HandlerFunc(han.CreateRepoPoolHandler)).Methods("POST", "OPTIONS")
 	apiRouter.Handle("/repositories/{repoID}/pools", http.HandlerFunc(han.CreateRepoPoolHandler)).Methods("POST", "OPTIONS")
 
+	// Export config
+	apiRouter.Handle("/repositories/{repoID}/config/", http.HandlerFunc(han.ExportRepoConfigHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/repositories/{repoID}/config", http.HandlerFunc(han.ExportRepoConfigHandler)).Methods("GET", "OPTIONS")
+	// Import config
+	apiRouter.Handle("/repositories/{repoID}/config/", http.HandlerFunc(han.ImportRepoConfigHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/repositories/{repoID}/config", http.HandlerFunc(han.ImportRepoConfigHandler)).Methods("POST", "OPTIONS")
+
 	// Repo instances list
 	apiRouter.Handle("/repositories/{repoID}/instances/", http.HandlerFunc(han.ListRepoInstancesHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/repositories/{repoID}/instances", http.HandlerFunc(han.ListRepoInstancesHandler)).Methods("GET", "OPTIONS")
 
+	// Sync repo jobs
+	apiRouter.Handle("/repositories/{repoID}/jobs/sync/", http.HandlerFunc(han.SyncRepoJobsHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/repositories/{repoID}/jobs/sync", http.HandlerFunc(han.SyncRepoJobsHandler)).Methods("POST", "OPTIONS")
+
 	// Get repo
 	apiRouter.Handle("/repositories/{repoID}/", http.HandlerFunc(han.GetRepoByIDHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/repositories/{repoID}", http.HandlerFunc(han.GetRepoByIDHandler)).Methods("GET", "OPTIONS")
@@ -296,6 +518,13 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/organizations/{orgID}/pools/", http.HandlerFunc(han.CreateOrgPoolHandler)).Methods("POST", "OPTIONS")
 	apiRouter.Handle("/organizations/{orgID}/pools", http.HandlerFunc(han.CreateOrgPoolHandler)).Methods("POST", "OPTIONS")
 
+	// Export config
+	apiRouter.Handle("/organizations/{orgID}/config/", http.HandlerFunc(han.ExportOrgConfigHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/organizations/{orgID}/config", http.HandlerFunc(han.ExportOrgConfigHandler)).Methods("GET", "OPTIONS")
+	// Import config
+	apiRouter.Handle("/organizations/{orgID}/config/", http.HandlerFunc(han.ImportOrgConfigHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/organizations/{orgID}/config", http.HandlerFunc(han.ImportOrgConfigHandler)).Methods("POST", "OPTIONS")
+
 	// Org instances list
 	apiRouter.Handle("/organizations/{orgID}/instances/", http.HandlerFunc(han.ListOrgInstancesHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/organizations/{orgID}/instances", http.HandlerFunc(han.ListOrgInstancesHandler)).Methods("GET", "OPTIONS")
@@ -346,10 +575,21 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/enterprises/{enterpriseID}/pools/", http.HandlerFunc(han.CreateEnterprisePoolHandler)).Methods("POST", "OPTIONS")
 	apiRouter.Handle("/enterprises/{enterpriseID}/pools", http.HandlerFunc(han.CreateEnterprisePoolHandler)).Methods("POST", "OPTIONS")
 
+	// Export config
+	apiRouter.Handle("/enterprises/{enterpriseID}/config/", http.HandlerFunc(han.ExportEnterpriseConfigHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/enterprises/{enterpriseID}/config", http.HandlerFunc(han.ExportEnterpriseConfigHandler)).Methods("GET", "OPTIONS")
+	// Import config
+	apiRouter.Handle("/enterprises/{enterpriseID}/config/", http.HandlerFunc(han.ImportEnterpriseConfigHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/enterprises/{enterpriseID}/config", http.HandlerFunc(han.ImportEnterpriseConfigHandler)).Methods("POST", "OPTIONS")
+
 	// Enterprise instances list
 	apiRouter.Handle("/enterprises/{enterpriseID}/instances/", http.HandlerFunc(han.ListEnterpriseInstancesHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/enterprises/{enterpriseID}/instances", http.HandlerFunc(han.ListEnterpriseInstancesHandler)).Methods("GET", "OPTIONS")
 
+	// Runner distribution
+	apiRouter.Handle("/enterprises/{enterpriseID}/runner-distribution/", http.HandlerFunc(han.GetEnterpriseRunnerDistributionHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/enterprises/{enterpriseID}/runner-distribution", http.HandlerFunc(han.GetEnterpriseRunnerDistributionHandler)).Methods("GET", "OPTIONS")
+
 	// Get enterprise
 	apiRouter.Handle("/enterprises/{enterpriseID}/", http.HandlerFunc(han.GetEnterpriseByIDHandler)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/enterprises/{enterpriseID}", http.HandlerFunc(han.GetEnterpriseByIDHandler)).Methods("GET", "OPTIONS")
@@ -366,9 +606,46 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/enterprises/", http.HandlerFunc(han.CreateEnterpriseHandler)).Methods("POST", "OPTIONS")
 	apiRouter.Handle("/enterprises", http.HandlerFunc(han.CreateEnterpriseHandler)).Methods("POST", "OPTIONS")
 
+	//////////////
+	// Entities //
+	//////////////
+	// List the runners GitHub sees for a repository, organization or enterprise
+	apiRouter.Handle("/entities/{entityID}/forge-runners/", http.HandlerFunc(han.ListEntityForgeRunnersHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/forge-runners", http.HandlerFunc(han.ListEntityForgeRunnersHandler)).Methods("GET", "OPTIONS")
+	// Preview the pools that would be used for a set of job labels
+	apiRouter.Handle("/entities/{entityID}/pools/match/", http.HandlerFunc(han.MatchEntityPoolsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/pools/match", http.HandlerFunc(han.MatchEntityPoolsHandler)).Methods("GET", "OPTIONS")
+	// Add, remove or rename a tag across the pools of a repository, organization or enterprise
+	apiRouter.Handle("/entities/{entityID}/pools/tags/", http.HandlerFunc(han.BulkUpdateEntityPoolTagsHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/pools/tags", http.HandlerFunc(han.BulkUpdateEntityPoolTagsHandler)).Methods("POST", "OPTIONS")
+	// List, create and look up the GitHub runner groups of an organization or enterprise
+	apiRouter.Handle("/entities/{entityID}/runner-groups/", http.HandlerFunc(han.ListEntityRunnerGroupsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/runner-groups", http.HandlerFunc(han.ListEntityRunnerGroupsHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/runner-groups/", http.HandlerFunc(han.CreateEntityRunnerGroupHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/runner-groups", http.HandlerFunc(han.CreateEntityRunnerGroupHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/runner-groups/{groupName}/", http.HandlerFunc(han.GetEntityRunnerGroupHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/entities/{entityID}/runner-groups/{groupName}", http.HandlerFunc(han.GetEntityRunnerGroupHandler)).Methods("GET", "OPTIONS")
+
 	// Providers
 	apiRouter.Handle("/providers/", http.HandlerFunc(han.ListProviders)).Methods("GET", "OPTIONS")
 	apiRouter.Handle("/providers", http.HandlerFunc(han.ListProviders)).Methods("GET", "OPTIONS")
+	// Pause provider
+	apiRouter.Handle("/providers/{providerName}/pause/", http.HandlerFunc(han.PauseProviderHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/pause", http.HandlerFunc(han.PauseProviderHandler)).Methods("POST", "OPTIONS")
+	// Resume provider
+	apiRouter.Handle("/providers/{providerName}/resume/", http.HandlerFunc(han.ResumeProviderHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/resume", http.HandlerFunc(han.ResumeProviderHandler)).Methods("POST", "OPTIONS")
+	// Get provider environment
+	apiRouter.Handle("/providers/{providerName}/environment/", http.HandlerFunc(han.GetProviderEnvironmentHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/environment", http.HandlerFunc(han.GetProviderEnvironmentHandler)).Methods("GET", "OPTIONS")
+	// Update provider environment
+	apiRouter.Handle("/providers/{providerName}/environment/", http.HandlerFunc(han.UpdateProviderEnvironmentHandler)).Methods("PUT", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/environment", http.HandlerFunc(han.UpdateProviderEnvironmentHandler)).Methods("PUT", "OPTIONS")
+	// Orphaned instances
+	apiRouter.Handle("/providers/{providerName}/orphans/", http.HandlerFunc(han.ListOrphanedInstancesHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/orphans", http.HandlerFunc(han.ListOrphanedInstancesHandler)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/orphans/cleanup/", http.HandlerFunc(han.CleanupOrphanedInstancesHandler)).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/providers/{providerName}/orphans/cleanup", http.HandlerFunc(han.CleanupOrphanedInstancesHandler)).Methods("POST", "OPTIONS")
 
 	//////////////////////
 	// Github Endpoints //
@@ -410,6 +687,9 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	// Update Github Credential
 	apiRouter.Handle("/github/credentials/{id}/", http.HandlerFunc(han.UpdateGithubCredential)).Methods("PUT", "OPTIONS")
 	apiRouter.Handle("/github/credentials/{id}", http.HandlerFunc(han.UpdateGithubCredential)).Methods("PUT", "OPTIONS")
+	// List rate limit forecasts
+	apiRouter.Handle("/github/rate-limits/", http.HandlerFunc(han.ListRateLimitForecasts)).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/github/rate-limits", http.HandlerFunc(han.ListRateLimitForecasts)).Methods("GET", "OPTIONS")
 
 	/////////////////////////
 	// Websocket endpoints //
@@ -425,6 +705,5 @@ func NewAPIRouter(han *controllers.APIController, authMiddleware, initMiddleware
 	apiRouter.Handle("/ws/events", http.HandlerFunc(han.EventsHandler)).Methods("GET")
 
 	// NotFound handler
-	apiRouter.PathPrefix("/").HandlerFunc(han.NotFoundHandler).Methods("GET", "POST", "PUT", "DELETE", "OPTIONS")
-	return router
+	apiRouter.PathPrefix("/").HandlerFunc(han.NotFoundHandler).Methods("GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS")
 }
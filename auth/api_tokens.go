@@ -0,0 +1,228 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm-provider-common/util"
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	// apiTokenLength is the number of random characters in an API token.
+	apiTokenLength = 40
+	// apiTokenLastUsedResolution is how often the last use of an API token is saved.
+	apiTokenLastUsedResolution = time.Minute
+)
+
+// apiTokenResources maps the resources in the API paths to the resources of the
+// API token scopes.
+var apiTokenResources = map[string]string{
+	"repositories":  "entities",
+	"organizations": "entities",
+	"enterprises":   "entities",
+	"pools":         "pools",
+	"instances":     "instances",
+	"jobs":          "jobs",
+}
+
+// SetAPIToken marks the context as authenticated with an API token.
+func SetAPIToken(ctx context.Context, token params.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenKey, token)
+}
+
+// GetAPIToken returns the API token the context was authenticated with, and a
+// boolean indicating whether or not an API token was used.
+func GetAPIToken(ctx context.Context) (params.APIToken, bool) {
+	elem := ctx.Value(apiTokenKey)
+	if elem == nil {
+		return params.APIToken{}, false
+	}
+	return elem.(params.APIToken), true
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// requiredAPITokenScope returns the scope an API token needs to make the request. The
+// scope is given by the last resource in the path of the route, and by the method of
+// the request. Routes that don't match a resource can't be used with API tokens.
+func requiredAPITokenScope(r *http.Request) (params.APITokenScope, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+
+	var resource string
+	for _, segment := range strings.Split(template, "/") {
+		if res, ok := apiTokenResources[segment]; ok {
+			resource = res
+		}
+	}
+	if resource == "" {
+		return "", false
+	}
+
+	access := "write"
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		access = "read"
+	}
+	return params.APITokenScope(fmt.Sprintf("%s:%s", resource, access)), true
+}
+
+// APITokenScopeMiddleware rejects requests made with an API token that does not have
+// the scope needed for the request. Requests authenticated with a JWT token are not
+// affected.
+func APITokenScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := GetAPIToken(r.Context())
+		if ok {
+			scope, found := requiredAPITokenScope(r)
+			if !found || !token.AllowsScope(scope) {
+				http.Error(w, "API token does not have the required scope", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiTokenToContext authenticates a request made with an API token. The request is
+// made on behalf of the user that created the token.
+func (amw *jwtMiddleware) apiTokenToContext(ctx context.Context, secret string) (context.Context, error) {
+	token, err := amw.store.GetAPITokenByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+	now := time.Now().UTC()
+	if token.IsExpired(now) {
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+
+	user, err := amw.store.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+	ctx = PopulateContext(ctx, user, token.ExpiresAt)
+	ctx = SetAPIToken(ctx, token)
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedResolution {
+		if err := amw.store.SetAPITokenLastUsed(ctx, token.ID, now); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to update API token", "token_id", token.ID)
+		}
+	}
+	return ctx, nil
+}
+
+// recordAPITokenAudit records an action taken on an API token.
+func (a *Authenticator) recordAPITokenAudit(ctx context.Context, action params.AuditAction, reason string) {
+	record := params.AuditRecord{
+		Action:   action,
+		UserID:   UserID(ctx),
+		Username: Username(ctx),
+		Reason:   reason,
+	}
+	if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
+
+// CreateAPIToken creates an API token that acts on behalf of the user in the context.
+// The secret token is only returned by this function.
+func (a *Authenticator) CreateAPIToken(ctx context.Context, param params.CreateAPITokenParams) (params.APIToken, error) {
+	if UserID(ctx) == "" {
+		return params.APIToken{}, runnerErrors.ErrUnauthorized
+	}
+	if _, ok := GetAPIToken(ctx); ok {
+		return params.APIToken{}, runnerErrors.NewBadRequestError("API tokens can not be used to create API tokens")
+	}
+	if _, ok := GetImpersonation(ctx); ok {
+		return params.APIToken{}, runnerErrors.NewBadRequestError("cannot create API tokens while impersonating a user")
+	}
+	if err := param.Validate(); err != nil {
+		return params.APIToken{}, err
+	}
+
+	random, err := util.GetRandomString(apiTokenLength)
+	if err != nil {
+		return params.APIToken{}, errors.Wrap(err, "generating random string")
+	}
+	secret := params.APITokenPrefix + random
+
+	token, err := a.store.CreateAPIToken(ctx, UserID(ctx), param, hashAPIToken(secret))
+	if err != nil {
+		return params.APIToken{}, errors.Wrap(err, "creating API token")
+	}
+	a.recordAPITokenAudit(ctx, params.AuditActionAPITokenCreated, fmt.Sprintf(
+		"created API token %s (%s) with scopes %v", token.Name, token.ID, token.Scopes))
+
+	token.Token = secret
+	return token, nil
+}
+
+// ListAPITokens returns the API tokens of the user in the context. The admin gets the
+// tokens of all users.
+func (a *Authenticator) ListAPITokens(ctx context.Context) ([]params.APIToken, error) {
+	if UserID(ctx) == "" {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+	userID := UserID(ctx)
+	if IsAdmin(ctx) {
+		userID = ""
+	}
+
+	tokens, err := a.store.ListAPITokens(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing API tokens")
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes an API token of the user in the context. The admin can revoke
+// the tokens of all users.
+func (a *Authenticator) RevokeAPIToken(ctx context.Context, tokenID string) error {
+	if UserID(ctx) == "" {
+		return runnerErrors.ErrUnauthorized
+	}
+	userID := UserID(ctx)
+	if IsAdmin(ctx) {
+		userID = ""
+	}
+
+	if err := a.store.DeleteAPIToken(ctx, userID, tokenID); err != nil {
+		return errors.Wrap(err, "revoking API token")
+	}
+	a.recordAPITokenAudit(ctx, params.AuditActionAPITokenRevoked, fmt.Sprintf("revoked API token %s", tokenID))
+	return nil
+}
@@ -16,6 +16,7 @@ package auth
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
@@ -32,14 +33,16 @@ import (
 
 func NewAuthenticator(cfg config.JWTAuth, store common.Store) *Authenticator {
 	return &Authenticator{
-		cfg:   cfg,
-		store: store,
+		cfg:     cfg,
+		store:   store,
+		limiter: newLoginLimiter(cfg.LoginAttemptsPerMinute()),
 	}
 }
 
 type Authenticator struct {
-	store common.Store
-	cfg   config.JWTAuth
+	store   common.Store
+	cfg     config.JWTAuth
+	limiter *loginLimiter
 }
 
 func (a *Authenticator) IsInitialized() bool {
@@ -117,6 +120,87 @@ func (a *Authenticator) GetJWTMetricsToken(ctx context.Context) (string, error)
 	return tokenString, nil
 }
 
+const (
+	defaultImpersonationTTL = 15 * time.Minute
+	maxImpersonationTTL     = time.Hour
+)
+
+// GetImpersonationToken returns a short lived JWT token that allows the admin to act on
+// behalf of another user. The token is only valid while impersonation is enabled in the
+// config. Issuing the token, and every request made with it, is recorded in the audit log.
+func (a *Authenticator) GetImpersonationToken(ctx context.Context, username string, param params.ImpersonateUserParams) (string, error) {
+	if !IsAdmin(ctx) {
+		return "", runnerErrors.ErrUnauthorized
+	}
+	if !a.cfg.AllowImpersonation {
+		return "", runnerErrors.NewBadRequestError("impersonation is disabled")
+	}
+	if _, ok := GetImpersonation(ctx); ok {
+		return "", runnerErrors.NewBadRequestError("cannot impersonate a user while impersonating another user")
+	}
+	if err := param.Validate(); err != nil {
+		return "", errors.Wrap(err, "validating params")
+	}
+
+	user, err := a.store.GetUser(ctx, username)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching user")
+	}
+	if user.ID == UserID(ctx) {
+		return "", runnerErrors.NewBadRequestError("cannot impersonate yourself")
+	}
+	if !user.Enabled {
+		return "", runnerErrors.NewBadRequestError("user is disabled")
+	}
+
+	ttl := defaultImpersonationTTL
+	if param.TTLMinutes > 0 {
+		ttl = time.Duration(param.TTLMinutes) * time.Minute
+	}
+	if ttl > maxImpersonationTTL {
+		ttl = maxImpersonationTTL
+	}
+
+	tokenID, err := util.GetRandomString(16)
+	if err != nil {
+		return "", errors.Wrap(err, "generating random string")
+	}
+	claims := JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			Issuer:    "garm",
+		},
+		UserID:              user.ID,
+		TokenID:             tokenID,
+		IsAdmin:             user.IsAdmin,
+		FullName:            user.FullName,
+		Generation:          user.Generation,
+		ImpersonatedBy:      UserID(ctx),
+		ImpersonationReason: param.Reason,
+	}
+
+	record := params.AuditRecord{
+		Action:         params.AuditActionImpersonationStarted,
+		ImpersonatorID: UserID(ctx),
+		Impersonator:   Username(ctx),
+		UserID:         user.ID,
+		Username:       user.Username,
+		Reason:         param.Reason,
+	}
+	// Refuse to issue the token if we can't record that it was issued.
+	if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+		return "", errors.Wrap(err, "creating audit record")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(a.cfg.Secret))
+	if err != nil {
+		return "", errors.Wrap(err, "fetching token string")
+	}
+
+	return tokenString, nil
+}
+
 func (a *Authenticator) InitController(ctx context.Context, param params.NewUserParams) (params.User, error) {
 	_, err := a.store.ControllerInfo()
 	if err != nil {
@@ -128,6 +212,13 @@ func (a *Authenticator) InitController(ctx context.Context, param params.NewUser
 		return params.User{}, runnerErrors.ErrNotFound
 	}
 
+	param.IsAdmin = true
+	param.Enabled = true
+
+	return a.createUser(ctx, param)
+}
+
+func (a *Authenticator) createUser(ctx context.Context, param params.NewUserParams) (params.User, error) {
 	if param.Email == "" || param.Username == "" {
 		return params.User{}, runnerErrors.NewBadRequestError("missing username or email")
 	}
@@ -141,9 +232,6 @@ func (a *Authenticator) InitController(ctx context.Context, param params.NewUser
 		return params.User{}, runnerErrors.NewBadRequestError("invalid username")
 	}
 
-	param.IsAdmin = true
-	param.Enabled = true
-
 	passwordStenght := zxcvbn.PasswordStrength(param.Password, nil)
 	if passwordStenght.Score < 4 {
 		return params.User{}, runnerErrors.NewBadRequestError("password is too weak")
@@ -159,11 +247,19 @@ func (a *Authenticator) InitController(ctx context.Context, param params.NewUser
 	return a.store.CreateUser(ctx, param)
 }
 
-func (a *Authenticator) AuthenticateUser(ctx context.Context, info params.PasswordLoginParams) (context.Context, error) {
+// AuthenticateUser checks the credentials of a user. Login attempts are rate limited for
+// each username and source address pair and for each source address, unless login
+// protection is disabled. If lockouts are enabled, users are also locked out after
+// repeated failed logins.
+func (a *Authenticator) AuthenticateUser(ctx context.Context, info params.PasswordLoginParams, source string) (context.Context, error) {
 	if info.Username == "" || info.Password == "" {
 		return ctx, runnerErrors.ErrUnauthorized
 	}
 
+	if err := a.checkLoginRate(ctx, info.Username, source); err != nil {
+		return ctx, err
+	}
+
 	user, err := a.store.GetUser(ctx, info.Username)
 	if err != nil {
 		if errors.Is(err, runnerErrors.ErrNotFound) {
@@ -180,9 +276,21 @@ func (a *Authenticator) AuthenticateUser(ctx context.Context, info params.Passwo
 		return ctx, runnerErrors.ErrUnauthorized
 	}
 
+	if a.cfg.LockoutEnabled() && user.IsLockedOut(time.Now().UTC()) {
+		slog.WarnContext(ctx, "refused login of locked out user", "username", user.Username, "locked_until", user.LockedUntil)
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(info.Password)); err != nil {
+		a.recordFailedLogin(ctx, user)
 		return ctx, runnerErrors.ErrUnauthorized
 	}
 
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if _, err := a.store.ResetFailedLogins(ctx, user.Username); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to reset failed logins", "username", user.Username)
+		}
+	}
+
 	return PopulateContext(ctx, user, nil), nil
 }
@@ -34,6 +34,11 @@ const (
 	jwtTokenFlag           contextFlags = "jwt_token"
 	authExpiresFlag        contextFlags = "auth_expires"
 	passwordGenerationFlag contextFlags = "password_generation"
+	usernameKey            contextFlags = "username"
+	impersonatorKey        contextFlags = "impersonator"
+	roleKey                contextFlags = "role"
+	roleGrantsKey          contextFlags = "role_grants"
+	apiTokenKey            contextFlags = "api_token"
 
 	instanceIDKey        contextFlags = "id"
 	instanceNameKey      contextFlags = "name"
@@ -177,8 +182,11 @@ func PopulateContext(ctx context.Context, user params.User, authExpires *time.Ti
 	ctx = SetAdmin(ctx, user.IsAdmin)
 	ctx = SetIsEnabled(ctx, user.Enabled)
 	ctx = SetFullName(ctx, user.FullName)
+	ctx = SetUsername(ctx, user.Username)
 	ctx = SetExpires(ctx, authExpires)
 	ctx = SetPasswordGeneration(ctx, user.Generation)
+	ctx = SetRole(ctx, user.Role)
+	ctx = SetRoleGrants(ctx, user.Grants)
 	return ctx
 }
 
@@ -209,6 +217,44 @@ func PasswordGeneration(ctx context.Context) uint {
 	return elem.(uint)
 }
 
+// Impersonation holds information about the admin that is impersonating
+// the user in the context.
+type Impersonation struct {
+	ImpersonatorID string
+	Impersonator   string
+	Reason         string
+}
+
+// SetImpersonation marks the context as belonging to a user that is being
+// impersonated by an admin.
+func SetImpersonation(ctx context.Context, impersonation Impersonation) context.Context {
+	return context.WithValue(ctx, impersonatorKey, impersonation)
+}
+
+// GetImpersonation returns the impersonation details from the context and
+// a boolean indicating whether or not the user is being impersonated.
+func GetImpersonation(ctx context.Context) (Impersonation, bool) {
+	elem := ctx.Value(impersonatorKey)
+	if elem == nil {
+		return Impersonation{}, false
+	}
+	return elem.(Impersonation), true
+}
+
+// SetUsername sets the username in the context
+func SetUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// Username returns the username from context
+func Username(ctx context.Context) string {
+	name := ctx.Value(usernameKey)
+	if name == nil {
+		return ""
+	}
+	return name.(string)
+}
+
 // SetFullName sets the user full name in the context
 func SetFullName(ctx context.Context, fullName string) context.Context {
 	return context.WithValue(ctx, fullNameKey, fullName)
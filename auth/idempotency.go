@@ -0,0 +1,251 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	apiParams "github.com/cloudbase/garm/apiserver/params"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const (
+	// IdempotencyKeyHeader is the header clients set to make a request idempotent.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses that were replayed from a
+	// previous request with the same idempotency key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// pendingIdempotencyRecordTTL is how long the key of a request that is being handled
+	// stays reserved. It only matters if the controller stops while handling the request.
+	pendingIdempotencyRecordTTL = 10 * time.Minute
+)
+
+// NewIdempotencyMiddleware returns a middleware that makes mutating requests which
+// carry an Idempotency-Key header safe to retry. The response of the first request
+// made with a key is recorded, and returned to any retry of that request that uses
+// the same key, without running the request again. The key is reserved in the database
+// while the request is handled, so retries that reach other controllers are rejected.
+func NewIdempotencyMiddleware(store common.Store) (Middleware, error) {
+	return &idempotencyMiddleware{
+		store: store,
+	}, nil
+}
+
+type idempotencyMiddleware struct {
+	store common.Store
+}
+
+func idempotencyErrorResponse(ctx context.Context, w http.ResponseWriter, statusCode int, details string) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(
+		apiParams.APIErrorResponse{
+			Error:   "Idempotency key error",
+			Details: details,
+		}); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to encode response")
+	}
+}
+
+type omitIdempotentResponseKey struct{}
+
+// OmitIdempotentResponse marks the responses of a handler as holding secrets, like API
+// tokens. When such a request is made with an idempotency key, its response is not
+// recorded, and retries are rejected with a 409 Conflict instead of being replayed.
+func OmitIdempotentResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if omit, ok := r.Context().Value(omitIdempotentResponseKey{}).(*bool); ok {
+			*omit = true
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hashIdempotentRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	// Query parameters change the meaning of a request (dry_run, keepWebhook, etc).
+	// They are normalized, so that their order does not matter.
+	hash.Write([]byte(r.URL.Query().Encode()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Middleware implements the middleware interface
+func (i *idempotencyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			key = ""
+		}
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if len(key) > maxIdempotencyKeyLength {
+			idempotencyErrorResponse(ctx, w, http.StatusBadRequest,
+				fmt.Sprintf("idempotency key must be at most %d characters long", maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			idempotencyErrorResponse(ctx, w, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashIdempotentRequest(r, body)
+
+		userID := UserID(ctx)
+		record, err := i.store.GetIdempotencyRecord(ctx, userID, key)
+		if err == nil {
+			if record.RequestHash != requestHash {
+				idempotencyErrorResponse(ctx, w, http.StatusUnprocessableEntity,
+					"idempotency key was already used for a different request")
+				return
+			}
+			if record.Pending {
+				idempotencyErrorResponse(ctx, w, http.StatusConflict,
+					"a request with the same idempotency key is in progress")
+				return
+			}
+			if record.ResponseOmitted {
+				idempotencyErrorResponse(ctx, w, http.StatusConflict,
+					"the request with this idempotency key already succeeded; its response held secrets and can not be replayed")
+				return
+			}
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			if _, err := w.Write(record.Response); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to write response")
+			}
+			return
+		}
+		if !errors.Is(err, runnerErrors.ErrNotFound) {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to fetch idempotency record")
+			idempotencyErrorResponse(ctx, w, http.StatusInternalServerError,
+				"failed to fetch idempotency record")
+			return
+		}
+
+		// Expired records are not returned, but they still hold the key. Remove them
+		// before reserving the key.
+		if err := i.store.DeleteExpiredIdempotencyRecords(ctx); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to delete expired idempotency records")
+		}
+		pending := params.IdempotencyRecord{
+			Key:         key,
+			UserID:      userID,
+			RequestHash: requestHash,
+			Pending:     true,
+			ExpiresAt:   time.Now().UTC().Add(pendingIdempotencyRecordTTL),
+		}
+		if _, err := i.store.CreateIdempotencyRecord(ctx, pending); err != nil {
+			if errors.Is(err, runnerErrors.ErrDuplicateEntity) {
+				idempotencyErrorResponse(ctx, w, http.StatusConflict,
+					"a request with the same idempotency key is in progress")
+				return
+			}
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create idempotency record")
+			idempotencyErrorResponse(ctx, w, http.StatusInternalServerError,
+				"failed to create idempotency record")
+			return
+		}
+
+		// Server errors are not recorded, and the key is released, so the request may be
+		// retried with the same key. This also covers handlers that panic.
+		recorded := false
+		defer func() {
+			if recorded {
+				return
+			}
+			if err := i.store.DeleteIdempotencyRecord(ctx, userID, key); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to delete idempotency record")
+			}
+		}()
+
+		statusCode := http.StatusOK
+		var response bytes.Buffer
+		wrapped := httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					statusCode = code
+					next(code)
+				}
+			},
+			Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+				return func(b []byte) (int, error) {
+					response.Write(b)
+					return next(b)
+				}
+			},
+		})
+		omitResponse := false
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(ctx, omitIdempotentResponseKey{}, &omitResponse)))
+
+		if statusCode >= http.StatusInternalServerError {
+			return
+		}
+		recorded = true
+
+		now := time.Now().UTC()
+		newRecord := params.IdempotencyRecord{
+			Key:         key,
+			UserID:      userID,
+			RequestHash: requestHash,
+			StatusCode:  statusCode,
+			ContentType: w.Header().Get("Content-Type"),
+			Response:    response.Bytes(),
+			ExpiresAt:   now.Add(appdefaults.DefaultIdempotencyKeyTTL),
+		}
+		// Error responses don't hold secrets, and are replayed as usual.
+		if omitResponse && statusCode < http.StatusBadRequest {
+			newRecord.ContentType = ""
+			newRecord.Response = nil
+			newRecord.ResponseOmitted = true
+		}
+		if _, err := i.store.UpdateIdempotencyRecord(ctx, newRecord); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to update idempotency record")
+		}
+	})
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+// idempotencyStore keeps idempotency records in memory. Calling any other
+// method of the store panics.
+type idempotencyStore struct {
+	common.Store
+
+	records map[string]params.IdempotencyRecord
+}
+
+func (i *idempotencyStore) GetIdempotencyRecord(_ context.Context, userID, key string) (params.IdempotencyRecord, error) {
+	record, ok := i.records[userID+":"+key]
+	if !ok {
+		return params.IdempotencyRecord{}, runnerErrors.ErrNotFound
+	}
+	return record, nil
+}
+
+func (i *idempotencyStore) CreateIdempotencyRecord(_ context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	if _, ok := i.records[record.UserID+":"+record.Key]; ok {
+		return params.IdempotencyRecord{}, runnerErrors.ErrDuplicateEntity
+	}
+	i.records[record.UserID+":"+record.Key] = record
+	return record, nil
+}
+
+func (i *idempotencyStore) UpdateIdempotencyRecord(_ context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	if _, ok := i.records[record.UserID+":"+record.Key]; !ok {
+		return params.IdempotencyRecord{}, runnerErrors.ErrNotFound
+	}
+	i.records[record.UserID+":"+record.Key] = record
+	return record, nil
+}
+
+func (i *idempotencyStore) DeleteIdempotencyRecord(_ context.Context, userID, key string) error {
+	delete(i.records, userID+":"+key)
+	return nil
+}
+
+func (i *idempotencyStore) DeleteExpiredIdempotencyRecords(_ context.Context) error {
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := &idempotencyStore{records: map[string]params.IdempotencyRecord{}}
+	middleware, err := NewIdempotencyMiddleware(store)
+	require.NoError(t, err)
+
+	calls := 0
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"call":%d,"dry_run":%q}`, calls, r.URL.Query().Get("dry_run"))
+	}))
+
+	do := func(target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"image":"ubuntu"}`))
+		req = req.WithContext(SetUserID(req.Context(), "user-id"))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/api/v1/repositories/repo-id/pools?dry_run=true", "key-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `{"call":1,"dry_run":"true"}`, rec.Body.String())
+
+	// A retry of the same request is replayed.
+	rec = do("/api/v1/repositories/repo-id/pools?dry_run=true", "key-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "true", rec.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, `{"call":1,"dry_run":"true"}`, rec.Body.String())
+	require.Equal(t, 1, calls)
+
+	// The key was used for a dry run, and can't be used to create the pool.
+	rec = do("/api/v1/repositories/repo-id/pools", "key-1")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, 1, calls)
+
+	rec = do("/api/v1/repositories/repo-id/pools", "key-2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, `{"call":2,"dry_run":""}`, rec.Body.String())
+
+	// Requests without a key are never replayed.
+	rec = do("/api/v1/repositories/repo-id/pools", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 3, calls)
+}
+
+func TestHashIdempotentRequestQuery(t *testing.T) {
+	body := []byte(`{}`)
+	hash := func(target string) string {
+		return hashIdempotentRequest(httptest.NewRequest(http.MethodDelete, target, nil), body)
+	}
+
+	require.NotEqual(t, hash("/api/v1/repositories/repo-id"), hash("/api/v1/repositories/repo-id?keepWebhook=true"))
+	// The order of the query parameters does not matter.
+	require.Equal(t, hash("/api/v1/pools?a=1&b=2"), hash("/api/v1/pools?b=2&a=1"))
+}
+
+func TestIdempotencyMiddlewareOmitsSecrets(t *testing.T) {
+	store := &idempotencyStore{records: map[string]params.IdempotencyRecord{}}
+	middleware, err := NewIdempotencyMiddleware(store)
+	require.NoError(t, err)
+
+	calls := 0
+	handler := middleware.Middleware(OmitIdempotentResponse(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"token":"secret-token"}`)
+	})))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(`{"name":"ci"}`))
+		req = req.WithContext(SetUserID(req.Context(), "user-id"))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `{"token":"secret-token"}`, rec.Body.String())
+
+	record := store.records["user-id:key-1"]
+	require.True(t, record.ResponseOmitted)
+	require.Empty(t, record.Response)
+
+	// The token is not replayed, and a second token is not created.
+	rec = do()
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.NotContains(t, rec.Body.String(), "secret-token")
+	require.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddlewareRequestInProgress(t *testing.T) {
+	store := &idempotencyStore{records: map[string]params.IdempotencyRecord{}}
+	middleware, err := NewIdempotencyMiddleware(store)
+	require.NoError(t, err)
+
+	calls := 0
+	statusCode := http.StatusInternalServerError
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The key is reserved while the request is handled.
+		record := store.records["user-id:key-1"]
+		require.True(t, record.Pending)
+
+		// A retry that reaches another controller is rejected.
+		rec := httptest.NewRecorder()
+		retry := httptest.NewRequest(r.Method, r.URL.String(), strings.NewReader(`{"image":"ubuntu"}`))
+		retry = retry.WithContext(SetUserID(retry.Context(), "user-id"))
+		retry.Header.Set(IdempotencyKeyHeader, "key-1")
+		other, err := NewIdempotencyMiddleware(store)
+		require.NoError(t, err)
+		other.Middleware(http.NotFoundHandler()).ServeHTTP(rec, retry)
+		require.Equal(t, http.StatusConflict, rec.Code)
+
+		w.WriteHeader(statusCode)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/repositories/repo-id/pools", strings.NewReader(`{"image":"ubuntu"}`))
+		req = req.WithContext(SetUserID(req.Context(), "user-id"))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Server errors release the key.
+	rec := do()
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Empty(t, store.records)
+
+	statusCode = http.StatusOK
+	rec = do()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.False(t, store.records["user-id:key-1"].Pending)
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	dbCommon "github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+// rejectedSourceAuditInterval is the minimum interval between two audit records
+// of rejected requests coming from the same address.
+const rejectedSourceAuditInterval = 1 * time.Minute
+
+// sourceAllowlist restricts the addresses allowed to call into the instance facing
+// endpoints. An empty allowlist allows all addresses.
+type sourceAllowlist struct {
+	store    dbCommon.Store
+	networks []*net.IPNet
+
+	mux sync.Mutex
+	// lastAudit holds the time a rejected request was last audited, for each address.
+	lastAudit map[string]time.Time
+}
+
+func newSourceAllowlist(store dbCommon.Store, cidrs []string) (*sourceAllowlist, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing CIDR %q", cidr)
+		}
+		networks = append(networks, network)
+	}
+	return &sourceAllowlist{
+		store:     store,
+		networks:  networks,
+		lastAudit: map[string]time.Time{},
+	}, nil
+}
+
+// sourceIP returns the address of the peer that sent the request. Forwarding headers
+// are not taken into account, as they can be set by anyone.
+func sourceIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// SourceAddress returns the address of the peer that sent the request, without the port.
+func SourceAddress(r *http.Request) string {
+	if ip := sourceIP(r); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+// allowed returns true if the request comes from one of the allowed networks.
+func (s *sourceAllowlist) allowed(r *http.Request) bool {
+	if len(s.networks) == 0 {
+		return true
+	}
+	ip := sourceIP(r)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordRejected adds an audit record for a request that was rejected because of its
+// source address. Requests from the same address are audited at most once every
+// rejectedSourceAuditInterval, so a scan does not flood the audit log.
+func (s *sourceAllowlist) recordRejected(ctx context.Context, r *http.Request) {
+	source := SourceAddress(r)
+
+	slog.WarnContext(ctx, "rejected instance request from address outside of the allowed networks", "source", source, "path", r.URL.Path)
+
+	now := time.Now().UTC()
+	s.mux.Lock()
+	for addr, last := range s.lastAudit {
+		if now.Sub(last) >= rejectedSourceAuditInterval {
+			delete(s.lastAudit, addr)
+		}
+	}
+	if _, ok := s.lastAudit[source]; ok {
+		s.mux.Unlock()
+		return
+	}
+	s.lastAudit[source] = now
+	s.mux.Unlock()
+
+	record := params.AuditRecord{
+		Action: params.AuditActionInstanceSourceRejected,
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Reason: fmt.Sprintf("request from %s is outside of the allowed instance networks", source),
+	}
+	if _, err := s.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
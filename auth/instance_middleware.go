@@ -16,9 +16,12 @@ package auth
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -46,6 +49,22 @@ type InstanceJWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// InstanceTokenAudience returns the audience set on the JWT tokens of instances
+// in the pool with the given ID.
+func InstanceTokenAudience(poolID string) string {
+	return fmt.Sprintf("garm-pool:%s", poolID)
+}
+
+// InstanceTokenKey derives the key used to sign the JWT tokens of instances in a
+// pool from the global JWT secret. Each pool gets its own key, so a token leaked
+// from an instance can't be used to authenticate as an instance of another pool.
+// The generation is incremented every time the pool is updated, which rotates the key.
+func InstanceTokenKey(jwtSecret, poolID string, generation uint) []byte {
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte(fmt.Sprintf("garm-instance-token:%s:%d", poolID, generation)))
+	return mac.Sum(nil)
+}
+
 func NewInstanceTokenGetter(jwtSecret string) (InstanceTokenGetter, error) {
 	if jwtSecret == "" {
 		return nil, fmt.Errorf("jwt secret is required")
@@ -59,7 +78,7 @@ type instanceToken struct {
 	jwtSecret string
 }
 
-func (i *instanceToken) NewInstanceJWTToken(instance params.Instance, entity string, poolType params.GithubEntityType, ttlMinutes uint) (string, error) {
+func (i *instanceToken) NewInstanceJWTToken(instance params.Instance, entity string, pool params.Pool, ttlMinutes uint) (string, error) {
 	// Token expiration is equal to the bootstrap timeout set on the pool plus the polling
 	// interval garm uses to check for timed out runners. Runners that have not sent their info
 	// by the end of this interval are most likely failed and will be reaped by garm anyway.
@@ -71,16 +90,17 @@ func (i *instanceToken) NewInstanceJWTToken(instance params.Instance, entity str
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: expires,
 			Issuer:    "garm",
+			Audience:  jwt.ClaimStrings{InstanceTokenAudience(pool.ID)},
 		},
 		ID:            instance.ID,
 		Name:          instance.Name,
 		PoolID:        instance.PoolID,
-		Scope:         poolType,
+		Scope:         pool.PoolType(),
 		Entity:        entity,
 		CreateAttempt: instance.CreateAttempt,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(i.jwtSecret))
+	tokenString, err := token.SignedString(InstanceTokenKey(i.jwtSecret, pool.ID, pool.InstanceTokenGeneration))
 	if err != nil {
 		return "", errors.Wrap(err, "signing token")
 	}
@@ -91,15 +111,22 @@ func (i *instanceToken) NewInstanceJWTToken(instance params.Instance, entity str
 // instanceMiddleware is the authentication middleware
 // used with gorilla
 type instanceMiddleware struct {
-	store dbCommon.Store
-	cfg   config.JWTAuth
+	store     dbCommon.Store
+	cfg       config.JWTAuth
+	allowlist *sourceAllowlist
 }
 
-// NewjwtMiddleware returns a populated jwtMiddleware
-func NewInstanceMiddleware(store dbCommon.Store, cfg config.JWTAuth) (Middleware, error) {
+// NewjwtMiddleware returns a populated jwtMiddleware. When allowedCIDRs is not
+// empty, only requests coming from those networks are accepted.
+func NewInstanceMiddleware(store dbCommon.Store, cfg config.JWTAuth, allowedCIDRs []string) (Middleware, error) {
+	allowlist, err := newSourceAllowlist(store, allowedCIDRs)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing allowed instance networks")
+	}
 	return &instanceMiddleware{
-		store: store,
-		cfg:   cfg,
+		store:     store,
+		cfg:       cfg,
+		allowlist: allowlist,
 	}, nil
 }
 
@@ -117,16 +144,58 @@ func (amw *instanceMiddleware) claimsToContext(ctx context.Context, claims *Inst
 		return ctx, runnerErrors.ErrUnauthorized
 	}
 
+	if instanceInfo.PoolID != claims.PoolID || !slices.Contains(claims.Audience, InstanceTokenAudience(instanceInfo.PoolID)) {
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+
 	ctx = PopulateInstanceContext(ctx, instanceInfo)
 	return ctx, nil
 }
 
+// verificationKeys returns the keys that may have been used to sign the token of
+// the instance. The token is signed when the instance is created, so the keys of all
+// pool generations since then are accepted. Updating a pool does not break instances
+// that are bootstrapping, while tokens signed before the instance existed are rejected.
+func (amw *instanceMiddleware) verificationKeys(ctx context.Context, claims *InstanceJWTClaims) (jwt.VerificationKeySet, error) {
+	if claims.Name == "" || claims.PoolID == "" {
+		return jwt.VerificationKeySet{}, fmt.Errorf("missing instance claims")
+	}
+
+	instance, err := amw.store.GetInstanceByName(ctx, claims.Name)
+	if err != nil {
+		return jwt.VerificationKeySet{}, errors.Wrap(err, "fetching instance")
+	}
+	if instance.PoolID != claims.PoolID {
+		return jwt.VerificationKeySet{}, fmt.Errorf("instance does not belong to pool")
+	}
+
+	pool, err := amw.store.GetPoolByID(ctx, claims.PoolID)
+	if err != nil {
+		return jwt.VerificationKeySet{}, errors.Wrap(err, "fetching pool")
+	}
+
+	keys := jwt.VerificationKeySet{}
+	for generation := pool.InstanceTokenGeneration; ; generation-- {
+		keys.Keys = append(keys.Keys, InstanceTokenKey(amw.cfg.Secret, pool.ID, generation))
+		if generation <= instance.InstanceTokenGeneration || generation == 0 {
+			break
+		}
+	}
+	return keys, nil
+}
+
 // Middleware implements the middleware interface
 func (amw *instanceMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// nolint:golangci-lint,godox
 		// TODO: Log error details when authentication fails
 		ctx := r.Context()
+		if !amw.allowlist.allowed(r) {
+			amw.allowlist.recordRejected(ctx, r)
+			invalidAuthResponse(ctx, w)
+			return
+		}
+
 		authorizationHeader := r.Header.Get("authorization")
 		if authorizationHeader == "" {
 			invalidAuthResponse(ctx, w)
@@ -144,7 +213,7 @@ func (amw *instanceMiddleware) Middleware(next http.Handler) http.Handler {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("invalid signing method")
 			}
-			return []byte(amw.cfg.Secret), nil
+			return amw.verificationKeys(ctx, claims)
 		})
 		if err != nil {
 			invalidAuthResponse(ctx, w)
@@ -0,0 +1,154 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+const testJWTSecret = "bocyasicgatEtenOubwonIbsudNutDom"
+
+// instanceStore holds a single pool and instance. Calling any other method of the
+// store panics.
+type instanceStore struct {
+	common.Store
+
+	pool     params.Pool
+	instance params.Instance
+}
+
+func (i *instanceStore) GetPoolByID(_ context.Context, poolID string) (params.Pool, error) {
+	if poolID != i.pool.ID {
+		return params.Pool{}, runnerErrors.ErrNotFound
+	}
+	return i.pool, nil
+}
+
+func (i *instanceStore) GetInstanceByName(_ context.Context, instanceName string) (params.Instance, error) {
+	if instanceName != i.instance.Name {
+		return params.Instance{}, runnerErrors.ErrNotFound
+	}
+	return i.instance, nil
+}
+
+func newTestInstanceStore() *instanceStore {
+	return &instanceStore{
+		pool: params.Pool{ID: "pool-id", RepoID: "repo-id"},
+		instance: params.Instance{
+			ID:           "instance-id",
+			Name:         "runner-1",
+			PoolID:       "pool-id",
+			Status:       commonParams.InstanceRunning,
+			RunnerStatus: params.RunnerInstalling,
+		},
+	}
+}
+
+func instanceTestToken(t *testing.T, store *instanceStore, expiresAt time.Time) string {
+	claims := InstanceJWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    "garm",
+			Audience:  jwt.ClaimStrings{InstanceTokenAudience(store.pool.ID)},
+		},
+		ID:     store.instance.ID,
+		Name:   store.instance.Name,
+		PoolID: store.pool.ID,
+		Scope:  params.GithubEntityTypeRepository,
+		Entity: "owner/repo",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(InstanceTokenKey(testJWTSecret, store.pool.ID, store.pool.InstanceTokenGeneration))
+	require.NoError(t, err)
+	return token
+}
+
+func serveInstanceRequest(t *testing.T, store *instanceStore, token string) *httptest.ResponseRecorder {
+	middleware, err := NewInstanceMiddleware(store, config.JWTAuth{Secret: testJWTSecret}, nil)
+	require.NoError(t, err)
+
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, store.instance.Name, InstanceName(r.Context()))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/metadata/runner-registration-token", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestInstanceMiddleware(t *testing.T) {
+	store := newTestInstanceStore()
+
+	rec := serveInstanceRequest(t, store, instanceTestToken(t, store, time.Now().Add(time.Hour)))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestInstanceMiddlewareRejectsExpiredToken(t *testing.T) {
+	store := newTestInstanceStore()
+
+	// Tokens are issued and validated using the clock of GARM, so an expired token
+	// is rejected regardless of the configured clock skew tolerance.
+	rec := serveInstanceRequest(t, store, instanceTestToken(t, store, time.Now().Add(-time.Minute)))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestInstanceMiddlewareRejectsInstalledRunner(t *testing.T) {
+	store := newTestInstanceStore()
+	store.instance.RunnerStatus = params.RunnerIdle
+
+	rec := serveInstanceRequest(t, store, instanceTestToken(t, store, time.Now().Add(time.Hour)))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestInstanceMiddlewareAcceptsTokenAfterPoolUpdates(t *testing.T) {
+	store := newTestInstanceStore()
+	store.pool.InstanceTokenGeneration = 3
+	store.instance.InstanceTokenGeneration = 3
+
+	token := instanceTestToken(t, store, time.Now().Add(time.Hour))
+	// The pool is updated twice in a row while the instance is bootstrapping.
+	store.pool.InstanceTokenGeneration = 5
+
+	rec := serveInstanceRequest(t, store, token)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestInstanceMiddlewareRejectsInvalidSignature(t *testing.T) {
+	store := newTestInstanceStore()
+	store.pool.InstanceTokenGeneration = 1
+
+	// The token is signed with the key of a generation older than the instance.
+	token := instanceTestToken(t, store, time.Now().Add(time.Hour))
+	store.pool.InstanceTokenGeneration = 2
+	store.instance.InstanceTokenGeneration = 2
+
+	rec := serveInstanceRequest(t, store, token)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
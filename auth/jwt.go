@@ -23,12 +23,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/felixge/httpsnoop"
 	jwt "github.com/golang-jwt/jwt/v5"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	apiParams "github.com/cloudbase/garm/apiserver/params"
 	"github.com/cloudbase/garm/config"
 	dbCommon "github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
 )
 
 // JWTClaims holds JWT claims
@@ -39,6 +41,10 @@ type JWTClaims struct {
 	IsAdmin     bool   `json:"is_admin"`
 	ReadMetrics bool   `json:"read_metrics"`
 	Generation  uint   `json:"generation"`
+	// ImpersonatedBy holds the ID of the admin that requested this token on
+	// behalf of the user.
+	ImpersonatedBy      string `json:"impersonated_by,omitempty"`
+	ImpersonationReason string `json:"impersonation_reason,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -83,9 +89,76 @@ func (amw *jwtMiddleware) claimsToContext(ctx context.Context, claims *JWTClaims
 	}
 
 	ctx = PopulateContext(ctx, userInfo, expiresAt)
+
+	if claims.ImpersonatedBy != "" {
+		// Impersonation tokens are only valid as long as impersonation is enabled
+		// and the admin that requested them is still an enabled admin.
+		if !amw.cfg.AllowImpersonation {
+			return ctx, runnerErrors.ErrUnauthorized
+		}
+		impersonator, err := amw.store.GetUserByID(ctx, claims.ImpersonatedBy)
+		if err != nil {
+			return ctx, runnerErrors.ErrUnauthorized
+		}
+		if !impersonator.Enabled || !impersonator.IsAdmin {
+			return ctx, runnerErrors.ErrUnauthorized
+		}
+		ctx = SetImpersonation(ctx, Impersonation{
+			ImpersonatorID: impersonator.ID,
+			Impersonator:   impersonator.Username,
+			Reason:         claims.ImpersonationReason,
+		})
+	}
 	return ctx, nil
 }
 
+// recordImpersonatedRequest adds an audit record for a request made using an
+// impersonation token.
+func (amw *jwtMiddleware) recordImpersonatedRequest(ctx context.Context, r *http.Request, impersonation Impersonation, statusCode int) {
+	slog.InfoContext(
+		ctx, "impersonated API request",
+		"impersonator", impersonation.Impersonator,
+		"username", Username(ctx),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status_code", statusCode)
+
+	record := params.AuditRecord{
+		Action:         params.AuditActionAPIRequest,
+		ImpersonatorID: impersonation.ImpersonatorID,
+		Impersonator:   impersonation.Impersonator,
+		UserID:         UserID(ctx),
+		Username:       Username(ctx),
+		Reason:         impersonation.Reason,
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		StatusCode:     statusCode,
+	}
+	if _, err := amw.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
+
+// jwtTokenToContext authenticates a request made with a JWT token.
+func (amw *jwtMiddleware) jwtTokenToContext(ctx context.Context, tokenString string) (context.Context, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("invalid signing method")
+		}
+		return []byte(amw.cfg.Secret), nil
+	})
+	if err != nil {
+		return ctx, err
+	}
+
+	if !token.Valid {
+		return ctx, runnerErrors.ErrUnauthorized
+	}
+
+	return amw.claimsToContext(ctx, claims)
+}
+
 func invalidAuthResponse(ctx context.Context, w http.ResponseWriter) {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
@@ -115,30 +188,24 @@ func (amw *jwtMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		claims := &JWTClaims{}
-		token, err := jwt.ParseWithClaims(bearerToken[1], claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("invalid signing method")
-			}
-			return []byte(amw.cfg.Secret), nil
-		})
+		var err error
+		if strings.HasPrefix(bearerToken[1], params.APITokenPrefix) {
+			ctx, err = amw.apiTokenToContext(ctx, bearerToken[1])
+		} else {
+			ctx, err = amw.jwtTokenToContext(ctx, bearerToken[1])
+		}
 		if err != nil {
 			invalidAuthResponse(ctx, w)
 			return
 		}
-
-		if !token.Valid {
+		if !IsEnabled(ctx) {
 			invalidAuthResponse(ctx, w)
 			return
 		}
 
-		ctx, err = amw.claimsToContext(ctx, claims)
-		if err != nil {
-			invalidAuthResponse(ctx, w)
-			return
-		}
-		if !IsEnabled(ctx) {
-			invalidAuthResponse(ctx, w)
+		if impersonation, ok := GetImpersonation(ctx); ok {
+			metrics := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+			amw.recordImpersonatedRequest(ctx, r, impersonation, metrics.Code)
 			return
 		}
 
@@ -0,0 +1,212 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+)
+
+// loginRateWindow is the window over which login attempts are counted.
+const loginRateWindow = 1 * time.Minute
+
+// LoginRateLimitedError is returned when a login attempt is refused because too many
+// attempts were made from the same address, or for the same username from that address.
+type LoginRateLimitedError struct {
+	// RetryAfter is the amount of time after which a new attempt is allowed.
+	RetryAfter time.Duration
+}
+
+func (e *LoginRateLimitedError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// loginLimiter counts the login attempts made for each key, over a sliding window of
+// one minute. Attempts are kept in memory, so each
+// controller enforces the limit on its own.
+type loginLimiter struct {
+	limit uint
+
+	mux      sync.Mutex
+	attempts map[string][]time.Time
+	// lastAudit holds the time a refused attempt was last audited, for each key.
+	lastAudit map[string]time.Time
+}
+
+func newLoginLimiter(limit uint) *loginLimiter {
+	return &loginLimiter{
+		limit:     limit,
+		attempts:  map[string][]time.Time{},
+		lastAudit: map[string]time.Time{},
+	}
+}
+
+// allow records a login attempt for the given keys. If any of the keys reached the
+// limit, the attempt is refused and not recorded. The returned duration is the time
+// after which a new attempt is allowed.
+func (l *loginLimiter) allow(now time.Time, keys ...string) (bool, time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for key, attempts := range l.attempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1]) >= loginRateWindow {
+			delete(l.attempts, key)
+		}
+	}
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		attempts := l.attempts[key]
+		for len(attempts) > 0 && now.Sub(attempts[0]) >= loginRateWindow {
+			attempts = attempts[1:]
+		}
+		l.attempts[key] = attempts
+		if uint(len(attempts)) >= l.limit {
+			retryAfter = max(retryAfter, loginRateWindow-now.Sub(attempts[0]))
+		}
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+
+	for _, key := range keys {
+		l.attempts[key] = append(l.attempts[key], now)
+	}
+	return true, 0
+}
+
+// shouldAudit returns true if a refused attempt for the key should be recorded in
+// the audit log. Refused attempts are audited at most once per window for each key,
+// so a password guessing attempt does not flood the audit log.
+func (l *loginLimiter) shouldAudit(now time.Time, key string) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for k, last := range l.lastAudit {
+		if now.Sub(last) >= loginRateWindow {
+			delete(l.lastAudit, k)
+		}
+	}
+	if _, ok := l.lastAudit[key]; ok {
+		return false
+	}
+	l.lastAudit[key] = now
+	return true
+}
+
+func (a *Authenticator) checkLoginRate(ctx context.Context, username, source string) error {
+	if a.cfg.DisableLoginProtection {
+		return nil
+	}
+
+	// Attempts for a username are only counted along with the address they come
+	// from, so failed logins from one address can't keep a user from logging in
+	// from another one.
+	now := time.Now().UTC()
+	keys := []string{"user:" + strings.ToLower(username) + "/source:" + source}
+	if source != "" {
+		keys = append(keys, "source:"+source)
+	}
+	allowed, retryAfter := a.limiter.allow(now, keys...)
+	if allowed {
+		return nil
+	}
+
+	slog.WarnContext(ctx, "refused login attempt because of the rate limit", "username", username, "source", source)
+	if a.limiter.shouldAudit(now, strings.Join(keys, "/")) {
+		record := params.AuditRecord{
+			Action:   params.AuditActionLoginRateLimited,
+			Username: username,
+			Reason:   fmt.Sprintf("too many login attempts from %s", source),
+		}
+		if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+		}
+	}
+	return &LoginRateLimitedError{RetryAfter: retryAfter}
+}
+
+// recordFailedLogin counts a failed login of the user, and records an audit record if
+// the user was locked out as a result.
+func (a *Authenticator) recordFailedLogin(ctx context.Context, user params.User) {
+	if !a.cfg.LockoutEnabled() {
+		return
+	}
+
+	updated, err := a.store.RecordFailedLogin(ctx, user.Username, a.cfg.LoginLockoutThreshold, a.cfg.LockoutDuration())
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to record failed login", "username", user.Username)
+		return
+	}
+	if !updated.IsLockedOut(time.Now().UTC()) {
+		return
+	}
+
+	slog.WarnContext(ctx, "user locked out after repeated failed logins", "username", user.Username, "locked_until", updated.LockedUntil)
+	record := params.AuditRecord{
+		Action:   params.AuditActionUserLockedOut,
+		UserID:   user.ID,
+		Username: user.Username,
+		Reason:   fmt.Sprintf("%d consecutive failed logins", a.cfg.LoginLockoutThreshold),
+	}
+	if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
+
+// GetUser returns a user, along with its lockout status.
+func (a *Authenticator) GetUser(ctx context.Context, username string) (params.User, error) {
+	if !IsAdmin(ctx) {
+		return params.User{}, runnerErrors.ErrUnauthorized
+	}
+
+	user, err := a.store.GetUser(ctx, username)
+	if err != nil {
+		return params.User{}, errors.Wrap(err, "fetching user")
+	}
+	return user, nil
+}
+
+// UnlockUser lifts the lockout of a user and clears its failed logins.
+func (a *Authenticator) UnlockUser(ctx context.Context, username string) (params.User, error) {
+	if !IsAdmin(ctx) {
+		return params.User{}, runnerErrors.ErrUnauthorized
+	}
+
+	user, err := a.store.ResetFailedLogins(ctx, username)
+	if err != nil {
+		return params.User{}, errors.Wrap(err, "unlocking user")
+	}
+
+	record := params.AuditRecord{
+		Action:   params.AuditActionUserUnlocked,
+		UserID:   user.ID,
+		Username: user.Username,
+		Reason:   fmt.Sprintf("unlocked by %s", Username(ctx)),
+	}
+	if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+	return user, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm-provider-common/util"
+	"github.com/cloudbase/garm/config"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	limiter := newLoginLimiter(2)
+	now := time.Now()
+
+	allowed, _ := limiter.allow(now, "a")
+	require.True(t, allowed)
+	allowed, _ = limiter.allow(now.Add(10*time.Second), "a")
+	require.True(t, allowed)
+
+	allowed, retryAfter := limiter.allow(now.Add(20*time.Second), "a")
+	require.False(t, allowed)
+	require.Equal(t, 40*time.Second, retryAfter)
+
+	// Other keys are counted separately.
+	allowed, _ = limiter.allow(now.Add(20*time.Second), "b")
+	require.True(t, allowed)
+
+	// An attempt is refused if any of its keys reached the limit, and is not counted
+	// against the other keys.
+	allowed, _ = limiter.allow(now.Add(20*time.Second), "a", "c")
+	require.False(t, allowed)
+	allowed, _ = limiter.allow(now.Add(20*time.Second), "c")
+	require.True(t, allowed)
+	allowed, _ = limiter.allow(now.Add(20*time.Second), "c")
+	require.True(t, allowed)
+
+	// Attempts older than the window are dropped.
+	allowed, _ = limiter.allow(now.Add(loginRateWindow), "a")
+	require.True(t, allowed)
+	allowed, _ = limiter.allow(now.Add(loginRateWindow+time.Second), "a")
+	require.False(t, allowed)
+}
+
+func TestLoginLimiterShouldAudit(t *testing.T) {
+	limiter := newLoginLimiter(1)
+	now := time.Now()
+
+	require.True(t, limiter.shouldAudit(now, "a"))
+	require.False(t, limiter.shouldAudit(now.Add(time.Second), "a"))
+	require.True(t, limiter.shouldAudit(now.Add(time.Second), "b"))
+	require.True(t, limiter.shouldAudit(now.Add(loginRateWindow), "a"))
+}
+
+func newLoginTestStore(t *testing.T) *dbMocks.Store {
+	hashed, err := util.PaswsordToBcrypt("right-password")
+	require.NoError(t, err)
+
+	store := dbMocks.NewStore(t)
+	store.On("GetUser", mock.Anything, "admin").Return(params.User{
+		ID:       "user-id",
+		Username: "admin",
+		Password: hashed,
+		Enabled:  true,
+	}, nil).Maybe()
+	store.On("CreateAuditRecord", mock.Anything, mock.Anything).Return(params.AuditRecord{}, nil).Maybe()
+	return store
+}
+
+func TestAuthenticateUserRateLimit(t *testing.T) {
+	// Lockouts are disabled by default, so failed logins are not recorded.
+	authenticator := NewAuthenticator(config.JWTAuth{LoginRateLimit: 2}, newLoginTestStore(t))
+	ctx := context.Background()
+	wrong := params.PasswordLoginParams{Username: "admin", Password: "wrong-password"}
+
+	for i := 0; i < 2; i++ {
+		_, err := authenticator.AuthenticateUser(ctx, wrong, "192.0.2.10")
+		require.ErrorIs(t, err, runnerErrors.ErrUnauthorized)
+	}
+
+	_, err := authenticator.AuthenticateUser(ctx, wrong, "192.0.2.10")
+	var rateLimited *LoginRateLimitedError
+	require.ErrorAs(t, err, &rateLimited)
+	require.Greater(t, rateLimited.RetryAfter, time.Duration(0))
+
+	// Failed logins from one address don't keep the user from logging in from another.
+	_, err = authenticator.AuthenticateUser(ctx, params.PasswordLoginParams{Username: "admin", Password: "right-password"}, "192.0.2.20")
+	require.NoError(t, err)
+}
+
+func TestAuthenticateUserLockout(t *testing.T) {
+	store := newLoginTestStore(t)
+	lockedUntil := time.Now().UTC().Add(time.Minute)
+	store.On("RecordFailedLogin", mock.Anything, "admin", uint(1), 15*time.Minute).Return(params.User{
+		Username:    "admin",
+		LockedUntil: &lockedUntil,
+	}, nil).Once()
+
+	authenticator := NewAuthenticator(config.JWTAuth{LoginLockoutThreshold: 1}, store)
+	_, err := authenticator.AuthenticateUser(context.Background(), params.PasswordLoginParams{Username: "admin", Password: "wrong-password"}, "192.0.2.10")
+	require.ErrorIs(t, err, runnerErrors.ErrUnauthorized)
+}
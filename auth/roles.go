@@ -0,0 +1,121 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// SetRole sets the role of the user in the context.
+func SetRole(ctx context.Context, role params.UserRole) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// Role returns the role of the user in the context on all entities.
+func Role(ctx context.Context) params.UserRole {
+	if IsAdmin(ctx) {
+		return params.UserRoleAdmin
+	}
+	elem := ctx.Value(roleKey)
+	if elem == nil {
+		return ""
+	}
+	return elem.(params.UserRole)
+}
+
+// SetRoleGrants sets the roles the user in the context has on single entities.
+func SetRoleGrants(ctx context.Context, grants []params.RoleGrant) context.Context {
+	return context.WithValue(ctx, roleGrantsKey, grants)
+}
+
+// RoleGrants returns the roles the user in the context has on single entities.
+func RoleGrants(ctx context.Context) []params.RoleGrant {
+	elem := ctx.Value(roleGrantsKey)
+	if elem == nil {
+		return nil
+	}
+	return elem.([]params.RoleGrant)
+}
+
+// EntityRole returns the role the user in the context has on an entity. This is the
+// role that allows the most, out of the role of the user on all entities and the
+// role granted on the entity.
+func EntityRole(ctx context.Context, entity params.GithubEntity) params.UserRole {
+	role := Role(ctx)
+	if role.CanManage() {
+		return role
+	}
+	for _, grant := range RoleGrants(ctx) {
+		if grant.EntityType != entity.EntityType || grant.EntityID != entity.ID {
+			continue
+		}
+		if grant.Role.CanManage() || !role.CanView() {
+			role = grant.Role
+		}
+	}
+	return role
+}
+
+// CanViewEntity returns true if the user in the context can view the entity, along
+// with its pools and runners.
+func CanViewEntity(ctx context.Context, entity params.GithubEntity) bool {
+	return EntityRole(ctx, entity).CanView()
+}
+
+// CanManageEntity returns true if the user in the context can manage the pools of
+// the entity.
+func CanManageEntity(ctx context.Context, entity params.GithubEntity) bool {
+	return EntityRole(ctx, entity).CanManage()
+}
+
+// HasRole returns true if the user in the context has a role on at least one
+// entity.
+func HasRole(ctx context.Context) bool {
+	return Role(ctx).CanView() || len(RoleGrants(ctx)) > 0
+}
+
+// RoleRequiredMiddleware rejects requests from users that have no role. What users
+// are allowed to do with their role is checked by the runner.
+func RoleRequiredMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !HasRole(r.Context()) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CanViewPool returns true if the user in the context can view the pool and its
+// runners.
+func CanViewPool(ctx context.Context, pool params.Pool) bool {
+	entity, err := pool.GithubEntity()
+	if err != nil {
+		return IsAdmin(ctx)
+	}
+	return CanViewEntity(ctx, entity)
+}
+
+// CanManagePool returns true if the user in the context can manage the pool.
+func CanManagePool(ctx context.Context, pool params.Pool) bool {
+	entity, err := pool.GithubEntity()
+	if err != nil {
+		return IsAdmin(ctx)
+	}
+	return CanManageEntity(ctx, entity)
+}
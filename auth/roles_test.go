@@ -0,0 +1,248 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/params"
+)
+
+var (
+	grantedRepo = params.GithubEntity{ID: "repo-1", EntityType: params.GithubEntityTypeRepository}
+	otherRepo   = params.GithubEntity{ID: "repo-2", EntityType: params.GithubEntityTypeRepository}
+	// sameIDOrg has the same ID as grantedRepo, so grants must also match on type.
+	sameIDOrg = params.GithubEntity{ID: "repo-1", EntityType: params.GithubEntityTypeOrganization}
+)
+
+func roleContext(isAdmin bool, role params.UserRole, grants ...params.RoleGrant) context.Context {
+	return PopulateContext(context.Background(), params.User{
+		ID:      "user-id",
+		IsAdmin: isAdmin,
+		Enabled: true,
+		Role:    role,
+		Grants:  grants,
+	}, nil)
+}
+
+func grant(role params.UserRole, entity params.GithubEntity) params.RoleGrant {
+	return params.RoleGrant{Role: role, EntityType: entity.EntityType, EntityID: entity.ID}
+}
+
+func TestEntityRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		entity   params.GithubEntity
+		expected params.UserRole
+	}{
+		{
+			name:     "admin",
+			ctx:      roleContext(true, ""),
+			entity:   otherRepo,
+			expected: params.UserRoleAdmin,
+		},
+		{
+			name:     "no role",
+			ctx:      roleContext(false, ""),
+			entity:   grantedRepo,
+			expected: "",
+		},
+		{
+			name:     "viewer on all entities",
+			ctx:      roleContext(false, params.UserRoleViewer),
+			entity:   otherRepo,
+			expected: params.UserRoleViewer,
+		},
+		{
+			name:     "operator on all entities",
+			ctx:      roleContext(false, params.UserRoleOperator),
+			entity:   otherRepo,
+			expected: params.UserRoleOperator,
+		},
+		{
+			name:     "viewer grant on the entity",
+			ctx:      roleContext(false, "", grant(params.UserRoleViewer, grantedRepo)),
+			entity:   grantedRepo,
+			expected: params.UserRoleViewer,
+		},
+		{
+			name:     "operator grant on the entity",
+			ctx:      roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			entity:   grantedRepo,
+			expected: params.UserRoleOperator,
+		},
+		{
+			name:     "grant on another entity",
+			ctx:      roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			entity:   otherRepo,
+			expected: "",
+		},
+		{
+			name:     "grant on an entity of another type with the same ID",
+			ctx:      roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			entity:   sameIDOrg,
+			expected: "",
+		},
+		{
+			name:     "operator grant raises the viewer role",
+			ctx:      roleContext(false, params.UserRoleViewer, grant(params.UserRoleOperator, grantedRepo)),
+			entity:   grantedRepo,
+			expected: params.UserRoleOperator,
+		},
+		{
+			name:     "viewer grant does not lower the operator role",
+			ctx:      roleContext(false, params.UserRoleOperator, grant(params.UserRoleViewer, grantedRepo)),
+			entity:   grantedRepo,
+			expected: params.UserRoleOperator,
+		},
+		{
+			name:     "operator grant only raises the role on the entity",
+			ctx:      roleContext(false, params.UserRoleViewer, grant(params.UserRoleOperator, grantedRepo)),
+			entity:   otherRepo,
+			expected: params.UserRoleViewer,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			role := EntityRole(tc.ctx, tc.entity)
+			require.Equal(t, tc.expected, role)
+			require.Equal(t, tc.expected.CanView(), CanViewEntity(tc.ctx, tc.entity))
+			require.Equal(t, tc.expected.CanManage(), CanManageEntity(tc.ctx, tc.entity))
+		})
+	}
+}
+
+func TestPoolRoles(t *testing.T) {
+	grantedPool := params.Pool{ID: "pool-1", RepoID: grantedRepo.ID}
+	otherPool := params.Pool{ID: "pool-2", RepoID: otherRepo.ID}
+	orphanPool := params.Pool{ID: "pool-3"}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		pool      params.Pool
+		canView   bool
+		canManage bool
+	}{
+		{
+			name:      "admin",
+			ctx:       roleContext(true, ""),
+			pool:      otherPool,
+			canView:   true,
+			canManage: true,
+		},
+		{
+			name:    "viewer grant on the entity of the pool",
+			ctx:     roleContext(false, "", grant(params.UserRoleViewer, grantedRepo)),
+			pool:    grantedPool,
+			canView: true,
+		},
+		{
+			name:      "operator grant on the entity of the pool",
+			ctx:       roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			pool:      grantedPool,
+			canView:   true,
+			canManage: true,
+		},
+		{
+			name: "operator grant on another entity",
+			ctx:  roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			pool: otherPool,
+		},
+		{
+			name: "pool without entity is only visible to admins",
+			ctx:  roleContext(false, params.UserRoleOperator),
+			pool: orphanPool,
+		},
+		{
+			name:      "admin can see pools without entity",
+			ctx:       roleContext(true, ""),
+			pool:      orphanPool,
+			canView:   true,
+			canManage: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.canView, CanViewPool(tc.ctx, tc.pool))
+			require.Equal(t, tc.canManage, CanManagePool(tc.ctx, tc.pool))
+		})
+	}
+}
+
+func TestRoleMiddlewares(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		roleStatus  int
+		adminStatus int
+	}{
+		{
+			name:        "admin",
+			ctx:         roleContext(true, ""),
+			roleStatus:  http.StatusOK,
+			adminStatus: http.StatusOK,
+		},
+		{
+			name:        "operator",
+			ctx:         roleContext(false, params.UserRoleOperator),
+			roleStatus:  http.StatusOK,
+			adminStatus: http.StatusForbidden,
+		},
+		{
+			name:        "viewer",
+			ctx:         roleContext(false, params.UserRoleViewer),
+			roleStatus:  http.StatusOK,
+			adminStatus: http.StatusForbidden,
+		},
+		{
+			name:        "grants only",
+			ctx:         roleContext(false, "", grant(params.UserRoleOperator, grantedRepo)),
+			roleStatus:  http.StatusOK,
+			adminStatus: http.StatusForbidden,
+		},
+		{
+			name:        "no role",
+			ctx:         roleContext(false, ""),
+			roleStatus:  http.StatusForbidden,
+			adminStatus: http.StatusForbidden,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/controller", nil).WithContext(tc.ctx)
+
+			w := httptest.NewRecorder()
+			RoleRequiredMiddleware(next).ServeHTTP(w, req)
+			require.Equal(t, tc.roleStatus, w.Code)
+
+			w = httptest.NewRecorder()
+			AdminRequiredMiddleware(next).ServeHTTP(w, req)
+			require.Equal(t, tc.adminStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+)
+
+// recordUserAudit records an action an admin took on a user.
+func (a *Authenticator) recordUserAudit(ctx context.Context, action params.AuditAction, user params.User, reason string) {
+	record := params.AuditRecord{
+		Action:   action,
+		UserID:   user.ID,
+		Username: user.Username,
+		Reason:   reason,
+	}
+	if _, err := a.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
+
+// CreateUser creates a new user, with an optional role on all entities. Users that
+// are created without a role can be given roles on single entities.
+func (a *Authenticator) CreateUser(ctx context.Context, param params.NewUserParams) (params.User, error) {
+	if !IsAdmin(ctx) {
+		return params.User{}, runnerErrors.ErrUnauthorized
+	}
+	if err := param.ValidateRole(); err != nil {
+		return params.User{}, err
+	}
+
+	param.IsAdmin = false
+	param.Enabled = true
+
+	user, err := a.createUser(ctx, param)
+	if err != nil {
+		return params.User{}, err
+	}
+	a.recordUserAudit(ctx, params.AuditActionUserCreated, user, fmt.Sprintf("created by %s with role %q", Username(ctx), user.Role))
+	return user, nil
+}
+
+// CreateRoleGrant gives a user a role on a repository, organization or enterprise.
+func (a *Authenticator) CreateRoleGrant(ctx context.Context, username string, param params.CreateRoleGrantParams) (params.RoleGrant, error) {
+	if !IsAdmin(ctx) {
+		return params.RoleGrant{}, runnerErrors.ErrUnauthorized
+	}
+	if err := param.Validate(); err != nil {
+		return params.RoleGrant{}, err
+	}
+
+	user, err := a.store.GetUser(ctx, username)
+	if err != nil {
+		return params.RoleGrant{}, errors.Wrap(err, "fetching user")
+	}
+	if user.IsAdmin {
+		return params.RoleGrant{}, runnerErrors.NewBadRequestError("the admin user already has access to all entities")
+	}
+
+	grant, err := a.store.CreateRoleGrant(ctx, user.Username, param)
+	if err != nil {
+		return params.RoleGrant{}, errors.Wrap(err, "creating role grant")
+	}
+	a.recordUserAudit(ctx, params.AuditActionRoleGranted, user, fmt.Sprintf(
+		"%s granted role %s on %s %s", Username(ctx), grant.Role, grant.EntityType, grant.EntityID))
+	return grant, nil
+}
+
+// DeleteRoleGrant removes a role grant of a user.
+func (a *Authenticator) DeleteRoleGrant(ctx context.Context, username, grantID string) error {
+	if !IsAdmin(ctx) {
+		return runnerErrors.ErrUnauthorized
+	}
+
+	user, err := a.store.GetUser(ctx, username)
+	if err != nil {
+		return errors.Wrap(err, "fetching user")
+	}
+	if err := a.store.DeleteRoleGrant(ctx, user.Username, grantID); err != nil {
+		return errors.Wrap(err, "deleting role grant")
+	}
+	a.recordUserAudit(ctx, params.AuditActionRoleRevoked, user, fmt.Sprintf("%s removed role grant %s", Username(ctx), grantID))
+	return nil
+}
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
@@ -96,6 +97,16 @@ up the GARM controller URLs as:
     --webhook-url=https://garm.example.com/webhooks \
     --metadata-url=https://garm.example.com/api/v1/metadata \
     --callback-url=https://garm.example.com/api/v1/callbacks
+
+Runners spawned in other regions or networks may need to reach GARM through
+different endpoints. Named URL sets can be defined for them and selected per
+pool using "garm-cli pool update --url-set=<name>":
+
+  garm-cli controller update \
+    --url-set=eu,https://garm-eu.example.com/api/v1/metadata,https://garm-eu.example.com/api/v1/callbacks
+
+The --url-set flag can be repeated and replaces all existing URL sets. Pass an
+empty value to remove all URL sets.
 `,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, _ []string) error {
@@ -118,9 +129,17 @@ up the GARM controller URLs as:
 			params.MinimumJobAgeBackoff = &minimumJobAgeBackoff
 		}
 
-		if params.WebhookURL == nil && params.MetadataURL == nil && params.CallbackURL == nil && params.MinimumJobAgeBackoff == nil {
+		if cmd.Flags().Changed("url-set") {
+			sets, err := parseURLSets(controllerURLSets)
+			if err != nil {
+				return err
+			}
+			params.URLSets = &sets
+		}
+
+		if params.WebhookURL == nil && params.MetadataURL == nil && params.CallbackURL == nil && params.MinimumJobAgeBackoff == nil && params.URLSets == nil {
 			cmd.Help()
-			return fmt.Errorf("at least one of minimum-job-age-backoff, metadata-url, callback-url or webhook-url must be provided")
+			return fmt.Errorf("at least one of minimum-job-age-backoff, metadata-url, callback-url, webhook-url or url-set must be provided")
 		}
 
 		updateUrlsReq := apiClientController.NewUpdateControllerParams()
@@ -160,10 +179,33 @@ func renderControllerInfoTable(info params.ControllerInfo) string {
 	t.AppendRow(table.Row{"Webhook Base URL", info.WebhookURL})
 	t.AppendRow(table.Row{"Controller Webhook URL", info.ControllerWebhookURL})
 	t.AppendRow(table.Row{"Minimum Job Age Backoff", info.MinimumJobAgeBackoff})
+	for _, set := range info.URLSets {
+		t.AppendRow(table.Row{fmt.Sprintf("URL Set %s", set.Name), fmt.Sprintf("metadata: %s\ncallback: %s", set.MetadataURL, set.CallbackURL)})
+	}
 	t.AppendRow(table.Row{"Version", serverVersion})
 	return t.Render()
 }
 
+func parseURLSets(vals []string) ([]params.ControllerURLSet, error) {
+	ret := []params.ControllerURLSet{}
+	for _, val := range vals {
+		val = strings.TrimSpace(val)
+		if val == "" {
+			continue
+		}
+		fields := strings.Split(val, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid url set %q, expected name,metadata_url,callback_url", val)
+		}
+		ret = append(ret, params.ControllerURLSet{
+			Name:        strings.TrimSpace(fields[0]),
+			MetadataURL: strings.TrimSpace(fields[1]),
+			CallbackURL: strings.TrimSpace(fields[2]),
+		})
+	}
+	return ret, nil
+}
+
 func formatInfo(info params.ControllerInfo) error {
 	if outputFormat == common.OutputFormatJSON {
 		printAsJSON(info)
@@ -178,6 +220,7 @@ func init() {
 	controllerUpdateCmd.Flags().StringVarP(&callbackURL, "callback-url", "c", "", "The callback URL for the controller (ie. https://garm.example.com/api/v1/callbacks)")
 	controllerUpdateCmd.Flags().StringVarP(&webhookURL, "webhook-url", "w", "", "The webhook URL for the controller (ie. https://garm.example.com/webhooks)")
 	controllerUpdateCmd.Flags().UintVarP(&minimumJobAgeBackoff, "minimum-job-age-backoff", "b", 0, "The minimum job age backoff for the controller")
+	controllerUpdateCmd.Flags().StringArrayVar(&controllerURLSets, "url-set", nil, "A named set of runner URLs in the form name,metadata_url,callback_url. Can be repeated; replaces all existing URL sets. Pass an empty value to remove all URL sets.")
 
 	controllerCmd.AddCommand(
 		controllerShowCmd,
@@ -0,0 +1,181 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	apiParams "github.com/cloudbase/garm/apiserver/params"
+	apiClient "github.com/cloudbase/garm/client"
+	"github.com/cloudbase/garm/cmd/garm-cli/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var (
+	controllerConfigFile   string
+	controllerConfigDryRun bool
+	controllerConfigPrune  bool
+)
+
+var controllerExportConfigCmd = &cobra.Command{
+	Use:   "export-config",
+	Short: "Export the controller config",
+	Long: `Export the configuration of all repositories, organizations and enterprises,
+their pools and the credentials they use, as YAML.
+
+Webhook secrets are replaced with a placeholder and the secrets of the
+credentials are left out, so the exported config can be stored in git.`,
+	SilenceUsage: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if needsInit {
+			return errNeedsInitError
+		}
+
+		cfg, err := doRawAPIRequest(http.MethodGet, "/config", nil, nil)
+		if err != nil {
+			return err
+		}
+		if controllerConfigFile == "" {
+			fmt.Print(string(cfg))
+			return nil
+		}
+		return os.WriteFile(controllerConfigFile, cfg, 0o600)
+	},
+}
+
+var controllerApplyConfigCmd = &cobra.Command{
+	Use:   "apply-config",
+	Short: "Apply a controller config",
+	Long: `Apply a controller config, as exported by export-config.
+
+Repositories, organizations, enterprises and pools are created and updated
+until they match the config. Entities and pools that are not in the config
+are only removed if --prune is set. Credentials must already exist, as the
+config does not hold their secrets.
+
+Applying the same config again makes no changes. Use --dry-run to list the
+changes without making them.`,
+	SilenceUsage: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if needsInit {
+			return errNeedsInitError
+		}
+
+		cfg, err := os.ReadFile(controllerConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+
+		query := url.Values{}
+		query.Set("dry_run", strconv.FormatBool(controllerConfigDryRun))
+		query.Set("prune", strconv.FormatBool(controllerConfigPrune))
+		response, err := doRawAPIRequest(http.MethodPost, "/config/apply", query, bytes.NewReader(cfg))
+		if err != nil {
+			return err
+		}
+		var result params.ControllerConfigApplyResult
+		if err := json.Unmarshal(response, &result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		formatConfigApplyResult(result)
+		return nil
+	},
+}
+
+// doRawAPIRequest calls an API endpoint that sends or accepts YAML documents, which are
+// not handled by the generated API client.
+func doRawAPIRequest(method, path string, query url.Values, body io.Reader) ([]byte, error) {
+	apiURL, err := url.JoinPath(mgr.BaseURL, apiClient.DefaultBasePath, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+	if len(query) > 0 {
+		apiURL = apiURL + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+mgr.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiParams.APIErrorResponse
+		if err := json.Unmarshal(data, &apiErr); err == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s: %s", apiErr.Error, apiErr.Details)
+		}
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return data, nil
+}
+
+func init() {
+	controllerExportConfigCmd.Flags().StringVar(&controllerConfigFile, "file", "", "Write the config to this file instead of the standard output.")
+
+	controllerApplyConfigCmd.Flags().StringVar(&controllerConfigFile, "file", "", "The config file to apply.")
+	controllerApplyConfigCmd.Flags().BoolVar(&controllerConfigDryRun, "dry-run", false, "Only list the changes that would be made.")
+	controllerApplyConfigCmd.Flags().BoolVar(&controllerConfigPrune, "prune", false, "Remove the entities and pools that are not in the config.")
+	controllerApplyConfigCmd.MarkFlagRequired("file") //nolint
+
+	controllerCmd.AddCommand(
+		controllerExportConfigCmd,
+		controllerApplyConfigCmd,
+	)
+}
+
+func formatConfigApplyResult(result params.ControllerConfigApplyResult) {
+	if outputFormat == common.OutputFormatJSON {
+		printAsJSON(result)
+		return
+	}
+	if len(result.Changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Action", "Type", "Name", "ID", "Fields"})
+	for _, change := range result.Changes {
+		t.AppendRow(table.Row{change.Action, change.ResourceType, change.Name, change.ID, strings.Join(change.Fields, ", ")})
+		t.AppendSeparator()
+	}
+	fmt.Println(t.Render())
+	if result.DryRun {
+		fmt.Println("Dry run, no changes were made")
+	}
+}
@@ -149,7 +149,7 @@ var enterpriseUpdateCmd = &cobra.Command{
 	Short:        "Update enterprise",
 	Long:         `Update enterprise credentials or webhook secret.`,
 	SilenceUsage: true,
-	RunE: func(_ *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if needsInit {
 			return errNeedsInitError
 		}
@@ -166,6 +166,15 @@ var enterpriseUpdateCmd = &cobra.Command{
 			WebhookSecret:    repoWebhookSecret,
 			CredentialsName:  repoCreds,
 			PoolBalancerType: params.PoolBalancerType(poolBalancerType),
+
+			WebhookSecretGracePeriod: webhookSecretGracePeriod,
+		}
+		if cmd.Flags().Changed("max-concurrent-jobs") {
+			updateEnterpriseReq.Body.MaxConcurrentJobs = &maxConcurrentJobs
+		}
+		if cmd.Flags().Changed("routing-rule") {
+			rules := parseRoutingRules(routingRules)
+			updateEnterpriseReq.Body.RoutingRules = &rules
 		}
 		updateEnterpriseReq.EnterpriseID = args[0]
 		response, err := apiCli.Enterprises.UpdateEnterprise(updateEnterpriseReq, authToken)
@@ -188,6 +197,9 @@ func init() {
 	enterpriseUpdateCmd.Flags().StringVar(&enterpriseWebhookSecret, "webhook-secret", "", "The webhook secret for this enterprise")
 	enterpriseUpdateCmd.Flags().StringVar(&enterpriseCreds, "credentials", "", "Credentials name. See credentials list.")
 	enterpriseUpdateCmd.Flags().StringVar(&poolBalancerType, "pool-balancer-type", "", "The balancing strategy to use when creating runners in pools matching requested labels.")
+	enterpriseUpdateCmd.Flags().UintVar(&maxConcurrentJobs, "max-concurrent-jobs", 0, "The maximum number of jobs this enterprise can have runners for at the same time. Set to 0 to remove the limit.")
+	enterpriseUpdateCmd.Flags().UintVar(&webhookSecretGracePeriod, "webhook-secret-grace-period", 0, "The number of minutes during which webhooks signed with the current secret are still accepted, after it is replaced with --webhook-secret. Use this to update the secret in GitHub without dropping webhooks.")
+	enterpriseUpdateCmd.Flags().StringArrayVar(&routingRules, "routing-rule", nil, "A rule used to choose the pool in which a runner is created. One of: prefer_idle, least_loaded or provider_order=provider1,provider2. Can be repeated; rules are applied in order. Pass an empty value to remove all rules.")
 
 	enterpriseCmd.AddCommand(
 		enterpriseListCmd,
@@ -228,6 +240,12 @@ func formatOneEnterprise(enterprise params.Enterprise) {
 	t.AppendRow(table.Row{"Name", enterprise.Name})
 	t.AppendRow(table.Row{"Endpoint", enterprise.Endpoint.Name})
 	t.AppendRow(table.Row{"Pool balancer type", enterprise.GetBalancerType()})
+	if enterprise.MaxConcurrentJobs > 0 {
+		t.AppendRow(table.Row{"Max concurrent jobs", enterprise.MaxConcurrentJobs})
+	}
+	if len(enterprise.RoutingRules) > 0 {
+		t.AppendRow(table.Row{"Routing rules", formatRoutingRules(enterprise.RoutingRules)})
+	}
 	t.AppendRow(table.Row{"Credentials", enterprise.Credentials.Name})
 	t.AppendRow(table.Row{"Pool manager running", enterprise.PoolManagerStatus.IsRunning})
 	if !enterprise.PoolManagerStatus.IsRunning {
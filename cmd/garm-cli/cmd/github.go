@@ -9,6 +9,9 @@ var (
 	endpointAPIBaseURL  string
 	endpointCACertPath  string
 	endpointDescription string
+
+	endpointToolsMetadataURL         string
+	endpointUseInternalToolsMetadata bool
 )
 
 // githubCmd represents the the github command. This command has a set
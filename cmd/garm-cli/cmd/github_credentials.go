@@ -375,6 +375,17 @@ func formatOneGithubCredential(cred params.GithubCredentials) {
 	t.AppendRow(table.Row{"Type", cred.AuthType})
 	t.AppendRow(table.Row{"Endpoint", cred.Endpoint.Name})
 
+	if cred.TokenSummary != nil {
+		t.AppendRow(table.Row{"", ""})
+		t.AppendRow(table.Row{"Token checked at", cred.TokenSummary.CheckedAt})
+		if cred.TokenSummary.ExpiresAt != nil {
+			t.AppendRow(table.Row{"Token expires at", *cred.TokenSummary.ExpiresAt})
+		}
+		for _, repo := range cred.TokenSummary.Repositories {
+			t.AppendRow(table.Row{"Token repositories", repo})
+		}
+	}
+
 	if len(cred.Repositories) > 0 {
 		t.AppendRow(table.Row{"", ""})
 		for _, repo := range cred.Repositories {
@@ -168,6 +168,14 @@ var githubEndpointUpdateCmd = &cobra.Command{
 			updateParams.APIBaseURL = &endpointAPIBaseURL
 		}
 
+		if cmd.Flags().Changed("tools-metadata-url") {
+			updateParams.ToolsMetadataURL = &endpointToolsMetadataURL
+		}
+
+		if cmd.Flags().Changed("use-internal-tools-metadata") {
+			updateParams.UseInternalToolsMetadata = &endpointUseInternalToolsMetadata
+		}
+
 		newGHEndpointUpdateReq := apiClientEndpoints.NewUpdateGithubEndpointParams()
 		newGHEndpointUpdateReq.Name = args[0]
 		newGHEndpointUpdateReq.Body = updateParams
@@ -188,6 +196,8 @@ func init() {
 	githubEndpointCreateCmd.Flags().StringVar(&endpointUploadURL, "upload-url", "", "Upload URL of the GitHub endpoint")
 	githubEndpointCreateCmd.Flags().StringVar(&endpointAPIBaseURL, "api-base-url", "", "API Base URL of the GitHub endpoint")
 	githubEndpointCreateCmd.Flags().StringVar(&endpointCACertPath, "ca-cert-path", "", "CA Cert Path of the GitHub endpoint")
+	githubEndpointCreateCmd.Flags().StringVar(&endpointToolsMetadataURL, "tools-metadata-url", "", "URL of a JSON document listing the runner tools for this endpoint. Useful for air-gapped installs.")
+	githubEndpointCreateCmd.Flags().BoolVar(&endpointUseInternalToolsMetadata, "use-internal-tools-metadata", false, "Ignore the tools metadata URL and fetch the tools from the GitHub API")
 
 	githubEndpointCreateCmd.MarkFlagRequired("name")
 	githubEndpointCreateCmd.MarkFlagRequired("base-url")
@@ -199,6 +209,8 @@ func init() {
 	githubEndpointUpdateCmd.Flags().StringVar(&endpointUploadURL, "upload-url", "", "Upload URL of the GitHub endpoint")
 	githubEndpointUpdateCmd.Flags().StringVar(&endpointAPIBaseURL, "api-base-url", "", "API Base URL of the GitHub endpoint")
 	githubEndpointUpdateCmd.Flags().StringVar(&endpointCACertPath, "ca-cert-path", "", "CA Cert Path of the GitHub endpoint")
+	githubEndpointUpdateCmd.Flags().StringVar(&endpointToolsMetadataURL, "tools-metadata-url", "", "URL of a JSON document listing the runner tools for this endpoint. Set to an empty string to remove it.")
+	githubEndpointUpdateCmd.Flags().BoolVar(&endpointUseInternalToolsMetadata, "use-internal-tools-metadata", false, "Ignore the tools metadata URL and fetch the tools from the GitHub API")
 
 	githubEndpointCmd.AddCommand(
 		githubEndpointListCmd,
@@ -246,6 +258,9 @@ func parseCreateParams() (params.CreateGithubEndpointParams, error) {
 		APIBaseURL:    endpointAPIBaseURL,
 		Description:   endpointDescription,
 		CACertBundle:  certBundleBytes,
+
+		ToolsMetadataURL:         endpointToolsMetadataURL,
+		UseInternalToolsMetadata: endpointUseInternalToolsMetadata,
 	}
 	return ret, nil
 }
@@ -277,6 +292,10 @@ func formatOneEndpoint(endpoint params.GithubEndpoint) {
 	t.AppendRow([]interface{}{"Base URL", endpoint.BaseURL})
 	t.AppendRow([]interface{}{"Upload URL", endpoint.UploadBaseURL})
 	t.AppendRow([]interface{}{"API Base URL", endpoint.APIBaseURL})
+	if endpoint.ToolsMetadataURL != "" {
+		t.AppendRow([]interface{}{"Tools Metadata URL", endpoint.ToolsMetadataURL})
+		t.AppendRow([]interface{}{"Use Internal Tools Metadata", endpoint.UseInternalToolsMetadata})
+	}
 	if len(endpoint.CACertBundle) > 0 {
 		t.AppendRow([]interface{}{"CA Cert Bundle", string(endpoint.CACertBundle)})
 	}
@@ -63,7 +63,7 @@ func formatJobs(jobs []params.Job) {
 		return
 	}
 	t := table.NewWriter()
-	header := table.Row{"ID", "Name", "Status", "Conclusion", "Runner Name", "Repository", "Requested Labels", "Locked by"}
+	header := table.Row{"ID", "Name", "Status", "Conclusion", "Runner Name", "Repository", "Requested Labels", "Locked by", "Decision"}
 	t.AppendHeader(header)
 
 	for _, job := range jobs {
@@ -72,7 +72,14 @@ func formatJobs(jobs []params.Job) {
 		if job.LockedBy != uuid.Nil {
 			lockedBy = job.LockedBy.String()
 		}
-		t.AppendRow(table.Row{job.ID, job.Name, job.Status, job.Conclusion, job.RunnerName, repo, strings.Join(job.Labels, " "), lockedBy})
+		decision := ""
+		if job.Decision != nil {
+			decision = string(job.Decision.Decision)
+			if job.Decision.Reason != "" {
+				decision = fmt.Sprintf("%s: %s", decision, job.Decision.Reason)
+			}
+		}
+		t.AppendRow(table.Row{job.ID, job.Name, job.Status, job.Conclusion, job.RunnerName, repo, strings.Join(job.Labels, " "), lockedBy, decision})
 		t.AppendSeparator()
 	}
 	fmt.Println(t.Render())
@@ -34,6 +34,7 @@ var (
 	insecureOrgWebhook     bool
 	keepOrgWebhook         bool
 	installOrgWebhook      bool
+	orgEntityPath          bool
 )
 
 // organizationCmd represents the organization command
@@ -77,6 +78,7 @@ var orgWebhookInstallCmd = &cobra.Command{
 		installWebhookReq := apiClientOrgs.NewInstallOrgWebhookParams()
 		installWebhookReq.OrgID = args[0]
 		installWebhookReq.Body.InsecureSSL = insecureOrgWebhook
+		installWebhookReq.Body.EntityWebhookPath = orgEntityPath
 		installWebhookReq.Body.WebhookEndpointType = params.WebhookEndpointDirect
 
 		response, err := apiCli.Organizations.InstallOrgWebhook(installWebhookReq, authToken)
@@ -201,7 +203,7 @@ var orgUpdateCmd = &cobra.Command{
 	Short:        "Update organization",
 	Long:         `Update organization credentials or webhook secret.`,
 	SilenceUsage: true,
-	RunE: func(_ *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if needsInit {
 			return errNeedsInitError
 		}
@@ -218,6 +220,15 @@ var orgUpdateCmd = &cobra.Command{
 			WebhookSecret:    orgWebhookSecret,
 			CredentialsName:  orgCreds,
 			PoolBalancerType: params.PoolBalancerType(poolBalancerType),
+
+			WebhookSecretGracePeriod: webhookSecretGracePeriod,
+		}
+		if cmd.Flags().Changed("max-concurrent-jobs") {
+			updateOrgReq.Body.MaxConcurrentJobs = &maxConcurrentJobs
+		}
+		if cmd.Flags().Changed("routing-rule") {
+			rules := parseRoutingRules(routingRules)
+			updateOrgReq.Body.RoutingRules = &rules
 		}
 		updateOrgReq.OrgID = args[0]
 		response, err := apiCli.Organizations.UpdateOrg(updateOrgReq, authToken)
@@ -320,8 +331,12 @@ func init() {
 	orgUpdateCmd.Flags().StringVar(&orgWebhookSecret, "webhook-secret", "", "The webhook secret for this organization")
 	orgUpdateCmd.Flags().StringVar(&orgCreds, "credentials", "", "Credentials name. See credentials list.")
 	orgUpdateCmd.Flags().StringVar(&poolBalancerType, "pool-balancer-type", "", "The balancing strategy to use when creating runners in pools matching requested labels.")
+	orgUpdateCmd.Flags().UintVar(&maxConcurrentJobs, "max-concurrent-jobs", 0, "The maximum number of jobs this organization can have runners for at the same time. Set to 0 to remove the limit.")
+	orgUpdateCmd.Flags().UintVar(&webhookSecretGracePeriod, "webhook-secret-grace-period", 0, "The number of minutes during which webhooks signed with the current secret are still accepted, after it is replaced with --webhook-secret. Use this to update the secret in GitHub without dropping webhooks.")
+	orgUpdateCmd.Flags().StringArrayVar(&routingRules, "routing-rule", nil, "A rule used to choose the pool in which a runner is created. One of: prefer_idle, least_loaded or provider_order=provider1,provider2. Can be repeated; rules are applied in order. Pass an empty value to remove all rules.")
 
 	orgWebhookInstallCmd.Flags().BoolVar(&insecureOrgWebhook, "insecure", false, "Ignore self signed certificate errors.")
+	orgWebhookInstallCmd.Flags().BoolVar(&orgEntityPath, "entity-path", false, "Install the webhook using a URL unique to this organization.")
 	orgWebhookCmd.AddCommand(
 		orgWebhookInstallCmd,
 		orgWebhookUninstallCmd,
@@ -368,16 +383,31 @@ func formatOneOrganization(org params.Organization) {
 	t.AppendRow(table.Row{"Name", org.Name})
 	t.AppendRow(table.Row{"Endpoint", org.Endpoint.Name})
 	t.AppendRow(table.Row{"Pool balancer type", org.GetBalancerType()})
+	if org.MaxConcurrentJobs > 0 {
+		t.AppendRow(table.Row{"Max concurrent jobs", org.MaxConcurrentJobs})
+	}
+	if len(org.RoutingRules) > 0 {
+		t.AppendRow(table.Row{"Routing rules", formatRoutingRules(org.RoutingRules)})
+	}
 	t.AppendRow(table.Row{"Credentials", org.CredentialsName})
 	t.AppendRow(table.Row{"Pool manager running", org.PoolManagerStatus.IsRunning})
 	if !org.PoolManagerStatus.IsRunning {
 		t.AppendRow(table.Row{"Failure reason", org.PoolManagerStatus.FailureReason})
 	}
+	if org.PendingWebhookInstall != nil {
+		t.AppendRow(table.Row{"Pending webhook install", true})
+	}
 	if len(org.Pools) > 0 {
 		for _, pool := range org.Pools {
 			t.AppendRow(table.Row{"Pools", pool.ID}, rowConfigAutoMerge)
 		}
 	}
+
+	if len(org.Events) > 0 {
+		for _, event := range org.Events {
+			t.AppendRow(table.Row{"Events", fmt.Sprintf("%s [%s]: %s", event.CreatedAt.Format("2006-01-02T15:04:05"), event.EventLevel, event.Message)}, rowConfigAutoMerge)
+		}
+	}
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{Number: 1, AutoMerge: true},
 		{Number: 2, AutoMerge: false},
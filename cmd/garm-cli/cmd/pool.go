@@ -18,8 +18,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -53,8 +57,36 @@ var (
 	poolAll                    bool
 	poolGitHubRunnerGroup      string
 	priority                   uint
+	poolWipeWorkspace          bool
+	poolPruneDockerImagesDays  uint
+	poolDNSServers             string
+	poolSearchDomains          string
+	poolNTPServers             string
+	poolHTTPProxy              string
+	poolHTTPSProxy             string
+	poolNoProxy                string
+	poolIdleDetectionWindow    uint
+	poolScaleDownGracePeriod   uint
+	poolScaleDownFactor        float64
+	poolCapacityWarning        uint
+	poolAutoscaleMaxBurst      uint
+	poolAutoscaleCooldown      uint
+	poolMaxCreatesPerMinute    uint
+	poolCreateJitter           uint
+	poolDraining               bool
+	poolRollingUpdateBatchSize uint
+	poolRollingUpdatePause     uint
+	poolURLSet                 string
+	poolInstanceMetadata       []string
+	poolWarmImageScriptFile    string
+	poolWarmImageRefresh       uint
+	poolDryRun                 bool
 )
 
+var poolNetworkSettingsFlags = []string{
+	"dns-servers", "search-domains", "ntp-servers", "http-proxy", "https-proxy", "no-proxy",
+}
+
 type poolsPayloadGetter interface {
 	GetPayload() params.Pools
 }
@@ -225,6 +257,45 @@ var poolAddCmd = &cobra.Command{
 			RunnerBootstrapTimeout: poolRunnerBootstrapTimeout,
 			GitHubRunnerGroup:      poolGitHubRunnerGroup,
 			Priority:               priority,
+			IdleDetectionWindow:    poolIdleDetectionWindow,
+			ScaleDownGracePeriod:   poolScaleDownGracePeriod,
+			ScaleDownFactor:        poolScaleDownFactor,
+
+			CapacityWarningThreshold: poolCapacityWarning,
+			AutoscaleMaxBurst:        poolAutoscaleMaxBurst,
+			AutoscaleCooldown:        poolAutoscaleCooldown,
+			MaxCreatesPerMinute:      poolMaxCreatesPerMinute,
+			CreateJitter:             poolCreateJitter,
+			RollingUpdateBatchSize:   poolRollingUpdateBatchSize,
+			RollingUpdatePause:       poolRollingUpdatePause,
+			URLSet:                   poolURLSet,
+		}
+
+		if poolWipeWorkspace || poolPruneDockerImagesDays > 0 {
+			newPoolParams.CleanupPolicy = &params.CleanupPolicy{
+				WipeWorkspace:                  poolWipeWorkspace,
+				PruneDockerImagesOlderThanDays: poolPruneDockerImagesDays,
+			}
+		}
+
+		if networkSettingsFlagsChanged(cmd) {
+			newPoolParams.NetworkSettings = networkSettingsFromFlags(cmd, params.NetworkSettings{})
+		}
+
+		if cmd.Flags().Changed("instance-metadata") {
+			metadata, err := parseInstanceMetadata(poolInstanceMetadata)
+			if err != nil {
+				return err
+			}
+			newPoolParams.InstanceMetadata = metadata
+		}
+
+		if warmImageFlagsChanged(cmd) {
+			settings, err := warmImageFromFlags(cmd, params.WarmImageSettings{})
+			if err != nil {
+				return err
+			}
+			newPoolParams.WarmImage = settings
 		}
 
 		if cmd.Flags().Changed("extra-specs") {
@@ -253,17 +324,17 @@ var poolAddCmd = &cobra.Command{
 			newRepoPoolReq := apiClientRepos.NewCreateRepoPoolParams()
 			newRepoPoolReq.RepoID = poolRepository
 			newRepoPoolReq.Body = newPoolParams
-			response, err = apiCli.Repositories.CreateRepoPool(newRepoPoolReq, authToken)
+			response, err = apiCli.Repositories.CreateRepoPool(newRepoPoolReq, authToken, poolDryRunOptions[apiClientRepos.ClientOption]()...)
 		} else if cmd.Flags().Changed("org") {
 			newOrgPoolReq := apiClientOrgs.NewCreateOrgPoolParams()
 			newOrgPoolReq.OrgID = poolOrganization
 			newOrgPoolReq.Body = newPoolParams
-			response, err = apiCli.Organizations.CreateOrgPool(newOrgPoolReq, authToken)
+			response, err = apiCli.Organizations.CreateOrgPool(newOrgPoolReq, authToken, poolDryRunOptions[apiClientOrgs.ClientOption]()...)
 		} else if cmd.Flags().Changed("enterprise") {
 			newEnterprisePoolReq := apiClientEnterprises.NewCreateEnterprisePoolParams()
 			newEnterprisePoolReq.EnterpriseID = poolEnterprise
 			newEnterprisePoolReq.Body = newPoolParams
-			response, err = apiCli.Enterprises.CreateEnterprisePool(newEnterprisePoolReq, authToken)
+			response, err = apiCli.Enterprises.CreateEnterprisePool(newEnterprisePoolReq, authToken, poolDryRunOptions[apiClientEnterprises.ClientOption]()...)
 		} else {
 			cmd.Help() //nolint
 			os.Exit(0)
@@ -353,6 +424,107 @@ explicitly remove them using the runner delete command.
 			poolUpdateParams.RunnerBootstrapTimeout = &poolRunnerBootstrapTimeout
 		}
 
+		if cmd.Flags().Changed("idle-detection-window") {
+			poolUpdateParams.IdleDetectionWindow = &poolIdleDetectionWindow
+		}
+
+		if cmd.Flags().Changed("scale-down-grace-period") {
+			poolUpdateParams.ScaleDownGracePeriod = &poolScaleDownGracePeriod
+		}
+		if cmd.Flags().Changed("scale-down-factor") {
+			poolUpdateParams.ScaleDownFactor = &poolScaleDownFactor
+		}
+		if cmd.Flags().Changed("capacity-warning-threshold") {
+			poolUpdateParams.CapacityWarningThreshold = &poolCapacityWarning
+		}
+		if cmd.Flags().Changed("autoscale-max-burst") {
+			poolUpdateParams.AutoscaleMaxBurst = &poolAutoscaleMaxBurst
+		}
+		if cmd.Flags().Changed("autoscale-cooldown") {
+			poolUpdateParams.AutoscaleCooldown = &poolAutoscaleCooldown
+		}
+		if cmd.Flags().Changed("max-creates-per-minute") {
+			poolUpdateParams.MaxCreatesPerMinute = &poolMaxCreatesPerMinute
+		}
+		if cmd.Flags().Changed("create-jitter") {
+			poolUpdateParams.CreateJitter = &poolCreateJitter
+		}
+		if cmd.Flags().Changed("rolling-update-batch-size") {
+			poolUpdateParams.RollingUpdateBatchSize = &poolRollingUpdateBatchSize
+		}
+		if cmd.Flags().Changed("rolling-update-pause") {
+			poolUpdateParams.RollingUpdatePause = &poolRollingUpdatePause
+		}
+		if cmd.Flags().Changed("draining") {
+			poolUpdateParams.Draining = &poolDraining
+		}
+		if cmd.Flags().Changed("url-set") {
+			poolUpdateParams.URLSet = &poolURLSet
+		}
+
+		if cmd.Flags().Changed("wipe-workspace") || cmd.Flags().Changed("prune-docker-images-older-than") {
+			// The API replaces the whole cleanup policy, so we start from the current one.
+			getPoolReq := apiClientPools.NewGetPoolParams()
+			getPoolReq.PoolID = args[0]
+			currentPool, err := apiCli.Pools.GetPool(getPoolReq, authToken)
+			if err != nil {
+				return err
+			}
+			policy := params.CleanupPolicy{}
+			if currentPool.Payload.CleanupPolicy != nil {
+				policy = *currentPool.Payload.CleanupPolicy
+			}
+			if cmd.Flags().Changed("wipe-workspace") {
+				policy.WipeWorkspace = poolWipeWorkspace
+			}
+			if cmd.Flags().Changed("prune-docker-images-older-than") {
+				policy.PruneDockerImagesOlderThanDays = poolPruneDockerImagesDays
+			}
+			poolUpdateParams.CleanupPolicy = &policy
+		}
+
+		if networkSettingsFlagsChanged(cmd) {
+			// The API replaces all network settings, so we start from the current ones.
+			getPoolReq := apiClientPools.NewGetPoolParams()
+			getPoolReq.PoolID = args[0]
+			currentPool, err := apiCli.Pools.GetPool(getPoolReq, authToken)
+			if err != nil {
+				return err
+			}
+			settings := params.NetworkSettings{}
+			if currentPool.Payload.NetworkSettings != nil {
+				settings = *currentPool.Payload.NetworkSettings
+			}
+			poolUpdateParams.NetworkSettings = networkSettingsFromFlags(cmd, settings)
+		}
+
+		if cmd.Flags().Changed("instance-metadata") {
+			metadata, err := parseInstanceMetadata(poolInstanceMetadata)
+			if err != nil {
+				return err
+			}
+			poolUpdateParams.InstanceMetadata = &metadata
+		}
+
+		if warmImageFlagsChanged(cmd) {
+			// The API replaces all warm image settings, so we start from the current ones.
+			getPoolReq := apiClientPools.NewGetPoolParams()
+			getPoolReq.PoolID = args[0]
+			currentPool, err := apiCli.Pools.GetPool(getPoolReq, authToken)
+			if err != nil {
+				return err
+			}
+			current := params.WarmImageSettings{}
+			if currentPool.Payload.WarmImage != nil {
+				current = *currentPool.Payload.WarmImage
+			}
+			settings, err := warmImageFromFlags(cmd, current)
+			if err != nil {
+				return err
+			}
+			poolUpdateParams.WarmImage = settings
+		}
+
 		if cmd.Flags().Changed("extra-specs") {
 			data, err := asRawMessage([]byte(poolExtraSpecs))
 			if err != nil {
@@ -371,7 +543,7 @@ explicitly remove them using the runner delete command.
 
 		updatePoolReq.PoolID = args[0]
 		updatePoolReq.Body = poolUpdateParams
-		response, err := apiCli.Pools.UpdatePool(updatePoolReq, authToken)
+		response, err := apiCli.Pools.UpdatePool(updatePoolReq, authToken, poolDryRunOptions[apiClientPools.ClientOption]()...)
 		if err != nil {
 			return err
 		}
@@ -400,8 +572,32 @@ func init() {
 	poolUpdateCmd.Flags().StringVar(&poolGitHubRunnerGroup, "runner-group", "", "The GitHub runner group in which all runners of this pool will be added.")
 	poolUpdateCmd.Flags().BoolVar(&poolEnabled, "enabled", false, "Enable this pool.")
 	poolUpdateCmd.Flags().UintVar(&poolRunnerBootstrapTimeout, "runner-bootstrap-timeout", 20, "Duration in minutes after which a runner is considered failed if it does not join Github.")
+	poolUpdateCmd.Flags().UintVar(&poolIdleDetectionWindow, "idle-detection-window", 0, "Duration in minutes a runner must be idle before it is considered for scale down. A value of 0 uses the default of 2 minutes.")
+	poolUpdateCmd.Flags().UintVar(&poolScaleDownGracePeriod, "scale-down-grace-period", 0, "Duration in minutes after a runner is created, during which it will not be scaled down. A value of 0 uses the default of 5 minutes.")
+	poolUpdateCmd.Flags().Float64Var(&poolScaleDownFactor, "scale-down-factor", 0, "Fraction of the surplus of idle runners removed on each scale down run, between 0 and 1. A value of 0 uses the default of 0.5.")
+	poolUpdateCmd.Flags().UintVar(&poolCapacityWarning, "capacity-warning-threshold", 0, "Percentage of max runners at which a capacity warning is emitted for the pool. A value of 0 uses the default of 80.")
+	poolUpdateCmd.Flags().UintVar(&poolAutoscaleMaxBurst, "autoscale-max-burst", 0, "Maximum number of runners created at once by the queue depth autoscaler. A value of 0 disables the autoscaler for this pool.")
+	poolUpdateCmd.Flags().UintVar(&poolAutoscaleCooldown, "autoscale-cooldown", 0, "Duration in seconds the queue depth autoscaler waits after scaling up the pool. A value of 0 uses the default of 60 seconds.")
+	poolUpdateCmd.Flags().UintVar(&poolMaxCreatesPerMinute, "max-creates-per-minute", 0, "Maximum number of runners created in this pool each minute. A value of 0 means no limit.")
+	poolUpdateCmd.Flags().UintVar(&poolCreateJitter, "create-jitter", 0, "Maximum random delay in seconds added before each runner of this pool is created. A value of 0 creates runners right away.")
+	poolUpdateCmd.Flags().UintVar(&poolRollingUpdateBatchSize, "rolling-update-batch-size", 0, "Number of outdated idle runners replaced at once after the image or flavor of this pool changed. A value of 0 uses the default of 1.")
+	poolUpdateCmd.Flags().UintVar(&poolRollingUpdatePause, "rolling-update-pause", 0, "Duration in seconds waited between two batches of a rolling update. A value of 0 uses the default of 60 seconds.")
+	poolUpdateCmd.Flags().BoolVar(&poolDraining, "draining", false, "Stop creating new runners in this pool and remove existing runners once they finish their jobs.")
+	poolUpdateCmd.Flags().StringArrayVar(&poolInstanceMetadata, "instance-metadata", nil, "A key=value pair served to runners by the metadata service. Can be repeated; replaces all existing instance metadata. Pass an empty value to remove it.")
+	poolUpdateCmd.Flags().StringVar(&poolWarmImageScriptFile, "warm-image-script-file", "", "A script the provider runs on an idle runner before snapshotting it into the warm image of the pool. Pass an empty value to stop using warm images.")
+	poolUpdateCmd.Flags().UintVar(&poolWarmImageRefresh, "warm-image-refresh-interval", 0, "Interval in hours at which the warm image is rebuilt. A value of 0 uses the default of 24 hours.")
+	poolUpdateCmd.Flags().StringVar(&poolURLSet, "url-set", "", "Name of the controller URL set used by runners in this pool. An empty value selects the default URLs.")
 	poolUpdateCmd.Flags().StringVar(&poolExtraSpecsFile, "extra-specs-file", "", "A file containing a valid json which will be passed to the IaaS provider managing the pool.")
 	poolUpdateCmd.Flags().StringVar(&poolExtraSpecs, "extra-specs", "", "A valid json which will be passed to the IaaS provider managing the pool.")
+	poolUpdateCmd.Flags().BoolVar(&poolDryRun, "dry-run", false, "Validate the changes and show the resulting pool, without saving it.")
+	poolUpdateCmd.Flags().BoolVar(&poolWipeWorkspace, "wipe-workspace", false, "Ask the provider to wipe the runner workspace on hosts that are reused.")
+	poolUpdateCmd.Flags().UintVar(&poolPruneDockerImagesDays, "prune-docker-images-older-than", 0, "Ask the provider to prune docker images older than this number of days on hosts that are reused. A value of 0 disables pruning.")
+	poolUpdateCmd.Flags().StringVar(&poolDNSServers, "dns-servers", "", "A comma separated list of DNS server IP addresses runners in this pool should use.")
+	poolUpdateCmd.Flags().StringVar(&poolSearchDomains, "search-domains", "", "A comma separated list of DNS search domains runners in this pool should use.")
+	poolUpdateCmd.Flags().StringVar(&poolNTPServers, "ntp-servers", "", "A comma separated list of NTP servers runners in this pool should use.")
+	poolUpdateCmd.Flags().StringVar(&poolHTTPProxy, "http-proxy", "", "The HTTP proxy runners in this pool should use.")
+	poolUpdateCmd.Flags().StringVar(&poolHTTPSProxy, "https-proxy", "", "The HTTPS proxy runners in this pool should use.")
+	poolUpdateCmd.Flags().StringVar(&poolNoProxy, "no-proxy", "", "A comma separated list of hosts runners in this pool should access without a proxy.")
 	poolUpdateCmd.MarkFlagsMutuallyExclusive("extra-specs-file", "extra-specs")
 
 	poolAddCmd.Flags().StringVar(&poolProvider, "provider-name", "", "The name of the provider where runners will be created.")
@@ -417,8 +613,31 @@ func init() {
 	poolAddCmd.Flags().StringVar(&poolGitHubRunnerGroup, "runner-group", "", "The GitHub runner group in which all runners of this pool will be added.")
 	poolAddCmd.Flags().UintVar(&poolMaxRunners, "max-runners", 5, "The maximum number of runner this pool will create.")
 	poolAddCmd.Flags().UintVar(&poolRunnerBootstrapTimeout, "runner-bootstrap-timeout", 20, "Duration in minutes after which a runner is considered failed if it does not join Github.")
+	poolAddCmd.Flags().UintVar(&poolIdleDetectionWindow, "idle-detection-window", 0, "Duration in minutes a runner must be idle before it is considered for scale down. A value of 0 uses the default of 2 minutes.")
+	poolAddCmd.Flags().UintVar(&poolScaleDownGracePeriod, "scale-down-grace-period", 0, "Duration in minutes after a runner is created, during which it will not be scaled down. A value of 0 uses the default of 5 minutes.")
+	poolAddCmd.Flags().Float64Var(&poolScaleDownFactor, "scale-down-factor", 0, "Fraction of the surplus of idle runners removed on each scale down run, between 0 and 1. A value of 0 uses the default of 0.5.")
+	poolAddCmd.Flags().UintVar(&poolCapacityWarning, "capacity-warning-threshold", 0, "Percentage of max runners at which a capacity warning is emitted for the pool. A value of 0 uses the default of 80.")
+	poolAddCmd.Flags().UintVar(&poolAutoscaleMaxBurst, "autoscale-max-burst", 0, "Maximum number of runners created at once by the queue depth autoscaler. A value of 0 disables the autoscaler for this pool.")
+	poolAddCmd.Flags().UintVar(&poolAutoscaleCooldown, "autoscale-cooldown", 0, "Duration in seconds the queue depth autoscaler waits after scaling up the pool. A value of 0 uses the default of 60 seconds.")
+	poolAddCmd.Flags().UintVar(&poolMaxCreatesPerMinute, "max-creates-per-minute", 0, "Maximum number of runners created in this pool each minute. A value of 0 means no limit.")
+	poolAddCmd.Flags().UintVar(&poolCreateJitter, "create-jitter", 0, "Maximum random delay in seconds added before each runner of this pool is created. A value of 0 creates runners right away.")
+	poolAddCmd.Flags().UintVar(&poolRollingUpdateBatchSize, "rolling-update-batch-size", 0, "Number of outdated idle runners replaced at once after the image or flavor of this pool changed. A value of 0 uses the default of 1.")
+	poolAddCmd.Flags().UintVar(&poolRollingUpdatePause, "rolling-update-pause", 0, "Duration in seconds waited between two batches of a rolling update. A value of 0 uses the default of 60 seconds.")
+	poolAddCmd.Flags().StringArrayVar(&poolInstanceMetadata, "instance-metadata", nil, "A key=value pair served to runners by the metadata service. Can be repeated.")
+	poolAddCmd.Flags().StringVar(&poolWarmImageScriptFile, "warm-image-script-file", "", "A script the provider runs on an idle runner before snapshotting it into the warm image of the pool. The provider must support snapshots.")
+	poolAddCmd.Flags().UintVar(&poolWarmImageRefresh, "warm-image-refresh-interval", 0, "Interval in hours at which the warm image is rebuilt. A value of 0 uses the default of 24 hours.")
+	poolAddCmd.Flags().StringVar(&poolURLSet, "url-set", "", "Name of the controller URL set used by runners in this pool. Defaults to the controller metadata and callback URLs.")
 	poolAddCmd.Flags().UintVar(&poolMinIdleRunners, "min-idle-runners", 1, "Attempt to maintain a minimum of idle self-hosted runners of this type.")
 	poolAddCmd.Flags().BoolVar(&poolEnabled, "enabled", false, "Enable this pool.")
+	poolAddCmd.Flags().BoolVar(&poolDryRun, "dry-run", false, "Validate the pool and show it, without creating it.")
+	poolAddCmd.Flags().BoolVar(&poolWipeWorkspace, "wipe-workspace", false, "Ask the provider to wipe the runner workspace on hosts that are reused.")
+	poolAddCmd.Flags().UintVar(&poolPruneDockerImagesDays, "prune-docker-images-older-than", 0, "Ask the provider to prune docker images older than this number of days on hosts that are reused. A value of 0 disables pruning.")
+	poolAddCmd.Flags().StringVar(&poolDNSServers, "dns-servers", "", "A comma separated list of DNS server IP addresses runners in this pool should use.")
+	poolAddCmd.Flags().StringVar(&poolSearchDomains, "search-domains", "", "A comma separated list of DNS search domains runners in this pool should use.")
+	poolAddCmd.Flags().StringVar(&poolNTPServers, "ntp-servers", "", "A comma separated list of NTP servers runners in this pool should use.")
+	poolAddCmd.Flags().StringVar(&poolHTTPProxy, "http-proxy", "", "The HTTP proxy runners in this pool should use.")
+	poolAddCmd.Flags().StringVar(&poolHTTPSProxy, "https-proxy", "", "The HTTPS proxy runners in this pool should use.")
+	poolAddCmd.Flags().StringVar(&poolNoProxy, "no-proxy", "", "A comma separated list of hosts runners in this pool should access without a proxy.")
 	poolAddCmd.MarkFlagRequired("provider-name") //nolint
 	poolAddCmd.MarkFlagRequired("image")         //nolint
 	poolAddCmd.MarkFlagRequired("flavor")        //nolint
@@ -500,6 +719,23 @@ func formatPools(pools []params.Pool) {
 	fmt.Println(t.Render())
 }
 
+// poolDryRunOptions returns the client options that set the dry_run query parameter
+// of pool create and update requests, if --dry-run was given.
+func poolDryRunOptions[T ~func(*runtime.ClientOperation)]() []T {
+	if !poolDryRun {
+		return nil
+	}
+	return []T{func(op *runtime.ClientOperation) {
+		writer := op.Params
+		op.Params = runtime.ClientRequestWriterFunc(func(req runtime.ClientRequest, reg strfmt.Registry) error {
+			if err := writer.WriteToRequest(req, reg); err != nil {
+				return err
+			}
+			return req.SetQueryParam("dry_run", "true")
+		})
+	}}
+}
+
 func formatOnePool(pool params.Pool) {
 	if outputFormat == common.OutputFormatJSON {
 		printAsJSON(pool)
@@ -541,13 +777,78 @@ func formatOnePool(pool params.Pool) {
 	t.AppendRow(table.Row{"Max Runners", pool.MaxRunners})
 	t.AppendRow(table.Row{"Min Idle Runners", pool.MinIdleRunners})
 	t.AppendRow(table.Row{"Runner Bootstrap Timeout", pool.RunnerBootstrapTimeout})
+	t.AppendRow(table.Row{"Idle Detection Window", pool.IdleDetectionWindow})
+	t.AppendRow(table.Row{"Scale Down Grace Period", pool.ScaleDownGracePeriod})
+	t.AppendRow(table.Row{"Scale Down Factor", pool.ScaleDownFactor})
+	t.AppendRow(table.Row{"Capacity Warning Threshold", pool.CapacityWarningThreshold})
+	if pool.AutoscaleEnabled() {
+		t.AppendRow(table.Row{"Autoscale Max Burst", pool.AutoscaleMaxBurst})
+		t.AppendRow(table.Row{"Autoscale Cooldown", pool.AutoscaleCooldownPeriod()})
+	}
+	if pool.MaxCreatesPerMinute > 0 {
+		t.AppendRow(table.Row{"Max Creates Per Minute", pool.MaxCreatesPerMinute})
+	}
+	if pool.CreateJitter > 0 {
+		t.AppendRow(table.Row{"Create Jitter", pool.CreateJitter})
+	}
+	t.AppendRow(table.Row{"Rolling Update Batch Size", pool.RollingUpdateBatch()})
+	t.AppendRow(table.Row{"Rolling Update Pause", pool.RollingUpdatePausePeriod()})
+	t.AppendRow(table.Row{"Capacity Warning", pool.CapacityWarning})
 	t.AppendRow(table.Row{"Tags", strings.Join(tags, ", ")})
 	t.AppendRow(table.Row{"Belongs to", belongsTo})
 	t.AppendRow(table.Row{"Level", level})
 	t.AppendRow(table.Row{"Enabled", pool.Enabled})
+	t.AppendRow(table.Row{"Draining", pool.Draining})
+	if pool.URLSet != "" {
+		t.AppendRow(table.Row{"URL Set", pool.URLSet})
+	}
 	t.AppendRow(table.Row{"Runner Prefix", pool.GetRunnerPrefix()})
 	t.AppendRow(table.Row{"Extra specs", string(pool.ExtraSpecs)})
 	t.AppendRow(table.Row{"GitHub Runner Group", pool.GitHubRunnerGroup})
+	if pool.CleanupPolicy != nil {
+		t.AppendRow(table.Row{"Wipe Workspace", pool.CleanupPolicy.WipeWorkspace})
+		t.AppendRow(table.Row{"Prune Docker Images Older Than (days)", pool.CleanupPolicy.PruneDockerImagesOlderThanDays})
+	}
+	if pool.NetworkSettings != nil {
+		t.AppendRow(table.Row{"DNS Servers", strings.Join(pool.NetworkSettings.DNSServers, ", ")})
+		t.AppendRow(table.Row{"Search Domains", strings.Join(pool.NetworkSettings.SearchDomains, ", ")})
+		t.AppendRow(table.Row{"NTP Servers", strings.Join(pool.NetworkSettings.NTPServers, ", ")})
+		t.AppendRow(table.Row{"HTTP Proxy", pool.NetworkSettings.HTTPProxy})
+		t.AppendRow(table.Row{"HTTPS Proxy", pool.NetworkSettings.HTTPSProxy})
+		t.AppendRow(table.Row{"No Proxy", pool.NetworkSettings.NoProxy})
+	}
+	if pool.WarmImage != nil {
+		t.AppendRow(table.Row{"Warm Image Refresh Interval", pool.WarmImage.RefreshPeriod()})
+		if pool.WarmImageStatus != nil {
+			if pool.WarmImageStatus.Image != "" {
+				t.AppendRow(table.Row{"Warm Image", fmt.Sprintf("%s (built %s from %s)", pool.WarmImageStatus.Image, pool.WarmImageStatus.BuiltAt.Format(time.RFC3339), pool.WarmImageStatus.BaseImage)})
+			}
+			if pool.WarmImageStatus.LastError != "" {
+				t.AppendRow(table.Row{"Warm Image Error", pool.WarmImageStatus.LastError})
+			}
+		}
+		t.AppendRow(table.Row{"Runner Image", pool.RunnerImage()})
+	}
+	if len(pool.InstanceMetadata) > 0 {
+		keys := make([]string, 0, len(pool.InstanceMetadata))
+		for key := range pool.InstanceMetadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			t.AppendRow(table.Row{"Instance Metadata", fmt.Sprintf("%s=%s", key, pool.InstanceMetadata[key])}, rowConfigAutoMerge)
+		}
+	}
+	if pool.Schedule != nil {
+		t.AppendRow(table.Row{"Schedule Timezone", pool.Schedule.Timezone})
+		for _, window := range pool.Schedule.Windows {
+			days := "every day"
+			if len(window.Days) > 0 {
+				days = strings.Join(window.Days, ", ")
+			}
+			t.AppendRow(table.Row{"Schedule Windows", fmt.Sprintf("%s %s-%s: %d min idle runners", days, window.Start, window.End, window.MinIdleRunners)}, rowConfigAutoMerge)
+		}
+	}
 
 	if len(pool.Instances) > 0 {
 		for _, instance := range pool.Instances {
@@ -561,3 +862,121 @@ func formatOnePool(pool params.Pool) {
 	})
 	fmt.Println(t.Render())
 }
+
+func networkSettingsFlagsChanged(cmd *cobra.Command) bool {
+	for _, flag := range poolNetworkSettingsFlags {
+		if cmd.Flags().Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkSettingsFromFlags applies the network settings flags that were set on the
+// command line over the supplied settings. Setting a flag to an empty string clears
+// the corresponding setting.
+func networkSettingsFromFlags(cmd *cobra.Command, settings params.NetworkSettings) *params.NetworkSettings {
+	if cmd.Flags().Changed("dns-servers") {
+		settings.DNSServers = splitCommaSeparated(poolDNSServers)
+	}
+	if cmd.Flags().Changed("search-domains") {
+		settings.SearchDomains = splitCommaSeparated(poolSearchDomains)
+	}
+	if cmd.Flags().Changed("ntp-servers") {
+		settings.NTPServers = splitCommaSeparated(poolNTPServers)
+	}
+	if cmd.Flags().Changed("http-proxy") {
+		settings.HTTPProxy = poolHTTPProxy
+	}
+	if cmd.Flags().Changed("https-proxy") {
+		settings.HTTPSProxy = poolHTTPSProxy
+	}
+	if cmd.Flags().Changed("no-proxy") {
+		settings.NoProxy = poolNoProxy
+	}
+	return &settings
+}
+
+func warmImageFlagsChanged(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("warm-image-script-file") || cmd.Flags().Changed("warm-image-refresh-interval")
+}
+
+// warmImageFromFlags applies the warm image flags that were set on the command line
+// over the given settings.
+func warmImageFromFlags(cmd *cobra.Command, settings params.WarmImageSettings) (*params.WarmImageSettings, error) {
+	if cmd.Flags().Changed("warm-image-script-file") {
+		settings.PrepareScript = ""
+		if poolWarmImageScriptFile != "" {
+			script, err := os.ReadFile(poolWarmImageScriptFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading warm image script: %w", err)
+			}
+			settings.PrepareScript = string(script)
+		}
+	}
+	if cmd.Flags().Changed("warm-image-refresh-interval") {
+		settings.RefreshInterval = poolWarmImageRefresh
+	}
+	if settings.IsEmpty() {
+		// Removing the script disables warm images.
+		settings.RefreshInterval = 0
+	}
+	return &settings, nil
+}
+
+func parseInstanceMetadata(vals []string) (params.InstanceMetadata, error) {
+	ret := params.InstanceMetadata{}
+	for _, val := range vals {
+		if strings.TrimSpace(val) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(val, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid instance metadata %q, expected key=value", val)
+		}
+		ret[strings.TrimSpace(key)] = value
+	}
+	return ret, nil
+}
+
+func splitCommaSeparated(val string) []string {
+	ret := []string{}
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// parseRoutingRules parses routing rules given as "type" or "type=provider1,provider2".
+// Empty values are ignored, so passing a single empty value clears the rules.
+func parseRoutingRules(vals []string) []params.RoutingRule {
+	ret := []params.RoutingRule{}
+	for _, val := range vals {
+		ruleType, providers, _ := strings.Cut(strings.TrimSpace(val), "=")
+		if ruleType == "" {
+			continue
+		}
+		rule := params.RoutingRule{
+			Type: params.RoutingRuleType(ruleType),
+		}
+		if providers != "" {
+			rule.Providers = splitCommaSeparated(providers)
+		}
+		ret = append(ret, rule)
+	}
+	return ret
+}
+
+func formatRoutingRules(rules []params.RoutingRule) string {
+	ret := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.Providers) > 0 {
+			ret = append(ret, fmt.Sprintf("%s=%s", rule.Type, strings.Join(rule.Providers, ",")))
+			continue
+		}
+		ret = append(ret, string(rule.Type))
+	}
+	return strings.Join(ret, " ")
+}
@@ -70,10 +70,14 @@ func formatProviders(providers []params.Provider) {
 		return
 	}
 	t := table.NewWriter()
-	header := table.Row{"Name", "Description", "Type"}
+	header := table.Row{"Name", "Description", "Type", "Version", "Paused"}
 	t.AppendHeader(header)
 	for _, val := range providers {
-		t.AppendRow(table.Row{val.Name, val.Description, val.ProviderType})
+		var version string
+		if val.VersionInfo != nil {
+			version = val.VersionInfo.Version
+		}
+		t.AppendRow(table.Row{val.Name, val.Description, val.ProviderType, version, val.Paused})
 		t.AppendSeparator()
 	}
 	fmt.Println(t.Render())
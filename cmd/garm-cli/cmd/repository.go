@@ -35,6 +35,7 @@ var (
 	insecureRepoWebhook bool
 	keepRepoWebhook     bool
 	installRepoWebhook  bool
+	repoEntityPath      bool
 )
 
 // repositoryCmd represents the repository command
@@ -78,6 +79,7 @@ var repoWebhookInstallCmd = &cobra.Command{
 		installWebhookReq := apiClientRepos.NewInstallRepoWebhookParams()
 		installWebhookReq.RepoID = args[0]
 		installWebhookReq.Body.InsecureSSL = insecureRepoWebhook
+		installWebhookReq.Body.EntityWebhookPath = repoEntityPath
 		installWebhookReq.Body.WebhookEndpointType = params.WebhookEndpointDirect
 
 		response, err := apiCli.Repositories.InstallRepoWebhook(installWebhookReq, authToken)
@@ -224,7 +226,7 @@ var repoUpdateCmd = &cobra.Command{
 	Short:        "Update repository",
 	Long:         `Update repository credentials or webhook secret.`,
 	SilenceUsage: true,
-	RunE: func(_ *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if needsInit {
 			return errNeedsInitError
 		}
@@ -241,6 +243,15 @@ var repoUpdateCmd = &cobra.Command{
 			WebhookSecret:    repoWebhookSecret,
 			CredentialsName:  repoCreds,
 			PoolBalancerType: params.PoolBalancerType(poolBalancerType),
+
+			WebhookSecretGracePeriod: webhookSecretGracePeriod,
+		}
+		if cmd.Flags().Changed("max-concurrent-jobs") {
+			updateReposReq.Body.MaxConcurrentJobs = &maxConcurrentJobs
+		}
+		if cmd.Flags().Changed("routing-rule") {
+			rules := parseRoutingRules(routingRules)
+			updateReposReq.Body.RoutingRules = &rules
 		}
 		updateReposReq.RepoID = args[0]
 
@@ -325,8 +336,12 @@ func init() {
 	repoUpdateCmd.Flags().StringVar(&repoWebhookSecret, "webhook-secret", "", "The webhook secret for this repository. If you update this secret, you will have to manually update the secret in GitHub as well.")
 	repoUpdateCmd.Flags().StringVar(&repoCreds, "credentials", "", "Credentials name. See credentials list.")
 	repoUpdateCmd.Flags().StringVar(&poolBalancerType, "pool-balancer-type", "", "The balancing strategy to use when creating runners in pools matching requested labels.")
+	repoUpdateCmd.Flags().UintVar(&maxConcurrentJobs, "max-concurrent-jobs", 0, "The maximum number of jobs this repository can have runners for at the same time. Set to 0 to remove the limit.")
+	repoUpdateCmd.Flags().UintVar(&webhookSecretGracePeriod, "webhook-secret-grace-period", 0, "The number of minutes during which webhooks signed with the current secret are still accepted, after it is replaced with --webhook-secret. Use this to update the secret in GitHub without dropping webhooks.")
+	repoUpdateCmd.Flags().StringArrayVar(&routingRules, "routing-rule", nil, "A rule used to choose the pool in which a runner is created. One of: prefer_idle, least_loaded or provider_order=provider1,provider2. Can be repeated; rules are applied in order. Pass an empty value to remove all rules.")
 
 	repoWebhookInstallCmd.Flags().BoolVar(&insecureRepoWebhook, "insecure", false, "Ignore self signed certificate errors.")
+	repoWebhookInstallCmd.Flags().BoolVar(&repoEntityPath, "entity-path", false, "Install the webhook using a URL unique to this repository.")
 
 	repoWebhookCmd.AddCommand(
 		repoWebhookInstallCmd,
@@ -375,17 +390,32 @@ func formatOneRepository(repo params.Repository) {
 	t.AppendRow(table.Row{"Name", repo.Name})
 	t.AppendRow(table.Row{"Endpoint", repo.Endpoint.Name})
 	t.AppendRow(table.Row{"Pool balancer type", repo.GetBalancerType()})
+	if repo.MaxConcurrentJobs > 0 {
+		t.AppendRow(table.Row{"Max concurrent jobs", repo.MaxConcurrentJobs})
+	}
+	if len(repo.RoutingRules) > 0 {
+		t.AppendRow(table.Row{"Routing rules", formatRoutingRules(repo.RoutingRules)})
+	}
 	t.AppendRow(table.Row{"Credentials", repo.CredentialsName})
 	t.AppendRow(table.Row{"Pool manager running", repo.PoolManagerStatus.IsRunning})
 	if !repo.PoolManagerStatus.IsRunning {
 		t.AppendRow(table.Row{"Failure reason", repo.PoolManagerStatus.FailureReason})
 	}
+	if repo.PendingWebhookInstall != nil {
+		t.AppendRow(table.Row{"Pending webhook install", true})
+	}
 
 	if len(repo.Pools) > 0 {
 		for _, pool := range repo.Pools {
 			t.AppendRow(table.Row{"Pools", pool.ID}, rowConfigAutoMerge)
 		}
 	}
+
+	if len(repo.Events) > 0 {
+		for _, event := range repo.Events {
+			t.AppendRow(table.Row{"Events", fmt.Sprintf("%s [%s]: %s", event.CreatedAt.Format("2006-01-02T15:04:05"), event.EventLevel, event.Message)}, rowConfigAutoMerge)
+		}
+	}
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{Number: 1, AutoMerge: true},
 		{Number: 2, AutoMerge: false},
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/go-openapi/runtime"
 	openapiRuntimeClient "github.com/go-openapi/runtime/client"
@@ -39,8 +40,15 @@ var (
 	needsInit         bool
 	debug             bool
 	poolBalancerType  string
+	maxConcurrentJobs uint
+	routingRules      []string
+	controllerURLSets []string
 	outputFormat      common.OutputFormat = common.OutputFormatTable
 	errNeedsInitError                     = fmt.Errorf("please log into a garm installation first")
+
+	// webhookSecretGracePeriod is the number of minutes the previous webhook secret
+	// is still accepted, after it is replaced.
+	webhookSecretGracePeriod uint
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -116,6 +124,20 @@ func formatOneHookInfo(hook params.HookInfo) {
 		{"Active", hook.Active},
 		{"Insecure SSL", hook.InsecureSSL},
 	})
+	if hook.DeliveryStats != nil {
+		var lastDelivery string
+		if hook.DeliveryStats.LastDeliveryAt != nil {
+			lastDelivery = hook.DeliveryStats.LastDeliveryAt.Format(time.RFC3339)
+		}
+		t.AppendRows([]table.Row{
+			{"Recent Deliveries", hook.DeliveryStats.RecentDeliveries},
+			{"Failed Deliveries", hook.DeliveryStats.FailedDeliveries},
+			{"Last Delivery Status", hook.DeliveryStats.LastDeliveryStatus},
+			{"Last Delivery Status Code", hook.DeliveryStats.LastDeliveryStatusCode},
+			{"Last Delivery At", lastDelivery},
+			{"Stats Updated At", hook.DeliveryStats.UpdatedAt.Format(time.RFC3339)},
+		})
+	}
 	fmt.Println(t.Render())
 }
 
@@ -266,6 +266,10 @@ func formatSingleInstance(instance params.Instance) {
 	t.AppendRow(table.Row{"Status", instance.Status}, table.RowConfig{AutoMerge: false})
 	t.AppendRow(table.Row{"Runner Status", instance.RunnerStatus}, table.RowConfig{AutoMerge: false})
 	t.AppendRow(table.Row{"Pool ID", instance.PoolID}, table.RowConfig{AutoMerge: false})
+	if instance.Image != "" {
+		t.AppendRow(table.Row{"Image", instance.Image}, table.RowConfig{AutoMerge: false})
+		t.AppendRow(table.Row{"Flavor", instance.Flavor}, table.RowConfig{AutoMerge: false})
+	}
 
 	if len(instance.Addresses) > 0 {
 		for _, addr := range instance.Addresses {
@@ -273,6 +277,10 @@ func formatSingleInstance(instance params.Instance) {
 		}
 	}
 
+	if instance.DiskUsage != nil {
+		t.AppendRow(table.Row{"Disk Usage", fmt.Sprintf("%s: %d/%d bytes (updated %s)", instance.DiskUsage.Path, instance.DiskUsage.UsedBytes, instance.DiskUsage.TotalBytes, instance.DiskUsage.UpdatedAt.Format("2006-01-02T15:04:05"))}, table.RowConfig{AutoMerge: false})
+	}
+
 	if len(instance.ProviderFault) > 0 {
 		t.AppendRow(table.Row{"Provider Fault", string(instance.ProviderFault)}, table.RowConfig{AutoMerge: true})
 	}
@@ -27,10 +27,13 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Embed the time zone database, so pool schedules work on hosts that don't have it.
+	_ "time/tzdata"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/cloudbase/garm-provider-common/util"
@@ -45,14 +48,17 @@ import (
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner" //nolint:typecheck
 	runnerMetrics "github.com/cloudbase/garm/runner/metrics"
+	"github.com/cloudbase/garm/tracing"
 	garmUtil "github.com/cloudbase/garm/util"
 	"github.com/cloudbase/garm/util/appdefaults"
+	garmGithub "github.com/cloudbase/garm/util/github"
 	"github.com/cloudbase/garm/websocket"
 )
 
 var (
-	conf    = flag.String("config", appdefaults.DefaultConfigFilePath, "garm config file")
-	version = flag.Bool("version", false, "prints version")
+	conf             = flag.String("config", appdefaults.DefaultConfigFilePath, "garm config file")
+	version          = flag.Bool("version", false, "prints version")
+	reEncryptSecrets = flag.Bool("reencrypt-secrets", false, "re-encrypts the secrets in the database using the configured secrets backend and exits")
 )
 
 var signals = []os.Signal{
@@ -202,6 +208,11 @@ func main() {
 	}
 	setupLogging(ctx, logCfg, hub)
 
+	if cfg.Tracing.Enable {
+		slog.InfoContext(ctx, "exporting traces to collector", "endpoint", cfg.Tracing.Endpoint)
+	}
+	shutdownTracing := tracing.Setup(ctx, cfg.Tracing)
+
 	// Migrate credentials to the new format. This field will be read
 	// by the DB migration logic.
 	cfg.Database.MigrateCredentials = cfg.Github
@@ -210,6 +221,19 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *reEncryptSecrets {
+		migrator, ok := db.(common.SecretsMigrator)
+		if !ok {
+			log.Fatalf("database backend %s does not support re-encrypting secrets", cfg.Database.DbBackend)
+		}
+		count, err := migrator.ReEncryptSecrets(ctx)
+		if err != nil {
+			log.Fatalf("failed to re-encrypt secrets: %+v", err)
+		}
+		fmt.Printf("re-encrypted %d secrets using the %s secrets backend\n", count, cfg.Database.Secrets.GetBackend())
+		return
+	}
+
 	if err := maybeInitController(db); err != nil {
 		log.Fatal(err)
 	}
@@ -218,6 +242,9 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Share the installation tokens of github apps between all the pools using them.
+	garmGithub.Default().Start(ctx)
+
 	runner, err := runner.NewRunner(ctx, *cfg, db)
 	if err != nil {
 		log.Fatalf("failed to create controller: %+v", err)
@@ -234,7 +261,7 @@ func main() {
 		log.Fatalf("failed to create controller: %+v", err)
 	}
 
-	instanceMiddleware, err := auth.NewInstanceMiddleware(db, cfg.JWTAuth)
+	instanceMiddleware, err := auth.NewInstanceMiddleware(db, cfg.JWTAuth, cfg.APIServer.InstanceAllowedCIDRs)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -254,18 +281,28 @@ func main() {
 		log.Fatal(err)
 	}
 
+	idempotencyMiddleware, err := auth.NewIdempotencyMiddleware(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	metricsMiddleware, err := auth.NewMetricsMiddleware(cfg.JWTAuth)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	router := routers.NewAPIRouter(controller, jwtMiddleware, initMiddleware, urlsRequiredMiddleware, instanceMiddleware, cfg.Default.EnableWebhookManagement)
+	internalListener := cfg.APIServer.InternalListener
+	router := routers.NewAPIRouter(controller, jwtMiddleware, initMiddleware, urlsRequiredMiddleware, instanceMiddleware, idempotencyMiddleware, cfg.Default.EnableWebhookManagement, internalListener == nil)
 
 	// start the metrics collector
 	if cfg.Metrics.Enable {
 		slog.InfoContext(ctx, "setting up metric routes")
 		router = routers.WithMetricsRouter(router, cfg.Metrics.DisableAuth, metricsMiddleware)
+	}
 
+	// metrics are also collected when they are only pushed to a remote write
+	// endpoint, for installs that can't be scraped.
+	if cfg.Metrics.Enable || cfg.Metrics.RemoteWrite.Enabled() {
 		slog.InfoContext(ctx, "register metrics")
 		if err := metrics.RegisterMetrics(); err != nil {
 			log.Fatal(err)
@@ -275,6 +312,11 @@ func main() {
 		runnerMetrics.CollectObjectMetric(ctx, runner, cfg.Metrics.Duration())
 	}
 
+	if cfg.Metrics.RemoteWrite.Enabled() {
+		slog.InfoContext(ctx, "start pushing metrics to remote write endpoint", "url", cfg.Metrics.RemoteWrite.URL)
+		metrics.NewRemoteWriter(cfg.Metrics.RemoteWrite, prometheus.DefaultGatherer).Start(ctx)
+	}
+
 	if cfg.Default.DebugServer {
 		slog.InfoContext(ctx, "setting up debug routes")
 		router = routers.WithDebugServer(router)
@@ -284,8 +326,8 @@ func main() {
 	router.Use(corsMw)
 
 	allowedOrigins := handlers.AllowedOrigins(cfg.APIServer.CORSOrigins)
-	methodsOk := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE"})
-	headersOk := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"})
+	methodsOk := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "OPTIONS", "DELETE"})
+	headersOk := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", "Idempotency-Key"})
 
 	// nolint:golangci-lint,gosec
 	// G112: Potential Slowloris Attack because ReadHeaderTimeout is not configured in the http.Server
@@ -299,30 +341,57 @@ func main() {
 	if err != nil {
 		log.Fatalf("creating listener: %q", err)
 	}
+	go serve(ctx, srv, listener, cfg.APIServer.UseTLS, cfg.APIServer.TLSConfig)
 
-	go func() {
-		if cfg.APIServer.UseTLS {
-			if err := srv.ServeTLS(listener, cfg.APIServer.TLSConfig.CRT, cfg.APIServer.TLSConfig.Key); err != nil {
-				slog.With(slog.Any("error", err)).ErrorContext(ctx, "Listening")
-			}
-		} else {
-			if err := srv.Serve(listener); err != http.ErrServerClosed {
-				slog.With(slog.Any("error", err)).ErrorContext(ctx, "Listening")
-			}
+	servers := []*http.Server{srv}
+	if internalListener != nil {
+		slog.InfoContext(ctx, "serving metadata, callback and webhook routes on internal listener", "address", internalListener.BindAddress())
+		// nolint:golangci-lint,gosec
+		// G112: Potential Slowloris Attack because ReadHeaderTimeout is not configured in the http.Server
+		internalSrv := &http.Server{
+			Addr:    internalListener.BindAddress(),
+			Handler: routers.NewInternalRouter(controller, instanceMiddleware),
 		}
-	}()
+
+		internalNetListener, err := net.Listen("tcp", internalSrv.Addr)
+		if err != nil {
+			log.Fatalf("creating internal listener: %q", err)
+		}
+		go serve(ctx, internalSrv, internalNetListener, internalListener.UseTLS, internalListener.TLSConfig)
+		servers = append(servers, internalSrv)
+	}
 
 	<-ctx.Done()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer shutdownCancel()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		slog.With(slog.Any("error", err)).ErrorContext(ctx, "graceful api server shutdown failed")
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "graceful api server shutdown failed", "address", server.Addr)
+		}
 	}
 
 	slog.With(slog.Any("error", err)).InfoContext(ctx, "waiting for runner to stop")
-	if err := runner.Wait(); err != nil {
-		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to shutdown workers")
+	runnerErr := runner.Wait()
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to export remaining spans")
+	}
+
+	if runnerErr != nil {
+		slog.With(slog.Any("error", runnerErr)).ErrorContext(ctx, "failed to shutdown workers")
 		os.Exit(1)
 	}
 }
+
+func serve(ctx context.Context, srv *http.Server, listener net.Listener, useTLS bool, tlsConfig config.TLSConfig) {
+	if useTLS {
+		if err := srv.ServeTLS(listener, tlsConfig.CRT, tlsConfig.Key); err != http.ErrServerClosed {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "Listening")
+		}
+	} else {
+		if err := srv.Serve(listener); err != http.ErrServerClosed {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "Listening")
+		}
+	}
+}
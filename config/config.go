@@ -26,6 +26,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -50,6 +52,8 @@ const (
 	MySQLBackend DBBackendType = "mysql"
 	// SQLiteBackend represents the SQLite3 DB backend
 	SQLiteBackend DBBackendType = "sqlite3"
+	// PostgresBackend represents the PostgreSQL DB backend
+	PostgresBackend DBBackendType = "postgres"
 	// EnvironmentVariablePrefix is the prefix for all environment variables
 	// that can not be used to get overwritten via the external provider
 	EnvironmentVariablePrefix = "GARM"
@@ -96,11 +100,16 @@ type Config struct {
 	Default   Default    `toml:"default" json:"default"`
 	APIServer APIServer  `toml:"apiserver,omitempty" json:"apiserver,omitempty"`
 	Metrics   Metrics    `toml:"metrics,omitempty" json:"metrics,omitempty"`
+	Tracing   Tracing    `toml:"tracing,omitempty" json:"tracing,omitempty"`
 	Database  Database   `toml:"database,omitempty" json:"database,omitempty"`
 	Providers []Provider `toml:"provider,omitempty" json:"provider,omitempty"`
 	Github    []Github   `toml:"github,omitempty"`
 	JWTAuth   JWTAuth    `toml:"jwt_auth" json:"jwt-auth"`
 	Logging   Logging    `toml:"logging" json:"logging"`
+	Cluster   Cluster    `toml:"cluster" json:"cluster"`
+	Jobs      Jobs       `toml:"jobs,omitempty" json:"jobs,omitempty"`
+
+	BootstrapTransformer BootstrapTransformer `toml:"bootstrap_transformer,omitempty" json:"bootstrap-transformer,omitempty"`
 }
 
 // Validate validates the config
@@ -122,6 +131,18 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := c.Metrics.Validate(); err != nil {
+		return fmt.Errorf("error validating metrics config: %w", err)
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("error validating tracing config: %w", err)
+	}
+
+	if err := c.Jobs.Validate(); err != nil {
+		return fmt.Errorf("error validating jobs config: %w", err)
+	}
+
 	if err := c.JWTAuth.Validate(); err != nil {
 		return fmt.Errorf("error validating jwt_auth config: %w", err)
 	}
@@ -130,6 +151,14 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("error validating logging config: %w", err)
 	}
 
+	if c.Cluster.Enabled && c.Database.DbBackend == SQLiteBackend {
+		return fmt.Errorf("error validating cluster config: clustering requires a database shared by all controllers, which sqlite3 is not")
+	}
+
+	if err := c.BootstrapTransformer.Validate(); err != nil {
+		return fmt.Errorf("error validating bootstrap_transformer config: %w", err)
+	}
+
 	providerNames := map[string]int{}
 
 	for _, provider := range c.Providers {
@@ -169,6 +198,77 @@ func (c *Config) GetLoggingConfig() Logging {
 	return logging
 }
 
+// Cluster is the config for running several GARM controllers against the same
+// database. Each controller registers itself as a node, and the repositories,
+// organizations and enterprises are split between the nodes that are alive.
+type Cluster struct {
+	// Enabled turns on clustering.
+	Enabled bool `toml:"enabled" json:"enabled"`
+	// NodeName is the name this controller registers with. Defaults to the hostname.
+	NodeName string `toml:"node_name" json:"node-name"`
+}
+
+// BootstrapTransformer is the config for a hook that can change the bootstrap params
+// of runners before they are sent to the provider. This allows operators to enforce
+// their own policies, without changing GARM or the providers.
+type BootstrapTransformer struct {
+	// Command is the absolute path to an executable that receives the bootstrap
+	// params on standard input, and writes the changes on standard output.
+	Command string `toml:"command" json:"command"`
+	// URL is an HTTP endpoint that receives the bootstrap params in a POST request,
+	// and returns the changes in the response body. Mutually exclusive with Command.
+	URL string `toml:"url" json:"url"`
+	// Headers are extra HTTP headers added to the requests sent to URL.
+	Headers map[string]string `toml:"headers" json:"headers"`
+	// Timeout is the time the hook has to respond.
+	Timeout time.Duration `toml:"timeout" json:"timeout"`
+}
+
+// Enabled returns true if a bootstrap transformer is configured.
+func (b *BootstrapTransformer) Enabled() bool {
+	return b.Command != "" || b.URL != ""
+}
+
+func (b *BootstrapTransformer) Validate() error {
+	if !b.Enabled() {
+		return nil
+	}
+
+	if b.Command != "" && b.URL != "" {
+		return fmt.Errorf("command and url are mutually exclusive")
+	}
+	if b.Command != "" {
+		if !filepath.IsAbs(b.Command) {
+			return fmt.Errorf("command must be an absolute path")
+		}
+		if _, err := os.Stat(b.Command); err != nil {
+			return fmt.Errorf("failed to access command %s: %w", b.Command, err)
+		}
+	}
+	if b.URL != "" {
+		u, err := url.ParseRequestURI(b.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid url scheme %q", u.Scheme)
+		}
+	}
+	if b.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// TimeoutOrDefault returns the configured timeout, or the default timeout if none
+// is configured.
+func (b *BootstrapTransformer) TimeoutOrDefault() time.Duration {
+	if b.Timeout == 0 {
+		return appdefaults.DefaultBootstrapTransformerTimeout
+	}
+	return b.Timeout
+}
+
 type Logging struct {
 	// LogFile is the location of the log file.
 	LogFile string `toml:"log_file,omitempty" json:"log-file"`
@@ -204,6 +304,43 @@ type Default struct {
 	WebhookURL string `toml:"webhook_url" json:"webhook-url"`
 	// EnableWebhookManagement enables the webhook management API.
 	EnableWebhookManagement bool `toml:"enable_webhook_management" json:"enable-webhook-management"`
+	// EnableJobPoolPinning allows jobs to target a specific pool by adding a
+	// label of the form "garm-pool=<pool ID>" to the runs-on field of a workflow.
+	// When enabled, all runners will also get this label.
+	EnableJobPoolPinning bool `toml:"enable_job_pool_pinning" json:"enable-job-pool-pinning"`
+	// VerifyActionsPolicy enables checking that the GitHub Actions policies allow
+	// workflows to run for repositories, before provisioning runners for them.
+	VerifyActionsPolicy bool `toml:"verify_actions_policy" json:"verify-actions-policy"`
+	// ReconcileJobsOnStartup enables checking the status of jobs recorded as queued
+	// against the GitHub API, when GARM starts. Jobs that completed or were cancelled
+	// while GARM was offline are marked accordingly and stale job locks are removed.
+	ReconcileJobsOnStartup bool `toml:"reconcile_jobs_on_startup" json:"reconcile-jobs-on-startup"`
+	// MaxConcurrentJobs is the maximum number of jobs GARM will have runners for at the
+	// same time, across all entities. Jobs over the limit are kept queued until other
+	// jobs finish. A value of 0 means no limit.
+	MaxConcurrentJobs uint `toml:"max_concurrent_jobs" json:"max-concurrent-jobs"`
+	// ObserverMode makes GARM record jobs and the pools it would have used to create
+	// runners for them, without calling any provider or removing runners from GitHub.
+	// This is useful to evaluate pool configurations against real traffic.
+	ObserverMode bool `toml:"observer_mode" json:"observer-mode"`
+	// GateEnvironmentJobs makes GARM hold off creating runners for jobs that wait for
+	// the approval of a protected deployment environment. Runners are created once the
+	// job is queued again, after it was approved.
+	GateEnvironmentJobs bool `toml:"gate_environment_jobs" json:"gate-environment-jobs"`
+	// AuditLogRetention is the amount of time audit records are kept for. Older records
+	// are removed periodically. A value of 0 keeps audit records forever.
+	AuditLogRetention time.Duration `toml:"audit_log_retention" json:"audit-log-retention"`
+	// OrphanSweepInterval is the interval at which GARM asks providers that support it for
+	// all the instances created by this controller, and looks for instances that have no
+	// corresponding database record. A value of 0 disables the periodic sweep.
+	OrphanSweepInterval time.Duration `toml:"orphan_sweep_interval" json:"orphan-sweep-interval"`
+	// OrphanSweepCleanup makes the periodic sweep remove the orphaned instances it finds.
+	// When disabled, orphaned instances are only logged.
+	OrphanSweepCleanup bool `toml:"orphan_sweep_cleanup" json:"orphan-sweep-cleanup"`
+	// GithubRateLimitThreshold is the number of remaining GitHub API requests below which
+	// non critical loops, like updating the runner tools and cleaning up orphaned runners,
+	// are deferred until the quota of the credentials is reset. Defaults to 500.
+	GithubRateLimitThreshold uint `toml:"github_rate_limit_threshold" json:"github-rate-limit-threshold"`
 
 	// LogFile is the location of the log file.
 	LogFile           string `toml:"log_file,omitempty" json:"log-file"`
@@ -230,9 +367,29 @@ func (d *Default) Validate() error {
 			return fmt.Errorf("invalid webhook_url: %w", err)
 		}
 	}
+
+	if d.AuditLogRetention < 0 {
+		return fmt.Errorf("audit_log_retention must not be negative")
+	}
+
+	if d.OrphanSweepInterval < 0 {
+		return fmt.Errorf("orphan_sweep_interval must not be negative")
+	}
+	if d.OrphanSweepInterval > 0 && d.OrphanSweepInterval < time.Minute {
+		return fmt.Errorf("orphan_sweep_interval must be at least 1m")
+	}
 	return nil
 }
 
+// RateLimitThreshold returns the configured GitHub rate limit threshold or the
+// default threshold if no value is configured.
+func (d *Default) RateLimitThreshold() uint {
+	if d.GithubRateLimitThreshold == 0 {
+		return appdefaults.DefaultGithubRateLimitThreshold
+	}
+	return d.GithubRateLimitThreshold
+}
+
 type GithubPAT struct {
 	OAuth2Token string `toml:"oauth2_token" json:"oauth2-token"`
 }
@@ -457,8 +614,58 @@ type Provider struct {
 	// DisableJITConfig explicitly disables JIT configuration and forces runner registration
 	// tokens to be used. This may happen if a provider has not yet been updated to support
 	// JIT configuration.
-	DisableJITConfig bool     `toml:"disable_jit_config" json:"disable-jit-config"`
-	External         External `toml:"external" json:"external"`
+	DisableJITConfig bool `toml:"disable_jit_config" json:"disable-jit-config"`
+	// SupportsDiagnostics indicates that the provider implements the GetInstanceDiagnostics
+	// command. If set, GARM will ask the provider for diagnostic information about instances
+	// that fail to register as runners, before removing them.
+	SupportsDiagnostics bool `toml:"supports_diagnostics" json:"supports-diagnostics"`
+	// SupportsInstanceSweep indicates that the provider implements the ListControllerInstances
+	// command. If set, GARM can look for instances created by this controller that no longer
+	// have a corresponding database record.
+	SupportsInstanceSweep bool `toml:"supports_instance_sweep" json:"supports-instance-sweep"`
+	// SupportsSnapshot indicates that the provider implements the CreateInstanceSnapshot
+	// command. If set, pools using this provider can build warm images.
+	SupportsSnapshot bool `toml:"supports_snapshot" json:"supports-snapshot"`
+	// SupportsPoolValidation indicates that the provider implements the ValidatePool
+	// command. If set, the image and flavor of pools are checked when pools are created
+	// or updated.
+	SupportsPoolValidation bool `toml:"supports_pool_validation" json:"supports-pool-validation"`
+	// PauseWhenUnhealthy stops the creation of new instances in pools that use this
+	// provider, while the provider fails its health checks.
+	PauseWhenUnhealthy bool     `toml:"pause_when_unhealthy" json:"pause-when-unhealthy"`
+	External           External `toml:"external" json:"external"`
+	// NameConstraints defines the limits this provider imposes on instance names.
+	// GARM will adjust the names it generates to fit these limits.
+	NameConstraints NameConstraints `toml:"name_constraints" json:"name-constraints"`
+}
+
+// NameConstraints holds the instance name constraints of a provider.
+type NameConstraints struct {
+	// MaxLength is the maximum length of an instance name. A value of 0 means no limit.
+	MaxLength uint `toml:"max_length" json:"max-length"`
+	// Charset is the set of characters allowed in an instance name. Valid values
+	// are "alphanumeric" and "dns-label". If empty, any character is allowed.
+	Charset params.NameCharset `toml:"charset" json:"charset"`
+}
+
+func (n *NameConstraints) Validate() error {
+	switch n.Charset {
+	case params.NameCharsetAny, params.NameCharsetAlphanumeric, params.NameCharsetDNSLabel:
+	default:
+		return fmt.Errorf("invalid charset: %s", n.Charset)
+	}
+
+	if err := n.AsParams().ValidatePrefix(params.DefaultRunnerPrefix); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (n *NameConstraints) AsParams() params.InstanceNameConstraints {
+	return params.InstanceNameConstraints{
+		MaxLength: n.MaxLength,
+		Charset:   n.Charset,
+	}
 }
 
 func (p *Provider) Validate() error {
@@ -474,6 +681,10 @@ func (p *Provider) Validate() error {
 	default:
 		return fmt.Errorf("unknown provider type: %s", p.ProviderType)
 	}
+
+	if err := p.NameConstraints.Validate(); err != nil {
+		return fmt.Errorf("invalid name constraints: %w", err)
+	}
 	return nil
 }
 
@@ -483,6 +694,7 @@ type Database struct {
 	DbBackend DBBackendType `toml:"backend" json:"backend"`
 	MySQL     MySQL         `toml:"mysql" json:"mysql"`
 	SQLite    SQLite        `toml:"sqlite3" json:"sqlite3"`
+	Postgres  Postgres      `toml:"postgres" json:"postgres"`
 	// Passphrase is used to encrypt any sensitive info before
 	// inserting it into the database. This is just temporary until
 	// we move to something like vault or barbican for secrets storage.
@@ -490,6 +702,11 @@ type Database struct {
 	// in the DB. This field must be set and must be exactly 32 characters.
 	Passphrase string `toml:"passphrase"`
 
+	// Secrets configures where the keys used to encrypt sensitive info are kept.
+	// The passphrase is still required, as it is used to read data that was
+	// encrypted before a different backend was configured.
+	Secrets Secrets `toml:"secrets" json:"secrets"`
+
 	// MigrateCredentials is a list of github credentials that need to be migrated
 	// from the config file to the database. This field will be removed once GARM
 	// reaches version 0.2.x. It's only meant to be used for the migration process.
@@ -513,6 +730,11 @@ func (d *Database) GormParams() (dbType DBBackendType, uri string, err error) {
 		if err != nil {
 			return "", "", errors.Wrap(err, "fetching sqlite3 connection string")
 		}
+	case PostgresBackend:
+		uri, err = d.Postgres.ConnectionString()
+		if err != nil {
+			return "", "", errors.Wrap(err, "fetching postgres connection string")
+		}
 	default:
 		return "", "", fmt.Errorf("invalid database backend: %s", dbType)
 	}
@@ -534,6 +756,10 @@ func (d *Database) Validate() error {
 		return fmt.Errorf("database passphrase is too weak")
 	}
 
+	if err := d.Secrets.Validate(); err != nil {
+		return fmt.Errorf("validating secrets config: %w", err)
+	}
+
 	switch d.DbBackend {
 	case MySQLBackend:
 		if err := d.MySQL.Validate(); err != nil {
@@ -543,12 +769,195 @@ func (d *Database) Validate() error {
 		if err := d.SQLite.Validate(); err != nil {
 			return fmt.Errorf("validating sqlite3 config: %w", err)
 		}
+	case PostgresBackend:
+		if err := d.Postgres.Validate(); err != nil {
+			return fmt.Errorf("validating postgres config: %w", err)
+		}
 	default:
 		return fmt.Errorf("invalid database backend: %s", d.DbBackend)
 	}
 	return nil
 }
 
+type SecretsBackendType string
+
+const (
+	// PassphraseSecretsBackend encrypts secrets with a key derived from the
+	// database passphrase.
+	PassphraseSecretsBackend SecretsBackendType = "passphrase"
+	// VaultSecretsBackend encrypts secrets with data keys generated by the
+	// HashiCorp Vault transit secrets engine.
+	VaultSecretsBackend SecretsBackendType = "vault"
+	// AWSKMSSecretsBackend encrypts secrets with data keys generated by AWS KMS.
+	AWSKMSSecretsBackend SecretsBackendType = "awskms"
+)
+
+// Secrets is the config for the backend used to encrypt sensitive info stored in
+// the database. When using vault or awskms, secrets are encrypted with a data key
+// and only the encrypted data key is stored next to them (envelope encryption).
+type Secrets struct {
+	// Backend is the secrets backend. Defaults to "passphrase".
+	Backend SecretsBackendType `toml:"backend" json:"backend"`
+	Vault   Vault              `toml:"vault" json:"vault"`
+	AWSKMS  AWSKMS             `toml:"awskms" json:"awskms"`
+}
+
+// GetBackend returns the configured backend or the passphrase backend if none
+// was set.
+func (s *Secrets) GetBackend() SecretsBackendType {
+	if s.Backend == "" {
+		return PassphraseSecretsBackend
+	}
+	return s.Backend
+}
+
+// Validate validates the secrets config.
+func (s *Secrets) Validate() error {
+	switch s.GetBackend() {
+	case PassphraseSecretsBackend:
+	case VaultSecretsBackend:
+		if err := s.Vault.Validate(); err != nil {
+			return fmt.Errorf("error validating vault: %w", err)
+		}
+	case AWSKMSSecretsBackend:
+		if err := s.AWSKMS.Validate(); err != nil {
+			return fmt.Errorf("error validating awskms: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid secrets backend %q", s.Backend)
+	}
+	return nil
+}
+
+// Vault holds the details needed to use a key of the Vault transit secrets engine.
+type Vault struct {
+	// Address is the URL of the Vault server.
+	Address string `toml:"address" json:"address"`
+	// Token is the Vault token. If empty, the VAULT_TOKEN environment variable is used.
+	Token string `toml:"token" json:"token"`
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string `toml:"namespace" json:"namespace"`
+	// MountPath is the path the transit engine is mounted at. Defaults to "transit".
+	MountPath string `toml:"mount_path" json:"mount-path"`
+	// KeyName is the name of the transit key. The key must be of type aes256-gcm96
+	// or chacha20-poly1305.
+	KeyName string `toml:"key_name" json:"key-name"`
+	// CACertFile is the path to a CA bundle used to validate the Vault certificate.
+	CACertFile string `toml:"ca_cert_file" json:"ca-cert-file"`
+}
+
+// GetToken returns the configured token or the value of VAULT_TOKEN.
+func (v *Vault) GetToken() string {
+	if v.Token != "" {
+		return v.Token
+	}
+	return os.Getenv("VAULT_TOKEN")
+}
+
+// GetMountPath returns the mount path of the transit engine.
+func (v *Vault) GetMountPath() string {
+	if v.MountPath == "" {
+		return "transit"
+	}
+	return strings.Trim(v.MountPath, "/")
+}
+
+// CACertBundle returns the contents of the CA bundle, if one was set.
+func (v *Vault) CACertBundle() ([]byte, error) {
+	if v.CACertFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(v.CACertFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("failed to parse CA bundle")
+	}
+	return data, nil
+}
+
+// Validate validates the vault config.
+func (v *Vault) Validate() error {
+	if v.Address == "" {
+		return fmt.Errorf("missing address")
+	}
+	u, err := url.ParseRequestURI(v.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid address scheme %q", u.Scheme)
+	}
+	if v.KeyName == "" {
+		return fmt.Errorf("missing key_name")
+	}
+	if v.GetToken() == "" {
+		return fmt.Errorf("missing token and VAULT_TOKEN is not set")
+	}
+	if _, err := v.CACertBundle(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AWSKMS holds the details needed to use an AWS KMS key.
+type AWSKMS struct {
+	// Region is the AWS region of the key.
+	Region string `toml:"region" json:"region"`
+	// KeyID is the ID, ARN or alias of a symmetric KMS key.
+	KeyID string `toml:"key_id" json:"key-id"`
+	// Endpoint overrides the KMS endpoint. Defaults to https://kms.<region>.amazonaws.com.
+	Endpoint string `toml:"endpoint" json:"endpoint"`
+	// AccessKeyID, SecretAccessKey and SessionToken default to the values of the
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+	// variables.
+	AccessKeyID     string `toml:"access_key_id" json:"access-key-id"`
+	SecretAccessKey string `toml:"secret_access_key" json:"secret-access-key"`
+	SessionToken    string `toml:"session_token" json:"session-token"`
+}
+
+// GetEndpoint returns the KMS endpoint.
+func (a *AWSKMS) GetEndpoint() string {
+	if a.Endpoint != "" {
+		return strings.TrimSuffix(a.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://kms.%s.amazonaws.com", a.Region)
+}
+
+// GetCredentials returns the configured credentials, falling back to the AWS
+// environment variables if no access key was set.
+func (a *AWSKMS) GetCredentials() (accessKeyID, secretAccessKey, sessionToken string) {
+	if a.AccessKeyID != "" {
+		return a.AccessKeyID, a.SecretAccessKey, a.SessionToken
+	}
+	return os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")
+}
+
+// Validate validates the awskms config.
+func (a *AWSKMS) Validate() error {
+	if a.Region == "" {
+		return fmt.Errorf("missing region")
+	}
+	if a.KeyID == "" {
+		return fmt.Errorf("missing key_id")
+	}
+	if a.Endpoint != "" {
+		u, err := url.ParseRequestURI(a.Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid endpoint scheme %q", u.Scheme)
+		}
+	}
+	if accessKeyID, secretAccessKey, _ := a.GetCredentials(); accessKeyID == "" || secretAccessKey == "" {
+		return fmt.Errorf("missing access_key_id or secret_access_key")
+	}
+	return nil
+}
+
 // SQLite is the config entry for the sqlite3 section
 type SQLite struct {
 	DBFile string `toml:"db_file" json:"db-file"`
@@ -605,6 +1014,58 @@ func (m *MySQL) ConnectionString() (string, error) {
 	return connString, nil
 }
 
+// Postgres is the config entry for the postgres section
+type Postgres struct {
+	Username     string `toml:"username" json:"username"`
+	Password     string `toml:"password" json:"password"`
+	Hostname     string `toml:"hostname" json:"hostname"`
+	Port         uint16 `toml:"port" json:"port"`
+	DatabaseName string `toml:"database" json:"database"`
+	// SSLMode is the libpq sslmode used to connect to the server. Defaults
+	// to "require".
+	SSLMode string `toml:"sslmode" json:"sslmode"`
+}
+
+// Validate validates a Postgres config entry
+func (p *Postgres) Validate() error {
+	if p.Username == "" || p.Password == "" || p.Hostname == "" || p.DatabaseName == "" {
+		return fmt.Errorf(
+			"database, username, password, hostname are mandatory parameters for the database section")
+	}
+
+	switch p.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("invalid sslmode: %s", p.SSLMode)
+	}
+	return nil
+}
+
+// ConnectionString returns a gorm compatible connection string
+func (p *Postgres) ConnectionString() (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	port := p.Port
+	if port == 0 {
+		port = 5432
+	}
+	sslMode := p.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(p.Username, p.Password),
+		Host:     net.JoinHostPort(p.Hostname, strconv.FormatUint(uint64(port), 10)),
+		Path:     "/" + p.DatabaseName,
+		RawQuery: url.Values{"sslmode": []string{sslMode}, "connect_timeout": []string{"5"}}.Encode(),
+	}
+	return connURL.String(), nil
+}
+
 // TLSConfig is the API server TLS config
 type TLSConfig struct {
 	CRT string `toml:"certificate" json:"certificate"`
@@ -634,6 +1095,228 @@ type Metrics struct {
 	// Period defines the internal period at which internal metrics are getting updated
 	// and propagated to the /metrics endpoint
 	Period time.Duration `toml:"period" json:"period"`
+	// RemoteWrite configures pushing the metrics to a Prometheus remote write endpoint.
+	RemoteWrite RemoteWrite `toml:"remote_write" json:"remote-write"`
+}
+
+// Validate validates the metrics config.
+func (m *Metrics) Validate() error {
+	if err := m.RemoteWrite.Validate(); err != nil {
+		return fmt.Errorf("error validating remote_write config: %w", err)
+	}
+	return nil
+}
+
+// RemoteWrite is the config for pushing metrics to an endpoint that implements
+// the Prometheus remote write protocol, for installs where GARM can't be scraped.
+type RemoteWrite struct {
+	// URL is the remote write endpoint. Pushing metrics is disabled if empty.
+	URL string `toml:"url" json:"url"`
+	// Interval is the interval at which metrics are pushed.
+	Interval time.Duration `toml:"interval" json:"interval"`
+	// Username and Password are used for basic authentication.
+	Username string `toml:"username" json:"username"`
+	Password string `toml:"password" json:"password"`
+	// BearerToken is sent in the Authorization header.
+	BearerToken string `toml:"bearer_token" json:"bearer-token"`
+	// Headers are extra HTTP headers added to each request. Some hosted
+	// services use them for tenant selection.
+	Headers map[string]string `toml:"headers" json:"headers"`
+}
+
+// Enabled returns true if a remote write endpoint is configured.
+func (r *RemoteWrite) Enabled() bool {
+	return r.URL != ""
+}
+
+func (r *RemoteWrite) Validate() error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	u, err := url.ParseRequestURI(r.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme %q", u.Scheme)
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	if r.Username == "" && r.Password != "" {
+		return fmt.Errorf("password set without username")
+	}
+	if r.Username != "" && r.BearerToken != "" {
+		return fmt.Errorf("basic auth and bearer_token are mutually exclusive")
+	}
+	return nil
+}
+
+// PushInterval returns the configured interval, or the default interval if none
+// is configured.
+func (r *RemoteWrite) PushInterval() time.Duration {
+	if r.Interval == 0 {
+		return appdefaults.DefaultRemoteWriteInterval
+	}
+	return r.Interval
+}
+
+// Tracing is the config for exporting traces of the path a workflow job takes through
+// GARM, from the webhook to the creation of the runner, to an OpenTelemetry collector.
+type Tracing struct {
+	// Enable defines if traces are exported.
+	Enable bool `toml:"enable" json:"enable"`
+	// Endpoint is the base URL of a collector that accepts OTLP over HTTP. Spans
+	// are sent to the /v1/traces path of this URL.
+	Endpoint string `toml:"endpoint" json:"endpoint"`
+	// ServiceName is the name of the service reported with the spans.
+	ServiceName string `toml:"service_name" json:"service-name"`
+	// Headers are extra HTTP headers added to each export request. Hosted
+	// collectors usually require an API key to be set in a header.
+	Headers map[string]string `toml:"headers" json:"headers"`
+}
+
+// Validate validates the tracing config.
+func (t *Tracing) Validate() error {
+	if !t.Enable {
+		return nil
+	}
+
+	if t.Endpoint == "" {
+		return fmt.Errorf("missing endpoint")
+	}
+	u, err := url.ParseRequestURI(t.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid endpoint scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// Service returns the configured service name, or the default service name if none
+// is configured.
+func (t *Tracing) Service() string {
+	if t.ServiceName == "" {
+		return appdefaults.DefaultTracingServiceName
+	}
+	return t.ServiceName
+}
+
+// Jobs is the config for how long completed jobs are kept, and where they are
+// archived before they are removed.
+type Jobs struct {
+	// Retention is the amount of time completed jobs are kept in the database.
+	// Completed jobs are used to report on how runners are used, so the retention
+	// can't be lower than the default of 24h.
+	Retention time.Duration `toml:"retention" json:"retention"`
+	// Archive configures where completed jobs are exported to before they are
+	// removed from the database. Jobs are not archived by default.
+	Archive JobArchive `toml:"archive" json:"archive"`
+}
+
+// Validate validates the jobs config.
+func (j *Jobs) Validate() error {
+	if j.Retention != 0 && j.Retention < appdefaults.DefaultCompletedJobsRetention {
+		return fmt.Errorf("retention must be at least %s", appdefaults.DefaultCompletedJobsRetention)
+	}
+	if err := j.Archive.Validate(); err != nil {
+		return fmt.Errorf("error validating archive: %w", err)
+	}
+	return nil
+}
+
+// CompletedJobsRetention returns the configured retention, or the default retention
+// if none is configured.
+func (j *Jobs) CompletedJobsRetention() time.Duration {
+	if j.Retention == 0 {
+		return appdefaults.DefaultCompletedJobsRetention
+	}
+	return j.Retention
+}
+
+type JobArchiveType string
+
+const (
+	// JobArchiveTypeFile writes the archives to a local directory.
+	JobArchiveTypeFile JobArchiveType = "file"
+	// JobArchiveTypeS3 uploads the archives to an S3 compatible bucket.
+	JobArchiveTypeS3 JobArchiveType = "s3"
+)
+
+// JobArchive is the config for archiving completed jobs. Each archive is a gzip
+// compressed file, holding one JSON encoded job per line.
+type JobArchive struct {
+	// Type is the type of archive. Leave empty to remove jobs without archiving them.
+	Type JobArchiveType `toml:"type" json:"type"`
+	// Directory is the directory the archives are written to, if the type is "file".
+	Directory string `toml:"directory" json:"directory"`
+	// S3 holds the details of the bucket the archives are uploaded to, if the type
+	// is "s3".
+	S3 S3Bucket `toml:"s3" json:"s3"`
+}
+
+// Validate validates the job archive config.
+func (a *JobArchive) Validate() error {
+	switch a.Type {
+	case "":
+	case JobArchiveTypeFile:
+		if a.Directory == "" {
+			return fmt.Errorf("missing directory")
+		}
+		if !filepath.IsAbs(a.Directory) {
+			return fmt.Errorf("directory must be an absolute path")
+		}
+	case JobArchiveTypeS3:
+		if err := a.S3.Validate(); err != nil {
+			return fmt.Errorf("error validating s3: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid archive type %q", a.Type)
+	}
+	return nil
+}
+
+// S3Bucket holds the details needed to upload objects to an S3 compatible bucket.
+// Objects are addressed using the path style (<endpoint>/<bucket>/<key>), which
+// both AWS S3 and self hosted services like MinIO support.
+type S3Bucket struct {
+	// Endpoint is the URL of the S3 API. For AWS, this is
+	// https://s3.<region>.amazonaws.com.
+	Endpoint string `toml:"endpoint" json:"endpoint"`
+	// Region is the region requests are signed for.
+	Region string `toml:"region" json:"region"`
+	Bucket string `toml:"bucket" json:"bucket"`
+	// Prefix is prepended to the names of the uploaded objects.
+	Prefix          string `toml:"prefix" json:"prefix"`
+	AccessKeyID     string `toml:"access_key_id" json:"access-key-id"`
+	SecretAccessKey string `toml:"secret_access_key" json:"secret-access-key"`
+}
+
+// Validate validates the S3 bucket config.
+func (s *S3Bucket) Validate() error {
+	if s.Endpoint == "" {
+		return fmt.Errorf("missing endpoint")
+	}
+	u, err := url.ParseRequestURI(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid endpoint scheme %q", u.Scheme)
+	}
+	if s.Region == "" {
+		return fmt.Errorf("missing region")
+	}
+	if s.Bucket == "" {
+		return fmt.Errorf("missing bucket")
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return fmt.Errorf("missing access_key_id or secret_access_key")
+	}
+	return nil
 }
 
 // ParseDuration parses the configured duration and returns a time.Duration of 0
@@ -668,6 +1351,17 @@ type APIServer struct {
 	UseTLS      bool      `toml:"use_tls" json:"use-tls"`
 	TLSConfig   TLSConfig `toml:"tls" json:"tls"`
 	CORSOrigins []string  `toml:"cors_origins" json:"cors-origins"`
+	// InternalListener is an optional, separate listener on which GARM will serve
+	// the metadata, callback and webhook routes. When set, these routes are no
+	// longer served by the main API listener. This allows you to only expose the
+	// management API to administrators, while runners and GitHub talk to a
+	// different address.
+	InternalListener *InternalListener `toml:"internal_listener" json:"internal-listener"`
+	// InstanceAllowedCIDRs is a list of networks allowed to call into the callback
+	// and metadata endpoints. These are usually the networks of the providers in which
+	// runners are created. Requests coming from other addresses are rejected before
+	// the instance token is checked. All addresses are allowed if empty.
+	InstanceAllowedCIDRs []string `toml:"instance_allowed_cidrs" json:"instance-allowed-cidrs"`
 }
 
 // BindAddress returns a host:port string.
@@ -682,11 +1376,57 @@ func (a *APIServer) Validate() error {
 			return fmt.Errorf("invalid tls config: %w", err)
 		}
 	}
-	if a.Port > 65535 || a.Port < 1 {
-		return fmt.Errorf("invalid port nr %d", a.Port)
+	if err := validateBindAddress(a.Bind, a.Port); err != nil {
+		return err
+	}
+
+	for _, cidr := range a.InstanceAllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid instance_allowed_cidrs entry %q: %w", cidr, err)
+		}
 	}
 
-	ip := net.ParseIP(a.Bind)
+	if a.InternalListener != nil {
+		if err := a.InternalListener.Validate(); err != nil {
+			return fmt.Errorf("invalid internal_listener config: %w", err)
+		}
+		if a.InternalListener.Port == a.Port && bindAddressesOverlap(a.Bind, a.InternalListener.Bind) {
+			return fmt.Errorf("internal_listener must use a different port or bind address than the apiserver")
+		}
+	}
+	return nil
+}
+
+// InternalListener holds the config for the listener that serves the metadata,
+// callback and webhook routes.
+type InternalListener struct {
+	Bind      string    `toml:"bind" json:"bind"`
+	Port      int       `toml:"port" json:"port"`
+	UseTLS    bool      `toml:"use_tls" json:"use-tls"`
+	TLSConfig TLSConfig `toml:"tls" json:"tls"`
+}
+
+// BindAddress returns a host:port string.
+func (i *InternalListener) BindAddress() string {
+	return fmt.Sprintf("%s:%d", i.Bind, i.Port)
+}
+
+// Validate validates the internal listener config
+func (i *InternalListener) Validate() error {
+	if i.UseTLS {
+		if err := i.TLSConfig.Validate(); err != nil {
+			return fmt.Errorf("invalid tls config: %w", err)
+		}
+	}
+	return validateBindAddress(i.Bind, i.Port)
+}
+
+func validateBindAddress(bind string, port int) error {
+	if port > 65535 || port < 1 {
+		return fmt.Errorf("invalid port nr %d", port)
+	}
+
+	ip := net.ParseIP(bind)
 	if ip == nil {
 		// No need for deeper validation here, as any invalid
 		// IP address specified in this setting will raise an error
@@ -696,6 +1436,18 @@ func (a *APIServer) Validate() error {
 	return nil
 }
 
+// bindAddressesOverlap returns true if listeners bound to the two addresses on the
+// same port would conflict. This is the case if the addresses are the same, or if
+// either of them binds to all addresses.
+func bindAddressesOverlap(a, b string) bool {
+	ipA := net.ParseIP(a)
+	ipB := net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	return ipA.Equal(ipB) || ipA.IsUnspecified() || ipB.IsUnspecified()
+}
+
 type timeToLive string
 
 func (d *timeToLive) ParseDuration() (time.Duration, error) {
@@ -735,6 +1487,59 @@ func (d *timeToLive) UnmarshalText(text []byte) error {
 type JWTAuth struct {
 	Secret     string     `toml:"secret" json:"secret"`
 	TimeToLive timeToLive `toml:"time_to_live" json:"time-to-live"`
+	// AllowImpersonation allows the admin to get short lived tokens that act on
+	// behalf of another user. Every request made with such a token is audited.
+	// Disabling this option invalidates all impersonation tokens issued so far.
+	AllowImpersonation bool `toml:"allow_impersonation" json:"allow-impersonation"`
+	// InstanceClockSkewTolerance is the clock skew above which GARM warns about
+	// instances with a badly set clock. It does not affect the validation of instance
+	// tokens, which are issued and validated using the clock of GARM. Defaults to 5 minutes.
+	InstanceClockSkewTolerance time.Duration `toml:"instance_clock_skew_tolerance" json:"instance-clock-skew-tolerance"`
+	// DisableLoginProtection disables the login rate limits and the lockout of users
+	// after repeated failed logins.
+	DisableLoginProtection bool `toml:"disable_login_protection" json:"disable-login-protection"`
+	// LoginRateLimit is the number of login attempts allowed each minute, for each
+	// username and source address pair and for each source address. Defaults to 10.
+	LoginRateLimit uint `toml:"login_rate_limit" json:"login-rate-limit"`
+	// LoginLockoutThreshold is the number of consecutive failed logins after which
+	// a user is locked out. Lockouts apply to logins from any address, so anyone able
+	// to reach the API can lock a user out. Defaults to 0, which disables lockouts.
+	LoginLockoutThreshold uint `toml:"login_lockout_threshold" json:"login-lockout-threshold"`
+	// LoginLockoutDuration is the amount of time a user is locked out for. Defaults
+	// to 15 minutes.
+	LoginLockoutDuration time.Duration `toml:"login_lockout_duration" json:"login-lockout-duration"`
+}
+
+// InstanceClockSkew returns the configured clock skew tolerance for instances
+// or the default tolerance if no value is configured.
+func (j *JWTAuth) InstanceClockSkew() time.Duration {
+	if j.InstanceClockSkewTolerance == 0 {
+		return appdefaults.DefaultInstanceClockSkewTolerance
+	}
+	return j.InstanceClockSkewTolerance
+}
+
+// LoginAttemptsPerMinute returns the configured login rate limit or the default
+// limit if no value is configured.
+func (j *JWTAuth) LoginAttemptsPerMinute() uint {
+	if j.LoginRateLimit == 0 {
+		return appdefaults.DefaultLoginRateLimit
+	}
+	return j.LoginRateLimit
+}
+
+// LockoutEnabled returns true if users are locked out after repeated failed logins.
+func (j *JWTAuth) LockoutEnabled() bool {
+	return !j.DisableLoginProtection && j.LoginLockoutThreshold > 0
+}
+
+// LockoutDuration returns the configured lockout duration or the default duration
+// if no value is configured.
+func (j *JWTAuth) LockoutDuration() time.Duration {
+	if j.LoginLockoutDuration == 0 {
+		return appdefaults.DefaultLoginLockoutDuration
+	}
+	return j.LoginLockoutDuration
 }
 
 // Validate validates the JWTAuth config
@@ -743,6 +1548,14 @@ func (j *JWTAuth) Validate() error {
 		return fmt.Errorf("invalid time_to_live: %w", err)
 	}
 
+	if j.InstanceClockSkewTolerance < 0 || j.InstanceClockSkewTolerance > appdefaults.MaxInstanceClockSkewTolerance {
+		return fmt.Errorf("instance_clock_skew_tolerance must be between 0 and %s", appdefaults.MaxInstanceClockSkewTolerance)
+	}
+
+	if j.LoginLockoutDuration < 0 {
+		return fmt.Errorf("login_lockout_duration must not be negative")
+	}
+
 	if j.Secret == "" {
 		return fmt.Errorf("invalid JWT secret")
 	}
@@ -7,6 +7,10 @@ import (
 
 	params "github.com/cloudbase/garm/params"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
 )
 
 // Store is an autogenerated mock type for the Store type
@@ -14,17 +18,63 @@ type Store struct {
 	mock.Mock
 }
 
-// AddInstanceEvent provides a mock function with given fields: ctx, instanceName, event, eventLevel, eventMessage
-func (_m *Store) AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, eventMessage string) error {
-	ret := _m.Called(ctx, instanceName, event, eventLevel, eventMessage)
+// AcquireEntityLease provides a mock function with given fields: ctx, entityID, nodeID, expiresAt
+func (_m *Store) AcquireEntityLease(ctx context.Context, entityID string, nodeID string, expiresAt time.Time) (bool, error) {
+	ret := _m.Called(ctx, entityID, nodeID, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireEntityLease")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (bool, error)); ok {
+		return rf(ctx, entityID, nodeID, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) bool); ok {
+		r0 = rf(ctx, entityID, nodeID, expiresAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, entityID, nodeID, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddEntityEvent provides a mock function with given fields: ctx, entity, event, eventLevel, statusMessage, maxEvents
+func (_m *Store) AddEntityEvent(ctx context.Context, entity params.GithubEntity, event params.EventType, eventLevel params.EventLevel, statusMessage string, maxEvents int) error {
+	ret := _m.Called(ctx, entity, event, eventLevel, statusMessage, maxEvents)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddEntityEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, params.EventType, params.EventLevel, string, int) error); ok {
+		r0 = rf(ctx, entity, event, eventLevel, statusMessage, maxEvents)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddInstanceEvent provides a mock function with given fields: ctx, instanceName, event, eventLevel, eventMessage, maxEvents
+func (_m *Store) AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, eventMessage string, maxEvents int) error {
+	ret := _m.Called(ctx, instanceName, event, eventLevel, eventMessage, maxEvents)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AddInstanceEvent")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, params.EventType, params.EventLevel, string) error); ok {
-		r0 = rf(ctx, instanceName, event, eventLevel, eventMessage)
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.EventType, params.EventLevel, string, int) error); ok {
+		r0 = rf(ctx, instanceName, event, eventLevel, eventMessage, maxEvents)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -78,6 +128,146 @@ func (_m *Store) ControllerInfo() (params.ControllerInfo, error) {
 	return r0, r1
 }
 
+// CreateAPIToken provides a mock function with given fields: ctx, userID, param, tokenHash
+func (_m *Store) CreateAPIToken(ctx context.Context, userID string, param params.CreateAPITokenParams, tokenHash string) (params.APIToken, error) {
+	ret := _m.Called(ctx, userID, param, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIToken")
+	}
+
+	var r0 params.APIToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.CreateAPITokenParams, string) (params.APIToken, error)); ok {
+		return rf(ctx, userID, param, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.CreateAPITokenParams, string) params.APIToken); ok {
+		r0 = rf(ctx, userID, param, tokenHash)
+	} else {
+		r0 = ret.Get(0).(params.APIToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.CreateAPITokenParams, string) error); ok {
+		r1 = rf(ctx, userID, param, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAuditRecord provides a mock function with given fields: ctx, record
+func (_m *Store) CreateAuditRecord(ctx context.Context, record params.AuditRecord) (params.AuditRecord, error) {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAuditRecord")
+	}
+
+	var r0 params.AuditRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.AuditRecord) (params.AuditRecord, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.AuditRecord) params.AuditRecord); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Get(0).(params.AuditRecord)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.AuditRecord) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateCapacityReservation provides a mock function with given fields: ctx, param
+func (_m *Store) CreateCapacityReservation(ctx context.Context, param params.CreateCapacityReservationParams) (params.CapacityReservation, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateCapacityReservation")
+	}
+
+	var r0 params.CapacityReservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateCapacityReservationParams) (params.CapacityReservation, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateCapacityReservationParams) params.CapacityReservation); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.CapacityReservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.CreateCapacityReservationParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateControllerIDMigration provides a mock function with given fields: ctx, newControllerID
+func (_m *Store) CreateControllerIDMigration(ctx context.Context, newControllerID uuid.UUID) (params.ControllerIDMigration, error) {
+	ret := _m.Called(ctx, newControllerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateControllerIDMigration")
+	}
+
+	var r0 params.ControllerIDMigration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (params.ControllerIDMigration, error)); ok {
+		return rf(ctx, newControllerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) params.ControllerIDMigration); ok {
+		r0 = rf(ctx, newControllerID)
+	} else {
+		r0 = ret.Get(0).(params.ControllerIDMigration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, newControllerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateDenyRule provides a mock function with given fields: ctx, param
+func (_m *Store) CreateDenyRule(ctx context.Context, param params.CreateDenyRuleParams) (params.DenyRule, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDenyRule")
+	}
+
+	var r0 params.DenyRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateDenyRuleParams) (params.DenyRule, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateDenyRuleParams) params.DenyRule); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.DenyRule)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.CreateDenyRuleParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateEnterprise provides a mock function with given fields: ctx, name, credentialsName, webhookSecret, poolBalancerType
 func (_m *Store) CreateEnterprise(ctx context.Context, name string, credentialsName string, webhookSecret string, poolBalancerType params.PoolBalancerType) (params.Enterprise, error) {
 	ret := _m.Called(ctx, name, credentialsName, webhookSecret, poolBalancerType)
@@ -190,6 +380,34 @@ func (_m *Store) CreateGithubEndpoint(ctx context.Context, param params.CreateGi
 	return r0, r1
 }
 
+// CreateIdempotencyRecord provides a mock function with given fields: ctx, record
+func (_m *Store) CreateIdempotencyRecord(ctx context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateIdempotencyRecord")
+	}
+
+	var r0 params.IdempotencyRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.IdempotencyRecord) (params.IdempotencyRecord, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.IdempotencyRecord) params.IdempotencyRecord); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Get(0).(params.IdempotencyRecord)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.IdempotencyRecord) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateInstance provides a mock function with given fields: ctx, poolID, param
 func (_m *Store) CreateInstance(ctx context.Context, poolID string, param params.CreateInstanceParams) (params.Instance, error) {
 	ret := _m.Called(ctx, poolID, param)
@@ -302,6 +520,34 @@ func (_m *Store) CreateRepository(ctx context.Context, owner string, name string
 	return r0, r1
 }
 
+// CreateRoleGrant provides a mock function with given fields: ctx, user, param
+func (_m *Store) CreateRoleGrant(ctx context.Context, user string, param params.CreateRoleGrantParams) (params.RoleGrant, error) {
+	ret := _m.Called(ctx, user, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRoleGrant")
+	}
+
+	var r0 params.RoleGrant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.CreateRoleGrantParams) (params.RoleGrant, error)); ok {
+		return rf(ctx, user, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.CreateRoleGrantParams) params.RoleGrant); ok {
+		r0 = rf(ctx, user, param)
+	} else {
+		r0 = ret.Get(0).(params.RoleGrant)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.CreateRoleGrantParams) error); ok {
+		r1 = rf(ctx, user, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateUser provides a mock function with given fields: ctx, user
 func (_m *Store) CreateUser(ctx context.Context, user params.NewUserParams) (params.User, error) {
 	ret := _m.Called(ctx, user)
@@ -330,35 +576,45 @@ func (_m *Store) CreateUser(ctx context.Context, user params.NewUserParams) (par
 	return r0, r1
 }
 
-// DeleteCompletedJobs provides a mock function with given fields: ctx
-func (_m *Store) DeleteCompletedJobs(ctx context.Context) error {
-	ret := _m.Called(ctx)
+// CreateWebhookDelivery provides a mock function with given fields: ctx, delivery
+func (_m *Store) CreateWebhookDelivery(ctx context.Context, delivery params.WebhookDelivery) (params.WebhookDelivery, error) {
+	ret := _m.Called(ctx, delivery)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteCompletedJobs")
+		panic("no return value specified for CreateWebhookDelivery")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
-		r0 = rf(ctx)
+	var r0 params.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.WebhookDelivery) (params.WebhookDelivery, error)); ok {
+		return rf(ctx, delivery)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.WebhookDelivery) params.WebhookDelivery); ok {
+		r0 = rf(ctx, delivery)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(params.WebhookDelivery)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, params.WebhookDelivery) error); ok {
+		r1 = rf(ctx, delivery)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// DeleteEnterprise provides a mock function with given fields: ctx, enterpriseID
-func (_m *Store) DeleteEnterprise(ctx context.Context, enterpriseID string) error {
-	ret := _m.Called(ctx, enterpriseID)
+// DeleteAPIToken provides a mock function with given fields: ctx, userID, tokenID
+func (_m *Store) DeleteAPIToken(ctx context.Context, userID string, tokenID string) error {
+	ret := _m.Called(ctx, userID, tokenID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteEnterprise")
+		panic("no return value specified for DeleteAPIToken")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, enterpriseID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, tokenID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -366,17 +622,17 @@ func (_m *Store) DeleteEnterprise(ctx context.Context, enterpriseID string) erro
 	return r0
 }
 
-// DeleteEntityPool provides a mock function with given fields: ctx, entity, poolID
-func (_m *Store) DeleteEntityPool(ctx context.Context, entity params.GithubEntity, poolID string) error {
-	ret := _m.Called(ctx, entity, poolID)
+// DeleteAuditRecordsBefore provides a mock function with given fields: ctx, before
+func (_m *Store) DeleteAuditRecordsBefore(ctx context.Context, before time.Time) error {
+	ret := _m.Called(ctx, before)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteEntityPool")
+		panic("no return value specified for DeleteAuditRecordsBefore")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, string) error); ok {
-		r0 = rf(ctx, entity, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, before)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -384,17 +640,17 @@ func (_m *Store) DeleteEntityPool(ctx context.Context, entity params.GithubEntit
 	return r0
 }
 
-// DeleteGithubCredentials provides a mock function with given fields: ctx, id
-func (_m *Store) DeleteGithubCredentials(ctx context.Context, id uint) error {
-	ret := _m.Called(ctx, id)
+// DeleteCapacityReservation provides a mock function with given fields: ctx, reservationID
+func (_m *Store) DeleteCapacityReservation(ctx context.Context, reservationID string) error {
+	ret := _m.Called(ctx, reservationID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteGithubCredentials")
+		panic("no return value specified for DeleteCapacityReservation")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, reservationID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -402,17 +658,17 @@ func (_m *Store) DeleteGithubCredentials(ctx context.Context, id uint) error {
 	return r0
 }
 
-// DeleteGithubEndpoint provides a mock function with given fields: ctx, name
-func (_m *Store) DeleteGithubEndpoint(ctx context.Context, name string) error {
-	ret := _m.Called(ctx, name)
+// DeleteCompletedJobs provides a mock function with given fields: ctx, completedBefore
+func (_m *Store) DeleteCompletedJobs(ctx context.Context, completedBefore time.Time) error {
+	ret := _m.Called(ctx, completedBefore)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteGithubEndpoint")
+		panic("no return value specified for DeleteCompletedJobs")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, name)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, completedBefore)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -420,17 +676,17 @@ func (_m *Store) DeleteGithubEndpoint(ctx context.Context, name string) error {
 	return r0
 }
 
-// DeleteInstance provides a mock function with given fields: ctx, poolID, instanceName
-func (_m *Store) DeleteInstance(ctx context.Context, poolID string, instanceName string) error {
-	ret := _m.Called(ctx, poolID, instanceName)
+// DeleteControllerNode provides a mock function with given fields: ctx, nodeID
+func (_m *Store) DeleteControllerNode(ctx context.Context, nodeID string) error {
+	ret := _m.Called(ctx, nodeID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteInstance")
+		panic("no return value specified for DeleteControllerNode")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, poolID, instanceName)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, nodeID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -438,17 +694,17 @@ func (_m *Store) DeleteInstance(ctx context.Context, poolID string, instanceName
 	return r0
 }
 
-// DeleteJob provides a mock function with given fields: ctx, jobID
-func (_m *Store) DeleteJob(ctx context.Context, jobID int64) error {
-	ret := _m.Called(ctx, jobID)
+// DeleteDenyRule provides a mock function with given fields: ctx, ruleID
+func (_m *Store) DeleteDenyRule(ctx context.Context, ruleID string) error {
+	ret := _m.Called(ctx, ruleID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteJob")
+		panic("no return value specified for DeleteDenyRule")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
-		r0 = rf(ctx, jobID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, ruleID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -456,17 +712,17 @@ func (_m *Store) DeleteJob(ctx context.Context, jobID int64) error {
 	return r0
 }
 
-// DeleteOrganization provides a mock function with given fields: ctx, orgID
-func (_m *Store) DeleteOrganization(ctx context.Context, orgID string) error {
-	ret := _m.Called(ctx, orgID)
+// DeleteEnterprise provides a mock function with given fields: ctx, enterpriseID
+func (_m *Store) DeleteEnterprise(ctx context.Context, enterpriseID string) error {
+	ret := _m.Called(ctx, enterpriseID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteOrganization")
+		panic("no return value specified for DeleteEnterprise")
 	}
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, orgID)
+		r0 = rf(ctx, enterpriseID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -474,17 +730,17 @@ func (_m *Store) DeleteOrganization(ctx context.Context, orgID string) error {
 	return r0
 }
 
-// DeletePoolByID provides a mock function with given fields: ctx, poolID
-func (_m *Store) DeletePoolByID(ctx context.Context, poolID string) error {
-	ret := _m.Called(ctx, poolID)
+// DeleteEntityPool provides a mock function with given fields: ctx, entity, poolID
+func (_m *Store) DeleteEntityPool(ctx context.Context, entity params.GithubEntity, poolID string) error {
+	ret := _m.Called(ctx, entity, poolID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeletePoolByID")
+		panic("no return value specified for DeleteEntityPool")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, string) error); ok {
+		r0 = rf(ctx, entity, poolID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -492,17 +748,17 @@ func (_m *Store) DeletePoolByID(ctx context.Context, poolID string) error {
 	return r0
 }
 
-// DeleteRepository provides a mock function with given fields: ctx, repoID
-func (_m *Store) DeleteRepository(ctx context.Context, repoID string) error {
-	ret := _m.Called(ctx, repoID)
+// DeleteExpiredIdempotencyRecords provides a mock function with given fields: ctx
+func (_m *Store) DeleteExpiredIdempotencyRecords(ctx context.Context) error {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteRepository")
+		panic("no return value specified for DeleteExpiredIdempotencyRecords")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, repoID)
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -510,20 +766,218 @@ func (_m *Store) DeleteRepository(ctx context.Context, repoID string) error {
 	return r0
 }
 
-// FindPoolsMatchingAllTags provides a mock function with given fields: ctx, entityType, entityID, tags
-func (_m *Store) FindPoolsMatchingAllTags(ctx context.Context, entityType params.GithubEntityType, entityID string, tags []string) ([]params.Pool, error) {
-	ret := _m.Called(ctx, entityType, entityID, tags)
+// DeleteGithubCredentials provides a mock function with given fields: ctx, id
+func (_m *Store) DeleteGithubCredentials(ctx context.Context, id uint) error {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for FindPoolsMatchingAllTags")
+		panic("no return value specified for DeleteGithubCredentials")
 	}
 
-	var r0 []params.Pool
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntityType, string, []string) ([]params.Pool, error)); ok {
-		return rf(ctx, entityType, entityID, tags)
-	}
-	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntityType, string, []string) []params.Pool); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteGithubEndpoint provides a mock function with given fields: ctx, name
+func (_m *Store) DeleteGithubEndpoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteGithubEndpoint")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteIdempotencyRecord provides a mock function with given fields: ctx, userID, key
+func (_m *Store) DeleteIdempotencyRecord(ctx context.Context, userID string, key string) error {
+	ret := _m.Called(ctx, userID, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteIdempotencyRecord")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteInstance provides a mock function with given fields: ctx, poolID, instanceName
+func (_m *Store) DeleteInstance(ctx context.Context, poolID string, instanceName string) error {
+	ret := _m.Called(ctx, poolID, instanceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteInstance")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, poolID, instanceName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteJob provides a mock function with given fields: ctx, jobID
+func (_m *Store) DeleteJob(ctx context.Context, jobID int64) error {
+	ret := _m.Called(ctx, jobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, jobID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteOrganization provides a mock function with given fields: ctx, orgID
+func (_m *Store) DeleteOrganization(ctx context.Context, orgID string) error {
+	ret := _m.Called(ctx, orgID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOrganization")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePoolByID provides a mock function with given fields: ctx, poolID
+func (_m *Store) DeletePoolByID(ctx context.Context, poolID string) error {
+	ret := _m.Called(ctx, poolID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePoolByID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, poolID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteRepository provides a mock function with given fields: ctx, repoID
+func (_m *Store) DeleteRepository(ctx context.Context, repoID string) error {
+	ret := _m.Called(ctx, repoID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRepository")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, repoID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteRoleGrant provides a mock function with given fields: ctx, user, grantID
+func (_m *Store) DeleteRoleGrant(ctx context.Context, user string, grantID string) error {
+	ret := _m.Called(ctx, user, grantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRoleGrant")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, user, grantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteStaleControllerNodes provides a mock function with given fields: ctx, since
+func (_m *Store) DeleteStaleControllerNodes(ctx context.Context, since time.Time) error {
+	ret := _m.Called(ctx, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteStaleControllerNodes")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, since)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteWebhookDeliveriesBefore provides a mock function with given fields: ctx, before
+func (_m *Store) DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) error {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteWebhookDeliveriesBefore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindPoolsMatchingAllTags provides a mock function with given fields: ctx, entityType, entityID, tags
+func (_m *Store) FindPoolsMatchingAllTags(ctx context.Context, entityType params.GithubEntityType, entityID string, tags []string) ([]params.Pool, error) {
+	ret := _m.Called(ctx, entityType, entityID, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindPoolsMatchingAllTags")
+	}
+
+	var r0 []params.Pool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntityType, string, []string) ([]params.Pool, error)); ok {
+		return rf(ctx, entityType, entityID, tags)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntityType, string, []string) []params.Pool); ok {
 		r0 = rf(ctx, entityType, entityID, tags)
 	} else {
 		if ret.Get(0) != nil {
@@ -540,6 +994,34 @@ func (_m *Store) FindPoolsMatchingAllTags(ctx context.Context, entityType params
 	return r0, r1
 }
 
+// GetAPITokenByHash provides a mock function with given fields: ctx, tokenHash
+func (_m *Store) GetAPITokenByHash(ctx context.Context, tokenHash string) (params.APIToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPITokenByHash")
+	}
+
+	var r0 params.APIToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.APIToken, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.APIToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Get(0).(params.APIToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAdminUser provides a mock function with given fields: ctx
 func (_m *Store) GetAdminUser(ctx context.Context) (params.User, error) {
 	ret := _m.Called(ctx)
@@ -568,6 +1050,34 @@ func (_m *Store) GetAdminUser(ctx context.Context) (params.User, error) {
 	return r0, r1
 }
 
+// GetControllerIDMigration provides a mock function with given fields: ctx
+func (_m *Store) GetControllerIDMigration(ctx context.Context) (params.ControllerIDMigration, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetControllerIDMigration")
+	}
+
+	var r0 params.ControllerIDMigration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (params.ControllerIDMigration, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) params.ControllerIDMigration); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(params.ControllerIDMigration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetEnterprise provides a mock function with given fields: ctx, name, endpointName
 func (_m *Store) GetEnterprise(ctx context.Context, name string, endpointName string) (params.Enterprise, error) {
 	ret := _m.Called(ctx, name, endpointName)
@@ -736,6 +1246,34 @@ func (_m *Store) GetGithubEndpoint(ctx context.Context, name string) (params.Git
 	return r0, r1
 }
 
+// GetIdempotencyRecord provides a mock function with given fields: ctx, userID, key
+func (_m *Store) GetIdempotencyRecord(ctx context.Context, userID string, key string) (params.IdempotencyRecord, error) {
+	ret := _m.Called(ctx, userID, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIdempotencyRecord")
+	}
+
+	var r0 params.IdempotencyRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (params.IdempotencyRecord, error)); ok {
+		return rf(ctx, userID, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) params.IdempotencyRecord); ok {
+		r0 = rf(ctx, userID, key)
+	} else {
+		r0 = ret.Get(0).(params.IdempotencyRecord)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetInstanceByName provides a mock function with given fields: ctx, instanceName
 func (_m *Store) GetInstanceByName(ctx context.Context, instanceName string) (params.Instance, error) {
 	ret := _m.Called(ctx, instanceName)
@@ -904,6 +1442,34 @@ func (_m *Store) GetPoolInstanceByName(ctx context.Context, poolID string, insta
 	return r0, r1
 }
 
+// GetProviderEnvironment provides a mock function with given fields: ctx, providerName
+func (_m *Store) GetProviderEnvironment(ctx context.Context, providerName string) (params.ProviderEnvironment, error) {
+	ret := _m.Called(ctx, providerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProviderEnvironment")
+	}
+
+	var r0 params.ProviderEnvironment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.ProviderEnvironment, error)); ok {
+		return rf(ctx, providerName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.ProviderEnvironment); ok {
+		r0 = rf(ctx, providerName)
+	} else {
+		r0 = ret.Get(0).(params.ProviderEnvironment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, providerName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRepository provides a mock function with given fields: ctx, owner, name, endpointName
 func (_m *Store) GetRepository(ctx context.Context, owner string, name string, endpointName string) (params.Repository, error) {
 	ret := _m.Called(ctx, owner, name, endpointName)
@@ -1016,8 +1582,36 @@ func (_m *Store) GetUserByID(ctx context.Context, userID string) (params.User, e
 	return r0, r1
 }
 
-// HasAdminUser provides a mock function with given fields: ctx
-func (_m *Store) HasAdminUser(ctx context.Context) bool {
+// GetWebhookDelivery provides a mock function with given fields: ctx, deliveryID
+func (_m *Store) GetWebhookDelivery(ctx context.Context, deliveryID string) (params.WebhookDelivery, error) {
+	ret := _m.Called(ctx, deliveryID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookDelivery")
+	}
+
+	var r0 params.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.WebhookDelivery, error)); ok {
+		return rf(ctx, deliveryID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.WebhookDelivery); ok {
+		r0 = rf(ctx, deliveryID)
+	} else {
+		r0 = ret.Get(0).(params.WebhookDelivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, deliveryID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasAdminUser provides a mock function with given fields: ctx
+func (_m *Store) HasAdminUser(ctx context.Context) bool {
 	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
@@ -1062,6 +1656,36 @@ func (_m *Store) InitController() (params.ControllerInfo, error) {
 	return r0, r1
 }
 
+// ListAPITokens provides a mock function with given fields: ctx, userID
+func (_m *Store) ListAPITokens(ctx context.Context, userID string) ([]params.APIToken, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPITokens")
+	}
+
+	var r0 []params.APIToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]params.APIToken, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []params.APIToken); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.APIToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListAllInstances provides a mock function with given fields: ctx
 func (_m *Store) ListAllInstances(ctx context.Context) ([]params.Instance, error) {
 	ret := _m.Called(ctx)
@@ -1122,6 +1746,34 @@ func (_m *Store) ListAllJobs(ctx context.Context) ([]params.Job, error) {
 	return r0, r1
 }
 
+// ListAllJobsPage provides a mock function with given fields: ctx, opts
+func (_m *Store) ListAllJobsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Job], error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAllJobsPage")
+	}
+
+	var r0 params.PaginatedResult[params.Job]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListOptions) (params.PaginatedResult[params.Job], error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListOptions) params.PaginatedResult[params.Job]); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		r0 = ret.Get(0).(params.PaginatedResult[params.Job])
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.ListOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListAllPools provides a mock function with given fields: ctx
 func (_m *Store) ListAllPools(ctx context.Context) ([]params.Pool, error) {
 	ret := _m.Called(ctx)
@@ -1152,6 +1804,182 @@ func (_m *Store) ListAllPools(ctx context.Context) ([]params.Pool, error) {
 	return r0, r1
 }
 
+// ListAllPoolsPage provides a mock function with given fields: ctx, opts
+func (_m *Store) ListAllPoolsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAllPoolsPage")
+	}
+
+	var r0 params.PaginatedResult[params.Pool]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListOptions) (params.PaginatedResult[params.Pool], error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListOptions) params.PaginatedResult[params.Pool]); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		r0 = ret.Get(0).(params.PaginatedResult[params.Pool])
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.ListOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAuditRecords provides a mock function with given fields: ctx, param
+func (_m *Store) ListAuditRecords(ctx context.Context, param params.ListAuditRecordsParams) (params.AuditRecordsPage, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAuditRecords")
+	}
+
+	var r0 params.AuditRecordsPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListAuditRecordsParams) (params.AuditRecordsPage, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListAuditRecordsParams) params.AuditRecordsPage); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.AuditRecordsPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.ListAuditRecordsParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListCapacityReservations provides a mock function with given fields: ctx
+func (_m *Store) ListCapacityReservations(ctx context.Context) ([]params.CapacityReservation, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCapacityReservations")
+	}
+
+	var r0 []params.CapacityReservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.CapacityReservation, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.CapacityReservation); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.CapacityReservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListCompletedJobs provides a mock function with given fields: ctx, completedBefore
+func (_m *Store) ListCompletedJobs(ctx context.Context, completedBefore time.Time) ([]params.Job, error) {
+	ret := _m.Called(ctx, completedBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCompletedJobs")
+	}
+
+	var r0 []params.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]params.Job, error)); ok {
+		return rf(ctx, completedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []params.Job); ok {
+		r0 = rf(ctx, completedBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, completedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListControllerNodes provides a mock function with given fields: ctx
+func (_m *Store) ListControllerNodes(ctx context.Context) ([]params.ControllerNode, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListControllerNodes")
+	}
+
+	var r0 []params.ControllerNode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.ControllerNode, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.ControllerNode); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.ControllerNode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDenyRules provides a mock function with given fields: ctx
+func (_m *Store) ListDenyRules(ctx context.Context) ([]params.DenyRule, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDenyRules")
+	}
+
+	var r0 []params.DenyRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.DenyRule, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.DenyRule); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.DenyRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListEnterprises provides a mock function with given fields: ctx
 func (_m *Store) ListEnterprises(ctx context.Context) ([]params.Enterprise, error) {
 	ret := _m.Called(ctx)
@@ -1182,6 +2010,36 @@ func (_m *Store) ListEnterprises(ctx context.Context) ([]params.Enterprise, erro
 	return r0, r1
 }
 
+// ListEntityEvents provides a mock function with given fields: ctx, eventLevel
+func (_m *Store) ListEntityEvents(ctx context.Context, eventLevel params.EventLevel) ([]params.EntityEvent, error) {
+	ret := _m.Called(ctx, eventLevel)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityEvents")
+	}
+
+	var r0 []params.EntityEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.EventLevel) ([]params.EntityEvent, error)); ok {
+		return rf(ctx, eventLevel)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.EventLevel) []params.EntityEvent); ok {
+		r0 = rf(ctx, eventLevel)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.EntityEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.EventLevel) error); ok {
+		r1 = rf(ctx, eventLevel)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListEntityInstances provides a mock function with given fields: ctx, entity
 func (_m *Store) ListEntityInstances(ctx context.Context, entity params.GithubEntity) ([]params.Instance, error) {
 	ret := _m.Called(ctx, entity)
@@ -1272,6 +2130,34 @@ func (_m *Store) ListEntityPools(ctx context.Context, entity params.GithubEntity
 	return r0, r1
 }
 
+// ListEntityPoolsPage provides a mock function with given fields: ctx, entity, opts
+func (_m *Store) ListEntityPoolsPage(ctx context.Context, entity params.GithubEntity, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
+	ret := _m.Called(ctx, entity, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityPoolsPage")
+	}
+
+	var r0 params.PaginatedResult[params.Pool]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, params.ListOptions) (params.PaginatedResult[params.Pool], error)); ok {
+		return rf(ctx, entity, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, params.ListOptions) params.PaginatedResult[params.Pool]); ok {
+		r0 = rf(ctx, entity, opts)
+	} else {
+		r0 = ret.Get(0).(params.PaginatedResult[params.Pool])
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.GithubEntity, params.ListOptions) error); ok {
+		r1 = rf(ctx, entity, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListGithubCredentials provides a mock function with given fields: ctx
 func (_m *Store) ListGithubCredentials(ctx context.Context) ([]params.GithubCredentials, error) {
 	ret := _m.Called(ctx)
@@ -1332,29 +2218,29 @@ func (_m *Store) ListGithubEndpoints(ctx context.Context) ([]params.GithubEndpoi
 	return r0, r1
 }
 
-// ListJobsByStatus provides a mock function with given fields: ctx, status
-func (_m *Store) ListJobsByStatus(ctx context.Context, status params.JobStatus) ([]params.Job, error) {
-	ret := _m.Called(ctx, status)
+// ListInstanceEvents provides a mock function with given fields: ctx, instanceName, event
+func (_m *Store) ListInstanceEvents(ctx context.Context, instanceName string, event params.EventType) ([]params.StatusMessage, error) {
+	ret := _m.Called(ctx, instanceName, event)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListJobsByStatus")
+		panic("no return value specified for ListInstanceEvents")
 	}
 
-	var r0 []params.Job
+	var r0 []params.StatusMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, params.JobStatus) ([]params.Job, error)); ok {
-		return rf(ctx, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.EventType) ([]params.StatusMessage, error)); ok {
+		return rf(ctx, instanceName, event)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, params.JobStatus) []params.Job); ok {
-		r0 = rf(ctx, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.EventType) []params.StatusMessage); ok {
+		r0 = rf(ctx, instanceName, event)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]params.Job)
+			r0 = ret.Get(0).([]params.StatusMessage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, params.JobStatus) error); ok {
-		r1 = rf(ctx, status)
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.EventType) error); ok {
+		r1 = rf(ctx, instanceName, event)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1362,29 +2248,27 @@ func (_m *Store) ListJobsByStatus(ctx context.Context, status params.JobStatus)
 	return r0, r1
 }
 
-// ListOrganizations provides a mock function with given fields: ctx
-func (_m *Store) ListOrganizations(ctx context.Context) ([]params.Organization, error) {
-	ret := _m.Called(ctx)
+// ListInstanceStatusMessages provides a mock function with given fields: ctx, instanceName, param
+func (_m *Store) ListInstanceStatusMessages(ctx context.Context, instanceName string, param params.ListStatusMessagesParams) (params.StatusMessagesPage, error) {
+	ret := _m.Called(ctx, instanceName, param)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListOrganizations")
+		panic("no return value specified for ListInstanceStatusMessages")
 	}
 
-	var r0 []params.Organization
+	var r0 params.StatusMessagesPage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]params.Organization, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.ListStatusMessagesParams) (params.StatusMessagesPage, error)); ok {
+		return rf(ctx, instanceName, param)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []params.Organization); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.ListStatusMessagesParams) params.StatusMessagesPage); ok {
+		r0 = rf(ctx, instanceName, param)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]params.Organization)
-		}
+		r0 = ret.Get(0).(params.StatusMessagesPage)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.ListStatusMessagesParams) error); ok {
+		r1 = rf(ctx, instanceName, param)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1392,28 +2276,236 @@ func (_m *Store) ListOrganizations(ctx context.Context) ([]params.Organization,
 	return r0, r1
 }
 
-// ListPoolInstances provides a mock function with given fields: ctx, poolID
-func (_m *Store) ListPoolInstances(ctx context.Context, poolID string) ([]params.Instance, error) {
-	ret := _m.Called(ctx, poolID)
+// ListInstances provides a mock function with given fields: ctx, entity, filter
+func (_m *Store) ListInstances(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter) ([]params.Instance, error) {
+	ret := _m.Called(ctx, entity, filter)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListPoolInstances")
+		panic("no return value specified for ListInstances")
 	}
 
 	var r0 []params.Instance
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]params.Instance, error)); ok {
-		return rf(ctx, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, *params.GithubEntity, params.InstanceFilter) ([]params.Instance, error)); ok {
+		return rf(ctx, entity, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []params.Instance); ok {
-		r0 = rf(ctx, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, *params.GithubEntity, params.InstanceFilter) []params.Instance); ok {
+		r0 = rf(ctx, entity, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]params.Instance)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, *params.GithubEntity, params.InstanceFilter) error); ok {
+		r1 = rf(ctx, entity, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListInstancesPage provides a mock function with given fields: ctx, entity, filter, opts
+func (_m *Store) ListInstancesPage(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter, opts params.ListOptions) (params.PaginatedResult[params.Instance], error) {
+	ret := _m.Called(ctx, entity, filter, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInstancesPage")
+	}
+
+	var r0 params.PaginatedResult[params.Instance]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *params.GithubEntity, params.InstanceFilter, params.ListOptions) (params.PaginatedResult[params.Instance], error)); ok {
+		return rf(ctx, entity, filter, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *params.GithubEntity, params.InstanceFilter, params.ListOptions) params.PaginatedResult[params.Instance]); ok {
+		r0 = rf(ctx, entity, filter, opts)
+	} else {
+		r0 = ret.Get(0).(params.PaginatedResult[params.Instance])
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *params.GithubEntity, params.InstanceFilter, params.ListOptions) error); ok {
+		r1 = rf(ctx, entity, filter, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListInstancesWithProviderFaults provides a mock function with given fields: ctx, since
+func (_m *Store) ListInstancesWithProviderFaults(ctx context.Context, since time.Time) ([]params.Instance, error) {
+	ret := _m.Called(ctx, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInstancesWithProviderFaults")
+	}
+
+	var r0 []params.Instance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]params.Instance, error)); ok {
+		return rf(ctx, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []params.Instance); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Instance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListJobsByStatus provides a mock function with given fields: ctx, status
+func (_m *Store) ListJobsByStatus(ctx context.Context, status params.JobStatus) ([]params.Job, error) {
+	ret := _m.Called(ctx, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListJobsByStatus")
+	}
+
+	var r0 []params.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.JobStatus) ([]params.Job, error)); ok {
+		return rf(ctx, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.JobStatus) []params.Job); ok {
+		r0 = rf(ctx, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.JobStatus) error); ok {
+		r1 = rf(ctx, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListJobsServedByEnterprise provides a mock function with given fields: ctx, enterpriseID, since
+func (_m *Store) ListJobsServedByEnterprise(ctx context.Context, enterpriseID string, since time.Time) ([]params.Job, error) {
+	ret := _m.Called(ctx, enterpriseID, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListJobsServedByEnterprise")
+	}
+
+	var r0 []params.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) ([]params.Job, error)); ok {
+		return rf(ctx, enterpriseID, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) []params.Job); ok {
+		r0 = rf(ctx, enterpriseID, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, enterpriseID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOrganizations provides a mock function with given fields: ctx
+func (_m *Store) ListOrganizations(ctx context.Context) ([]params.Organization, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOrganizations")
+	}
+
+	var r0 []params.Organization
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.Organization, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.Organization); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Organization)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPausedProviders provides a mock function with given fields: ctx
+func (_m *Store) ListPausedProviders(ctx context.Context) ([]params.ProviderPause, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPausedProviders")
+	}
+
+	var r0 []params.ProviderPause
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.ProviderPause, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.ProviderPause); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.ProviderPause)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPoolInstances provides a mock function with given fields: ctx, poolID
+func (_m *Store) ListPoolInstances(ctx context.Context, poolID string) ([]params.Instance, error) {
+	ret := _m.Called(ctx, poolID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPoolInstances")
+	}
+
+	var r0 []params.Instance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]params.Instance, error)); ok {
+		return rf(ctx, poolID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []params.Instance); ok {
+		r0 = rf(ctx, poolID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Instance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = rf(ctx, poolID)
 	} else {
 		r1 = ret.Error(1)
@@ -1422,29 +2514,267 @@ func (_m *Store) ListPoolInstances(ctx context.Context, poolID string) ([]params
 	return r0, r1
 }
 
-// ListRepositories provides a mock function with given fields: ctx
-func (_m *Store) ListRepositories(ctx context.Context) ([]params.Repository, error) {
-	ret := _m.Called(ctx)
+// ListRepositories provides a mock function with given fields: ctx
+func (_m *Store) ListRepositories(ctx context.Context) ([]params.Repository, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRepositories")
+	}
+
+	var r0 []params.Repository
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.Repository, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.Repository); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Repository)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRunnerUsage provides a mock function with given fields: ctx, since, until
+func (_m *Store) ListRunnerUsage(ctx context.Context, since time.Time, until time.Time) ([]params.RunnerUsage, error) {
+	ret := _m.Called(ctx, since, until)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRunnerUsage")
+	}
+
+	var r0 []params.RunnerUsage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) ([]params.RunnerUsage, error)); ok {
+		return rf(ctx, since, until)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) []params.RunnerUsage); ok {
+		r0 = rf(ctx, since, until)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.RunnerUsage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, since, until)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: ctx, param
+func (_m *Store) ListWebhookDeliveries(ctx context.Context, param params.ListWebhookDeliveriesParams) (params.WebhookDeliveriesPage, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookDeliveries")
+	}
+
+	var r0 params.WebhookDeliveriesPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListWebhookDeliveriesParams) (params.WebhookDeliveriesPage, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.ListWebhookDeliveriesParams) params.WebhookDeliveriesPage); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.WebhookDeliveriesPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.ListWebhookDeliveriesParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LockJob provides a mock function with given fields: ctx, jobID, entityID
+func (_m *Store) LockJob(ctx context.Context, jobID int64, entityID string) error {
+	ret := _m.Called(ctx, jobID, entityID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LockJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, jobID, entityID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkInstancesForDeletion provides a mock function with given fields: ctx, param
+func (_m *Store) MarkInstancesForDeletion(ctx context.Context, param params.BulkDeleteInstancesParams) ([]string, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkInstancesForDeletion")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.BulkDeleteInstancesParams) ([]string, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.BulkDeleteInstancesParams) []string); ok {
+		r0 = rf(ctx, param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.BulkDeleteInstancesParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PauseProvider provides a mock function with given fields: ctx, providerName, reason
+func (_m *Store) PauseProvider(ctx context.Context, providerName string, reason string) (params.ProviderPause, error) {
+	ret := _m.Called(ctx, providerName, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PauseProvider")
+	}
+
+	var r0 params.ProviderPause
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (params.ProviderPause, error)); ok {
+		return rf(ctx, providerName, reason)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) params.ProviderPause); ok {
+		r0 = rf(ctx, providerName, reason)
+	} else {
+		r0 = ret.Get(0).(params.ProviderPause)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, providerName, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PoolInstanceCount provides a mock function with given fields: ctx, poolID
+func (_m *Store) PoolInstanceCount(ctx context.Context, poolID string) (int64, error) {
+	ret := _m.Called(ctx, poolID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PoolInstanceCount")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, poolID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, poolID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, poolID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordFailedLogin provides a mock function with given fields: ctx, user, threshold, lockoutDuration
+func (_m *Store) RecordFailedLogin(ctx context.Context, user string, threshold uint, lockoutDuration time.Duration) (params.User, error) {
+	ret := _m.Called(ctx, user, threshold, lockoutDuration)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordFailedLogin")
+	}
+
+	var r0 params.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, uint, time.Duration) (params.User, error)); ok {
+		return rf(ctx, user, threshold, lockoutDuration)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, uint, time.Duration) params.User); ok {
+		r0 = rf(ctx, user, threshold, lockoutDuration)
+	} else {
+		r0 = ret.Get(0).(params.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, uint, time.Duration) error); ok {
+		r1 = rf(ctx, user, threshold, lockoutDuration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseEntityLease provides a mock function with given fields: ctx, entityID, nodeID
+func (_m *Store) ReleaseEntityLease(ctx context.Context, entityID string, nodeID string) error {
+	ret := _m.Called(ctx, entityID, nodeID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListRepositories")
+		panic("no return value specified for ReleaseEntityLease")
 	}
 
-	var r0 []params.Repository
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, entityID, nodeID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplacePoolsTags provides a mock function with given fields: ctx, poolTags
+func (_m *Store) ReplacePoolsTags(ctx context.Context, poolTags map[string][]string) ([]params.Pool, error) {
+	ret := _m.Called(ctx, poolTags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplacePoolsTags")
+	}
+
+	var r0 []params.Pool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]params.Repository, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, map[string][]string) ([]params.Pool, error)); ok {
+		return rf(ctx, poolTags)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []params.Repository); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, map[string][]string) []params.Pool); ok {
+		r0 = rf(ctx, poolTags)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]params.Repository)
+			r0 = ret.Get(0).([]params.Pool)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, map[string][]string) error); ok {
+		r1 = rf(ctx, poolTags)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1452,17 +2782,45 @@ func (_m *Store) ListRepositories(ctx context.Context) ([]params.Repository, err
 	return r0, r1
 }
 
-// LockJob provides a mock function with given fields: ctx, jobID, entityID
-func (_m *Store) LockJob(ctx context.Context, jobID int64, entityID string) error {
-	ret := _m.Called(ctx, jobID, entityID)
+// ResetFailedLogins provides a mock function with given fields: ctx, user
+func (_m *Store) ResetFailedLogins(ctx context.Context, user string) (params.User, error) {
+	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for LockJob")
+		panic("no return value specified for ResetFailedLogins")
+	}
+
+	var r0 params.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.User, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.User); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(params.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResumeProvider provides a mock function with given fields: ctx, providerName
+func (_m *Store) ResumeProvider(ctx context.Context, providerName string) error {
+	ret := _m.Called(ctx, providerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResumeProvider")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
-		r0 = rf(ctx, jobID, entityID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, providerName)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -1470,27 +2828,45 @@ func (_m *Store) LockJob(ctx context.Context, jobID int64, entityID string) erro
 	return r0
 }
 
-// PoolInstanceCount provides a mock function with given fields: ctx, poolID
-func (_m *Store) PoolInstanceCount(ctx context.Context, poolID string) (int64, error) {
-	ret := _m.Called(ctx, poolID)
+// SetAPITokenLastUsed provides a mock function with given fields: ctx, tokenID, lastUsed
+func (_m *Store) SetAPITokenLastUsed(ctx context.Context, tokenID string, lastUsed time.Time) error {
+	ret := _m.Called(ctx, tokenID, lastUsed)
 
 	if len(ret) == 0 {
-		panic("no return value specified for PoolInstanceCount")
+		panic("no return value specified for SetAPITokenLastUsed")
 	}
 
-	var r0 int64
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, tokenID, lastUsed)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetControllerID provides a mock function with given fields: ctx, controllerID, previousID
+func (_m *Store) SetControllerID(ctx context.Context, controllerID uuid.UUID, previousID *uuid.UUID) (params.ControllerInfo, error) {
+	ret := _m.Called(ctx, controllerID, previousID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetControllerID")
+	}
+
+	var r0 params.ControllerInfo
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
-		return rf(ctx, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *uuid.UUID) (params.ControllerInfo, error)); ok {
+		return rf(ctx, controllerID, previousID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
-		r0 = rf(ctx, poolID)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *uuid.UUID) params.ControllerInfo); ok {
+		r0 = rf(ctx, controllerID, previousID)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(params.ControllerInfo)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, poolID)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *uuid.UUID) error); ok {
+		r1 = rf(ctx, controllerID, previousID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1498,6 +2874,60 @@ func (_m *Store) PoolInstanceCount(ctx context.Context, poolID string) (int64, e
 	return r0, r1
 }
 
+// SetEntityPendingWebhookInstall provides a mock function with given fields: ctx, entity, param
+func (_m *Store) SetEntityPendingWebhookInstall(ctx context.Context, entity params.GithubEntity, param *params.InstallWebhookParams) error {
+	ret := _m.Called(ctx, entity, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetEntityPendingWebhookInstall")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, *params.InstallWebhookParams) error); ok {
+		r0 = rf(ctx, entity, param)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetEntityWebhookPath provides a mock function with given fields: ctx, entity, enabled
+func (_m *Store) SetEntityWebhookPath(ctx context.Context, entity params.GithubEntity, enabled bool) error {
+	ret := _m.Called(ctx, entity, enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetEntityWebhookPath")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.GithubEntity, bool) error); ok {
+		r0 = rf(ctx, entity, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetJobDecision provides a mock function with given fields: ctx, jobID, decision
+func (_m *Store) SetJobDecision(ctx context.Context, jobID int64, decision params.JobDecision) error {
+	ret := _m.Called(ctx, jobID, decision)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetJobDecision")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, params.JobDecision) error); ok {
+		r0 = rf(ctx, jobID, decision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnlockJob provides a mock function with given fields: ctx, jobID, entityID
 func (_m *Store) UnlockJob(ctx context.Context, jobID int64, entityID string) error {
 	ret := _m.Called(ctx, jobID, entityID)
@@ -1544,6 +2974,62 @@ func (_m *Store) UpdateController(info params.UpdateControllerParams) (params.Co
 	return r0, r1
 }
 
+// UpdateControllerIDMigration provides a mock function with given fields: ctx, migrationID, param
+func (_m *Store) UpdateControllerIDMigration(ctx context.Context, migrationID string, param params.UpdateControllerIDMigrationParams) (params.ControllerIDMigration, error) {
+	ret := _m.Called(ctx, migrationID, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateControllerIDMigration")
+	}
+
+	var r0 params.ControllerIDMigration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.UpdateControllerIDMigrationParams) (params.ControllerIDMigration, error)); ok {
+		return rf(ctx, migrationID, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.UpdateControllerIDMigrationParams) params.ControllerIDMigration); ok {
+		r0 = rf(ctx, migrationID, param)
+	} else {
+		r0 = ret.Get(0).(params.ControllerIDMigration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.UpdateControllerIDMigrationParams) error); ok {
+		r1 = rf(ctx, migrationID, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateControllerNodeHeartbeat provides a mock function with given fields: ctx, nodeID, name
+func (_m *Store) UpdateControllerNodeHeartbeat(ctx context.Context, nodeID string, name string) (params.ControllerNode, error) {
+	ret := _m.Called(ctx, nodeID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateControllerNodeHeartbeat")
+	}
+
+	var r0 params.ControllerNode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (params.ControllerNode, error)); ok {
+		return rf(ctx, nodeID, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) params.ControllerNode); ok {
+		r0 = rf(ctx, nodeID, name)
+	} else {
+		r0 = ret.Get(0).(params.ControllerNode)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, nodeID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateEnterprise provides a mock function with given fields: ctx, enterpriseID, param
 func (_m *Store) UpdateEnterprise(ctx context.Context, enterpriseID string, param params.UpdateEntityParams) (params.Enterprise, error) {
 	ret := _m.Called(ctx, enterpriseID, param)
@@ -1656,6 +3142,34 @@ func (_m *Store) UpdateGithubEndpoint(ctx context.Context, name string, param pa
 	return r0, r1
 }
 
+// UpdateIdempotencyRecord provides a mock function with given fields: ctx, record
+func (_m *Store) UpdateIdempotencyRecord(ctx context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateIdempotencyRecord")
+	}
+
+	var r0 params.IdempotencyRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.IdempotencyRecord) (params.IdempotencyRecord, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.IdempotencyRecord) params.IdempotencyRecord); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Get(0).(params.IdempotencyRecord)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.IdempotencyRecord) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateInstance provides a mock function with given fields: ctx, instanceName, param
 func (_m *Store) UpdateInstance(ctx context.Context, instanceName string, param params.UpdateInstanceParams) (params.Instance, error) {
 	ret := _m.Called(ctx, instanceName, param)
@@ -1684,6 +3198,34 @@ func (_m *Store) UpdateInstance(ctx context.Context, instanceName string, param
 	return r0, r1
 }
 
+// UpdateJobMetadata provides a mock function with given fields: ctx, jobID, param
+func (_m *Store) UpdateJobMetadata(ctx context.Context, jobID int64, param params.UpdateJobMetadataParams) (params.Job, error) {
+	ret := _m.Called(ctx, jobID, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateJobMetadata")
+	}
+
+	var r0 params.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, params.UpdateJobMetadataParams) (params.Job, error)); ok {
+		return rf(ctx, jobID, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, params.UpdateJobMetadataParams) params.Job); ok {
+		r0 = rf(ctx, jobID, param)
+	} else {
+		r0 = ret.Get(0).(params.Job)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, params.UpdateJobMetadataParams) error); ok {
+		r1 = rf(ctx, jobID, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateOrganization provides a mock function with given fields: ctx, orgID, param
 func (_m *Store) UpdateOrganization(ctx context.Context, orgID string, param params.UpdateEntityParams) (params.Organization, error) {
 	ret := _m.Called(ctx, orgID, param)
@@ -1712,6 +3254,62 @@ func (_m *Store) UpdateOrganization(ctx context.Context, orgID string, param par
 	return r0, r1
 }
 
+// UpdatePoolWarmImageStatus provides a mock function with given fields: ctx, poolID, status
+func (_m *Store) UpdatePoolWarmImageStatus(ctx context.Context, poolID string, status params.WarmImageStatus) (params.Pool, error) {
+	ret := _m.Called(ctx, poolID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePoolWarmImageStatus")
+	}
+
+	var r0 params.Pool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.WarmImageStatus) (params.Pool, error)); ok {
+		return rf(ctx, poolID, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.WarmImageStatus) params.Pool); ok {
+		r0 = rf(ctx, poolID, status)
+	} else {
+		r0 = ret.Get(0).(params.Pool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.WarmImageStatus) error); ok {
+		r1 = rf(ctx, poolID, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProviderEnvironment provides a mock function with given fields: ctx, providerName, param
+func (_m *Store) UpdateProviderEnvironment(ctx context.Context, providerName string, param params.UpdateProviderEnvironmentParams) (params.ProviderEnvironment, error) {
+	ret := _m.Called(ctx, providerName, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProviderEnvironment")
+	}
+
+	var r0 params.ProviderEnvironment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.UpdateProviderEnvironmentParams) (params.ProviderEnvironment, error)); ok {
+		return rf(ctx, providerName, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, params.UpdateProviderEnvironmentParams) params.ProviderEnvironment); ok {
+		r0 = rf(ctx, providerName, param)
+	} else {
+		r0 = ret.Get(0).(params.ProviderEnvironment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, params.UpdateProviderEnvironmentParams) error); ok {
+		r1 = rf(ctx, providerName, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateRepository provides a mock function with given fields: ctx, repoID, param
 func (_m *Store) UpdateRepository(ctx context.Context, repoID string, param params.UpdateEntityParams) (params.Repository, error) {
 	ret := _m.Called(ctx, repoID, param)
@@ -1768,6 +3366,34 @@ func (_m *Store) UpdateUser(ctx context.Context, user string, param params.Updat
 	return r0, r1
 }
 
+// UpdateWebhookDeliveryResult provides a mock function with given fields: ctx, deliveryID, entityID, status, deliveryErr
+func (_m *Store) UpdateWebhookDeliveryResult(ctx context.Context, deliveryID string, entityID string, status params.WebhookDeliveryStatus, deliveryErr string) (params.WebhookDelivery, error) {
+	ret := _m.Called(ctx, deliveryID, entityID, status, deliveryErr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateWebhookDeliveryResult")
+	}
+
+	var r0 params.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, params.WebhookDeliveryStatus, string) (params.WebhookDelivery, error)); ok {
+		return rf(ctx, deliveryID, entityID, status, deliveryErr)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, params.WebhookDeliveryStatus, string) params.WebhookDelivery); ok {
+		r0 = rf(ctx, deliveryID, entityID, status, deliveryErr)
+	} else {
+		r0 = ret.Get(0).(params.WebhookDelivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, params.WebhookDeliveryStatus, string) error); ok {
+		r1 = rf(ctx, deliveryID, entityID, status, deliveryErr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewStore creates a new instance of Store. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewStore(t interface {
@@ -16,6 +16,9 @@ package common
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/cloudbase/garm/params"
 )
@@ -65,10 +68,8 @@ type EnterpriseStore interface {
 }
 
 type PoolStore interface {
-	// Probably a bad idea without some king of filter or at least pagination
-	// nolint:golangci-lint,godox
-	// TODO: add filter/pagination
 	ListAllPools(ctx context.Context) ([]params.Pool, error)
+	ListAllPoolsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Pool], error)
 	GetPoolByID(ctx context.Context, poolID string) (params.Pool, error)
 	DeletePoolByID(ctx context.Context, poolID string) error
 
@@ -77,6 +78,11 @@ type PoolStore interface {
 	PoolInstanceCount(ctx context.Context, poolID string) (int64, error)
 	GetPoolInstanceByName(ctx context.Context, poolID string, instanceName string) (params.Instance, error)
 	FindPoolsMatchingAllTags(ctx context.Context, entityType params.GithubEntityType, entityID string, tags []string) ([]params.Pool, error)
+	// ReplacePoolsTags replaces the tags of several pools in a single transaction. The
+	// map is keyed by pool ID.
+	ReplacePoolsTags(ctx context.Context, poolTags map[string][]string) ([]params.Pool, error)
+	// UpdatePoolWarmImageStatus records the result of the last warm image build of a pool.
+	UpdatePoolWarmImageStatus(ctx context.Context, poolID string, status params.WarmImageStatus) (params.Pool, error)
 }
 
 type UserStore interface {
@@ -87,6 +93,16 @@ type UserStore interface {
 	CreateUser(ctx context.Context, user params.NewUserParams) (params.User, error)
 	UpdateUser(ctx context.Context, user string, param params.UpdateUserParams) (params.User, error)
 	HasAdminUser(ctx context.Context) bool
+
+	// RecordFailedLogin counts a failed login and locks out the user once the
+	// threshold is reached.
+	RecordFailedLogin(ctx context.Context, user string, threshold uint, lockoutDuration time.Duration) (params.User, error)
+	// ResetFailedLogins clears the failed logins of a user and lifts any lockout.
+	ResetFailedLogins(ctx context.Context, user string) (params.User, error)
+
+	// CreateRoleGrant gives a user a role on a repository, organization or enterprise.
+	CreateRoleGrant(ctx context.Context, user string, param params.CreateRoleGrantParams) (params.RoleGrant, error)
+	DeleteRoleGrant(ctx context.Context, user string, grantID string) error
 }
 
 type InstanceStore interface {
@@ -99,9 +115,26 @@ type InstanceStore interface {
 	// nolint:golangci-lint,godox
 	// TODO: add filter/pagination
 	ListAllInstances(ctx context.Context) ([]params.Instance, error)
+	// ListInstances returns the instances that match the filter. If entity is set, only
+	// instances in the pools of that entity are returned.
+	ListInstances(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter) ([]params.Instance, error)
+	ListInstancesPage(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter, opts params.ListOptions) (params.PaginatedResult[params.Instance], error)
+	// ListInstancesWithProviderFaults returns all instances that have a provider fault
+	// recorded and were updated after the given time.
+	ListInstancesWithProviderFaults(ctx context.Context, since time.Time) ([]params.Instance, error)
 
 	GetInstanceByName(ctx context.Context, instanceName string) (params.Instance, error)
-	AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, eventMessage string) error
+	// MarkInstancesForDeletion sets all the instances matching the filters in param to
+	// pending_delete, or to pending_force_delete, in a single transaction. It returns
+	// the names of the matching instances. Nothing is changed if param.DryRun is set.
+	MarkInstancesForDeletion(ctx context.Context, param params.BulkDeleteInstancesParams) ([]string, error)
+	// AddInstanceEvent records a status message for an instance. Only the last maxEvents
+	// messages are kept for each instance.
+	AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, eventMessage string, maxEvents int) error
+	// ListInstanceEvents returns the events of the given type recorded for an instance, oldest first.
+	ListInstanceEvents(ctx context.Context, instanceName string, event params.EventType) ([]params.StatusMessage, error)
+	// ListInstanceStatusMessages returns one page of the status messages of an instance, newest first.
+	ListInstanceStatusMessages(ctx context.Context, instanceName string, param params.ListStatusMessagesParams) (params.StatusMessagesPage, error)
 }
 
 type JobsStore interface {
@@ -109,14 +142,19 @@ type JobsStore interface {
 	ListEntityJobsByStatus(ctx context.Context, entityType params.GithubEntityType, entityID string, status params.JobStatus) ([]params.Job, error)
 	ListJobsByStatus(ctx context.Context, status params.JobStatus) ([]params.Job, error)
 	ListAllJobs(ctx context.Context) ([]params.Job, error)
+	ListAllJobsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Job], error)
+	ListJobsServedByEnterprise(ctx context.Context, enterpriseID string, since time.Time) ([]params.Job, error)
 
 	GetJobByID(ctx context.Context, jobID int64) (params.Job, error)
 	DeleteJob(ctx context.Context, jobID int64) error
 	UnlockJob(ctx context.Context, jobID int64, entityID string) error
 	LockJob(ctx context.Context, jobID int64, entityID string) error
 	BreakLockJobIsQueued(ctx context.Context, jobID int64) error
+	SetJobDecision(ctx context.Context, jobID int64, decision params.JobDecision) error
+	UpdateJobMetadata(ctx context.Context, jobID int64, param params.UpdateJobMetadataParams) (params.Job, error)
 
-	DeleteCompletedJobs(ctx context.Context) error
+	ListCompletedJobs(ctx context.Context, completedBefore time.Time) ([]params.Job, error)
+	DeleteCompletedJobs(ctx context.Context, completedBefore time.Time) error
 }
 
 type EntityPoolStore interface {
@@ -127,12 +165,131 @@ type EntityPoolStore interface {
 
 	ListEntityPools(ctx context.Context, entity params.GithubEntity) ([]params.Pool, error)
 	ListEntityInstances(ctx context.Context, entity params.GithubEntity) ([]params.Instance, error)
+	ListEntityPoolsPage(ctx context.Context, entity params.GithubEntity, opts params.ListOptions) (params.PaginatedResult[params.Pool], error)
+}
+
+type EntityStore interface {
+	// AddEntityEvent records an event for a repository, organization or enterprise. Only the
+	// last maxEvents events are kept for each entity.
+	AddEntityEvent(ctx context.Context, entity params.GithubEntity, event params.EventType, eventLevel params.EventLevel, statusMessage string, maxEvents int) error
+	// ListEntityEvents returns the events with the given level recorded for all repositories,
+	// organizations and enterprises.
+	ListEntityEvents(ctx context.Context, eventLevel params.EventLevel) ([]params.EntityEvent, error)
+	// SetEntityPendingWebhookInstall flags an entity as needing a webhook install to be retried.
+	// Passing a nil param clears the flag.
+	SetEntityPendingWebhookInstall(ctx context.Context, entity params.GithubEntity, param *params.InstallWebhookParams) error
+	// SetEntityWebhookPath records whether the webhook of an entity was installed using the URL
+	// unique to that entity. Such entities no longer accept webhooks on the shared controller URL.
+	SetEntityWebhookPath(ctx context.Context, entity params.GithubEntity, enabled bool) error
+}
+
+type WebhookDeliveryStore interface {
+	CreateWebhookDelivery(ctx context.Context, delivery params.WebhookDelivery) (params.WebhookDelivery, error)
+	// GetWebhookDelivery returns a delivery, including its payload.
+	GetWebhookDelivery(ctx context.Context, deliveryID string) (params.WebhookDelivery, error)
+	// UpdateWebhookDeliveryResult records the result of processing a delivery again,
+	// and increments its attempts.
+	UpdateWebhookDeliveryResult(ctx context.Context, deliveryID, entityID string, status params.WebhookDeliveryStatus, deliveryErr string) (params.WebhookDelivery, error)
+	// ListWebhookDeliveries returns one page of deliveries, newest first. The payload
+	// is not part of the returned deliveries.
+	ListWebhookDeliveries(ctx context.Context, param params.ListWebhookDeliveriesParams) (params.WebhookDeliveriesPage, error)
+	DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) error
+}
+
+type AuditStore interface {
+	CreateAuditRecord(ctx context.Context, record params.AuditRecord) (params.AuditRecord, error)
+	ListAuditRecords(ctx context.Context, param params.ListAuditRecordsParams) (params.AuditRecordsPage, error)
+	DeleteAuditRecordsBefore(ctx context.Context, before time.Time) error
+}
+
+type IdempotencyStore interface {
+	// GetIdempotencyRecord returns the record saved by a user for an idempotency key.
+	// Expired records are not returned.
+	GetIdempotencyRecord(ctx context.Context, userID, key string) (params.IdempotencyRecord, error)
+	// CreateIdempotencyRecord returns ErrDuplicateEntity if the user already has a record
+	// for the key.
+	CreateIdempotencyRecord(ctx context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error)
+	UpdateIdempotencyRecord(ctx context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error)
+	DeleteIdempotencyRecord(ctx context.Context, userID, key string) error
+	DeleteExpiredIdempotencyRecords(ctx context.Context) error
+}
+
+type ProviderPauseStore interface {
+	// PauseProvider marks a provider as paused. Pausing a provider that is already
+	// paused updates the reason.
+	PauseProvider(ctx context.Context, providerName, reason string) (params.ProviderPause, error)
+	ResumeProvider(ctx context.Context, providerName string) error
+	ListPausedProviders(ctx context.Context) ([]params.ProviderPause, error)
+}
+
+type ProviderEnvironmentStore interface {
+	// GetProviderEnvironment returns the environment variables stored for a provider.
+	// An empty environment is returned if no variables were stored.
+	GetProviderEnvironment(ctx context.Context, providerName string) (params.ProviderEnvironment, error)
+	UpdateProviderEnvironment(ctx context.Context, providerName string, param params.UpdateProviderEnvironmentParams) (params.ProviderEnvironment, error)
+}
+
+type APITokenStore interface {
+	// CreateAPIToken saves a new API token of a user. Only the hash of the token is saved.
+	CreateAPIToken(ctx context.Context, userID string, param params.CreateAPITokenParams, tokenHash string) (params.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (params.APIToken, error)
+	// ListAPITokens returns the API tokens of a user, or the tokens of all users if
+	// userID is empty.
+	ListAPITokens(ctx context.Context, userID string) ([]params.APIToken, error)
+	// DeleteAPIToken revokes an API token of a user, or of any user if userID is empty.
+	DeleteAPIToken(ctx context.Context, userID, tokenID string) error
+	SetAPITokenLastUsed(ctx context.Context, tokenID string, lastUsed time.Time) error
+}
+
+type DenyRuleStore interface {
+	CreateDenyRule(ctx context.Context, param params.CreateDenyRuleParams) (params.DenyRule, error)
+	ListDenyRules(ctx context.Context) ([]params.DenyRule, error)
+	DeleteDenyRule(ctx context.Context, ruleID string) error
+}
+
+type CapacityReservationStore interface {
+	CreateCapacityReservation(ctx context.Context, param params.CreateCapacityReservationParams) (params.CapacityReservation, error)
+	// ListCapacityReservations returns all reservations, ordered by start time.
+	ListCapacityReservations(ctx context.Context) ([]params.CapacityReservation, error)
+	DeleteCapacityReservation(ctx context.Context, reservationID string) error
+}
+
+type RunnerUsageStore interface {
+	// ListRunnerUsage returns the usage records of the runners that existed at any
+	// point between since and until.
+	ListRunnerUsage(ctx context.Context, since, until time.Time) ([]params.RunnerUsage, error)
+}
+
+type ControllerNodeStore interface {
+	// UpdateControllerNodeHeartbeat registers a controller node, or refreshes the
+	// heartbeat of a node that is already registered.
+	UpdateControllerNodeHeartbeat(ctx context.Context, nodeID, name string) (params.ControllerNode, error)
+	ListControllerNodes(ctx context.Context) ([]params.ControllerNode, error)
+	// DeleteControllerNode removes a node along with its leases.
+	DeleteControllerNode(ctx context.Context, nodeID string) error
+	// DeleteStaleControllerNodes removes the nodes that didn't send a heartbeat since
+	// the given time, along with their leases.
+	DeleteStaleControllerNodes(ctx context.Context, since time.Time) error
+	// AcquireEntityLease gives a node the lease on an entity until expiresAt. The lease
+	// is only given if it is free, expired, or already held by the node. The returned
+	// bool is false if another node holds the lease.
+	AcquireEntityLease(ctx context.Context, entityID, nodeID string, expiresAt time.Time) (bool, error)
+	ReleaseEntityLease(ctx context.Context, entityID, nodeID string) error
 }
 
 type ControllerStore interface {
 	ControllerInfo() (params.ControllerInfo, error)
 	InitController() (params.ControllerInfo, error)
 	UpdateController(info params.UpdateControllerParams) (params.ControllerInfo, error)
+	// SetControllerID changes the ID of the controller. The previous ID is saved
+	// as well, and can be cleared by passing a nil previousID.
+	SetControllerID(ctx context.Context, controllerID uuid.UUID, previousID *uuid.UUID) (params.ControllerInfo, error)
+	// CreateControllerIDMigration records a new migration of the controller ID. Only one
+	// migration may run at any given time.
+	CreateControllerIDMigration(ctx context.Context, newControllerID uuid.UUID) (params.ControllerIDMigration, error)
+	// GetControllerIDMigration returns the most recent controller ID migration.
+	GetControllerIDMigration(ctx context.Context) (params.ControllerIDMigration, error)
+	UpdateControllerIDMigration(ctx context.Context, migrationID string, param params.UpdateControllerIDMigrationParams) (params.ControllerIDMigration, error)
 }
 
 //go:generate mockery --name=Store
@@ -142,13 +299,32 @@ type Store interface {
 	EnterpriseStore
 	PoolStore
 	UserStore
+	APITokenStore
 	InstanceStore
 	JobsStore
 	GithubEndpointStore
 	GithubCredentialsStore
 	ControllerStore
 	EntityPoolStore
+	EntityStore
+	AuditStore
+	WebhookDeliveryStore
+	IdempotencyStore
+	ProviderPauseStore
+	ProviderEnvironmentStore
+	DenyRuleStore
+	ControllerNodeStore
+	CapacityReservationStore
+	RunnerUsageStore
 
 	ControllerInfo() (params.ControllerInfo, error)
 	InitController() (params.ControllerInfo, error)
 }
+
+// SecretsMigrator is implemented by stores that can re-encrypt the sensitive info
+// they hold, after the secrets backend was changed.
+type SecretsMigrator interface {
+	// ReEncryptSecrets seals all the sensitive info with the configured secrets
+	// backend and returns the number of values that were re-encrypted.
+	ReEncryptSecrets(ctx context.Context) (int, error)
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/util/sigv4"
+)
+
+// awsKMSKeyProvider generates data keys using AWS KMS. Requests are made to the
+// KMS JSON API and signed using AWS Signature Version 4.
+type awsKMSKeyProvider struct {
+	cfg    config.AWSKMS
+	client *http.Client
+	now    func() time.Time
+}
+
+func newAWSKMSKeyProvider(cfg config.AWSKMS) *awsKMSKeyProvider {
+	return &awsKMSKeyProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: providerRequestTimeout},
+		now:    time.Now,
+	}
+}
+
+type kmsRequest struct {
+	KeyID          string `json:"KeyId"`
+	KeySpec        string `json:"KeySpec,omitempty"`
+	CiphertextBlob []byte `json:"CiphertextBlob,omitempty"`
+}
+
+type kmsResponse struct {
+	CiphertextBlob []byte `json:"CiphertextBlob"`
+	Plaintext      []byte `json:"Plaintext"`
+	Type           string `json:"__type"`
+	Message        string `json:"message"`
+}
+
+func (a *awsKMSKeyProvider) do(ctx context.Context, operation string, body kmsRequest) (kmsResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return kmsResponse{}, errors.Wrap(err, "marshaling request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.GetEndpoint()+"/", bytes.NewReader(payload))
+	if err != nil {
+		return kmsResponse{}, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+operation)
+
+	accessKeyID, secretAccessKey, sessionToken := a.cfg.GetCredentials()
+	sigv4.SignRequest(req, payload, sigv4.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	}, a.cfg.Region, "kms", a.now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return kmsResponse{}, errors.Wrap(err, "calling kms")
+	}
+	defer resp.Body.Close()
+
+	var ret kmsResponse
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return kmsResponse{}, errors.Wrap(err, "reading response")
+	}
+	if err := json.Unmarshal(data, &ret); err != nil && resp.StatusCode/100 == 2 {
+		return kmsResponse{}, errors.Wrap(err, "decoding response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return kmsResponse{}, fmt.Errorf("kms returned %s: %s %s", resp.Status, ret.Type, ret.Message)
+	}
+	return ret, nil
+}
+
+func (a *awsKMSKeyProvider) generateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	resp, err := a.do(ctx, "GenerateDataKey", kmsRequest{
+		KeyID:   a.cfg.KeyID,
+		KeySpec: "AES_256",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Plaintext, resp.CiphertextBlob, nil
+}
+
+func (a *awsKMSKeyProvider) decryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	resp, err := a.do(ctx, "Decrypt", kmsRequest{
+		KeyID:          a.cfg.KeyID,
+		CiphertextBlob: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
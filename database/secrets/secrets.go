@@ -0,0 +1,202 @@
+// Package secrets holds the backends used to encrypt sensitive info, like webhook
+// secrets and credentials, before it is stored in the database.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/cloudbase/garm-provider-common/util"
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/config"
+)
+
+// dataKeySize is the size of the data keys used to seal secrets (aes 256).
+const dataKeySize = 32
+
+// Backend encrypts and decrypts sensitive info.
+type Backend interface {
+	// Type returns the type of the backend.
+	Type() config.SecretsBackendType
+	// Seal encrypts the given data.
+	Seal(data []byte) ([]byte, error)
+	// Unseal decrypts data that was sealed by this backend. Data sealed using the
+	// database passphrase can always be unsealed, regardless of the backend.
+	Unseal(data []byte) ([]byte, error)
+}
+
+// NewBackend returns the secrets backend set in the database config.
+func NewBackend(ctx context.Context, cfg config.Database) (Backend, error) {
+	switch cfg.Secrets.GetBackend() {
+	case config.PassphraseSecretsBackend:
+		return NewPassphraseBackend(cfg.Passphrase), nil
+	case config.VaultSecretsBackend:
+		keys, err := newVaultKeyProvider(cfg.Secrets.Vault)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating vault key provider")
+		}
+		return newEnvelopeBackend(ctx, config.VaultSecretsBackend, keys, cfg.Passphrase), nil
+	case config.AWSKMSSecretsBackend:
+		return newEnvelopeBackend(ctx, config.AWSKMSSecretsBackend, newAWSKMSKeyProvider(cfg.Secrets.AWSKMS), cfg.Passphrase), nil
+	default:
+		return nil, fmt.Errorf("invalid secrets backend: %s", cfg.Secrets.Backend)
+	}
+}
+
+// envelope is the format of the data sealed by the vault and awskms backends. The
+// data is sealed with a data key, which is stored encrypted by the key provider.
+type envelope struct {
+	Backend config.SecretsBackendType `json:"backend"`
+	DataKey []byte                    `json:"data_key"`
+	Data    []byte                    `json:"data"`
+}
+
+func parseEnvelope(data []byte) (envelope, bool) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Backend == "" {
+		return envelope{}, false
+	}
+	return env, true
+}
+
+// SealedWith returns the type of the backend that sealed the given data.
+func SealedWith(data []byte) config.SecretsBackendType {
+	if env, ok := parseEnvelope(data); ok {
+		return env.Backend
+	}
+	return config.PassphraseSecretsBackend
+}
+
+// NewPassphraseBackend returns a backend that seals data with a key derived from
+// the database passphrase.
+func NewPassphraseBackend(passphrase string) Backend {
+	return &passphraseBackend{passphrase: passphrase}
+}
+
+type passphraseBackend struct {
+	passphrase string
+}
+
+func (p *passphraseBackend) Type() config.SecretsBackendType {
+	return config.PassphraseSecretsBackend
+}
+
+func (p *passphraseBackend) Seal(data []byte) ([]byte, error) {
+	return util.Seal(data, []byte(p.passphrase))
+}
+
+func (p *passphraseBackend) Unseal(data []byte) ([]byte, error) {
+	if env, ok := parseEnvelope(data); ok {
+		return nil, fmt.Errorf("data was sealed by the %s secrets backend", env.Backend)
+	}
+	return util.Unseal(data, []byte(p.passphrase))
+}
+
+// keyProvider generates and decrypts the data keys used by the envelope backend.
+type keyProvider interface {
+	// generateDataKey returns a new data key, both in plain text and encrypted.
+	generateDataKey(ctx context.Context) (plaintext, encrypted []byte, err error)
+	// decryptDataKey decrypts a data key returned by generateDataKey.
+	decryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error)
+}
+
+// envelopeBackend seals data with data keys generated by a key provider. A single
+// data key is generated for the lifetime of the backend, and decrypted data keys
+// are cached, so the key provider is not called for every secret.
+type envelopeBackend struct {
+	ctx         context.Context
+	backendType config.SecretsBackendType
+	keys        keyProvider
+	passphrase  string
+
+	mux              sync.Mutex
+	plainDataKey     []byte
+	encryptedDataKey []byte
+	decryptedKeys    map[string][]byte
+}
+
+func newEnvelopeBackend(ctx context.Context, backendType config.SecretsBackendType, keys keyProvider, passphrase string) *envelopeBackend {
+	return &envelopeBackend{
+		ctx:           ctx,
+		backendType:   backendType,
+		keys:          keys,
+		passphrase:    passphrase,
+		decryptedKeys: map[string][]byte{},
+	}
+}
+
+func (e *envelopeBackend) Type() config.SecretsBackendType {
+	return e.backendType
+}
+
+func (e *envelopeBackend) currentDataKey() ([]byte, []byte, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.plainDataKey == nil {
+		plaintext, encrypted, err := e.keys.generateDataKey(e.ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "generating data key")
+		}
+		if len(plaintext) != dataKeySize {
+			return nil, nil, fmt.Errorf("invalid data key size %d", len(plaintext))
+		}
+		e.plainDataKey = plaintext
+		e.encryptedDataKey = encrypted
+		e.decryptedKeys[string(encrypted)] = plaintext
+	}
+	return e.plainDataKey, e.encryptedDataKey, nil
+}
+
+func (e *envelopeBackend) dataKey(encrypted []byte) ([]byte, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if key, ok := e.decryptedKeys[string(encrypted)]; ok {
+		return key, nil
+	}
+	key, err := e.keys.decryptDataKey(e.ctx, encrypted)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting data key")
+	}
+	e.decryptedKeys[string(encrypted)] = key
+	return key, nil
+}
+
+func (e *envelopeBackend) Seal(data []byte) ([]byte, error) {
+	plaintext, encrypted, err := e.currentDataKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := util.Seal(data, plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "sealing data")
+	}
+	asJs, err := json.Marshal(envelope{
+		Backend: e.backendType,
+		DataKey: encrypted,
+		Data:    sealed,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling envelope")
+	}
+	return asJs, nil
+}
+
+func (e *envelopeBackend) Unseal(data []byte) ([]byte, error) {
+	env, ok := parseEnvelope(data)
+	if !ok {
+		return util.Unseal(data, []byte(e.passphrase))
+	}
+	if env.Backend != e.backendType {
+		return nil, fmt.Errorf("data was sealed by the %s secrets backend", env.Backend)
+	}
+	key, err := e.dataKey(env.DataKey)
+	if err != nil {
+		return nil, err
+	}
+	return util.Unseal(env.Data, key)
+}
@@ -0,0 +1,238 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudbase/garm-provider-common/util"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/config"
+)
+
+const testPassphrase = "bocyasicgatEtenOubwonIbsudNutDom"
+
+// fakeKeyServer stores the data keys it generates and counts the requests it
+// receives.
+type fakeKeyServer struct {
+	mux      sync.Mutex
+	keys     map[string][]byte
+	requests int
+}
+
+func (f *fakeKeyServer) newKey(t *testing.T) (string, []byte) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	key := make([]byte, dataKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	id := fmt.Sprintf("key-%d", len(f.keys))
+	f.keys[id] = key
+	return id, key
+}
+
+func (f *fakeKeyServer) key(id string) ([]byte, bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	key, ok := f.keys[id]
+	return key, ok
+}
+
+func newFakeVault(t *testing.T) (*fakeKeyServer, *httptest.Server) {
+	fake := &fakeKeyServer{keys: map[string][]byte{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mux.Lock()
+		fake.requests++
+		fake.mux.Unlock()
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`)) //nolint:errcheck
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/garm":
+			id, key := fake.newKey(t)
+			fmt.Fprintf(w, `{"data":{"plaintext":%q,"ciphertext":"vault:v1:%s"}}`, base64.StdEncoding.EncodeToString(key), id)
+		case "/v1/transit/decrypt/garm":
+			key, ok := fake.key(strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors":["invalid ciphertext"]}`)) //nolint:errcheck
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"plaintext":%q}}`, base64.StdEncoding.EncodeToString(key))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func newFakeKMS(t *testing.T) (*fakeKeyServer, *httptest.Server) {
+	fake := &fakeKeyServer{keys: map[string][]byte{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mux.Lock()
+		fake.requests++
+		fake.mux.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"UnrecognizedClientException","message":"invalid credentials"}`)) //nolint:errcheck
+			return
+		}
+		var body kmsRequest
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GenerateDataKey":
+			id, key := fake.newKey(t)
+			json.NewEncoder(w).Encode(kmsResponse{CiphertextBlob: []byte(id), Plaintext: key}) //nolint:errcheck
+		case "TrentService.Decrypt":
+			key, ok := fake.key(string(body.CiphertextBlob))
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"InvalidCiphertextException","message":"invalid ciphertext"}`)) //nolint:errcheck
+				return
+			}
+			json.NewEncoder(w).Encode(kmsResponse{Plaintext: key}) //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func vaultConfig(address string) config.Database {
+	return config.Database{
+		Passphrase: testPassphrase,
+		Secrets: config.Secrets{
+			Backend: config.VaultSecretsBackend,
+			Vault: config.Vault{
+				Address: address,
+				Token:   "token",
+				KeyName: "garm",
+			},
+		},
+	}
+}
+
+func TestPassphraseBackend(t *testing.T) {
+	backend, err := NewBackend(context.Background(), config.Database{Passphrase: testPassphrase})
+	require.NoError(t, err)
+	require.Equal(t, config.PassphraseSecretsBackend, backend.Type())
+
+	sealed, err := backend.Seal([]byte("secret"))
+	require.NoError(t, err)
+	require.Equal(t, config.PassphraseSecretsBackend, SealedWith(sealed))
+
+	// Data sealed by the backend can still be read with util.Unseal.
+	data, err := util.Unseal(sealed, []byte(testPassphrase))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(data))
+
+	data, err = backend.Unseal(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(data))
+}
+
+func TestVaultBackend(t *testing.T) {
+	fake, srv := newFakeVault(t)
+	backend, err := NewBackend(context.Background(), vaultConfig(srv.URL))
+	require.NoError(t, err)
+	require.Equal(t, config.VaultSecretsBackend, backend.Type())
+
+	first, err := backend.Seal([]byte("first"))
+	require.NoError(t, err)
+	second, err := backend.Seal([]byte("second"))
+	require.NoError(t, err)
+	require.Equal(t, config.VaultSecretsBackend, SealedWith(first))
+	// A single data key is generated and reused.
+	require.Equal(t, 1, fake.requests)
+
+	// A new backend has to decrypt the data key once.
+	backend, err = NewBackend(context.Background(), vaultConfig(srv.URL))
+	require.NoError(t, err)
+	data, err := backend.Unseal(first)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(data))
+	data, err = backend.Unseal(second)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(data))
+	require.Equal(t, 2, fake.requests)
+
+	// Secrets sealed with the passphrase can still be read.
+	legacy, err := util.Seal([]byte("legacy"), []byte(testPassphrase))
+	require.NoError(t, err)
+	data, err = backend.Unseal(legacy)
+	require.NoError(t, err)
+	require.Equal(t, "legacy", string(data))
+
+	// The passphrase backend can't read data sealed by vault.
+	_, err = NewPassphraseBackend(testPassphrase).Unseal(first)
+	require.EqualError(t, err, "data was sealed by the vault secrets backend")
+}
+
+func TestVaultBackendError(t *testing.T) {
+	_, srv := newFakeVault(t)
+	cfg := vaultConfig(srv.URL)
+	cfg.Secrets.Vault.Token = "bogus"
+	backend, err := NewBackend(context.Background(), cfg)
+	require.NoError(t, err)
+
+	_, err = backend.Seal([]byte("secret"))
+	require.ErrorContains(t, err, "vault returned 403 Forbidden: permission denied")
+}
+
+func TestAWSKMSBackend(t *testing.T) {
+	fake, srv := newFakeKMS(t)
+	cfg := config.Database{
+		Passphrase: testPassphrase,
+		Secrets: config.Secrets{
+			Backend: config.AWSKMSSecretsBackend,
+			AWSKMS: config.AWSKMS{
+				Region:          "us-east-1",
+				KeyID:           "alias/garm",
+				Endpoint:        srv.URL,
+				AccessKeyID:     "access",
+				SecretAccessKey: "secret",
+			},
+		},
+	}
+	backend, err := NewBackend(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, config.AWSKMSSecretsBackend, backend.Type())
+
+	sealed, err := backend.Seal([]byte("secret"))
+	require.NoError(t, err)
+	require.Equal(t, config.AWSKMSSecretsBackend, SealedWith(sealed))
+
+	backend, err = NewBackend(context.Background(), cfg)
+	require.NoError(t, err)
+	data, err := backend.Unseal(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(data))
+	require.Equal(t, 2, fake.requests)
+
+	// Data sealed by a different backend is rejected.
+	_, vaultSrv := newFakeVault(t)
+	vaultBackend, err := NewBackend(context.Background(), vaultConfig(vaultSrv.URL))
+	require.NoError(t, err)
+	_, err = vaultBackend.Unseal(sealed)
+	require.EqualError(t, err, "data was sealed by the awskms secrets backend")
+
+	cfg.Secrets.AWSKMS.AccessKeyID = "bogus"
+	backend, err = NewBackend(context.Background(), cfg)
+	require.NoError(t, err)
+	_, err = backend.Unseal(sealed)
+	require.ErrorContains(t, err, "UnrecognizedClientException invalid credentials")
+}
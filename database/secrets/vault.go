@@ -0,0 +1,121 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/config"
+)
+
+const providerRequestTimeout = 30 * time.Second
+
+// vaultKeyProvider generates data keys using the Vault transit secrets engine.
+type vaultKeyProvider struct {
+	cfg    config.Vault
+	client *http.Client
+}
+
+func newVaultKeyProvider(cfg config.Vault) (*vaultKeyProvider, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	caBundle, err := cfg.CACertBundle()
+	if err != nil {
+		return nil, err
+	}
+	if caBundle != nil {
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(caBundle)
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return &vaultKeyProvider{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   providerRequestTimeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+type vaultResponse struct {
+	Data struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func (v *vaultKeyProvider) do(ctx context.Context, path string, body interface{}) (vaultResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return vaultResponse{}, errors.Wrap(err, "marshaling request")
+	}
+	u := fmt.Sprintf("%s/v1/%s/%s/%s", strings.TrimSuffix(v.cfg.Address, "/"), v.cfg.GetMountPath(), path, v.cfg.KeyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return vaultResponse{}, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.cfg.GetToken())
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return vaultResponse{}, errors.Wrap(err, "calling vault")
+	}
+	defer resp.Body.Close()
+
+	var ret vaultResponse
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return vaultResponse{}, errors.Wrap(err, "reading response")
+	}
+	if err := json.Unmarshal(data, &ret); err != nil && resp.StatusCode/100 == 2 {
+		return vaultResponse{}, errors.Wrap(err, "decoding response")
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(ret.Errors) > 0 {
+			return vaultResponse{}, fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(ret.Errors, "; "))
+		}
+		return vaultResponse{}, fmt.Errorf("vault returned %s", resp.Status)
+	}
+	return ret, nil
+}
+
+func (v *vaultKeyProvider) generateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	resp, err := v.do(ctx, "datakey/plaintext", map[string]interface{}{"bits": dataKeySize * 8})
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "decoding data key")
+	}
+	return plaintext, []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *vaultKeyProvider) decryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	resp, err := v.do(ctx, "decrypt", map[string]interface{}{"ciphertext": string(encrypted)})
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding data key")
+	}
+	return plaintext, nil
+}
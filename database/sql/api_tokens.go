@@ -0,0 +1,151 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.APITokenStore = &sqlDatabase{}
+
+func sqlToParamsAPIToken(token APIToken) (params.APIToken, error) {
+	ret := params.APIToken{
+		ID:         token.ID.String(),
+		CreatedAt:  token.CreatedAt,
+		Name:       token.Name,
+		UserID:     token.UserID.String(),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+	if len(token.Scopes) > 0 {
+		if err := json.Unmarshal(token.Scopes, &ret.Scopes); err != nil {
+			return params.APIToken{}, errors.Wrap(err, "unmarshaling scopes")
+		}
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) CreateAPIToken(_ context.Context, userID string, param params.CreateAPITokenParams, tokenHash string) (params.APIToken, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return params.APIToken{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing user id")
+	}
+	scopes, err := json.Marshal(param.Scopes)
+	if err != nil {
+		return params.APIToken{}, errors.Wrap(err, "marshaling scopes")
+	}
+
+	newToken := APIToken{
+		UserID:    id,
+		Name:      param.Name,
+		TokenHash: tokenHash,
+		Scopes:    scopes,
+	}
+	if param.TTLDays > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(param.TTLDays) * 24 * time.Hour)
+		newToken.ExpiresAt = &expiresAt
+	}
+
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.getUserByID(tx, userID); err != nil {
+			return errors.Wrap(err, "fetching user")
+		}
+		var existing APIToken
+		q := tx.Where("user_id = ? and name = ?", id, param.Name).First(&existing)
+		if q.Error == nil {
+			return runnerErrors.NewConflictError("an API token named %s already exists", param.Name)
+		}
+		if !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return errors.Wrap(q.Error, "fetching API token")
+		}
+		if q := tx.Create(&newToken); q.Error != nil {
+			return errors.Wrap(q.Error, "saving API token")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.APIToken{}, errors.Wrap(err, "creating API token")
+	}
+	return sqlToParamsAPIToken(newToken)
+}
+
+func (s *sqlDatabase) GetAPITokenByHash(_ context.Context, tokenHash string) (params.APIToken, error) {
+	var token APIToken
+	q := s.conn.Where("token_hash = ?", tokenHash).First(&token)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.APIToken{}, runnerErrors.ErrNotFound
+		}
+		return params.APIToken{}, errors.Wrap(q.Error, "fetching API token")
+	}
+	return sqlToParamsAPIToken(token)
+}
+
+// ListAPITokens returns the API tokens of a user, or of all users if userID is empty,
+// oldest first.
+func (s *sqlDatabase) ListAPITokens(_ context.Context, userID string) ([]params.APIToken, error) {
+	q := s.conn.Model(&APIToken{})
+	if userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, errors.Wrap(runnerErrors.ErrBadRequest, "parsing user id")
+		}
+		q = q.Where("user_id = ?", id)
+	}
+
+	var tokens []APIToken
+	if err := q.Order("created_at").Find(&tokens).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching API tokens")
+	}
+	ret := make([]params.APIToken, len(tokens))
+	for idx, token := range tokens {
+		var err error
+		ret[idx], err = sqlToParamsAPIToken(token)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting API token")
+		}
+	}
+	return ret, nil
+}
+
+// DeleteAPIToken revokes an API token. Revoking a token that does not exist is not an
+// error.
+func (s *sqlDatabase) DeleteAPIToken(_ context.Context, userID, tokenID string) error {
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	q := s.conn.Unscoped().Where("id = ?", id)
+	if userID != "" {
+		owner, err := uuid.Parse(userID)
+		if err != nil {
+			return errors.Wrap(runnerErrors.ErrBadRequest, "parsing user id")
+		}
+		q = q.Where("user_id = ?", owner)
+	}
+	if err := q.Delete(&APIToken{}).Error; err != nil {
+		return errors.Wrap(err, "deleting API token")
+	}
+	return nil
+}
+
+func (s *sqlDatabase) SetAPITokenLastUsed(_ context.Context, tokenID string, lastUsed time.Time) error {
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+	q := s.conn.Model(&APIToken{}).Where("id = ?", id).Update("last_used_at", lastUsed)
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "updating API token")
+	}
+	return nil
+}
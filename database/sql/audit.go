@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.AuditStore = &sqlDatabase{}
+
+func sqlToParamsAuditRecord(record AuditRecord) (params.AuditRecord, error) {
+	ret := params.AuditRecord{
+		ID:             record.ID.String(),
+		CreatedAt:      record.CreatedAt,
+		Action:         record.Action,
+		ImpersonatorID: record.ImpersonatorID,
+		Impersonator:   record.Impersonator,
+		UserID:         record.UserID,
+		Username:       record.Username,
+		Reason:         record.Reason,
+		Method:         record.Method,
+		Path:           record.Path,
+		StatusCode:     record.StatusCode,
+		ResourceType:   record.ResourceType,
+		ResourceID:     record.ResourceID,
+	}
+	if len(record.Changes) > 0 {
+		if err := json.Unmarshal(record.Changes, &ret.Changes); err != nil {
+			return params.AuditRecord{}, errors.Wrap(err, "unmarshaling changes")
+		}
+	}
+	return ret, nil
+}
+
+// CreateAuditRecord stores a new audit record. Audit records are never updated.
+func (s *sqlDatabase) CreateAuditRecord(_ context.Context, record params.AuditRecord) (params.AuditRecord, error) {
+	newRecord := AuditRecord{
+		Action:         record.Action,
+		ImpersonatorID: record.ImpersonatorID,
+		Impersonator:   record.Impersonator,
+		UserID:         record.UserID,
+		Username:       record.Username,
+		Reason:         record.Reason,
+		Method:         record.Method,
+		Path:           record.Path,
+		StatusCode:     record.StatusCode,
+		ResourceType:   record.ResourceType,
+		ResourceID:     record.ResourceID,
+	}
+	if len(record.Changes) > 0 {
+		changes, err := json.Marshal(record.Changes)
+		if err != nil {
+			return params.AuditRecord{}, errors.Wrap(err, "marshaling changes")
+		}
+		newRecord.Changes = changes
+	}
+	if q := s.conn.Create(&newRecord); q.Error != nil {
+		return params.AuditRecord{}, errors.Wrap(q.Error, "creating audit record")
+	}
+	return sqlToParamsAuditRecord(newRecord)
+}
+
+// ListAuditRecords returns one page of audit records, newest first.
+func (s *sqlDatabase) ListAuditRecords(_ context.Context, param params.ListAuditRecordsParams) (params.AuditRecordsPage, error) {
+	if err := param.Validate(); err != nil {
+		return params.AuditRecordsPage{}, errors.Wrap(err, "validating params")
+	}
+
+	q := s.conn.Model(&AuditRecord{})
+	if param.Action != "" {
+		q = q.Where("action = ?", param.Action)
+	}
+	if param.ResourceType != "" {
+		q = q.Where("resource_type = ?", param.ResourceType)
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return params.AuditRecordsPage{}, errors.Wrap(err, "counting audit records")
+	}
+
+	var records []AuditRecord
+	if err := q.Order("created_at desc").
+		Offset(int((param.Page - 1) * param.PageSize)).
+		Limit(int(param.PageSize)).
+		Find(&records).Error; err != nil {
+		return params.AuditRecordsPage{}, errors.Wrap(err, "fetching audit records")
+	}
+
+	total := uint(count)
+	ret := params.AuditRecordsPage{
+		Records:    make([]params.AuditRecord, len(records)),
+		Page:       param.Page,
+		PageSize:   param.PageSize,
+		TotalCount: total,
+		TotalPages: (total + param.PageSize - 1) / param.PageSize,
+	}
+	for idx, record := range records {
+		asParams, err := sqlToParamsAuditRecord(record)
+		if err != nil {
+			return params.AuditRecordsPage{}, errors.Wrap(err, "converting audit record")
+		}
+		ret.Records[idx] = asParams
+	}
+	return ret, nil
+}
+
+// DeleteAuditRecordsBefore removes the audit records created before the given time.
+func (s *sqlDatabase) DeleteAuditRecordsBefore(_ context.Context, before time.Time) error {
+	if q := s.conn.Unscoped().Where("created_at < ?", before).Delete(&AuditRecord{}); q.Error != nil {
+		return errors.Wrap(q.Error, "deleting audit records")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.CapacityReservationStore = &sqlDatabase{}
+
+func sqlToParamsCapacityReservation(reservation CapacityReservation) params.CapacityReservation {
+	return params.CapacityReservation{
+		ID:               reservation.ID.String(),
+		CreatedAt:        reservation.CreatedAt,
+		PoolID:           reservation.PoolID.String(),
+		ExtraIdleRunners: reservation.ExtraIdleRunners,
+		StartTime:        reservation.StartTime,
+		EndTime:          reservation.EndTime,
+		Reason:           reservation.Reason,
+	}
+}
+
+func (s *sqlDatabase) CreateCapacityReservation(_ context.Context, param params.CreateCapacityReservationParams) (params.CapacityReservation, error) {
+	pool, err := s.getPoolByID(s.conn, param.PoolID)
+	if err != nil {
+		return params.CapacityReservation{}, errors.Wrap(err, "fetching pool")
+	}
+
+	newReservation := CapacityReservation{
+		PoolID:           pool.ID,
+		ExtraIdleRunners: param.ExtraIdleRunners,
+		StartTime:        param.StartTime.UTC(),
+		EndTime:          param.EndTime.UTC(),
+		Reason:           param.Reason,
+	}
+	if q := s.conn.Omit("Pool").Create(&newReservation); q.Error != nil {
+		return params.CapacityReservation{}, errors.Wrap(q.Error, "creating capacity reservation")
+	}
+	return sqlToParamsCapacityReservation(newReservation), nil
+}
+
+func (s *sqlDatabase) ListCapacityReservations(_ context.Context) ([]params.CapacityReservation, error) {
+	var reservations []CapacityReservation
+	if q := s.conn.Model(&CapacityReservation{}).Order("start_time, created_at").Find(&reservations); q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching capacity reservations")
+	}
+
+	ret := make([]params.CapacityReservation, len(reservations))
+	for idx, reservation := range reservations {
+		ret[idx] = sqlToParamsCapacityReservation(reservation)
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) DeleteCapacityReservation(_ context.Context, reservationID string) error {
+	id, err := uuid.Parse(reservationID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	var reservation CapacityReservation
+	if q := s.conn.Where("id = ?", id).First(&reservation); q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.Wrap(q.Error, "fetching capacity reservation")
+	}
+	if q := s.conn.Unscoped().Delete(&reservation); q.Error != nil {
+		return errors.Wrap(q.Error, "deleting capacity reservation")
+	}
+	return nil
+}
@@ -15,6 +15,8 @@
 package sql
 
 import (
+	"context"
+	"encoding/json"
 	"net/url"
 
 	"github.com/google/uuid"
@@ -33,17 +35,46 @@ func dbControllerToCommonController(dbInfo ControllerInfo) (params.ControllerInf
 		return params.ControllerInfo{}, errors.Wrap(err, "joining webhook URL")
 	}
 
+	var urlSets []params.ControllerURLSet
+	if len(dbInfo.URLSets) > 0 {
+		if err := json.Unmarshal(dbInfo.URLSets, &urlSets); err != nil {
+			return params.ControllerInfo{}, errors.Wrap(err, "unmarshaling url sets")
+		}
+	}
+
 	return params.ControllerInfo{
 		ControllerID:         dbInfo.ControllerID,
+		PreviousControllerID: dbInfo.PreviousControllerID,
 		MetadataURL:          dbInfo.MetadataURL,
 		WebhookURL:           dbInfo.WebhookBaseURL,
 		ControllerWebhookURL: url,
 		CallbackURL:          dbInfo.CallbackURL,
 		MinimumJobAgeBackoff: dbInfo.MinimumJobAgeBackoff,
 		Version:              appdefaults.GetVersion(),
+		URLSets:              urlSets,
 	}, nil
 }
 
+// validateURLSet makes sure the URL set selected by a pool exists. An empty name
+// selects the default URLs.
+func (s *sqlDatabase) validateURLSet(tx *gorm.DB, name string) error {
+	if name == "" {
+		return nil
+	}
+	var dbInfo ControllerInfo
+	if q := tx.Model(&ControllerInfo{}).First(&dbInfo); q.Error != nil {
+		return errors.Wrap(q.Error, "fetching controller info")
+	}
+	info, err := dbControllerToCommonController(dbInfo)
+	if err != nil {
+		return errors.Wrap(err, "converting controller info")
+	}
+	if !info.HasURLSet(name) {
+		return runnerErrors.NewBadRequestError("url set %s does not exist", name)
+	}
+	return nil
+}
+
 func (s *sqlDatabase) ControllerInfo() (params.ControllerInfo, error) {
 	var info ControllerInfo
 	q := s.conn.Model(&ControllerInfo{}).First(&info)
@@ -123,6 +154,32 @@ func (s *sqlDatabase) UpdateController(info params.UpdateControllerParams) (para
 			dbInfo.MinimumJobAgeBackoff = *info.MinimumJobAgeBackoff
 		}
 
+		if info.URLSets != nil {
+			names := make([]string, 0, len(*info.URLSets))
+			for _, set := range *info.URLSets {
+				names = append(names, set.Name)
+			}
+			// URL sets that are still selected by a pool can't be removed.
+			var pool Pool
+			q := tx.Model(&Pool{}).Where("url_set != ''")
+			if len(names) > 0 {
+				q = q.Where("url_set NOT IN ?", names)
+			}
+			q = q.Limit(1).Find(&pool)
+			if q.Error != nil {
+				return errors.Wrap(q.Error, "fetching pools")
+			}
+			if q.RowsAffected > 0 {
+				return runnerErrors.NewBadRequestError("url set %s is used by pool %s", pool.URLSet, pool.ID)
+			}
+
+			asJs, err := json.Marshal(*info.URLSets)
+			if err != nil {
+				return errors.Wrap(err, "marshaling url sets")
+			}
+			dbInfo.URLSets = asJs
+		}
+
 		q = tx.Save(&dbInfo)
 		if q.Error != nil {
 			return errors.Wrap(q.Error, "saving controller info")
@@ -139,3 +196,151 @@ func (s *sqlDatabase) UpdateController(info params.UpdateControllerParams) (para
 	}
 	return paramInfo, nil
 }
+
+func (s *sqlDatabase) SetControllerID(_ context.Context, controllerID uuid.UUID, previousID *uuid.UUID) (paramInfo params.ControllerInfo, err error) {
+	defer func() {
+		if err == nil {
+			s.sendNotify(common.ControllerEntityType, common.UpdateOperation, paramInfo)
+		}
+	}()
+	var dbInfo ControllerInfo
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&ControllerInfo{}).First(&dbInfo)
+		if q.Error != nil {
+			if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+				return errors.Wrap(runnerErrors.ErrNotFound, "fetching controller info")
+			}
+			return errors.Wrap(q.Error, "fetching controller info")
+		}
+
+		dbInfo.ControllerID = controllerID
+		dbInfo.PreviousControllerID = previousID
+		q = tx.Save(&dbInfo)
+		if q.Error != nil {
+			return errors.Wrap(q.Error, "saving controller info")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.ControllerInfo{}, errors.Wrap(err, "setting controller ID")
+	}
+
+	paramInfo, err = dbControllerToCommonController(dbInfo)
+	if err != nil {
+		return params.ControllerInfo{}, errors.Wrap(err, "converting controller info")
+	}
+	return paramInfo, nil
+}
+
+func sqlToParamsControllerIDMigration(migration ControllerIDMigration) (params.ControllerIDMigration, error) {
+	ret := params.ControllerIDMigration{
+		ID:                   migration.ID.String(),
+		PreviousControllerID: migration.PreviousControllerID,
+		NewControllerID:      migration.NewControllerID,
+		Phase:                migration.Phase,
+		PendingRunners:       migration.PendingRunners,
+		SwitchedAt:           migration.SwitchedAt,
+		CompletedAt:          migration.CompletedAt,
+		CreatedAt:            migration.CreatedAt,
+		UpdatedAt:            migration.UpdatedAt,
+	}
+	if len(migration.Webhooks) > 0 {
+		if err := json.Unmarshal(migration.Webhooks, &ret.Webhooks); err != nil {
+			return params.ControllerIDMigration{}, errors.Wrap(err, "unmarshaling webhooks")
+		}
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) CreateControllerIDMigration(_ context.Context, newControllerID uuid.UUID) (params.ControllerIDMigration, error) {
+	var migration ControllerIDMigration
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		var info ControllerInfo
+		q := tx.Model(&ControllerInfo{}).First(&info)
+		if q.Error != nil {
+			if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+				return errors.Wrap(runnerErrors.ErrNotFound, "fetching controller info")
+			}
+			return errors.Wrap(q.Error, "fetching controller info")
+		}
+		if info.ControllerID == newControllerID {
+			return runnerErrors.NewBadRequestError("controller already uses ID %s", newControllerID)
+		}
+
+		var running int64
+		q = tx.Model(&ControllerIDMigration{}).Where("phase != ?", params.MigrationPhaseCompleted).Count(&running)
+		if q.Error != nil {
+			return errors.Wrap(q.Error, "counting controller ID migrations")
+		}
+		if running > 0 {
+			return runnerErrors.NewConflictError("a controller ID migration is already running")
+		}
+
+		migration = ControllerIDMigration{
+			PreviousControllerID: info.ControllerID,
+			NewControllerID:      newControllerID,
+			Phase:                params.MigrationPhaseUpdateController,
+		}
+		if q := tx.Create(&migration); q.Error != nil {
+			return errors.Wrap(q.Error, "creating controller ID migration")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.ControllerIDMigration{}, errors.Wrap(err, "creating controller ID migration")
+	}
+	return sqlToParamsControllerIDMigration(migration)
+}
+
+func (s *sqlDatabase) GetControllerIDMigration(_ context.Context) (params.ControllerIDMigration, error) {
+	var migration ControllerIDMigration
+	q := s.conn.Model(&ControllerIDMigration{}).Order("created_at desc").First(&migration)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.ControllerIDMigration{}, errors.Wrap(runnerErrors.ErrNotFound, "fetching controller ID migration")
+		}
+		return params.ControllerIDMigration{}, errors.Wrap(q.Error, "fetching controller ID migration")
+	}
+	return sqlToParamsControllerIDMigration(migration)
+}
+
+func (s *sqlDatabase) UpdateControllerIDMigration(_ context.Context, migrationID string, param params.UpdateControllerIDMigrationParams) (params.ControllerIDMigration, error) {
+	id, err := uuid.Parse(migrationID)
+	if err != nil {
+		return params.ControllerIDMigration{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	var migration ControllerIDMigration
+	q := s.conn.Model(&ControllerIDMigration{}).Where("id = ?", id).First(&migration)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.ControllerIDMigration{}, errors.Wrap(runnerErrors.ErrNotFound, "fetching controller ID migration")
+		}
+		return params.ControllerIDMigration{}, errors.Wrap(q.Error, "fetching controller ID migration")
+	}
+
+	if param.Phase != nil {
+		migration.Phase = *param.Phase
+	}
+	if param.Webhooks != nil {
+		asJSON, err := json.Marshal(param.Webhooks)
+		if err != nil {
+			return params.ControllerIDMigration{}, errors.Wrap(err, "marshaling webhooks")
+		}
+		migration.Webhooks = asJSON
+	}
+	if param.PendingRunners != nil {
+		migration.PendingRunners = *param.PendingRunners
+	}
+	if param.SwitchedAt != nil {
+		migration.SwitchedAt = param.SwitchedAt
+	}
+	if param.CompletedAt != nil {
+		migration.CompletedAt = param.CompletedAt
+	}
+
+	if q := s.conn.Save(&migration); q.Error != nil {
+		return params.ControllerIDMigration{}, errors.Wrap(q.Error, "saving controller ID migration")
+	}
+	return sqlToParamsControllerIDMigration(migration)
+}
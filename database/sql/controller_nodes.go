@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.ControllerNodeStore = &sqlDatabase{}
+
+func sqlToParamsControllerNode(node ControllerNode) params.ControllerNode {
+	return params.ControllerNode{
+		ID:            node.ID.String(),
+		Name:          node.Name,
+		LastHeartbeat: node.LastHeartbeat,
+	}
+}
+
+func (s *sqlDatabase) UpdateControllerNodeHeartbeat(_ context.Context, nodeID, name string) (params.ControllerNode, error) {
+	id, err := uuid.Parse(nodeID)
+	if err != nil {
+		return params.ControllerNode{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	node := ControllerNode{
+		ID:            id,
+		Name:          name,
+		LastHeartbeat: time.Now().UTC(),
+	}
+	q := s.conn.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "last_heartbeat"}),
+	}).Create(&node)
+	if q.Error != nil {
+		return params.ControllerNode{}, errors.Wrap(q.Error, "updating controller node")
+	}
+	return sqlToParamsControllerNode(node), nil
+}
+
+// ListControllerNodes returns the registered controller nodes, along with the
+// entities each of them holds an unexpired lease on.
+func (s *sqlDatabase) ListControllerNodes(_ context.Context) ([]params.ControllerNode, error) {
+	var nodes []ControllerNode
+	if q := s.conn.Order("name, id").Find(&nodes); q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching controller nodes")
+	}
+
+	var leases []EntityLease
+	if q := s.conn.Where("expires_at > ?", time.Now().UTC()).Order("entity_id").Find(&leases); q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching entity leases")
+	}
+	entities := map[uuid.UUID][]string{}
+	for _, lease := range leases {
+		entities[lease.NodeID] = append(entities[lease.NodeID], lease.EntityID.String())
+	}
+
+	ret := make([]params.ControllerNode, len(nodes))
+	for idx, node := range nodes {
+		ret[idx] = sqlToParamsControllerNode(node)
+		ret[idx].Entities = entities[node.ID]
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) DeleteControllerNode(_ context.Context, nodeID string) error {
+	id, err := uuid.Parse(nodeID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		if q := tx.Where("node_id = ?", id).Delete(&EntityLease{}); q.Error != nil {
+			return errors.Wrap(q.Error, "deleting entity leases")
+		}
+		if q := tx.Where("id = ?", id).Delete(&ControllerNode{}); q.Error != nil {
+			return errors.Wrap(q.Error, "deleting controller node")
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "deleting controller node")
+	}
+	return nil
+}
+
+func (s *sqlDatabase) DeleteStaleControllerNodes(_ context.Context, since time.Time) error {
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		var stale []uuid.UUID
+		if q := tx.Model(&ControllerNode{}).Where("last_heartbeat < ?", since).Pluck("id", &stale); q.Error != nil {
+			return errors.Wrap(q.Error, "fetching stale controller nodes")
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		if q := tx.Where("node_id in ?", stale).Delete(&EntityLease{}); q.Error != nil {
+			return errors.Wrap(q.Error, "deleting entity leases")
+		}
+		if q := tx.Where("id in ?", stale).Delete(&ControllerNode{}); q.Error != nil {
+			return errors.Wrap(q.Error, "deleting controller nodes")
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "deleting stale controller nodes")
+	}
+	return nil
+}
+
+func (s *sqlDatabase) AcquireEntityLease(_ context.Context, entityID, nodeID string, expiresAt time.Time) (bool, error) {
+	entity, err := uuid.Parse(entityID)
+	if err != nil {
+		return false, errors.Wrap(runnerErrors.ErrBadRequest, "parsing entity id")
+	}
+	node, err := uuid.Parse(nodeID)
+	if err != nil {
+		return false, errors.Wrap(runnerErrors.ErrBadRequest, "parsing node id")
+	}
+
+	// Take over the lease if it's ours or if it expired. This is a single statement,
+	// so two nodes can't both take over the same expired lease.
+	q := s.conn.Model(&EntityLease{}).
+		Where("entity_id = ? and (node_id = ? or expires_at < ?)", entity, node, time.Now().UTC()).
+		Updates(map[string]interface{}{
+			"node_id":    node,
+			"expires_at": expiresAt,
+		})
+	if q.Error != nil {
+		return false, errors.Wrap(q.Error, "updating entity lease")
+	}
+	if q.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// There is no lease for this entity, or another node holds it.
+	lease := EntityLease{
+		EntityID:  entity,
+		NodeID:    node,
+		ExpiresAt: expiresAt,
+	}
+	q = s.conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease)
+	if q.Error != nil {
+		return false, errors.Wrap(q.Error, "creating entity lease")
+	}
+	return q.RowsAffected > 0, nil
+}
+
+func (s *sqlDatabase) ReleaseEntityLease(_ context.Context, entityID, nodeID string) error {
+	entity, err := uuid.Parse(entityID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing entity id")
+	}
+	node, err := uuid.Parse(nodeID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing node id")
+	}
+
+	if q := s.conn.Where("entity_id = ? and node_id = ?", entity, node).Delete(&EntityLease{}); q.Error != nil {
+		return errors.Wrap(q.Error, "releasing entity lease")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.DenyRuleStore = &sqlDatabase{}
+
+func sqlToParamsDenyRule(rule DenyRule) params.DenyRule {
+	return params.DenyRule{
+		ID:            rule.ID.String(),
+		CreatedAt:     rule.CreatedAt,
+		ProviderName:  rule.ProviderName,
+		ImagePattern:  rule.ImagePattern,
+		FlavorPattern: rule.FlavorPattern,
+		Reason:        rule.Reason,
+	}
+}
+
+func (s *sqlDatabase) CreateDenyRule(_ context.Context, param params.CreateDenyRuleParams) (params.DenyRule, error) {
+	newRule := DenyRule{
+		ProviderName:  param.ProviderName,
+		ImagePattern:  param.ImagePattern,
+		FlavorPattern: param.FlavorPattern,
+		Reason:        param.Reason,
+	}
+	if q := s.conn.Create(&newRule); q.Error != nil {
+		return params.DenyRule{}, errors.Wrap(q.Error, "creating deny rule")
+	}
+	return sqlToParamsDenyRule(newRule), nil
+}
+
+// ListDenyRules returns all deny rules, oldest first.
+func (s *sqlDatabase) ListDenyRules(_ context.Context) ([]params.DenyRule, error) {
+	var rules []DenyRule
+	if q := s.conn.Model(&DenyRule{}).Order("created_at").Find(&rules); q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching deny rules")
+	}
+
+	ret := make([]params.DenyRule, len(rules))
+	for idx, rule := range rules {
+		ret[idx] = sqlToParamsDenyRule(rule)
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) DeleteDenyRule(_ context.Context, ruleID string) error {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	var rule DenyRule
+	if q := s.conn.Where("id = ?", id).First(&rule); q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.Wrap(q.Error, "fetching deny rule")
+	}
+	if q := s.conn.Unscoped().Delete(&rule); q.Error != nil {
+		return errors.Wrap(q.Error, "deleting deny rule")
+	}
+	return nil
+}
@@ -23,7 +23,6 @@ import (
 	"gorm.io/gorm"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
-	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
@@ -32,7 +31,7 @@ func (s *sqlDatabase) CreateEnterprise(ctx context.Context, name, credentialsNam
 	if webhookSecret == "" {
 		return params.Enterprise{}, errors.New("creating enterprise: missing secret")
 	}
-	secret, err := util.Seal([]byte(webhookSecret), []byte(s.cfg.Passphrase))
+	secret, err := s.seal([]byte(webhookSecret))
 	if err != nil {
 		return params.Enterprise{}, errors.Wrap(err, "encoding secret")
 	}
@@ -96,7 +95,7 @@ func (s *sqlDatabase) GetEnterprise(ctx context.Context, name, endpointName stri
 }
 
 func (s *sqlDatabase) GetEnterpriseByID(ctx context.Context, enterpriseID string) (params.Enterprise, error) {
-	enterprise, err := s.getEnterpriseByID(ctx, s.conn, enterpriseID, "Pools", "Credentials", "Endpoint")
+	enterprise, err := s.getEnterpriseByID(ctx, s.conn, enterpriseID, "Pools", "Credentials", "Credentials.Endpoint", "Endpoint", "Events")
 	if err != nil {
 		return params.Enterprise{}, errors.Wrap(err, "fetching enterprise")
 	}
@@ -148,9 +147,18 @@ func (s *sqlDatabase) DeleteEnterprise(ctx context.Context, enterpriseID string)
 		}
 	}(enterprise)
 
-	q := s.conn.Unscoped().Delete(&enterprise)
-	if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
-		return errors.Wrap(q.Error, "deleting enterprise")
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Unscoped().Delete(&enterprise)
+		if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return errors.Wrap(q.Error, "deleting enterprise")
+		}
+		if err := s.deleteEntityRoleGrants(tx, enterprise.ID); err != nil {
+			return errors.Wrap(err, "deleting enterprise")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -190,17 +198,26 @@ func (s *sqlDatabase) UpdateEnterprise(ctx context.Context, enterpriseID string,
 			enterprise.CredentialsID = &creds.ID
 		}
 		if param.WebhookSecret != "" {
-			secret, err := util.Seal([]byte(param.WebhookSecret), []byte(s.cfg.Passphrase))
-			if err != nil {
+			if err := s.updateWebhookSecret(param, &enterprise.WebhookSecret, &enterprise.PreviousWebhookSecret, &enterprise.PreviousWebhookSecretExpiresAt); err != nil {
 				return errors.Wrap(err, "encoding secret")
 			}
-			enterprise.WebhookSecret = secret
 		}
 
 		if param.PoolBalancerType != "" {
 			enterprise.PoolBalancerType = param.PoolBalancerType
 		}
 
+		if param.MaxConcurrentJobs != nil {
+			enterprise.MaxConcurrentJobs = *param.MaxConcurrentJobs
+		}
+
+		if param.RoutingRules != nil {
+			enterprise.RoutingRules, err = marshalRoutingRules(*param.RoutingRules)
+			if err != nil {
+				return errors.Wrap(err, "marshaling routing rules")
+			}
+		}
+
 		q := tx.Save(&enterprise)
 		if q.Error != nil {
 			return errors.Wrap(q.Error, "saving enterprise")
@@ -226,7 +243,7 @@ func (s *sqlDatabase) UpdateEnterprise(ctx context.Context, enterpriseID string,
 func (s *sqlDatabase) getEnterprise(_ context.Context, name, endpointName string) (Enterprise, error) {
 	var enterprise Enterprise
 
-	q := s.conn.Where("name = ? COLLATE NOCASE and endpoint_name = ? COLLATE NOCASE", name, endpointName).
+	q := s.conn.Where(s.equalsNoCase("name")+" and "+s.equalsNoCase("endpoint_name"), name, endpointName).
 		Preload("Credentials").
 		Preload("Credentials.Endpoint").
 		Preload("Endpoint").
@@ -0,0 +1,228 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.EntityStore = &sqlDatabase{}
+
+func (s *sqlDatabase) AddEntityEvent(ctx context.Context, entity params.GithubEntity, event params.EventType, eventLevel params.EventLevel, statusMessage string, maxEvents int) error {
+	if maxEvents <= 0 {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "max events must be greater than 0")
+	}
+
+	var model interface{}
+	var fkColumn string
+	var entityID uuid.UUID
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		repo, err := s.getRepoByID(ctx, s.conn, entity.ID)
+		if err != nil {
+			return errors.Wrap(err, "fetching repo")
+		}
+		msg := RepositoryEvent{
+			Message:    statusMessage,
+			EventType:  event,
+			EventLevel: eventLevel,
+		}
+		if err := s.conn.Model(&repo).Association("Events").Append(&msg); err != nil {
+			return errors.Wrap(err, "adding status message")
+		}
+		model, fkColumn, entityID = &RepositoryEvent{}, "repo_id", repo.ID
+	case params.GithubEntityTypeOrganization:
+		org, err := s.getOrgByID(ctx, s.conn, entity.ID)
+		if err != nil {
+			return errors.Wrap(err, "fetching org")
+		}
+		msg := OrganizationEvent{
+			Message:    statusMessage,
+			EventType:  event,
+			EventLevel: eventLevel,
+		}
+		if err := s.conn.Model(&org).Association("Events").Append(&msg); err != nil {
+			return errors.Wrap(err, "adding status message")
+		}
+		model, fkColumn, entityID = &OrganizationEvent{}, "org_id", org.ID
+	case params.GithubEntityTypeEnterprise:
+		enterprise, err := s.getEnterpriseByID(ctx, s.conn, entity.ID)
+		if err != nil {
+			return errors.Wrap(err, "fetching enterprise")
+		}
+		msg := EnterpriseEvent{
+			Message:    statusMessage,
+			EventType:  event,
+			EventLevel: eventLevel,
+		}
+		if err := s.conn.Model(&enterprise).Association("Events").Append(&msg); err != nil {
+			return errors.Wrap(err, "adding status message")
+		}
+		model, fkColumn, entityID = &EnterpriseEvent{}, "enterprise_id", enterprise.ID
+	default:
+		return errors.Wrap(runnerErrors.ErrBadRequest, "invalid entity type")
+	}
+
+	if err := s.trimEvents(model, fkColumn, entityID, maxEvents); err != nil {
+		// The event was recorded. Failing to remove old events is not fatal.
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to remove old entity events", "entity", entity.String())
+	}
+	return nil
+}
+
+func (s *sqlDatabase) ListEntityEvents(_ context.Context, eventLevel params.EventLevel) ([]params.EntityEvent, error) {
+	ret := []params.EntityEvent{}
+
+	var repoEvents []RepositoryEvent
+	if err := s.conn.Preload("Repo").Where("event_level = ?", eventLevel).Find(&repoEvents).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching repository events")
+	}
+	for _, event := range repoEvents {
+		ret = append(ret, params.EntityEvent{
+			StatusMessage: params.StatusMessage{
+				CreatedAt:  event.CreatedAt,
+				Message:    event.Message,
+				EventType:  event.EventType,
+				EventLevel: event.EventLevel,
+			},
+			EntityType: params.GithubEntityTypeRepository,
+			EntityID:   event.RepoID.String(),
+			EntityName: fmt.Sprintf("%s/%s", event.Repo.Owner, event.Repo.Name),
+		})
+	}
+
+	var orgEvents []OrganizationEvent
+	if err := s.conn.Preload("Org").Where("event_level = ?", eventLevel).Find(&orgEvents).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching organization events")
+	}
+	for _, event := range orgEvents {
+		ret = append(ret, params.EntityEvent{
+			StatusMessage: params.StatusMessage{
+				CreatedAt:  event.CreatedAt,
+				Message:    event.Message,
+				EventType:  event.EventType,
+				EventLevel: event.EventLevel,
+			},
+			EntityType: params.GithubEntityTypeOrganization,
+			EntityID:   event.OrgID.String(),
+			EntityName: event.Org.Name,
+		})
+	}
+
+	var enterpriseEvents []EnterpriseEvent
+	if err := s.conn.Preload("Enterprise").Where("event_level = ?", eventLevel).Find(&enterpriseEvents).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching enterprise events")
+	}
+	for _, event := range enterpriseEvents {
+		ret = append(ret, params.EntityEvent{
+			StatusMessage: params.StatusMessage{
+				CreatedAt:  event.CreatedAt,
+				Message:    event.Message,
+				EventType:  event.EventType,
+				EventLevel: event.EventLevel,
+			},
+			EntityType: params.GithubEntityTypeEnterprise,
+			EntityID:   event.EnterpriseID.String(),
+			EntityName: event.Enterprise.Name,
+		})
+	}
+	return ret, nil
+}
+
+// trimEvents removes the oldest events of an entity or instance, keeping at most maxEvents.
+func (s *sqlDatabase) trimEvents(model interface{}, fkColumn string, entityID uuid.UUID, maxEvents int) error {
+	return s.conn.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(model).Where(fkColumn+" = ?", entityID).Count(&count).Error; err != nil {
+			return errors.Wrap(err, "counting events")
+		}
+		if count <= int64(maxEvents) {
+			return nil
+		}
+
+		var ids []uuid.UUID
+		q := tx.Model(model).
+			Where(fkColumn+" = ?", entityID).
+			Order("created_at asc").
+			Limit(int(count)-maxEvents).
+			Pluck("id", &ids)
+		if q.Error != nil {
+			return errors.Wrap(q.Error, "fetching old events")
+		}
+
+		if err := tx.Unscoped().Where("id in ?", ids).Delete(model).Error; err != nil {
+			return errors.Wrap(err, "deleting old events")
+		}
+		return nil
+	})
+}
+
+func (s *sqlDatabase) SetEntityPendingWebhookInstall(ctx context.Context, entity params.GithubEntity, param *params.InstallWebhookParams) error {
+	var pending []byte
+	if param != nil {
+		var err error
+		pending, err = json.Marshal(param)
+		if err != nil {
+			return errors.Wrap(err, "marshaling webhook params")
+		}
+	}
+	return s.updateWebhookEntityColumn(ctx, entity, "pending_webhook_install", pending)
+}
+
+func (s *sqlDatabase) SetEntityWebhookPath(ctx context.Context, entity params.GithubEntity, enabled bool) error {
+	return s.updateWebhookEntityColumn(ctx, entity, "entity_webhook_path", enabled)
+}
+
+// updateWebhookEntityColumn updates a column of a repository or organization, the only
+// entities GARM can install webhooks for, and notifies the watchers of the change.
+func (s *sqlDatabase) updateWebhookEntityColumn(ctx context.Context, entity params.GithubEntity, column string, value interface{}) error {
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		repo, err := s.getRepoByID(ctx, s.conn, entity.ID)
+		if err != nil {
+			return errors.Wrap(err, "fetching repo")
+		}
+		if err := s.conn.Model(&repo).Update(column, value).Error; err != nil {
+			return errors.Wrap(err, "updating repo")
+		}
+		repo, err = s.getRepoByID(ctx, s.conn, entity.ID, "Endpoint", "Credentials", "Credentials.Endpoint")
+		if err != nil {
+			return errors.Wrap(err, "fetching repo")
+		}
+		asParams, err := s.sqlToCommonRepository(repo, true)
+		if err != nil {
+			return errors.Wrap(err, "converting repo")
+		}
+		s.sendNotify(common.RepositoryEntityType, common.UpdateOperation, asParams)
+	case params.GithubEntityTypeOrganization:
+		org, err := s.getOrgByID(ctx, s.conn, entity.ID)
+		if err != nil {
+			return errors.Wrap(err, "fetching org")
+		}
+		if err := s.conn.Model(&org).Update(column, value).Error; err != nil {
+			return errors.Wrap(err, "updating org")
+		}
+		org, err = s.getOrgByID(ctx, s.conn, entity.ID, "Endpoint", "Credentials", "Credentials.Endpoint")
+		if err != nil {
+			return errors.Wrap(err, "fetching org")
+		}
+		asParams, err := s.sqlToCommonOrganization(org, true)
+		if err != nil {
+			return errors.Wrap(err, "converting org")
+		}
+		s.sendNotify(common.OrganizationEntityType, common.UpdateOperation, asParams)
+	default:
+		return errors.Wrapf(runnerErrors.ErrBadRequest, "webhooks are not supported for entity type %s", entity.EntityType)
+	}
+	return nil
+}
@@ -16,13 +16,13 @@ package sql
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
-	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
@@ -36,7 +36,7 @@ func (s *sqlDatabase) sqlToCommonGithubCredentials(creds GithubCredentials) (par
 	if len(creds.Payload) == 0 {
 		return params.GithubCredentials{}, errors.New("empty credentials payload")
 	}
-	data, err := util.Unseal(creds.Payload, []byte(s.cfg.Passphrase))
+	data, err := s.unseal(creds.Payload)
 	if err != nil {
 		return params.GithubCredentials{}, errors.Wrap(err, "unsealing credentials")
 	}
@@ -59,6 +59,14 @@ func (s *sqlDatabase) sqlToCommonGithubCredentials(creds GithubCredentials) (par
 		CredentialsPayload: data,
 	}
 
+	if len(creds.TokenSummary) > 0 {
+		var summary params.GithubTokenSummary
+		if err := json.Unmarshal(creds.TokenSummary, &summary); err != nil {
+			return params.GithubCredentials{}, errors.Wrap(err, "unmarshaling token summary")
+		}
+		commonCreds.TokenSummary = &summary
+	}
+
 	for _, repo := range creds.Repositories {
 		commonRepo, err := s.sqlToCommonRepository(repo, false)
 		if err != nil {
@@ -94,6 +102,9 @@ func (s *sqlDatabase) sqlToCommonGithubEndpoint(ep GithubEndpoint) (params.Githu
 		BaseURL:       ep.BaseURL,
 		UploadBaseURL: ep.UploadBaseURL,
 		CACertBundle:  ep.CACertBundle,
+
+		ToolsMetadataURL:         ep.ToolsMetadataURL,
+		UseInternalToolsMetadata: ep.UseInternalToolsMetadata,
 	}, nil
 }
 
@@ -128,6 +139,9 @@ func (s *sqlDatabase) CreateGithubEndpoint(_ context.Context, param params.Creat
 			BaseURL:       param.BaseURL,
 			UploadBaseURL: param.UploadBaseURL,
 			CACertBundle:  param.CACertBundle,
+
+			ToolsMetadataURL:         param.ToolsMetadataURL,
+			UseInternalToolsMetadata: param.UseInternalToolsMetadata,
 		}
 
 		if err := tx.Create(&endpoint).Error; err != nil {
@@ -201,6 +215,14 @@ func (s *sqlDatabase) UpdateGithubEndpoint(_ context.Context, name string, param
 			endpoint.Description = *param.Description
 		}
 
+		if param.ToolsMetadataURL != nil {
+			endpoint.ToolsMetadataURL = *param.ToolsMetadataURL
+		}
+
+		if param.UseInternalToolsMetadata != nil {
+			endpoint.UseInternalToolsMetadata = *param.UseInternalToolsMetadata
+		}
+
 		if err := tx.Save(&endpoint).Error; err != nil {
 			return errors.Wrap(err, "updating github endpoint")
 		}
@@ -344,6 +366,14 @@ func (s *sqlDatabase) CreateGithubCredentials(ctx context.Context, param params.
 			UserID:       &userID,
 		}
 
+		if param.TokenSummary != nil {
+			summary, err := json.Marshal(param.TokenSummary)
+			if err != nil {
+				return errors.Wrap(err, "marshaling token summary")
+			}
+			creds.TokenSummary = summary
+		}
+
 		if err := tx.Create(&creds).Error; err != nil {
 			return errors.Wrap(err, "creating github credentials")
 		}
@@ -493,6 +523,16 @@ func (s *sqlDatabase) UpdateGithubCredentials(ctx context.Context, id uint, para
 		case params.GithubAuthTypePAT:
 			if param.PAT != nil {
 				data, err = s.marshalAndSeal(param.PAT)
+				if err != nil {
+					return errors.Wrap(err, "marshaling and sealing credentials")
+				}
+				// The summary describes the old token. Drop it unless a new one was supplied.
+				creds.TokenSummary = nil
+				if param.TokenSummary != nil {
+					if creds.TokenSummary, err = json.Marshal(param.TokenSummary); err != nil {
+						return errors.Wrap(err, "marshaling token summary")
+					}
+				}
 			}
 
 			if param.App != nil {
@@ -192,6 +192,39 @@ func (s *GithubTestSuite) TestUpdateEndpoint() {
 	s.Require().Equal(caCertBundle, updatedEndpoint.CACertBundle)
 }
 
+func (s *GithubTestSuite) TestUpdateEndpointToolsMetadata() {
+	ctx := garmTesting.ImpersonateAdminContext(context.Background(), s.db, s.T())
+
+	createEpParams := params.CreateGithubEndpointParams{
+		Name:             testEndpointName,
+		Description:      testEndpointDescription,
+		APIBaseURL:       testAPIBaseURL,
+		UploadBaseURL:    testUploadBaseURL,
+		BaseURL:          testBaseURL,
+		ToolsMetadataURL: "https://mirror.example.com/tools.json",
+	}
+
+	endpoint, err := s.db.CreateGithubEndpoint(ctx, createEpParams)
+	s.Require().NoError(err)
+	s.Require().Equal(createEpParams.ToolsMetadataURL, endpoint.ToolsMetadataURL)
+	s.Require().True(endpoint.UsesToolsMetadataURL())
+
+	useInternal := true
+	updatedEndpoint, err := s.db.UpdateGithubEndpoint(ctx, testEndpointName, params.UpdateGithubEndpointParams{
+		UseInternalToolsMetadata: &useInternal,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(createEpParams.ToolsMetadataURL, updatedEndpoint.ToolsMetadataURL)
+	s.Require().False(updatedEndpoint.UsesToolsMetadataURL())
+
+	emptyURL := ""
+	updatedEndpoint, err = s.db.UpdateGithubEndpoint(ctx, testEndpointName, params.UpdateGithubEndpointParams{
+		ToolsMetadataURL: &emptyURL,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal("", updatedEndpoint.ToolsMetadataURL)
+}
+
 func (s *GithubTestSuite) TestUpdatingNonExistingEndpointReturnsNotFoundError() {
 	ctx := garmTesting.ImpersonateAdminContext(context.Background(), s.db, s.T())
 
@@ -0,0 +1,115 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.IdempotencyStore = &sqlDatabase{}
+
+func sqlToParamsIdempotencyRecord(record IdempotencyRecord) params.IdempotencyRecord {
+	return params.IdempotencyRecord{
+		Key:         record.IdempotencyKey,
+		UserID:      record.UserID,
+		RequestHash: record.RequestHash,
+		StatusCode:  record.StatusCode,
+		ContentType: record.ContentType,
+		Response:    record.Response,
+		CreatedAt:   record.CreatedAt,
+		ExpiresAt:   record.ExpiresAt,
+
+		ResponseOmitted: record.ResponseOmitted,
+		Pending:         record.Pending,
+	}
+}
+
+func (s *sqlDatabase) GetIdempotencyRecord(_ context.Context, userID, key string) (params.IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	q := s.conn.Model(&IdempotencyRecord{}).
+		Where("user_id = ? and idempotency_key = ? and expires_at > ?", userID, key, time.Now().UTC()).
+		First(&record)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.IdempotencyRecord{}, runnerErrors.ErrNotFound
+		}
+		return params.IdempotencyRecord{}, errors.Wrap(q.Error, "fetching idempotency record")
+	}
+	return sqlToParamsIdempotencyRecord(record), nil
+}
+
+func (s *sqlDatabase) CreateIdempotencyRecord(_ context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	newRecord := IdempotencyRecord{
+		UserID:         record.UserID,
+		IdempotencyKey: record.Key,
+		RequestHash:    record.RequestHash,
+		StatusCode:     record.StatusCode,
+		ContentType:    record.ContentType,
+		Response:       record.Response,
+		ExpiresAt:      record.ExpiresAt,
+
+		ResponseOmitted: record.ResponseOmitted,
+		Pending:         record.Pending,
+	}
+	// The unique index on the user and key makes sure only one request gets to create
+	// the record, even if retries are handled by different controllers.
+	q := s.conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&newRecord)
+	if q.Error != nil {
+		return params.IdempotencyRecord{}, errors.Wrap(q.Error, "creating idempotency record")
+	}
+	if q.RowsAffected == 0 {
+		return params.IdempotencyRecord{}, errors.Wrap(runnerErrors.ErrDuplicateEntity, "idempotency record already exists")
+	}
+	return sqlToParamsIdempotencyRecord(newRecord), nil
+}
+
+// UpdateIdempotencyRecord records the response of the request in the record of the
+// user for the key.
+func (s *sqlDatabase) UpdateIdempotencyRecord(_ context.Context, record params.IdempotencyRecord) (params.IdempotencyRecord, error) {
+	var dbRecord IdempotencyRecord
+	q := s.conn.Where("user_id = ? and idempotency_key = ?", record.UserID, record.Key).First(&dbRecord)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.IdempotencyRecord{}, runnerErrors.ErrNotFound
+		}
+		return params.IdempotencyRecord{}, errors.Wrap(q.Error, "fetching idempotency record")
+	}
+
+	dbRecord.RequestHash = record.RequestHash
+	dbRecord.StatusCode = record.StatusCode
+	dbRecord.ContentType = record.ContentType
+	dbRecord.Response = record.Response
+	dbRecord.ExpiresAt = record.ExpiresAt
+	dbRecord.ResponseOmitted = record.ResponseOmitted
+	dbRecord.Pending = record.Pending
+	if q := s.conn.Save(&dbRecord); q.Error != nil {
+		return params.IdempotencyRecord{}, errors.Wrap(q.Error, "saving idempotency record")
+	}
+	return sqlToParamsIdempotencyRecord(dbRecord), nil
+}
+
+// DeleteIdempotencyRecord removes the record of the user for the key, so the key
+// can be used again.
+func (s *sqlDatabase) DeleteIdempotencyRecord(_ context.Context, userID, key string) error {
+	q := s.conn.Unscoped().Where("user_id = ? and idempotency_key = ?", userID, key).Delete(&IdempotencyRecord{})
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "deleting idempotency record")
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyRecords removes the idempotency records that expired.
+func (s *sqlDatabase) DeleteExpiredIdempotencyRecords(_ context.Context) error {
+	q := s.conn.Unscoped().Where("expires_at <= ?", time.Now().UTC()).Delete(&IdempotencyRecord{})
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "deleting expired idempotency records")
+	}
+	return nil
+}
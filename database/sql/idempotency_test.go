@@ -0,0 +1,125 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	dbCommon "github.com/cloudbase/garm/database/common"
+	garmTesting "github.com/cloudbase/garm/internal/testing" //nolint:typecheck
+	"github.com/cloudbase/garm/params"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	Store dbCommon.Store
+}
+
+func (s *IdempotencyTestSuite) SetupTest() {
+	db, err := NewSQLDatabase(context.Background(), garmTesting.GetTestSqliteDBConfig(s.T()))
+	if err != nil {
+		s.FailNow(fmt.Sprintf("failed to create db connection: %s", err))
+	}
+	s.Store = db
+}
+
+func (s *IdempotencyTestSuite) TestCreateAndGetIdempotencyRecord() {
+	_, err := s.Store.CreateIdempotencyRecord(context.Background(), params.IdempotencyRecord{
+		Key:         "create-pool-1",
+		UserID:      "user-1",
+		RequestHash: "hash",
+		StatusCode:  200,
+		ContentType: "application/json",
+		Response:    []byte(`{"id":"pool-1"}`),
+		ExpiresAt:   time.Now().UTC().Add(time.Hour),
+	})
+	s.Require().Nil(err)
+
+	record, err := s.Store.GetIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().Nil(err)
+	s.Require().Equal("hash", record.RequestHash)
+	s.Require().Equal(200, record.StatusCode)
+	s.Require().Equal("application/json", record.ContentType)
+	s.Require().Equal([]byte(`{"id":"pool-1"}`), record.Response)
+
+	// Keys are scoped to the user.
+	_, err = s.Store.GetIdempotencyRecord(context.Background(), "user-2", "create-pool-1")
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+}
+
+func (s *IdempotencyTestSuite) TestExpiredIdempotencyRecords() {
+	_, err := s.Store.CreateIdempotencyRecord(context.Background(), params.IdempotencyRecord{
+		Key:         "create-pool-1",
+		UserID:      "user-1",
+		RequestHash: "hash",
+		StatusCode:  200,
+		ExpiresAt:   time.Now().UTC().Add(-time.Minute),
+	})
+	s.Require().Nil(err)
+
+	_, err = s.Store.GetIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+
+	err = s.Store.DeleteExpiredIdempotencyRecords(context.Background())
+	s.Require().Nil(err)
+
+	// The key can be used again once the expired record is removed.
+	_, err = s.Store.CreateIdempotencyRecord(context.Background(), params.IdempotencyRecord{
+		Key:         "create-pool-1",
+		UserID:      "user-1",
+		RequestHash: "other-hash",
+		StatusCode:  200,
+		ExpiresAt:   time.Now().UTC().Add(time.Hour),
+	})
+	s.Require().Nil(err)
+
+	record, err := s.Store.GetIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().Nil(err)
+	s.Require().Equal("other-hash", record.RequestHash)
+}
+
+func (s *IdempotencyTestSuite) TestPendingIdempotencyRecord() {
+	pending := params.IdempotencyRecord{
+		Key:         "create-pool-1",
+		UserID:      "user-1",
+		RequestHash: "hash",
+		Pending:     true,
+		ExpiresAt:   time.Now().UTC().Add(time.Minute),
+	}
+	_, err := s.Store.CreateIdempotencyRecord(context.Background(), pending)
+	s.Require().Nil(err)
+
+	// Only one request can reserve the key.
+	_, err = s.Store.CreateIdempotencyRecord(context.Background(), pending)
+	s.Require().ErrorIs(err, runnerErrors.ErrDuplicateEntity)
+
+	completed := pending
+	completed.Pending = false
+	completed.StatusCode = 200
+	completed.Response = []byte(`{"id":"pool-1"}`)
+	completed.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	_, err = s.Store.UpdateIdempotencyRecord(context.Background(), completed)
+	s.Require().Nil(err)
+
+	record, err := s.Store.GetIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().Nil(err)
+	s.Require().False(record.Pending)
+	s.Require().Equal(200, record.StatusCode)
+	s.Require().Equal([]byte(`{"id":"pool-1"}`), record.Response)
+
+	err = s.Store.DeleteIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().Nil(err)
+	_, err = s.Store.GetIdempotencyRecord(context.Background(), "user-1", "create-pool-1")
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+	_, err = s.Store.CreateIdempotencyRecord(context.Background(), pending)
+	s.Require().Nil(err)
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(IdempotencyTestSuite))
+}
@@ -15,9 +15,11 @@
 package sql
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -26,10 +28,15 @@ import (
 	"gorm.io/gorm/clause"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
 
+// instanceStatusMessagesInResponse is the number of recent status messages returned
+// along with an instance. All messages can be listed with ListInstanceStatusMessages.
+const instanceStatusMessagesInResponse = 10
+
 func (s *sqlDatabase) CreateInstance(_ context.Context, poolID string, param params.CreateInstanceParams) (instance params.Instance, err error) {
 	pool, err := s.getPoolByID(s.conn, poolID)
 	if err != nil {
@@ -71,15 +78,24 @@ func (s *sqlDatabase) CreateInstance(_ context.Context, poolID string, param par
 		JitConfiguration:  secret,
 		AditionalLabels:   labels,
 		AgentID:           param.AgentID,
+
+		InstanceTokenGeneration: pool.InstanceTokenGeneration,
 	}
 	q := s.conn.Create(&newInstance)
 	if q.Error != nil {
 		return params.Instance{}, errors.Wrap(q.Error, "creating instance")
 	}
+	s.recordRunnerCreated(newInstance, pool)
 
 	return s.sqlToParamsInstance(newInstance)
 }
 
+// recentStatusMessages limits the status messages loaded along with an instance to the
+// most recent ones.
+func recentStatusMessages(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at desc").Limit(instanceStatusMessagesInResponse)
+}
+
 func (s *sqlDatabase) getPoolInstanceByName(poolID string, instanceName string) (Instance, error) {
 	pool, err := s.getPoolByID(s.conn, poolID)
 	if err != nil {
@@ -89,6 +105,7 @@ func (s *sqlDatabase) getPoolInstanceByName(poolID string, instanceName string)
 	var instance Instance
 	q := s.conn.Model(&Instance{}).
 		Preload(clause.Associations).
+		Preload("StatusMessages", recentStatusMessages).
 		Where("name = ? and pool_id = ?", instanceName, pool.ID).
 		First(&instance)
 	if q.Error != nil {
@@ -113,6 +130,7 @@ func (s *sqlDatabase) getInstanceByName(_ context.Context, instanceName string,
 
 	q = q.Model(&Instance{}).
 		Preload(clause.Associations).
+		Preload("StatusMessages", recentStatusMessages).
 		Where("name = ?", instanceName).
 		First(&instance)
 	if q.Error != nil {
@@ -134,7 +152,7 @@ func (s *sqlDatabase) GetPoolInstanceByName(_ context.Context, poolID string, in
 }
 
 func (s *sqlDatabase) GetInstanceByName(ctx context.Context, instanceName string) (params.Instance, error) {
-	instance, err := s.getInstanceByName(ctx, instanceName, "StatusMessages")
+	instance, err := s.getInstanceByName(ctx, instanceName)
 	if err != nil {
 		return params.Instance{}, errors.Wrap(err, "fetching instance")
 	}
@@ -172,10 +190,15 @@ func (s *sqlDatabase) DeleteInstance(_ context.Context, poolID string, instanceN
 		}
 		return errors.Wrap(q.Error, "deleting instance")
 	}
+	s.recordRunnerRemoved(instance)
 	return nil
 }
 
-func (s *sqlDatabase) AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, statusMessage string) error {
+func (s *sqlDatabase) AddInstanceEvent(ctx context.Context, instanceName string, event params.EventType, eventLevel params.EventLevel, statusMessage string, maxEvents int) error {
+	if maxEvents <= 0 {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "max events must be greater than 0")
+	}
+
 	instance, err := s.getInstanceByName(ctx, instanceName)
 	if err != nil {
 		return errors.Wrap(err, "updating instance")
@@ -190,9 +213,89 @@ func (s *sqlDatabase) AddInstanceEvent(ctx context.Context, instanceName string,
 	if err := s.conn.Model(&instance).Association("StatusMessages").Append(&msg); err != nil {
 		return errors.Wrap(err, "adding status message")
 	}
+
+	if err := s.trimEvents(&InstanceStatusUpdate{}, "instance_id", instance.ID, maxEvents); err != nil {
+		// The message was recorded. Failing to remove old messages is not fatal.
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to remove old instance status messages", "runner_name", instanceName)
+	}
 	return nil
 }
 
+func (s *sqlDatabase) ListInstanceEvents(ctx context.Context, instanceName string, event params.EventType) ([]params.StatusMessage, error) {
+	instance, err := s.getInstanceByName(ctx, instanceName)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching instance")
+	}
+
+	var messages []InstanceStatusUpdate
+	q := s.conn.Model(&InstanceStatusUpdate{}).
+		Where("instance_id = ? and event_type = ?", instance.ID, event).
+		Order("created_at asc").
+		Find(&messages)
+	if q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching instance events")
+	}
+
+	ret := make([]params.StatusMessage, len(messages))
+	for idx, msg := range messages {
+		ret[idx] = params.StatusMessage{
+			CreatedAt:  msg.CreatedAt,
+			Message:    msg.Message,
+			EventType:  msg.EventType,
+			EventLevel: msg.EventLevel,
+		}
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) ListInstanceStatusMessages(ctx context.Context, instanceName string, param params.ListStatusMessagesParams) (params.StatusMessagesPage, error) {
+	if err := param.Validate(); err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "validating params")
+	}
+
+	instance, err := s.getInstanceByName(ctx, instanceName)
+	if err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "fetching instance")
+	}
+
+	q := s.conn.Model(&InstanceStatusUpdate{}).Where("instance_id = ?", instance.ID)
+	if param.EventLevel != "" {
+		q = q.Where("event_level = ?", param.EventLevel)
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "counting status messages")
+	}
+
+	var messages []InstanceStatusUpdate
+	if err := q.Order("created_at desc").
+		Offset(int((param.Page - 1) * param.PageSize)).
+		Limit(int(param.PageSize)).
+		Find(&messages).Error; err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "fetching status messages")
+	}
+
+	total := uint(count)
+	ret := params.StatusMessagesPage{
+		Messages:   make([]params.StatusMessage, len(messages)),
+		Page:       param.Page,
+		PageSize:   param.PageSize,
+		TotalCount: total,
+		TotalPages: (total + param.PageSize - 1) / param.PageSize,
+	}
+	for idx, msg := range messages {
+		ret.Messages[idx] = params.StatusMessage{
+			CreatedAt:  msg.CreatedAt,
+			Message:    msg.Message,
+			EventType:  msg.EventType,
+			EventLevel: msg.EventLevel,
+		}
+	}
+	return ret, nil
+}
+
 func (s *sqlDatabase) UpdateInstance(ctx context.Context, instanceName string, param params.UpdateInstanceParams) (params.Instance, error) {
 	instance, err := s.getInstanceByName(ctx, instanceName)
 	if err != nil {
@@ -215,6 +318,7 @@ func (s *sqlDatabase) UpdateInstance(ctx context.Context, instanceName string, p
 		instance.OSVersion = param.OSVersion
 	}
 
+	previousRunnerStatus := instance.RunnerStatus
 	if string(param.RunnerStatus) != "" {
 		instance.RunnerStatus = param.RunnerStatus
 	}
@@ -230,6 +334,18 @@ func (s *sqlDatabase) UpdateInstance(ctx context.Context, instanceName string, p
 		instance.TokenFetched = *param.TokenFetched
 	}
 
+	if param.JitRegistrationRemoved != nil {
+		instance.JitRegistrationRemoved = *param.JitRegistrationRemoved
+	}
+
+	if param.Image != "" {
+		instance.Image = param.Image
+	}
+
+	if param.Flavor != "" {
+		instance.Flavor = param.Flavor
+	}
+
 	if param.JitConfiguration != nil {
 		secret, err := s.marshalAndSeal(param.JitConfiguration)
 		if err != nil {
@@ -238,12 +354,28 @@ func (s *sqlDatabase) UpdateInstance(ctx context.Context, instanceName string, p
 		instance.JitConfiguration = secret
 	}
 
+	if param.DiskUsage != nil {
+		diskUsage, err := json.Marshal(param.DiskUsage)
+		if err != nil {
+			return params.Instance{}, errors.Wrap(err, "marshalling disk usage")
+		}
+		instance.DiskUsage = diskUsage
+	}
+
+	switch {
+	case len(param.ProviderFault) == 0:
+		instance.ProviderFaultAt = nil
+	case !bytes.Equal(instance.ProviderFault, param.ProviderFault):
+		faultAt := time.Now().UTC()
+		instance.ProviderFaultAt = &faultAt
+	}
 	instance.ProviderFault = param.ProviderFault
 
 	q := s.conn.Save(&instance)
 	if q.Error != nil {
 		return params.Instance{}, errors.Wrap(q.Error, "updating instance")
 	}
+	s.recordRunnerStatusChange(instance, previousRunnerStatus)
 
 	if len(param.Addresses) > 0 {
 		addrs := []Address{}
@@ -265,6 +397,73 @@ func (s *sqlDatabase) UpdateInstance(ctx context.Context, instanceName string, p
 	return inst, nil
 }
 
+func (s *sqlDatabase) MarkInstancesForDeletion(_ context.Context, param params.BulkDeleteInstancesParams) ([]string, error) {
+	statuses := []commonParams.InstanceStatus{commonParams.InstanceRunning, commonParams.InstanceError}
+	if param.Status != "" {
+		statuses = []commonParams.InstanceStatus{param.Status}
+	}
+	newStatus := commonParams.InstancePendingDelete
+	if param.ForceRemove {
+		newStatus = commonParams.InstancePendingForceDelete
+	}
+
+	var instances []Instance
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&Instance{}).
+			Preload(clause.Associations).
+			Where("status in ?", statuses)
+		if param.PoolID != "" {
+			poolID, err := uuid.Parse(param.PoolID)
+			if err != nil {
+				return errors.Wrap(runnerErrors.ErrBadRequest, "parsing pool id")
+			}
+			q = q.Where("pool_id = ?", poolID)
+		}
+		if param.OlderThan > 0 {
+			q = q.Where("created_at < ?", time.Now().UTC().Add(-param.OlderThan))
+		}
+		if !param.ForceRemove {
+			// Runners that are running a job are only removed when forced, the same way
+			// GitHub refuses to remove them.
+			q = q.Where("runner_status is null or runner_status <> ?", params.RunnerActive)
+		}
+		if err := q.Order("name").Find(&instances).Error; err != nil {
+			return errors.Wrap(err, "fetching instances")
+		}
+		if param.DryRun || len(instances) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(instances))
+		for idx, instance := range instances {
+			ids[idx] = instance.ID
+		}
+		if err := tx.Model(&Instance{}).Where("id in ?", ids).Update("status", newStatus).Error; err != nil {
+			return errors.Wrap(err, "updating instances")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "marking instances for deletion")
+	}
+
+	names := make([]string, len(instances))
+	for idx, instance := range instances {
+		names[idx] = instance.Name
+		if param.DryRun {
+			continue
+		}
+		instance.Status = newStatus
+		inst, err := s.sqlToParamsInstance(instance)
+		if err != nil {
+			slog.With(slog.Any("error", err)).Error("failed to convert instance", "runner_name", instance.Name)
+			continue
+		}
+		s.sendNotify(common.InstanceEntityType, common.UpdateOperation, inst)
+	}
+	return names, nil
+}
+
 func (s *sqlDatabase) ListPoolInstances(_ context.Context, poolID string) ([]params.Instance, error) {
 	u, err := uuid.Parse(poolID)
 	if err != nil {
@@ -306,6 +505,30 @@ func (s *sqlDatabase) ListAllInstances(_ context.Context) ([]params.Instance, er
 	return ret, nil
 }
 
+func (s *sqlDatabase) ListInstancesWithProviderFaults(_ context.Context, since time.Time) ([]params.Instance, error) {
+	var instances []Instance
+
+	q := s.conn.Model(&Instance{}).
+		Where("provider_fault IS NOT NULL and provider_fault_at >= ?", since).
+		Find(&instances)
+	if q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching instances")
+	}
+
+	ret := []params.Instance{}
+	for _, instance := range instances {
+		if len(instance.ProviderFault) == 0 {
+			continue
+		}
+		paramsInstance, err := s.sqlToParamsInstance(instance)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting instance")
+		}
+		ret = append(ret, paramsInstance)
+	}
+	return ret, nil
+}
+
 func (s *sqlDatabase) PoolInstanceCount(_ context.Context, poolID string) (int64, error) {
 	pool, err := s.getPoolByID(s.conn, poolID)
 	if err != nil {
@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
@@ -28,6 +29,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	garmTesting "github.com/cloudbase/garm/internal/testing"
@@ -201,6 +203,16 @@ func (s *InstancesTestSuite) TestCreateInstance() {
 	s.Require().Equal(storeInstance.CallbackURL, instance.CallbackURL)
 }
 
+func (s *InstancesTestSuite) TestCreateInstanceRecordsTokenGeneration() {
+	db := s.Store.(*sqlDatabase)
+	q := db.conn.Model(&Pool{}).Where("id = ?", s.Fixtures.Pool.ID).Update("instance_token_generation", 2)
+	s.Require().Nil(q.Error)
+
+	instance, err := s.Store.CreateInstance(s.adminCtx, s.Fixtures.Pool.ID, s.Fixtures.CreateInstanceParams)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(2), instance.InstanceTokenGeneration)
+}
+
 func (s *InstancesTestSuite) TestCreateInstanceInvalidPoolID() {
 	_, err := s.Store.CreateInstance(s.adminCtx, "dummy-pool-id", params.CreateInstanceParams{})
 
@@ -279,6 +291,72 @@ func (s *InstancesTestSuite) TestDeleteInstance() {
 	s.Require().Equal("fetching instance: fetching pool instance by name: not found", err.Error())
 }
 
+func (s *InstancesTestSuite) TestDeleteInstanceRecordsUsage() {
+	storeInstance := s.Fixtures.Instances[0]
+	since := time.Now().UTC().Add(-1 * time.Minute)
+
+	err := s.Store.DeleteInstance(s.adminCtx, s.Fixtures.Pool.ID, storeInstance.Name)
+	s.Require().Nil(err)
+
+	usage, err := s.Store.ListRunnerUsage(s.adminCtx, since, time.Now().UTC().Add(1*time.Minute))
+	s.Require().Nil(err)
+	s.Require().Len(usage, len(s.Fixtures.Instances))
+	for _, record := range usage {
+		s.Require().Equal(s.Fixtures.Pool.ID, record.PoolID)
+		s.Require().Equal(s.Fixtures.Pool.ProviderName, record.ProviderName)
+		s.Require().Equal(params.GithubEntityTypeOrganization, record.EntityType)
+		if record.Name == storeInstance.Name {
+			s.Require().NotNil(record.RemovedAt)
+		} else {
+			s.Require().Nil(record.RemovedAt)
+		}
+	}
+
+	// Runners removed before the start of the period are left out.
+	usage, err = s.Store.ListRunnerUsage(s.adminCtx, time.Now().UTC().Add(1*time.Second), time.Now().UTC().Add(1*time.Minute))
+	s.Require().Nil(err)
+	s.Require().Len(usage, len(s.Fixtures.Instances)-1)
+}
+
+func (s *InstancesTestSuite) TestUpdateInstanceRecordsJobsServed() {
+	storeInstance := s.Fixtures.Instances[0]
+	setStatus := func(status params.RunnerStatus) {
+		_, err := s.Store.UpdateInstance(s.adminCtx, storeInstance.Name, params.UpdateInstanceParams{RunnerStatus: status})
+		s.Require().Nil(err)
+	}
+
+	// The runner picks up two jobs. Updates that don't change the status are ignored.
+	setStatus(params.RunnerIdle)
+	setStatus(params.RunnerActive)
+	setStatus(params.RunnerActive)
+	setStatus(params.RunnerIdle)
+	setStatus(params.RunnerActive)
+
+	usage, err := s.Store.ListRunnerUsage(s.adminCtx, time.Time{}, time.Now().UTC().Add(1*time.Minute))
+	s.Require().Nil(err)
+	var record params.RunnerUsage
+	for _, u := range usage {
+		if u.Name == storeInstance.Name {
+			record = u
+		}
+	}
+	s.Require().Equal(uint(2), record.JobsServed)
+	s.Require().NotNil(record.FirstJobAt)
+	s.Require().NotNil(record.BusySince)
+
+	// Removing the runner ends the job it was running.
+	err = s.Store.DeleteInstance(s.adminCtx, s.Fixtures.Pool.ID, storeInstance.Name)
+	s.Require().Nil(err)
+	usage, err = s.Store.ListRunnerUsage(s.adminCtx, time.Time{}, time.Now().UTC().Add(1*time.Minute))
+	s.Require().Nil(err)
+	for _, u := range usage {
+		if u.Name == storeInstance.Name {
+			s.Require().Nil(u.BusySince)
+			s.Require().NotNil(u.RemovedAt)
+		}
+	}
+}
+
 func (s *InstancesTestSuite) TestDeleteInstanceInvalidPoolID() {
 	err := s.Store.DeleteInstance(s.adminCtx, "dummy-pool-id", "dummy-instance-name")
 
@@ -306,8 +384,8 @@ func (s *InstancesTestSuite) TestDeleteInstanceDBRecordNotFoundErr() {
 		WithArgs(instance.ID).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL")).
-		WithArgs(instance.ID).
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT ?")).
+		WithArgs(instance.ID, instanceStatusMessagesInResponse).
 		WillReturnRows(sqlmock.NewRows([]string{"message", "instance_id"}).AddRow("instance sample message", instance.ID))
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -343,8 +421,8 @@ func (s *InstancesTestSuite) TestDeleteInstanceDBDeleteErr() {
 		WithArgs(instance.ID).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL")).
-		WithArgs(instance.ID).
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT ?")).
+		WithArgs(instance.ID, instanceStatusMessagesInResponse).
 		WillReturnRows(sqlmock.NewRows([]string{"message", "instance_id"}).AddRow("instance sample message", instance.ID))
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -364,7 +442,7 @@ func (s *InstancesTestSuite) TestAddInstanceEvent() {
 	storeInstance := s.Fixtures.Instances[0]
 	statusMsg := "test-status-message"
 
-	err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, statusMsg)
+	err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, statusMsg, 10)
 
 	s.Require().Nil(err)
 	instance, err := s.Store.GetInstanceByName(s.adminCtx, storeInstance.Name)
@@ -375,6 +453,109 @@ func (s *InstancesTestSuite) TestAddInstanceEvent() {
 	s.Require().Equal(statusMsg, instance.StatusMessages[0].Message)
 }
 
+func (s *InstancesTestSuite) TestListInstanceEvents() {
+	storeInstance := s.Fixtures.Instances[0]
+
+	err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, "installing runner", 10)
+	s.Require().Nil(err)
+	err = s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.ProviderOperationEvent, params.EventInfo, "creating instance", 10)
+	s.Require().Nil(err)
+	err = s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.ProviderOperationEvent, params.EventError, "failed to create instance", 10)
+	s.Require().Nil(err)
+
+	events, err := s.Store.ListInstanceEvents(s.adminCtx, storeInstance.Name, params.ProviderOperationEvent)
+	s.Require().Nil(err)
+	s.Require().Len(events, 2)
+	s.Require().Equal("creating instance", events[0].Message)
+	s.Require().Equal("failed to create instance", events[1].Message)
+	s.Require().Equal(params.EventError, events[1].EventLevel)
+}
+
+func (s *InstancesTestSuite) TestAddInstanceEventKeepsLastEvents() {
+	storeInstance := s.Fixtures.Instances[0]
+
+	for i := 0; i < 5; i++ {
+		err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, fmt.Sprintf("message %d", i), 3)
+		s.Require().Nil(err)
+	}
+
+	instance, err := s.Store.GetInstanceByName(s.adminCtx, storeInstance.Name)
+	s.Require().Nil(err)
+	messages := []string{}
+	for _, msg := range instance.StatusMessages {
+		messages = append(messages, msg.Message)
+	}
+	s.Require().ElementsMatch([]string{"message 2", "message 3", "message 4"}, messages)
+}
+
+func (s *InstancesTestSuite) TestAddInstanceEventInvalidMaxEvents() {
+	err := s.Store.AddInstanceEvent(s.adminCtx, s.Fixtures.Instances[0].Name, params.StatusEvent, params.EventInfo, "message", 0)
+
+	s.Require().NotNil(err)
+	s.Require().Equal("max events must be greater than 0: invalid request", err.Error())
+}
+
+func (s *InstancesTestSuite) TestGetInstanceByNameReturnsRecentStatusMessages() {
+	storeInstance := s.Fixtures.Instances[0]
+
+	for i := 0; i < instanceStatusMessagesInResponse+5; i++ {
+		err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, fmt.Sprintf("message %d", i), 100)
+		s.Require().Nil(err)
+	}
+
+	instance, err := s.Store.GetInstanceByName(s.adminCtx, storeInstance.Name)
+	s.Require().Nil(err)
+	s.Require().Len(instance.StatusMessages, instanceStatusMessagesInResponse)
+	for idx := 1; idx < len(instance.StatusMessages); idx++ {
+		s.Require().False(instance.StatusMessages[idx].CreatedAt.Before(instance.StatusMessages[idx-1].CreatedAt))
+	}
+}
+
+func (s *InstancesTestSuite) TestListInstanceStatusMessages() {
+	storeInstance := s.Fixtures.Instances[0]
+
+	for i := 0; i < 5; i++ {
+		err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.StatusEvent, params.EventInfo, fmt.Sprintf("message %d", i), 100)
+		s.Require().Nil(err)
+	}
+	err := s.Store.AddInstanceEvent(s.adminCtx, storeInstance.Name, params.ProviderOperationEvent, params.EventError, "failed to create instance", 100)
+	s.Require().Nil(err)
+
+	page, err := s.Store.ListInstanceStatusMessages(s.adminCtx, storeInstance.Name, params.ListStatusMessagesParams{Page: 2, PageSize: 4})
+	s.Require().Nil(err)
+	s.Require().Equal(uint(6), page.TotalCount)
+	s.Require().Equal(uint(2), page.TotalPages)
+	s.Require().Len(page.Messages, 2)
+
+	page, err = s.Store.ListInstanceStatusMessages(s.adminCtx, storeInstance.Name, params.ListStatusMessagesParams{
+		EventLevel: params.EventError,
+		Page:       1,
+		PageSize:   4,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(uint(1), page.TotalCount)
+	s.Require().Len(page.Messages, 1)
+	s.Require().Equal("failed to create instance", page.Messages[0].Message)
+}
+
+func (s *InstancesTestSuite) TestListInstanceStatusMessagesInvalidParams() {
+	_, err := s.Store.ListInstanceStatusMessages(s.adminCtx, s.Fixtures.Instances[0].Name, params.ListStatusMessagesParams{
+		EventLevel: "debug",
+		Page:       1,
+		PageSize:   10,
+	})
+
+	s.Require().NotNil(err)
+	s.Require().Equal("validating params: invalid event level \"debug\"", err.Error())
+}
+
+func (s *InstancesTestSuite) TestListInstanceEventsInvalidInstance() {
+	_, err := s.Store.ListInstanceEvents(s.adminCtx, "dummy-instance-name", params.ProviderOperationEvent)
+
+	s.Require().NotNil(err)
+	s.Require().Equal("fetching instance: fetching instance by name: not found", err.Error())
+}
+
 func (s *InstancesTestSuite) TestAddInstanceEventDBUpdateErr() {
 	instance := s.Fixtures.Instances[0]
 	statusMsg := "test-status-message"
@@ -392,8 +573,8 @@ func (s *InstancesTestSuite) TestAddInstanceEventDBUpdateErr() {
 		WithArgs(instance.ID).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL")).
-		WithArgs(instance.ID).
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT ?")).
+		WithArgs(instance.ID, instanceStatusMessagesInResponse).
 		WillReturnRows(sqlmock.NewRows([]string{"message", "instance_id"}).AddRow("instance sample message", instance.ID))
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -405,7 +586,7 @@ func (s *InstancesTestSuite) TestAddInstanceEventDBUpdateErr() {
 		WillReturnError(fmt.Errorf("mocked add status message error"))
 	s.Fixtures.SQLMock.ExpectRollback()
 
-	err := s.StoreSQLMocked.AddInstanceEvent(s.adminCtx, instance.Name, params.StatusEvent, params.EventInfo, statusMsg)
+	err := s.StoreSQLMocked.AddInstanceEvent(s.adminCtx, instance.Name, params.StatusEvent, params.EventInfo, statusMsg, 10)
 
 	s.Require().NotNil(err)
 	s.Require().Equal("adding status message: mocked add status message error", err.Error())
@@ -425,6 +606,43 @@ func (s *InstancesTestSuite) TestUpdateInstance() {
 	s.Require().Equal(s.Fixtures.UpdateInstanceParams.CreateAttempt, instance.CreateAttempt)
 }
 
+func (s *InstancesTestSuite) TestUpdateInstanceImageAndFlavor() {
+	instance, err := s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[0].Name, params.UpdateInstanceParams{
+		Image:  "new-image",
+		Flavor: "new-flavor",
+	})
+	s.Require().Nil(err)
+	s.Require().Equal("new-image", instance.Image)
+	s.Require().Equal("new-flavor", instance.Flavor)
+
+	// Updates that don't set the image and flavor keep the recorded values.
+	instance, err = s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[0].Name, params.UpdateInstanceParams{
+		OSName: "ubuntu",
+	})
+	s.Require().Nil(err)
+	s.Require().Equal("new-image", instance.Image)
+	s.Require().Equal("new-flavor", instance.Flavor)
+}
+
+func (s *InstancesTestSuite) TestUpdateInstanceDiskUsage() {
+	diskUsage := &params.InstanceDiskUsage{
+		Path:       "/home/runner",
+		TotalBytes: 1000,
+		UsedBytes:  250,
+		UpdatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	instance, err := s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[0].Name, params.UpdateInstanceParams{
+		DiskUsage: diskUsage,
+	})
+
+	s.Require().Nil(err)
+	s.Require().NotNil(instance.DiskUsage)
+	s.Require().Equal(diskUsage.Path, instance.DiskUsage.Path)
+	s.Require().Equal(diskUsage.TotalBytes, instance.DiskUsage.TotalBytes)
+	s.Require().Equal(diskUsage.UsedBytes, instance.DiskUsage.UsedBytes)
+	s.Require().True(diskUsage.UpdatedAt.Equal(instance.DiskUsage.UpdatedAt))
+}
+
 func (s *InstancesTestSuite) TestUpdateInstanceDBUpdateInstanceErr() {
 	instance := s.Fixtures.Instances[0]
 
@@ -441,8 +659,8 @@ func (s *InstancesTestSuite) TestUpdateInstanceDBUpdateInstanceErr() {
 		WithArgs(instance.ID).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL")).
-		WithArgs(instance.ID).
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT ?")).
+		WithArgs(instance.ID, instanceStatusMessagesInResponse).
 		WillReturnRows(sqlmock.NewRows([]string{"message", "instance_id"}).AddRow("instance sample message", instance.ID))
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -473,8 +691,8 @@ func (s *InstancesTestSuite) TestUpdateInstanceDBUpdateAddressErr() {
 		WithArgs(instance.ID).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL")).
-		WithArgs(instance.ID).
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `instance_status_updates` WHERE `instance_status_updates`.`instance_id` = ? AND `instance_status_updates`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT ?")).
+		WithArgs(instance.ID, instanceStatusMessagesInResponse).
 		WillReturnRows(sqlmock.NewRows([]string{"message", "instance_id"}).AddRow("instance sample message", instance.ID))
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -503,6 +721,46 @@ func (s *InstancesTestSuite) TestUpdateInstanceDBUpdateAddressErr() {
 	s.assertSQLMockExpectations()
 }
 
+func (s *InstancesTestSuite) TestMarkInstancesForDeletion() {
+	pool := s.Fixtures.Pool
+	errStatus := commonParams.InstanceError
+	_, err := s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[0].Name, params.UpdateInstanceParams{Status: errStatus})
+	s.Require().Nil(err)
+	_, err = s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[1].Name, params.UpdateInstanceParams{RunnerStatus: params.RunnerActive})
+	s.Require().Nil(err)
+
+	names, err := s.Store.MarkInstancesForDeletion(s.adminCtx, params.BulkDeleteInstancesParams{PoolID: pool.ID, DryRun: true})
+	s.Require().Nil(err)
+	s.Require().Equal([]string{s.Fixtures.Instances[0].Name, s.Fixtures.Instances[2].Name}, names)
+
+	names, err = s.Store.MarkInstancesForDeletion(s.adminCtx, params.BulkDeleteInstancesParams{PoolID: pool.ID, Status: errStatus})
+	s.Require().Nil(err)
+	s.Require().Equal([]string{s.Fixtures.Instances[0].Name}, names)
+	instance, err := s.Store.GetInstanceByName(s.adminCtx, s.Fixtures.Instances[0].Name)
+	s.Require().Nil(err)
+	s.Require().Equal(commonParams.InstancePendingDelete, instance.Status)
+	instance, err = s.Store.GetInstanceByName(s.adminCtx, s.Fixtures.Instances[2].Name)
+	s.Require().Nil(err)
+	s.Require().Equal(commonParams.InstanceRunning, instance.Status)
+
+	names, err = s.Store.MarkInstancesForDeletion(s.adminCtx, params.BulkDeleteInstancesParams{OlderThan: time.Hour})
+	s.Require().Nil(err)
+	s.Require().Len(names, 0)
+
+	// Runners running a job are only removed when forced.
+	names, err = s.Store.MarkInstancesForDeletion(s.adminCtx, params.BulkDeleteInstancesParams{PoolID: pool.ID, ForceRemove: true})
+	s.Require().Nil(err)
+	s.Require().Equal([]string{s.Fixtures.Instances[1].Name, s.Fixtures.Instances[2].Name}, names)
+	instance, err = s.Store.GetInstanceByName(s.adminCtx, s.Fixtures.Instances[1].Name)
+	s.Require().Nil(err)
+	s.Require().Equal(commonParams.InstancePendingForceDelete, instance.Status)
+}
+
+func (s *InstancesTestSuite) TestMarkInstancesForDeletionInvalidPoolID() {
+	_, err := s.Store.MarkInstancesForDeletion(s.adminCtx, params.BulkDeleteInstancesParams{PoolID: "dummy-pool-id"})
+	s.Require().ErrorIs(err, runnerErrors.ErrBadRequest)
+}
+
 func (s *InstancesTestSuite) TestListPoolInstances() {
 	instances, err := s.Store.ListPoolInstances(s.adminCtx, s.Fixtures.Pool.ID)
 
@@ -535,6 +793,188 @@ func (s *InstancesTestSuite) TestListAllInstancesDBFetchErr() {
 	s.Require().Equal("fetching instances: fetch instances mock error", err.Error())
 }
 
+func (s *InstancesTestSuite) TestListInstancesPageByPool() {
+	filter := params.InstanceFilter{PoolID: s.Fixtures.Pool.ID}
+	opts := params.ListOptions{Page: 1, PageSize: 2, Sort: "-name"}
+	page, err := s.Store.ListInstancesPage(s.adminCtx, nil, filter, opts)
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 2)
+	s.Require().Equal("test-instance-3", page.Items[0].Name)
+	s.Require().Equal("test-instance-2", page.Items[1].Name)
+	s.Require().Equal(uint(3), page.TotalCount)
+	s.Require().Equal(uint(2), page.TotalPages)
+
+	opts.Page = 2
+	page, err = s.Store.ListInstancesPage(s.adminCtx, nil, filter, opts)
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal("test-instance-1", page.Items[0].Name)
+}
+
+func (s *InstancesTestSuite) TestListInstancesPageByEntity() {
+	entity, err := s.Fixtures.Org.GetEntity()
+	s.Require().Nil(err)
+
+	page, err := s.Store.ListInstancesPage(s.adminCtx, &entity, params.InstanceFilter{}, params.ListOptions{Page: 1, PageSize: 10})
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 3)
+	s.Require().Equal("test-instance-1", page.Items[0].Name)
+	s.Require().Equal(uint(3), page.TotalCount)
+	s.Require().Equal(uint(1), page.TotalPages)
+}
+
+func (s *InstancesTestSuite) TestListInstancesPageOutOfRange() {
+	page, err := s.Store.ListInstancesPage(s.adminCtx, nil, params.InstanceFilter{}, params.ListOptions{Page: 5, PageSize: 10})
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 0)
+	s.Require().Equal(uint(3), page.TotalCount)
+}
+
+func (s *InstancesTestSuite) TestListInstancesPageInvalidOptions() {
+	for _, opts := range []params.ListOptions{
+		{Page: 0, PageSize: 10},
+		{Page: 1, PageSize: 0},
+		{Page: 1, PageSize: 10, Sort: "provider_id"},
+	} {
+		_, err := s.Store.ListInstancesPage(s.adminCtx, nil, params.InstanceFilter{}, opts)
+
+		var badRequest *runnerErrors.BadRequestError
+		s.Require().ErrorAs(err, &badRequest)
+	}
+}
+
+func (s *InstancesTestSuite) TestListInstancesByStatus() {
+	_, err := s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[0].Name, params.UpdateInstanceParams{
+		Status: commonParams.InstancePendingDelete,
+	})
+	s.Require().Nil(err)
+	_, err = s.Store.UpdateInstance(s.adminCtx, s.Fixtures.Instances[1].Name, params.UpdateInstanceParams{
+		RunnerStatus: params.RunnerActive,
+	})
+	s.Require().Nil(err)
+
+	instances, err := s.Store.ListInstances(s.adminCtx, nil, params.InstanceFilter{
+		Statuses: []commonParams.InstanceStatus{commonParams.InstancePendingDelete, commonParams.InstancePendingForceDelete},
+	})
+	s.Require().Nil(err)
+	s.Require().Len(instances, 1)
+	s.Require().Equal(s.Fixtures.Instances[0].Name, instances[0].Name)
+
+	instances, err = s.Store.ListInstances(s.adminCtx, nil, params.InstanceFilter{
+		Statuses:       []commonParams.InstanceStatus{commonParams.InstanceRunning},
+		RunnerStatuses: []params.RunnerStatus{params.RunnerActive},
+	})
+	s.Require().Nil(err)
+	s.Require().Len(instances, 1)
+	s.Require().Equal(s.Fixtures.Instances[1].Name, instances[0].Name)
+}
+
+func (s *InstancesTestSuite) TestListInstancesByProviderAndPool() {
+	entity, err := s.Fixtures.Org.GetEntity()
+	s.Require().Nil(err)
+
+	instances, err := s.Store.ListInstances(s.adminCtx, &entity, params.InstanceFilter{
+		ProviderName: s.Fixtures.Pool.ProviderName,
+		PoolID:       s.Fixtures.Pool.ID,
+	})
+	s.Require().Nil(err)
+	s.equalInstancesByName(s.Fixtures.Instances, instances)
+
+	instances, err = s.Store.ListInstances(s.adminCtx, &entity, params.InstanceFilter{ProviderName: "other-provider"})
+	s.Require().Nil(err)
+	s.Require().Len(instances, 0)
+}
+
+func (s *InstancesTestSuite) TestListInstancesByCreationTime() {
+	now := time.Now()
+	past := now.Add(-1 * time.Hour)
+
+	instances, err := s.Store.ListInstances(s.adminCtx, nil, params.InstanceFilter{CreatedAfter: &past, CreatedBefore: &now})
+	s.Require().Nil(err)
+	s.equalInstancesByName(s.Fixtures.Instances, instances)
+
+	instances, err = s.Store.ListInstances(s.adminCtx, nil, params.InstanceFilter{CreatedBefore: &past})
+	s.Require().Nil(err)
+	s.Require().Len(instances, 0)
+}
+
+func (s *InstancesTestSuite) TestListInstancesInvalidFilter() {
+	now := time.Now()
+	past := now.Add(-1 * time.Hour)
+	for _, filter := range []params.InstanceFilter{
+		{Statuses: []commonParams.InstanceStatus{"bogus"}},
+		{RunnerStatuses: []params.RunnerStatus{"bogus"}},
+		{PoolID: "dummy-pool-id"},
+		{CreatedAfter: &now, CreatedBefore: &past},
+	} {
+		_, err := s.Store.ListInstances(s.adminCtx, nil, filter)
+
+		var badRequest *runnerErrors.BadRequestError
+		s.Require().ErrorAs(err, &badRequest)
+	}
+}
+
+func (s *InstancesTestSuite) TestListInstancesWithProviderFaults() {
+	faultyInstance := s.Fixtures.Instances[0]
+	_, err := s.Store.UpdateInstance(s.adminCtx, faultyInstance.Name, params.UpdateInstanceParams{
+		Status:        commonParams.InstanceError,
+		ProviderFault: []byte("quota exceeded"),
+	})
+	s.Require().Nil(err)
+
+	instances, err := s.Store.ListInstancesWithProviderFaults(s.adminCtx, time.Now().Add(-1*time.Hour))
+
+	s.Require().Nil(err)
+	s.Require().Len(instances, 1)
+	s.Require().Equal(faultyInstance.Name, instances[0].Name)
+	s.Require().Equal([]byte("quota exceeded"), instances[0].ProviderFault)
+
+	instances, err = s.Store.ListInstancesWithProviderFaults(s.adminCtx, time.Now().Add(1*time.Hour))
+
+	s.Require().Nil(err)
+	s.Require().Len(instances, 0)
+}
+
+func (s *InstancesTestSuite) TestListInstancesWithProviderFaultsUsesFaultTime() {
+	faultyInstance := s.Fixtures.Instances[0]
+	updateParams := params.UpdateInstanceParams{
+		Status:        commonParams.InstanceError,
+		ProviderFault: []byte("quota exceeded"),
+	}
+	instance, err := s.Store.UpdateInstance(s.adminCtx, faultyInstance.Name, updateParams)
+	s.Require().Nil(err)
+	s.Require().NotNil(instance.ProviderFaultAt)
+
+	// Move the fault out of the window. Updating the instance again with the same
+	// fault does not make the fault recent.
+	db := s.Store.(*sqlDatabase)
+	faultAt := time.Now().Add(-2 * time.Hour)
+	s.Require().Nil(db.conn.Model(&Instance{}).Where("name = ?", faultyInstance.Name).Update("provider_fault_at", faultAt).Error)
+	instance, err = s.Store.UpdateInstance(s.adminCtx, faultyInstance.Name, updateParams)
+	s.Require().Nil(err)
+	s.Require().WithinDuration(faultAt, *instance.ProviderFaultAt, time.Second)
+
+	instances, err := s.Store.ListInstancesWithProviderFaults(s.adminCtx, time.Now().Add(-1*time.Hour))
+	s.Require().Nil(err)
+	s.Require().Len(instances, 0)
+
+	// A new fault is recorded with the current time.
+	updateParams.ProviderFault = []byte("image not found")
+	_, err = s.Store.UpdateInstance(s.adminCtx, faultyInstance.Name, updateParams)
+	s.Require().Nil(err)
+	instances, err = s.Store.ListInstancesWithProviderFaults(s.adminCtx, time.Now().Add(-1*time.Hour))
+	s.Require().Nil(err)
+	s.Require().Len(instances, 1)
+
+	instance, err = s.Store.UpdateInstance(s.adminCtx, faultyInstance.Name, params.UpdateInstanceParams{Status: commonParams.InstanceRunning})
+	s.Require().Nil(err)
+	s.Require().Nil(instance.ProviderFaultAt)
+}
+
 func (s *InstancesTestSuite) TestPoolInstanceCount() {
 	instancesCount, err := s.Store.PoolInstanceCount(s.adminCtx, s.Fixtures.Pool.ID)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -13,6 +14,7 @@ import (
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
 )
 
 var _ common.JobsStore = &sqlDatabase{}
@@ -46,11 +48,23 @@ func sqlWorkflowJobToParamsJob(job WorkflowJob) (params.Job, error) {
 		CreatedAt:       job.CreatedAt,
 		UpdatedAt:       job.UpdatedAt,
 		LockedBy:        job.LockedBy,
+		Metadata:        json.RawMessage(job.Metadata),
+	}
+
+	if len(job.Decision) > 0 {
+		var decision params.JobDecision
+		if err := json.Unmarshal(job.Decision, &decision); err != nil {
+			return params.Job{}, errors.Wrap(err, "unmarshaling decision")
+		}
+		jobParam.Decision = &decision
 	}
 
 	if job.InstanceID != nil {
 		jobParam.RunnerName = job.Instance.Name
 	}
+	if job.PoolID != nil {
+		jobParam.PoolID = job.PoolID.String()
+	}
 	return jobParam, nil
 }
 
@@ -88,6 +102,7 @@ func (s *sqlDatabase) paramsJobToWorkflowJob(ctx context.Context, job params.Job
 			slog.DebugContext(ctx, "failed to get instance by name", "instance_name", job.RunnerName)
 		} else {
 			workflofJob.InstanceID = &instance.ID
+			workflofJob.PoolID = &instance.PoolID
 		}
 	}
 
@@ -244,6 +259,7 @@ func (s *sqlDatabase) CreateOrUpdateJob(ctx context.Context, job params.Job) (pa
 			instance, err := s.getInstanceByName(ctx, job.RunnerName)
 			if err == nil {
 				workflowJob.InstanceID = &instance.ID
+				workflowJob.PoolID = &instance.PoolID
 			} else {
 				// This usually is very normal as not all jobs run on our runners.
 				slog.DebugContext(ctx, "failed to get instance by name", "instance_name", job.RunnerName)
@@ -364,6 +380,39 @@ func (s *sqlDatabase) ListAllJobs(_ context.Context) ([]params.Job, error) {
 	return ret, nil
 }
 
+// ListJobsServedByEnterprise lists the jobs received by an enterprise, that were picked
+// up by runners in the pools of that enterprise, since the given time.
+func (s *sqlDatabase) ListJobsServedByEnterprise(_ context.Context, enterpriseID string, since time.Time) ([]params.Job, error) {
+	u, err := uuid.Parse(enterpriseID)
+	if err != nil {
+		return nil, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	enterprisePools := s.conn.Model(&Pool{}).Select("id").Where("enterprise_id = ?", u)
+	var jobs []WorkflowJob
+	query := s.conn.Model(&WorkflowJob{}).
+		Preload("Instance").
+		Where("enterprise_id = ? and started_at >= ?", u, since).
+		Where("pool_id IN (?)", enterprisePools)
+
+	if err := query.Find(&jobs); err.Error != nil {
+		if errors.Is(err.Error, gorm.ErrRecordNotFound) {
+			return []params.Job{}, nil
+		}
+		return nil, err.Error
+	}
+
+	ret := make([]params.Job, len(jobs))
+	for idx, job := range jobs {
+		jobParam, err := sqlWorkflowJobToParamsJob(job)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting job")
+		}
+		ret[idx] = jobParam
+	}
+	return ret, nil
+}
+
 // GetJobByID gets a job by id.
 func (s *sqlDatabase) GetJobByID(_ context.Context, jobID int64) (params.Job, error) {
 	var job WorkflowJob
@@ -379,9 +428,105 @@ func (s *sqlDatabase) GetJobByID(_ context.Context, jobID int64) (params.Job, er
 	return sqlWorkflowJobToParamsJob(job)
 }
 
-// DeleteCompletedJobs deletes all completed jobs.
-func (s *sqlDatabase) DeleteCompletedJobs(_ context.Context) error {
-	query := s.conn.Model(&WorkflowJob{}).Where("status = ?", params.JobStatusCompleted)
+// SetJobDecision records the last decision about creating a runner for a job. The
+// update time of the job is not changed, as it is used to back off from queued jobs.
+func (s *sqlDatabase) SetJobDecision(_ context.Context, jobID int64, decision params.JobDecision) error {
+	asJSON, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "marshaling decision")
+	}
+
+	q := s.conn.Model(&WorkflowJob{}).Where("id = ?", jobID).UpdateColumn("decision", asJSON)
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "updating job decision")
+	}
+	if q.RowsAffected == 0 {
+		return runnerErrors.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateJobMetadata merges the given keys into the metadata of a job. Keys set to
+// null are removed. As with the decision, the update time of the job is not changed.
+func (s *sqlDatabase) UpdateJobMetadata(_ context.Context, jobID int64, param params.UpdateJobMetadataParams) (params.Job, error) {
+	var workflowJob WorkflowJob
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Instance").Where("id = ?", jobID).First(&workflowJob)
+		if q.Error != nil {
+			if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+				return runnerErrors.ErrNotFound
+			}
+			return errors.Wrap(q.Error, "fetching job")
+		}
+
+		metadata := map[string]json.RawMessage{}
+		if len(workflowJob.Metadata) > 0 {
+			if err := json.Unmarshal(workflowJob.Metadata, &metadata); err != nil {
+				return errors.Wrap(err, "unmarshaling metadata")
+			}
+		}
+		for key, value := range param.Metadata {
+			if string(value) == "null" {
+				delete(metadata, key)
+				continue
+			}
+			metadata[key] = value
+		}
+
+		var asJSON []byte
+		if len(metadata) > 0 {
+			var err error
+			asJSON, err = json.Marshal(metadata)
+			if err != nil {
+				return errors.Wrap(err, "marshaling metadata")
+			}
+			if len(asJSON) > appdefaults.MaxJobMetadataSize {
+				return runnerErrors.NewBadRequestError("job metadata exceeds %d bytes", appdefaults.MaxJobMetadataSize)
+			}
+		}
+
+		workflowJob.Metadata = asJSON
+		if err := tx.Model(&workflowJob).UpdateColumn("metadata", workflowJob.Metadata).Error; err != nil {
+			return errors.Wrap(err, "updating job metadata")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.Job{}, err
+	}
+
+	asParams, err := sqlWorkflowJobToParamsJob(workflowJob)
+	if err != nil {
+		return params.Job{}, errors.Wrap(err, "converting job")
+	}
+	s.sendNotify(common.JobEntityType, common.UpdateOperation, asParams)
+	return asParams, nil
+}
+
+// ListCompletedJobs lists the jobs that were completed before the given time. These
+// are the jobs DeleteCompletedJobs removes for the same time.
+func (s *sqlDatabase) ListCompletedJobs(_ context.Context, completedBefore time.Time) ([]params.Job, error) {
+	var jobs []WorkflowJob
+	query := s.conn.Model(&WorkflowJob{}).Where("status = ? and updated_at < ?", params.JobStatusCompleted, completedBefore)
+
+	if err := query.Preload("Instance").Order("updated_at").Find(&jobs); err.Error != nil {
+		return nil, errors.Wrap(err.Error, "fetching jobs")
+	}
+
+	ret := make([]params.Job, len(jobs))
+	for idx, job := range jobs {
+		jobParam, err := sqlWorkflowJobToParamsJob(job)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting job")
+		}
+		ret[idx] = jobParam
+	}
+	return ret, nil
+}
+
+// DeleteCompletedJobs deletes all jobs that were completed before the given time.
+func (s *sqlDatabase) DeleteCompletedJobs(_ context.Context, completedBefore time.Time) error {
+	query := s.conn.Model(&WorkflowJob{}).Where("status = ? and updated_at < ?", params.JobStatusCompleted, completedBefore)
 
 	if err := query.Unscoped().Delete(&WorkflowJob{}); err.Error != nil {
 		if errors.Is(err.Error, gorm.ErrRecordNotFound) {
@@ -72,6 +72,28 @@ type Pool struct {
 	// any kind of data needed by providers.
 	ExtraSpecs        datatypes.JSON
 	GitHubRunnerGroup string
+	CleanupPolicy     datatypes.JSON
+	NetworkSettings   datatypes.JSON
+	Schedule          datatypes.JSON
+	// InstanceTokenGeneration is incremented on every update of the pool and is
+	// used to derive the key that signs instance tokens.
+	InstanceTokenGeneration uint
+	IdleDetectionWindow     uint
+	ScaleDownGracePeriod    uint
+	ScaleDownFactor         float64
+	// CapacityWarningThreshold is a percentage of MaxRunners.
+	CapacityWarningThreshold uint
+	AutoscaleMaxBurst        uint
+	AutoscaleCooldown        uint
+	MaxCreatesPerMinute      uint
+	CreateJitter             uint
+	RollingUpdateBatchSize   uint
+	RollingUpdatePause       uint
+	Draining                 bool
+	URLSet                   string `gorm:"type:varchar(64);index"`
+	InstanceMetadata         datatypes.JSON
+	WarmImage                datatypes.JSON
+	WarmImageStatus          datatypes.JSON
 
 	RepoID     *uuid.UUID `gorm:"index"`
 	Repository Repository `gorm:"foreignKey:RepoID;"`
@@ -100,9 +122,34 @@ type Repository struct {
 	Pools            []Pool                  `gorm:"foreignKey:RepoID"`
 	Jobs             []WorkflowJob           `gorm:"foreignKey:RepoID;constraint:OnDelete:SET NULL"`
 	PoolBalancerType params.PoolBalancerType `gorm:"type:varchar(64)"`
+	// MaxConcurrentJobs limits the number of jobs that can have runners at the same time.
+	MaxConcurrentJobs uint
+	// RoutingRules holds the JSON encoded []params.RoutingRule of the entity.
+	RoutingRules datatypes.JSON
 
 	EndpointName *string        `gorm:"index:idx_owner_nocase,unique,collate:nocase"`
 	Endpoint     GithubEndpoint `gorm:"foreignKey:EndpointName;constraint:OnDelete:SET NULL"`
+
+	Events                []RepositoryEvent `gorm:"foreignKey:RepoID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;"`
+	PendingWebhookInstall []byte
+	// EntityWebhookPath is set if the webhook of the entity was installed using the URL
+	// unique to the entity.
+	EntityWebhookPath bool
+	// PreviousWebhookSecret is accepted along with WebhookSecret until
+	// PreviousWebhookSecretExpiresAt, while the webhook secret is being rotated.
+	PreviousWebhookSecret          []byte
+	PreviousWebhookSecretExpiresAt *time.Time
+}
+
+type RepositoryEvent struct {
+	Base
+
+	EventType  params.EventType `gorm:"index:idx_repository_events_event_type"`
+	EventLevel params.EventLevel
+	Message    string `gorm:"type:text"`
+
+	RepoID uuid.UUID  `gorm:"index:idx_repository_events_repo_id"`
+	Repo   Repository `gorm:"foreignKey:RepoID"`
 }
 
 type Organization struct {
@@ -118,9 +165,34 @@ type Organization struct {
 	Pools            []Pool                  `gorm:"foreignKey:OrgID"`
 	Jobs             []WorkflowJob           `gorm:"foreignKey:OrgID;constraint:OnDelete:SET NULL"`
 	PoolBalancerType params.PoolBalancerType `gorm:"type:varchar(64)"`
+	// MaxConcurrentJobs limits the number of jobs that can have runners at the same time.
+	MaxConcurrentJobs uint
+	// RoutingRules holds the JSON encoded []params.RoutingRule of the entity.
+	RoutingRules datatypes.JSON
 
 	EndpointName *string        `gorm:"index:idx_org_name_nocase,collate:nocase"`
 	Endpoint     GithubEndpoint `gorm:"foreignKey:EndpointName;constraint:OnDelete:SET NULL"`
+
+	Events                []OrganizationEvent `gorm:"foreignKey:OrgID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;"`
+	PendingWebhookInstall []byte
+	// EntityWebhookPath is set if the webhook of the entity was installed using the URL
+	// unique to the entity.
+	EntityWebhookPath bool
+	// PreviousWebhookSecret is accepted along with WebhookSecret until
+	// PreviousWebhookSecretExpiresAt, while the webhook secret is being rotated.
+	PreviousWebhookSecret          []byte
+	PreviousWebhookSecretExpiresAt *time.Time
+}
+
+type OrganizationEvent struct {
+	Base
+
+	EventType  params.EventType `gorm:"index:idx_organization_events_event_type"`
+	EventLevel params.EventLevel
+	Message    string `gorm:"type:text"`
+
+	OrgID uuid.UUID    `gorm:"index:idx_organization_events_org_id"`
+	Org   Organization `gorm:"foreignKey:OrgID"`
 }
 
 type Enterprise struct {
@@ -136,9 +208,30 @@ type Enterprise struct {
 	Pools            []Pool                  `gorm:"foreignKey:EnterpriseID"`
 	Jobs             []WorkflowJob           `gorm:"foreignKey:EnterpriseID;constraint:OnDelete:SET NULL"`
 	PoolBalancerType params.PoolBalancerType `gorm:"type:varchar(64)"`
+	// MaxConcurrentJobs limits the number of jobs that can have runners at the same time.
+	MaxConcurrentJobs uint
+	// RoutingRules holds the JSON encoded []params.RoutingRule of the entity.
+	RoutingRules datatypes.JSON
 
 	EndpointName *string        `gorm:"index:idx_ent_name_nocase,collate:nocase"`
 	Endpoint     GithubEndpoint `gorm:"foreignKey:EndpointName;constraint:OnDelete:SET NULL"`
+
+	Events []EnterpriseEvent `gorm:"foreignKey:EnterpriseID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;"`
+	// PreviousWebhookSecret is accepted along with WebhookSecret until
+	// PreviousWebhookSecretExpiresAt, while the webhook secret is being rotated.
+	PreviousWebhookSecret          []byte
+	PreviousWebhookSecretExpiresAt *time.Time
+}
+
+type EnterpriseEvent struct {
+	Base
+
+	EventType  params.EventType `gorm:"index:idx_enterprise_events_event_type"`
+	EventLevel params.EventLevel
+	Message    string `gorm:"type:text"`
+
+	EnterpriseID uuid.UUID  `gorm:"index:idx_enterprise_events_enterprise_id"`
+	Enterprise   Enterprise `gorm:"foreignKey:EnterpriseID"`
 }
 
 type Address struct {
@@ -177,12 +270,24 @@ type Instance struct {
 	RunnerStatus      params.RunnerStatus
 	CallbackURL       string
 	MetadataURL       string
-	ProviderFault     []byte `gorm:"type:longblob"`
+	ProviderFault     []byte     `gorm:"type:longblob"`
+	ProviderFaultAt   *time.Time `gorm:"index"`
 	CreateAttempt     int
 	TokenFetched      bool
 	JitConfiguration  []byte `gorm:"type:longblob"`
 	GitHubRunnerGroup string
 	AditionalLabels   datatypes.JSON
+	DiskUsage         datatypes.JSON
+
+	// JitRegistrationRemoved is set when the unused runner registration of an
+	// instance that failed to be created was removed from GitHub.
+	JitRegistrationRemoved bool
+	// InstanceTokenGeneration is the generation of the pool when the instance was
+	// created. Instance tokens signed with older keys are rejected.
+	InstanceTokenGeneration uint
+
+	Image  string
+	Flavor string
 
 	PoolID uuid.UUID
 	Pool   Pool `gorm:"foreignKey:PoolID"`
@@ -202,6 +307,161 @@ type User struct {
 	Generation uint
 	IsAdmin    bool
 	Enabled    bool
+
+	FailedLoginAttempts uint
+	LockedUntil         *time.Time
+
+	Role   params.UserRole `gorm:"type:varchar(64)"`
+	Grants []RoleGrant     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// APIToken is a long lived token used by automation. Only the hash of the token is
+// stored.
+type APIToken struct {
+	Base
+
+	UserID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_api_tokens_user_name"`
+	Name       string    `gorm:"type:varchar(64);uniqueIndex:idx_api_tokens_user_name"`
+	TokenHash  string    `gorm:"type:varchar(64);uniqueIndex:idx_api_tokens_hash"`
+	Scopes     datatypes.JSON
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// RoleGrant gives a user a role on a single repository, organization or enterprise.
+type RoleGrant struct {
+	Base
+
+	UserID     uuid.UUID               `gorm:"type:uuid;uniqueIndex:idx_role_grants_user_entity"`
+	Role       params.UserRole         `gorm:"type:varchar(64)"`
+	EntityType params.GithubEntityType `gorm:"type:varchar(64);uniqueIndex:idx_role_grants_user_entity"`
+	EntityID   uuid.UUID               `gorm:"type:uuid;uniqueIndex:idx_role_grants_user_entity;index:idx_role_grants_entity_id"`
+}
+
+type AuditRecord struct {
+	Base
+
+	Action         params.AuditAction `gorm:"index:idx_audit_records_action"`
+	ImpersonatorID string             `gorm:"index:idx_audit_records_impersonator_id"`
+	Impersonator   string             `gorm:"type:varchar(64)"`
+	UserID         string             `gorm:"index:idx_audit_records_user_id"`
+	Username       string             `gorm:"type:varchar(64)"`
+	Reason         string             `gorm:"type:text"`
+	Method         string             `gorm:"type:varchar(16)"`
+	Path           string             `gorm:"type:text"`
+	StatusCode     int
+
+	ResourceType params.AuditResourceType `gorm:"index:idx_audit_records_resource"`
+	ResourceID   string                   `gorm:"index:idx_audit_records_resource"`
+	Changes      datatypes.JSON
+}
+
+type WebhookDelivery struct {
+	Base
+
+	DeliveryID     string `gorm:"index:idx_webhook_deliveries_delivery_id"`
+	EntityID       string `gorm:"index:idx_webhook_deliveries_entity_id"`
+	TargetEntityID string
+	Headers        datatypes.JSON
+	PayloadHash    string `gorm:"type:varchar(64)"`
+	Payload        []byte
+	Status         params.WebhookDeliveryStatus `gorm:"index:idx_webhook_deliveries_status"`
+	Error          string                       `gorm:"type:text"`
+	Attempts       uint
+}
+
+type IdempotencyRecord struct {
+	Base
+
+	UserID         string `gorm:"type:varchar(64);uniqueIndex:idx_idempotency_user_key"`
+	IdempotencyKey string `gorm:"type:varchar(255);uniqueIndex:idx_idempotency_user_key"`
+	RequestHash    string `gorm:"type:varchar(64)"`
+	StatusCode     int
+	ContentType    string    `gorm:"type:varchar(255)"`
+	Response       []byte    `gorm:"type:longblob"`
+	ExpiresAt      time.Time `gorm:"index"`
+	// ResponseOmitted is set if the response held secrets and was not recorded.
+	ResponseOmitted bool
+	// Pending is set while the request is being handled.
+	Pending bool
+}
+
+// ProviderPause records that a provider was paused. Pools that use a paused provider
+// don't create new instances until the provider is resumed.
+type ProviderPause struct {
+	ProviderName string `gorm:"type:varchar(64);primary_key;"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Reason string `gorm:"type:text"`
+}
+
+// ProviderEnvironment holds the environment variables GARM passes to an external
+// provider. The variables are stored as a sealed JSON object.
+type ProviderEnvironment struct {
+	ProviderName string `gorm:"type:varchar(64);primary_key;"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Variables []byte `gorm:"type:longblob"`
+}
+
+// DenyRule bans an image and flavor combination, optionally on a single provider.
+type DenyRule struct {
+	Base
+
+	ProviderName  string `gorm:"type:varchar(64);index:idx_deny_rules_provider_name"`
+	ImagePattern  string `gorm:"type:text"`
+	FlavorPattern string `gorm:"type:text"`
+	Reason        string `gorm:"type:text"`
+}
+
+// CapacityReservation raises the min idle runners of a pool for a window of time.
+type CapacityReservation struct {
+	Base
+
+	PoolID           uuid.UUID `gorm:"type:uuid;index:idx_capacity_reservations_pool_id"`
+	Pool             Pool      `gorm:"foreignKey:PoolID;constraint:OnDelete:CASCADE"`
+	ExtraIdleRunners uint
+	StartTime        time.Time
+	EndTime          time.Time `gorm:"index"`
+	Reason           string    `gorm:"type:text"`
+}
+
+// RunnerUsage records the lifetime of a runner, for usage accounting. Records are
+// kept after the runner and its pool are removed, so they hold copies of the pool,
+// provider and entity of the runner.
+type RunnerUsage struct {
+	ID           uint      `gorm:"primarykey"`
+	InstanceID   uuid.UUID `gorm:"type:uuid;index:idx_runner_usage_instance_id"`
+	Name         string
+	PoolID       string `gorm:"index:idx_runner_usage_pool_id"`
+	ProviderName string
+	EntityID     string
+	EntityType   params.GithubEntityType
+	CreatedAt    time.Time  `gorm:"index:idx_runner_usage_created_at"`
+	RemovedAt    *time.Time `gorm:"index:idx_runner_usage_removed_at"`
+	// JobsServed is the number of times the runner became active.
+	JobsServed  uint
+	FirstJobAt  *time.Time
+	BusySince   *time.Time
+	BusySeconds float64
+}
+
+// ControllerNode is a GARM controller that takes part in a cluster.
+type ControllerNode struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;"`
+	CreatedAt     time.Time
+	Name          string    `gorm:"type:varchar(255)"`
+	LastHeartbeat time.Time `gorm:"index"`
+}
+
+// EntityLease records which controller node manages an entity, and until when.
+type EntityLease struct {
+	EntityID  uuid.UUID `gorm:"type:uuid;primary_key;"`
+	NodeID    uuid.UUID `gorm:"type:uuid;index"`
+	ExpiresAt time.Time
+	UpdatedAt time.Time
 }
 
 type ControllerInfo struct {
@@ -218,6 +478,22 @@ type ControllerInfo struct {
 	// pick up the job. GARM would allow this amount of time for runners to react
 	// before spinning up a new one and potentially having to scale down later.
 	MinimumJobAgeBackoff uint
+	// PreviousControllerID is set while the controller ID is being migrated.
+	PreviousControllerID *uuid.UUID
+	// URLSets holds the JSON encoded []params.ControllerURLSet of the controller.
+	URLSets datatypes.JSON
+}
+
+type ControllerIDMigration struct {
+	Base
+
+	PreviousControllerID uuid.UUID
+	NewControllerID      uuid.UUID
+	Phase                params.MigrationPhase `gorm:"index"`
+	Webhooks             datatypes.JSON
+	PendingRunners       uint
+	SwitchedAt           *time.Time
+	CompletedAt          *time.Time
 }
 
 type WorkflowJob struct {
@@ -244,6 +520,10 @@ type WorkflowJob struct {
 
 	InstanceID *uuid.UUID `gorm:"index:idx_instance_job"`
 	Instance   Instance   `gorm:"foreignKey:InstanceID"`
+	// PoolID is the ID of the pool of the runner that picked up the job. Unlike the
+	// instance, which is removed once the job completes, the pool ID is kept, so we
+	// know which pool served the job.
+	PoolID *uuid.UUID `gorm:"index"`
 
 	RunnerGroupID   int64
 	RunnerGroupName string
@@ -273,6 +553,10 @@ type WorkflowJob struct {
 	Enterprise   Enterprise `gorm:"foreignKey:EnterpriseID"`
 
 	LockedBy uuid.UUID
+	// Decision is the last decision about creating a runner for the job, as JSON.
+	Decision datatypes.JSON
+	// Metadata is the JSON object set on the job through the API.
+	Metadata datatypes.JSON
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -290,6 +574,9 @@ type GithubEndpoint struct {
 	UploadBaseURL string `gorm:"type:text collate nocase"`
 	BaseURL       string `gorm:"type:text collate nocase"`
 	CACertBundle  []byte `gorm:"type:longblob"`
+
+	ToolsMetadataURL         string `gorm:"type:text"`
+	UseInternalToolsMetadata bool
 }
 
 type GithubCredentials struct {
@@ -302,6 +589,8 @@ type GithubCredentials struct {
 	Description string                `gorm:"type:text"`
 	AuthType    params.GithubAuthType `gorm:"index"`
 	Payload     []byte                `gorm:"type:longblob"`
+	// TokenSummary holds the JSON encoded params.GithubTokenSummary of fine-grained PATs.
+	TokenSummary datatypes.JSON
 
 	Endpoint     GithubEndpoint `gorm:"foreignKey:EndpointName"`
 	EndpointName *string        `gorm:"index"`
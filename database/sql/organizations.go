@@ -24,7 +24,6 @@ import (
 	"gorm.io/gorm"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
-	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
@@ -33,7 +32,7 @@ func (s *sqlDatabase) CreateOrganization(ctx context.Context, name, credentialsN
 	if webhookSecret == "" {
 		return params.Organization{}, errors.New("creating org: missing secret")
 	}
-	secret, err := util.Seal([]byte(webhookSecret), []byte(s.cfg.Passphrase))
+	secret, err := s.seal([]byte(webhookSecret))
 	if err != nil {
 		return params.Organization{}, errors.Wrap(err, "encoding secret")
 	}
@@ -139,9 +138,18 @@ func (s *sqlDatabase) DeleteOrganization(ctx context.Context, orgID string) (err
 		}
 	}(org)
 
-	q := s.conn.Unscoped().Delete(&org)
-	if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
-		return errors.Wrap(q.Error, "deleting org")
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Unscoped().Delete(&org)
+		if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return errors.Wrap(q.Error, "deleting org")
+		}
+		if err := s.deleteEntityRoleGrants(tx, org.ID); err != nil {
+			return errors.Wrap(err, "deleting org")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -182,17 +190,26 @@ func (s *sqlDatabase) UpdateOrganization(ctx context.Context, orgID string, para
 		}
 
 		if param.WebhookSecret != "" {
-			secret, err := util.Seal([]byte(param.WebhookSecret), []byte(s.cfg.Passphrase))
-			if err != nil {
+			if err := s.updateWebhookSecret(param, &org.WebhookSecret, &org.PreviousWebhookSecret, &org.PreviousWebhookSecretExpiresAt); err != nil {
 				return fmt.Errorf("saving org: failed to encrypt string: %w", err)
 			}
-			org.WebhookSecret = secret
 		}
 
 		if param.PoolBalancerType != "" {
 			org.PoolBalancerType = param.PoolBalancerType
 		}
 
+		if param.MaxConcurrentJobs != nil {
+			org.MaxConcurrentJobs = *param.MaxConcurrentJobs
+		}
+
+		if param.RoutingRules != nil {
+			org.RoutingRules, err = marshalRoutingRules(*param.RoutingRules)
+			if err != nil {
+				return errors.Wrap(err, "marshaling routing rules")
+			}
+		}
+
 		q := tx.Save(&org)
 		if q.Error != nil {
 			return errors.Wrap(q.Error, "saving org")
@@ -216,7 +233,7 @@ func (s *sqlDatabase) UpdateOrganization(ctx context.Context, orgID string, para
 }
 
 func (s *sqlDatabase) GetOrganizationByID(ctx context.Context, orgID string) (params.Organization, error) {
-	org, err := s.getOrgByID(ctx, s.conn, orgID, "Pools", "Credentials", "Endpoint")
+	org, err := s.getOrgByID(ctx, s.conn, orgID, "Pools", "Credentials", "Credentials.Endpoint", "Endpoint", "Events")
 	if err != nil {
 		return params.Organization{}, errors.Wrap(err, "fetching org")
 	}
@@ -255,7 +272,7 @@ func (s *sqlDatabase) getOrgByID(_ context.Context, db *gorm.DB, id string, prel
 func (s *sqlDatabase) getOrg(_ context.Context, name, endpointName string) (Organization, error) {
 	var org Organization
 
-	q := s.conn.Where("name = ? COLLATE NOCASE and endpoint_name = ? COLLATE NOCASE", name, endpointName).
+	q := s.conn.Where(s.equalsNoCase("name")+" and "+s.equalsNoCase("endpoint_name"), name, endpointName).
 		Preload("Credentials").
 		Preload("Credentials.Endpoint").
 		Preload("Endpoint").
@@ -0,0 +1,204 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+)
+
+// orderBy returns the ORDER BY clause for the requested sort field. The field must
+// have been validated against the fields allowed for the resource, which are named
+// after their columns. The key column is used to break ties, so pages are stable.
+func orderBy(opts params.ListOptions, defaultSort, keyColumn string) string {
+	if opts.Sort == "" {
+		opts.Sort = defaultSort
+	}
+	field, desc := opts.SortField()
+	direction := "asc"
+	if desc {
+		direction = "desc"
+	}
+	return fmt.Sprintf("%s %s, %s %s", field, direction, keyColumn, direction)
+}
+
+// paginate counts the rows matched by q, and returns the requested page of rows. The
+// find function adds the clauses that only apply when fetching the rows, like
+// preloading associations.
+func paginate[M any, T any](q *gorm.DB, opts params.ListOptions, order string, find func(*gorm.DB) *gorm.DB, convert func(M) (T, error)) (params.PaginatedResult[T], error) {
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return params.PaginatedResult[T]{}, errors.Wrap(err, "counting results")
+	}
+
+	var rows []M
+	if err := find(q).Order(order).
+		Offset(int((opts.Page - 1) * opts.PageSize)).
+		Limit(int(opts.PageSize)).
+		Find(&rows).Error; err != nil {
+		return params.PaginatedResult[T]{}, errors.Wrap(err, "fetching results")
+	}
+
+	total := uint(count)
+	ret := params.PaginatedResult[T]{
+		Items:      make([]T, len(rows)),
+		Page:       opts.Page,
+		PageSize:   opts.PageSize,
+		TotalCount: total,
+		TotalPages: (total + opts.PageSize - 1) / opts.PageSize,
+	}
+	for idx, row := range rows {
+		item, err := convert(row)
+		if err != nil {
+			return params.PaginatedResult[T]{}, errors.Wrap(err, "converting result")
+		}
+		ret.Items[idx] = item
+	}
+	return ret, nil
+}
+
+// entityPoolsQuery returns a query for the pools of an entity.
+func (s *sqlDatabase) entityPoolsQuery(entity params.GithubEntity) (*gorm.DB, error) {
+	if _, err := uuid.Parse(entity.ID); err != nil {
+		return nil, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+	if err := s.hasGithubEntity(s.conn, entity.EntityType, entity.ID); err != nil {
+		return nil, errors.Wrap(err, "checking entity existence")
+	}
+
+	var fieldName string
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		fieldName = entityTypeRepoName
+	case params.GithubEntityTypeOrganization:
+		fieldName = entityTypeOrgName
+	case params.GithubEntityTypeEnterprise:
+		fieldName = entityTypeEnterpriseName
+	default:
+		return nil, fmt.Errorf("invalid entityType: %v", entity.EntityType)
+	}
+	return s.conn.Model(&Pool{}).Where(fmt.Sprintf("%s = ?", fieldName), entity.ID), nil
+}
+
+func findPools(q *gorm.DB) *gorm.DB {
+	return q.Preload("Tags").
+		Preload("Organization").
+		Preload("Repository").
+		Preload("Enterprise").
+		Omit("extra_specs")
+}
+
+func findInstances(q *gorm.DB) *gorm.DB {
+	return q.Preload("Job")
+}
+
+func findJobs(q *gorm.DB) *gorm.DB {
+	return q.Preload("Instance")
+}
+
+// ListAllPoolsPage returns one page of all pools.
+func (s *sqlDatabase) ListAllPoolsPage(_ context.Context, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
+	if err := opts.Validate(params.PoolSortFields); err != nil {
+		return params.PaginatedResult[params.Pool]{}, errors.Wrap(err, "validating list options")
+	}
+	q := s.conn.Model(&Pool{})
+	return paginate(q, opts, orderBy(opts, "created_at", "id"), findPools, s.sqlToCommonPool)
+}
+
+// ListEntityPoolsPage returns one page of the pools of an entity.
+func (s *sqlDatabase) ListEntityPoolsPage(_ context.Context, entity params.GithubEntity, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
+	if err := opts.Validate(params.PoolSortFields); err != nil {
+		return params.PaginatedResult[params.Pool]{}, errors.Wrap(err, "validating list options")
+	}
+	q, err := s.entityPoolsQuery(entity)
+	if err != nil {
+		return params.PaginatedResult[params.Pool]{}, errors.Wrap(err, "fetching pools")
+	}
+	return paginate(q, opts, orderBy(opts, "created_at", "id"), findPools, s.sqlToCommonPool)
+}
+
+// instancesQuery returns a query for the instances that match the filter. If entity is
+// set, only instances in the pools of that entity are matched.
+func (s *sqlDatabase) instancesQuery(entity *params.GithubEntity, filter params.InstanceFilter) (*gorm.DB, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validating filter")
+	}
+
+	q := s.conn.Model(&Instance{})
+	if entity != nil {
+		pools, err := s.entityPoolsQuery(*entity)
+		if err != nil {
+			return nil, errors.Wrap(err, "fetching entity")
+		}
+		q = q.Where("pool_id IN (?)", pools.Select("id"))
+	}
+	if len(filter.Statuses) > 0 {
+		q = q.Where("status IN ?", filter.Statuses)
+	}
+	if len(filter.RunnerStatuses) > 0 {
+		q = q.Where("runner_status IN ?", filter.RunnerStatuses)
+	}
+	if filter.PoolID != "" {
+		q = q.Where("pool_id = ?", uuid.MustParse(filter.PoolID))
+	}
+	if filter.ProviderName != "" {
+		q = q.Where("pool_id IN (?)", s.conn.Model(&Pool{}).Select("id").Where("provider_name = ?", filter.ProviderName))
+	}
+	if filter.CreatedBefore != nil {
+		q = q.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.CreatedAfter != nil {
+		q = q.Where("created_at > ?", *filter.CreatedAfter)
+	}
+	return q, nil
+}
+
+// ListInstances returns the instances that match the filter. If entity is set, only
+// instances in the pools of that entity are returned.
+func (s *sqlDatabase) ListInstances(_ context.Context, entity *params.GithubEntity, filter params.InstanceFilter) ([]params.Instance, error) {
+	q, err := s.instancesQuery(entity, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	if err := findInstances(q).Find(&instances).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching instances")
+	}
+	ret := make([]params.Instance, len(instances))
+	for idx, instance := range instances {
+		ret[idx], err = s.sqlToParamsInstance(instance)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting instance")
+		}
+	}
+	return ret, nil
+}
+
+// ListInstancesPage returns one page of the instances that match the filter. If entity
+// is set, only instances in the pools of that entity are returned.
+func (s *sqlDatabase) ListInstancesPage(_ context.Context, entity *params.GithubEntity, filter params.InstanceFilter, opts params.ListOptions) (params.PaginatedResult[params.Instance], error) {
+	if err := opts.Validate(params.InstanceSortFields); err != nil {
+		return params.PaginatedResult[params.Instance]{}, errors.Wrap(err, "validating list options")
+	}
+	q, err := s.instancesQuery(entity, filter)
+	if err != nil {
+		return params.PaginatedResult[params.Instance]{}, err
+	}
+	return paginate(q, opts, orderBy(opts, "name", "id"), findInstances, s.sqlToParamsInstance)
+}
+
+// ListAllJobsPage returns one page of all jobs. Jobs are sorted newest first, unless
+// another order is requested.
+func (s *sqlDatabase) ListAllJobsPage(_ context.Context, opts params.ListOptions) (params.PaginatedResult[params.Job], error) {
+	if err := opts.Validate(params.JobSortFields); err != nil {
+		return params.PaginatedResult[params.Job]{}, errors.Wrap(err, "validating list options")
+	}
+	q := s.conn.Model(&WorkflowJob{})
+	return paginate(q, opts, orderBy(opts, "-created_at", "id"), findJobs, sqlWorkflowJobToParamsJob)
+}
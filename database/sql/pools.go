@@ -16,7 +16,10 @@ package sql
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -24,6 +27,7 @@ import (
 	"gorm.io/gorm"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/config"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
@@ -34,6 +38,9 @@ const (
 	entityTypeRepoName       = "repo_id"
 )
 
+// errDryRun rolls back the transaction of a dry run pool create or update.
+var errDryRun = errors.New("dry run")
+
 func (s *sqlDatabase) ListAllPools(_ context.Context) ([]params.Pool, error) {
 	var pools []Pool
 
@@ -67,6 +74,31 @@ func (s *sqlDatabase) GetPoolByID(_ context.Context, poolID string) (params.Pool
 	return s.sqlToCommonPool(pool)
 }
 
+// UpdatePoolWarmImageStatus records the result of a warm image build. Unlike other pool
+// updates, this does not invalidate the instance tokens of the pool.
+func (s *sqlDatabase) UpdatePoolWarmImageStatus(_ context.Context, poolID string, status params.WarmImageStatus) (updatedPool params.Pool, err error) {
+	defer func() {
+		if err == nil {
+			s.sendNotify(common.PoolEntityType, common.UpdateOperation, updatedPool)
+		}
+	}()
+
+	pool, err := s.getPoolByID(s.conn, poolID, "Tags", "Instances", "Enterprise", "Organization", "Repository")
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "fetching pool by ID")
+	}
+
+	asJs, err := json.Marshal(status)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "marshaling warm image status")
+	}
+	if q := s.conn.Model(&pool).Update("warm_image_status", datatypes.JSON(asJs)); q.Error != nil {
+		return params.Pool{}, errors.Wrap(q.Error, "saving warm image status")
+	}
+
+	return s.sqlToCommonPool(pool)
+}
+
 func (s *sqlDatabase) DeletePoolByID(_ context.Context, poolID string) (err error) {
 	pool, err := s.getPoolByID(s.conn, poolID)
 	if err != nil {
@@ -207,6 +239,14 @@ func (s *sqlDatabase) findPoolByTags(id string, poolType params.GithubEntityType
 
 	var pools []Pool
 	where := fmt.Sprintf("tags.name COLLATE NOCASE in ? and %s = ? and enabled = true", fieldName)
+	if s.cfg.DbBackend == config.PostgresBackend {
+		where = fmt.Sprintf("lower(tags.name) in ? and %s = ? and enabled = true", fieldName)
+		lowered := make([]string, len(tags))
+		for idx, tag := range tags {
+			lowered[idx] = strings.ToLower(tag)
+		}
+		tags = lowered
+	}
 	q := s.conn.Joins("JOIN pool_tags on pool_tags.pool_id=pools.id").
 		Joins("JOIN tags on tags.id=pool_tags.tag_id").
 		Group("pools.id").
@@ -254,13 +294,70 @@ func (s *sqlDatabase) FindPoolsMatchingAllTags(_ context.Context, entityType par
 	return pools, nil
 }
 
+func (s *sqlDatabase) ReplacePoolsTags(_ context.Context, poolTags map[string][]string) (updatedPools []params.Pool, err error) {
+	defer func() {
+		if err == nil {
+			for _, pool := range updatedPools {
+				s.sendNotify(common.PoolEntityType, common.UpdateOperation, pool)
+			}
+		}
+	}()
+
+	poolIDs := make([]string, 0, len(poolTags))
+	for poolID := range poolTags {
+		poolIDs = append(poolIDs, poolID)
+	}
+	sort.Strings(poolIDs)
+
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		updatedPools = make([]params.Pool, 0, len(poolIDs))
+		for _, poolID := range poolIDs {
+			if len(poolTags[poolID]) == 0 {
+				return runnerErrors.NewBadRequestError("no tags specified for pool %s", poolID)
+			}
+
+			pool, err := s.getPoolByID(tx, poolID)
+			if err != nil {
+				return errors.Wrap(err, "fetching pool")
+			}
+
+			tags := make([]Tag, 0, len(poolTags[poolID]))
+			for _, val := range poolTags[poolID] {
+				t, err := s.getOrCreateTag(tx, val)
+				if err != nil {
+					return errors.Wrap(err, "fetching tag")
+				}
+				tags = append(tags, t)
+			}
+			if err := tx.Model(&pool).Association("Tags").Replace(&tags); err != nil {
+				return errors.Wrap(err, "replacing tags")
+			}
+
+			pool, err = s.getPoolByID(tx, poolID, "Tags", "Enterprise", "Organization", "Repository")
+			if err != nil {
+				return errors.Wrap(err, "fetching pool")
+			}
+			updated, err := s.sqlToCommonPool(pool)
+			if err != nil {
+				return errors.Wrap(err, "converting pool")
+			}
+			updatedPools = append(updatedPools, updated)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "replacing pool tags")
+	}
+	return updatedPools, nil
+}
+
 func (s *sqlDatabase) CreateEntityPool(_ context.Context, entity params.GithubEntity, param params.CreatePoolParams) (pool params.Pool, err error) {
 	if len(param.Tags) == 0 {
 		return params.Pool{}, runnerErrors.NewBadRequestError("no tags specified")
 	}
 
 	defer func() {
-		if err == nil {
+		if err == nil && !param.DryRun {
 			s.sendNotify(common.PoolEntityType, common.CreateOperation, pool)
 		}
 	}()
@@ -278,11 +375,48 @@ func (s *sqlDatabase) CreateEntityPool(_ context.Context, entity params.GithubEn
 		RunnerBootstrapTimeout: param.RunnerBootstrapTimeout,
 		GitHubRunnerGroup:      param.GitHubRunnerGroup,
 		Priority:               param.Priority,
+		IdleDetectionWindow:    param.IdleDetectionWindow,
+		ScaleDownGracePeriod:   param.ScaleDownGracePeriod,
+		ScaleDownFactor:        param.ScaleDownFactor,
+
+		CapacityWarningThreshold: param.CapacityWarningThreshold,
+		AutoscaleMaxBurst:        param.AutoscaleMaxBurst,
+		AutoscaleCooldown:        param.AutoscaleCooldown,
+		MaxCreatesPerMinute:      param.MaxCreatesPerMinute,
+		CreateJitter:             param.CreateJitter,
+		RollingUpdateBatchSize:   param.RollingUpdateBatchSize,
+		RollingUpdatePause:       param.RollingUpdatePause,
+		URLSet:                   param.URLSet,
 	}
 	if len(param.ExtraSpecs) > 0 {
 		newPool.ExtraSpecs = datatypes.JSON(param.ExtraSpecs)
 	}
 
+	newPool.CleanupPolicy, err = cleanupPolicyToJSON(param.CleanupPolicy)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "creating pool")
+	}
+
+	newPool.NetworkSettings, err = networkSettingsToJSON(param.NetworkSettings)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "creating pool")
+	}
+
+	newPool.Schedule, err = poolScheduleToJSON(param.Schedule)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "creating pool")
+	}
+
+	newPool.InstanceMetadata, err = instanceMetadataToJSON(param.InstanceMetadata)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "creating pool")
+	}
+
+	newPool.WarmImage, err = warmImageToJSON(param.WarmImage)
+	if err != nil {
+		return params.Pool{}, errors.Wrap(err, "creating pool")
+	}
+
 	entityID, err := uuid.Parse(entity.ID)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
@@ -301,6 +435,10 @@ func (s *sqlDatabase) CreateEntityPool(_ context.Context, entity params.GithubEn
 			return errors.Wrap(err, "checking entity existence")
 		}
 
+		if err := s.validateURLSet(tx, newPool.URLSet); err != nil {
+			return err
+		}
+
 		tags := []Tag{}
 		for _, val := range param.Tags {
 			t, err := s.getOrCreateTag(tx, val)
@@ -320,8 +458,24 @@ func (s *sqlDatabase) CreateEntityPool(_ context.Context, entity params.GithubEn
 				return errors.Wrap(err, "associating tags")
 			}
 		}
+
+		if param.DryRun {
+			dbPool, err := s.getPoolByID(tx, newPool.ID.String(), "Tags", "Instances", "Enterprise", "Organization", "Repository")
+			if err != nil {
+				return errors.Wrap(err, "fetching pool")
+			}
+			if pool, err = s.sqlToCommonPool(dbPool); err != nil {
+				return errors.Wrap(err, "fetching pool")
+			}
+			// The pool is not saved, so it has no ID.
+			pool.ID = ""
+			return errDryRun
+		}
 		return nil
 	})
+	if errors.Is(err, errDryRun) {
+		return pool, nil
+	}
 	if err != nil {
 		return params.Pool{}, err
 	}
@@ -381,7 +535,7 @@ func (s *sqlDatabase) DeleteEntityPool(_ context.Context, entity params.GithubEn
 
 func (s *sqlDatabase) UpdateEntityPool(_ context.Context, entity params.GithubEntity, poolID string, param params.UpdatePoolParams) (updatedPool params.Pool, err error) {
 	defer func() {
-		if err == nil {
+		if err == nil && !param.DryRun {
 			s.sendNotify(common.PoolEntityType, common.UpdateOperation, updatedPool)
 		}
 	}()
@@ -395,8 +549,14 @@ func (s *sqlDatabase) UpdateEntityPool(_ context.Context, entity params.GithubEn
 		if err != nil {
 			return errors.Wrap(err, "updating pool")
 		}
+		if param.DryRun {
+			return errDryRun
+		}
 		return nil
 	})
+	if errors.Is(err, errDryRun) {
+		return updatedPool, nil
+	}
 	if err != nil {
 		return params.Pool{}, err
 	}
@@ -27,6 +27,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	garmTesting "github.com/cloudbase/garm/internal/testing"
 	"github.com/cloudbase/garm/params"
@@ -135,9 +136,44 @@ func (s *PoolsTestSuite) TestListAllPools() {
 	garmTesting.EqualDBEntityID(s.T(), s.Fixtures.Pools, pools)
 }
 
+func (s *PoolsTestSuite) TestListAllPoolsPage() {
+	page, err := s.Store.ListAllPoolsPage(s.adminCtx, params.ListOptions{Page: 2, PageSize: 2, Sort: "-image"})
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal("test-image-1", page.Items[0].Image)
+	s.Require().Len(page.Items[0].Tags, 1)
+	s.Require().Equal(uint(3), page.TotalCount)
+	s.Require().Equal(uint(2), page.TotalPages)
+}
+
+func (s *PoolsTestSuite) TestListEntityPoolsPage() {
+	entity, err := s.Fixtures.Org.GetEntity()
+	s.Require().Nil(err)
+
+	page, err := s.Store.ListEntityPoolsPage(s.adminCtx, entity, params.ListOptions{Page: 1, PageSize: 2, Sort: "image"})
+
+	s.Require().Nil(err)
+	s.Require().Len(page.Items, 2)
+	s.Require().Equal("test-image-1", page.Items[0].Image)
+	s.Require().Equal("test-image-2", page.Items[1].Image)
+	s.Require().Equal(uint(3), page.TotalCount)
+}
+
+func (s *PoolsTestSuite) TestListEntityPoolsPageEntityNotFound() {
+	entity := params.GithubEntity{
+		ID:         "c5b8ad3b-8d2b-4a36-9b4d-1f2a46c8a1b2",
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+
+	_, err := s.Store.ListEntityPoolsPage(s.adminCtx, entity, params.ListOptions{Page: 1, PageSize: 2})
+
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+}
+
 func (s *PoolsTestSuite) TestListAllPoolsDBFetchErr() {
 	s.Fixtures.SQLMock.
-		ExpectQuery(regexp.QuoteMeta("SELECT `pools`.`id`,`pools`.`created_at`,`pools`.`updated_at`,`pools`.`deleted_at`,`pools`.`provider_name`,`pools`.`runner_prefix`,`pools`.`max_runners`,`pools`.`min_idle_runners`,`pools`.`runner_bootstrap_timeout`,`pools`.`image`,`pools`.`flavor`,`pools`.`os_type`,`pools`.`os_arch`,`pools`.`enabled`,`pools`.`git_hub_runner_group`,`pools`.`repo_id`,`pools`.`org_id`,`pools`.`enterprise_id`,`pools`.`priority` FROM `pools` WHERE `pools`.`deleted_at` IS NULL")).
+		ExpectQuery(regexp.QuoteMeta("SELECT `pools`.`id`,`pools`.`created_at`,`pools`.`updated_at`,`pools`.`deleted_at`,`pools`.`provider_name`,`pools`.`runner_prefix`,`pools`.`max_runners`,`pools`.`min_idle_runners`,`pools`.`runner_bootstrap_timeout`,`pools`.`image`,`pools`.`flavor`,`pools`.`os_type`,`pools`.`os_arch`,`pools`.`enabled`,`pools`.`git_hub_runner_group`,`pools`.`cleanup_policy`,`pools`.`network_settings`,`pools`.`schedule`,`pools`.`instance_token_generation`,`pools`.`idle_detection_window`,`pools`.`scale_down_grace_period`,`pools`.`scale_down_factor`,`pools`.`capacity_warning_threshold`,`pools`.`autoscale_max_burst`,`pools`.`autoscale_cooldown`,`pools`.`max_creates_per_minute`,`pools`.`create_jitter`,`pools`.`rolling_update_batch_size`,`pools`.`rolling_update_pause`,`pools`.`draining`,`pools`.`url_set`,`pools`.`instance_metadata`,`pools`.`warm_image`,`pools`.`warm_image_status`,`pools`.`repo_id`,`pools`.`org_id`,`pools`.`enterprise_id`,`pools`.`priority` FROM `pools` WHERE `pools`.`deleted_at` IS NULL")).
 		WillReturnError(fmt.Errorf("mocked fetching all pools error"))
 
 	_, err := s.StoreSQLMocked.ListAllPools(s.adminCtx)
@@ -147,6 +183,85 @@ func (s *PoolsTestSuite) TestListAllPoolsDBFetchErr() {
 	s.Require().Equal("fetching all pools: mocked fetching all pools error", err.Error())
 }
 
+func (s *PoolsTestSuite) TestPoolURLSet() {
+	_, err := s.Store.InitController()
+	s.Require().Nil(err)
+	entity, err := s.Fixtures.Org.GetEntity()
+	s.Require().Nil(err)
+	urlSet := "eu"
+
+	_, err = s.Store.UpdateEntityPool(s.adminCtx, entity, s.Fixtures.Pools[0].ID, params.UpdatePoolParams{URLSet: &urlSet})
+	var badRequest *runnerErrors.BadRequestError
+	s.Require().ErrorAs(err, &badRequest)
+
+	sets := []params.ControllerURLSet{
+		{
+			Name:        urlSet,
+			MetadataURL: "https://garm-eu.example.com/api/v1/metadata",
+			CallbackURL: "https://garm-eu.example.com/api/v1/callbacks",
+		},
+	}
+	info, err := s.Store.UpdateController(params.UpdateControllerParams{URLSets: &sets})
+	s.Require().Nil(err)
+	s.Require().Equal(sets, info.URLSets)
+
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, s.Fixtures.Pools[0].ID, params.UpdatePoolParams{URLSet: &urlSet})
+	s.Require().Nil(err)
+	s.Require().Equal(urlSet, pool.URLSet)
+
+	metadataURL, callbackURL := info.RunnerURLs(pool.URLSet)
+	s.Require().Equal(sets[0].MetadataURL, metadataURL)
+	s.Require().Equal(sets[0].CallbackURL, callbackURL)
+
+	// A URL set selected by a pool can't be removed.
+	_, err = s.Store.UpdateController(params.UpdateControllerParams{URLSets: &[]params.ControllerURLSet{}})
+	s.Require().ErrorAs(err, &badRequest)
+
+	noURLSet := ""
+	_, err = s.Store.UpdateEntityPool(s.adminCtx, entity, s.Fixtures.Pools[0].ID, params.UpdatePoolParams{URLSet: &noURLSet})
+	s.Require().Nil(err)
+	info, err = s.Store.UpdateController(params.UpdateControllerParams{URLSets: &[]params.ControllerURLSet{}})
+	s.Require().Nil(err)
+	s.Require().Empty(info.URLSets)
+}
+
+func (s *PoolsTestSuite) TestPoolDryRun() {
+	entity, err := s.Fixtures.Org.GetEntity()
+	s.Require().Nil(err)
+
+	pool, err := s.Store.CreateEntityPool(s.adminCtx, entity, params.CreatePoolParams{
+		ProviderName: "test-provider",
+		MaxRunners:   4,
+		Image:        "test-image",
+		Flavor:       "test-flavor",
+		OSType:       "linux",
+		Tags:         []string{"amd64-linux-runner", "dry-run"},
+		DryRun:       true,
+	})
+	s.Require().Nil(err)
+	s.Require().Empty(pool.ID)
+	s.Require().Equal("test-image", pool.Image)
+	s.Require().Len(pool.Tags, 2)
+	s.Require().Equal(s.Fixtures.Org.ID, pool.OrgID)
+
+	pools, err := s.Store.ListEntityPools(s.adminCtx, entity)
+	s.Require().Nil(err)
+	s.Require().Len(pools, len(s.Fixtures.Pools))
+
+	maxRunners := uint(10)
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, s.Fixtures.Pools[0].ID, params.UpdatePoolParams{
+		MaxRunners: &maxRunners,
+		DryRun:     true,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(s.Fixtures.Pools[0].ID, pool.ID)
+	s.Require().Equal(maxRunners, pool.MaxRunners)
+
+	pool, err = s.Store.GetPoolByID(s.adminCtx, s.Fixtures.Pools[0].ID)
+	s.Require().Nil(err)
+	s.Require().Equal(s.Fixtures.Pools[0].MaxRunners, pool.MaxRunners)
+}
+
 func (s *PoolsTestSuite) TestGetPoolByID() {
 	pool, err := s.Store.GetPoolByID(s.adminCtx, s.Fixtures.Pools[0].ID)
 
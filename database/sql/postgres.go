@@ -0,0 +1,44 @@
+package sql
+
+import (
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// postgresDialector is the PostgreSQL dialector, with the column types used by the
+// models that PostgreSQL doesn't know about mapped to their PostgreSQL equivalent.
+type postgresDialector struct {
+	postgres.Dialector
+}
+
+func newPostgresDialector(dsn string) gorm.Dialector {
+	return postgresDialector{
+		Dialector: postgres.Dialector{Config: &postgres.Config{DSN: dsn}},
+	}
+}
+
+// Migrator returns the PostgreSQL migrator. It is overridden so that the migrator
+// uses DataTypeOf from this dialector.
+func (d postgresDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+func (d postgresDialector) DataTypeOf(field *schema.Field) string {
+	dataType := strings.ToLower(string(field.DataType))
+	switch {
+	case dataType == "longblob":
+		return "bytea"
+	case strings.HasSuffix(dataType, " collate nocase"):
+		// There is no NOCASE collation. Case insensitive lookups use lower().
+		return strings.TrimSuffix(dataType, " collate nocase")
+	}
+	return d.Dialector.DataTypeOf(field)
+}
@@ -0,0 +1,33 @@
+package sql
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func TestPostgresDataTypeOf(t *testing.T) {
+	dialector := newPostgresDialector("").(postgresDialector)
+
+	tests := []struct {
+		model    interface{}
+		field    string
+		expected string
+	}{
+		{model: &GithubEndpoint{}, field: "Name", expected: "varchar(64)"},
+		{model: &GithubEndpoint{}, field: "CACertBundle", expected: "bytea"},
+		{model: &Instance{}, field: "ProviderFault", expected: "bytea"},
+		{model: &Tag{}, field: "Name", expected: "varchar(64)"},
+		{model: &Pool{}, field: "Enabled", expected: "boolean"},
+	}
+
+	for _, tc := range tests {
+		sch, err := schema.Parse(tc.model, &sync.Map{}, schema.NamingStrategy{})
+		require.Nil(t, err)
+		field := sch.LookUpField(tc.field)
+		require.NotNil(t, field, tc.field)
+		require.Equal(t, tc.expected, dialector.DataTypeOf(field), "%s.%s", sch.Name, tc.field)
+	}
+}
@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var (
+	_ common.ProviderPauseStore       = &sqlDatabase{}
+	_ common.ProviderEnvironmentStore = &sqlDatabase{}
+)
+
+func sqlToParamsProviderPause(pause ProviderPause) params.ProviderPause {
+	return params.ProviderPause{
+		ProviderName: pause.ProviderName,
+		Reason:       pause.Reason,
+		PausedAt:     pause.CreatedAt,
+	}
+}
+
+func (s *sqlDatabase) PauseProvider(_ context.Context, providerName, reason string) (params.ProviderPause, error) {
+	var pause ProviderPause
+	q := s.conn.Where("provider_name = ?", providerName).First(&pause)
+	if q.Error != nil {
+		if !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.ProviderPause{}, errors.Wrap(q.Error, "fetching provider pause")
+		}
+		pause.ProviderName = providerName
+	}
+	pause.Reason = reason
+
+	if q := s.conn.Save(&pause); q.Error != nil {
+		return params.ProviderPause{}, errors.Wrap(q.Error, "pausing provider")
+	}
+	return sqlToParamsProviderPause(pause), nil
+}
+
+func (s *sqlDatabase) ResumeProvider(_ context.Context, providerName string) error {
+	q := s.conn.Where("provider_name = ?", providerName).Delete(&ProviderPause{})
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "resuming provider")
+	}
+	return nil
+}
+
+func (s *sqlDatabase) ListPausedProviders(_ context.Context) ([]params.ProviderPause, error) {
+	var pauses []ProviderPause
+	if q := s.conn.Order("provider_name").Find(&pauses); q.Error != nil {
+		return nil, errors.Wrap(q.Error, "fetching paused providers")
+	}
+
+	ret := make([]params.ProviderPause, len(pauses))
+	for idx, pause := range pauses {
+		ret[idx] = sqlToParamsProviderPause(pause)
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) sqlToParamsProviderEnvironment(env ProviderEnvironment) (params.ProviderEnvironment, error) {
+	ret := params.ProviderEnvironment{
+		ProviderName: env.ProviderName,
+		UpdatedAt:    env.UpdatedAt,
+		Variables:    map[string]string{},
+	}
+	if len(env.Variables) > 0 {
+		if err := s.unsealAndUnmarshal(env.Variables, &ret.Variables); err != nil {
+			return params.ProviderEnvironment{}, errors.Wrap(err, "decoding provider environment")
+		}
+	}
+
+	ret.VariableNames = make([]string, 0, len(ret.Variables))
+	for name := range ret.Variables {
+		ret.VariableNames = append(ret.VariableNames, name)
+	}
+	sort.Strings(ret.VariableNames)
+	return ret, nil
+}
+
+func (s *sqlDatabase) GetProviderEnvironment(_ context.Context, providerName string) (params.ProviderEnvironment, error) {
+	var env ProviderEnvironment
+	q := s.conn.Where("provider_name = ?", providerName).First(&env)
+	if q.Error != nil {
+		if !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return params.ProviderEnvironment{}, errors.Wrap(q.Error, "fetching provider environment")
+		}
+		env.ProviderName = providerName
+	}
+	return s.sqlToParamsProviderEnvironment(env)
+}
+
+func (s *sqlDatabase) UpdateProviderEnvironment(_ context.Context, providerName string, param params.UpdateProviderEnvironmentParams) (params.ProviderEnvironment, error) {
+	var ret params.ProviderEnvironment
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		var env ProviderEnvironment
+		q := tx.Where("provider_name = ?", providerName).First(&env)
+		if q.Error != nil {
+			if !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+				return errors.Wrap(q.Error, "fetching provider environment")
+			}
+			env.ProviderName = providerName
+		}
+
+		current, err := s.sqlToParamsProviderEnvironment(env)
+		if err != nil {
+			return err
+		}
+		for name, value := range param.Set {
+			current.Variables[name] = value
+		}
+		for _, name := range param.Unset {
+			delete(current.Variables, name)
+		}
+
+		env.Variables, err = s.marshalAndSeal(current.Variables)
+		if err != nil {
+			return errors.Wrap(err, "encoding provider environment")
+		}
+		if q := tx.Save(&env); q.Error != nil {
+			return errors.Wrap(q.Error, "saving provider environment")
+		}
+
+		ret, err = s.sqlToParamsProviderEnvironment(env)
+		return err
+	})
+	if err != nil {
+		return params.ProviderEnvironment{}, errors.Wrap(err, "updating provider environment")
+	}
+	return ret, nil
+}
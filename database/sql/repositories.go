@@ -24,7 +24,6 @@ import (
 	"gorm.io/gorm"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
-	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
@@ -39,7 +38,7 @@ func (s *sqlDatabase) CreateRepository(ctx context.Context, owner, name, credent
 	if webhookSecret == "" {
 		return params.Repository{}, errors.New("creating repo: missing secret")
 	}
-	secret, err := util.Seal([]byte(webhookSecret), []byte(s.cfg.Passphrase))
+	secret, err := s.seal([]byte(webhookSecret))
 	if err != nil {
 		return params.Repository{}, fmt.Errorf("failed to encrypt string")
 	}
@@ -138,9 +137,18 @@ func (s *sqlDatabase) DeleteRepository(ctx context.Context, repoID string) (err
 		}
 	}(repo)
 
-	q := s.conn.Unscoped().Delete(&repo)
-	if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
-		return errors.Wrap(q.Error, "deleting repo")
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		q := tx.Unscoped().Delete(&repo)
+		if q.Error != nil && !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return errors.Wrap(q.Error, "deleting repo")
+		}
+		if err := s.deleteEntityRoleGrants(tx, repo.ID); err != nil {
+			return errors.Wrap(err, "deleting repo")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -181,17 +189,26 @@ func (s *sqlDatabase) UpdateRepository(ctx context.Context, repoID string, param
 		}
 
 		if param.WebhookSecret != "" {
-			secret, err := util.Seal([]byte(param.WebhookSecret), []byte(s.cfg.Passphrase))
-			if err != nil {
+			if err := s.updateWebhookSecret(param, &repo.WebhookSecret, &repo.PreviousWebhookSecret, &repo.PreviousWebhookSecretExpiresAt); err != nil {
 				return fmt.Errorf("saving repo: failed to encrypt string: %w", err)
 			}
-			repo.WebhookSecret = secret
 		}
 
 		if param.PoolBalancerType != "" {
 			repo.PoolBalancerType = param.PoolBalancerType
 		}
 
+		if param.MaxConcurrentJobs != nil {
+			repo.MaxConcurrentJobs = *param.MaxConcurrentJobs
+		}
+
+		if param.RoutingRules != nil {
+			repo.RoutingRules, err = marshalRoutingRules(*param.RoutingRules)
+			if err != nil {
+				return errors.Wrap(err, "marshaling routing rules")
+			}
+		}
+
 		q := tx.Save(&repo)
 		if q.Error != nil {
 			return errors.Wrap(q.Error, "saving repo")
@@ -216,7 +233,7 @@ func (s *sqlDatabase) UpdateRepository(ctx context.Context, repoID string, param
 }
 
 func (s *sqlDatabase) GetRepositoryByID(ctx context.Context, repoID string) (params.Repository, error) {
-	repo, err := s.getRepoByID(ctx, s.conn, repoID, "Pools", "Credentials", "Endpoint")
+	repo, err := s.getRepoByID(ctx, s.conn, repoID, "Pools", "Credentials", "Credentials.Endpoint", "Endpoint", "Events")
 	if err != nil {
 		return params.Repository{}, errors.Wrap(err, "fetching repo")
 	}
@@ -231,7 +248,7 @@ func (s *sqlDatabase) GetRepositoryByID(ctx context.Context, repoID string) (par
 func (s *sqlDatabase) getRepo(_ context.Context, owner, name, endpointName string) (Repository, error) {
 	var repo Repository
 
-	q := s.conn.Where("name = ? COLLATE NOCASE and owner = ? COLLATE NOCASE and endpoint_name = ? COLLATE NOCASE", name, owner, endpointName).
+	q := s.conn.Where(s.equalsNoCase("name")+" and "+s.equalsNoCase("owner")+" and "+s.equalsNoCase("endpoint_name"), name, owner, endpointName).
 		Preload("Credentials").
 		Preload("Credentials.Endpoint").
 		Preload("Endpoint").
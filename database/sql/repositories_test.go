@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
@@ -387,6 +388,52 @@ func (s *RepoTestSuite) TestUpdateRepository() {
 	s.Require().Equal(s.Fixtures.UpdateRepoParams.WebhookSecret, repo.WebhookSecret)
 }
 
+func (s *RepoTestSuite) TestUpdateRepositoryMaxConcurrentJobs() {
+	maxJobs := uint(5)
+	repo, err := s.Store.UpdateRepository(s.adminCtx, s.Fixtures.Repos[0].ID, params.UpdateEntityParams{
+		MaxConcurrentJobs: &maxJobs,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(maxJobs, repo.MaxConcurrentJobs)
+
+	// Updating other fields does not reset the limit.
+	repo, err = s.Store.UpdateRepository(s.adminCtx, s.Fixtures.Repos[0].ID, params.UpdateEntityParams{
+		PoolBalancerType: params.PoolBalancerTypePack,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(maxJobs, repo.MaxConcurrentJobs)
+
+	entity, err := repo.GetEntity()
+	s.Require().Nil(err)
+	s.Require().Equal(maxJobs, entity.MaxConcurrentJobs)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryWebhookSecretGracePeriod() {
+	oldSecret := s.Fixtures.Repos[0].WebhookSecret
+	repo, err := s.Store.UpdateRepository(s.adminCtx, s.Fixtures.Repos[0].ID, params.UpdateEntityParams{
+		WebhookSecret:            "new-secret",
+		WebhookSecretGracePeriod: 10,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal("new-secret", repo.WebhookSecret)
+	s.Require().Equal(oldSecret, repo.PreviousWebhookSecret)
+	s.Require().NotNil(repo.PreviousWebhookSecretExpiresAt)
+	s.Require().WithinDuration(time.Now().Add(10*time.Minute), *repo.PreviousWebhookSecretExpiresAt, time.Minute)
+
+	entity, err := repo.GetEntity()
+	s.Require().Nil(err)
+	s.Require().Equal(oldSecret, entity.PreviousWebhookSecret)
+
+	// Replacing the secret without a grace period drops the previous secret.
+	repo, err = s.Store.UpdateRepository(s.adminCtx, s.Fixtures.Repos[0].ID, params.UpdateEntityParams{
+		WebhookSecret: "newer-secret",
+	})
+	s.Require().Nil(err)
+	s.Require().Equal("newer-secret", repo.WebhookSecret)
+	s.Require().Empty(repo.PreviousWebhookSecret)
+	s.Require().Nil(repo.PreviousWebhookSecretExpiresAt)
+}
+
 func (s *RepoTestSuite) TestUpdateRepositoryInvalidRepoID() {
 	_, err := s.Store.UpdateRepository(s.adminCtx, "dummy-repo-id", s.Fixtures.UpdateRepoParams)
 
@@ -494,6 +541,10 @@ func (s *RepoTestSuite) TestGetRepositoryByIDDBDecryptingErr() {
 		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `repositories` WHERE id = ? AND `repositories`.`deleted_at` IS NULL ORDER BY `repositories`.`id` LIMIT ?")).
 		WithArgs(s.Fixtures.Repos[0].ID, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.Fixtures.Repos[0].ID))
+	s.Fixtures.SQLMock.
+		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `repository_events` WHERE `repository_events`.`repo_id` = ? AND `repository_events`.`deleted_at` IS NULL")).
+		WithArgs(s.Fixtures.Repos[0].ID).
+		WillReturnRows(sqlmock.NewRows([]string{"repo_id"}))
 	s.Fixtures.SQLMock.
 		ExpectQuery(regexp.QuoteMeta("SELECT * FROM `pools` WHERE `pools`.`repo_id` = ? AND `pools`.`deleted_at` IS NULL")).
 		WithArgs(s.Fixtures.Repos[0].ID).
@@ -819,6 +870,215 @@ func (s *RepoTestSuite) TestUpdateRepositoryPool() {
 	s.Require().Equal(s.Fixtures.UpdatePoolParams.Flavor, pool.Flavor)
 }
 
+func (s *RepoTestSuite) TestUpdateRepositoryPoolRotatesInstanceTokenGeneration() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, s.Fixtures.CreatePoolParams)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(0), repoPool.InstanceTokenGeneration)
+
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, s.Fixtures.UpdatePoolParams)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(1), pool.InstanceTokenGeneration)
+
+	pool, err = s.Store.GetPoolByID(s.adminCtx, repoPool.ID)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(1), pool.InstanceTokenGeneration)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolScaleDownSettings() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.IdleDetectionWindow = 10
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(10), repoPool.IdleDetectionWindow)
+	s.Require().Equal(uint(0), repoPool.ScaleDownGracePeriod)
+	s.Require().Equal(0.5, repoPool.ScaleDownFraction())
+
+	idleWindow := uint(0)
+	gracePeriod := uint(15)
+	factor := 0.25
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		IdleDetectionWindow:  &idleWindow,
+		ScaleDownGracePeriod: &gracePeriod,
+		ScaleDownFactor:      &factor,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(uint(0), pool.IdleDetectionWindow)
+	s.Require().Equal(uint(15), pool.ScaleDownGracePeriod)
+	s.Require().Equal(uint(2), pool.IdleWindow())
+	s.Require().Equal(uint(15), pool.ScaleDownGrace())
+	s.Require().Equal(0.25, pool.ScaleDownFraction())
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolCleanupPolicy() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.CleanupPolicy = &params.CleanupPolicy{
+		WipeWorkspace: true,
+	}
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create repo pool: %v", err))
+	}
+	s.Require().NotNil(repoPool.CleanupPolicy)
+	s.Require().True(repoPool.CleanupPolicy.WipeWorkspace)
+
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		CleanupPolicy: &params.CleanupPolicy{
+			PruneDockerImagesOlderThanDays: 7,
+		},
+	})
+	s.Require().Nil(err)
+	s.Require().NotNil(pool.CleanupPolicy)
+	s.Require().False(pool.CleanupPolicy.WipeWorkspace)
+	s.Require().Equal(uint(7), pool.CleanupPolicy.PruneDockerImagesOlderThanDays)
+
+	// An empty policy removes it.
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		CleanupPolicy: &params.CleanupPolicy{},
+	})
+	s.Require().Nil(err)
+	s.Require().Nil(pool.CleanupPolicy)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolInstanceMetadata() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.InstanceMetadata = params.InstanceMetadata{
+		"artifact_cache": "https://cache.example.com",
+	}
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create repo pool: %v", err))
+	}
+	s.Require().Equal(createParams.InstanceMetadata, repoPool.InstanceMetadata)
+
+	// Updates without instance metadata leave it untouched.
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{})
+	s.Require().Nil(err)
+	s.Require().Equal(createParams.InstanceMetadata, pool.InstanceMetadata)
+
+	metadata := params.InstanceMetadata{"region": "eu-west"}
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		InstanceMetadata: &metadata,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(metadata, pool.InstanceMetadata)
+
+	// Empty metadata removes it.
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		InstanceMetadata: &params.InstanceMetadata{},
+	})
+	s.Require().Nil(err)
+	s.Require().Empty(pool.InstanceMetadata)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolWarmImage() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.WarmImage = &params.WarmImageSettings{PrepareScript: "#!/bin/sh"}
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create repo pool: %v", err))
+	}
+	s.Require().Equal(createParams.WarmImage, repoPool.WarmImage)
+	s.Require().Nil(repoPool.WarmImageStatus)
+
+	builtAt := time.Now().UTC().Truncate(time.Second)
+	pool, err := s.Store.UpdatePoolWarmImageStatus(s.adminCtx, repoPool.ID, params.WarmImageStatus{
+		Image:         "warm-image",
+		BaseImage:     repoPool.Image,
+		BuiltAt:       builtAt,
+		LastAttemptAt: builtAt,
+	})
+	s.Require().Nil(err)
+	s.Require().NotNil(pool.WarmImageStatus)
+	s.Require().Equal("warm-image", pool.RunnerImage())
+	s.Require().Equal(repoPool.InstanceTokenGeneration, pool.InstanceTokenGeneration)
+
+	// Changing the settings keeps the warm image, but schedules a rebuild.
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		WarmImage: &params.WarmImageSettings{PrepareScript: "#!/bin/sh\necho warm", RefreshInterval: 48},
+	})
+	s.Require().Nil(err)
+	s.Require().Equal("warm-image", pool.WarmImageStatus.Image)
+	s.Require().True(pool.WarmImageStatus.LastAttemptAt.IsZero())
+
+	// Empty settings disable warm images.
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		WarmImage: &params.WarmImageSettings{},
+	})
+	s.Require().Nil(err)
+	s.Require().Nil(pool.WarmImage)
+	s.Require().Nil(pool.WarmImageStatus)
+	s.Require().Equal(repoPool.Image, pool.RunnerImage())
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolNetworkSettings() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.NetworkSettings = &params.NetworkSettings{
+		DNSServers: []string{"10.0.0.2"},
+		HTTPProxy:  "http://proxy.example.com:3128",
+	}
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create repo pool: %v", err))
+	}
+	s.Require().NotNil(repoPool.NetworkSettings)
+	s.Require().Equal([]string{"10.0.0.2"}, repoPool.NetworkSettings.DNSServers)
+
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		NetworkSettings: &params.NetworkSettings{
+			NTPServers: []string{"ntp.example.com"},
+		},
+	})
+	s.Require().Nil(err)
+	s.Require().NotNil(pool.NetworkSettings)
+	s.Require().Empty(pool.NetworkSettings.DNSServers)
+	s.Require().Equal([]string{"ntp.example.com"}, pool.NetworkSettings.NTPServers)
+
+	// Empty settings remove them.
+	pool, err = s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		NetworkSettings: &params.NetworkSettings{},
+	})
+	s.Require().Nil(err)
+	s.Require().Nil(pool.NetworkSettings)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryPoolSchedule() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+	createParams := s.Fixtures.CreatePoolParams
+	createParams.Schedule = &params.PoolSchedule{
+		Timezone: "Europe/Bucharest",
+		Windows: []params.ScheduleWindow{
+			{Days: []string{"monday"}, Start: "08:00", End: "20:00", MinIdleRunners: 2},
+		},
+	}
+	repoPool, err := s.Store.CreateEntityPool(s.adminCtx, entity, createParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create repo pool: %v", err))
+	}
+	s.Require().NotNil(repoPool.Schedule)
+	s.Require().Equal("Europe/Bucharest", repoPool.Schedule.Timezone)
+	s.Require().Len(repoPool.Schedule.Windows, 1)
+
+	// Empty schedules remove them.
+	pool, err := s.Store.UpdateEntityPool(s.adminCtx, entity, repoPool.ID, params.UpdatePoolParams{
+		Schedule: &params.PoolSchedule{},
+	})
+	s.Require().Nil(err)
+	s.Require().Nil(pool.Schedule)
+}
+
 func (s *RepoTestSuite) TestUpdateRepositoryPoolInvalidRepoID() {
 	entity := params.GithubEntity{
 		ID:         "dummy-repo-id",
@@ -830,6 +1090,96 @@ func (s *RepoTestSuite) TestUpdateRepositoryPoolInvalidRepoID() {
 	s.Require().Equal("fetching pool: parsing id: invalid request", err.Error())
 }
 
+func (s *RepoTestSuite) TestAddRepositoryEventKeepsLastEvents() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+
+	for i := 0; i < 5; i++ {
+		err := s.Store.AddEntityEvent(s.adminCtx, entity, params.WebhookInstallEvent, params.EventInfo, fmt.Sprintf("event %d", i), 3)
+		s.Require().Nil(err)
+	}
+
+	repo, err := s.Store.GetRepositoryByID(s.adminCtx, s.Fixtures.Repos[0].ID)
+	s.Require().Nil(err)
+	s.Require().Len(repo.Events, 3)
+	messages := []string{}
+	for _, event := range repo.Events {
+		s.Require().Equal(params.WebhookInstallEvent, event.EventType)
+		messages = append(messages, event.Message)
+	}
+	s.Require().ElementsMatch([]string{"event 2", "event 3", "event 4"}, messages)
+}
+
+func (s *RepoTestSuite) TestListEntityEvents() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+
+	err = s.Store.AddEntityEvent(s.adminCtx, entity, params.WebhookInstallEvent, params.EventInfo, "webhook installed", 10)
+	s.Require().Nil(err)
+	err = s.Store.AddEntityEvent(s.adminCtx, entity, params.PoolManagerEvent, params.EventError, "pool manager stopped", 10)
+	s.Require().Nil(err)
+
+	events, err := s.Store.ListEntityEvents(s.adminCtx, params.EventError)
+	s.Require().Nil(err)
+	s.Require().Len(events, 1)
+	s.Require().Equal(params.GithubEntityTypeRepository, events[0].EntityType)
+	s.Require().Equal(s.Fixtures.Repos[0].ID, events[0].EntityID)
+	s.Require().Equal(fmt.Sprintf("%s/%s", s.Fixtures.Repos[0].Owner, s.Fixtures.Repos[0].Name), events[0].EntityName)
+	s.Require().Equal(params.PoolManagerEvent, events[0].EventType)
+	s.Require().Equal("pool manager stopped", events[0].Message)
+}
+
+func (s *RepoTestSuite) TestAddRepositoryEventInvalidMaxEvents() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+
+	err = s.Store.AddEntityEvent(s.adminCtx, entity, params.WebhookInstallEvent, params.EventInfo, "event", 0)
+	s.Require().NotNil(err)
+	s.Require().Equal("max events must be greater than 0: invalid request", err.Error())
+}
+
+func (s *RepoTestSuite) TestSetRepositoryPendingWebhookInstall() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+
+	param := &params.InstallWebhookParams{
+		WebhookEndpointType: params.WebhookEndpointDirect,
+		InsecureSSL:         true,
+	}
+	err = s.Store.SetEntityPendingWebhookInstall(s.adminCtx, entity, param)
+	s.Require().Nil(err)
+
+	repo, err := s.Store.GetRepositoryByID(s.adminCtx, s.Fixtures.Repos[0].ID)
+	s.Require().Nil(err)
+	s.Require().Equal(param, repo.PendingWebhookInstall)
+
+	err = s.Store.SetEntityPendingWebhookInstall(s.adminCtx, entity, nil)
+	s.Require().Nil(err)
+
+	repo, err = s.Store.GetRepositoryByID(s.adminCtx, s.Fixtures.Repos[0].ID)
+	s.Require().Nil(err)
+	s.Require().Nil(repo.PendingWebhookInstall)
+}
+
+func (s *RepoTestSuite) TestSetRepositoryWebhookPath() {
+	entity, err := s.Fixtures.Repos[0].GetEntity()
+	s.Require().Nil(err)
+
+	err = s.Store.SetEntityWebhookPath(s.adminCtx, entity, true)
+	s.Require().Nil(err)
+
+	repo, err := s.Store.GetRepositoryByID(s.adminCtx, s.Fixtures.Repos[0].ID)
+	s.Require().Nil(err)
+	s.Require().True(repo.EntityWebhookPath)
+
+	err = s.Store.SetEntityWebhookPath(s.adminCtx, entity, false)
+	s.Require().Nil(err)
+
+	repo, err = s.Store.GetRepositoryByID(s.adminCtx, s.Fixtures.Repos[0].ID)
+	s.Require().Nil(err)
+	s.Require().False(repo.EntityWebhookPath)
+}
+
 func TestRepoTestSuite(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,95 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/database/secrets"
+)
+
+var _ common.SecretsMigrator = &sqlDatabase{}
+
+// sealedColumn is a column that holds data sealed by the secrets backend.
+type sealedColumn struct {
+	model  interface{}
+	key    string
+	column string
+}
+
+var sealedColumns = []sealedColumn{
+	{model: &Repository{}, key: "id", column: "webhook_secret"},
+	{model: &Repository{}, key: "id", column: "previous_webhook_secret"},
+	{model: &Organization{}, key: "id", column: "webhook_secret"},
+	{model: &Organization{}, key: "id", column: "previous_webhook_secret"},
+	{model: &Enterprise{}, key: "id", column: "webhook_secret"},
+	{model: &Enterprise{}, key: "id", column: "previous_webhook_secret"},
+	{model: &GithubCredentials{}, key: "id", column: "payload"},
+	{model: &Instance{}, key: "id", column: "jit_configuration"},
+	{model: &ProviderEnvironment{}, key: "provider_name", column: "variables"},
+}
+
+type sealedValue struct {
+	SealedKey   string
+	SealedValue []byte
+}
+
+func (s *sqlDatabase) reEncryptColumn(tx *gorm.DB, backend secrets.Backend, col sealedColumn) (int, error) {
+	var values []sealedValue
+	q := tx.Unscoped().Model(col.model).
+		Select(fmt.Sprintf("%s as sealed_key, %s as sealed_value", col.key, col.column)).
+		Where(fmt.Sprintf("%s IS NOT NULL", col.column)).
+		Scan(&values)
+	if q.Error != nil {
+		return 0, errors.Wrap(q.Error, "fetching sealed values")
+	}
+
+	var count int
+	for _, val := range values {
+		if len(val.SealedValue) == 0 || secrets.SealedWith(val.SealedValue) == backend.Type() {
+			continue
+		}
+		data, err := backend.Unseal(val.SealedValue)
+		if err != nil {
+			return 0, errors.Wrapf(err, "unsealing %s", val.SealedKey)
+		}
+		sealed, err := backend.Seal(data)
+		if err != nil {
+			return 0, errors.Wrapf(err, "sealing %s", val.SealedKey)
+		}
+		q := tx.Unscoped().Model(col.model).
+			Where(fmt.Sprintf("%s = ?", col.key), val.SealedKey).
+			UpdateColumn(col.column, sealed)
+		if q.Error != nil {
+			return 0, errors.Wrapf(q.Error, "updating %s", val.SealedKey)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ReEncryptSecrets seals all the sensitive info in the database with the configured
+// secrets backend. Values that were already sealed by the backend are skipped.
+func (s *sqlDatabase) ReEncryptSecrets(ctx context.Context) (int, error) {
+	backend := s.secretsBackend()
+	var count int
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		for _, col := range sealedColumns {
+			n, err := s.reEncryptColumn(tx, backend, col)
+			if err != nil {
+				return errors.Wrapf(err, "re-encrypting %s", col.column)
+			}
+			count += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "re-encrypting secrets")
+	}
+	slog.InfoContext(ctx, "re-encrypted secrets", "backend", backend.Type(), "count", count)
+	return count, nil
+}
@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/database/secrets"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+)
+
+// newFakeTransit returns a server implementing the parts of the Vault transit
+// engine used by the vault secrets backend.
+func newFakeTransit(t *testing.T) *httptest.Server {
+	var mux sync.Mutex
+	keys := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/garm":
+			key := make([]byte, 32)
+			rand.Read(key) //nolint:errcheck
+			id := fmt.Sprintf("vault:v1:%d", len(keys))
+			keys[id] = key
+			fmt.Fprintf(w, `{"data":{"plaintext":%q,"ciphertext":%q}}`, base64.StdEncoding.EncodeToString(key), id)
+		case "/v1/transit/decrypt/garm":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+			fmt.Fprintf(w, `{"data":{"plaintext":%q}}`, base64.StdEncoding.EncodeToString(keys[body["ciphertext"]]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReEncryptSecrets(t *testing.T) {
+	ctx := context.Background()
+	dbCfg := garmTesting.GetTestSqliteDBConfig(t)
+	store, err := NewSQLDatabase(ctx, dbCfg)
+	require.NoError(t, err)
+	db := store.(*sqlDatabase)
+	adminCtx := garmTesting.ImpersonateAdminContext(ctx, db, t)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	repo, err := db.CreateRepository(adminCtx, "owner", "repo", creds.Name, "webhook-secret", params.PoolBalancerTypeRoundRobin)
+	require.NoError(t, err)
+
+	dbCfg.Secrets = config.Secrets{
+		Backend: config.VaultSecretsBackend,
+		Vault: config.Vault{
+			Address: newFakeTransit(t).URL,
+			Token:   "token",
+			KeyName: "garm",
+		},
+	}
+	db.secrets, err = secrets.NewBackend(ctx, dbCfg)
+	require.NoError(t, err)
+
+	// Secrets sealed with the passphrase are readable before the migration.
+	_, err = db.GetRepositoryByID(adminCtx, repo.ID)
+	require.NoError(t, err)
+
+	count, err := db.ReEncryptSecrets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	var sqlRepo Repository
+	require.NoError(t, db.conn.Where("id = ?", repo.ID).First(&sqlRepo).Error)
+	require.Equal(t, config.VaultSecretsBackend, secrets.SealedWith(sqlRepo.WebhookSecret))
+
+	migrated, err := db.GetRepositoryByID(adminCtx, repo.ID)
+	require.NoError(t, err)
+	require.Equal(t, "webhook-secret", migrated.WebhookSecret)
+	migratedCreds, err := db.GetGithubCredentialsByName(adminCtx, creds.Name, false)
+	require.NoError(t, err)
+	require.Equal(t, creds.Name, migratedCreds.Name)
+
+	// Secrets already sealed by the backend are skipped.
+	count, err = db.ReEncryptSecrets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	// The passphrase backend can't read the re-encrypted secrets.
+	db.secrets = nil
+	_, err = db.GetRepositoryByID(adminCtx, repo.ID)
+	require.ErrorContains(t, err, "data was sealed by the vault secrets backend")
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/config"
 	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/database/secrets"
 	"github.com/cloudbase/garm/database/watcher"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/util/appdefaults"
@@ -53,6 +54,8 @@ func newDBConn(dbCfg config.Database) (conn *gorm.DB, err error) {
 		conn, err = gorm.Open(mysql.Open(connURI), gormConfig)
 	case config.SQLiteBackend:
 		conn, err = gorm.Open(sqlite.Open(connURI), gormConfig)
+	case config.PostgresBackend:
+		conn, err = gorm.Open(newPostgresDialector(connURI), gormConfig)
 	}
 	if err != nil {
 		return nil, errors.Wrap(err, "connecting to database")
@@ -73,11 +76,16 @@ func NewSQLDatabase(ctx context.Context, cfg config.Database) (common.Store, err
 	if err != nil {
 		return nil, errors.Wrap(err, "registering producer")
 	}
+	secretsBackend, err := secrets.NewBackend(ctx, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating secrets backend")
+	}
 	db := &sqlDatabase{
 		conn:     conn,
 		ctx:      ctx,
 		cfg:      cfg,
 		producer: producer,
+		secrets:  secretsBackend,
 	}
 
 	if err := db.migrateDB(); err != nil {
@@ -91,6 +99,7 @@ type sqlDatabase struct {
 	ctx      context.Context
 	cfg      config.Database
 	producer common.Producer
+	secrets  secrets.Backend
 }
 
 var renameTemplate = `
@@ -118,6 +127,19 @@ DROP TABLE %s_old;
 COMMIT;
 `
 
+// setForeignKeys toggles foreign key enforcement during migrations. Only SQLite
+// needs this. The other backends ignore it.
+func (s *sqlDatabase) setForeignKeys(enabled bool) {
+	if s.cfg.DbBackend != config.SQLiteBackend {
+		return
+	}
+	if enabled {
+		s.conn.Exec("PRAGMA foreign_keys = ON")
+	} else {
+		s.conn.Exec("PRAGMA foreign_keys = OFF")
+	}
+}
+
 func (s *sqlDatabase) cascadeMigrationSQLite(model interface{}, name string, justDrop bool) error {
 	if !s.conn.Migrator().HasTable(name) {
 		return nil
@@ -194,7 +216,7 @@ func (s *sqlDatabase) cascadeMigration() error {
 		if err := s.cascadeMigrationSQLite(&WorkflowJob{}, "workflow_jobs", false); err != nil {
 			return fmt.Errorf("failed to migrate addresses: %w", err)
 		}
-	case config.MySQLBackend:
+	case config.MySQLBackend, config.PostgresBackend:
 		return nil
 	default:
 		return fmt.Errorf("invalid db backend: %s", s.cfg.DbBackend)
@@ -222,8 +244,8 @@ func (s *sqlDatabase) ensureGithubEndpoint() error {
 }
 
 func (s *sqlDatabase) migrateCredentialsToDB() (err error) {
-	s.conn.Exec("PRAGMA foreign_keys = OFF")
-	defer s.conn.Exec("PRAGMA foreign_keys = ON")
+	s.setForeignKeys(false)
+	defer s.setForeignKeys(true)
 
 	adminUser, err := s.GetAdminUser(s.ctx)
 	if err != nil {
@@ -413,9 +435,16 @@ func (s *sqlDatabase) migrateDB() error {
 		hasMinAgeField = true
 	}
 
-	s.conn.Exec("PRAGMA foreign_keys = OFF")
+	var hasProviderFaultAtField bool
+	if s.conn.Migrator().HasTable(&Instance{}) && s.conn.Migrator().HasColumn(&Instance{}, "provider_fault_at") {
+		hasProviderFaultAtField = true
+	}
+
+	s.setForeignKeys(false)
 	if err := s.conn.AutoMigrate(
 		&User{},
+		&RoleGrant{},
+		&APIToken{},
 		&GithubEndpoint{},
 		&GithubCredentials{},
 		&Tag{},
@@ -423,15 +452,29 @@ func (s *sqlDatabase) migrateDB() error {
 		&Repository{},
 		&Organization{},
 		&Enterprise{},
+		&RepositoryEvent{},
+		&OrganizationEvent{},
+		&EnterpriseEvent{},
 		&Address{},
 		&InstanceStatusUpdate{},
 		&Instance{},
 		&ControllerInfo{},
+		&ControllerIDMigration{},
 		&WorkflowJob{},
+		&AuditRecord{},
+		&WebhookDelivery{},
+		&IdempotencyRecord{},
+		&ProviderPause{},
+		&ProviderEnvironment{},
+		&DenyRule{},
+		&ControllerNode{},
+		&EntityLease{},
+		&CapacityReservation{},
+		&RunnerUsage{},
 	); err != nil {
 		return errors.Wrap(err, "running auto migrate")
 	}
-	s.conn.Exec("PRAGMA foreign_keys = ON")
+	s.setForeignKeys(true)
 
 	if !hasMinAgeField {
 		var controller ControllerInfo
@@ -447,6 +490,13 @@ func (s *sqlDatabase) migrateDB() error {
 		}
 	}
 
+	if !hasProviderFaultAtField {
+		// The time of the last update is the best guess we have for existing faults.
+		if err := s.conn.Exec("update instances set provider_fault_at = updated_at where provider_fault is not null").Error; err != nil {
+			return errors.Wrap(err, "updating instances")
+		}
+	}
+
 	if err := s.ensureGithubEndpoint(); err != nil {
 		return errors.Wrap(err, "ensuring github endpoint")
 	}
@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// recordRunnerCreated starts the usage record of a new runner. Failing to record usage
+// does not fail the creation of the runner.
+func (s *sqlDatabase) recordRunnerCreated(instance Instance, pool Pool) {
+	usage := RunnerUsage{
+		InstanceID:   instance.ID,
+		Name:         instance.Name,
+		PoolID:       pool.ID.String(),
+		ProviderName: pool.ProviderName,
+		CreatedAt:    instance.CreatedAt,
+	}
+	switch {
+	case pool.RepoID != nil:
+		usage.EntityID = pool.RepoID.String()
+		usage.EntityType = params.GithubEntityTypeRepository
+	case pool.OrgID != nil:
+		usage.EntityID = pool.OrgID.String()
+		usage.EntityType = params.GithubEntityTypeOrganization
+	case pool.EnterpriseID != nil:
+		usage.EntityID = pool.EnterpriseID.String()
+		usage.EntityType = params.GithubEntityTypeEnterprise
+	}
+	if q := s.conn.Create(&usage); q.Error != nil {
+		slog.With(slog.Any("error", q.Error)).Error("failed to record runner usage", "runner_name", instance.Name)
+	}
+}
+
+// recordRunnerRemoved ends the usage record of a runner.
+func (s *sqlDatabase) recordRunnerRemoved(instance Instance) {
+	now := time.Now().UTC()
+	s.updateRunnerUsage(instance, func(usage *RunnerUsage) {
+		endBusyPeriod(usage, now)
+		usage.RemovedAt = &now
+	})
+}
+
+// recordRunnerStatusChange keeps track of the jobs served by a runner, and of the time
+// it spent running them. A runner picks up a job when it becomes active, and finishes
+// it when it stops being active.
+func (s *sqlDatabase) recordRunnerStatusChange(instance Instance, previous params.RunnerStatus) {
+	wasActive := previous == params.RunnerActive
+	isActive := instance.RunnerStatus == params.RunnerActive
+	if wasActive == isActive {
+		return
+	}
+
+	now := time.Now().UTC()
+	s.updateRunnerUsage(instance, func(usage *RunnerUsage) {
+		if !isActive {
+			endBusyPeriod(usage, now)
+			return
+		}
+		usage.JobsServed++
+		usage.BusySince = &now
+		if usage.FirstJobAt == nil {
+			usage.FirstJobAt = &now
+		}
+	})
+}
+
+func endBusyPeriod(usage *RunnerUsage, now time.Time) {
+	if usage.BusySince == nil {
+		return
+	}
+	usage.BusySeconds += max(now.Sub(*usage.BusySince).Seconds(), 0)
+	usage.BusySince = nil
+}
+
+// updateRunnerUsage applies the update function to the usage record of a runner that
+// was not removed yet. Failing to record usage does not fail the operation on the runner.
+func (s *sqlDatabase) updateRunnerUsage(instance Instance, update func(usage *RunnerUsage)) {
+	var usage RunnerUsage
+	q := s.conn.Model(&RunnerUsage{}).
+		Where("instance_id = ? and removed_at is null", instance.ID).
+		Order("id desc").
+		Limit(1).
+		Find(&usage)
+	if q.Error != nil {
+		slog.With(slog.Any("error", q.Error)).Error("failed to fetch runner usage", "runner_name", instance.Name)
+		return
+	}
+	if q.RowsAffected == 0 {
+		return
+	}
+
+	update(&usage)
+	if err := s.conn.Save(&usage).Error; err != nil {
+		slog.With(slog.Any("error", err)).Error("failed to record runner usage", "runner_name", instance.Name)
+	}
+}
+
+// ListRunnerUsage returns the usage records of the runners that existed at any point
+// between since and until. A zero since returns all records created before until.
+func (s *sqlDatabase) ListRunnerUsage(_ context.Context, since, until time.Time) ([]params.RunnerUsage, error) {
+	var records []RunnerUsage
+	q := s.conn.Model(&RunnerUsage{}).Where("created_at < ?", until)
+	if !since.IsZero() {
+		q = q.Where("removed_at is null or removed_at > ?", since)
+	}
+	if err := q.Order("id").Find(&records).Error; err != nil {
+		return nil, errors.Wrap(err, "fetching runner usage")
+	}
+
+	ret := make([]params.RunnerUsage, len(records))
+	for idx, record := range records {
+		ret[idx] = params.RunnerUsage{
+			InstanceID:   record.InstanceID.String(),
+			Name:         record.Name,
+			PoolID:       record.PoolID,
+			ProviderName: record.ProviderName,
+			EntityID:     record.EntityID,
+			EntityType:   record.EntityType,
+			CreatedAt:    record.CreatedAt,
+			RemovedAt:    record.RemovedAt,
+			JobsServed:   record.JobsServed,
+			FirstJobAt:   record.FirstJobAt,
+			BusySince:    record.BusySince,
+			BusySeconds:  record.BusySeconds,
+		}
+	}
+	return ret, nil
+}
@@ -17,7 +17,9 @@ package sql
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 
@@ -67,6 +69,7 @@ func (s *sqlDatabase) CreateUser(_ context.Context, user params.NewUserParams) (
 		Enabled:  user.Enabled,
 		Email:    user.Email,
 		IsAdmin:  user.IsAdmin,
+		Role:     user.Role,
 	}
 	err := s.conn.Transaction(func(tx *gorm.DB) error {
 		if _, err := s.getUserByUsernameOrEmail(tx, user.Username); err == nil || !errors.Is(err, runnerErrors.ErrNotFound) {
@@ -103,7 +106,7 @@ func (s *sqlDatabase) HasAdminUser(_ context.Context) bool {
 }
 
 func (s *sqlDatabase) GetUser(_ context.Context, user string) (params.User, error) {
-	dbUser, err := s.getUserByUsernameOrEmail(s.conn, user)
+	dbUser, err := s.getUserByUsernameOrEmail(s.conn.Preload("Grants"), user)
 	if err != nil {
 		return params.User{}, errors.Wrap(err, "fetching user")
 	}
@@ -111,7 +114,7 @@ func (s *sqlDatabase) GetUser(_ context.Context, user string) (params.User, erro
 }
 
 func (s *sqlDatabase) GetUserByID(_ context.Context, userID string) (params.User, error) {
-	dbUser, err := s.getUserByID(s.conn, userID)
+	dbUser, err := s.getUserByID(s.conn.Preload("Grants"), userID)
 	if err != nil {
 		return params.User{}, errors.Wrap(err, "fetching user")
 	}
@@ -151,10 +154,63 @@ func (s *sqlDatabase) UpdateUser(_ context.Context, user string, param params.Up
 	return s.sqlToParamsUser(dbUser), nil
 }
 
+// RecordFailedLogin counts a failed login of a user. Once the number of consecutive
+// failed logins reaches the threshold, the user is locked out for the given duration
+// and the counter starts over.
+func (s *sqlDatabase) RecordFailedLogin(_ context.Context, user string, threshold uint, lockoutDuration time.Duration) (params.User, error) {
+	var dbUser User
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		var err error
+		dbUser, err = s.getUserByUsernameOrEmail(tx, user)
+		if err != nil {
+			return errors.Wrap(err, "fetching user")
+		}
+
+		dbUser.FailedLoginAttempts++
+		if dbUser.FailedLoginAttempts >= threshold {
+			lockedUntil := time.Now().UTC().Add(lockoutDuration)
+			dbUser.LockedUntil = &lockedUntil
+			dbUser.FailedLoginAttempts = 0
+		}
+
+		if q := tx.Save(&dbUser); q.Error != nil {
+			return errors.Wrap(q.Error, "saving user")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.User{}, errors.Wrap(err, "recording failed login")
+	}
+	return s.sqlToParamsUser(dbUser), nil
+}
+
+// ResetFailedLogins clears the failed logins of a user and lifts any lockout.
+func (s *sqlDatabase) ResetFailedLogins(_ context.Context, user string) (params.User, error) {
+	var dbUser User
+	err := s.conn.Transaction(func(tx *gorm.DB) error {
+		var err error
+		dbUser, err = s.getUserByUsernameOrEmail(tx, user)
+		if err != nil {
+			return errors.Wrap(err, "fetching user")
+		}
+
+		dbUser.FailedLoginAttempts = 0
+		dbUser.LockedUntil = nil
+		if q := tx.Save(&dbUser); q.Error != nil {
+			return errors.Wrap(q.Error, "saving user")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.User{}, errors.Wrap(err, "resetting failed logins")
+	}
+	return s.sqlToParamsUser(dbUser), nil
+}
+
 // GetAdminUser returns the system admin user. This is only for internal use.
 func (s *sqlDatabase) GetAdminUser(_ context.Context) (params.User, error) {
 	var user User
-	q := s.conn.Model(&User{}).Where("is_admin = ?", true).First(&user)
+	q := s.conn.Model(&User{}).Preload("Grants").Where("is_admin = ?", true).First(&user)
 	if q.Error != nil {
 		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
 			return params.User{}, runnerErrors.ErrNotFound
@@ -163,3 +219,81 @@ func (s *sqlDatabase) GetAdminUser(_ context.Context) (params.User, error) {
 	}
 	return s.sqlToParamsUser(user), nil
 }
+
+func sqlToParamsRoleGrant(grant RoleGrant) params.RoleGrant {
+	return params.RoleGrant{
+		ID:         grant.ID.String(),
+		CreatedAt:  grant.CreatedAt,
+		Role:       grant.Role,
+		EntityType: grant.EntityType,
+		EntityID:   grant.EntityID.String(),
+	}
+}
+
+// CreateRoleGrant gives a user a role on a repository, organization or enterprise. A
+// user has at most one role on an entity, so an existing grant is replaced.
+func (s *sqlDatabase) CreateRoleGrant(_ context.Context, user string, param params.CreateRoleGrantParams) (params.RoleGrant, error) {
+	entityID, err := uuid.Parse(param.EntityID)
+	if err != nil {
+		return params.RoleGrant{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing entity id")
+	}
+
+	var grant RoleGrant
+	err = s.conn.Transaction(func(tx *gorm.DB) error {
+		dbUser, err := s.getUserByUsernameOrEmail(tx, user)
+		if err != nil {
+			return errors.Wrap(err, "fetching user")
+		}
+		if err := s.hasGithubEntity(tx, param.EntityType, param.EntityID); err != nil {
+			return errors.Wrap(err, "fetching entity")
+		}
+
+		q := tx.Where("user_id = ? and entity_type = ? and entity_id = ?", dbUser.ID, param.EntityType, entityID).First(&grant)
+		if q.Error != nil {
+			if !errors.Is(q.Error, gorm.ErrRecordNotFound) {
+				return errors.Wrap(q.Error, "fetching role grant")
+			}
+			grant = RoleGrant{
+				UserID:     dbUser.ID,
+				EntityType: param.EntityType,
+				EntityID:   entityID,
+			}
+		}
+		grant.Role = param.Role
+		if q := tx.Save(&grant); q.Error != nil {
+			return errors.Wrap(q.Error, "saving role grant")
+		}
+		return nil
+	})
+	if err != nil {
+		return params.RoleGrant{}, errors.Wrap(err, "creating role grant")
+	}
+	return sqlToParamsRoleGrant(grant), nil
+}
+
+// DeleteRoleGrant removes a role grant of a user. Removing a grant that does not
+// exist is not an error.
+func (s *sqlDatabase) DeleteRoleGrant(_ context.Context, user string, grantID string) error {
+	id, err := uuid.Parse(grantID)
+	if err != nil {
+		return errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	dbUser, err := s.getUserByUsernameOrEmail(s.conn, user)
+	if err != nil {
+		return errors.Wrap(err, "fetching user")
+	}
+	q := s.conn.Unscoped().Where("id = ? and user_id = ?", id, dbUser.ID).Delete(&RoleGrant{})
+	if q.Error != nil {
+		return errors.Wrap(q.Error, "deleting role grant")
+	}
+	return nil
+}
+
+// deleteEntityRoleGrants removes the role grants on an entity that was deleted.
+func (s *sqlDatabase) deleteEntityRoleGrants(tx *gorm.DB, entityID uuid.UUID) error {
+	if q := tx.Unscoped().Where("entity_id = ?", entityID).Delete(&RoleGrant{}); q.Error != nil {
+		return errors.Wrap(q.Error, "deleting role grants")
+	}
+	return nil
+}
@@ -16,10 +16,12 @@ package sql
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
@@ -27,6 +29,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	garmTesting "github.com/cloudbase/garm/internal/testing"
 	"github.com/cloudbase/garm/params"
@@ -256,6 +259,33 @@ func (s *UserTestSuite) TestUpdateUserNotFound() {
 	s.Require().Equal("updating user: fetching user: not found", err.Error())
 }
 
+func (s *UserTestSuite) TestRecordFailedLoginLocksOutUser() {
+	username := s.Fixtures.Users[0].Username
+
+	user, err := s.Store.RecordFailedLogin(context.Background(), username, 2, time.Minute)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(1), user.FailedLoginAttempts)
+	s.Require().False(user.IsLockedOut(time.Now().UTC()))
+
+	user, err = s.Store.RecordFailedLogin(context.Background(), username, 2, time.Minute)
+	s.Require().Nil(err)
+	s.Require().Equal(uint(0), user.FailedLoginAttempts)
+	s.Require().True(user.IsLockedOut(time.Now().UTC()))
+	s.Require().False(user.IsLockedOut(time.Now().UTC().Add(2 * time.Minute)))
+
+	user, err = s.Store.ResetFailedLogins(context.Background(), username)
+	s.Require().Nil(err)
+	s.Require().Nil(user.LockedUntil)
+	s.Require().Equal(uint(0), user.FailedLoginAttempts)
+}
+
+func (s *UserTestSuite) TestRecordFailedLoginNotFound() {
+	_, err := s.Store.RecordFailedLogin(context.Background(), "dummy-user", 2, time.Minute)
+
+	s.Require().NotNil(err)
+	s.Require().Equal("recording failed login: fetching user: not found", err.Error())
+}
+
 func (s *UserTestSuite) TestUpdateUserDBSaveErr() {
 	s.Fixtures.SQLMock.ExpectBegin()
 	s.Fixtures.SQLMock.
@@ -274,6 +304,193 @@ func (s *UserTestSuite) TestUpdateUserDBSaveErr() {
 	s.Require().Equal("updating user: saving user: saving user mock error", err.Error())
 }
 
+func (s *UserTestSuite) TestCreateAndListAuditRecords() {
+	record, err := s.Store.CreateAuditRecord(context.Background(), params.AuditRecord{
+		Action:         params.AuditActionImpersonationStarted,
+		ImpersonatorID: s.Fixtures.Users[0].ID,
+		Impersonator:   s.Fixtures.Users[0].Username,
+		UserID:         s.Fixtures.Users[1].ID,
+		Username:       s.Fixtures.Users[1].Username,
+		Reason:         "debugging a pool",
+	})
+	s.Require().Nil(err)
+	s.Require().NotEmpty(record.ID)
+
+	_, err = s.Store.CreateAuditRecord(context.Background(), params.AuditRecord{
+		Action:         params.AuditActionAPIRequest,
+		ImpersonatorID: s.Fixtures.Users[0].ID,
+		UserID:         s.Fixtures.Users[1].ID,
+		Method:         "GET",
+		Path:           "/api/v1/pools",
+		StatusCode:     200,
+	})
+	s.Require().Nil(err)
+
+	page, err := s.Store.ListAuditRecords(context.Background(), params.ListAuditRecordsParams{Page: 1, PageSize: 10})
+	s.Require().Nil(err)
+	s.Require().Len(page.Records, 2)
+	s.Require().Equal(uint(2), page.TotalCount)
+	actions := []params.AuditAction{page.Records[0].Action, page.Records[1].Action}
+	s.Require().ElementsMatch([]params.AuditAction{params.AuditActionImpersonationStarted, params.AuditActionAPIRequest}, actions)
+}
+
+func (s *UserTestSuite) TestListAuditRecordsFiltersAndPaginates() {
+	for i := 0; i < 3; i++ {
+		_, err := s.Store.CreateAuditRecord(context.Background(), params.AuditRecord{
+			Action:       params.AuditActionResourceUpdated,
+			UserID:       s.Fixtures.Users[0].ID,
+			ResourceType: params.AuditResourcePool,
+			ResourceID:   fmt.Sprintf("pool-%d", i),
+			Changes: []params.AuditChange{
+				{Field: "max_runners", Before: json.RawMessage("1"), After: json.RawMessage("2")},
+			},
+		})
+		s.Require().Nil(err)
+	}
+	_, err := s.Store.CreateAuditRecord(context.Background(), params.AuditRecord{
+		Action: params.AuditActionImpersonationStarted,
+		UserID: s.Fixtures.Users[1].ID,
+	})
+	s.Require().Nil(err)
+
+	page, err := s.Store.ListAuditRecords(context.Background(), params.ListAuditRecordsParams{
+		ResourceType: params.AuditResourcePool,
+		Page:         2,
+		PageSize:     2,
+	})
+	s.Require().Nil(err)
+	s.Require().Equal(uint(3), page.TotalCount)
+	s.Require().Equal(uint(2), page.TotalPages)
+	s.Require().Len(page.Records, 1)
+	s.Require().Equal([]params.AuditChange{
+		{Field: "max_runners", Before: json.RawMessage("1"), After: json.RawMessage("2")},
+	}, page.Records[0].Changes)
+
+	_, err = s.Store.ListAuditRecords(context.Background(), params.ListAuditRecordsParams{Page: 1, PageSize: 0})
+	s.Require().Regexp("page_size must be between 1 and", err.Error())
+}
+
+func (s *UserTestSuite) TestDeleteAuditRecordsBefore() {
+	_, err := s.Store.CreateAuditRecord(context.Background(), params.AuditRecord{
+		Action: params.AuditActionImpersonationStarted,
+		UserID: s.Fixtures.Users[1].ID,
+	})
+	s.Require().Nil(err)
+
+	err = s.Store.DeleteAuditRecordsBefore(context.Background(), time.Now().Add(-time.Hour))
+	s.Require().Nil(err)
+	page, err := s.Store.ListAuditRecords(context.Background(), params.ListAuditRecordsParams{Page: 1, PageSize: 10})
+	s.Require().Nil(err)
+	s.Require().Len(page.Records, 1)
+
+	err = s.Store.DeleteAuditRecordsBefore(context.Background(), time.Now().Add(time.Hour))
+	s.Require().Nil(err)
+	page, err = s.Store.ListAuditRecords(context.Background(), params.ListAuditRecordsParams{Page: 1, PageSize: 10})
+	s.Require().Nil(err)
+	s.Require().Len(page.Records, 0)
+}
+
 func TestUserTestSuite(t *testing.T) {
 	suite.Run(t, new(UserTestSuite))
 }
+
+func (s *UserTestSuite) TestRoleGrants() {
+	adminCtx := garmTesting.ImpersonateAdminContext(context.Background(), s.Store, s.T())
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, s.Store, s.T())
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", s.Store, s.T(), endpoint)
+	org, err := s.Store.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhook-secret", params.PoolBalancerTypeRoundRobin)
+	s.Require().Nil(err)
+	username := s.Fixtures.Users[0].Username
+
+	grantParams := params.CreateRoleGrantParams{
+		Role:       params.UserRoleViewer,
+		EntityType: params.GithubEntityTypeOrganization,
+		EntityID:   org.ID,
+	}
+	grant, err := s.Store.CreateRoleGrant(adminCtx, username, grantParams)
+	s.Require().Nil(err)
+	s.Require().Equal(params.UserRoleViewer, grant.Role)
+
+	// A second grant on the same entity replaces the role.
+	grantParams.Role = params.UserRoleOperator
+	_, err = s.Store.CreateRoleGrant(adminCtx, username, grantParams)
+	s.Require().Nil(err)
+
+	user, err := s.Store.GetUser(adminCtx, username)
+	s.Require().Nil(err)
+	s.Require().Len(user.Grants, 1)
+	s.Require().Equal(grant.ID, user.Grants[0].ID)
+	s.Require().Equal(params.UserRoleOperator, user.Grants[0].Role)
+
+	s.Require().Nil(s.Store.DeleteRoleGrant(adminCtx, username, grant.ID))
+	user, err = s.Store.GetUserByID(adminCtx, user.ID)
+	s.Require().Nil(err)
+	s.Require().Empty(user.Grants)
+
+	// Grants are removed along with the entity.
+	_, err = s.Store.CreateRoleGrant(adminCtx, username, grantParams)
+	s.Require().Nil(err)
+	s.Require().Nil(s.Store.DeleteOrganization(adminCtx, org.ID))
+	user, err = s.Store.GetUser(adminCtx, username)
+	s.Require().Nil(err)
+	s.Require().Empty(user.Grants)
+}
+
+func (s *UserTestSuite) TestCreateRoleGrantEntityNotFound() {
+	_, err := s.Store.CreateRoleGrant(context.Background(), s.Fixtures.Users[0].Username, params.CreateRoleGrantParams{
+		Role:       params.UserRoleViewer,
+		EntityType: params.GithubEntityTypeOrganization,
+		EntityID:   "9cab8f2c-6b0e-4f0e-8c3f-5c2f1c1f4d1e",
+	})
+
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+}
+
+func (s *UserTestSuite) TestAPITokens() {
+	ctx := context.Background()
+	owner := s.Fixtures.Users[0]
+	other := s.Fixtures.Users[1]
+	param := params.CreateAPITokenParams{
+		Name:    "ci",
+		Scopes:  []params.APITokenScope{params.APITokenScopePoolsWrite},
+		TTLDays: 30,
+	}
+
+	token, err := s.Store.CreateAPIToken(ctx, owner.ID, param, "test-hash")
+	s.Require().Nil(err)
+	s.Require().Equal(owner.ID, token.UserID)
+	s.Require().Equal(param.Scopes, token.Scopes)
+	s.Require().NotNil(token.ExpiresAt)
+	s.Require().Empty(token.Token)
+
+	_, err = s.Store.CreateAPIToken(ctx, owner.ID, param, "other-hash")
+	var conflictErr *runnerErrors.ConflictError
+	s.Require().ErrorAs(err, &conflictErr)
+
+	fetched, err := s.Store.GetAPITokenByHash(ctx, "test-hash")
+	s.Require().Nil(err)
+	s.Require().Equal(token.ID, fetched.ID)
+	_, err = s.Store.GetAPITokenByHash(ctx, "missing-hash")
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+
+	lastUsed := time.Now().UTC().Truncate(time.Second)
+	s.Require().Nil(s.Store.SetAPITokenLastUsed(ctx, token.ID, lastUsed))
+	tokens, err := s.Store.ListAPITokens(ctx, owner.ID)
+	s.Require().Nil(err)
+	s.Require().Len(tokens, 1)
+	s.Require().True(lastUsed.Equal(*tokens[0].LastUsedAt))
+
+	tokens, err = s.Store.ListAPITokens(ctx, other.ID)
+	s.Require().Nil(err)
+	s.Require().Empty(tokens)
+
+	// Users can only revoke their own tokens.
+	s.Require().Nil(s.Store.DeleteAPIToken(ctx, other.ID, token.ID))
+	tokens, err = s.Store.ListAPITokens(ctx, "")
+	s.Require().Nil(err)
+	s.Require().Len(tokens, 1)
+
+	s.Require().Nil(s.Store.DeleteAPIToken(ctx, owner.ID, token.ID))
+	_, err = s.Store.GetAPITokenByHash(ctx, "test-hash")
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+}
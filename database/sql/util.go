@@ -15,8 +15,11 @@
 package sql
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -25,8 +28,9 @@ import (
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
-	"github.com/cloudbase/garm-provider-common/util"
+	"github.com/cloudbase/garm/config"
 	dbCommon "github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/database/secrets"
 	"github.com/cloudbase/garm/params"
 )
 
@@ -65,11 +69,17 @@ func (s *sqlDatabase) sqlToParamsInstance(instance Instance) (params.Instance, e
 		MetadataURL:       instance.MetadataURL,
 		StatusMessages:    []params.StatusMessage{},
 		CreateAttempt:     instance.CreateAttempt,
+		CreatedAt:         instance.CreatedAt,
 		UpdatedAt:         instance.UpdatedAt,
 		TokenFetched:      instance.TokenFetched,
 		JitConfiguration:  jitConfig,
 		GitHubRunnerGroup: instance.GitHubRunnerGroup,
 		AditionalLabels:   labels,
+		Image:             instance.Image,
+		Flavor:            instance.Flavor,
+
+		JitRegistrationRemoved:  instance.JitRegistrationRemoved,
+		InstanceTokenGeneration: instance.InstanceTokenGeneration,
 	}
 
 	if instance.Job != nil {
@@ -82,6 +92,15 @@ func (s *sqlDatabase) sqlToParamsInstance(instance Instance) (params.Instance, e
 
 	if len(instance.ProviderFault) > 0 {
 		ret.ProviderFault = instance.ProviderFault
+		ret.ProviderFaultAt = instance.ProviderFaultAt
+	}
+
+	if len(instance.DiskUsage) > 0 {
+		var diskUsage params.InstanceDiskUsage
+		if err := json.Unmarshal(instance.DiskUsage, &diskUsage); err != nil {
+			return params.Instance{}, errors.Wrap(err, "unmarshaling disk usage")
+		}
+		ret.DiskUsage = &diskUsage
 	}
 
 	for _, addr := range instance.Addresses {
@@ -96,6 +115,10 @@ func (s *sqlDatabase) sqlToParamsInstance(instance Instance) (params.Instance, e
 			EventLevel: msg.EventLevel,
 		})
 	}
+	// Only the most recent messages are loaded, newest first. Return them oldest first.
+	sort.SliceStable(ret.StatusMessages, func(i, j int) bool {
+		return ret.StatusMessages[i].CreatedAt.Before(ret.StatusMessages[j].CreatedAt)
+	})
 	return ret, nil
 }
 
@@ -106,14 +129,51 @@ func (s *sqlDatabase) sqlAddressToParamsAddress(addr Address) commonParams.Addre
 	}
 }
 
+// updateWebhookSecret encrypts the new webhook secret of an entity. If a grace period is
+// set, the secret it replaces is kept as the previous secret until the grace period ends.
+// Otherwise, any previous secret is removed.
+func (s *sqlDatabase) updateWebhookSecret(param params.UpdateEntityParams, secret, previous *[]byte, previousExpiresAt **time.Time) error {
+	newSecret, err := s.seal([]byte(param.WebhookSecret))
+	if err != nil {
+		return err
+	}
+
+	*previous = nil
+	*previousExpiresAt = nil
+	if param.WebhookSecretGracePeriod > 0 && len(*secret) > 0 {
+		expiresAt := time.Now().Add(time.Duration(param.WebhookSecretGracePeriod) * time.Minute)
+		*previous = *secret
+		*previousExpiresAt = &expiresAt
+	}
+	*secret = newSecret
+	return nil
+}
+
+// unsealPreviousWebhookSecret decrypts the previous webhook secret of an entity. Once its
+// grace period ended, the previous secret is no longer returned.
+func (s *sqlDatabase) unsealPreviousWebhookSecret(sealed []byte, expiresAt *time.Time) (string, *time.Time, error) {
+	if len(sealed) == 0 || expiresAt == nil || !time.Now().Before(*expiresAt) {
+		return "", nil, nil
+	}
+	secret, err := s.unseal(sealed)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(secret), expiresAt, nil
+}
+
 func (s *sqlDatabase) sqlToCommonOrganization(org Organization, detailed bool) (params.Organization, error) {
 	if len(org.WebhookSecret) == 0 {
 		return params.Organization{}, errors.New("missing secret")
 	}
-	secret, err := util.Unseal(org.WebhookSecret, []byte(s.cfg.Passphrase))
+	secret, err := s.unseal(org.WebhookSecret)
 	if err != nil {
 		return params.Organization{}, errors.Wrap(err, "decrypting secret")
 	}
+	previousSecret, previousExpiresAt, err := s.unsealPreviousWebhookSecret(org.PreviousWebhookSecret, org.PreviousWebhookSecretExpiresAt)
+	if err != nil {
+		return params.Organization{}, errors.Wrap(err, "decrypting previous secret")
+	}
 
 	endpoint, err := s.sqlToCommonGithubEndpoint(org.Endpoint)
 	if err != nil {
@@ -127,8 +187,20 @@ func (s *sqlDatabase) sqlToCommonOrganization(org Organization, detailed bool) (
 		WebhookSecret:    string(secret),
 		PoolBalancerType: org.PoolBalancerType,
 		Endpoint:         endpoint,
+
+		PreviousWebhookSecret:          previousSecret,
+		PreviousWebhookSecretExpiresAt: previousExpiresAt,
+
+		MaxConcurrentJobs: org.MaxConcurrentJobs,
+		EntityWebhookPath: org.EntityWebhookPath,
 	}
 
+	routingRules, err := unmarshalRoutingRules(org.RoutingRules)
+	if err != nil {
+		return params.Organization{}, errors.Wrap(err, "unmarshaling routing rules")
+	}
+	ret.RoutingRules = routingRules
+
 	if org.CredentialsID != nil {
 		ret.CredentialsID = *org.CredentialsID
 	}
@@ -152,6 +224,23 @@ func (s *sqlDatabase) sqlToCommonOrganization(org Organization, detailed bool) (
 		}
 	}
 
+	for _, event := range org.Events {
+		ret.Events = append(ret.Events, params.StatusMessage{
+			CreatedAt:  event.CreatedAt,
+			Message:    event.Message,
+			EventType:  event.EventType,
+			EventLevel: event.EventLevel,
+		})
+	}
+
+	if len(org.PendingWebhookInstall) > 0 {
+		var pending params.InstallWebhookParams
+		if err := json.Unmarshal(org.PendingWebhookInstall, &pending); err != nil {
+			return params.Organization{}, errors.Wrap(err, "unmarshaling pending webhook install")
+		}
+		ret.PendingWebhookInstall = &pending
+	}
+
 	return ret, nil
 }
 
@@ -159,10 +248,14 @@ func (s *sqlDatabase) sqlToCommonEnterprise(enterprise Enterprise, detailed bool
 	if len(enterprise.WebhookSecret) == 0 {
 		return params.Enterprise{}, errors.New("missing secret")
 	}
-	secret, err := util.Unseal(enterprise.WebhookSecret, []byte(s.cfg.Passphrase))
+	secret, err := s.unseal(enterprise.WebhookSecret)
 	if err != nil {
 		return params.Enterprise{}, errors.Wrap(err, "decrypting secret")
 	}
+	previousSecret, previousExpiresAt, err := s.unsealPreviousWebhookSecret(enterprise.PreviousWebhookSecret, enterprise.PreviousWebhookSecretExpiresAt)
+	if err != nil {
+		return params.Enterprise{}, errors.Wrap(err, "decrypting previous secret")
+	}
 
 	endpoint, err := s.sqlToCommonGithubEndpoint(enterprise.Endpoint)
 	if err != nil {
@@ -176,8 +269,19 @@ func (s *sqlDatabase) sqlToCommonEnterprise(enterprise Enterprise, detailed bool
 		WebhookSecret:    string(secret),
 		PoolBalancerType: enterprise.PoolBalancerType,
 		Endpoint:         endpoint,
+
+		PreviousWebhookSecret:          previousSecret,
+		PreviousWebhookSecretExpiresAt: previousExpiresAt,
+
+		MaxConcurrentJobs: enterprise.MaxConcurrentJobs,
 	}
 
+	routingRules, err := unmarshalRoutingRules(enterprise.RoutingRules)
+	if err != nil {
+		return params.Enterprise{}, errors.Wrap(err, "unmarshaling routing rules")
+	}
+	ret.RoutingRules = routingRules
+
 	if enterprise.CredentialsID != nil {
 		ret.CredentialsID = *enterprise.CredentialsID
 	}
@@ -201,6 +305,15 @@ func (s *sqlDatabase) sqlToCommonEnterprise(enterprise Enterprise, detailed bool
 		}
 	}
 
+	for _, event := range enterprise.Events {
+		ret.Events = append(ret.Events, params.StatusMessage{
+			CreatedAt:  event.CreatedAt,
+			Message:    event.Message,
+			EventType:  event.EventType,
+			EventLevel: event.EventLevel,
+		})
+	}
+
 	return ret, nil
 }
 
@@ -224,6 +337,21 @@ func (s *sqlDatabase) sqlToCommonPool(pool Pool) (params.Pool, error) {
 		ExtraSpecs:             json.RawMessage(pool.ExtraSpecs),
 		GitHubRunnerGroup:      pool.GitHubRunnerGroup,
 		Priority:               pool.Priority,
+
+		InstanceTokenGeneration: pool.InstanceTokenGeneration,
+		IdleDetectionWindow:     pool.IdleDetectionWindow,
+		ScaleDownGracePeriod:    pool.ScaleDownGracePeriod,
+		ScaleDownFactor:         pool.ScaleDownFactor,
+
+		CapacityWarningThreshold: pool.CapacityWarningThreshold,
+		AutoscaleMaxBurst:        pool.AutoscaleMaxBurst,
+		AutoscaleCooldown:        pool.AutoscaleCooldown,
+		MaxCreatesPerMinute:      pool.MaxCreatesPerMinute,
+		CreateJitter:             pool.CreateJitter,
+		RollingUpdateBatchSize:   pool.RollingUpdateBatchSize,
+		RollingUpdatePause:       pool.RollingUpdatePause,
+		Draining:                 pool.Draining,
+		URLSet:                   pool.URLSet,
 	}
 
 	if pool.RepoID != nil {
@@ -255,9 +383,117 @@ func (s *sqlDatabase) sqlToCommonPool(pool Pool) (params.Pool, error) {
 		}
 	}
 
+	if len(pool.CleanupPolicy) > 0 {
+		var policy params.CleanupPolicy
+		if err := json.Unmarshal(pool.CleanupPolicy, &policy); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling cleanup policy")
+		}
+		ret.CleanupPolicy = &policy
+	}
+
+	if len(pool.NetworkSettings) > 0 {
+		var settings params.NetworkSettings
+		if err := json.Unmarshal(pool.NetworkSettings, &settings); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling network settings")
+		}
+		ret.NetworkSettings = &settings
+	}
+
+	if len(pool.Schedule) > 0 {
+		var schedule params.PoolSchedule
+		if err := json.Unmarshal(pool.Schedule, &schedule); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling schedule")
+		}
+		ret.Schedule = &schedule
+	}
+
+	if len(pool.InstanceMetadata) > 0 {
+		if err := json.Unmarshal(pool.InstanceMetadata, &ret.InstanceMetadata); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling instance metadata")
+		}
+	}
+
+	if len(pool.WarmImage) > 0 {
+		var settings params.WarmImageSettings
+		if err := json.Unmarshal(pool.WarmImage, &settings); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling warm image settings")
+		}
+		ret.WarmImage = &settings
+	}
+
+	if len(pool.WarmImageStatus) > 0 {
+		var status params.WarmImageStatus
+		if err := json.Unmarshal(pool.WarmImageStatus, &status); err != nil {
+			return params.Pool{}, errors.Wrap(err, "unmarshaling warm image status")
+		}
+		ret.WarmImageStatus = &status
+	}
+
 	return ret, nil
 }
 
+// cleanupPolicyToJSON serializes a pool cleanup policy. Empty policies are stored as null.
+func cleanupPolicyToJSON(policy *params.CleanupPolicy) (datatypes.JSON, error) {
+	if policy == nil || policy.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(policy)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling cleanup policy")
+	}
+	return datatypes.JSON(asJs), nil
+}
+
+// poolScheduleToJSON serializes a pool schedule. Empty schedules are stored as null.
+func poolScheduleToJSON(schedule *params.PoolSchedule) (datatypes.JSON, error) {
+	if schedule == nil || schedule.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling schedule")
+	}
+	return datatypes.JSON(asJs), nil
+}
+
+// networkSettingsToJSON serializes pool network settings. Empty settings are stored as null.
+func networkSettingsToJSON(settings *params.NetworkSettings) (datatypes.JSON, error) {
+	if settings == nil || settings.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling network settings")
+	}
+	return datatypes.JSON(asJs), nil
+}
+
+// instanceMetadataToJSON serializes the instance metadata of a pool. Empty metadata is
+// stored as null.
+func instanceMetadataToJSON(metadata params.InstanceMetadata) (datatypes.JSON, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling instance metadata")
+	}
+	return datatypes.JSON(asJs), nil
+}
+
+// warmImageToJSON serializes the warm image settings of a pool. Empty settings are
+// stored as null.
+func warmImageToJSON(settings *params.WarmImageSettings) (datatypes.JSON, error) {
+	if settings == nil || settings.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling warm image settings")
+	}
+	return datatypes.JSON(asJs), nil
+}
+
 func (s *sqlDatabase) sqlToCommonTags(tag Tag) params.Tag {
 	return params.Tag{
 		ID:   tag.ID.String(),
@@ -269,10 +505,14 @@ func (s *sqlDatabase) sqlToCommonRepository(repo Repository, detailed bool) (par
 	if len(repo.WebhookSecret) == 0 {
 		return params.Repository{}, errors.New("missing secret")
 	}
-	secret, err := util.Unseal(repo.WebhookSecret, []byte(s.cfg.Passphrase))
+	secret, err := s.unseal(repo.WebhookSecret)
 	if err != nil {
 		return params.Repository{}, errors.Wrap(err, "decrypting secret")
 	}
+	previousSecret, previousExpiresAt, err := s.unsealPreviousWebhookSecret(repo.PreviousWebhookSecret, repo.PreviousWebhookSecretExpiresAt)
+	if err != nil {
+		return params.Repository{}, errors.Wrap(err, "decrypting previous secret")
+	}
 	endpoint, err := s.sqlToCommonGithubEndpoint(repo.Endpoint)
 	if err != nil {
 		return params.Repository{}, errors.Wrap(err, "converting endpoint")
@@ -286,7 +526,19 @@ func (s *sqlDatabase) sqlToCommonRepository(repo Repository, detailed bool) (par
 		WebhookSecret:    string(secret),
 		PoolBalancerType: repo.PoolBalancerType,
 		Endpoint:         endpoint,
+
+		PreviousWebhookSecret:          previousSecret,
+		PreviousWebhookSecretExpiresAt: previousExpiresAt,
+
+		MaxConcurrentJobs: repo.MaxConcurrentJobs,
+		EntityWebhookPath: repo.EntityWebhookPath,
+	}
+
+	routingRules, err := unmarshalRoutingRules(repo.RoutingRules)
+	if err != nil {
+		return params.Repository{}, errors.Wrap(err, "unmarshaling routing rules")
 	}
+	ret.RoutingRules = routingRules
 
 	if repo.CredentialsID != nil {
 		ret.CredentialsID = *repo.CredentialsID
@@ -311,10 +563,35 @@ func (s *sqlDatabase) sqlToCommonRepository(repo Repository, detailed bool) (par
 		}
 	}
 
+	for _, event := range repo.Events {
+		ret.Events = append(ret.Events, params.StatusMessage{
+			CreatedAt:  event.CreatedAt,
+			Message:    event.Message,
+			EventType:  event.EventType,
+			EventLevel: event.EventLevel,
+		})
+	}
+
+	if len(repo.PendingWebhookInstall) > 0 {
+		var pending params.InstallWebhookParams
+		if err := json.Unmarshal(repo.PendingWebhookInstall, &pending); err != nil {
+			return params.Repository{}, errors.Wrap(err, "unmarshaling pending webhook install")
+		}
+		ret.PendingWebhookInstall = &pending
+	}
+
 	return ret, nil
 }
 
 func (s *sqlDatabase) sqlToParamsUser(user User) params.User {
+	role := user.Role
+	if user.IsAdmin {
+		role = params.UserRoleAdmin
+	}
+	grants := make([]params.RoleGrant, len(user.Grants))
+	for idx, grant := range user.Grants {
+		grants[idx] = sqlToParamsRoleGrant(grant)
+	}
 	return params.User{
 		ID:         user.ID.String(),
 		CreatedAt:  user.CreatedAt,
@@ -326,12 +603,26 @@ func (s *sqlDatabase) sqlToParamsUser(user User) params.User {
 		Enabled:    user.Enabled,
 		IsAdmin:    user.IsAdmin,
 		Generation: user.Generation,
+
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		LockedUntil:         user.LockedUntil,
+		Role:                role,
+		Grants:              grants,
+	}
+}
+
+// equalsNoCase returns a case insensitive comparison of column with a query argument.
+// PostgreSQL does not have a NOCASE collation.
+func (s *sqlDatabase) equalsNoCase(column string) string {
+	if s.cfg.DbBackend == config.PostgresBackend {
+		return fmt.Sprintf("lower(%s) = lower(?)", column)
 	}
+	return fmt.Sprintf("%s = ? COLLATE NOCASE", column)
 }
 
 func (s *sqlDatabase) getOrCreateTag(tx *gorm.DB, tagName string) (Tag, error) {
 	var tag Tag
-	q := tx.Where("name = ? COLLATE NOCASE", tagName).First(&tag)
+	q := tx.Where(s.equalsNoCase("name"), tagName).First(&tag)
 	if q.Error == nil {
 		return tag, nil
 	}
@@ -397,6 +688,119 @@ func (s *sqlDatabase) updatePool(tx *gorm.DB, pool Pool, param params.UpdatePool
 		pool.Priority = *param.Priority
 	}
 
+	if param.IdleDetectionWindow != nil {
+		pool.IdleDetectionWindow = *param.IdleDetectionWindow
+	}
+
+	if param.ScaleDownGracePeriod != nil {
+		pool.ScaleDownGracePeriod = *param.ScaleDownGracePeriod
+	}
+
+	if param.ScaleDownFactor != nil {
+		pool.ScaleDownFactor = *param.ScaleDownFactor
+	}
+
+	if param.CapacityWarningThreshold != nil {
+		pool.CapacityWarningThreshold = *param.CapacityWarningThreshold
+	}
+
+	if param.AutoscaleMaxBurst != nil {
+		pool.AutoscaleMaxBurst = *param.AutoscaleMaxBurst
+	}
+
+	if param.AutoscaleCooldown != nil {
+		pool.AutoscaleCooldown = *param.AutoscaleCooldown
+	}
+
+	if param.MaxCreatesPerMinute != nil {
+		pool.MaxCreatesPerMinute = *param.MaxCreatesPerMinute
+	}
+
+	if param.CreateJitter != nil {
+		pool.CreateJitter = *param.CreateJitter
+	}
+
+	if param.RollingUpdateBatchSize != nil {
+		pool.RollingUpdateBatchSize = *param.RollingUpdateBatchSize
+	}
+
+	if param.RollingUpdatePause != nil {
+		pool.RollingUpdatePause = *param.RollingUpdatePause
+	}
+
+	if param.Draining != nil {
+		pool.Draining = *param.Draining
+	}
+
+	if param.URLSet != nil {
+		if err := s.validateURLSet(tx, *param.URLSet); err != nil {
+			return params.Pool{}, err
+		}
+		pool.URLSet = *param.URLSet
+	}
+
+	if param.CleanupPolicy != nil {
+		policy, err := cleanupPolicyToJSON(param.CleanupPolicy)
+		if err != nil {
+			return params.Pool{}, errors.Wrap(err, "updating cleanup policy")
+		}
+		pool.CleanupPolicy = policy
+	}
+
+	if param.NetworkSettings != nil {
+		settings, err := networkSettingsToJSON(param.NetworkSettings)
+		if err != nil {
+			return params.Pool{}, errors.Wrap(err, "updating network settings")
+		}
+		pool.NetworkSettings = settings
+	}
+
+	if param.InstanceMetadata != nil {
+		metadata, err := instanceMetadataToJSON(*param.InstanceMetadata)
+		if err != nil {
+			return params.Pool{}, errors.Wrap(err, "updating instance metadata")
+		}
+		pool.InstanceMetadata = metadata
+	}
+
+	if param.WarmImage != nil {
+		settings, err := warmImageToJSON(param.WarmImage)
+		if err != nil {
+			return params.Pool{}, errors.Wrap(err, "updating warm image settings")
+		}
+		switch {
+		case settings == nil:
+			// Warm images are disabled. Forget the last one, so runners go back to
+			// the image of the pool.
+			pool.WarmImageStatus = nil
+		case len(pool.WarmImageStatus) > 0 && !bytes.Equal(settings, pool.WarmImage):
+			// The settings changed. Keep using the current warm image, but rebuild
+			// it right away.
+			var status params.WarmImageStatus
+			if err := json.Unmarshal(pool.WarmImageStatus, &status); err != nil {
+				return params.Pool{}, errors.Wrap(err, "unmarshaling warm image status")
+			}
+			status.LastAttemptAt = time.Time{}
+			asJs, err := json.Marshal(status)
+			if err != nil {
+				return params.Pool{}, errors.Wrap(err, "marshaling warm image status")
+			}
+			pool.WarmImageStatus = asJs
+		}
+		pool.WarmImage = settings
+	}
+
+	if param.Schedule != nil {
+		schedule, err := poolScheduleToJSON(param.Schedule)
+		if err != nil {
+			return params.Pool{}, errors.Wrap(err, "updating schedule")
+		}
+		pool.Schedule = schedule
+	}
+
+	// Rotate the key used to sign instance tokens.
+	pool.InstanceTokenGeneration++
+
 	if q := tx.Save(&pool); q.Error != nil {
 		return params.Pool{}, errors.Wrap(q.Error, "saving database entry")
 	}
@@ -470,16 +874,33 @@ func (s *sqlDatabase) hasGithubEntity(tx *gorm.DB, entityType params.GithubEntit
 	return nil
 }
 
+// secretsBackend returns the backend used to seal sensitive info. Stores that were
+// not created by NewSQLDatabase use the database passphrase.
+func (s *sqlDatabase) secretsBackend() secrets.Backend {
+	if s.secrets != nil {
+		return s.secrets
+	}
+	return secrets.NewPassphraseBackend(s.cfg.Passphrase)
+}
+
+func (s *sqlDatabase) seal(data []byte) ([]byte, error) {
+	return s.secretsBackend().Seal(data)
+}
+
+func (s *sqlDatabase) unseal(data []byte) ([]byte, error) {
+	return s.secretsBackend().Unseal(data)
+}
+
 func (s *sqlDatabase) marshalAndSeal(data interface{}) ([]byte, error) {
 	enc, err := json.Marshal(data)
 	if err != nil {
 		return nil, errors.Wrap(err, "marshalling data")
 	}
-	return util.Seal(enc, []byte(s.cfg.Passphrase))
+	return s.seal(enc)
 }
 
 func (s *sqlDatabase) unsealAndUnmarshal(data []byte, target interface{}) error {
-	decrypted, err := util.Unseal(data, []byte(s.cfg.Passphrase))
+	decrypted, err := s.unseal(data)
 	if err != nil {
 		return errors.Wrap(err, "decrypting data")
 	}
@@ -504,3 +925,25 @@ func (s *sqlDatabase) sendNotify(entityType dbCommon.DatabaseEntityType, op dbCo
 	}
 	return s.producer.Notify(message)
 }
+
+func marshalRoutingRules(rules []params.RoutingRule) (datatypes.JSON, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	asJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	return asJSON, nil
+}
+
+func unmarshalRoutingRules(data datatypes.JSON) ([]params.RoutingRule, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var rules []params.RoutingRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
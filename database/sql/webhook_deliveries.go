@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/params"
+)
+
+var _ common.WebhookDeliveryStore = &sqlDatabase{}
+
+func sqlToParamsWebhookDelivery(delivery WebhookDelivery) (params.WebhookDelivery, error) {
+	ret := params.WebhookDelivery{
+		ID:             delivery.ID.String(),
+		DeliveryID:     delivery.DeliveryID,
+		EntityID:       delivery.EntityID,
+		TargetEntityID: delivery.TargetEntityID,
+		PayloadHash:    delivery.PayloadHash,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Error:          delivery.Error,
+		Attempts:       delivery.Attempts,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+	if len(delivery.Headers) > 0 {
+		if err := json.Unmarshal(delivery.Headers, &ret.Headers); err != nil {
+			return params.WebhookDelivery{}, errors.Wrap(err, "unmarshaling headers")
+		}
+	}
+	return ret, nil
+}
+
+func (s *sqlDatabase) getWebhookDelivery(deliveryID string) (WebhookDelivery, error) {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return WebhookDelivery{}, errors.Wrap(runnerErrors.ErrBadRequest, "parsing id")
+	}
+
+	var delivery WebhookDelivery
+	q := s.conn.Model(&WebhookDelivery{}).Where("id = ?", id).First(&delivery)
+	if q.Error != nil {
+		if errors.Is(q.Error, gorm.ErrRecordNotFound) {
+			return WebhookDelivery{}, errors.Wrap(runnerErrors.ErrNotFound, "fetching webhook delivery")
+		}
+		return WebhookDelivery{}, errors.Wrap(q.Error, "fetching webhook delivery")
+	}
+	return delivery, nil
+}
+
+func (s *sqlDatabase) CreateWebhookDelivery(_ context.Context, delivery params.WebhookDelivery) (params.WebhookDelivery, error) {
+	newDelivery := WebhookDelivery{
+		DeliveryID:     delivery.DeliveryID,
+		EntityID:       delivery.EntityID,
+		TargetEntityID: delivery.TargetEntityID,
+		PayloadHash:    delivery.PayloadHash,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Error:          delivery.Error,
+		Attempts:       1,
+	}
+	if len(delivery.Headers) > 0 {
+		headers, err := json.Marshal(delivery.Headers)
+		if err != nil {
+			return params.WebhookDelivery{}, errors.Wrap(err, "marshaling headers")
+		}
+		newDelivery.Headers = headers
+	}
+	if q := s.conn.Create(&newDelivery); q.Error != nil {
+		return params.WebhookDelivery{}, errors.Wrap(q.Error, "creating webhook delivery")
+	}
+	return sqlToParamsWebhookDelivery(newDelivery)
+}
+
+func (s *sqlDatabase) GetWebhookDelivery(_ context.Context, deliveryID string) (params.WebhookDelivery, error) {
+	delivery, err := s.getWebhookDelivery(deliveryID)
+	if err != nil {
+		return params.WebhookDelivery{}, errors.Wrap(err, "fetching webhook delivery")
+	}
+	return sqlToParamsWebhookDelivery(delivery)
+}
+
+func (s *sqlDatabase) UpdateWebhookDeliveryResult(_ context.Context, deliveryID, entityID string, status params.WebhookDeliveryStatus, deliveryErr string) (params.WebhookDelivery, error) {
+	delivery, err := s.getWebhookDelivery(deliveryID)
+	if err != nil {
+		return params.WebhookDelivery{}, errors.Wrap(err, "fetching webhook delivery")
+	}
+
+	if entityID != "" {
+		delivery.EntityID = entityID
+	}
+	delivery.Status = status
+	delivery.Error = deliveryErr
+	delivery.Attempts++
+	if q := s.conn.Save(&delivery); q.Error != nil {
+		return params.WebhookDelivery{}, errors.Wrap(q.Error, "saving webhook delivery")
+	}
+	return sqlToParamsWebhookDelivery(delivery)
+}
+
+func (s *sqlDatabase) ListWebhookDeliveries(_ context.Context, param params.ListWebhookDeliveriesParams) (params.WebhookDeliveriesPage, error) {
+	if err := param.Validate(); err != nil {
+		return params.WebhookDeliveriesPage{}, errors.Wrap(err, "validating params")
+	}
+
+	q := s.conn.Model(&WebhookDelivery{})
+	if param.EntityID != "" {
+		q = q.Where("entity_id = ?", param.EntityID)
+	}
+	if param.Status != "" {
+		q = q.Where("status = ?", param.Status)
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return params.WebhookDeliveriesPage{}, errors.Wrap(err, "counting webhook deliveries")
+	}
+
+	var deliveries []WebhookDelivery
+	if err := q.Omit("payload").
+		Order("created_at desc").
+		Offset(int((param.Page - 1) * param.PageSize)).
+		Limit(int(param.PageSize)).
+		Find(&deliveries).Error; err != nil {
+		return params.WebhookDeliveriesPage{}, errors.Wrap(err, "fetching webhook deliveries")
+	}
+
+	total := uint(count)
+	ret := params.WebhookDeliveriesPage{
+		Deliveries: make([]params.WebhookDelivery, len(deliveries)),
+		Page:       param.Page,
+		PageSize:   param.PageSize,
+		TotalCount: total,
+		TotalPages: (total + param.PageSize - 1) / param.PageSize,
+	}
+	for idx, delivery := range deliveries {
+		asParams, err := sqlToParamsWebhookDelivery(delivery)
+		if err != nil {
+			return params.WebhookDeliveriesPage{}, errors.Wrap(err, "converting webhook delivery")
+		}
+		ret.Deliveries[idx] = asParams
+	}
+	return ret, nil
+}
+
+// DeleteWebhookDeliveriesBefore removes the webhook deliveries received before the given time.
+func (s *sqlDatabase) DeleteWebhookDeliveriesBefore(_ context.Context, before time.Time) error {
+	if q := s.conn.Unscoped().Where("created_at < ?", before).Delete(&WebhookDelivery{}); q.Error != nil {
+		return errors.Wrap(q.Error, "deleting webhook deliveries")
+	}
+	return nil
+}
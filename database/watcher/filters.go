@@ -1,6 +1,9 @@
 package watcher
 
 import (
+	"sync"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 )
@@ -161,6 +164,112 @@ func WithEntityJobFilter(ghEntity params.GithubEntity) dbCommon.PayloadFilterFun
 	}
 }
 
+// WithInstancePoolFilter returns a filter function that matches instances that belong
+// to any of the supplied pools.
+func WithInstancePoolFilter(poolIDs ...string) dbCommon.PayloadFilterFunc {
+	return func(payload dbCommon.ChangePayload) bool {
+		if payload.EntityType != dbCommon.InstanceEntityType {
+			return false
+		}
+		instance, ok := payload.Payload.(params.Instance)
+		if !ok {
+			return false
+		}
+		for _, poolID := range poolIDs {
+			if instance.PoolID == poolID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithInstanceStatusFilter returns a filter function that matches instances in any of
+// the supplied statuses.
+func WithInstanceStatusFilter(statuses ...commonParams.InstanceStatus) dbCommon.PayloadFilterFunc {
+	return func(payload dbCommon.ChangePayload) bool {
+		if payload.EntityType != dbCommon.InstanceEntityType {
+			return false
+		}
+		instance, ok := payload.Payload.(params.Instance)
+		if !ok {
+			return false
+		}
+		for _, status := range statuses {
+			if instance.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithJobStatusFilter returns a filter function that matches jobs in any of the
+// supplied statuses.
+func WithJobStatusFilter(statuses ...params.JobStatus) dbCommon.PayloadFilterFunc {
+	return func(payload dbCommon.ChangePayload) bool {
+		if payload.EntityType != dbCommon.JobEntityType {
+			return false
+		}
+		job, ok := payload.Payload.(params.Job)
+		if !ok {
+			return false
+		}
+		for _, status := range statuses {
+			if job.Status == string(status) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithEntityInstanceFilter returns a filter function that matches instances belonging to
+// pools of the supplied entity. Instance payloads do not hold a reference to the entity,
+// so the filter needs the IDs of the entity pools that exist when it is created. Pools
+// that are created or deleted afterwards are tracked by looking at pool change payloads.
+// For this to work, the filter must see pool payloads, so it should not be placed after
+// filters that would short circuit on them.
+func WithEntityInstanceFilter(ghEntity params.GithubEntity, poolIDs ...string) dbCommon.PayloadFilterFunc {
+	var mux sync.Mutex
+	pools := make(map[string]struct{}, len(poolIDs))
+	for _, poolID := range poolIDs {
+		pools[poolID] = struct{}{}
+	}
+	entityPoolFilter := WithEntityPoolFilter(ghEntity)
+
+	return func(payload dbCommon.ChangePayload) bool {
+		mux.Lock()
+		defer mux.Unlock()
+
+		switch payload.EntityType {
+		case dbCommon.PoolEntityType:
+			pool, ok := payload.Payload.(params.Pool)
+			if !ok {
+				return false
+			}
+			switch payload.Operation {
+			case dbCommon.CreateOperation:
+				if entityPoolFilter(payload) {
+					pools[pool.ID] = struct{}{}
+				}
+			case dbCommon.DeleteOperation:
+				delete(pools, pool.ID)
+			}
+			return false
+		case dbCommon.InstanceEntityType:
+			instance, ok := payload.Payload.(params.Instance)
+			if !ok {
+				return false
+			}
+			_, ok = pools[instance.PoolID]
+			return ok
+		default:
+			return false
+		}
+	}
+}
+
 // WithGithubCredentialsFilter returns a filter function that filters payloads by Github credentials.
 func WithGithubCredentialsFilter(creds params.GithubCredentials) dbCommon.PayloadFilterFunc {
 	return func(payload dbCommon.ChangePayload) bool {
@@ -175,6 +284,20 @@ func WithGithubCredentialsFilter(creds params.GithubCredentials) dbCommon.Payloa
 	}
 }
 
+// WithGithubEndpointFilter returns a filter function that filters payloads by Github endpoint.
+func WithGithubEndpointFilter(endpoint params.GithubEndpoint) dbCommon.PayloadFilterFunc {
+	return func(payload dbCommon.ChangePayload) bool {
+		if payload.EntityType != dbCommon.GithubEndpointEntityType {
+			return false
+		}
+		epPayload, ok := payload.Payload.(params.GithubEndpoint)
+		if !ok {
+			return false
+		}
+		return epPayload.Name == endpoint.Name
+	}
+}
+
 // WithUserIDFilter returns a filter function that filters payloads by user ID.
 func WithUserIDFilter(userID string) dbCommon.PayloadFilterFunc {
 	return func(payload dbCommon.ChangePayload) bool {
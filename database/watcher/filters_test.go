@@ -0,0 +1,91 @@
+//go:build testing
+
+package watcher_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/database/watcher"
+	"github.com/cloudbase/garm/params"
+)
+
+func instancePayload(poolID string, status commonParams.InstanceStatus) common.ChangePayload {
+	return common.ChangePayload{
+		EntityType: common.InstanceEntityType,
+		Operation:  common.UpdateOperation,
+		Payload: params.Instance{
+			Name:   "test-instance",
+			PoolID: poolID,
+			Status: status,
+		},
+	}
+}
+
+func TestInstancePoolAndStatusFilters(t *testing.T) {
+	poolFilter := watcher.WithInstancePoolFilter("pool-1", "pool-2")
+	require.True(t, poolFilter(instancePayload("pool-2", commonParams.InstanceRunning)))
+	require.False(t, poolFilter(instancePayload("pool-3", commonParams.InstanceRunning)))
+	require.False(t, poolFilter(common.ChangePayload{
+		EntityType: common.PoolEntityType,
+		Payload:    params.Pool{ID: "pool-1"},
+	}))
+
+	statusFilter := watcher.WithInstanceStatusFilter(commonParams.InstanceError)
+	require.True(t, statusFilter(instancePayload("pool-1", commonParams.InstanceError)))
+	require.False(t, statusFilter(instancePayload("pool-1", commonParams.InstanceRunning)))
+}
+
+func TestEntityInstanceFilterTracksPools(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "repo-1",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	filter := watcher.WithEntityInstanceFilter(entity, "pool-1")
+
+	require.True(t, filter(instancePayload("pool-1", commonParams.InstanceRunning)))
+	require.False(t, filter(instancePayload("pool-2", commonParams.InstanceRunning)))
+
+	// A pool created for another entity is ignored.
+	require.False(t, filter(common.ChangePayload{
+		EntityType: common.PoolEntityType,
+		Operation:  common.CreateOperation,
+		Payload:    params.Pool{ID: "pool-3", RepoID: "repo-2"},
+	}))
+	require.False(t, filter(instancePayload("pool-3", commonParams.InstanceRunning)))
+
+	// A pool created for our entity is tracked.
+	require.False(t, filter(common.ChangePayload{
+		EntityType: common.PoolEntityType,
+		Operation:  common.CreateOperation,
+		Payload:    params.Pool{ID: "pool-2", RepoID: "repo-1"},
+	}))
+	require.True(t, filter(instancePayload("pool-2", commonParams.InstanceRunning)))
+
+	// Deleted pools are no longer tracked.
+	require.False(t, filter(common.ChangePayload{
+		EntityType: common.PoolEntityType,
+		Operation:  common.DeleteOperation,
+		Payload:    params.Pool{ID: "pool-1"},
+	}))
+	require.False(t, filter(instancePayload("pool-1", commonParams.InstanceRunning)))
+}
+
+func TestJobStatusFilter(t *testing.T) {
+	filter := watcher.WithJobStatusFilter(params.JobStatusQueued, params.JobStatusInProgress)
+	jobPayload := func(status params.JobStatus) common.ChangePayload {
+		return common.ChangePayload{
+			EntityType: common.JobEntityType,
+			Operation:  common.UpdateOperation,
+			Payload:    params.Job{ID: 1, Status: string(status)},
+		}
+	}
+
+	require.True(t, filter(jobPayload(params.JobStatusQueued)))
+	require.True(t, filter(jobPayload(params.JobStatusInProgress)))
+	require.False(t, filter(jobPayload(params.JobStatusCompleted)))
+	require.False(t, filter(instancePayload("pool-1", commonParams.InstanceRunning)))
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	EntityErrorEvents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsEntitySubsystem,
+		Name:      "error_events",
+		Help:      "Number of error events recorded for a repository, organization or enterprise",
+	}, []string{"entity_type", "entity_id", "entity_name", "category"})
+
+	EntityLastErrorTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsEntitySubsystem,
+		Name:      "last_error_timestamp_seconds",
+		Help:      "Unix timestamp of the last error event recorded for a repository, organization or enterprise",
+	}, []string{"entity_type", "entity_id", "entity_name", "category"})
+)
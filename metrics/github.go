@@ -16,4 +16,32 @@ var (
 		Name:      "errors_total",
 		Help:      "Total number of failed github operation attempts",
 	}, []string{"operation", "scope"})
+
+	GithubAppTokenExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsGithubSubsystem,
+		Name:      "app_token_expires_at_seconds",
+		Help:      "Unix time at which the cached installation token of the github app credentials expires",
+	}, []string{"credentials"})
+
+	GithubAppTokenRefreshCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsGithubSubsystem,
+		Name:      "app_token_refreshes_total",
+		Help:      "Total number of installation token refreshes of the github app credentials",
+	}, []string{"credentials", "success"})
+
+	GithubRateLimitThrottled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsGithubSubsystem,
+		Name:      "rate_limit_throttled",
+		Help:      "Whether non critical loops are deferred because the rate limit of the credentials is running low",
+	}, []string{"credentials"})
+
+	GithubRateLimitDeferredCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsGithubSubsystem,
+		Name:      "rate_limit_deferred_total",
+		Help:      "Total number of loop runs deferred because the rate limit of the credentials is running low",
+	}, []string{"credentials", "loop"})
 )
@@ -25,4 +25,25 @@ var (
 		Name:      "errors_total",
 		Help:      "Total number of failed instance operation attempts",
 	}, []string{"operation", "provider"})
+
+	InstanceRunnerStatusCorrectionCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsRunnerSubsystem,
+		Name:      "status_corrections_total",
+		Help:      "Total number of runner status corrections applied after comparing with the busy flag in GitHub",
+	}, []string{"pool_id", "from", "to"})
+
+	InstanceLeakedJITRegistrations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsRunnerSubsystem,
+		Name:      "leaked_jit_registrations",
+		Help:      "Number of GitHub runner registrations of instances that failed to be created, which are still waiting to be removed",
+	}, []string{"pool_id"})
+
+	InstanceLeakedJITRegistrationsRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsRunnerSubsystem,
+		Name:      "leaked_jit_registrations_removed_total",
+		Help:      "Total number of GitHub runner registrations removed because the instance they were created for never used them",
+	}, []string{"pool_id", "reason"})
 )
@@ -0,0 +1,17 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var JobsThrottled = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsJobsSubsystem,
+	Name:      "throttled_total",
+	Help:      "The total number of times a queued job was held back because a concurrency limit was reached",
+}, []string{"entity", "limit"})
+
+var JobsObservedPlacements = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsJobsSubsystem,
+	Name:      "observed_placements_total",
+	Help:      "The total number of queued jobs for which a runner placement was decided in observer mode. The pool_id label is empty if no pool could have created a runner",
+}, []string{"entity", "pool_id", "provider"})
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	InstanceLocksHeld = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsLockSubsystem,
+		Name:      "held",
+		Help:      "Number of instance locks currently held",
+	}, []string{"entity_id"})
+
+	InstanceLocksStale = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsLockSubsystem,
+		Name:      "stale",
+		Help:      "Number of instance locks held for longer than the stale threshold",
+	}, []string{"entity_id"})
+
+	InstanceLocksOldestAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsLockSubsystem,
+		Name:      "oldest_age_seconds",
+		Help:      "Age in seconds of the oldest instance lock currently held",
+	}, []string{"entity_id"})
+
+	InstanceLocksReleased = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsLockSubsystem,
+		Name:      "released_total",
+		Help:      "Number of instance locks that were force released",
+	}, []string{"entity_id", "reason"})
+)
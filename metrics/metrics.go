@@ -14,6 +14,11 @@ const (
 	metricsEnterpriseSubsystem   = "enterprise"
 	metricsWebhookSubsystem      = "webhook"
 	metricsGithubSubsystem       = "github"
+	metricsWorkerSubsystem       = "worker"
+	metricsJobsSubsystem         = "jobs"
+	metricsEntitySubsystem       = "entity"
+	metricsUsageSubsystem        = "usage"
+	metricsLockSubsystem         = "lock"
 )
 
 // RegisterMetrics registers all the metrics
@@ -34,16 +39,34 @@ func RegisterMetrics() error {
 		// repository metrics
 		RepositoryInfo,
 		RepositoryPoolManagerStatus,
+		// entity event metrics
+		EntityErrorEvents,
+		EntityLastErrorTimestamp,
 		// provider metrics
 		ProviderInfo,
+		ProviderPaused,
 		// pool metrics
 		PoolInfo,
 		PoolStatus,
 		PoolMaxRunners,
 		PoolMinIdleRunners,
 		PoolBootstrapTimeout,
+		PoolCapacityWarningThreshold,
+		PoolCapacityWarning,
+		// usage metrics
+		UsageRunnerMinutes,
+		UsageRunners,
+		UsageJobsServed,
+		UsageFirstJobLatency,
+		UsageIdleMinutes,
+		UsageBusyMinutes,
 		// health metrics
 		GarmHealth,
+		WorkerHealthy,
+		// lock metrics
+		InstanceLocksHeld,
+		InstanceLocksStale,
+		InstanceLocksOldestAge,
 
 		// metrics used within normal garm operations
 		// e.g. count instance creations, count github api calls, ...
@@ -51,11 +74,30 @@ func RegisterMetrics() error {
 		// runner instances
 		InstanceOperationCount,
 		InstanceOperationFailedCount,
+		InstanceRunnerStatusCorrectionCount,
+		InstanceLeakedJITRegistrations,
+		InstanceLeakedJITRegistrationsRemoved,
 		// github
 		GithubOperationCount,
 		GithubOperationFailedCount,
+		GithubAppTokenExpiry,
+		GithubAppTokenRefreshCount,
+		GithubRateLimitThrottled,
+		GithubRateLimitDeferredCount,
 		// webhook metrics
 		WebhooksReceived,
+		WebhooksDeduplicated,
+		WebhookProcessingDuration,
+		// provider metrics
+		ProviderOperationDuration,
+		ProviderOperationErrors,
+		// worker metrics
+		WorkerRestarts,
+		// lock metrics
+		InstanceLocksReleased,
+		// job metrics
+		JobsThrottled,
+		JobsObservedPlacements,
 	)
 
 	for _, c := range collectors {
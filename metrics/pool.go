@@ -39,4 +39,18 @@ var (
 		Name:      "bootstrap_timeout",
 		Help:      "Runner bootstrap timeout in the pool",
 	}, []string{"id"})
+
+	PoolCapacityWarningThreshold = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsPoolSubsystem,
+		Name:      "capacity_warning_threshold",
+		Help:      "Percentage of max runners above which the pool emits a capacity warning",
+	}, []string{"id"})
+
+	PoolCapacityWarning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsPoolSubsystem,
+		Name:      "capacity_warning",
+		Help:      "Whether the pool reached its capacity warning threshold (1 = yes, 0 = no)",
+	}, []string{"id"})
 )
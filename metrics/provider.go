@@ -10,3 +10,25 @@ var ProviderInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Name:      "info",
 	Help:      "Info of the organization",
 }, []string{"name", "type", "description"})
+
+var ProviderPaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsProviderSubsystem,
+	Name:      "paused",
+	Help:      "Whether the provider is paused",
+}, []string{"name"})
+
+var ProviderOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsProviderSubsystem,
+	Name:      "operation_duration_seconds",
+	Help:      "Time it took the provider to perform an operation for a pool",
+	Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+}, []string{"provider", "pool_id", "operation"})
+
+var ProviderOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsProviderSubsystem,
+	Name:      "operation_errors_total",
+	Help:      "Total number of provider operations that failed",
+}, []string{"provider", "pool_id", "operation"})
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const remoteWriteTimeout = 30 * time.Second
+
+type label struct {
+	name  string
+	value string
+}
+
+type timeSeries struct {
+	labels    []label
+	value     float64
+	timestamp int64
+}
+
+// NewRemoteWriter returns a RemoteWriter that pushes the metrics gathered from
+// the given gatherer to the configured remote write endpoint.
+func NewRemoteWriter(cfg config.RemoteWrite, gatherer prometheus.Gatherer) *RemoteWriter {
+	return &RemoteWriter{
+		cfg:      cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: remoteWriteTimeout},
+	}
+}
+
+// RemoteWriter periodically pushes a snapshot of the metrics to an endpoint that
+// implements the Prometheus remote write protocol (version 1).
+type RemoteWriter struct {
+	cfg      config.RemoteWrite
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+// Start pushes the metrics every push interval, until the context is canceled.
+// A snapshot that fails to be pushed is dropped, and the next one is pushed at
+// the next interval.
+func (r *RemoteWriter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.cfg.PushInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Push(ctx); err != nil {
+					slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to push metrics to remote write endpoint")
+				}
+			}
+		}
+	}()
+}
+
+// Push sends a snapshot of the metrics to the remote write endpoint.
+func (r *RemoteWriter) Push(ctx context.Context) error {
+	families, err := r.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("error gathering metrics: %w", err)
+	}
+
+	series := familiesToTimeSeries(families, time.Now())
+	if len(series) == 0 {
+		return nil
+	}
+	body := s2.EncodeSnappy(nil, encodeWriteRequest(series))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", fmt.Sprintf("garm/%s", appdefaults.GetVersion()))
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for name, value := range r.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case r.cfg.Username != "":
+		req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	case r.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+r.cfg.BearerToken)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// familiesToTimeSeries flattens the gathered metric families into samples, the
+// same way the text exposition format does. Histograms and summaries are split
+// into their _bucket, _sum and _count (or quantile) series.
+func familiesToTimeSeries(families []*dto.MetricFamily, now time.Time) []timeSeries {
+	timestamp := now.UnixMilli()
+
+	var ret []timeSeries
+	add := func(name string, labels []label, value float64) {
+		all := make([]label, 0, len(labels)+1)
+		all = append(all, label{name: "__name__", value: name})
+		all = append(all, labels...)
+		sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+		ret = append(ret, timeSeries{labels: all, value: value, timestamp: timestamp})
+	}
+	with := func(labels []label, name, value string) []label {
+		return append(append([]label{}, labels...), label{name: name, value: value})
+	}
+
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			labels := make([]label, 0, len(metric.GetLabel()))
+			for _, pair := range metric.GetLabel() {
+				labels = append(labels, label{name: pair.GetName(), value: pair.GetValue()})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, labels, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, labels, metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, labels, metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.GetBucket() {
+					le := strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)
+					add(name+"_bucket", with(labels, "le", le), float64(bucket.GetCumulativeCount()))
+				}
+				add(name+"_bucket", with(labels, "le", "+Inf"), float64(histogram.GetSampleCount()))
+				add(name+"_sum", labels, histogram.GetSampleSum())
+				add(name+"_count", labels, float64(histogram.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					q := strconv.FormatFloat(quantile.GetQuantile(), 'g', -1, 64)
+					add(name, with(labels, "quantile", q), quantile.GetValue())
+				}
+				add(name+"_sum", labels, summary.GetSampleSum())
+				add(name+"_count", labels, float64(summary.GetSampleCount()))
+			}
+		}
+	}
+	return ret
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protobuf
+// message. The message is small enough that it's not worth pulling in the
+// generated types from the prometheus repository.
+func encodeWriteRequest(series []timeSeries) []byte {
+	var buf []byte
+	for _, ts := range series {
+		var tsBuf []byte
+		for _, l := range ts.labels {
+			var labelBuf []byte
+			labelBuf = protowire.AppendTag(labelBuf, 1, protowire.BytesType)
+			labelBuf = protowire.AppendString(labelBuf, l.name)
+			labelBuf = protowire.AppendTag(labelBuf, 2, protowire.BytesType)
+			labelBuf = protowire.AppendString(labelBuf, l.value)
+
+			tsBuf = protowire.AppendTag(tsBuf, 1, protowire.BytesType)
+			tsBuf = protowire.AppendBytes(tsBuf, labelBuf)
+		}
+
+		var sampleBuf []byte
+		sampleBuf = protowire.AppendTag(sampleBuf, 1, protowire.Fixed64Type)
+		sampleBuf = protowire.AppendFixed64(sampleBuf, math.Float64bits(ts.value))
+		sampleBuf = protowire.AppendTag(sampleBuf, 2, protowire.VarintType)
+		sampleBuf = protowire.AppendVarint(sampleBuf, uint64(ts.timestamp))
+
+		tsBuf = protowire.AppendTag(tsBuf, 2, protowire.BytesType)
+		tsBuf = protowire.AppendBytes(tsBuf, sampleBuf)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, tsBuf)
+	}
+	return buf
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/cloudbase/garm/config"
+)
+
+// decodeWriteRequest decodes the label sets of a prometheus.WriteRequest message.
+func decodeWriteRequest(t *testing.T, buf []byte) []map[string]string {
+	consume := func(buf []byte) (protowire.Number, []byte, []byte) {
+		num, typ, n := protowire.ConsumeTag(buf)
+		require.GreaterOrEqual(t, n, 0)
+		buf = buf[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+			require.GreaterOrEqual(t, n, 0)
+			return num, nil, buf[n:]
+		}
+		value, n := protowire.ConsumeBytes(buf)
+		require.GreaterOrEqual(t, n, 0)
+		return num, value, buf[n:]
+	}
+
+	var ret []map[string]string
+	for len(buf) > 0 {
+		var ts []byte
+		_, ts, buf = consume(buf)
+		labels := map[string]string{}
+		for len(ts) > 0 {
+			var num protowire.Number
+			var field []byte
+			num, field, ts = consume(ts)
+			if num != 1 {
+				continue
+			}
+			var name, value []byte
+			_, name, field = consume(field)
+			_, value, _ = consume(field)
+			labels[string(name)] = string(value)
+		}
+		ret = append(ret, labels)
+	}
+	return ret
+}
+
+func TestFamiliesToTimeSeries(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total"}, []string{"pool_id"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1, 5}})
+	registry.MustRegister(counter, histogram)
+	counter.WithLabelValues("pool-1").Add(3)
+	histogram.Observe(2)
+
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	series := familiesToTimeSeries(families, time.UnixMilli(1000))
+
+	// 2 buckets, +Inf, _sum and _count for the histogram, and the counter.
+	require.Len(t, series, 6)
+	require.Equal(t, []label{{name: "__name__", value: "test_seconds_bucket"}, {name: "le", value: "1"}}, series[0].labels)
+	require.Equal(t, float64(0), series[0].value)
+	require.Equal(t, float64(1), series[1].value)
+	require.Equal(t, "+Inf", series[2].labels[1].value)
+	require.Equal(t, []label{{name: "__name__", value: "test_total"}, {name: "pool_id", value: "pool-1"}}, series[5].labels)
+	require.Equal(t, float64(3), series[5].value)
+	require.Equal(t, int64(1000), series[5].timestamp)
+}
+
+func TestRemoteWriterPush(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge"})
+	registry.MustRegister(gauge)
+	gauge.Set(42)
+
+	var got []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		require.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+		if username, password, ok := r.BasicAuth(); !ok || username != "garm" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		compressed, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+		body, err := s2.Decode(nil, compressed)
+		require.Nil(t, err)
+		got = decodeWriteRequest(t, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.RemoteWrite{
+		URL:      srv.URL,
+		Username: "garm",
+		Password: "secret",
+		Headers:  map[string]string{"X-Scope-OrgID": "tenant-1"},
+	}
+	require.Nil(t, NewRemoteWriter(cfg, registry).Push(context.Background()))
+	require.Equal(t, []map[string]string{{"__name__": "test_gauge"}}, got)
+
+	cfg.Password = "wrong"
+	err := NewRemoteWriter(cfg, registry).Push(context.Background())
+	require.NotNil(t, err)
+	require.Regexp(t, "remote write endpoint returned 401 Unauthorized", err.Error())
+}
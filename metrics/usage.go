@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	UsageRunnerMinutes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "runner_minutes",
+		Help:      "Total runtime in minutes of the runners created by a pool",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+
+	UsageRunners = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "runners",
+		Help:      "Total number of runners created by a pool",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+
+	UsageJobsServed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "jobs_served",
+		Help:      "Total number of jobs served by the runners of a pool",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+
+	UsageFirstJobLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "first_job_latency_seconds",
+		Help:      "Average time between the creation of the runners of a pool and their first job",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+
+	UsageIdleMinutes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "idle_minutes",
+		Help:      "Total time in minutes the runners of a pool existed without running a job",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+
+	UsageBusyMinutes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsUsageSubsystem,
+		Name:      "busy_minutes",
+		Help:      "Total time in minutes the runners of a pool spent running jobs",
+	}, []string{"pool_id", "provider", "entity_id", "entity_type"})
+)
@@ -8,3 +8,18 @@ var WebhooksReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name:      "received",
 	Help:      "The total number of webhooks received",
 }, []string{"valid", "reason"})
+
+var WebhooksDeduplicated = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsWebhookSubsystem,
+	Name:      "deduplicated",
+	Help:      "The total number of workflow job webhooks ignored because the same event was already processed",
+})
+
+var WebhookProcessingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsWebhookSubsystem,
+	Name:      "processing_duration_seconds",
+	Help:      "Time it took to process a received webhook",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"valid"})
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	WorkerHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsWorkerSubsystem,
+		Name:      "healthy",
+		Help:      "Whether the worker loop reported a heartbeat recently (1) or not (0)",
+	}, []string{"entity_id", "worker"})
+
+	WorkerRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsWorkerSubsystem,
+		Name:      "restarts_total",
+		Help:      "Number of times a worker loop recovered from a panic or was restarted by the watchdog",
+	}, []string{"entity_id", "worker", "reason"})
+)
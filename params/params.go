@@ -22,28 +22,66 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v57/github"
 	"github.com/google/uuid"
 	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v3"
 
 	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/util/appdefaults"
 )
 
 type (
-	GithubEntityType    string
-	EventType           string
-	EventLevel          string
-	ProviderType        string
-	JobStatus           string
-	RunnerStatus        string
-	WebhookEndpointType string
-	GithubAuthType      string
-	PoolBalancerType    string
+	GithubEntityType      string
+	EventType             string
+	EventLevel            string
+	ProviderType          string
+	JobStatus             string
+	JobDecisionType       string
+	MigrationPhase        string
+	WebhookDeliveryStatus string
+	RunnerStatus          string
+	WebhookEndpointType   string
+	GithubAuthType        string
+	PoolBalancerType      string
+	NameCharset           string
+	ErrorClass            string
+)
+
+const (
+	ErrorClassQuota          ErrorClass = "quota"
+	ErrorClassImageNotFound  ErrorClass = "image_not_found"
+	ErrorClassFlavorNotFound ErrorClass = "flavor_not_found"
+	ErrorClassAuthentication ErrorClass = "authentication"
+	ErrorClassTimeout        ErrorClass = "timeout"
+	ErrorClassNetwork        ErrorClass = "network"
+	ErrorClassUnknown        ErrorClass = "unknown"
+)
+
+const (
+	// NameCharsetAny places no restriction on the characters used in instance names.
+	NameCharsetAny NameCharset = ""
+	// NameCharsetAlphanumeric allows letters, digits and hyphens in instance names.
+	NameCharsetAlphanumeric NameCharset = "alphanumeric"
+	// NameCharsetDNSLabel restricts instance names to lower case letters, digits
+	// and hyphens. Names must start and end with a letter or a digit (RFC 1123).
+	NameCharsetDNSLabel NameCharset = "dns-label"
+)
+
+const (
+	// maxInstanceIDLength is the maximum length of the random ID GARM appends
+	// to the runner prefix when generating instance names.
+	maxInstanceIDLength = 22
 )
 
 const (
@@ -61,6 +99,32 @@ const (
 	PoolBalancerTypeNone PoolBalancerType = ""
 )
 
+type RoutingRuleType string
+
+const (
+	// RoutingRulePreferIdle prefers pools that have idle runners.
+	RoutingRulePreferIdle RoutingRuleType = "prefer_idle"
+	// RoutingRuleLeastLoaded prefers pools that use the smallest share of their
+	// max runners.
+	RoutingRuleLeastLoaded RoutingRuleType = "least_loaded"
+	// RoutingRuleProviderOrder prefers pools by the order of their provider in the
+	// list of providers of the rule. Pools of providers that are not in the list
+	// come last. This can be used to prefer the cheapest provider, and to fall back
+	// to the others when its pools are full.
+	RoutingRuleProviderOrder RoutingRuleType = "provider_order"
+)
+
+// RoutingRule is a rule used to choose the pool in which a runner is created for a
+// queued job. The rules of an entity are applied in order: pools are sorted by the
+// first rule, pools that are equal by the first rule are sorted by the second one
+// and so on. Pool priority breaks the remaining ties.
+type RoutingRule struct {
+	Type RoutingRuleType `json:"type"`
+	// Providers is the list of providers used by the provider_order rule, from
+	// the most preferred to the least preferred.
+	Providers []string `json:"providers,omitempty"`
+}
+
 const (
 	// LXDProvider represents the LXD provider.
 	LXDProvider ProviderType = "lxd"
@@ -80,10 +144,37 @@ const (
 
 const (
 	JobStatusQueued     JobStatus = "queued"
+	JobStatusWaiting    JobStatus = "waiting"
 	JobStatusInProgress JobStatus = "in_progress"
 	JobStatusCompleted  JobStatus = "completed"
 )
 
+const (
+	// JobDecisionRunnerCreated means a runner was created for the job.
+	JobDecisionRunnerCreated JobDecisionType = "runner_created"
+	// JobDecisionDeferred means no runner was created for the job yet, but the job
+	// will be considered again. For example, when a concurrency limit was reached.
+	JobDecisionDeferred JobDecisionType = "deferred"
+	// JobDecisionSkipped means no runner could be created for the job.
+	JobDecisionSkipped JobDecisionType = "skipped"
+)
+
+const (
+	// MigrationPhaseUpdateController is the phase in which the new controller ID is
+	// saved. The previous ID is kept, so runners and webhooks using it are still
+	// recognized while the migration runs.
+	MigrationPhaseUpdateController MigrationPhase = "update_controller"
+	// MigrationPhaseReinstallWebhooks is the phase in which the webhooks managed by GARM
+	// are moved to the new controller webhook URL.
+	MigrationPhaseReinstallWebhooks MigrationPhase = "reinstall_webhooks"
+	// MigrationPhaseDrainRunners is the phase in which the idle runners labeled with the
+	// previous controller ID are removed, and GARM waits for the busy ones to finish.
+	MigrationPhaseDrainRunners MigrationPhase = "drain_runners"
+	// MigrationPhaseCompleted means the migration is done and the previous controller ID
+	// was dropped.
+	MigrationPhaseCompleted MigrationPhase = "completed"
+)
+
 const (
 	GithubEntityTypeRepository   GithubEntityType = "repository"
 	GithubEntityTypeOrganization GithubEntityType = "organization"
@@ -97,8 +188,36 @@ const (
 )
 
 const (
-	StatusEvent     EventType = "status"
-	FetchTokenEvent EventType = "fetchToken"
+	StatusEvent         EventType = "status"
+	FetchTokenEvent     EventType = "fetchToken"
+	WebhookInstallEvent EventType = "webhookInstall"
+	// ProviderOperationEvent is recorded by GARM every time it asks the provider
+	// to create or delete an instance.
+	ProviderOperationEvent EventType = "providerOperation"
+	// PoolManagerEvent is recorded when the pool manager of an entity fails
+	// or recovers.
+	PoolManagerEvent EventType = "poolManager"
+	// ClockSkewEvent is recorded on an instance when the clock of the instance
+	// differs from the clock of GARM by more than the configured tolerance.
+	ClockSkewEvent EventType = "clockSkew"
+	// ObserverEvent is recorded when GARM runs in observer mode and decides where
+	// a runner for a queued job would have been created.
+	ObserverEvent EventType = "observer"
+	// DenyRuleEvent is recorded when a pool uses an image and flavor combination
+	// that is banned by a deny rule.
+	DenyRuleEvent EventType = "denyRule"
+	// CapacityWarningEvent is recorded when the number of runners of a pool goes above
+	// the capacity warning threshold of the pool, and when it goes back below it.
+	CapacityWarningEvent EventType = "capacityWarning"
+	// RateLimitWarningEvent is recorded when the GitHub API rate limit of the entity
+	// credentials is expected to run out before it is reset, and when it no longer is.
+	RateLimitWarningEvent EventType = "rateLimitWarning"
+	// ProviderHealthEvent is recorded when the health check of a provider used by the
+	// pools of an entity keeps failing, and when the provider recovers.
+	ProviderHealthEvent EventType = "providerHealth"
+	// WarmImageEvent is recorded when GARM builds the warm image of a pool, or fails
+	// to build it.
+	WarmImageEvent EventType = "warmImage"
 )
 
 const (
@@ -134,6 +253,26 @@ type StatusMessage struct {
 	EventLevel EventLevel `json:"event_level,omitempty"`
 }
 
+// used by swagger client generated code
+type StatusMessages []StatusMessage
+
+// StatusMessagesPage is one page of the status messages of an instance, newest first.
+type StatusMessagesPage struct {
+	Messages   []StatusMessage `json:"messages"`
+	Page       uint            `json:"page"`
+	PageSize   uint            `json:"page_size"`
+	TotalCount uint            `json:"total_count"`
+	TotalPages uint            `json:"total_pages"`
+}
+
+// EntityEvent is an event recorded for a repository, organization or enterprise.
+type EntityEvent struct {
+	StatusMessage
+	EntityType GithubEntityType `json:"entity_type,omitempty"`
+	EntityID   string           `json:"entity_id,omitempty"`
+	EntityName string           `json:"entity_name,omitempty"`
+}
+
 type Instance struct {
 	// ID is the database ID of this instance.
 	ID string `json:"id,omitempty"`
@@ -182,10 +321,15 @@ type Instance struct {
 	// responsible for managing the lifecycle of the runner.
 	ProviderFault []byte `json:"provider_fault,omitempty"`
 
+	// ProviderFaultAt is the time the provider fault was recorded.
+	ProviderFaultAt *time.Time `json:"provider_fault_at,omitempty"`
+
 	// StatusMessages is a list of status messages sent back by the runner as it sets itself
 	// up.
 	StatusMessages []StatusMessage `json:"status_messages,omitempty"`
 
+	// CreatedAt is the timestamp of the creation of this runner.
+	CreatedAt time.Time `json:"created_at,omitempty"`
 	// UpdatedAt is the timestamp of the last update to this runner.
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 
@@ -193,9 +337,17 @@ type Instance struct {
 	// The runner group must be created by someone with access to the enterprise.
 	GitHubRunnerGroup string `json:"github-runner-group,omitempty"`
 
+	// Image is the image of the pool at the time the runner was created.
+	Image string `json:"image,omitempty"`
+	// Flavor is the flavor of the pool at the time the runner was created.
+	Flavor string `json:"flavor,omitempty"`
+
 	// Job is the current job that is being serviced by this runner.
 	Job *Job `json:"job,omitempty"`
 
+	// DiskUsage is the last disk usage reported by the runner.
+	DiskUsage *InstanceDiskUsage `json:"disk_usage,omitempty"`
+
 	// Do not serialize sensitive info.
 	CallbackURL      string            `json:"-"`
 	MetadataURL      string            `json:"-"`
@@ -203,6 +355,12 @@ type Instance struct {
 	TokenFetched     bool              `json:"-"`
 	AditionalLabels  []string          `json:"-"`
 	JitConfiguration map[string]string `json:"-"`
+	// JitRegistrationRemoved is set when the runner registration created in GitHub
+	// along with the JIT config was removed, because the instance never used it.
+	JitRegistrationRemoved bool `json:"-"`
+	// InstanceTokenGeneration is the generation of the pool when the instance was
+	// created. It is the oldest generation whose key may have signed its token.
+	InstanceTokenGeneration uint `json:"-"`
 }
 
 func (i Instance) GetName() string {
@@ -319,6 +477,365 @@ type Pool struct {
 	// When fetching matching pools for a set of tags, the result will be sorted in descending
 	// order of priority.
 	Priority uint `json:"priority,omitempty"`
+
+	// CleanupPolicy is the disk cleanup policy that providers which reuse hosts
+	// should enforce for runners in this pool.
+	CleanupPolicy *CleanupPolicy `json:"cleanup_policy,omitempty"`
+
+	// NetworkSettings holds the network configuration runners in this pool need
+	// in order to come up in restricted networks.
+	NetworkSettings *NetworkSettings `json:"network_settings,omitempty"`
+
+	// Schedule holds the time windows during which the pool keeps a different number
+	// of idle runners.
+	Schedule *PoolSchedule `json:"schedule,omitempty"`
+
+	// InstanceTokenGeneration is incremented every time the pool is updated. It is
+	// used to rotate the key that signs the JWT tokens of instances in this pool.
+	InstanceTokenGeneration uint `json:"instance_token_generation,omitempty"`
+
+	// IdleDetectionWindow is the amount of time in minutes a runner must be idle
+	// before it is considered for scale down.
+	IdleDetectionWindow uint `json:"idle_detection_window,omitempty"`
+	// ScaleDownGracePeriod is the amount of time in minutes after a runner is created,
+	// during which it will not be scaled down.
+	ScaleDownGracePeriod uint `json:"scale_down_grace_period,omitempty"`
+	// ScaleDownFactor is the fraction of the surplus of idle runners that is
+	// removed on each scale down run.
+	ScaleDownFactor float64 `json:"scale_down_factor,omitempty"`
+	// CapacityWarningThreshold is the percentage of max runners above which a warning
+	// is emitted for the pool.
+	CapacityWarningThreshold uint `json:"capacity_warning_threshold,omitempty"`
+	// AutoscaleMaxBurst is the maximum number of runners the queue depth autoscaler
+	// creates in the pool at once. The autoscaler is disabled for the pool if 0.
+	AutoscaleMaxBurst uint `json:"autoscale_max_burst,omitempty"`
+	// AutoscaleCooldown is the amount of time in seconds the queue depth autoscaler
+	// waits after scaling up the pool, before scaling it up again.
+	AutoscaleCooldown uint `json:"autoscale_cooldown,omitempty"`
+	// MaxCreatesPerMinute is the maximum number of runners created in the pool each
+	// minute. A value of 0 means no limit.
+	MaxCreatesPerMinute uint `json:"max_creates_per_minute,omitempty"`
+	// CreateJitter is the maximum amount of time in seconds a runner of the pool waits
+	// before it is created. Each runner waits a random amount of time up to this value.
+	CreateJitter uint `json:"create_jitter,omitempty"`
+	// RollingUpdateBatchSize is the number of outdated idle runners replaced at once,
+	// after the image or flavor of the pool changed.
+	RollingUpdateBatchSize uint `json:"rolling_update_batch_size,omitempty"`
+	// RollingUpdatePause is the amount of time in seconds waited between two batches
+	// of a rolling update.
+	RollingUpdatePause uint `json:"rolling_update_pause,omitempty"`
+	// Draining is set when the pool is being drained. A draining pool does not create
+	// new runners and removes its existing runners once they become idle.
+	Draining bool `json:"draining,omitempty"`
+	// URLSet is the name of the controller URL set whose metadata and callback URLs
+	// are sent to the runners of the pool. The default URLs are used if empty.
+	URLSet string `json:"url_set,omitempty"`
+	// InstanceMetadata holds user defined key/value pairs served to the runners of
+	// the pool by the metadata service.
+	InstanceMetadata InstanceMetadata `json:"instance_metadata,omitempty"`
+	// WarmImage holds the settings used to build a warm image for the pool. Warm
+	// images are snapshots of runners prepared with a script, which are used instead
+	// of Image when creating new runners.
+	WarmImage *WarmImageSettings `json:"warm_image,omitempty"`
+	// WarmImageStatus holds the result of the last warm image build of the pool.
+	WarmImageStatus *WarmImageStatus `json:"warm_image_status,omitempty"`
+
+	// ProviderPaused is set when the provider of this pool is paused. The pool will
+	// not create new instances until the provider is resumed.
+	ProviderPaused bool `json:"provider_paused,omitempty"`
+	// CapacityWarning is set when the number of runners in the pool is at or above
+	// the capacity warning threshold.
+	CapacityWarning bool `json:"capacity_warning,omitempty"`
+}
+
+// ScheduleWindow is a recurring time window during which a pool keeps a different
+// number of idle runners. Start and End use the HH:MM format. Windows where End is
+// not after Start end on the next day.
+type ScheduleWindow struct {
+	// Days are the days of the week on which the window starts (monday, tuesday, etc).
+	// The window starts every day if no days are set.
+	Days           []string `json:"days,omitempty"`
+	Start          string   `json:"start"`
+	End            string   `json:"end"`
+	MinIdleRunners uint     `json:"min_idle_runners"`
+}
+
+// PoolSchedule holds the time windows during which a pool keeps a different number of
+// idle runners. Outside of these windows, the min idle runners of the pool is used.
+type PoolSchedule struct {
+	// Timezone is the IANA name of the time zone the windows are defined in.
+	// Defaults to UTC.
+	Timezone string           `json:"timezone,omitempty"`
+	Windows  []ScheduleWindow `json:"windows,omitempty"`
+}
+
+// IsEmpty returns true if the schedule has no windows.
+func (p PoolSchedule) IsEmpty() bool {
+	return len(p.Windows) == 0
+}
+
+// Validate checks the time zone and the windows of the schedule. The min idle runners
+// of a window cannot be larger than the max runners of the pool.
+func (p PoolSchedule) Validate(maxRunners uint) error {
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", p.Timezone)
+	}
+	for _, window := range p.Windows {
+		start, err := parseScheduleTime(window.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseScheduleTime(window.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("schedule window start and end cannot be the same")
+		}
+		for _, day := range window.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid day %q", day)
+			}
+		}
+		if window.MinIdleRunners > maxRunners {
+			return fmt.Errorf("schedule window min_idle_runners cannot be larger than max_runners")
+		}
+	}
+	return nil
+}
+
+// MinIdleRunners returns the min idle runners of the windows that are active at the
+// given time. If several windows are active, the largest value is returned. The second
+// value is false if no window is active.
+func (p PoolSchedule) MinIdleRunners(now time.Time) (uint, bool) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return 0, false
+	}
+	now = now.In(loc)
+	minutes := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+
+	var ret uint
+	var active bool
+	for _, window := range p.Windows {
+		start, err := parseScheduleTime(window.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseScheduleTime(window.End)
+		if err != nil {
+			continue
+		}
+		var inWindow bool
+		if start < end {
+			inWindow = window.startsOn(today) && minutes >= start && minutes < end
+		} else {
+			inWindow = (window.startsOn(today) && minutes >= start) || (window.startsOn(yesterday) && minutes < end)
+		}
+		if inWindow {
+			ret = max(ret, window.MinIdleRunners)
+			active = true
+		}
+	}
+	return ret, active
+}
+
+func (w ScheduleWindow) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, val := range w.Days {
+		if weekday, ok := weekdays[strings.ToLower(val)]; ok && weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseScheduleTime returns the number of minutes since midnight of a HH:MM time.
+func parseScheduleTime(val string) (int, error) {
+	parsed, err := time.Parse("15:04", val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q: must use the HH:MM format", val)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// NetworkSettings holds network configuration that is applied to runners while they
+// are bootstrapped. The settings are passed on to providers, which may render them into
+// the user data of the instances, and are served to runners by the metadata service.
+// The proxy settings are also set in the environment of the runner service.
+type NetworkSettings struct {
+	// DNSServers is a list of DNS server IP addresses.
+	DNSServers []string `json:"dns_servers,omitempty"`
+	// SearchDomains is a list of DNS search domains.
+	SearchDomains []string `json:"search_domains,omitempty"`
+	// NTPServers is a list of NTP servers, as IP addresses or host names.
+	NTPServers []string `json:"ntp_servers,omitempty"`
+	// HTTPProxy is the proxy used by the runner for HTTP requests.
+	HTTPProxy string `json:"http_proxy,omitempty"`
+	// HTTPSProxy is the proxy used by the runner for HTTPS requests.
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	// NoProxy is a comma separated list of hosts that should not be proxied.
+	NoProxy string `json:"no_proxy,omitempty"`
+}
+
+// IsEmpty returns true if no network setting is set.
+func (n NetworkSettings) IsEmpty() bool {
+	return len(n.DNSServers) == 0 && len(n.SearchDomains) == 0 && len(n.NTPServers) == 0 &&
+		n.HTTPProxy == "" && n.HTTPSProxy == "" && n.NoProxy == ""
+}
+
+// Validate checks that the network settings are well formed. The values end up in
+// config files and in the runner service environment, so we don't allow whitespace,
+// quotes or other characters that would need escaping.
+func (n NetworkSettings) Validate() error {
+	for _, server := range n.DNSServers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid DNS server %q: must be an IP address", server)
+		}
+	}
+	for _, domain := range n.SearchDomains {
+		if domain == "" || !isValidNetworkSettingValue(domain) {
+			return fmt.Errorf("invalid search domain %q", domain)
+		}
+	}
+	for _, server := range n.NTPServers {
+		if server == "" || !isValidNetworkSettingValue(server) {
+			return fmt.Errorf("invalid NTP server %q", server)
+		}
+	}
+	for name, proxy := range map[string]string{"http_proxy": n.HTTPProxy, "https_proxy": n.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		parsed, err := url.Parse(proxy)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid %s %q: must be a http or https URL", name, proxy)
+		}
+		if !isValidNetworkSettingValue(proxy) {
+			return fmt.Errorf("invalid %s: contains characters that are not allowed", name)
+		}
+	}
+	if n.NoProxy != "" && !isValidNetworkSettingValue(n.NoProxy) {
+		return fmt.Errorf("invalid no_proxy %q", n.NoProxy)
+	}
+	return nil
+}
+
+// WarmImageSettings holds the settings used to build the warm image of a pool. GARM
+// periodically takes an idle runner of the pool, asks the provider to run the preparation
+// script on it and to snapshot it into a new image. New runners of the pool are then
+// created from that image, which saves them from downloading tools on every boot.
+type WarmImageSettings struct {
+	// PrepareScript is the script the provider runs on the runner before taking the
+	// snapshot. It usually warms up the tool cache and cleans up the runner.
+	PrepareScript string `json:"prepare_script,omitempty"`
+	// RefreshInterval is the interval, in hours, at which the warm image is rebuilt.
+	// A value of 0 uses the default of 24 hours.
+	RefreshInterval uint `json:"refresh_interval,omitempty"`
+}
+
+// IsEmpty returns true if no preparation script is set.
+func (w WarmImageSettings) IsEmpty() bool {
+	return w.PrepareScript == ""
+}
+
+// RefreshPeriod returns the interval at which the warm image is rebuilt.
+func (w WarmImageSettings) RefreshPeriod() time.Duration {
+	if w.RefreshInterval == 0 {
+		return time.Duration(appdefaults.DefaultWarmImageRefreshInterval) * time.Hour
+	}
+	return time.Duration(w.RefreshInterval) * time.Hour
+}
+
+// Validate checks that the preparation script and the refresh interval are within limits.
+func (w WarmImageSettings) Validate() error {
+	if w.IsEmpty() {
+		if w.RefreshInterval != 0 {
+			return fmt.Errorf("refresh_interval requires a prepare_script")
+		}
+		return nil
+	}
+	if len(w.PrepareScript) > appdefaults.MaxWarmImageScriptSize {
+		return fmt.Errorf("prepare_script cannot be larger than %d bytes", appdefaults.MaxWarmImageScriptSize)
+	}
+	if w.RefreshInterval > appdefaults.MaxWarmImageRefreshInterval {
+		return fmt.Errorf("refresh_interval cannot be larger than %d hours", appdefaults.MaxWarmImageRefreshInterval)
+	}
+	return nil
+}
+
+// WarmImageStatus holds the result of the warm image builds of a pool.
+type WarmImageStatus struct {
+	// Image is the provider specific identifier of the last warm image that was built.
+	Image string `json:"image,omitempty"`
+	// BaseImage is the image of the pool the warm image was built from. The warm
+	// image is not used once the image of the pool changes.
+	BaseImage string `json:"base_image,omitempty"`
+	// BuiltAt is the time at which Image was built.
+	BuiltAt time.Time `json:"built_at,omitempty"`
+	// LastAttemptAt is the time of the last build attempt, successful or not.
+	LastAttemptAt time.Time `json:"last_attempt_at,omitempty"`
+	// LastError is the error of the last build attempt. It is cleared once a build
+	// succeeds.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// InstanceMetadata holds arbitrary, non secret, key/value pairs set by the operator on
+// a pool. Runners fetch them from the metadata service while they are set up, and may
+// use them for things like proxy settings or the URL of an artifact cache. Unlike
+// extra specs, they are not interpreted by providers.
+type InstanceMetadata map[string]string
+
+var instanceMetadataKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// Validate checks that all keys are made of letters, digits, dots, dashes or
+// underscores, and that the metadata is not too large.
+func (m InstanceMetadata) Validate() error {
+	size := 0
+	for key, val := range m {
+		if !instanceMetadataKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid instance metadata key %q", key)
+		}
+		size += len(key) + len(val)
+	}
+	if size > appdefaults.MaxInstanceMetadataSize {
+		return fmt.Errorf("instance metadata cannot be larger than %d bytes", appdefaults.MaxInstanceMetadataSize)
+	}
+	return nil
+}
+
+func isValidNetworkSettingValue(val string) bool {
+	return !strings.ContainsAny(val, " \t\r\n\"'`\\&<>+;$")
+}
+
+// CleanupPolicy describes the disk hygiene that should be enforced for runners
+// spawned on hosts that get reused. GARM does not act on this policy itself. It
+// is passed on to providers, which may enforce it when creating or deleting instances.
+type CleanupPolicy struct {
+	// WipeWorkspace indicates that the runner work folder should be removed.
+	WipeWorkspace bool `json:"wipe_workspace,omitempty"`
+	// PruneDockerImagesOlderThanDays indicates that docker images older than the
+	// specified number of days should be removed. A value of 0 disables pruning.
+	PruneDockerImagesOlderThanDays uint `json:"prune_docker_images_older_than_days,omitempty"`
+}
+
+// IsEmpty returns true if the policy does not require any cleanup.
+func (c CleanupPolicy) IsEmpty() bool {
+	return !c.WipeWorkspace && c.PruneDockerImagesOlderThanDays == 0
 }
 
 func (p Pool) GithubEntity() (GithubEntity, error) {
@@ -353,6 +870,103 @@ func (p *Pool) RunnerTimeout() uint {
 	return p.RunnerBootstrapTimeout
 }
 
+// IdleWindow returns the idle detection window of the pool in minutes.
+func (p *Pool) IdleWindow() uint {
+	if p.IdleDetectionWindow == 0 {
+		return appdefaults.DefaultIdleDetectionWindow
+	}
+	return p.IdleDetectionWindow
+}
+
+// ScaleDownGrace returns the scale down grace period of the pool in minutes.
+func (p *Pool) ScaleDownGrace() uint {
+	if p.ScaleDownGracePeriod == 0 {
+		return appdefaults.DefaultScaleDownGracePeriod
+	}
+	return p.ScaleDownGracePeriod
+}
+
+// CapacityThreshold returns the capacity warning threshold of the pool, as a
+// percentage of its max runners.
+func (p *Pool) CapacityThreshold() uint {
+	if p.CapacityWarningThreshold == 0 {
+		return appdefaults.DefaultCapacityWarningThreshold
+	}
+	return p.CapacityWarningThreshold
+}
+
+// CapacityWarningReached returns true if the given number of runners is at or
+// above the capacity warning threshold of the pool.
+func (p *Pool) CapacityWarningReached(runners uint) bool {
+	if p.MaxRunners == 0 {
+		return false
+	}
+	return runners*100 >= p.CapacityThreshold()*p.MaxRunners
+}
+
+// ScaleDownFraction returns the fraction of the surplus of idle runners that is
+// removed on each scale down run.
+func (p *Pool) ScaleDownFraction() float64 {
+	if p.ScaleDownFactor == 0 {
+		return appdefaults.DefaultScaleDownFactor
+	}
+	return p.ScaleDownFactor
+}
+
+// AutoscaleEnabled returns true if the pool is scaled up by the queue depth autoscaler.
+func (p *Pool) AutoscaleEnabled() bool {
+	return p.AutoscaleMaxBurst > 0
+}
+
+// AutoscaleCooldownPeriod returns the amount of time in seconds the queue depth
+// autoscaler waits between two scale ups of the pool.
+func (p *Pool) AutoscaleCooldownPeriod() uint {
+	if p.AutoscaleCooldown == 0 {
+		return appdefaults.DefaultAutoscaleCooldown
+	}
+	return p.AutoscaleCooldown
+}
+
+// RollingUpdateBatch returns the number of outdated idle runners replaced at once.
+func (p *Pool) RollingUpdateBatch() uint {
+	if p.RollingUpdateBatchSize == 0 {
+		return appdefaults.DefaultRollingUpdateBatchSize
+	}
+	return p.RollingUpdateBatchSize
+}
+
+// RollingUpdatePausePeriod returns the amount of time in seconds waited between two
+// batches of a rolling update.
+func (p *Pool) RollingUpdatePausePeriod() uint {
+	if p.RollingUpdatePause == 0 {
+		return appdefaults.DefaultRollingUpdatePause
+	}
+	return p.RollingUpdatePause
+}
+
+// RunnerImage returns the image used to create new runners in the pool. This is the
+// warm image of the pool, if one was built from the current image of the pool, or the
+// image of the pool otherwise.
+func (p *Pool) RunnerImage() string {
+	if p.WarmImage == nil || p.WarmImage.IsEmpty() || p.WarmImageStatus == nil {
+		return p.Image
+	}
+	if p.WarmImageStatus.Image == "" || p.WarmImageStatus.BaseImage != p.Image {
+		return p.Image
+	}
+	return p.WarmImageStatus.Image
+}
+
+// IsOutdated returns true if the instance was created with an image or flavor that
+// is different from the current one of the pool. Instances created before GARM
+// recorded the image and flavor are never considered outdated.
+func (p *Pool) IsOutdated(instance Instance) bool {
+	if instance.Image == "" && instance.Flavor == "" {
+		return false
+	}
+	return instance.Image != p.RunnerImage() || instance.Flavor != p.Flavor
+}
+
 func (p *Pool) PoolType() GithubEntityType {
 	switch {
 	case p.RepoID != "":
@@ -396,8 +1010,25 @@ type Repository struct {
 	PoolManagerStatus PoolManagerStatus `json:"pool_manager_status,omitempty"`
 	PoolBalancerType  PoolBalancerType  `json:"pool_balancing_type,omitempty"`
 	Endpoint          GithubEndpoint    `json:"endpoint,omitempty"`
+	Events            []StatusMessage   `json:"events,omitempty"`
+	// PendingWebhookInstall holds the parameters of a webhook install that failed
+	// and will be retried in the background.
+	PendingWebhookInstall *InstallWebhookParams `json:"pending_webhook_install,omitempty"`
+	// EntityWebhookPath is set if the webhook of the entity was installed using the URL
+	// unique to the entity. Webhooks received on the shared controller URL are rejected.
+	EntityWebhookPath bool `json:"entity_webhook_path,omitempty"`
+	// MaxConcurrentJobs is the maximum number of jobs this entity can have runners
+	// for at the same time. A value of 0 means no limit.
+	MaxConcurrentJobs uint `json:"max_concurrent_jobs,omitempty"`
+	// RoutingRules are used to choose the pool in which a runner is created for a
+	// queued job. When set, they take precedence over the pool balancer type.
+	RoutingRules []RoutingRule `json:"routing_rules,omitempty"`
+	// PreviousWebhookSecretExpiresAt is set while the webhook secret is being rotated.
+	// Until then, webhooks signed with the previous secret are still accepted.
+	PreviousWebhookSecretExpiresAt *time.Time `json:"previous_webhook_secret_expires_at,omitempty"`
 	// Do not serialize sensitive info.
-	WebhookSecret string `json:"-"`
+	WebhookSecret         string `json:"-"`
+	PreviousWebhookSecret string `json:"-"`
 }
 
 func (r Repository) GetEntity() (GithubEntity, error) {
@@ -412,6 +1043,14 @@ func (r Repository) GetEntity() (GithubEntity, error) {
 		PoolBalancerType: r.PoolBalancerType,
 		Credentials:      r.Credentials,
 		WebhookSecret:    r.WebhookSecret,
+
+		PreviousWebhookSecret:          r.PreviousWebhookSecret,
+		PreviousWebhookSecretExpiresAt: r.PreviousWebhookSecretExpiresAt,
+
+		PendingWebhookInstall: r.PendingWebhookInstall,
+		MaxConcurrentJobs:     r.MaxConcurrentJobs,
+		EntityWebhookPath:     r.EntityWebhookPath,
+		RoutingRules:          r.RoutingRules,
 	}, nil
 }
 
@@ -450,8 +1089,25 @@ type Organization struct {
 	PoolManagerStatus PoolManagerStatus `json:"pool_manager_status,omitempty"`
 	PoolBalancerType  PoolBalancerType  `json:"pool_balancing_type,omitempty"`
 	Endpoint          GithubEndpoint    `json:"endpoint,omitempty"`
+	Events            []StatusMessage   `json:"events,omitempty"`
+	// PendingWebhookInstall holds the parameters of a webhook install that failed
+	// and will be retried in the background.
+	PendingWebhookInstall *InstallWebhookParams `json:"pending_webhook_install,omitempty"`
+	// EntityWebhookPath is set if the webhook of the entity was installed using the URL
+	// unique to the entity. Webhooks received on the shared controller URL are rejected.
+	EntityWebhookPath bool `json:"entity_webhook_path,omitempty"`
+	// MaxConcurrentJobs is the maximum number of jobs this entity can have runners
+	// for at the same time. A value of 0 means no limit.
+	MaxConcurrentJobs uint `json:"max_concurrent_jobs,omitempty"`
+	// RoutingRules are used to choose the pool in which a runner is created for a
+	// queued job. When set, they take precedence over the pool balancer type.
+	RoutingRules []RoutingRule `json:"routing_rules,omitempty"`
+	// PreviousWebhookSecretExpiresAt is set while the webhook secret is being rotated.
+	// Until then, webhooks signed with the previous secret are still accepted.
+	PreviousWebhookSecretExpiresAt *time.Time `json:"previous_webhook_secret_expires_at,omitempty"`
 	// Do not serialize sensitive info.
-	WebhookSecret string `json:"-"`
+	WebhookSecret         string `json:"-"`
+	PreviousWebhookSecret string `json:"-"`
 }
 
 func (o Organization) GetEntity() (GithubEntity, error) {
@@ -465,6 +1121,14 @@ func (o Organization) GetEntity() (GithubEntity, error) {
 		WebhookSecret:    o.WebhookSecret,
 		PoolBalancerType: o.PoolBalancerType,
 		Credentials:      o.Credentials,
+
+		PreviousWebhookSecret:          o.PreviousWebhookSecret,
+		PreviousWebhookSecretExpiresAt: o.PreviousWebhookSecretExpiresAt,
+
+		PendingWebhookInstall: o.PendingWebhookInstall,
+		MaxConcurrentJobs:     o.MaxConcurrentJobs,
+		EntityWebhookPath:     o.EntityWebhookPath,
+		RoutingRules:          o.RoutingRules,
 	}, nil
 }
 
@@ -499,8 +1163,19 @@ type Enterprise struct {
 	PoolManagerStatus PoolManagerStatus `json:"pool_manager_status,omitempty"`
 	PoolBalancerType  PoolBalancerType  `json:"pool_balancing_type,omitempty"`
 	Endpoint          GithubEndpoint    `json:"endpoint,omitempty"`
+	Events            []StatusMessage   `json:"events,omitempty"`
+	// MaxConcurrentJobs is the maximum number of jobs this entity can have runners
+	// for at the same time. A value of 0 means no limit.
+	MaxConcurrentJobs uint `json:"max_concurrent_jobs,omitempty"`
+	// RoutingRules are used to choose the pool in which a runner is created for a
+	// queued job. When set, they take precedence over the pool balancer type.
+	RoutingRules []RoutingRule `json:"routing_rules,omitempty"`
+	// PreviousWebhookSecretExpiresAt is set while the webhook secret is being rotated.
+	// Until then, webhooks signed with the previous secret are still accepted.
+	PreviousWebhookSecretExpiresAt *time.Time `json:"previous_webhook_secret_expires_at,omitempty"`
 	// Do not serialize sensitive info.
-	WebhookSecret string `json:"-"`
+	WebhookSecret         string `json:"-"`
+	PreviousWebhookSecret string `json:"-"`
 }
 
 func (e Enterprise) GetEntity() (GithubEntity, error) {
@@ -514,6 +1189,12 @@ func (e Enterprise) GetEntity() (GithubEntity, error) {
 		WebhookSecret:    e.WebhookSecret,
 		PoolBalancerType: e.PoolBalancerType,
 		Credentials:      e.Credentials,
+
+		PreviousWebhookSecret:          e.PreviousWebhookSecret,
+		PreviousWebhookSecretExpiresAt: e.PreviousWebhookSecretExpiresAt,
+
+		MaxConcurrentJobs: e.MaxConcurrentJobs,
+		RoutingRules:      e.RoutingRules,
 	}, nil
 }
 
@@ -545,11 +1226,130 @@ type User struct {
 	FullName  string    `json:"full_name,omitempty"`
 	Enabled   bool      `json:"enabled,omitempty"`
 	IsAdmin   bool      `json:"is_admin,omitempty"`
+	// FailedLoginAttempts is the number of consecutive failed logins of the user.
+	FailedLoginAttempts uint `json:"failed_login_attempts"`
+	// LockedUntil is set while the user is locked out after repeated failed logins.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// Role is the role of the user on all repositories, organizations and enterprises.
+	// Users without a role can only access the entities they were granted a role on.
+	Role UserRole `json:"role,omitempty"`
+	// Grants are the roles of the user on single entities.
+	Grants []RoleGrant `json:"grants,omitempty"`
 	// Do not serialize sensitive info.
 	Password   string `json:"-"`
 	Generation uint   `json:"-"`
 }
 
+// UserRole defines what a user is allowed to do.
+type UserRole string
+
+const (
+	// UserRoleAdmin can manage everything, including users and their roles. There is
+	// only one admin, the user created when the controller is initialized.
+	UserRoleAdmin UserRole = "admin"
+	// UserRoleOperator can view entities and manage their pools.
+	UserRoleOperator UserRole = "operator"
+	// UserRoleViewer can view entities, along with their pools and runners.
+	UserRoleViewer UserRole = "viewer"
+)
+
+// CanManage returns true if the role allows managing pools.
+func (u UserRole) CanManage() bool {
+	return u == UserRoleAdmin || u == UserRoleOperator
+}
+
+// CanView returns true if the role allows viewing entities, pools and runners.
+func (u UserRole) CanView() bool {
+	return u.CanManage() || u == UserRoleViewer
+}
+
+// RoleGrant gives a user a role on a single repository, organization or enterprise.
+type RoleGrant struct {
+	ID         string           `json:"id,omitempty"`
+	CreatedAt  time.Time        `json:"created_at,omitempty"`
+	Role       UserRole         `json:"role,omitempty"`
+	EntityType GithubEntityType `json:"entity_type,omitempty"`
+	EntityID   string           `json:"entity_id,omitempty"`
+}
+
+// APITokenPrefix is the prefix of API tokens. It tells them apart from JWT tokens.
+const APITokenPrefix = "garm_"
+
+// APITokenScope limits what an API token can be used for. Scopes are made of a resource
+// and an access level, separated by a colon. Write access includes read access.
+type APITokenScope string
+
+const (
+	APITokenScopeEntitiesRead   APITokenScope = "entities:read"
+	APITokenScopeEntitiesWrite  APITokenScope = "entities:write"
+	APITokenScopePoolsRead      APITokenScope = "pools:read"
+	APITokenScopePoolsWrite     APITokenScope = "pools:write"
+	APITokenScopeInstancesRead  APITokenScope = "instances:read"
+	APITokenScopeInstancesWrite APITokenScope = "instances:write"
+	APITokenScopeJobsRead       APITokenScope = "jobs:read"
+	APITokenScopeJobsWrite      APITokenScope = "jobs:write"
+)
+
+// APITokenScopes holds all valid API token scopes.
+var APITokenScopes = []APITokenScope{
+	APITokenScopeEntitiesRead,
+	APITokenScopeEntitiesWrite,
+	APITokenScopePoolsRead,
+	APITokenScopePoolsWrite,
+	APITokenScopeInstancesRead,
+	APITokenScopeInstancesWrite,
+	APITokenScopeJobsRead,
+	APITokenScopeJobsWrite,
+}
+
+// Allows returns true if the scope grants the required scope.
+func (a APITokenScope) Allows(required APITokenScope) bool {
+	if a == required {
+		return true
+	}
+	resource, access, _ := strings.Cut(string(a), ":")
+	requiredResource, requiredAccess, _ := strings.Cut(string(required), ":")
+	return resource == requiredResource && access == "write" && requiredAccess == "read"
+}
+
+// APIToken is a long lived token that lets automation use the API on behalf of
+// a user, without the password of the user. What the token can be used for is
+// limited by its scopes, and by the role of the user.
+type APIToken struct {
+	ID         string          `json:"id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+	Scopes     []APITokenScope `json:"scopes,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time      `json:"last_used_at,omitempty"`
+	// Token is the secret token. It is only returned when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+// IsExpired returns true if the token expired at the given time.
+func (a APIToken) IsExpired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// AllowsScope returns true if one of the scopes of the token grants the required scope.
+func (a APIToken) AllowsScope(required APITokenScope) bool {
+	for _, scope := range a.Scopes {
+		if scope.Allows(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// used by swagger client generated code
+type APITokens []APIToken
+
+// IsLockedOut returns true if the user is locked out at the given time.
+func (u User) IsLockedOut(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
 // JWTResponse holds the JWT token returned as a result of a
 // successful auth
 type JWTResponse struct {
@@ -560,6 +1360,9 @@ type ControllerInfo struct {
 	// ControllerID is the unique ID of this controller. This ID gets generated
 	// automatically on controller init.
 	ControllerID uuid.UUID `json:"controller_id,omitempty"`
+	// PreviousControllerID is set while a controller ID migration is running. Runners
+	// and webhooks that use this ID are still considered to belong to this controller.
+	PreviousControllerID *uuid.UUID `json:"previous_controller_id,omitempty"`
 	// Hostname is the hostname of the machine that runs this controller. In the
 	// future, this field will be migrated to a separate table that will keep track
 	// of each the controller nodes that are part of a cluster. This will happen when
@@ -598,6 +1401,120 @@ type ControllerInfo struct {
 	MinimumJobAgeBackoff uint `json:"minimum_job_age_backoff,omitempty"`
 	// Version is the version of the GARM controller.
 	Version string `json:"version,omitempty"`
+	// ObserverMode is true when the controller only observes jobs and records where
+	// it would have created runners, without calling any provider.
+	ObserverMode bool `json:"observer_mode,omitempty"`
+	// URLSets are additional named sets of metadata and callback URLs. Pools can select
+	// one of them, so runners in isolated networks reach the controller through a
+	// different address.
+	URLSets []ControllerURLSet `json:"url_sets,omitempty"`
+}
+
+// ControllerURLSet is a named set of URLs through which runners reach the controller.
+type ControllerURLSet struct {
+	Name        string `json:"name"`
+	MetadataURL string `json:"metadata_url"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// RunnerURLs returns the metadata and callback URLs of the given URL set, or the
+// default URLs if no URL set is given or the URL set does not exist.
+func (c ControllerInfo) RunnerURLs(urlSet string) (metadataURL, callbackURL string) {
+	if urlSet != "" {
+		for _, set := range c.URLSets {
+			if set.Name == urlSet {
+				return set.MetadataURL, set.CallbackURL
+			}
+		}
+	}
+	return c.MetadataURL, c.CallbackURL
+}
+
+// HasURLSet returns true if the controller has a URL set with the given name.
+func (c ControllerInfo) HasURLSet(name string) bool {
+	for _, set := range c.URLSets {
+		if set.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrationWebhookStatus is the outcome of moving the webhook of an entity to the
+// new controller webhook URL.
+type MigrationWebhookStatus struct {
+	EntityID   string           `json:"entity_id"`
+	EntityType GithubEntityType `json:"entity_type"`
+	Name       string           `json:"name"`
+	// Reinstalled is true if a webhook was moved to the new URL. It is false if the
+	// entity had no webhook managed by GARM.
+	Reinstalled bool   `json:"reinstalled"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ControllerIDMigration tracks the change of the controller ID. The migration is
+// carried out in the background, one phase at a time, and resumes from the last
+// phase if GARM is restarted.
+type ControllerIDMigration struct {
+	ID                   string         `json:"id"`
+	PreviousControllerID uuid.UUID      `json:"previous_controller_id"`
+	NewControllerID      uuid.UUID      `json:"new_controller_id"`
+	Phase                MigrationPhase `json:"phase"`
+	// Webhooks holds the entities whose webhooks were processed. Entities that failed
+	// are retried until the webhook is moved.
+	Webhooks []MigrationWebhookStatus `json:"webhooks,omitempty"`
+	// PendingRunners is the number of runners labeled with the previous controller ID
+	// that still need to go away before the migration completes.
+	PendingRunners uint `json:"pending_runners"`
+	// SwitchedAt is the time the new controller ID was saved. Instances created before
+	// this time carry the previous controller ID.
+	SwitchedAt  *time.Time `json:"switched_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ControllerNode is a GARM controller that takes part in a cluster. The nodes
+// of a cluster share the same database, and split the entities between them.
+type ControllerNode struct {
+	ID            string    `json:"id,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	LastHeartbeat time.Time `json:"last_heartbeat,omitempty"`
+	// Entities holds the IDs of the repositories, organizations and enterprises
+	// this node holds a lease on.
+	Entities []string `json:"entities,omitempty"`
+}
+
+// used by swagger client generated code
+type ControllerNodes []ControllerNode
+
+// BootstrapTransformRequest is sent to the bootstrap transformer for every runner that
+// is about to be created. The instance token is removed from the bootstrap params.
+type BootstrapTransformRequest struct {
+	EntityType GithubEntityType               `json:"entity_type"`
+	Owner      string                         `json:"owner"`
+	Name       string                         `json:"name,omitempty"`
+	Bootstrap  commonParams.BootstrapInstance `json:"bootstrap"`
+}
+
+// BootstrapTransformResponse holds the bootstrap params the transformer changes. Fields
+// that are not set are left as they are.
+type BootstrapTransformResponse struct {
+	Labels          []string                      `json:"labels,omitempty"`
+	ExtraSpecs      json.RawMessage               `json:"extra_specs,omitempty"`
+	UserDataOptions *commonParams.UserDataOptions `json:"user_data_options,omitempty"`
+}
+
+// GithubTokenSummary describes what a fine-grained PAT was able to access when it was
+// added or last updated. GitHub does not report the permissions granted to a fine-grained
+// token, so those are checked against each entity the credentials are attached to.
+type GithubTokenSummary struct {
+	// Repositories holds the full names of the repositories the token can access.
+	Repositories []string `json:"repositories,omitempty"`
+	// ExpiresAt is the expiration date of the token, as reported by GitHub. It is
+	// not set for tokens that don't expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CheckedAt time.Time  `json:"checked_at,omitempty"`
 }
 
 type GithubCredentials struct {
@@ -615,11 +1532,28 @@ type GithubCredentials struct {
 	Enterprises   []Enterprise   `json:"enterprises,omitempty"`
 	Endpoint      GithubEndpoint `json:"endpoint,omitempty"`
 
+	// TokenSummary is only set for fine-grained PATs.
+	TokenSummary *GithubTokenSummary `json:"token_summary,omitempty"`
+
 	// Do not serialize sensitive info.
 	CredentialsPayload []byte `json:"-"`
 }
 
-func (g GithubCredentials) GetHTTPClient(ctx context.Context) (*http.Client, error) {
+// IsFineGrainedPAT returns true if the credentials hold a fine-grained personal access token.
+func (g GithubCredentials) IsFineGrainedPAT() bool {
+	if g.AuthType != GithubAuthTypePAT {
+		return false
+	}
+	var pat GithubPAT
+	if err := json.Unmarshal(g.CredentialsPayload, &pat); err != nil {
+		return false
+	}
+	return pat.IsFineGrained()
+}
+
+// HTTPTransport returns a transport that trusts the CA bundle of the credentials, if one
+// was set.
+func (g GithubCredentials) HTTPTransport() (*http.Transport, error) {
 	var roots *x509.CertPool
 	if g.CABundle != nil {
 		roots = x509.NewCertPool()
@@ -629,22 +1563,38 @@ func (g GithubCredentials) GetHTTPClient(ctx context.Context) (*http.Client, err
 		}
 	}
 
-	httpTransport := &http.Transport{
+	return &http.Transport{
 		TLSClientConfig: &tls.Config{
 			RootCAs:    roots,
 			MinVersion: tls.VersionTLS12,
 		},
+	}, nil
+}
+
+// GithubApp returns the github app details of the credentials.
+func (g GithubCredentials) GithubApp() (GithubApp, error) {
+	var app GithubApp
+	if err := json.Unmarshal(g.CredentialsPayload, &app); err != nil {
+		return GithubApp{}, fmt.Errorf("failed to unmarshal github app credentials: %w", err)
+	}
+	if app.AppID == 0 || app.InstallationID == 0 || len(app.PrivateKeyBytes) == 0 {
+		return GithubApp{}, fmt.Errorf("github app credentials are missing required fields")
+	}
+	return app, nil
+}
+
+func (g GithubCredentials) GetHTTPClient(ctx context.Context) (*http.Client, error) {
+	httpTransport, err := g.HTTPTransport()
+	if err != nil {
+		return nil, err
 	}
 
 	var tc *http.Client
 	switch g.AuthType {
 	case GithubAuthTypeApp:
-		var app GithubApp
-		if err := json.Unmarshal(g.CredentialsPayload, &app); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal github app credentials: %w", err)
-		}
-		if app.AppID == 0 || app.InstallationID == 0 || len(app.PrivateKeyBytes) == 0 {
-			return nil, fmt.Errorf("github app credentials are missing required fields")
+		app, err := g.GithubApp()
+		if err != nil {
+			return nil, err
 		}
 		itr, err := ghinstallation.New(httpTransport, app.AppID, app.InstallationID, app.PrivateKeyBytes)
 		if err != nil {
@@ -710,6 +1660,632 @@ type Provider struct {
 	Name         string       `json:"name,omitempty"`
 	ProviderType ProviderType `json:"type,omitempty"`
 	Description  string       `json:"description,omitempty"`
+	// NameConstraints holds the constraints the provider imposes on instance names.
+	NameConstraints InstanceNameConstraints `json:"name_constraints,omitempty"`
+	// Paused is set when the provider was paused. Pools that use a paused provider
+	// don't create new instances, but existing instances can still be removed.
+	Paused      bool       `json:"paused"`
+	PauseReason string     `json:"pause_reason,omitempty"`
+	PausedAt    *time.Time `json:"paused_at,omitempty"`
+	// InterfaceVersion is the version of the provider interface GARM uses to talk
+	// to the provider.
+	InterfaceVersion string `json:"interface_version,omitempty"`
+	// Capabilities are the optional features the provider supports.
+	Capabilities []ProviderCapability `json:"capabilities,omitempty"`
+	// VersionInfo is the version information reported by the provider binary.
+	VersionInfo *ProviderVersionInfo `json:"version_info,omitempty"`
+	// PauseWhenUnhealthy is set if pools that use the provider stop creating new
+	// instances while the provider fails its health checks.
+	PauseWhenUnhealthy bool `json:"pause_when_unhealthy,omitempty"`
+}
+
+type ProviderCapability string
+
+const (
+	// ProviderCapabilityJITConfig is set for providers that can bootstrap runners
+	// using just in time configurations.
+	ProviderCapabilityJITConfig ProviderCapability = "jit_config"
+	// ProviderCapabilityDiagnostics is set for providers that can collect diagnostics
+	// about an instance.
+	ProviderCapabilityDiagnostics ProviderCapability = "diagnostics"
+	// ProviderCapabilityInstanceSweep is set for providers that can list all the
+	// instances of the controller, regardless of pool.
+	ProviderCapabilityInstanceSweep ProviderCapability = "instance_sweep"
+	// ProviderCapabilitySnapshot is set for providers that can run a script on an
+	// instance and snapshot it into a new image.
+	ProviderCapabilitySnapshot ProviderCapability = "snapshot"
+	// ProviderCapabilityPoolValidation is set for providers that can check the image
+	// and flavor of pools.
+	ProviderCapabilityPoolValidation ProviderCapability = "pool_validation"
+)
+
+// ProviderVersionInfo holds the version information reported by a provider binary.
+// The information is cached, and CheckedAt is the time it was last fetched.
+type ProviderVersionInfo struct {
+	Version string `json:"version,omitempty"`
+	// SupportedInterfaceVersions are the versions of the provider interface the
+	// binary supports. Only providers that use interface version v0.1.1 or later
+	// report them.
+	SupportedInterfaceVersions []string  `json:"supported_interface_versions,omitempty"`
+	CheckedAt                  time.Time `json:"checked_at"`
+	// Error is set if the version information could not be fetched.
+	Error string `json:"error,omitempty"`
+}
+
+// ProviderPause holds information about a paused provider.
+type ProviderPause struct {
+	ProviderName string    `json:"provider_name,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	PausedAt     time.Time `json:"paused_at,omitempty"`
+}
+
+// OrphanReason is the reason an instance reported by a provider is considered orphaned.
+type OrphanReason string
+
+const (
+	// OrphanReasonNoInstanceRecord is set for instances that have no database record.
+	OrphanReasonNoInstanceRecord OrphanReason = "no_instance_record"
+	// OrphanReasonProviderMismatch is set for instances that have a database record, but
+	// belong to a pool that uses a different provider. GARM never removes these instances.
+	OrphanReasonProviderMismatch OrphanReason = "provider_mismatch"
+)
+
+// OrphanedInstance is an instance reported by a provider, for which GARM has no
+// matching record.
+type OrphanedInstance struct {
+	ProviderID string                      `json:"provider_id,omitempty"`
+	Name       string                      `json:"name,omitempty"`
+	Status     commonParams.InstanceStatus `json:"status,omitempty"`
+	Reason     OrphanReason                `json:"reason,omitempty"`
+	// Removed is set if the instance was removed from the provider.
+	Removed bool `json:"removed"`
+	// Error holds the error returned by the provider, if removing the instance failed.
+	Error string `json:"error,omitempty"`
+}
+
+// OrphanSweep is the result of looking for orphaned instances in a provider.
+type OrphanSweep struct {
+	ProviderName string `json:"provider_name,omitempty"`
+	// DryRun is set if orphaned instances were only listed, and not removed.
+	DryRun    bool               `json:"dry_run"`
+	Instances []OrphanedInstance `json:"instances"`
+	SweptAt   time.Time          `json:"swept_at,omitempty"`
+}
+
+// RunnerUsage holds the lifetime of a runner. Usage records are kept after the runner
+// and its pool are removed.
+type RunnerUsage struct {
+	InstanceID   string           `json:"instance_id,omitempty"`
+	Name         string           `json:"name,omitempty"`
+	PoolID       string           `json:"pool_id,omitempty"`
+	ProviderName string           `json:"provider_name,omitempty"`
+	EntityID     string           `json:"entity_id,omitempty"`
+	EntityType   GithubEntityType `json:"entity_type,omitempty"`
+	CreatedAt    time.Time        `json:"created_at,omitempty"`
+	// RemovedAt is nil while the runner still exists.
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+	// JobsServed is the number of jobs the runner picked up.
+	JobsServed uint       `json:"jobs_served"`
+	FirstJobAt *time.Time `json:"first_job_at,omitempty"`
+	// BusySince is set while the runner is running a job.
+	BusySince *time.Time `json:"busy_since,omitempty"`
+	// BusySeconds is the time the runner spent running jobs, excluding the job it is
+	// currently running.
+	BusySeconds float64 `json:"busy_seconds"`
+}
+
+// Runtime returns the amount of time the runner existed between since and until.
+func (r RunnerUsage) Runtime(since, until time.Time) time.Duration {
+	start := r.CreatedAt
+	if start.Before(since) {
+		start = since
+	}
+	end := until
+	if r.RemovedAt != nil && r.RemovedAt.Before(until) {
+		end = *r.RemovedAt
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// BusyTime returns the amount of time the runner spent running jobs until the given
+// time. It never exceeds the lifetime of the runner.
+func (r RunnerUsage) BusyTime(until time.Time) time.Duration {
+	busy := time.Duration(r.BusySeconds * float64(time.Second))
+	if r.BusySince != nil && r.BusySince.Before(until) {
+		busy += until.Sub(*r.BusySince)
+	}
+	return min(busy, r.Runtime(time.Time{}, until))
+}
+
+// FirstJobLatency returns the time it took the runner to pick up its first job, after
+// it was created. It returns false if the runner never ran a job.
+func (r RunnerUsage) FirstJobLatency() (time.Duration, bool) {
+	if r.FirstJobAt == nil {
+		return 0, false
+	}
+	return max(r.FirstJobAt.Sub(r.CreatedAt), 0), true
+}
+
+// UsageSummary holds the runner usage of a pool, provider or entity.
+type UsageSummary struct {
+	PoolID       string           `json:"pool_id,omitempty"`
+	ProviderName string           `json:"provider_name,omitempty"`
+	EntityID     string           `json:"entity_id,omitempty"`
+	EntityType   GithubEntityType `json:"entity_type,omitempty"`
+	// EntityName is empty if the entity was removed.
+	EntityName string `json:"entity_name,omitempty"`
+	// Runners is the number of runners that existed during the reported period.
+	Runners uint `json:"runners"`
+	// RuntimeMinutes is the total runtime of the runners during the reported period.
+	RuntimeMinutes float64 `json:"runtime_minutes"`
+}
+
+// UsageReport aggregates the runtime of runners over a period of time, per pool,
+// provider and entity.
+type UsageReport struct {
+	Since     time.Time      `json:"since,omitempty"`
+	Until     time.Time      `json:"until,omitempty"`
+	Pools     []UsageSummary `json:"pools"`
+	Providers []UsageSummary `json:"providers"`
+	Entities  []UsageSummary `json:"entities"`
+}
+
+// PoolEfficiency holds statistics about how well the runners of a pool were used.
+type PoolEfficiency struct {
+	PoolID       string           `json:"pool_id"`
+	ProviderName string           `json:"provider_name,omitempty"`
+	EntityID     string           `json:"entity_id,omitempty"`
+	EntityType   GithubEntityType `json:"entity_type,omitempty"`
+	// EntityName is empty if the entity was removed.
+	EntityName string `json:"entity_name,omitempty"`
+	// Runners is the number of runners that existed during the reported period.
+	Runners    uint `json:"runners"`
+	JobsServed uint `json:"jobs_served"`
+	// JobsPerRunner is the average number of jobs each runner picked up.
+	JobsPerRunner float64 `json:"jobs_per_runner"`
+	// UnusedRunners is the number of runners that were removed without running a job.
+	UnusedRunners uint `json:"unused_runners"`
+	// AvgFirstJobLatencySeconds is the average time between the creation of a runner
+	// and the moment it picked up its first job. It includes the boot time.
+	AvgFirstJobLatencySeconds float64 `json:"avg_first_job_latency_seconds"`
+	BusyMinutes               float64 `json:"busy_minutes"`
+	// IdleMinutes is the time the runners existed without running a job, including
+	// the time they spent booting.
+	IdleMinutes float64 `json:"idle_minutes"`
+	// IdlePercent is the percentage of the runtime of the runners that was idle.
+	IdlePercent float64 `json:"idle_percent"`
+}
+
+// EfficiencyReport holds the efficiency of the runners of each pool, over a period of time.
+type EfficiencyReport struct {
+	Since time.Time        `json:"since,omitempty"`
+	Until time.Time        `json:"until,omitempty"`
+	Pools []PoolEfficiency `json:"pools"`
+}
+
+const (
+	// EntityConfigVersion is the version of the format used to export entity configs.
+	EntityConfigVersion = 1
+	// EntityConfigSecretPlaceholder replaces secrets in exported entity configs. It must
+	// be replaced with the actual secret before the config is imported as a new entity.
+	EntityConfigSecretPlaceholder = "<REPLACE_ME>"
+)
+
+// EntityConfig is the configuration of a repository, organization or enterprise, along
+// with its pools. It is exported as YAML, using the same field names as the API, and
+// can be imported in another entity or controller. Secrets are replaced with
+// EntityConfigSecretPlaceholder.
+type EntityConfig struct {
+	Version    int              `json:"version"`
+	EntityType GithubEntityType `json:"entity_type"`
+	// Owner is only set for repositories.
+	Owner           string `json:"owner,omitempty"`
+	Name            string `json:"name"`
+	CredentialsName string `json:"credentials_name"`
+	// Endpoint is the name of the GitHub endpoint of the entity. Entities with the
+	// same name on different endpoints are different entities. If left out, it is
+	// the endpoint of the credentials.
+	Endpoint          string             `json:"endpoint,omitempty"`
+	WebhookSecret     string             `json:"webhook_secret"`
+	PoolBalancerType  PoolBalancerType   `json:"pool_balancer_type,omitempty"`
+	MaxConcurrentJobs uint               `json:"max_concurrent_jobs,omitempty"`
+	RoutingRules      []RoutingRule      `json:"routing_rules,omitempty"`
+	Pools             []EntityConfigPool `json:"pools"`
+}
+
+// EntityConfigPool is a pool in an entity config. The ID is set when the config is
+// exported and is used to match the pool to an existing one when a controller config
+// is applied. It is ignored when pools are imported.
+type EntityConfigPool struct {
+	ID string `json:"id,omitempty"`
+	CreatePoolParams
+}
+
+// MarshalYAML converts the config to a YAML node through its JSON form, so the YAML
+// document uses the same field names, in the same order, as the API.
+func (e EntityConfig) MarshalYAML() (interface{}, error) {
+	type entityConfig EntityConfig
+	return yamlNodeFromJSON(entityConfig(e))
+}
+
+// UnmarshalYAML decodes a config written with the same field names as the API.
+func (e *EntityConfig) UnmarshalYAML(value *yaml.Node) error {
+	type entityConfig EntityConfig
+	var cfg entityConfig
+	if err := decodeYAMLAsJSON(value, &cfg); err != nil {
+		return err
+	}
+	*e = EntityConfig(cfg)
+	return nil
+}
+
+// yamlNodeFromJSON returns the YAML node of the JSON form of v.
+func yamlNodeFromJSON(v interface{}) (*yaml.Node, error) {
+	asJSON, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, so the document can be parsed as is. The flow style and the
+	// quoting of the JSON document are dropped, to get a regular YAML document.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(asJSON, &doc); err != nil {
+		return nil, err
+	}
+	var resetStyle func(node *yaml.Node)
+	resetStyle = func(node *yaml.Node) {
+		node.Style = 0
+		for _, child := range node.Content {
+			resetStyle(child)
+		}
+	}
+	resetStyle(&doc)
+	return doc.Content[0], nil
+}
+
+// decodeYAMLAsJSON decodes a YAML node into v, using the JSON field names of v.
+func decodeYAMLAsJSON(value *yaml.Node, v interface{}) error {
+	var generic interface{}
+	if err := value.Decode(&generic); err != nil {
+		return err
+	}
+	asJSON, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(asJSON, v)
+}
+
+// EntityConfigImport is the result of importing an entity config as a new entity.
+type EntityConfigImport struct {
+	EntityID   string           `json:"entity_id,omitempty"`
+	EntityType GithubEntityType `json:"entity_type,omitempty"`
+	Pools      []Pool           `json:"pools"`
+}
+
+// ControllerConfigVersion is the version of the format used to export controller configs.
+const ControllerConfigVersion = 1
+
+// ControllerConfig is the declarative configuration of all the entities of a controller,
+// along with their pools and the credentials they use. It is exported as YAML and can
+// be applied to a controller, which then creates, updates and optionally removes
+// entities and pools until they match the config. Secrets are left out.
+type ControllerConfig struct {
+	Version     int                 `json:"version"`
+	Credentials []CredentialsConfig `json:"credentials,omitempty"`
+	Entities    []EntityConfig      `json:"entities,omitempty"`
+}
+
+// MarshalYAML converts the config to a YAML node through its JSON form.
+func (c ControllerConfig) MarshalYAML() (interface{}, error) {
+	type controllerConfig ControllerConfig
+	return yamlNodeFromJSON(controllerConfig(c))
+}
+
+// UnmarshalYAML decodes a config written with the same field names as the API.
+func (c *ControllerConfig) UnmarshalYAML(value *yaml.Node) error {
+	type controllerConfig ControllerConfig
+	var cfg controllerConfig
+	if err := decodeYAMLAsJSON(value, &cfg); err != nil {
+		return err
+	}
+	*c = ControllerConfig(cfg)
+	return nil
+}
+
+// CredentialsConfig describes GitHub credentials in a controller config. Credentials
+// hold secrets, so they are not created when a config is applied. They must already
+// exist, and only their description is updated.
+type CredentialsConfig struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Endpoint    string         `json:"endpoint"`
+	AuthType    GithubAuthType `json:"auth_type"`
+}
+
+type ConfigChangeAction string
+
+const (
+	ConfigChangeActionCreate ConfigChangeAction = "create"
+	ConfigChangeActionUpdate ConfigChangeAction = "update"
+	ConfigChangeActionDelete ConfigChangeAction = "delete"
+)
+
+type ConfigResourceType string
+
+const (
+	ConfigResourceCredentials  ConfigResourceType = "credentials"
+	ConfigResourceRepository   ConfigResourceType = "repository"
+	ConfigResourceOrganization ConfigResourceType = "organization"
+	ConfigResourceEnterprise   ConfigResourceType = "enterprise"
+	ConfigResourcePool         ConfigResourceType = "pool"
+)
+
+// ConfigChange is a change made, or planned, while applying a controller config.
+type ConfigChange struct {
+	Action       ConfigChangeAction `json:"action"`
+	ResourceType ConfigResourceType `json:"resource_type"`
+	// Name identifies the resource in the config. Pools are identified by the name of
+	// their entity and their position in the list of pools of the entity.
+	Name string `json:"name"`
+	// ID is the ID of the resource, if it exists.
+	ID string `json:"id,omitempty"`
+	// Fields holds the names of the fields that are changed by an update.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ControllerConfigApplyResult holds the changes made while applying a controller config.
+type ControllerConfigApplyResult struct {
+	// DryRun is set if the changes were only planned, and not made.
+	DryRun  bool           `json:"dry_run"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// BulkDeleteInstancesResult holds the runners marked for deletion by a bulk delete.
+type BulkDeleteInstancesResult struct {
+	// DryRun is set if the runners were only listed, and not marked for deletion.
+	DryRun bool `json:"dry_run"`
+	// Instances holds the names of the matching runners.
+	Instances []string `json:"instances"`
+}
+
+// PoolTagsChange describes how the tags of a pool change in a bulk tag operation.
+type PoolTagsChange struct {
+	PoolID  string   `json:"pool_id,omitempty"`
+	OldTags []string `json:"old_tags,omitempty"`
+	NewTags []string `json:"new_tags,omitempty"`
+}
+
+// JobMatchChange describes a queued job that stops or starts matching a pool after
+// a bulk tag operation.
+type JobMatchChange struct {
+	JobID         int64    `json:"job_id,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	PoolID        string   `json:"pool_id,omitempty"`
+	MatchedBefore bool     `json:"matched_before"`
+	MatchesAfter  bool     `json:"matches_after"`
+}
+
+// BulkUpdatePoolTagsResult holds the outcome of a bulk tag operation.
+type BulkUpdatePoolTagsResult struct {
+	// DryRun is true if the changes were not applied.
+	DryRun bool             `json:"dry_run"`
+	Pools  []PoolTagsChange `json:"pools"`
+	Jobs   []JobMatchChange `json:"jobs"`
+}
+
+// PoolMatch is a pool that matches the labels of a job.
+type PoolMatch struct {
+	// Order is the position in which the pool is tried, starting from 1.
+	Order        int    `json:"order"`
+	PoolID       string `json:"pool_id"`
+	ProviderName string `json:"provider_name"`
+	Priority     uint   `json:"priority"`
+	Tags         []Tag  `json:"tags"`
+	// Eligible is false if a runner can't be created in the pool right now, for
+	// example because the pool is disabled or full. Reason holds the cause.
+	Eligible bool   `json:"eligible"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PoolMatchResult lists the pools that would be tried, in order, to create a runner
+// for a job with the given labels.
+type PoolMatchResult struct {
+	Labels           []string         `json:"labels"`
+	PoolBalancerType PoolBalancerType `json:"pool_balancer_type"`
+	RoutingRules     []RoutingRule    `json:"routing_rules,omitempty"`
+	// Autoscaled is true if all the matching pools use the queue depth autoscaler,
+	// which creates the runners for new jobs instead.
+	Autoscaled bool        `json:"autoscaled"`
+	Pools      []PoolMatch `json:"pools"`
+}
+
+// DenyRule bans an image and flavor combination. Pools can not be created or updated
+// to use a combination that matches a rule, and no new instances are created in
+// existing pools that match one.
+type DenyRule struct {
+	ID        string    `json:"id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// ProviderName is the provider the rule applies to. An empty provider name
+	// applies the rule to all providers.
+	ProviderName string `json:"provider_name,omitempty"`
+	// ImagePattern is a regular expression matched against the whole image name. An
+	// empty pattern matches any image.
+	ImagePattern string `json:"image_pattern,omitempty"`
+	// FlavorPattern is a regular expression matched against the whole flavor name. An
+	// empty pattern matches any flavor.
+	FlavorPattern string `json:"flavor_pattern,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Matches returns true if the rule bans the image and flavor on the given provider.
+func (d DenyRule) Matches(providerName, image, flavor string) bool {
+	if d.ProviderName != "" && d.ProviderName != providerName {
+		return false
+	}
+	return matchesDenyPattern(d.ImagePattern, image) && matchesDenyPattern(d.FlavorPattern, flavor)
+}
+
+func matchesDenyPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+	if err != nil {
+		// Patterns are validated when the rule is created. A pattern that does not
+		// compile is treated as matching, so a bad rule errs on the side of denying.
+		return true
+	}
+	return re.MatchString(value)
+}
+
+// MatchingDenyRule returns the first rule that bans the image and flavor on the given provider.
+func MatchingDenyRule(rules []DenyRule, providerName, image, flavor string) (DenyRule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(providerName, image, flavor) {
+			return rule, true
+		}
+	}
+	return DenyRule{}, false
+}
+
+// used by swagger client generated code
+type DenyRules []DenyRule
+
+// CapacityReservation raises the minimum number of idle runners of a pool for a window
+// of time, to prepare for planned load. Once the window ends, the pool goes back to its
+// own minimum and the extra idle runners are scaled down.
+type CapacityReservation struct {
+	ID        string    `json:"id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	PoolID    string    `json:"pool_id,omitempty"`
+	// ExtraIdleRunners is added to the min idle runners of the pool during the window.
+	ExtraIdleRunners uint      `json:"extra_idle_runners,omitempty"`
+	StartTime        time.Time `json:"start_time,omitempty"`
+	EndTime          time.Time `json:"end_time,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+}
+
+// IsActive returns true if the given time is inside the window of the reservation.
+func (c CapacityReservation) IsActive(now time.Time) bool {
+	return !now.Before(c.StartTime) && now.Before(c.EndTime)
+}
+
+// Overlaps returns true if the window of the reservation overlaps the given window.
+func (c CapacityReservation) Overlaps(start, end time.Time) bool {
+	return c.StartTime.Before(end) && start.Before(c.EndTime)
+}
+
+// used by swagger client generated code
+type CapacityReservations []CapacityReservation
+
+// ProviderEnvironment holds the environment variables GARM passes to an external
+// provider, in addition to the ones configured in the config file. The values are
+// stored encrypted and are never returned by the API.
+type ProviderEnvironment struct {
+	ProviderName  string    `json:"provider_name,omitempty"`
+	VariableNames []string  `json:"variable_names,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+
+	Variables map[string]string `json:"-"`
+}
+
+// Environ returns the variables in the "key=value" form used by exec.Cmd.
+func (p ProviderEnvironment) Environ() []string {
+	ret := make([]string, 0, len(p.VariableNames))
+	for _, name := range p.VariableNames {
+		ret = append(ret, fmt.Sprintf("%s=%s", name, p.Variables[name]))
+	}
+	return ret
+}
+
+// InstanceNameConstraints describes the limits a provider imposes on the names
+// of the instances it creates.
+type InstanceNameConstraints struct {
+	// MaxLength is the maximum length of an instance name. A value of 0 means
+	// there is no limit.
+	MaxLength uint `json:"max_length,omitempty"`
+	// Charset is the set of characters allowed in an instance name.
+	Charset NameCharset `json:"charset,omitempty"`
+}
+
+func (n InstanceNameConstraints) isAllowed(r rune) bool {
+	switch n.Charset {
+	case NameCharsetAlphanumeric:
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-'
+	case NameCharsetDNSLabel:
+		return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-'
+	}
+	return true
+}
+
+func (n InstanceNameConstraints) sanitize(value string) string {
+	if n.Charset == NameCharsetDNSLabel {
+		value = strings.ToLower(value)
+	}
+	if n.Charset == NameCharsetAny {
+		return value
+	}
+
+	ret := []rune{}
+	for _, r := range value {
+		if !n.isAllowed(r) {
+			r = '-'
+		}
+		ret = append(ret, r)
+	}
+	return strings.Trim(string(ret), "-")
+}
+
+// NewInstanceID returns a random ID to append to the runner prefix. IDs for the
+// dns-label charset are generated from lower case letters and digits, as lowering
+// the case of a base62 ID would make collisions more likely.
+func (n InstanceNameConstraints) NewInstanceID() string {
+	if n.Charset != NameCharsetDNSLabel {
+		return util.NewID()
+	}
+	// 14 bytes always fit in maxInstanceIDLength base36 digits.
+	id := uuid.New()
+	return new(big.Int).SetBytes(id[:14]).Text(36)
+}
+
+// ValidatePrefix checks that runner names using the given prefix can always
+// be made to fit these constraints.
+func (n InstanceNameConstraints) ValidatePrefix(prefix string) error {
+	if n.sanitize(prefix) == "" {
+		return fmt.Errorf("runner prefix %q has no characters allowed by the %q charset", prefix, n.Charset)
+	}
+	// We need room for at least one character of the prefix, the hyphen and the ID.
+	if n.MaxLength > 0 && n.MaxLength < maxInstanceIDLength+2 {
+		return fmt.Errorf("max name length %d is too small; it must be at least %d", n.MaxLength, maxInstanceIDLength+2)
+	}
+	return nil
+}
+
+// FormatName composes an instance name from a runner prefix and a random ID.
+// Characters not allowed by the charset are replaced with hyphens and the
+// prefix is truncated if the name would exceed the maximum length. The same
+// input always yields the same name.
+func (n InstanceNameConstraints) FormatName(prefix, id string) (string, error) {
+	prefix = n.sanitize(prefix)
+	id = n.sanitize(id)
+	if prefix == "" || id == "" {
+		return "", fmt.Errorf("cannot compose a valid name from prefix and ID")
+	}
+
+	if n.MaxLength > 0 {
+		maxPrefixLen := int(n.MaxLength) - len(id) - 1
+		if maxPrefixLen < 1 {
+			return "", fmt.Errorf("max name length %d is too small", n.MaxLength)
+		}
+		if len(prefix) > maxPrefixLen {
+			prefix = strings.TrimRight(prefix[:maxPrefixLen], "-")
+		}
+	}
+	return fmt.Sprintf("%s-%s", prefix, id), nil
 }
 
 // used by swagger client generated code
@@ -718,8 +2294,85 @@ type Providers []Provider
 type PoolManagerStatus struct {
 	IsRunning     bool   `json:"running,omitempty"`
 	FailureReason string `json:"failure_reason,omitempty"`
+	// DegradedPools holds the pools that use a provider which fails its health checks.
+	DegradedPools []DegradedPool `json:"degraded_pools,omitempty"`
+}
+
+// DegradedPool holds information about a pool whose provider is unhealthy.
+type DegradedPool struct {
+	PoolID       string `json:"pool_id"`
+	ProviderName string `json:"provider_name"`
+	// Reason is the error returned by the last health check of the provider.
+	Reason string `json:"reason,omitempty"`
+	// Since is the time of the first failed health check.
+	Since time.Time `json:"since"`
+	// CreationPaused is set if no new instances are created in the pool until the
+	// provider recovers.
+	CreationPaused bool `json:"creation_paused"`
 }
 
+// WorkerHealth holds the liveness information of one of the worker loops
+// of a pool manager.
+type WorkerHealth struct {
+	Name          string    `json:"name"`
+	EntityID      string    `json:"entity_id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Restarts      uint      `json:"restarts"`
+	Healthy       bool      `json:"healthy"`
+}
+
+// used by swagger client generated code
+type WorkersHealth []WorkerHealth
+
+// InstanceLock holds information about a lock held by a pool manager on an
+// instance. While an instance is locked, it is not reconciled by any other worker.
+type InstanceLock struct {
+	InstanceName string    `json:"instance_name"`
+	EntityID     string    `json:"entity_id"`
+	Holder       string    `json:"holder"`
+	Fence        uint64    `json:"fence"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	Stale        bool      `json:"stale"`
+}
+
+// used by swagger client generated code
+type InstanceLocks []InstanceLock
+
+// EntityRateLimitUsage holds the number of API requests an entity made with a set of
+// credentials during the forecast window.
+type EntityRateLimitUsage struct {
+	EntityID          string  `json:"entity_id"`
+	Requests          int     `json:"requests"`
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	// Share is the percentage of the requests made with the credentials during the
+	// forecast window that belong to this entity.
+	Share float64 `json:"share"`
+}
+
+// RateLimitForecast holds an estimate of when the GitHub API rate limit of a set of
+// credentials will be exhausted, at the current consumption rate.
+type RateLimitForecast struct {
+	CredentialsID   uint      `json:"credentials_id"`
+	CredentialsName string    `json:"credentials_name"`
+	Limit           int       `json:"limit"`
+	Remaining       int       `json:"remaining"`
+	ResetAt         time.Time `json:"reset_at"`
+	SampledAt       time.Time `json:"sampled_at"`
+	// ConsumptionPerMinute is the rate at which the quota is consumed. It includes
+	// requests made with the same credentials by applications other than GARM.
+	ConsumptionPerMinute float64 `json:"consumption_per_minute"`
+	// ExhaustsAt is the time at which the quota is expected to run out. It is not
+	// set if the quota is not being consumed.
+	ExhaustsAt *time.Time `json:"exhausts_at,omitempty"`
+	// ExhaustsBeforeReset is true if the quota is expected to run out before it is
+	// reset by GitHub.
+	ExhaustsBeforeReset bool                   `json:"exhausts_before_reset"`
+	Entities            []EntityRateLimitUsage `json:"entities,omitempty"`
+}
+
+// used by swagger client generated code
+type RateLimitForecasts []RateLimitForecast
+
 type RunnerInfo struct {
 	Name   string   `json:"name,omitempty"`
 	Labels []string `json:"labels,omitempty"`
@@ -756,8 +2409,10 @@ type Job struct {
 	StartedAt   time.Time `json:"started_at,omitempty"`
 	CompletedAt time.Time `json:"completed_at,omitempty"`
 
-	GithubRunnerID  int64  `json:"runner_id,omitempty"`
-	RunnerName      string `json:"runner_name,omitempty"`
+	GithubRunnerID int64  `json:"runner_id,omitempty"`
+	RunnerName     string `json:"runner_name,omitempty"`
+	// PoolID is the ID of the pool of the GARM runner that picked up the job.
+	PoolID          string `json:"pool_id,omitempty"`
 	RunnerGroupID   int64  `json:"runner_group_id,omitempty"`
 	RunnerGroupName string `json:"runner_group_name,omitempty"`
 
@@ -782,24 +2437,182 @@ type Job struct {
 
 	LockedBy uuid.UUID `json:"locked_by,omitempty"`
 
+	// Decision is the last decision GARM made about creating a runner for the job.
+	Decision *JobDecision `json:"decision,omitempty"`
+
+	// Metadata is a JSON object set through the API by external systems, like
+	// deploy pipelines, to correlate the job with their own records.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
+// JobDecision records why GARM did or did not create a runner for a queued job.
+type JobDecision struct {
+	Decision JobDecisionType `json:"decision"`
+	// PoolID is the ID of the pool in which a runner was created for the job.
+	PoolID string `json:"pool_id,omitempty"`
+	// Reason explains the decision.
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Equals returns true if both decisions have the same outcome, ignoring the time
+// at which they were made.
+func (d *JobDecision) Equals(other *JobDecision) bool {
+	if d == nil || other == nil {
+		return d == other
+	}
+	return d.Decision == other.Decision && d.PoolID == other.PoolID && d.Reason == other.Reason
+}
+
 // used by swagger client generated code
 type Jobs []Job
 
+// RunnerDistributionEntry holds the number of jobs an organization or a repository
+// ran on shared runners, and the amount of runner time they used.
+type RunnerDistributionEntry struct {
+	// Name is the name of the organization, or the owner/name of the repository.
+	Name           string `json:"name"`
+	Jobs           uint   `json:"jobs"`
+	InProgressJobs uint   `json:"in_progress_jobs"`
+	CompletedJobs  uint   `json:"completed_jobs"`
+	// Runners is the number of distinct runners that picked up jobs.
+	Runners uint `json:"runners"`
+	// RunnerMinutes is the total time in minutes the jobs ran on runners. For jobs
+	// that are still in progress, the time until the report was generated is counted.
+	RunnerMinutes float64 `json:"runner_minutes"`
+}
+
+// EnterpriseRunnerDistribution shows how the runners of the enterprise pools were
+// used by the organizations and repositories of the enterprise, in a time window.
+type EnterpriseRunnerDistribution struct {
+	EnterpriseID   string                    `json:"enterprise_id"`
+	EnterpriseName string                    `json:"enterprise_name"`
+	Since          time.Time                 `json:"since"`
+	Until          time.Time                 `json:"until"`
+	Organizations  []RunnerDistributionEntry `json:"organizations"`
+	Repositories   []RunnerDistributionEntry `json:"repositories"`
+}
+
 type InstallWebhookParams struct {
 	WebhookEndpointType WebhookEndpointType `json:"webhook_endpoint_type,omitempty"`
 	InsecureSSL         bool                `json:"insecure_ssl,omitempty"`
+
+	// EntityWebhookPath installs the webhook using a URL unique to the entity. Events
+	// received on that URL are only ever handled by, and validated with the secret of,
+	// that entity.
+	EntityWebhookPath bool `json:"entity_webhook_path,omitempty"`
 }
 
+// ForgeRunnerCorrelation describes how a runner registered in GitHub relates to
+// the instances recorded by GARM.
+type ForgeRunnerCorrelation string
+
+const (
+	// ForgeRunnerManaged is a runner that matches an instance recorded by GARM.
+	ForgeRunnerManaged ForgeRunnerCorrelation = "managed"
+	// ForgeRunnerOrphaned is a runner created by this GARM controller, for which
+	// there is no instance record.
+	ForgeRunnerOrphaned ForgeRunnerCorrelation = "orphaned"
+	// ForgeRunnerForeign is a runner created by another GARM controller.
+	ForgeRunnerForeign ForgeRunnerCorrelation = "foreign"
+	// ForgeRunnerUnmanaged is a runner that was not created by GARM.
+	ForgeRunnerUnmanaged ForgeRunnerCorrelation = "unmanaged"
+	// ForgeRunnerPending is an instance recorded by GARM that has not yet
+	// registered in GitHub.
+	ForgeRunnerPending ForgeRunnerCorrelation = "pending"
+	// ForgeRunnerMissing is an instance recorded by GARM that should have been
+	// registered in GitHub, but could not be found there.
+	ForgeRunnerMissing ForgeRunnerCorrelation = "missing"
+)
+
+// ForgeRunner is a runner as seen by GitHub, joined with the instance GARM has
+// recorded for it, if any.
+type ForgeRunner struct {
+	// ForgeID is the ID of the runner in GitHub. It is not set for instances
+	// that could not be found in GitHub.
+	ForgeID int64    `json:"forge_id,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	// ForgeStatus is the status of the runner as reported by GitHub (online or offline).
+	ForgeStatus string `json:"forge_status,omitempty"`
+	Busy        bool   `json:"busy"`
+
+	Correlation ForgeRunnerCorrelation `json:"correlation"`
+
+	InstanceID     string                      `json:"instance_id,omitempty"`
+	PoolID         string                      `json:"pool_id,omitempty"`
+	InstanceStatus commonParams.InstanceStatus `json:"instance_status,omitempty"`
+	RunnerStatus   RunnerStatus                `json:"runner_status,omitempty"`
+}
+
+// ForgeRunners is a list of forge runners.
+type ForgeRunners []ForgeRunner
+
+// RunnerGroupVisibility defines which repositories can use the runners of a group.
+type RunnerGroupVisibility string
+
+const (
+	// RunnerGroupVisibilityAll allows all repositories to use the group.
+	RunnerGroupVisibilityAll RunnerGroupVisibility = "all"
+	// RunnerGroupVisibilitySelected allows only the selected repositories (or
+	// organizations, for enterprise groups) to use the group.
+	RunnerGroupVisibilitySelected RunnerGroupVisibility = "selected"
+	// RunnerGroupVisibilityPrivate allows only the private repositories of an
+	// organization to use the group. It is not available for enterprises.
+	RunnerGroupVisibilityPrivate RunnerGroupVisibility = "private"
+)
+
+// RunnerGroup is a GitHub runner group of an organization or enterprise.
+type RunnerGroup struct {
+	ID         int64                 `json:"id"`
+	Name       string                `json:"name"`
+	Visibility RunnerGroupVisibility `json:"visibility,omitempty"`
+	// Default is true for the group runners are added to when no group is set.
+	Default bool `json:"default"`
+	// Inherited is true for organization groups that are shared by the enterprise.
+	Inherited                bool     `json:"inherited"`
+	AllowsPublicRepositories bool     `json:"allows_public_repositories"`
+	RestrictedToWorkflows    bool     `json:"restricted_to_workflows"`
+	SelectedWorkflows        []string `json:"selected_workflows,omitempty"`
+}
+
+// RunnerGroups is a list of runner groups.
+type RunnerGroups []RunnerGroup
+
 type HookInfo struct {
 	ID          int64    `json:"id,omitempty"`
 	URL         string   `json:"url,omitempty"`
 	Events      []string `json:"events,omitempty"`
 	Active      bool     `json:"active,omitempty"`
 	InsecureSSL bool     `json:"insecure_ssl,omitempty"`
+	// DeliveryStats holds statistics about the most recent deliveries GitHub
+	// attempted for this hook. It is only populated for hooks managed by GARM
+	// and only after the pool manager has fetched the stats at least once.
+	DeliveryStats *HookDeliveryStats `json:"delivery_stats,omitempty"`
+}
+
+// HookDeliveryStats summarizes the recent deliveries of a webhook, as reported
+// by the GitHub API. It helps distinguish between GitHub not sending events and
+// GARM not receiving them.
+type HookDeliveryStats struct {
+	// RecentDeliveries is the number of deliveries that were taken into account.
+	RecentDeliveries int `json:"recent_deliveries"`
+	// FailedDeliveries is the number of recent deliveries that did not receive
+	// a 2xx response.
+	FailedDeliveries int `json:"failed_deliveries"`
+	// LastDeliveryStatus is the status of the most recent delivery, as reported
+	// by GitHub (for example "OK" or "Invalid HTTP Response: 503").
+	LastDeliveryStatus string `json:"last_delivery_status,omitempty"`
+	// LastDeliveryStatusCode is the HTTP status code returned by GARM for the
+	// most recent delivery. A value of 0 means GitHub could not connect at all.
+	LastDeliveryStatusCode int `json:"last_delivery_status_code"`
+	// LastDeliveryAt is the time of the most recent delivery.
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+	// UpdatedAt is the time these stats were fetched from GitHub.
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CertificateBundle struct {
@@ -807,9 +2620,20 @@ type CertificateBundle struct {
 }
 
 type UpdateSystemInfoParams struct {
-	OSName    string `json:"os_name,omitempty"`
-	OSVersion string `json:"os_version,omitempty"`
-	AgentID   *int64 `json:"agent_id,omitempty"`
+	OSName    string             `json:"os_name,omitempty"`
+	OSVersion string             `json:"os_version,omitempty"`
+	AgentID   *int64             `json:"agent_id,omitempty"`
+	DiskUsage *InstanceDiskUsage `json:"disk_usage,omitempty"`
+}
+
+// InstanceDiskUsage holds the disk usage reported by a runner.
+type InstanceDiskUsage struct {
+	// Path is the path on the runner for which the usage was measured.
+	Path       string `json:"path,omitempty"`
+	TotalBytes uint64 `json:"total_bytes,omitempty"`
+	UsedBytes  uint64 `json:"used_bytes,omitempty"`
+	// UpdatedAt is set by GARM when the disk usage is recorded.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
 type GithubEntity struct {
@@ -819,8 +2643,24 @@ type GithubEntity struct {
 	EntityType       GithubEntityType  `json:"entity_type,omitempty"`
 	Credentials      GithubCredentials `json:"credentials,omitempty"`
 	PoolBalancerType PoolBalancerType  `json:"pool_balancing_type,omitempty"`
-
-	WebhookSecret string `json:"-"`
+	// PendingWebhookInstall is set if a webhook install for this entity failed
+	// and needs to be retried.
+	PendingWebhookInstall *InstallWebhookParams `json:"pending_webhook_install,omitempty"`
+	// EntityWebhookPath is set if the webhook of the entity was installed using the URL
+	// unique to the entity. Webhooks received on the shared controller URL are rejected.
+	EntityWebhookPath bool `json:"entity_webhook_path,omitempty"`
+	// MaxConcurrentJobs is the maximum number of jobs this entity can have runners
+	// for at the same time. A value of 0 means no limit.
+	MaxConcurrentJobs uint `json:"max_concurrent_jobs,omitempty"`
+	// RoutingRules are used to choose the pool in which a runner is created for a
+	// queued job. When set, they take precedence over the pool balancer type.
+	RoutingRules []RoutingRule `json:"routing_rules,omitempty"`
+	// PreviousWebhookSecretExpiresAt is the time until which webhooks signed with
+	// the previous webhook secret are accepted.
+	PreviousWebhookSecretExpiresAt *time.Time `json:"previous_webhook_secret_expires_at,omitempty"`
+
+	WebhookSecret         string `json:"-"`
+	PreviousWebhookSecret string `json:"-"`
 }
 
 func (g GithubEntity) GetPoolBalancerType() PoolBalancerType {
@@ -862,6 +2702,210 @@ type GithubEndpoint struct {
 	UploadBaseURL string `json:"upload_base_url,omitempty"`
 	BaseURL       string `json:"base_url,omitempty"`
 	CACertBundle  []byte `json:"ca_cert_bundle,omitempty"`
+	// ToolsMetadataURL is the URL of a JSON document that lists the runner tools
+	// available for this endpoint. The document uses the same format as the GitHub
+	// API endpoint that lists runner application downloads. This is useful for
+	// air-gapped installs where the runner archives are hosted on an internal mirror.
+	ToolsMetadataURL string `json:"tools_metadata_url,omitempty"`
+	// UseInternalToolsMetadata will make GARM ignore the ToolsMetadataURL and fetch
+	// the tools from the GitHub API.
+	UseInternalToolsMetadata bool `json:"use_internal_tools_metadata,omitempty"`
 
 	Credentials []GithubCredentials `json:"credentials,omitempty"`
 }
+
+// UsesToolsMetadataURL returns true if tools for this endpoint should be fetched
+// from the tools metadata URL instead of the GitHub API.
+func (g GithubEndpoint) UsesToolsMetadataURL() bool {
+	return g.ToolsMetadataURL != "" && !g.UseInternalToolsMetadata
+}
+
+// InstanceFailureGroup holds the provider faults recorded for instances of one pool
+// that share the same error class.
+type InstanceFailureGroup struct {
+	ProviderName string     `json:"provider_name,omitempty"`
+	PoolID       string     `json:"pool_id,omitempty"`
+	ErrorClass   ErrorClass `json:"error_class,omitempty"`
+	Count        uint       `json:"count"`
+	LastSeen     time.Time  `json:"last_seen,omitempty"`
+	// SampleMessages holds a few distinct provider fault messages from this group.
+	SampleMessages []string `json:"sample_messages,omitempty"`
+}
+
+// InstanceFailureAnalytics aggregates the provider faults of instances over a
+// time window.
+type InstanceFailureAnalytics struct {
+	Since         time.Time              `json:"since"`
+	Until         time.Time              `json:"until"`
+	TotalFailures uint                   `json:"total_failures"`
+	Groups        []InstanceFailureGroup `json:"groups"`
+}
+
+type AuditAction string
+
+const (
+	// AuditActionImpersonationStarted is recorded when an admin requests a token
+	// that impersonates another user.
+	AuditActionImpersonationStarted AuditAction = "impersonation_started"
+	// AuditActionAPIRequest is recorded for every API request made with an
+	// impersonation token.
+	AuditActionAPIRequest AuditAction = "api_request"
+	// AuditActionDenyRuleMatched is recorded whenever an image and flavor combination
+	// banned by a deny rule is used.
+	AuditActionDenyRuleMatched AuditAction = "deny_rule_matched"
+	// AuditActionResourceCreated is recorded when a resource is created through the API.
+	AuditActionResourceCreated AuditAction = "resource_created"
+	// AuditActionResourceUpdated is recorded when a resource is updated through the API.
+	AuditActionResourceUpdated AuditAction = "resource_updated"
+	// AuditActionResourceDeleted is recorded when a resource is deleted through the API.
+	AuditActionResourceDeleted AuditAction = "resource_deleted"
+	// AuditActionInstanceSourceRejected is recorded when a request to the callback or
+	// metadata endpoints comes from outside of the allowed instance networks.
+	AuditActionInstanceSourceRejected AuditAction = "instance_source_rejected"
+	// AuditActionLoginRateLimited is recorded when login attempts are refused because
+	// too many attempts were made for a username or from a source address.
+	AuditActionLoginRateLimited AuditAction = "login_rate_limited"
+	// AuditActionUserLockedOut is recorded when a user is locked out after repeated
+	// failed logins.
+	AuditActionUserLockedOut AuditAction = "user_locked_out"
+	// AuditActionUserUnlocked is recorded when an admin unlocks a user.
+	AuditActionUserUnlocked AuditAction = "user_unlocked"
+	// AuditActionUserCreated is recorded when an admin creates a user.
+	AuditActionUserCreated AuditAction = "user_created"
+	// AuditActionRoleGranted is recorded when an admin gives a user a role on an entity.
+	AuditActionRoleGranted AuditAction = "role_granted"
+	// AuditActionRoleRevoked is recorded when an admin removes a role grant of a user.
+	AuditActionRoleRevoked AuditAction = "role_revoked"
+	// AuditActionAPITokenCreated is recorded when a user creates an API token.
+	AuditActionAPITokenCreated AuditAction = "api_token_created"
+	// AuditActionAPITokenRevoked is recorded when an API token is revoked.
+	AuditActionAPITokenRevoked AuditAction = "api_token_revoked"
+)
+
+type AuditResourceType string
+
+const (
+	AuditResourceRepository        AuditResourceType = "repository"
+	AuditResourceOrganization      AuditResourceType = "organization"
+	AuditResourceEnterprise        AuditResourceType = "enterprise"
+	AuditResourcePool              AuditResourceType = "pool"
+	AuditResourceGithubCredentials AuditResourceType = "github_credentials"
+	AuditResourceGithubEndpoint    AuditResourceType = "github_endpoint"
+	AuditResourceJob               AuditResourceType = "job"
+)
+
+// AuditChange is a field of a resource that was changed. Before is not set for
+// created resources and After is not set for deleted resources.
+type AuditChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditRecord holds information about an action taken by an admin on behalf of
+// another user, about an attempt to use a banned image and flavor combination, or
+// about a resource that was created, updated or deleted through the API.
+type AuditRecord struct {
+	ID        string      `json:"id,omitempty"`
+	CreatedAt time.Time   `json:"created_at,omitempty"`
+	Action    AuditAction `json:"action,omitempty"`
+	// ImpersonatorID is the ID of the admin that impersonated the user.
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
+	Impersonator   string `json:"impersonator,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	Username       string `json:"username,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	Method         string `json:"method,omitempty"`
+	Path           string `json:"path,omitempty"`
+	StatusCode     int    `json:"status_code,omitempty"`
+
+	ResourceType AuditResourceType `json:"resource_type,omitempty"`
+	ResourceID   string            `json:"resource_id,omitempty"`
+	Changes      []AuditChange     `json:"changes,omitempty"`
+}
+
+// used by swagger client generated code
+type AuditRecords []AuditRecord
+
+// PaginatedResult is one page of the results of a list operation.
+type PaginatedResult[T any] struct {
+	Items      []T  `json:"items"`
+	Page       uint `json:"page"`
+	PageSize   uint `json:"page_size"`
+	TotalCount uint `json:"total_count"`
+	TotalPages uint `json:"total_pages"`
+}
+
+// AuditRecordsPage is one page of audit records, newest first.
+type AuditRecordsPage struct {
+	Records    []AuditRecord `json:"records"`
+	Page       uint          `json:"page"`
+	PageSize   uint          `json:"page_size"`
+	TotalCount uint          `json:"total_count"`
+	TotalPages uint          `json:"total_pages"`
+}
+
+const (
+	// WebhookDeliveryProcessed means the webhook was validated and handed over to the
+	// pool managers of the entity.
+	WebhookDeliveryProcessed WebhookDeliveryStatus = "processed"
+	// WebhookDeliveryFailed means the webhook failed validation or processing. Failed
+	// deliveries can be processed again.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a workflow job webhook received by GARM, along with the result
+// of processing it.
+type WebhookDelivery struct {
+	ID string `json:"id"`
+	// DeliveryID is the ID GitHub gave to the delivery.
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// EntityID is the ID of the repository, organization or enterprise the webhook
+	// was meant for. It is empty if GARM could not find the entity.
+	EntityID string `json:"entity_id,omitempty"`
+	// TargetEntityID is the entity ID that was part of the webhook URL, if any.
+	TargetEntityID string `json:"target_entity_id,omitempty"`
+	// Headers holds the GitHub headers of the request.
+	Headers     map[string]string     `json:"headers,omitempty"`
+	PayloadHash string                `json:"payload_hash"`
+	Status      WebhookDeliveryStatus `json:"status"`
+	Error       string                `json:"error,omitempty"`
+	// Attempts is the number of times the delivery was processed.
+	Attempts  uint      `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Payload is kept to be able to process the delivery again. It is not
+	// returned by the API.
+	Payload []byte `json:"-"`
+}
+
+// WebhookDeliveriesPage is one page of webhook deliveries, newest first.
+type WebhookDeliveriesPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page       uint              `json:"page"`
+	PageSize   uint              `json:"page_size"`
+	TotalCount uint              `json:"total_count"`
+	TotalPages uint              `json:"total_pages"`
+}
+
+// IdempotencyRecord holds the response of a request that was made with an idempotency
+// key. Retries of the request that use the same key get the same response.
+type IdempotencyRecord struct {
+	Key    string `json:"key,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	// RequestHash is a hash of the method, path, query and body of the request. It is used
+	// to detect keys that are reused for different requests.
+	RequestHash string    `json:"request_hash,omitempty"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Response    []byte    `json:"response,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	// ResponseOmitted is set if the response held secrets, like API tokens, and
+	// was not recorded. Retries of such requests are rejected instead of replayed.
+	ResponseOmitted bool `json:"response_omitted,omitempty"`
+	// Pending is set while the request is being handled, possibly by another
+	// controller. The response is recorded once the request finishes.
+	Pending bool `json:"pending,omitempty"`
+}
@@ -20,11 +20,17 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/util/appdefaults"
 )
 
 const (
@@ -134,6 +140,20 @@ type NewUserParams struct {
 	Password string `json:"password,omitempty"`
 	IsAdmin  bool   `json:"-"`
 	Enabled  bool   `json:"-"`
+	// Role is the role of the new user on all entities. It can be left empty, and
+	// the user given roles on single entities.
+	Role UserRole `json:"role,omitempty"`
+}
+
+// ValidateRole checks the role of a user created through the API. The admin role
+// is reserved for the user created when the controller is initialized.
+func (n NewUserParams) ValidateRole() error {
+	switch n.Role {
+	case "", UserRoleOperator, UserRoleViewer:
+		return nil
+	default:
+		return runnerErrors.NewBadRequestError("invalid role %q, must be one of %s or %s", n.Role, UserRoleOperator, UserRoleViewer)
+	}
 }
 
 type UpdatePoolParams struct {
@@ -154,6 +174,95 @@ type UpdatePoolParams struct {
 	// The runner group must be created by someone with access to the enterprise.
 	GitHubRunnerGroup *string `json:"github-runner-group,omitempty"`
 	Priority          *uint   `json:"priority,omitempty"`
+	// CleanupPolicy replaces the cleanup policy of the pool. Set an empty
+	// policy to remove it.
+	CleanupPolicy *CleanupPolicy `json:"cleanup_policy,omitempty"`
+	// NetworkSettings replaces the network settings of the pool. Set empty
+	// settings to remove them.
+	NetworkSettings *NetworkSettings `json:"network_settings,omitempty"`
+	// Schedule replaces the schedule of the pool. Set an empty schedule to
+	// remove it.
+	Schedule *PoolSchedule `json:"schedule,omitempty"`
+	// IdleDetectionWindow is the amount of time in minutes a runner must be idle
+	// before it is considered for scale down. Set to 0 to use the default.
+	IdleDetectionWindow *uint `json:"idle_detection_window,omitempty"`
+	// ScaleDownGracePeriod is the amount of time in minutes after a runner is created,
+	// during which it will not be scaled down. Set to 0 to use the default.
+	ScaleDownGracePeriod *uint `json:"scale_down_grace_period,omitempty"`
+	// ScaleDownFactor is the fraction of the surplus of idle runners that is removed
+	// on each scale down run. Set to 0 to use the default.
+	ScaleDownFactor *float64 `json:"scale_down_factor,omitempty"`
+	// CapacityWarningThreshold is the percentage of max runners above which a warning
+	// is emitted for the pool. Set to 0 to use the default.
+	CapacityWarningThreshold *uint `json:"capacity_warning_threshold,omitempty"`
+	// AutoscaleMaxBurst is the maximum number of runners the queue depth autoscaler
+	// creates in the pool at once. Set to 0 to disable the autoscaler for the pool.
+	AutoscaleMaxBurst *uint `json:"autoscale_max_burst,omitempty"`
+	// AutoscaleCooldown is the amount of time in seconds the queue depth autoscaler
+	// waits after scaling up the pool. Set to 0 to use the default.
+	AutoscaleCooldown *uint `json:"autoscale_cooldown,omitempty"`
+	// MaxCreatesPerMinute is the maximum number of runners created in the pool each
+	// minute. Set to 0 to remove the limit.
+	MaxCreatesPerMinute *uint `json:"max_creates_per_minute,omitempty"`
+	// CreateJitter is the maximum amount of time in seconds a runner of the pool waits
+	// before it is created. Set to 0 to create runners right away.
+	CreateJitter *uint `json:"create_jitter,omitempty"`
+	// RollingUpdateBatchSize is the number of outdated idle runners replaced at once,
+	// after the image or flavor of the pool changed. Set to 0 to use the default.
+	RollingUpdateBatchSize *uint `json:"rolling_update_batch_size,omitempty"`
+	// RollingUpdatePause is the amount of time in seconds waited between two batches
+	// of a rolling update. Set to 0 to use the default.
+	RollingUpdatePause *uint `json:"rolling_update_pause,omitempty"`
+	// Draining stops the pool from creating new runners. Existing runners are allowed
+	// to finish their jobs and are removed once they become idle.
+	Draining *bool `json:"draining,omitempty"`
+	// URLSet is the name of the controller URL set used by the runners of the pool.
+	// Set to an empty string to use the default URLs.
+	URLSet *string `json:"url_set,omitempty"`
+	// InstanceMetadata replaces the instance metadata of the pool. Set an empty
+	// object to remove it.
+	InstanceMetadata *InstanceMetadata `json:"instance_metadata,omitempty"`
+	// WarmImage replaces the warm image settings of the pool. Set empty settings to
+	// stop building warm images and to go back to the image of the pool.
+	WarmImage *WarmImageSettings `json:"warm_image,omitempty"`
+
+	// DryRun runs all the validations and returns the pool as it would be after the
+	// update, without saving it. It is set from the dry_run query parameter of the API.
+	DryRun bool `json:"-"`
+}
+
+// ValidateScaleDownSettings validates the idle detection window, the scale down
+// grace period and the scale down factor of a pool. A nil value means the setting
+// is not changed.
+func ValidateScaleDownSettings(idleDetectionWindow, scaleDownGracePeriod *uint, scaleDownFactor *float64) error {
+	if idleDetectionWindow != nil && *idleDetectionWindow > appdefaults.MaxScaleDownWindow {
+		return fmt.Errorf("idle_detection_window cannot be larger than %d minutes", appdefaults.MaxScaleDownWindow)
+	}
+	if scaleDownGracePeriod != nil && *scaleDownGracePeriod > appdefaults.MaxScaleDownWindow {
+		return fmt.Errorf("scale_down_grace_period cannot be larger than %d minutes", appdefaults.MaxScaleDownWindow)
+	}
+	if scaleDownFactor != nil && (*scaleDownFactor < 0 || *scaleDownFactor > 1) {
+		return fmt.Errorf("scale_down_factor must be between 0 and 1")
+	}
+	return nil
+}
+
+// ValidateCreateJitter validates the creation jitter of a pool. A nil value means the
+// setting is not changed.
+func ValidateCreateJitter(jitter *uint) error {
+	if jitter != nil && *jitter > appdefaults.MaxCreateJitter {
+		return fmt.Errorf("create_jitter cannot be larger than %d seconds", appdefaults.MaxCreateJitter)
+	}
+	return nil
+}
+
+// ValidateCapacityWarningThreshold validates the capacity warning threshold of a pool.
+// A nil value means the setting is not changed.
+func ValidateCapacityWarningThreshold(threshold *uint) error {
+	if threshold != nil && *threshold > 100 {
+		return fmt.Errorf("capacity_warning_threshold cannot be larger than 100")
+	}
+	return nil
 }
 
 type CreateInstanceParams struct {
@@ -190,8 +299,57 @@ type CreatePoolParams struct {
 	// GithubRunnerGroup is the github runner group in which the runners of this
 	// pool will be added to.
 	// The runner group must be created by someone with access to the enterprise.
-	GitHubRunnerGroup string `json:"github-runner-group,omitempty"`
-	Priority          uint   `json:"priority,omitempty"`
+	GitHubRunnerGroup string         `json:"github-runner-group,omitempty"`
+	Priority          uint           `json:"priority,omitempty"`
+	CleanupPolicy     *CleanupPolicy `json:"cleanup_policy,omitempty"`
+	// NetworkSettings holds the network configuration applied to runners while
+	// they are bootstrapped.
+	NetworkSettings *NetworkSettings `json:"network_settings,omitempty"`
+	// Schedule holds the time windows during which the pool keeps a different
+	// number of idle runners.
+	Schedule *PoolSchedule `json:"schedule,omitempty"`
+	// IdleDetectionWindow is the amount of time in minutes a runner must be idle
+	// before it is considered for scale down. Defaults to 2 minutes.
+	IdleDetectionWindow uint `json:"idle_detection_window,omitempty"`
+	// ScaleDownGracePeriod is the amount of time in minutes after a runner is created,
+	// during which it will not be scaled down. Defaults to 5 minutes.
+	ScaleDownGracePeriod uint `json:"scale_down_grace_period,omitempty"`
+	// ScaleDownFactor is the fraction of the surplus of idle runners that is removed
+	// on each scale down run. Defaults to 0.5.
+	ScaleDownFactor float64 `json:"scale_down_factor,omitempty"`
+	// CapacityWarningThreshold is the percentage of max runners above which a warning
+	// is emitted for the pool. Defaults to 80.
+	CapacityWarningThreshold uint `json:"capacity_warning_threshold,omitempty"`
+	// AutoscaleMaxBurst is the maximum number of runners the queue depth autoscaler
+	// creates in the pool at once. The autoscaler is disabled for the pool if 0.
+	AutoscaleMaxBurst uint `json:"autoscale_max_burst,omitempty"`
+	// AutoscaleCooldown is the amount of time in seconds the queue depth autoscaler
+	// waits after scaling up the pool. Defaults to 60 seconds.
+	AutoscaleCooldown uint `json:"autoscale_cooldown,omitempty"`
+	// MaxCreatesPerMinute is the maximum number of runners created in the pool each
+	// minute. A value of 0 means no limit.
+	MaxCreatesPerMinute uint `json:"max_creates_per_minute,omitempty"`
+	// CreateJitter is the maximum amount of time in seconds a runner of the pool waits
+	// before it is created. A value of 0 means runners are created right away.
+	CreateJitter uint `json:"create_jitter,omitempty"`
+	// RollingUpdateBatchSize is the number of outdated idle runners replaced at once,
+	// after the image or flavor of the pool changed. A value of 0 uses the default.
+	RollingUpdateBatchSize uint `json:"rolling_update_batch_size,omitempty"`
+	// RollingUpdatePause is the amount of time in seconds waited between two batches
+	// of a rolling update. A value of 0 uses the default.
+	RollingUpdatePause uint `json:"rolling_update_pause,omitempty"`
+	// URLSet is the name of the controller URL set used by the runners of the pool.
+	// The default URLs are used if empty.
+	URLSet string `json:"url_set,omitempty"`
+	// InstanceMetadata holds user defined key/value pairs served to the runners of
+	// the pool by the metadata service.
+	InstanceMetadata InstanceMetadata `json:"instance_metadata,omitempty"`
+	// WarmImage holds the settings used to build a warm image for the pool.
+	WarmImage *WarmImageSettings `json:"warm_image,omitempty"`
+
+	// DryRun runs all the validations and returns the pool that would be created,
+	// without saving it. It is set from the dry_run query parameter of the API.
+	DryRun bool `json:"-"`
 }
 
 func (p *CreatePoolParams) Validate() error {
@@ -219,6 +377,40 @@ func (p *CreatePoolParams) Validate() error {
 		return fmt.Errorf("missing image")
 	}
 
+	if p.NetworkSettings != nil {
+		if err := p.NetworkSettings.Validate(); err != nil {
+			return fmt.Errorf("invalid network settings: %w", err)
+		}
+	}
+
+	if err := p.InstanceMetadata.Validate(); err != nil {
+		return err
+	}
+
+	if p.WarmImage != nil {
+		if err := p.WarmImage.Validate(); err != nil {
+			return fmt.Errorf("invalid warm image settings: %w", err)
+		}
+	}
+
+	if p.Schedule != nil {
+		if err := p.Schedule.Validate(p.MaxRunners); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	if err := ValidateScaleDownSettings(&p.IdleDetectionWindow, &p.ScaleDownGracePeriod, &p.ScaleDownFactor); err != nil {
+		return err
+	}
+
+	if err := ValidateCapacityWarningThreshold(&p.CapacityWarningThreshold); err != nil {
+		return err
+	}
+
+	if err := ValidateCreateJitter(&p.CreateJitter); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -239,6 +431,14 @@ type UpdateInstanceParams struct {
 	CreateAttempt    int                         `json:"-"`
 	TokenFetched     *bool                       `json:"-"`
 	JitConfiguration map[string]string           `json:"-"`
+	DiskUsage        *InstanceDiskUsage          `json:"-"`
+	// JitRegistrationRemoved marks the GitHub runner registration of the instance
+	// as removed.
+	JitRegistrationRemoved *bool `json:"-"`
+	// Image and Flavor record the image and flavor of the pool the instance was
+	// created with.
+	Image  string `json:"-"`
+	Flavor string `json:"-"`
 }
 
 type UpdateUserParams struct {
@@ -263,16 +463,290 @@ func (p PasswordLoginParams) Validate() error {
 	return nil
 }
 
+// ImpersonateUserParams holds the parameters used by an admin to request a
+// token that impersonates another user.
+type ImpersonateUserParams struct {
+	// Reason is recorded in the audit log, along with every action taken
+	// using the impersonation token.
+	Reason string `json:"reason,omitempty"`
+	// TTLMinutes is the number of minutes the impersonation token is valid for.
+	// Defaults to 15 minutes and may not exceed 60 minutes.
+	TTLMinutes uint `json:"ttl_minutes,omitempty"`
+}
+
+func (i ImpersonateUserParams) Validate() error {
+	if i.Reason == "" {
+		return runnerErrors.NewBadRequestError("missing reason")
+	}
+	if i.TTLMinutes > 60 {
+		return runnerErrors.NewBadRequestError("ttl_minutes may not exceed 60")
+	}
+	return nil
+}
+
+// PauseProviderParams holds the parameters used to pause a provider.
+type PauseProviderParams struct {
+	// Reason is an optional note on why the provider was paused (a maintenance
+	// window, an outage, etc).
+	Reason string `json:"reason,omitempty"`
+}
+
+// providerEnvironmentVariableName matches valid environment variable names.
+var providerEnvironmentVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// UpdateProviderEnvironmentParams holds the parameters used to update the environment
+// variables GARM passes to an external provider.
+type UpdateProviderEnvironmentParams struct {
+	// Set adds or replaces environment variables.
+	Set map[string]string `json:"set,omitempty"`
+	// Unset removes environment variables.
+	Unset []string `json:"unset,omitempty"`
+}
+
+func (u UpdateProviderEnvironmentParams) Validate() error {
+	if len(u.Set) == 0 && len(u.Unset) == 0 {
+		return runnerErrors.NewBadRequestError("no environment variables to set or unset")
+	}
+
+	for name := range u.Set {
+		if !providerEnvironmentVariableName.MatchString(name) {
+			return runnerErrors.NewBadRequestError("invalid environment variable name: %q", name)
+		}
+		// Variables prefixed with GARM are used to pass the command and its
+		// parameters to the provider and may not be overwritten.
+		if strings.HasPrefix(strings.ToUpper(name), "GARM") {
+			return runnerErrors.NewBadRequestError("environment variable %s uses the reserved GARM prefix", name)
+		}
+	}
+
+	for _, name := range u.Unset {
+		if _, ok := u.Set[name]; ok {
+			return runnerErrors.NewBadRequestError("environment variable %s is both set and unset", name)
+		}
+	}
+	return nil
+}
+
+// CreateDenyRuleParams holds the parameters used to ban an image and flavor combination.
+type CreateDenyRuleParams struct {
+	ProviderName  string `json:"provider_name,omitempty"`
+	ImagePattern  string `json:"image_pattern,omitempty"`
+	FlavorPattern string `json:"flavor_pattern,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+func (c CreateDenyRuleParams) Validate() error {
+	if c.ImagePattern == "" && c.FlavorPattern == "" {
+		return runnerErrors.NewBadRequestError("at least one of image_pattern or flavor_pattern must be set")
+	}
+	for name, pattern := range map[string]string{"image_pattern": c.ImagePattern, "flavor_pattern": c.FlavorPattern} {
+		if pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return runnerErrors.NewBadRequestError("invalid %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// CreateAPITokenParams holds the parameters used to create an API token.
+type CreateAPITokenParams struct {
+	Name   string          `json:"name,omitempty"`
+	Scopes []APITokenScope `json:"scopes,omitempty"`
+	// TTLDays is the number of days the token is valid for. Tokens created without
+	// a TTL don't expire, and must be revoked when no longer needed.
+	TTLDays uint `json:"ttl_days,omitempty"`
+}
+
+func (c CreateAPITokenParams) Validate() error {
+	if c.Name == "" || len(c.Name) > 64 {
+		return runnerErrors.NewBadRequestError("name must be between 1 and 64 characters")
+	}
+	if len(c.Scopes) == 0 {
+		return runnerErrors.NewBadRequestError("at least one scope is required")
+	}
+	for _, scope := range c.Scopes {
+		if !slices.Contains(APITokenScopes, scope) {
+			return runnerErrors.NewBadRequestError("invalid scope %q", scope)
+		}
+	}
+	if c.TTLDays > appdefaults.MaxAPITokenTTLDays {
+		return runnerErrors.NewBadRequestError("ttl_days may not exceed %d", appdefaults.MaxAPITokenTTLDays)
+	}
+	return nil
+}
+
+// CreateRoleGrantParams holds the parameters used to give a user a role on a single
+// repository, organization or enterprise.
+type CreateRoleGrantParams struct {
+	Role       UserRole         `json:"role,omitempty"`
+	EntityType GithubEntityType `json:"entity_type,omitempty"`
+	EntityID   string           `json:"entity_id,omitempty"`
+}
+
+func (c CreateRoleGrantParams) Validate() error {
+	if c.Role != UserRoleOperator && c.Role != UserRoleViewer {
+		return runnerErrors.NewBadRequestError("invalid role %q, must be one of %s or %s", c.Role, UserRoleOperator, UserRoleViewer)
+	}
+	switch c.EntityType {
+	case GithubEntityTypeRepository, GithubEntityTypeOrganization, GithubEntityTypeEnterprise:
+	default:
+		return runnerErrors.NewBadRequestError("invalid entity_type %q", c.EntityType)
+	}
+	if _, err := uuid.Parse(c.EntityID); err != nil {
+		return runnerErrors.NewBadRequestError("invalid entity_id")
+	}
+	return nil
+}
+
+// CreateCapacityReservationParams holds the parameters used to reserve extra idle
+// runners in a pool for a window of time.
+type CreateCapacityReservationParams struct {
+	PoolID           string    `json:"pool_id,omitempty"`
+	ExtraIdleRunners uint      `json:"extra_idle_runners,omitempty"`
+	StartTime        time.Time `json:"start_time,omitempty"`
+	EndTime          time.Time `json:"end_time,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+}
+
+func (c CreateCapacityReservationParams) Validate() error {
+	if c.PoolID == "" {
+		return runnerErrors.NewBadRequestError("missing pool_id")
+	}
+	if c.ExtraIdleRunners == 0 {
+		return runnerErrors.NewBadRequestError("extra_idle_runners must be greater than 0")
+	}
+	if c.StartTime.IsZero() || c.EndTime.IsZero() {
+		return runnerErrors.NewBadRequestError("start_time and end_time are mandatory")
+	}
+	if !c.EndTime.After(c.StartTime) {
+		return runnerErrors.NewBadRequestError("end_time must be after start_time")
+	}
+	if !c.EndTime.After(time.Now().UTC()) {
+		return runnerErrors.NewBadRequestError("end_time must be in the future")
+	}
+	return nil
+}
+
+// PoolTagOperation is an operation applied to the tags of many pools at once.
+type PoolTagOperation string
+
+const (
+	PoolTagOperationAdd    PoolTagOperation = "add"
+	PoolTagOperationRemove PoolTagOperation = "remove"
+	PoolTagOperationRename PoolTagOperation = "rename"
+)
+
+// BulkUpdatePoolTagsParams holds the parameters used to add, remove or rename a tag
+// across many pools.
+type BulkUpdatePoolTagsParams struct {
+	Operation PoolTagOperation `json:"operation"`
+	// Tag is the tag to add, remove or rename.
+	Tag string `json:"tag"`
+	// NewTag is the new name of the tag. Only used when renaming a tag.
+	NewTag string `json:"new_tag,omitempty"`
+	// Filter limits the operation to pools that have all of these tags.
+	Filter []string `json:"filter,omitempty"`
+	// DryRun returns the changes that would be made, without applying them.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+func (b BulkUpdatePoolTagsParams) Validate() error {
+	if b.Tag == "" {
+		return runnerErrors.NewBadRequestError("missing tag")
+	}
+
+	switch b.Operation {
+	case PoolTagOperationAdd, PoolTagOperationRemove:
+		if b.NewTag != "" {
+			return runnerErrors.NewBadRequestError("new_tag can only be set when renaming a tag")
+		}
+	case PoolTagOperationRename:
+		if b.NewTag == "" {
+			return runnerErrors.NewBadRequestError("missing new_tag")
+		}
+		if strings.EqualFold(b.Tag, b.NewTag) {
+			return runnerErrors.NewBadRequestError("new_tag must differ from tag")
+		}
+	default:
+		return runnerErrors.NewBadRequestError("invalid operation %q", b.Operation)
+	}
+	return nil
+}
+
+func (r RoutingRule) Validate() error {
+	switch r.Type {
+	case RoutingRulePreferIdle, RoutingRuleLeastLoaded:
+		if len(r.Providers) > 0 {
+			return runnerErrors.NewBadRequestError("providers can only be set on %s routing rules", RoutingRuleProviderOrder)
+		}
+	case RoutingRuleProviderOrder:
+		if len(r.Providers) == 0 {
+			return runnerErrors.NewBadRequestError("%s routing rules need at least one provider", RoutingRuleProviderOrder)
+		}
+	default:
+		return runnerErrors.NewBadRequestError("invalid routing rule type: %s", r.Type)
+	}
+	return nil
+}
+
+// ValidateRoutingRules validates a list of routing rules. Each rule type can only be
+// used once.
+func ValidateRoutingRules(rules []RoutingRule) error {
+	seen := map[RoutingRuleType]struct{}{}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if _, ok := seen[rule.Type]; ok {
+			return runnerErrors.NewBadRequestError("duplicate routing rule: %s", rule.Type)
+		}
+		seen[rule.Type] = struct{}{}
+	}
+	return nil
+}
+
 type UpdateEntityParams struct {
 	CredentialsName  string           `json:"credentials_name,omitempty"`
 	WebhookSecret    string           `json:"webhook_secret,omitempty"`
 	PoolBalancerType PoolBalancerType `json:"pool_balancer_type,omitempty"`
+	// MaxConcurrentJobs sets the maximum number of jobs the entity can have runners
+	// for at the same time. Set to 0 to remove the limit.
+	MaxConcurrentJobs *uint `json:"max_concurrent_jobs,omitempty"`
+	// RoutingRules replaces the routing rules of the entity. Set to an empty list
+	// to remove them.
+	RoutingRules *[]RoutingRule `json:"routing_rules,omitempty"`
+	// WebhookSecretGracePeriod is the number of minutes during which webhooks signed
+	// with the current secret are still accepted, after it is replaced by WebhookSecret.
+	// This allows the secret to be changed in GitHub after it was changed in GARM. If
+	// 0, the current secret is no longer accepted once it is replaced.
+	WebhookSecretGracePeriod uint `json:"webhook_secret_grace_period,omitempty"`
 }
 
+func (u UpdateEntityParams) Validate() error {
+	if u.WebhookSecretGracePeriod > 0 && u.WebhookSecret == "" {
+		return runnerErrors.NewBadRequestError("webhook_secret_grace_period requires a new webhook_secret")
+	}
+	if u.WebhookSecretGracePeriod > appdefaults.MaxWebhookSecretGracePeriod {
+		return runnerErrors.NewBadRequestError("webhook_secret_grace_period cannot be larger than %d minutes", appdefaults.MaxWebhookSecretGracePeriod)
+	}
+	return nil
+}
+
+// InstanceClockSkewHeader is set on the response to a status update sent by an
+// instance whose clock differs from the clock of GARM by more than the configured
+// tolerance. The value is the skew in seconds. A positive value means the clock of
+// the instance is ahead.
+const InstanceClockSkewHeader = "Garm-Clock-Skew"
+
 type InstanceUpdateMessage struct {
 	Status  RunnerStatus `json:"status,omitempty"`
 	Message string       `json:"message,omitempty"`
 	AgentID *int64       `json:"agent_id,omitempty"`
+	// Timestamp is the time on the instance when the update was sent. It is
+	// used to detect instances with a skewed clock.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
 type CreateGithubEndpointParams struct {
@@ -282,6 +756,9 @@ type CreateGithubEndpointParams struct {
 	UploadBaseURL string `json:"upload_base_url,omitempty"`
 	BaseURL       string `json:"base_url,omitempty"`
 	CACertBundle  []byte `json:"ca_cert_bundle,omitempty"`
+
+	ToolsMetadataURL         string `json:"tools_metadata_url,omitempty"`
+	UseInternalToolsMetadata bool   `json:"use_internal_tools_metadata,omitempty"`
 }
 
 func (c CreateGithubEndpointParams) Validate() error {
@@ -339,6 +816,25 @@ func (c CreateGithubEndpointParams) Validate() error {
 		}
 	}
 
+	if c.ToolsMetadataURL != "" {
+		if err := validateToolsMetadataURL(c.ToolsMetadataURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateToolsMetadataURL(metadataURL string) error {
+	url, err := url.Parse(metadataURL)
+	if err != nil || url.Scheme == "" || url.Host == "" {
+		return runnerErrors.NewBadRequestError("invalid tools_metadata_url")
+	}
+	switch url.Scheme {
+	case httpsScheme, httpScheme:
+	default:
+		return runnerErrors.NewBadRequestError("invalid tools_metadata_url")
+	}
 	return nil
 }
 
@@ -348,6 +844,9 @@ type UpdateGithubEndpointParams struct {
 	UploadBaseURL *string `json:"upload_base_url,omitempty"`
 	BaseURL       *string `json:"base_url,omitempty"`
 	CACertBundle  []byte  `json:"ca_cert_bundle,omitempty"`
+	// ToolsMetadataURL can be set to an empty string to remove the tools metadata URL.
+	ToolsMetadataURL         *string `json:"tools_metadata_url,omitempty"`
+	UseInternalToolsMetadata *bool   `json:"use_internal_tools_metadata,omitempty"`
 }
 
 func (u UpdateGithubEndpointParams) Validate() error {
@@ -397,13 +896,29 @@ func (u UpdateGithubEndpointParams) Validate() error {
 		}
 	}
 
+	if u.ToolsMetadataURL != nil && *u.ToolsMetadataURL != "" {
+		if err := validateToolsMetadataURL(*u.ToolsMetadataURL); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// fineGrainedPATPrefix is the prefix GitHub uses for fine-grained personal access tokens.
+const fineGrainedPATPrefix = "github_pat_"
+
 type GithubPAT struct {
 	OAuth2Token string `json:"oauth2_token,omitempty"`
 }
 
+// IsFineGrained returns true if the token is a fine-grained personal access token.
+// Fine-grained tokens are limited to the repositories and permissions selected when
+// the token was created.
+func (g GithubPAT) IsFineGrained() bool {
+	return strings.HasPrefix(g.OAuth2Token, fineGrainedPATPrefix)
+}
+
 type GithubApp struct {
 	AppID           int64  `json:"app_id,omitempty"`
 	InstallationID  int64  `json:"installation_id,omitempty"`
@@ -443,6 +958,9 @@ type CreateGithubCredentialsParams struct {
 	AuthType    GithubAuthType `json:"auth_type,omitempty"`
 	PAT         GithubPAT      `json:"pat,omitempty"`
 	App         GithubApp      `json:"app,omitempty"`
+
+	// TokenSummary is set by the runner after probing a fine-grained PAT.
+	TokenSummary *GithubTokenSummary `json:"-"`
 }
 
 func (c CreateGithubCredentialsParams) Validate() error {
@@ -480,6 +998,10 @@ type UpdateGithubCredentialsParams struct {
 	Description *string    `json:"description,omitempty"`
 	PAT         *GithubPAT `json:"pat,omitempty"`
 	App         *GithubApp `json:"app,omitempty"`
+
+	// TokenSummary is set by the runner after probing a fine-grained PAT. It replaces
+	// the summary of the old token whenever the PAT is updated.
+	TokenSummary *GithubTokenSummary `json:"-"`
 }
 
 func (u UpdateGithubCredentialsParams) Validate() error {
@@ -507,6 +1029,10 @@ type UpdateControllerParams struct {
 	CallbackURL          *string `json:"callback_url,omitempty"`
 	WebhookURL           *string `json:"webhook_url,omitempty"`
 	MinimumJobAgeBackoff *uint   `json:"minimum_job_age_backoff,omitempty"`
+
+	// URLSets replaces the named URL sets of the controller. An empty list removes
+	// all of them.
+	URLSets *[]ControllerURLSet `json:"url_sets,omitempty"`
 }
 
 func (u UpdateControllerParams) Validate() error {
@@ -531,5 +1057,464 @@ func (u UpdateControllerParams) Validate() error {
 		}
 	}
 
+	if u.URLSets != nil {
+		names := map[string]bool{}
+		for _, set := range *u.URLSets {
+			if set.Name == "" || len(set.Name) > 64 {
+				return runnerErrors.NewBadRequestError("url set names must be between 1 and 64 characters")
+			}
+			if names[set.Name] {
+				return runnerErrors.NewBadRequestError("duplicate url set %s", set.Name)
+			}
+			names[set.Name] = true
+			if parsed, err := url.Parse(set.MetadataURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return runnerErrors.NewBadRequestError("invalid metadata_url in url set %s", set.Name)
+			}
+			if parsed, err := url.Parse(set.CallbackURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return runnerErrors.NewBadRequestError("invalid callback_url in url set %s", set.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// StartControllerIDMigrationParams holds the parameters used to change the
+// controller ID.
+type StartControllerIDMigrationParams struct {
+	// NewControllerID is the ID the controller will use once the migration is done.
+	// A new ID is generated if not set.
+	NewControllerID *uuid.UUID `json:"new_controller_id,omitempty"`
+}
+
+// UpdateControllerIDMigrationParams holds the fields of a controller ID migration
+// that are updated as the migration advances.
+type UpdateControllerIDMigrationParams struct {
+	Phase          *MigrationPhase
+	Webhooks       []MigrationWebhookStatus
+	PendingRunners *uint
+	SwitchedAt     *time.Time
+	CompletedAt    *time.Time
+}
+
+// ListStatusMessagesParams holds the parameters used to list the status messages
+// of an instance.
+type ListStatusMessagesParams struct {
+	// EventLevel limits the results to messages with this level. All messages
+	// are returned if empty.
+	EventLevel EventLevel
+	// Page is the page to return, starting from 1.
+	Page uint
+	// PageSize is the number of messages in each page.
+	PageSize uint
+}
+
+func (l ListStatusMessagesParams) Validate() error {
+	switch l.EventLevel {
+	case "", EventInfo, EventWarning, EventError:
+	default:
+		return runnerErrors.NewBadRequestError("invalid event level %q", l.EventLevel)
+	}
+	if l.Page == 0 {
+		return runnerErrors.NewBadRequestError("page must be greater than 0")
+	}
+	if l.PageSize == 0 || l.PageSize > appdefaults.MaxStatusMessagesPageSize {
+		return runnerErrors.NewBadRequestError("page_size must be between 1 and %d", appdefaults.MaxStatusMessagesPageSize)
+	}
+	return nil
+}
+
+// BulkDeleteInstancesParams holds the filters used to select the runners removed by a
+// bulk delete. Runners must match all the filters that are set.
+type BulkDeleteInstancesParams struct {
+	// PoolID limits the deletion to the runners of a pool.
+	PoolID string
+	// Status limits the deletion to runners in this status. Only running runners and
+	// runners in error can be removed. Both are removed if empty.
+	Status commonParams.InstanceStatus
+	// OlderThan limits the deletion to runners created more than this long ago.
+	OlderThan time.Duration
+	// ForceRemove marks runners as pending_force_delete, and also removes runners
+	// that are running a job.
+	ForceRemove bool
+	// DryRun only lists the runners that would be removed.
+	DryRun bool
+}
+
+func (b BulkDeleteInstancesParams) Validate() error {
+	if b.PoolID == "" && b.Status == "" && b.OlderThan == 0 {
+		return runnerErrors.NewBadRequestError("at least one of pool_id, status or older_than must be set")
+	}
+	switch b.Status {
+	case "", commonParams.InstanceRunning, commonParams.InstanceError:
+	default:
+		return runnerErrors.NewBadRequestError("status must be one of %q or %q", commonParams.InstanceRunning, commonParams.InstanceError)
+	}
+	if b.OlderThan < 0 {
+		return runnerErrors.NewBadRequestError("older_than cannot be negative")
+	}
+	return nil
+}
+
+// ListWebhookDeliveriesParams holds the parameters used to list webhook deliveries.
+type ListWebhookDeliveriesParams struct {
+	// EntityID limits the results to the deliveries meant for this entity.
+	EntityID string
+	// Status limits the results to deliveries with this status.
+	Status WebhookDeliveryStatus
+	// Page is the page to return, starting from 1.
+	Page uint
+	// PageSize is the number of deliveries in each page.
+	PageSize uint
+}
+
+func (l ListWebhookDeliveriesParams) Validate() error {
+	switch l.Status {
+	case "", WebhookDeliveryProcessed, WebhookDeliveryFailed:
+	default:
+		return runnerErrors.NewBadRequestError("invalid status %q", l.Status)
+	}
+	if l.Page == 0 {
+		return runnerErrors.NewBadRequestError("page must be greater than 0")
+	}
+	if l.PageSize == 0 || l.PageSize > appdefaults.MaxWebhookDeliveriesPageSize {
+		return runnerErrors.NewBadRequestError("page_size must be between 1 and %d", appdefaults.MaxWebhookDeliveriesPageSize)
+	}
+	return nil
+}
+
+type ListAuditRecordsParams struct {
+	// Action limits the results to records of this action. All records are
+	// returned if empty.
+	Action AuditAction
+	// ResourceType limits the results to records about this type of resource.
+	ResourceType AuditResourceType
+	// Page is the page to return, starting from 1.
+	Page uint
+	// PageSize is the number of records in each page.
+	PageSize uint
+}
+
+func (l ListAuditRecordsParams) Validate() error {
+	switch l.Action {
+	case "", AuditActionImpersonationStarted, AuditActionAPIRequest, AuditActionDenyRuleMatched,
+		AuditActionResourceCreated, AuditActionResourceUpdated, AuditActionResourceDeleted,
+		AuditActionInstanceSourceRejected, AuditActionLoginRateLimited, AuditActionUserLockedOut,
+		AuditActionUserUnlocked:
+	default:
+		return runnerErrors.NewBadRequestError("invalid action %q", l.Action)
+	}
+	switch l.ResourceType {
+	case "", AuditResourceRepository, AuditResourceOrganization, AuditResourceEnterprise,
+		AuditResourcePool, AuditResourceGithubCredentials, AuditResourceGithubEndpoint,
+		AuditResourceJob:
+	default:
+		return runnerErrors.NewBadRequestError("invalid resource type %q", l.ResourceType)
+	}
+	if l.Page == 0 {
+		return runnerErrors.NewBadRequestError("page must be greater than 0")
+	}
+	if l.PageSize == 0 || l.PageSize > appdefaults.MaxAuditRecordsPageSize {
+		return runnerErrors.NewBadRequestError("page_size must be between 1 and %d", appdefaults.MaxAuditRecordsPageSize)
+	}
+	return nil
+}
+
+var (
+	// InstanceSortFields are the fields by which instances can be sorted.
+	InstanceSortFields = []string{"name", "status", "runner_status", "created_at", "updated_at"}
+	// PoolSortFields are the fields by which pools can be sorted.
+	PoolSortFields = []string{"provider_name", "image", "flavor", "priority", "created_at", "updated_at"}
+	// JobSortFields are the fields by which jobs can be sorted.
+	JobSortFields = []string{"id", "name", "status", "started_at", "completed_at", "created_at", "updated_at"}
+)
+
+// ListOptions selects one page of the results of a list operation, and the order
+// of the results.
+type ListOptions struct {
+	// Page is the page to return, starting from 1.
+	Page uint
+	// PageSize is the number of items in each page.
+	PageSize uint
+	// Sort is the field by which the results are sorted. Results are sorted in
+	// descending order if the field is prefixed with "-". If empty, the default
+	// order of the list operation is used.
+	Sort string
+}
+
+// SortField returns the field by which the results are sorted, and whether they are
+// sorted in descending order.
+func (l ListOptions) SortField() (string, bool) {
+	if strings.HasPrefix(l.Sort, "-") {
+		return l.Sort[1:], true
+	}
+	return l.Sort, false
+}
+
+// Validate checks the requested page, and that the results can be sorted by the
+// requested field.
+func (l ListOptions) Validate(sortFields []string) error {
+	if l.Page == 0 {
+		return runnerErrors.NewBadRequestError("page must be greater than 0")
+	}
+	if l.PageSize == 0 || l.PageSize > appdefaults.MaxListPageSize {
+		return runnerErrors.NewBadRequestError("page_size must be between 1 and %d", appdefaults.MaxListPageSize)
+	}
+	if l.Sort == "" {
+		return nil
+	}
+	field, _ := l.SortField()
+	for _, allowed := range sortFields {
+		if field == allowed {
+			return nil
+		}
+	}
+	return runnerErrors.NewBadRequestError("invalid sort field %q, must be one of: %s", field, strings.Join(sortFields, ", "))
+}
+
+// InstanceFilter limits the instances returned by list operations. Only instances
+// that match all the set fields are returned.
+type InstanceFilter struct {
+	// Statuses limits the results to instances in one of these statuses.
+	Statuses []commonParams.InstanceStatus
+	// RunnerStatuses limits the results to instances with one of these runner
+	// statuses.
+	RunnerStatuses []RunnerStatus
+	// ProviderName limits the results to instances in pools of this provider.
+	ProviderName string
+	// PoolID limits the results to instances in this pool.
+	PoolID string
+	// CreatedBefore limits the results to instances created before this time.
+	CreatedBefore *time.Time
+	// CreatedAfter limits the results to instances created after this time.
+	CreatedAfter *time.Time
+}
+
+// IsEmpty returns true if no field of the filter is set.
+func (f InstanceFilter) IsEmpty() bool {
+	return len(f.Statuses) == 0 && len(f.RunnerStatuses) == 0 && f.ProviderName == "" &&
+		f.PoolID == "" && f.CreatedBefore == nil && f.CreatedAfter == nil
+}
+
+// Validate checks that the statuses are known, and that the pool ID and creation times
+// are valid.
+func (f InstanceFilter) Validate() error {
+	for _, status := range f.Statuses {
+		switch status {
+		case commonParams.InstanceRunning, commonParams.InstanceStopped, commonParams.InstanceError,
+			commonParams.InstancePendingDelete, commonParams.InstancePendingForceDelete,
+			commonParams.InstanceDeleting, commonParams.InstancePendingCreate,
+			commonParams.InstanceCreating, commonParams.InstanceStatusUnknown:
+		default:
+			return runnerErrors.NewBadRequestError("invalid status %q", status)
+		}
+	}
+	for _, status := range f.RunnerStatuses {
+		switch status {
+		case RunnerIdle, RunnerPending, RunnerTerminated, RunnerInstalling, RunnerFailed, RunnerActive:
+		default:
+			return runnerErrors.NewBadRequestError("invalid runner status %q", status)
+		}
+	}
+	if f.PoolID != "" {
+		if _, err := uuid.Parse(f.PoolID); err != nil {
+			return runnerErrors.NewBadRequestError("invalid pool ID %q", f.PoolID)
+		}
+	}
+	if f.CreatedBefore != nil && f.CreatedAfter != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return runnerErrors.NewBadRequestError("created_after must be before created_before")
+	}
+	return nil
+}
+
+// CleanupOrphanedInstancesParams holds the options used when removing orphaned instances
+// from a provider.
+type CleanupOrphanedInstancesParams struct {
+	// DryRun only lists the instances that would be removed.
+	DryRun bool `json:"dry_run"`
+}
+
+// UsageReportParams holds the period covered by a usage report.
+type UsageReportParams struct {
+	// Since is the start of the period. A zero value covers all recorded usage.
+	Since time.Time
+	// Until is the end of the period. A zero value means now.
+	Until time.Time
+}
+
+func (u UsageReportParams) Validate() error {
+	if !u.Until.IsZero() && !u.Since.IsZero() && !u.Since.Before(u.Until) {
+		return runnerErrors.NewBadRequestError("since must be before until")
+	}
+	return nil
+}
+
+// Validate checks the parts of an entity config that are needed to import its pools
+// in an existing entity.
+func (e EntityConfig) Validate() error {
+	if e.Version != EntityConfigVersion {
+		return runnerErrors.NewBadRequestError("unsupported config version %d", e.Version)
+	}
+	for idx, pool := range e.Pools {
+		if err := pool.Validate(); err != nil {
+			return runnerErrors.NewBadRequestError("invalid pool %d: %s", idx+1, err)
+		}
+	}
+	return nil
+}
+
+// ValidateNewEntity checks that the config can be used to create a new entity.
+func (e EntityConfig) ValidateNewEntity() error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	if err := e.validateSettings(); err != nil {
+		return err
+	}
+	if e.WebhookSecret == "" || e.WebhookSecret == EntityConfigSecretPlaceholder {
+		return runnerErrors.NewMissingSecretError("webhook_secret must be set to the secret of the new entity")
+	}
+	return nil
+}
+
+// ResourceName returns the name of the entity, as owner/name for repositories.
+func (e EntityConfig) ResourceName() string {
+	if e.EntityType == GithubEntityTypeRepository {
+		return e.Owner + "/" + e.Name
+	}
+	return e.Name
+}
+
+func (e EntityConfig) validateSettings() error {
+	switch e.EntityType {
+	case GithubEntityTypeRepository:
+		if e.Owner == "" {
+			return runnerErrors.NewBadRequestError("missing owner")
+		}
+	case GithubEntityTypeOrganization, GithubEntityTypeEnterprise:
+	default:
+		return runnerErrors.NewBadRequestError("invalid entity type %q", e.EntityType)
+	}
+	if e.Name == "" {
+		return runnerErrors.NewBadRequestError("missing name")
+	}
+	if e.CredentialsName == "" {
+		return runnerErrors.NewBadRequestError("missing credentials name")
+	}
+	return nil
+}
+
+// ApplyControllerConfigParams holds the options used when applying a controller config.
+type ApplyControllerConfigParams struct {
+	// DryRun only lists the changes that would be made.
+	DryRun bool
+	// Prune removes the entities and pools that are not in the config.
+	Prune bool
+}
+
+// Validate checks the config before it is compared to the state of the controller. The
+// version of the entities in the config may be left out.
+func (c ControllerConfig) Validate() error {
+	if c.Version != ControllerConfigVersion {
+		return runnerErrors.NewBadRequestError("unsupported config version %d", c.Version)
+	}
+
+	credentials := map[string]struct{}{}
+	for _, creds := range c.Credentials {
+		if creds.Name == "" {
+			return runnerErrors.NewBadRequestError("missing credentials name")
+		}
+		if _, ok := credentials[creds.Name]; ok {
+			return runnerErrors.NewBadRequestError("duplicate credentials %s", creds.Name)
+		}
+		credentials[creds.Name] = struct{}{}
+	}
+
+	entities := map[string]struct{}{}
+	for _, entity := range c.Entities {
+		if entity.Version == 0 {
+			entity.Version = EntityConfigVersion
+		}
+		if err := entity.validateSettings(); err != nil {
+			return runnerErrors.NewBadRequestError("invalid entity %s: %s", entity.ResourceName(), err)
+		}
+		if err := entity.Validate(); err != nil {
+			return runnerErrors.NewBadRequestError("invalid %s %s: %s", entity.EntityType, entity.ResourceName(), err)
+		}
+		endpoint := entity.Endpoint
+		for _, creds := range c.Credentials {
+			if creds.Name != entity.CredentialsName || creds.Endpoint == "" {
+				continue
+			}
+			if endpoint != "" && endpoint != creds.Endpoint {
+				return runnerErrors.NewBadRequestError("the endpoint of %s %s does not match the endpoint of credentials %s", entity.EntityType, entity.ResourceName(), creds.Name)
+			}
+			endpoint = creds.Endpoint
+		}
+		key := strings.ToLower(string(entity.EntityType) + "/" + endpoint + "/" + entity.ResourceName())
+		if _, ok := entities[key]; ok {
+			return runnerErrors.NewBadRequestError("duplicate %s %s", entity.EntityType, entity.ResourceName())
+		}
+		entities[key] = struct{}{}
+
+		poolIDs := map[string]struct{}{}
+		for _, pool := range entity.Pools {
+			if pool.ID == "" {
+				continue
+			}
+			if _, ok := poolIDs[pool.ID]; ok {
+				return runnerErrors.NewBadRequestError("duplicate pool %s in %s %s", pool.ID, entity.EntityType, entity.ResourceName())
+			}
+			poolIDs[pool.ID] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// CreateRunnerGroupParams holds the parameters for creating a runner group in
+// an organization or enterprise.
+type CreateRunnerGroupParams struct {
+	Name string `json:"name"`
+	// Visibility defaults to "all" if not set.
+	Visibility               RunnerGroupVisibility `json:"visibility,omitempty"`
+	AllowsPublicRepositories bool                  `json:"allows_public_repositories,omitempty"`
+	// RestrictedToWorkflows limits the group to the workflows in SelectedWorkflows.
+	RestrictedToWorkflows bool     `json:"restricted_to_workflows,omitempty"`
+	SelectedWorkflows     []string `json:"selected_workflows,omitempty"`
+}
+
+func (c CreateRunnerGroupParams) Validate() error {
+	if c.Name == "" {
+		return runnerErrors.NewBadRequestError("missing name")
+	}
+	switch c.Visibility {
+	case "", RunnerGroupVisibilityAll, RunnerGroupVisibilitySelected, RunnerGroupVisibilityPrivate:
+	default:
+		return runnerErrors.NewBadRequestError("invalid visibility %q", c.Visibility)
+	}
+	if !c.RestrictedToWorkflows && len(c.SelectedWorkflows) > 0 {
+		return runnerErrors.NewBadRequestError("selected_workflows requires restricted_to_workflows")
+	}
+	return nil
+}
+
+// UpdateJobMetadataParams holds the metadata keys to set on a job. The keys are
+// merged into the existing metadata of the job. Keys set to null are removed.
+type UpdateJobMetadataParams struct {
+	Metadata map[string]json.RawMessage `json:"metadata"`
+}
+
+func (u UpdateJobMetadataParams) Validate() error {
+	if len(u.Metadata) == 0 {
+		return runnerErrors.NewBadRequestError("missing metadata")
+	}
+	for key, value := range u.Metadata {
+		if key == "" {
+			return runnerErrors.NewBadRequestError("metadata keys must not be empty")
+		}
+		if !json.Valid(value) {
+			return runnerErrors.NewBadRequestError("invalid value for metadata key %q", key)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,152 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package params
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+)
+
+// JSONSchema is a subset of a JSON schema, describing the parameters accepted by the API.
+type JSONSchema struct {
+	Schema     string                 `json:"$schema,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Format     string                 `json:"format,omitempty"`
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	Items      *JSONSchema            `json:"items,omitempty"`
+	// AdditionalProperties describes the values of free form objects, like maps.
+	AdditionalProperties *JSONSchema `json:"additionalProperties,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+	Enum                 []string    `json:"enum,omitempty"`
+	Minimum              *float64    `json:"minimum,omitempty"`
+}
+
+// PoolSchema holds the schemas of the parameters used to create and update pools.
+type PoolSchema struct {
+	Create *JSONSchema `json:"create"`
+	Update *JSONSchema `json:"update"`
+}
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+
+	// schemaEnums holds the values accepted for types that are used as enums.
+	schemaEnums = map[reflect.Type][]string{
+		reflect.TypeOf(commonParams.OSType("")): {
+			string(commonParams.Linux), string(commonParams.Windows),
+		},
+		reflect.TypeOf(commonParams.OSArch("")): {
+			string(commonParams.Amd64), string(commonParams.I386),
+			string(commonParams.Arm64), string(commonParams.Arm),
+		},
+	}
+)
+
+// NewJSONSchema returns the JSON schema of the value, based on the JSON tags of its fields.
+func NewJSONSchema(title string, v interface{}) *JSONSchema {
+	schema := jsonSchemaForType(reflect.TypeOf(v), map[reflect.Type]bool{})
+	schema.Schema = jsonSchemaDraft
+	schema.Title = title
+	return schema
+}
+
+func jsonSchemaForType(typ reflect.Type, seen map[reflect.Type]bool) *JSONSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if enum, ok := schemaEnums[typ]; ok {
+		return &JSONSchema{Type: "string", Enum: enum}
+	}
+
+	switch typ {
+	case rawMessageType:
+		// Raw JSON, like the extra specs of a pool, is passed as is to the provider.
+		return &JSONSchema{Type: "object"}
+	case timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	}
+
+	minimum := float64(0)
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings.
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: jsonSchemaForType(typ.Elem(), seen)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: jsonSchemaForType(typ.Elem(), seen)}
+	case reflect.Struct:
+		if seen[typ] {
+			// Recursive types are described only once.
+			return &JSONSchema{Type: "object"}
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+
+		schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+		addStructProperties(schema, typ, seen)
+		return schema
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func addStructProperties(schema *JSONSchema, typ reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructProperties(schema, field.Type, seen)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = jsonSchemaForType(field.Type, seen)
+	}
+}
+
+// SetRequired marks properties of the schema as required.
+func (s *JSONSchema) SetRequired(names ...string) {
+	s.Required = append(s.Required, names...)
+	sort.Strings(s.Required)
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	// maxFailureSampleMessages is the maximum number of distinct messages we
+	// return for each failure group.
+	maxFailureSampleMessages = 3
+	// maxFailureSampleMessageLength is the length at which we truncate sample
+	// messages. Provider faults can include full stack traces.
+	maxFailureSampleMessageLength = 512
+)
+
+// errorClassMatchers maps error classes to substrings commonly found in provider
+// faults for that class. Matching is done in order, on the lower cased fault.
+var errorClassMatchers = []struct {
+	class    params.ErrorClass
+	patterns []string
+}{
+	{params.ErrorClassQuota, []string{"quota", "limitexceeded", "limit exceeded", "insufficient capacity", "insufficientinstancecapacity", "resource exhausted"}},
+	{params.ErrorClassImageNotFound, []string{"image not found", "imagenotfound", "no such image", "image does not exist", "invalidamiid", "could not find image", "failed to find image"}},
+	{params.ErrorClassFlavorNotFound, []string{"flavor not found", "flavornotfound", "no such flavor", "invalid instance type", "unsupported instance type", "profile not found"}},
+	{params.ErrorClassAuthentication, []string{"unauthorized", "forbidden", "authentication", "permission denied", "access denied", "invalid credentials"}},
+	{params.ErrorClassTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{params.ErrorClassNetwork, []string{"connection refused", "connection reset", "no route to host", "network is unreachable", "no such host", "tls handshake"}},
+}
+
+func classifyProviderFault(fault string) params.ErrorClass {
+	lowered := strings.ToLower(fault)
+	for _, matcher := range errorClassMatchers {
+		for _, pattern := range matcher.patterns {
+			if strings.Contains(lowered, pattern) {
+				return matcher.class
+			}
+		}
+	}
+	return params.ErrorClassUnknown
+}
+
+// truncateSampleMessage truncates a message to maxFailureSampleMessageLength bytes,
+// without splitting a multi byte character.
+func truncateSampleMessage(msg string) string {
+	if len(msg) <= maxFailureSampleMessageLength {
+		return msg
+	}
+	end := maxFailureSampleMessageLength
+	for end > 0 && !utf8.RuneStart(msg[end]) {
+		end--
+	}
+	return msg[:end]
+}
+
+// GetInstanceFailureAnalytics groups the provider faults recorded in the given time
+// window by provider, pool and error class.
+func (r *Runner) GetInstanceFailureAnalytics(ctx context.Context, window time.Duration) (params.InstanceFailureAnalytics, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.InstanceFailureAnalytics{}, runnerErrors.ErrUnauthorized
+	}
+
+	if window <= 0 {
+		return params.InstanceFailureAnalytics{}, runnerErrors.NewBadRequestError("window must be a positive duration")
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-window)
+
+	instances, err := r.store.ListInstancesWithProviderFaults(ctx, since)
+	if err != nil {
+		return params.InstanceFailureAnalytics{}, errors.Wrap(err, "fetching instances")
+	}
+
+	pools, err := r.store.ListAllPools(ctx)
+	if err != nil {
+		return params.InstanceFailureAnalytics{}, errors.Wrap(err, "fetching pools")
+	}
+	poolProviders := make(map[string]string, len(pools))
+	for _, pool := range pools {
+		poolProviders[pool.ID] = pool.ProviderName
+	}
+
+	ret := params.InstanceFailureAnalytics{
+		Since:  since,
+		Until:  until,
+		Groups: []params.InstanceFailureGroup{},
+	}
+	groups := map[string]*params.InstanceFailureGroup{}
+	var keys []string
+	for _, instance := range instances {
+		fault := strings.TrimSpace(string(instance.ProviderFault))
+		if fault == "" {
+			continue
+		}
+
+		class := classifyProviderFault(fault)
+		key := strings.Join([]string{instance.PoolID, string(class)}, "^")
+		group, ok := groups[key]
+		if !ok {
+			group = &params.InstanceFailureGroup{
+				ProviderName: poolProviders[instance.PoolID],
+				PoolID:       instance.PoolID,
+				ErrorClass:   class,
+			}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+
+		group.Count++
+		ret.TotalFailures++
+		faultAt := instance.UpdatedAt
+		if instance.ProviderFaultAt != nil {
+			faultAt = *instance.ProviderFaultAt
+		}
+		if faultAt.After(group.LastSeen) {
+			group.LastSeen = faultAt
+		}
+
+		fault = truncateSampleMessage(fault)
+		if len(group.SampleMessages) < maxFailureSampleMessages && !slices.Contains(group.SampleMessages, fault) {
+			group.SampleMessages = append(group.SampleMessages, fault)
+		}
+	}
+
+	for _, key := range keys {
+		ret.Groups = append(ret.Groups, *groups[key])
+	}
+	// Most frequent failures first.
+	sort.SliceStable(ret.Groups, func(i, j int) bool {
+		return ret.Groups[i].Count > ret.Groups[j].Count
+	})
+
+	return ret, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestClassifyProviderFault(t *testing.T) {
+	tests := map[string]params.ErrorClass{
+		"Quota exceeded for instances: Requested 1, but already used 10 of 10": params.ErrorClassQuota,
+		"InvalidAMIID.NotFound: The image id '[ami-123]' does not exist":       params.ErrorClassImageNotFound,
+		"Flavor not found: m1.huge":                                            params.ErrorClassFlavorNotFound,
+		"401 Unauthorized":                                                     params.ErrorClassAuthentication,
+		"context deadline exceeded":                                            params.ErrorClassTimeout,
+		"dial tcp 10.0.0.1:443: connect: connection refused":                   params.ErrorClassNetwork,
+		"something unexpected happened":                                        params.ErrorClassUnknown,
+	}
+
+	for fault, expected := range tests {
+		require.Equal(t, expected, classifyProviderFault(fault), fault)
+	}
+}
+
+func TestTruncateSampleMessage(t *testing.T) {
+	short := "quota exceeded"
+	require.Equal(t, short, truncateSampleMessage(short))
+
+	// A multi byte character that straddles the limit is dropped whole.
+	long := strings.Repeat("a", maxFailureSampleMessageLength-1) + "é" + "tail"
+	truncated := truncateSampleMessage(long)
+	require.True(t, utf8.ValidString(truncated))
+	require.Equal(t, strings.Repeat("a", maxFailureSampleMessageLength-1), truncated)
+}
+
+func TestGetInstanceFailureAnalytics(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	org, err := db.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+
+	pool, err := db.CreateEntityPool(adminCtx, params.GithubEntity{ID: org.ID, EntityType: params.GithubEntityTypeOrganization}, params.CreatePoolParams{
+		ProviderName: "test-provider",
+		MaxRunners:   4,
+		Image:        "test-image",
+		Flavor:       "test-flavor",
+		OSType:       "linux",
+		Tags:         []string{"amd64-linux-runner"},
+	})
+	require.Nil(t, err)
+
+	faults := []string{
+		"quota exceeded",
+		"quota exceeded",
+		"Quota exceeded for cores",
+		"image not found",
+	}
+	for idx, fault := range faults {
+		name := fmt.Sprintf("test-instance-%d", idx)
+		_, err := db.CreateInstance(adminCtx, pool.ID, params.CreateInstanceParams{Name: name, OSType: "linux"})
+		require.Nil(t, err)
+		_, err = db.UpdateInstance(adminCtx, name, params.UpdateInstanceParams{
+			Status:        commonParams.InstanceError,
+			ProviderFault: []byte(fault),
+		})
+		require.Nil(t, err)
+	}
+	// An instance without a provider fault should be ignored.
+	_, err = db.CreateInstance(adminCtx, pool.ID, params.CreateInstanceParams{Name: "healthy-instance", OSType: "linux"})
+	require.Nil(t, err)
+
+	r := &Runner{
+		store: db,
+		ctx:   adminCtx,
+	}
+
+	analytics, err := r.GetInstanceFailureAnalytics(adminCtx, time.Hour)
+	require.Nil(t, err)
+	require.Equal(t, uint(4), analytics.TotalFailures)
+	require.Len(t, analytics.Groups, 2)
+
+	require.Equal(t, params.ErrorClassQuota, analytics.Groups[0].ErrorClass)
+	require.Equal(t, uint(3), analytics.Groups[0].Count)
+	require.Equal(t, pool.ID, analytics.Groups[0].PoolID)
+	require.Equal(t, "test-provider", analytics.Groups[0].ProviderName)
+	require.Equal(t, []string{"quota exceeded", "Quota exceeded for cores"}, analytics.Groups[0].SampleMessages)
+
+	require.Equal(t, params.ErrorClassImageNotFound, analytics.Groups[1].ErrorClass)
+	require.Equal(t, uint(1), analytics.Groups[1].Count)
+
+	_, err = r.GetInstanceFailureAnalytics(adminCtx, 0)
+	require.NotNil(t, err)
+
+	_, err = r.GetInstanceFailureAnalytics(context.Background(), time.Hour)
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+}
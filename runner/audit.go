@@ -0,0 +1,156 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// auditPruneInterval is the interval at which audit records older than the
+// configured retention are removed.
+const auditPruneInterval = 1 * time.Hour
+
+// auditIgnoredFields are fields that change without being part of an update made
+// through the API, or that are not part of the resource itself.
+var auditIgnoredFields = map[string]struct{}{
+	"updated_at":          {},
+	"instances":           {},
+	"events":              {},
+	"pool_manager_status": {},
+	"provider_paused":     {},
+	"capacity_warning":    {},
+}
+
+// ListAuditRecords returns one page of audit records.
+func (r *Runner) ListAuditRecords(ctx context.Context, param params.ListAuditRecordsParams) (params.AuditRecordsPage, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.AuditRecordsPage{}, runnerErrors.ErrUnauthorized
+	}
+
+	records, err := r.store.ListAuditRecords(ctx, param)
+	if err != nil {
+		return params.AuditRecordsPage{}, errors.Wrap(err, "fetching audit records")
+	}
+	return records, nil
+}
+
+// auditChanges returns the fields that differ between the JSON representations of
+// before and after. Either of them may be nil, for created or deleted resources.
+// Fields that are not serialized, like secrets, are never part of the changes.
+func auditChanges(before, after interface{}) ([]params.AuditChange, error) {
+	toFields := func(obj interface{}) (map[string]json.RawMessage, error) {
+		fields := map[string]json.RawMessage{}
+		if obj == nil {
+			return fields, nil
+		}
+		asJSON, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(asJSON, &fields); err != nil {
+			return nil, err
+		}
+		return fields, nil
+	}
+
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing resource")
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing resource")
+	}
+
+	names := map[string]struct{}{}
+	for name := range beforeFields {
+		names[name] = struct{}{}
+	}
+	for name := range afterFields {
+		names[name] = struct{}{}
+	}
+
+	changes := []params.AuditChange{}
+	for name := range names {
+		if _, ok := auditIgnoredFields[name]; ok {
+			continue
+		}
+		if bytes.Equal(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, params.AuditChange{
+			Field:  name,
+			Before: beforeFields[name],
+			After:  afterFields[name],
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// recordMutation adds an audit record for a resource that was created, updated or
+// deleted through the API. Failing to record the mutation does not fail the request,
+// as the change was already made.
+func (r *Runner) recordMutation(ctx context.Context, action params.AuditAction, resourceType params.AuditResourceType, resourceID string, before, after interface{}) {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to compute audit changes",
+			"resource_type", resourceType,
+			"resource_id", resourceID)
+	}
+
+	record := params.AuditRecord{
+		Action:       action,
+		UserID:       auth.UserID(ctx),
+		Username:     auth.Username(ctx),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Changes:      changes,
+	}
+	if _, err := r.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+}
+
+// pruneAuditRecords removes the audit records that are older than the configured
+// retention, until the runner context is canceled.
+func (r *Runner) pruneAuditRecords(retention time.Duration) {
+	ticker := time.NewTicker(auditPruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.store.DeleteAuditRecordsBefore(r.ctx, time.Now().UTC().Add(-retention)); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to prune audit records")
+		}
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+// Package bootstrap runs the bootstrap transformer configured by the operator.
+//
+// The transformer is an executable or an HTTP endpoint that receives the bootstrap
+// params of every runner before they are sent to the provider. It can change the
+// labels, the extra specs and the user data options of the runner. Any other field
+// it returns is ignored. The instance token is never sent to the transformer, as it
+// gives access to the metadata of the runner, including its JIT config.
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	garmExec "github.com/cloudbase/garm-provider-common/util/exec"
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// maxResponseSize is the maximum size of the response of the transformer.
+const maxResponseSize = 1 << 20
+
+// NewTransformer returns a transformer that runs the hook described by cfg.
+func NewTransformer(cfg config.BootstrapTransformer) common.BootstrapTransformer {
+	return &transformer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.TimeoutOrDefault()},
+	}
+}
+
+type transformer struct {
+	cfg    config.BootstrapTransformer
+	client *http.Client
+}
+
+func (t *transformer) Transform(ctx context.Context, entity params.GithubEntity, bootstrapArgs commonParams.BootstrapInstance) (commonParams.BootstrapInstance, error) {
+	req := params.BootstrapTransformRequest{
+		EntityType: entity.EntityType,
+		Owner:      entity.Owner,
+		Name:       entity.Name,
+		Bootstrap:  bootstrapArgs,
+	}
+	req.Bootstrap.InstanceToken = ""
+
+	asJs, err := json.Marshal(req)
+	if err != nil {
+		return commonParams.BootstrapInstance{}, errors.Wrap(err, "marshaling bootstrap params")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.TimeoutOrDefault())
+	defer cancel()
+
+	var out []byte
+	if t.cfg.Command != "" {
+		out, err = garmExec.Exec(ctx, t.cfg.Command, asJs, nil)
+	} else {
+		out, err = t.post(ctx, asJs)
+	}
+	if err != nil {
+		return commonParams.BootstrapInstance{}, errors.Wrap(err, "running bootstrap transformer")
+	}
+
+	var resp params.BootstrapTransformResponse
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return commonParams.BootstrapInstance{}, errors.Wrap(err, "decoding bootstrap transformer response")
+		}
+	}
+	return applyTransformation(bootstrapArgs, resp), nil
+}
+
+func (t *transformer) post(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("bootstrap transformer returned %s: %s", resp.Status, bytes.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// applyTransformation copies the fields the transformer is allowed to change.
+func applyTransformation(bootstrapArgs commonParams.BootstrapInstance, resp params.BootstrapTransformResponse) commonParams.BootstrapInstance {
+	if resp.Labels != nil {
+		bootstrapArgs.Labels = resp.Labels
+	}
+	if len(resp.ExtraSpecs) > 0 {
+		bootstrapArgs.ExtraSpecs = resp.ExtraSpecs
+	}
+	if resp.UserDataOptions != nil {
+		bootstrapArgs.UserDataOptions = *resp.UserDataOptions
+	}
+	return bootstrapArgs
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/params"
+)
+
+var testEntity = params.GithubEntity{
+	Owner:      "test-org",
+	EntityType: params.GithubEntityTypeOrganization,
+}
+
+func testBootstrapArgs() commonParams.BootstrapInstance {
+	return commonParams.BootstrapInstance{
+		Name:          "garm-test",
+		InstanceToken: "secret-token",
+		Image:         "ubuntu:22.04",
+		Flavor:        "default",
+		Labels:        []string{"linux"},
+		ExtraSpecs:    json.RawMessage(`{"disk_size": 20}`),
+	}
+}
+
+func TestTransformURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer policy-token", r.Header.Get("Authorization"))
+
+		var req params.BootstrapTransformRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, params.GithubEntityTypeOrganization, req.EntityType)
+		require.Equal(t, "test-org", req.Owner)
+		require.Equal(t, "garm-test", req.Bootstrap.Name)
+		require.Empty(t, req.Bootstrap.InstanceToken)
+
+		// The image is not one of the fields the transformer may change.
+		_, _ = w.Write([]byte(`{
+			"labels": ["linux", "team-a"],
+			"extra_specs": {"disk_size": 50},
+			"user_data_options": {"extra_packages": ["jq"]},
+			"image": "something-else"
+		}`))
+	}))
+	defer srv.Close()
+
+	transformer := NewTransformer(config.BootstrapTransformer{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer policy-token"},
+	})
+	out, err := transformer.Transform(context.Background(), testEntity, testBootstrapArgs())
+	require.NoError(t, err)
+	require.Equal(t, []string{"linux", "team-a"}, out.Labels)
+	require.JSONEq(t, `{"disk_size": 50}`, string(out.ExtraSpecs))
+	require.Equal(t, []string{"jq"}, out.UserDataOptions.ExtraPackages)
+	require.Equal(t, "ubuntu:22.04", out.Image)
+	require.Equal(t, "secret-token", out.InstanceToken)
+}
+
+func TestTransformURLError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "flavor not allowed", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	transformer := NewTransformer(config.BootstrapTransformer{URL: srv.URL})
+	_, err := transformer.Transform(context.Background(), testEntity, testBootstrapArgs())
+	require.ErrorContains(t, err, "flavor not allowed")
+}
+
+func TestTransformCommand(t *testing.T) {
+	command := filepath.Join(t.TempDir(), "transformer")
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"labels\": [\"from-command\"]}'\n"
+	require.NoError(t, os.WriteFile(command, []byte(script), 0o700))
+
+	transformer := NewTransformer(config.BootstrapTransformer{Command: command})
+	out, err := transformer.Transform(context.Background(), testEntity, testBootstrapArgs())
+	require.NoError(t, err)
+	require.Equal(t, []string{"from-command"}, out.Labels)
+	// Fields that are not returned are left as they are.
+	require.JSONEq(t, `{"disk_size": 20}`, string(out.ExtraSpecs))
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// CreateCapacityReservation raises the min idle runners of a pool for a window of time.
+// The reservation is refused if, together with the reservations that overlap it, it
+// would raise the min idle runners of the pool above its max runners.
+func (r *Runner) CreateCapacityReservation(ctx context.Context, param params.CreateCapacityReservationParams) (params.CapacityReservation, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.CapacityReservation{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.CapacityReservation{}, errors.Wrap(err, "validating params")
+	}
+
+	pool, err := r.store.GetPoolByID(ctx, param.PoolID)
+	if err != nil {
+		return params.CapacityReservation{}, errors.Wrap(err, "fetching pool")
+	}
+
+	reservations, err := r.store.ListCapacityReservations(ctx)
+	if err != nil {
+		return params.CapacityReservation{}, errors.Wrap(err, "fetching capacity reservations")
+	}
+
+	reserved := peakReservedIdleRunners(reservations, pool.ID, param.StartTime, param.EndTime)
+	if total := pool.MinIdleRunners + reserved + param.ExtraIdleRunners; total > pool.MaxRunners {
+		return params.CapacityReservation{}, runnerErrors.NewBadRequestError(
+			"reservation would raise min idle runners of pool %s to %d, which is above its max runners (%d)",
+			pool.ID, total, pool.MaxRunners)
+	}
+
+	reservation, err := r.store.CreateCapacityReservation(ctx, param)
+	if err != nil {
+		return params.CapacityReservation{}, errors.Wrap(err, "creating capacity reservation")
+	}
+	return reservation, nil
+}
+
+func (r *Runner) ListCapacityReservations(ctx context.Context) ([]params.CapacityReservation, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	reservations, err := r.store.ListCapacityReservations(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching capacity reservations")
+	}
+	return reservations, nil
+}
+
+// DeleteCapacityReservation removes a reservation. If the reservation is active, the
+// extra idle runners are scaled down.
+func (r *Runner) DeleteCapacityReservation(ctx context.Context, reservationID string) error {
+	if !auth.IsAdmin(ctx) {
+		return runnerErrors.ErrUnauthorized
+	}
+
+	if err := r.store.DeleteCapacityReservation(ctx, reservationID); err != nil {
+		return errors.Wrap(err, "deleting capacity reservation")
+	}
+	return nil
+}
+
+// peakReservedIdleRunners returns the highest number of extra idle runners reserved for
+// a pool at any time during the given window.
+func peakReservedIdleRunners(reservations []params.CapacityReservation, poolID string, start, end time.Time) uint {
+	var overlapping []params.CapacityReservation
+	for _, reservation := range reservations {
+		if reservation.PoolID == poolID && reservation.Overlaps(start, end) {
+			overlapping = append(overlapping, reservation)
+		}
+	}
+
+	// The number of reserved runners only goes up when a reservation starts, so the peak
+	// is at the start of the window or at the start of one of the reservations.
+	points := []time.Time{start}
+	for _, reservation := range overlapping {
+		if reservation.StartTime.After(start) {
+			points = append(points, reservation.StartTime)
+		}
+	}
+
+	var peak uint
+	for _, point := range points {
+		var reserved uint
+		for _, reservation := range overlapping {
+			if reservation.IsActive(point) {
+				reserved += reservation.ExtraIdleRunners
+			}
+		}
+		if reserved > peak {
+			peak = reserved
+		}
+	}
+	return peak
+}
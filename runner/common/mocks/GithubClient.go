@@ -85,6 +85,34 @@ func (_m *GithubClient) CreateEntityRegistrationToken(ctx context.Context) (*git
 	return r0, r1, r2
 }
 
+// CreateEntityRunnerGroup provides a mock function with given fields: ctx, param
+func (_m *GithubClient) CreateEntityRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEntityRunnerGroup")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) (params.RunnerGroup, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) params.RunnerGroup); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.CreateRunnerGroupParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteEntityHook provides a mock function with given fields: ctx, id
 func (_m *GithubClient) DeleteEntityHook(ctx context.Context, id int64) (*github.Response, error) {
 	ret := _m.Called(ctx, id)
@@ -115,6 +143,84 @@ func (_m *GithubClient) DeleteEntityHook(ctx context.Context, id int64) (*github
 	return r0, r1
 }
 
+// GetActionsPermissions provides a mock function with given fields: ctx, org
+func (_m *GithubClient) GetActionsPermissions(ctx context.Context, org string) (*github.ActionsPermissions, *github.Response, error) {
+	ret := _m.Called(ctx, org)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActionsPermissions")
+	}
+
+	var r0 *github.ActionsPermissions
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*github.ActionsPermissions, *github.Response, error)); ok {
+		return rf(ctx, org)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *github.ActionsPermissions); ok {
+		r0 = rf(ctx, org)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.ActionsPermissions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) *github.Response); ok {
+		r1 = rf(ctx, org)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, org)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetEntityActionsPermissions provides a mock function with given fields: ctx
+func (_m *GithubClient) GetEntityActionsPermissions(ctx context.Context) (*github.ActionsPermissionsRepository, *github.Response, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEntityActionsPermissions")
+	}
+
+	var r0 *github.ActionsPermissionsRepository
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*github.ActionsPermissionsRepository, *github.Response, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *github.ActionsPermissionsRepository); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.ActionsPermissionsRepository)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) *github.Response); ok {
+		r1 = rf(ctx)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetEntityHook provides a mock function with given fields: ctx, id
 func (_m *GithubClient) GetEntityHook(ctx context.Context, id int64) (*github.Hook, error) {
 	ret := _m.Called(ctx, id)
@@ -184,6 +290,34 @@ func (_m *GithubClient) GetEntityJITConfig(ctx context.Context, instance string,
 	return r0, r1, r2
 }
 
+// GetEntityRunnerGroupByName provides a mock function with given fields: ctx, name
+func (_m *GithubClient) GetEntityRunnerGroupByName(ctx context.Context, name string) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEntityRunnerGroupByName")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.RunnerGroup, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.RunnerGroup); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWorkflowJobByID provides a mock function with given fields: ctx, owner, repo, jobID
 func (_m *GithubClient) GetWorkflowJobByID(ctx context.Context, owner string, repo string, jobID int64) (*github.WorkflowJob, *github.Response, error) {
 	ret := _m.Called(ctx, owner, repo, jobID)
@@ -223,6 +357,84 @@ func (_m *GithubClient) GetWorkflowJobByID(ctx context.Context, owner string, re
 	return r0, r1, r2
 }
 
+// ListEnabledReposInOrg provides a mock function with given fields: ctx, owner, opts
+func (_m *GithubClient) ListEnabledReposInOrg(ctx context.Context, owner string, opts *github.ListOptions) (*github.ActionsEnabledOnOrgRepos, *github.Response, error) {
+	ret := _m.Called(ctx, owner, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEnabledReposInOrg")
+	}
+
+	var r0 *github.ActionsEnabledOnOrgRepos
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *github.ListOptions) (*github.ActionsEnabledOnOrgRepos, *github.Response, error)); ok {
+		return rf(ctx, owner, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *github.ListOptions) *github.ActionsEnabledOnOrgRepos); ok {
+		r0 = rf(ctx, owner, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.ActionsEnabledOnOrgRepos)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *github.ListOptions) *github.Response); ok {
+		r1 = rf(ctx, owner, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, *github.ListOptions) error); ok {
+		r2 = rf(ctx, owner, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListEntityHookDeliveries provides a mock function with given fields: ctx, id, opts
+func (_m *GithubClient) ListEntityHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+	ret := _m.Called(ctx, id, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityHookDeliveries")
+	}
+
+	var r0 []*github.HookDelivery
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)); ok {
+		return rf(ctx, id, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *github.ListCursorOptions) []*github.HookDelivery); ok {
+		r0 = rf(ctx, id, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*github.HookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *github.ListCursorOptions) *github.Response); ok {
+		r1 = rf(ctx, id, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, *github.ListCursorOptions) error); ok {
+		r2 = rf(ctx, id, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ListEntityHooks provides a mock function with given fields: ctx, opts
 func (_m *GithubClient) ListEntityHooks(ctx context.Context, opts *github.ListOptions) ([]*github.Hook, *github.Response, error) {
 	ret := _m.Called(ctx, opts)
@@ -301,6 +513,36 @@ func (_m *GithubClient) ListEntityRunnerApplicationDownloads(ctx context.Context
 	return r0, r1, r2
 }
 
+// ListEntityRunnerGroups provides a mock function with given fields: ctx
+func (_m *GithubClient) ListEntityRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityRunnerGroups")
+	}
+
+	var r0 []params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.RunnerGroup, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.RunnerGroup); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.RunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListEntityRunners provides a mock function with given fields: ctx, opts
 func (_m *GithubClient) ListEntityRunners(ctx context.Context, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
 	ret := _m.Called(ctx, opts)
@@ -340,6 +582,162 @@ func (_m *GithubClient) ListEntityRunners(ctx context.Context, opts *github.List
 	return r0, r1, r2
 }
 
+// ListOrganizationRunnerGroups provides a mock function with given fields: ctx, org, opts
+func (_m *GithubClient) ListOrganizationRunnerGroups(ctx context.Context, org string, opts *github.ListOrgRunnerGroupOptions) (*github.RunnerGroups, *github.Response, error) {
+	ret := _m.Called(ctx, org, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOrganizationRunnerGroups")
+	}
+
+	var r0 *github.RunnerGroups
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *github.ListOrgRunnerGroupOptions) (*github.RunnerGroups, *github.Response, error)); ok {
+		return rf(ctx, org, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *github.ListOrgRunnerGroupOptions) *github.RunnerGroups); ok {
+		r0 = rf(ctx, org, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.RunnerGroups)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *github.ListOrgRunnerGroupOptions) *github.Response); ok {
+		r1 = rf(ctx, org, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, *github.ListOrgRunnerGroupOptions) error); ok {
+		r2 = rf(ctx, org, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListRepositoryAccessRunnerGroup provides a mock function with given fields: ctx, org, groupID, opts
+func (_m *GithubClient) ListRepositoryAccessRunnerGroup(ctx context.Context, org string, groupID int64, opts *github.ListOptions) (*github.ListRepositories, *github.Response, error) {
+	ret := _m.Called(ctx, org, groupID, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRepositoryAccessRunnerGroup")
+	}
+
+	var r0 *github.ListRepositories
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, *github.ListOptions) (*github.ListRepositories, *github.Response, error)); ok {
+		return rf(ctx, org, groupID, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, *github.ListOptions) *github.ListRepositories); ok {
+		r0 = rf(ctx, org, groupID, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.ListRepositories)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, *github.ListOptions) *github.Response); ok {
+		r1 = rf(ctx, org, groupID, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, int64, *github.ListOptions) error); ok {
+		r2 = rf(ctx, org, groupID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListRepositoryWorkflowRuns provides a mock function with given fields: ctx, owner, repo, opts
+func (_m *GithubClient) ListRepositoryWorkflowRuns(ctx context.Context, owner string, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	ret := _m.Called(ctx, owner, repo, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRepositoryWorkflowRuns")
+	}
+
+	var r0 *github.WorkflowRuns
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)); ok {
+		return rf(ctx, owner, repo, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *github.ListWorkflowRunsOptions) *github.WorkflowRuns); ok {
+		r0 = rf(ctx, owner, repo, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.WorkflowRuns)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *github.ListWorkflowRunsOptions) *github.Response); ok {
+		r1 = rf(ctx, owner, repo, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, *github.ListWorkflowRunsOptions) error); ok {
+		r2 = rf(ctx, owner, repo, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListWorkflowJobs provides a mock function with given fields: ctx, owner, repo, runID, opts
+func (_m *GithubClient) ListWorkflowJobs(ctx context.Context, owner string, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error) {
+	ret := _m.Called(ctx, owner, repo, runID, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWorkflowJobs")
+	}
+
+	var r0 *github.Jobs
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error)); ok {
+		return rf(ctx, owner, repo, runID, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, *github.ListWorkflowJobsOptions) *github.Jobs); ok {
+		r0 = rf(ctx, owner, repo, runID, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.Jobs)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64, *github.ListWorkflowJobsOptions) *github.Response); ok {
+		r1 = rf(ctx, owner, repo, runID, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, int64, *github.ListWorkflowJobsOptions) error); ok {
+		r2 = rf(ctx, owner, repo, runID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // PingEntityHook provides a mock function with given fields: ctx, id
 func (_m *GithubClient) PingEntityHook(ctx context.Context, id int64) (*github.Response, error) {
 	ret := _m.Called(ctx, id)
@@ -85,6 +85,34 @@ func (_m *GithubEntityOperations) CreateEntityRegistrationToken(ctx context.Cont
 	return r0, r1, r2
 }
 
+// CreateEntityRunnerGroup provides a mock function with given fields: ctx, param
+func (_m *GithubEntityOperations) CreateEntityRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEntityRunnerGroup")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) (params.RunnerGroup, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) params.RunnerGroup); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.CreateRunnerGroupParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteEntityHook provides a mock function with given fields: ctx, id
 func (_m *GithubEntityOperations) DeleteEntityHook(ctx context.Context, id int64) (*github.Response, error) {
 	ret := _m.Called(ctx, id)
@@ -115,6 +143,45 @@ func (_m *GithubEntityOperations) DeleteEntityHook(ctx context.Context, id int64
 	return r0, r1
 }
 
+// GetEntityActionsPermissions provides a mock function with given fields: ctx
+func (_m *GithubEntityOperations) GetEntityActionsPermissions(ctx context.Context) (*github.ActionsPermissionsRepository, *github.Response, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEntityActionsPermissions")
+	}
+
+	var r0 *github.ActionsPermissionsRepository
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*github.ActionsPermissionsRepository, *github.Response, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *github.ActionsPermissionsRepository); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.ActionsPermissionsRepository)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) *github.Response); ok {
+		r1 = rf(ctx)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetEntityHook provides a mock function with given fields: ctx, id
 func (_m *GithubEntityOperations) GetEntityHook(ctx context.Context, id int64) (*github.Hook, error) {
 	ret := _m.Called(ctx, id)
@@ -184,6 +251,73 @@ func (_m *GithubEntityOperations) GetEntityJITConfig(ctx context.Context, instan
 	return r0, r1, r2
 }
 
+// GetEntityRunnerGroupByName provides a mock function with given fields: ctx, name
+func (_m *GithubEntityOperations) GetEntityRunnerGroupByName(ctx context.Context, name string) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEntityRunnerGroupByName")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.RunnerGroup, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.RunnerGroup); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEntityHookDeliveries provides a mock function with given fields: ctx, id, opts
+func (_m *GithubEntityOperations) ListEntityHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+	ret := _m.Called(ctx, id, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityHookDeliveries")
+	}
+
+	var r0 []*github.HookDelivery
+	var r1 *github.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)); ok {
+		return rf(ctx, id, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *github.ListCursorOptions) []*github.HookDelivery); ok {
+		r0 = rf(ctx, id, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*github.HookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *github.ListCursorOptions) *github.Response); ok {
+		r1 = rf(ctx, id, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, *github.ListCursorOptions) error); ok {
+		r2 = rf(ctx, id, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ListEntityHooks provides a mock function with given fields: ctx, opts
 func (_m *GithubEntityOperations) ListEntityHooks(ctx context.Context, opts *github.ListOptions) ([]*github.Hook, *github.Response, error) {
 	ret := _m.Called(ctx, opts)
@@ -262,6 +396,36 @@ func (_m *GithubEntityOperations) ListEntityRunnerApplicationDownloads(ctx conte
 	return r0, r1, r2
 }
 
+// ListEntityRunnerGroups provides a mock function with given fields: ctx
+func (_m *GithubEntityOperations) ListEntityRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEntityRunnerGroups")
+	}
+
+	var r0 []params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.RunnerGroup, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.RunnerGroup); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.RunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListEntityRunners provides a mock function with given fields: ctx, opts
 func (_m *GithubEntityOperations) ListEntityRunners(ctx context.Context, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
 	ret := _m.Called(ctx, opts)
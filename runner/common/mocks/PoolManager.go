@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 
+	garm_provider_commonparams "github.com/cloudbase/garm-provider-common/params"
+
 	params "github.com/cloudbase/garm/params"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,6 +16,34 @@ type PoolManager struct {
 	mock.Mock
 }
 
+// CreateRunnerGroup provides a mock function with given fields: ctx, param
+func (_m *PoolManager) CreateRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, param)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRunnerGroup")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) (params.RunnerGroup, error)); ok {
+		return rf(ctx, param)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, params.CreateRunnerGroupParams) params.RunnerGroup); ok {
+		r0 = rf(ctx, param)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, params.CreateRunnerGroupParams) error); ok {
+		r1 = rf(ctx, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteRunner provides a mock function with given fields: runner, forceRemove, bypassGHUnauthorizedError
 func (_m *PoolManager) DeleteRunner(runner params.Instance, forceRemove bool, bypassGHUnauthorizedError bool) error {
 	ret := _m.Called(runner, forceRemove, bypassGHUnauthorizedError)
@@ -32,6 +62,52 @@ func (_m *PoolManager) DeleteRunner(runner params.Instance, forceRemove bool, by
 	return r0
 }
 
+// EntityWebhookPath provides a mock function with given fields:
+func (_m *PoolManager) EntityWebhookPath() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for EntityWebhookPath")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// GetRunnerGroup provides a mock function with given fields: ctx, name
+func (_m *PoolManager) GetRunnerGroup(ctx context.Context, name string) (params.RunnerGroup, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRunnerGroup")
+	}
+
+	var r0 params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (params.RunnerGroup, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) params.RunnerGroup); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(params.RunnerGroup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWebhookInfo provides a mock function with given fields: ctx
 func (_m *PoolManager) GetWebhookInfo(ctx context.Context) (params.HookInfo, error) {
 	ret := _m.Called(ctx)
@@ -152,6 +228,150 @@ func (_m *PoolManager) InstallWebhook(ctx context.Context, param params.InstallW
 	return r0, r1
 }
 
+// InstanceLocks provides a mock function with given fields:
+func (_m *PoolManager) InstanceLocks() []params.InstanceLock {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for InstanceLocks")
+	}
+
+	var r0 []params.InstanceLock
+	if rf, ok := ret.Get(0).(func() []params.InstanceLock); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.InstanceLock)
+		}
+	}
+
+	return r0
+}
+
+// ListForgeRunners provides a mock function with given fields: ctx
+func (_m *PoolManager) ListForgeRunners(ctx context.Context) ([]params.ForgeRunner, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListForgeRunners")
+	}
+
+	var r0 []params.ForgeRunner
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.ForgeRunner, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.ForgeRunner); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.ForgeRunner)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRunnerGroups provides a mock function with given fields: ctx
+func (_m *PoolManager) ListRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRunnerGroups")
+	}
+
+	var r0 []params.RunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.RunnerGroup, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.RunnerGroup); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.RunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MatchPools provides a mock function with given fields: ctx, labels
+func (_m *PoolManager) MatchPools(ctx context.Context, labels []string) (params.PoolMatchResult, error) {
+	ret := _m.Called(ctx, labels)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MatchPools")
+	}
+
+	var r0 params.PoolMatchResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (params.PoolMatchResult, error)); ok {
+		return rf(ctx, labels)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) params.PoolMatchResult); ok {
+		r0 = rf(ctx, labels)
+	} else {
+		r0 = ret.Get(0).(params.PoolMatchResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, labels)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PreviousWebhookSecret provides a mock function with given fields:
+func (_m *PoolManager) PreviousWebhookSecret() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for PreviousWebhookSecret")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// ReleaseInstanceLock provides a mock function with given fields: instanceName
+func (_m *PoolManager) ReleaseInstanceLock(instanceName string) bool {
+	ret := _m.Called(instanceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseInstanceLock")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(instanceName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // RootCABundle provides a mock function with given fields:
 func (_m *PoolManager) RootCABundle() (params.CertificateBundle, error) {
 	ret := _m.Called()
@@ -234,6 +454,66 @@ func (_m *PoolManager) Stop() error {
 	return r0
 }
 
+// SyncQueuedJobs provides a mock function with given fields: ctx
+func (_m *PoolManager) SyncQueuedJobs(ctx context.Context) ([]params.Job, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SyncQueuedJobs")
+	}
+
+	var r0 []params.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]params.Job, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []params.Job); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Tools provides a mock function with given fields:
+func (_m *PoolManager) Tools() ([]garm_provider_commonparams.RunnerApplicationDownload, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Tools")
+	}
+
+	var r0 []garm_provider_commonparams.RunnerApplicationDownload
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]garm_provider_commonparams.RunnerApplicationDownload, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []garm_provider_commonparams.RunnerApplicationDownload); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]garm_provider_commonparams.RunnerApplicationDownload)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UninstallWebhook provides a mock function with given fields: ctx
 func (_m *PoolManager) UninstallWebhook(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -288,6 +568,26 @@ func (_m *PoolManager) WebhookSecret() string {
 	return r0
 }
 
+// WorkerHealth provides a mock function with given fields:
+func (_m *PoolManager) WorkerHealth() []params.WorkerHealth {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for WorkerHealth")
+	}
+
+	var r0 []params.WorkerHealth
+	if rf, ok := ret.Get(0).(func() []params.WorkerHealth); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]params.WorkerHealth)
+		}
+	}
+
+	return r0
+}
+
 // NewPoolManager creates a new instance of PoolManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewPoolManager(t interface {
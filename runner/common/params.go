@@ -52,6 +52,14 @@ type StartParams struct {
 	StartV011 StartV011Params
 }
 
+type CreateSnapshotParams struct {
+	CreateSnapshotV011 CreateSnapshotV011Params
+}
+
+type ValidatePoolParams struct {
+	ValidatePoolV011 ValidatePoolV011Params
+}
+
 // Struct for the base provider parameters.
 type ProviderBaseParams struct {
 	PoolInfo       params.Pool
@@ -86,3 +94,16 @@ type StopV011Params struct {
 type StartV011Params struct {
 	ProviderBaseParams
 }
+
+type CreateSnapshotV011Params struct {
+	ProviderBaseParams
+	// SnapshotName is the name of the image that will be created.
+	SnapshotName string
+	// PrepareScript is the script that is run on the instance before the snapshot
+	// is taken.
+	PrepareScript string
+}
+
+type ValidatePoolV011Params struct {
+	ProviderBaseParams
+}
@@ -18,7 +18,9 @@ import (
 	"context"
 	"time"
 
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
 )
 
 const (
@@ -30,12 +32,92 @@ const (
 	// we spin up. We cache the tools for 5 minutes. This should save us a lot of API calls
 	// in cases where we have a lot of runners spin up at the same time.
 	PoolToolUpdateInterval = 5 * time.Minute
+	// PoolWebhookDeliveryStatsInterval is the interval at which we fetch the delivery
+	// stats of the webhook managed by GARM.
+	PoolWebhookDeliveryStatsInterval = 5 * time.Minute
+	// PoolAutoscaleInterval is the interval at which the queue depth autoscaler
+	// checks if pools need to be scaled up.
+	PoolAutoscaleInterval = 10 * time.Second
+	// PoolRollingUpdateInterval is the interval at which we check if pools have outdated
+	// idle runners that need to be replaced.
+	PoolRollingUpdateInterval = 10 * time.Second
+	// AutoscaleQueueWindow is the sliding window used by the queue depth autoscaler.
+	// Jobs queued within this window count towards the queue depth of the pools that
+	// match them. Jobs that stay queued for longer get a runner of their own, like jobs
+	// that don't match any autoscaled pool.
+	AutoscaleQueueWindow = 5 * time.Minute
+	// PoolWebhookInstallRetryInterval is the interval at which we check if a failed webhook
+	// install needs to be retried. This is also the initial backoff between retries.
+	PoolWebhookInstallRetryInterval = 1 * time.Minute
+	// PoolJITRegistrationCleanupInterval is the interval at which we remove the GitHub
+	// runner registrations of instances that failed to be created.
+	PoolJITRegistrationCleanupInterval = 1 * time.Minute
+	// WebhookInstallRetryMaxBackoff is the maximum time we wait between two attempts to
+	// install a webhook.
+	WebhookInstallRetryMaxBackoff = 30 * time.Minute
+	// PoolRateLimitForecastInterval is the interval at which we check if the rate limit
+	// of the entity credentials is about to be exhausted.
+	PoolRateLimitForecastInterval = 1 * time.Minute
+	// RateLimitWarningThreshold is how close the forecasted exhaustion of the rate limit
+	// needs to be, for a warning event to be recorded. Exhaustion is only a concern if it
+	// happens before GitHub resets the quota.
+	RateLimitWarningThreshold = 15 * time.Minute
+	// PoolProviderHealthCheckInterval is the interval at which we check the health of
+	// the providers used by the pools of an entity.
+	PoolProviderHealthCheckInterval = 1 * time.Minute
+	// PoolWarmImageInterval is the interval at which we check if the warm image of
+	// a pool needs to be rebuilt.
+	PoolWarmImageInterval = 1 * time.Minute
+	// ProviderUnhealthyThreshold is the number of consecutive failed health checks
+	// after which a provider is considered unhealthy.
+	ProviderUnhealthyThreshold = 3
+
+	// MaxStartupJobReconciliations is the maximum number of queued jobs each pool manager
+	// checks against the GitHub API when it starts.
+	MaxStartupJobReconciliations = 100
+
+	// MaxEntityEvents is the number of events we keep for each entity.
+	MaxEntityEvents = 100
+	// MaxInstanceEvents is the number of status messages we keep for each instance.
+	// Long lived runners, like the ones in reusable pools, would otherwise accumulate
+	// messages for as long as they exist.
+	MaxInstanceEvents = 200
+
+	// WatchdogInterval is the interval at which the pool manager checks the heartbeat
+	// of its worker loops.
+	WatchdogInterval = 1 * time.Minute
+	// on top of its own interval, before it is reported as stale.
+	// on top of its own interval, before it is considered wedged and is restarted.
+	WorkerHeartbeatGracePeriod = 10 * time.Minute
+
+	// InstanceLockStaleAfter is the amount of time after which a lock held on an
+	// instance is reported as stale.
+	InstanceLockStaleAfter = 10 * time.Minute
+
+	// CompletedJobsRetention is the default amount of time completed jobs are kept in
+	// the database. Completed jobs are used to report on how runners are used.
+	CompletedJobsRetention = appdefaults.DefaultCompletedJobsRetention
 
 	// BackoffTimer is the time we wait before attempting to make another request
 	// to the github API.
 	BackoffTimer = 1 * time.Minute
 )
 
+// LeaderElector tells a pool manager if this controller manages its entity. When several
+// controllers share a database, only the leader of an entity runs the workers that
+// create and remove runners for it.
+type LeaderElector interface {
+	// IsLeader returns true if this controller manages the entity with the given ID.
+	IsLeader(entityID string) bool
+}
+
+// BootstrapTransformer lets operators change the bootstrap params of runners before they are
+// sent to the provider.
+type BootstrapTransformer interface {
+	// Transform returns the bootstrap params to send to the provider for a runner of the entity.
+	Transform(ctx context.Context, entity params.GithubEntity, bootstrapArgs commonParams.BootstrapInstance) (commonParams.BootstrapInstance, error)
+}
+
 //go:generate mockery --all
 type PoolManager interface {
 	// ID returns the ID of the entity (repo, org, enterprise)
@@ -45,6 +127,13 @@ type PoolManager interface {
 	// GARM will have to create a webhook in GitHub which points to the GARM API server. To authenticate
 	// the webhook, a webhook secret is used. This function returns that secret.
 	WebhookSecret() string
+	// PreviousWebhookSecret returns the webhook secret that was replaced by the current one, while
+	// the rotation grace period lasts. Webhooks signed with either secret are accepted. It returns an
+	// empty string if no rotation is in progress.
+	PreviousWebhookSecret() string
+	// EntityWebhookPath returns true if the webhook of the entity was installed using the URL unique
+	// to the entity. Webhooks for such entities are not accepted on the shared controller webhook URL.
+	EntityWebhookPath() bool
 	// GithubRunnerRegistrationToken returns a new registration token for a github runner. This is used
 	// for GHES installations that have not yet upgraded to a version >= 3.10. Starting with 3.10, we use
 	// just-in-time runners, which no longer require exposing a runner registration token.
@@ -68,12 +157,39 @@ type PoolManager interface {
 	// UninstallWebhook will remove the webhook installed in github for the entity associated with this pool manager.
 	UninstallWebhook(ctx context.Context) error
 
+	// ListForgeRunners returns the runners registered in github for the entity associated with this pool
+	// manager, correlated with the instances GARM has recorded for that entity.
+	ListForgeRunners(ctx context.Context) ([]params.ForgeRunner, error)
+
+	// ListRunnerGroups returns the runner groups of the organization or enterprise associated
+	// with this pool manager. Repositories don't have runner groups.
+	ListRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error)
+	// GetRunnerGroup returns the runner group with the given name. A not found error is
+	// returned if the group does not exist.
+	GetRunnerGroup(ctx context.Context, name string) (params.RunnerGroup, error)
+	// CreateRunnerGroup creates a runner group in the organization or enterprise associated
+	// with this pool manager.
+	CreateRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error)
+
+	// MatchPools returns the pools of the entity associated with this pool manager that would be
+	// tried, in order, to create a runner for a queued job with the given labels.
+	MatchPools(ctx context.Context, labels []string) (params.PoolMatchResult, error)
+
+	// SyncQueuedJobs records the jobs that are queued in github for the entity associated with this
+	// pool manager, and that GARM did not receive a webhook for. It returns the jobs it recorded.
+	SyncQueuedJobs(ctx context.Context) ([]params.Job, error)
+
 	// RootCABundle will return a CA bundle that must be installed on all runners in order to properly validate
 	// x509 certificates used by various systems involved. This CA bundle is defined in the GARM config file and
 	// can include multiple CA certificates for the GARM api server, GHES server and any provider API endpoint that
 	// may use internal or self signed certificates.
 	RootCABundle() (params.CertificateBundle, error)
 
+	// Tools returns the runner tools that were last fetched for the entity associated with this
+	// pool manager. Depending on the github endpoint settings, tools are fetched either from the
+	// GitHub API or from the tools metadata URL of the endpoint.
+	Tools() ([]commonParams.RunnerApplicationDownload, error)
+
 	// Start will start the pool manager and all associated workers.
 	Start() error
 	// Stop will stop the pool manager and all associated workers.
@@ -82,4 +198,11 @@ type PoolManager interface {
 	Status() params.PoolManagerStatus
 	// Wait will block until the pool manager has stopped.
 	Wait() error
+	// WorkerHealth returns the liveness information of the worker loops of the pool manager.
+	WorkerHealth() []params.WorkerHealth
+	// InstanceLocks returns the locks currently held by the pool manager on instances.
+	InstanceLocks() []params.InstanceLock
+	// ReleaseInstanceLock releases the lock held on an instance, regardless of who holds it.
+	// It returns false if the instance is not locked.
+	ReleaseInstanceLock(instanceName string) bool
 }
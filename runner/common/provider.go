@@ -44,3 +44,53 @@ type Provider interface {
 
 	AsParams() params.Provider
 }
+
+// DiagnosticsProvider is an optional interface that providers can implement, if they
+// are able to collect diagnostic information about an instance, like the serial console
+// log or a detailed status from the IaaS.
+type DiagnosticsProvider interface {
+	// SupportsDiagnostics returns true if the provider is able to collect diagnostics.
+	SupportsDiagnostics() bool
+	// GetInstanceDiagnostics returns diagnostic information about an instance.
+	GetInstanceDiagnostics(ctx context.Context, instance string, getInstanceParams GetInstanceParams) (string, error)
+}
+
+// InstanceSweepProvider is an optional interface that providers can implement, if they
+// are able to list all the instances created by this controller, regardless of the pool
+// they belong to.
+type InstanceSweepProvider interface {
+	// SupportsInstanceSweep returns true if the provider is able to list all instances
+	// created by this controller.
+	SupportsInstanceSweep() bool
+	// ListControllerInstances lists all instances tagged with the ID of this controller.
+	ListControllerInstances(ctx context.Context) ([]commonParams.ProviderInstance, error)
+}
+
+// SnapshotProvider is an optional interface that providers can implement, if they are
+// able to run a script on an instance and to snapshot it into a new image. It is used
+// to build the warm images of pools.
+type SnapshotProvider interface {
+	// SupportsSnapshot returns true if the provider is able to snapshot instances.
+	SupportsSnapshot() bool
+	// CreateInstanceSnapshot runs the preparation script on the instance, snapshots it
+	// and returns the provider specific identifier of the new image.
+	CreateInstanceSnapshot(ctx context.Context, instance string, createSnapshotParams CreateSnapshotParams) (string, error)
+}
+
+// PoolValidationProvider is an optional interface that providers can implement, if they
+// are able to check that the image and flavor of a pool exist, before any runner is
+// created.
+type PoolValidationProvider interface {
+	// SupportsPoolValidation returns true if the provider is able to validate pools.
+	SupportsPoolValidation() bool
+	// ValidatePool returns an error if the provider can not create runners for the pool.
+	ValidatePool(ctx context.Context, validatePoolParams ValidatePoolParams) error
+}
+
+// VersionProvider is an optional interface that providers can implement, if they are
+// able to report the version of the provider and the interface versions it supports.
+type VersionProvider interface {
+	// GetVersionInfo returns the version information of the provider. Errors are
+	// reported in the Error field of the result.
+	GetVersionInfo(ctx context.Context) params.ProviderVersionInfo
+}
@@ -14,11 +14,16 @@ type GithubEntityOperations interface {
 	CreateEntityHook(ctx context.Context, hook *github.Hook) (ret *github.Hook, err error)
 	DeleteEntityHook(ctx context.Context, id int64) (ret *github.Response, err error)
 	PingEntityHook(ctx context.Context, id int64) (ret *github.Response, err error)
+	ListEntityHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) (ret []*github.HookDelivery, response *github.Response, err error)
 	ListEntityRunners(ctx context.Context, opts *github.ListOptions) (*github.Runners, *github.Response, error)
 	ListEntityRunnerApplicationDownloads(ctx context.Context) ([]*github.RunnerApplicationDownload, *github.Response, error)
 	RemoveEntityRunner(ctx context.Context, runnerID int64) (*github.Response, error)
 	CreateEntityRegistrationToken(ctx context.Context) (*github.RegistrationToken, *github.Response, error)
 	GetEntityJITConfig(ctx context.Context, instance string, pool params.Pool, labels []string) (jitConfigMap map[string]string, runner *github.Runner, err error)
+	GetEntityActionsPermissions(ctx context.Context) (ret *github.ActionsPermissionsRepository, response *github.Response, err error)
+	ListEntityRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error)
+	GetEntityRunnerGroupByName(ctx context.Context, name string) (params.RunnerGroup, error)
+	CreateEntityRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error)
 }
 
 // GithubClient that describes the minimum list of functions we need to interact with github.
@@ -30,4 +35,18 @@ type GithubClient interface {
 
 	// GetWorkflowJobByID gets details about a single workflow job.
 	GetWorkflowJobByID(ctx context.Context, owner, repo string, jobID int64) (*github.WorkflowJob, *github.Response, error)
+	// GetActionsPermissions gets the GitHub Actions permissions policy for an organization.
+	GetActionsPermissions(ctx context.Context, org string) (*github.ActionsPermissions, *github.Response, error)
+	// ListEnabledReposInOrg lists the repositories in an organization that are allowed to run
+	// GitHub Actions, when the organization policy is set to "selected".
+	ListEnabledReposInOrg(ctx context.Context, owner string, opts *github.ListOptions) (*github.ActionsEnabledOnOrgRepos, *github.Response, error)
+	// ListOrganizationRunnerGroups lists the runner groups of an organization.
+	ListOrganizationRunnerGroups(ctx context.Context, org string, opts *github.ListOrgRunnerGroupOptions) (*github.RunnerGroups, *github.Response, error)
+	// ListRepositoryAccessRunnerGroup lists the repositories that can use a runner group
+	// of an organization, when the visibility of the group is set to "selected".
+	ListRepositoryAccessRunnerGroup(ctx context.Context, org string, groupID int64, opts *github.ListOptions) (*github.ListRepositories, *github.Response, error)
+	// ListRepositoryWorkflowRuns lists the workflow runs of a repository.
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	// ListWorkflowJobs lists the jobs of a workflow run.
+	ListWorkflowJobs(ctx context.Context, owner, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error)
 }
@@ -0,0 +1,692 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+// configPlan holds the changes needed to bring the controller in line with a config,
+// along with the functions that make them.
+type configPlan struct {
+	credentials map[string]params.GithubCredentials
+
+	changes   []params.ConfigChange
+	steps     []func(ctx context.Context) error
+	deletions []params.ConfigChange
+	deletes   []func(ctx context.Context) error
+}
+
+// add records a change. The step may be nil if the change is made by another step.
+func (p *configPlan) add(change params.ConfigChange, step func(ctx context.Context) error) {
+	p.changes = append(p.changes, change)
+	if step != nil {
+		p.steps = append(p.steps, step)
+	}
+}
+
+// addDeletion records a removal. Removals are made after all other changes.
+func (p *configPlan) addDeletion(change params.ConfigChange, step func(ctx context.Context) error) {
+	p.deletions = append(p.deletions, change)
+	p.deletes = append(p.deletes, step)
+}
+
+func (p *configPlan) allChanges() []params.ConfigChange {
+	return append(append([]params.ConfigChange{}, p.changes...), p.deletions...)
+}
+
+func (p *configPlan) apply(ctx context.Context) error {
+	for _, step := range append(append([]func(ctx context.Context) error{}, p.steps...), p.deletes...) {
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportControllerConfig returns the configuration of all the entities of the controller,
+// their pools and the credentials they use. Webhook secrets are replaced with a
+// placeholder, and the secrets of the credentials are left out.
+func (r *Runner) ExportControllerConfig(ctx context.Context) (params.ControllerConfig, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ControllerConfig{}, runnerErrors.ErrUnauthorized
+	}
+
+	creds, err := r.store.ListGithubCredentials(ctx)
+	if err != nil {
+		return params.ControllerConfig{}, errors.Wrap(err, "fetching credentials")
+	}
+	entities, err := r.listConfigEntities(ctx)
+	if err != nil {
+		return params.ControllerConfig{}, err
+	}
+
+	cfg := params.ControllerConfig{
+		Version:     params.ControllerConfigVersion,
+		Credentials: make([]params.CredentialsConfig, len(creds)),
+		Entities:    make([]params.EntityConfig, len(entities)),
+	}
+	for idx, cred := range creds {
+		cfg.Credentials[idx] = params.CredentialsConfig{
+			Name:        cred.Name,
+			Description: cred.Description,
+			Endpoint:    cred.Endpoint.Name,
+			AuthType:    cred.AuthType,
+		}
+	}
+	for idx, entity := range entities {
+		cfg.Entities[idx], err = r.ExportEntityConfig(ctx, entity.EntityType, entity.ID)
+		if err != nil {
+			return params.ControllerConfig{}, errors.Wrapf(err, "exporting %s %s", entity.EntityType, entity.ID)
+		}
+	}
+	return cfg, nil
+}
+
+// ApplyControllerConfig compares a controller config with the entities and pools of the
+// controller, and makes the changes needed for them to match the config. Entities are
+// matched by their name and endpoint, and pools by the ID set when the config was
+// exported or, if the ID is left out, by their provider and tags. Pools that match no pool of the
+// entity are created. Entities and pools that are not in the config are only removed if prune is set.
+// Credentials are never created or removed, as the config does not hold their secrets.
+//
+// All the changes are planned and validated before any of them is made. If a change
+// fails, the changes made so far are kept, and applying the config again resumes from
+// where it stopped.
+func (r *Runner) ApplyControllerConfig(ctx context.Context, cfg params.ControllerConfig, param params.ApplyControllerConfigParams) (params.ControllerConfigApplyResult, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ControllerConfigApplyResult{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return params.ControllerConfigApplyResult{}, errors.Wrap(err, "validating config")
+	}
+
+	plan, err := r.planControllerConfig(ctx, cfg, param.Prune)
+	if err != nil {
+		return params.ControllerConfigApplyResult{}, err
+	}
+
+	result := params.ControllerConfigApplyResult{
+		DryRun:  param.DryRun,
+		Changes: plan.allChanges(),
+	}
+	if param.DryRun {
+		return result, nil
+	}
+	if err := plan.apply(ctx); err != nil {
+		return params.ControllerConfigApplyResult{}, errors.Wrap(err, "applying config")
+	}
+	return result, nil
+}
+
+func (r *Runner) planControllerConfig(ctx context.Context, cfg params.ControllerConfig, prune bool) (*configPlan, error) {
+	creds, err := r.store.ListGithubCredentials(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching credentials")
+	}
+	plan := &configPlan{
+		credentials: make(map[string]params.GithubCredentials, len(creds)),
+	}
+	for _, cred := range creds {
+		plan.credentials[cred.Name] = cred
+	}
+	for _, desired := range cfg.Credentials {
+		if err := r.planCredentials(desired, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	entities, err := r.listConfigEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]params.GithubEntity, len(entities))
+	for _, entity := range entities {
+		existing[configEntityKey(entity.EntityType, entity.Credentials.Endpoint.Name, entityResourceName(entity))] = entity
+	}
+
+	wanted := map[string]struct{}{}
+	for _, desired := range cfg.Entities {
+		if desired.Version == 0 {
+			desired.Version = params.EntityConfigVersion
+		}
+		endpoint, err := configEntityEndpoint(desired, plan)
+		if err != nil {
+			return nil, err
+		}
+		key := configEntityKey(desired.EntityType, endpoint, desired.ResourceName())
+		wanted[key] = struct{}{}
+
+		entity, ok := existing[key]
+		if !ok {
+			err = r.planNewEntity(ctx, desired, plan)
+		} else {
+			err = r.planEntityUpdate(ctx, entity, desired, prune, plan)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "planning %s %s", desired.EntityType, desired.ResourceName())
+		}
+	}
+
+	if prune {
+		for _, entity := range entities {
+			if _, ok := wanted[configEntityKey(entity.EntityType, entity.Credentials.Endpoint.Name, entityResourceName(entity))]; ok {
+				continue
+			}
+			if err := r.planEntityRemoval(ctx, entity, plan); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return plan, nil
+}
+
+func (r *Runner) planCredentials(desired params.CredentialsConfig, plan *configPlan) error {
+	current, ok := plan.credentials[desired.Name]
+	if !ok {
+		return runnerErrors.NewBadRequestError("credentials %s do not exist and must be created before the config is applied", desired.Name)
+	}
+	if desired.Endpoint != "" && desired.Endpoint != current.Endpoint.Name {
+		return runnerErrors.NewBadRequestError("the endpoint of credentials %s cannot be changed", desired.Name)
+	}
+	if desired.AuthType != "" && desired.AuthType != current.AuthType {
+		return runnerErrors.NewBadRequestError("the auth type of credentials %s cannot be changed", desired.Name)
+	}
+	if desired.Description == current.Description {
+		return nil
+	}
+
+	description := desired.Description
+	plan.add(params.ConfigChange{
+		Action:       params.ConfigChangeActionUpdate,
+		ResourceType: params.ConfigResourceCredentials,
+		Name:         desired.Name,
+		ID:           fmt.Sprintf("%d", current.ID),
+		Fields:       []string{"description"},
+	}, func(ctx context.Context) error {
+		_, err := r.UpdateGithubCredentials(ctx, current.ID, params.UpdateGithubCredentialsParams{
+			Description: &description,
+		})
+		return err
+	})
+	return nil
+}
+
+func (r *Runner) planNewEntity(ctx context.Context, desired params.EntityConfig, plan *configPlan) error {
+	if err := desired.ValidateNewEntity(); err != nil {
+		return err
+	}
+	if _, ok := plan.credentials[desired.CredentialsName]; !ok {
+		return runnerErrors.NewBadRequestError("credentials %s do not exist", desired.CredentialsName)
+	}
+	if err := r.validateRoutingRules(desired.RoutingRules); err != nil {
+		return errors.Wrap(err, "validating routing rules")
+	}
+	if err := r.validateConfigPools(ctx, desired.Pools); err != nil {
+		return err
+	}
+
+	plan.add(params.ConfigChange{
+		Action:       params.ConfigChangeActionCreate,
+		ResourceType: configResourceOfEntity(desired.EntityType),
+		Name:         desired.ResourceName(),
+	}, func(ctx context.Context) error {
+		_, err := r.ImportEntity(ctx, desired)
+		return err
+	})
+	// The pools are created along with the entity.
+	for idx := range desired.Pools {
+		plan.add(params.ConfigChange{
+			Action:       params.ConfigChangeActionCreate,
+			ResourceType: params.ConfigResourcePool,
+			Name:         configPoolName(desired.ResourceName(), idx),
+		}, nil)
+	}
+	return nil
+}
+
+func (r *Runner) planEntityUpdate(ctx context.Context, entity params.GithubEntity, desired params.EntityConfig, prune bool, plan *configPlan) error {
+	current, err := r.ExportEntityConfig(ctx, entity.EntityType, entity.ID)
+	if err != nil {
+		return err
+	}
+
+	update, fields, err := r.entityConfigUpdate(entity, current, desired, plan)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		plan.add(params.ConfigChange{
+			Action:       params.ConfigChangeActionUpdate,
+			ResourceType: configResourceOfEntity(entity.EntityType),
+			Name:         desired.ResourceName(),
+			ID:           entity.ID,
+			Fields:       fields,
+		}, func(ctx context.Context) error {
+			return r.updateEntity(ctx, entity, update)
+		})
+	}
+
+	if err := r.validateConfigPools(ctx, desired.Pools); err != nil {
+		return err
+	}
+	currentPools := make(map[string]params.CreatePoolParams, len(current.Pools))
+	for _, pool := range current.Pools {
+		currentPools[pool.ID] = pool.CreatePoolParams
+	}
+	poolIDs := matchConfigPools(current.Pools, desired.Pools)
+
+	kept := map[string]struct{}{}
+	for idx, pool := range desired.Pools {
+		name := configPoolName(desired.ResourceName(), idx)
+		pool.ID = poolIDs[idx]
+		currentPool, ok := currentPools[pool.ID]
+		if !ok {
+			createParams := pool.CreatePoolParams
+			plan.add(params.ConfigChange{
+				Action:       params.ConfigChangeActionCreate,
+				ResourceType: params.ConfigResourcePool,
+				Name:         name,
+			}, func(ctx context.Context) error {
+				_, err := r.createEntityPool(ctx, entity, createParams)
+				return err
+			})
+			continue
+		}
+		kept[pool.ID] = struct{}{}
+
+		poolFields, err := changedPoolFields(currentPool, pool.CreatePoolParams)
+		if err != nil {
+			return errors.Wrapf(err, "comparing pool %s", pool.ID)
+		}
+		if len(poolFields) == 0 {
+			continue
+		}
+		if slices.Contains(poolFields, "provider_name") {
+			return runnerErrors.NewBadRequestError("the provider of pool %s cannot be changed", pool.ID)
+		}
+		poolID := pool.ID
+		updateParams := poolConfigToUpdateParams(pool.CreatePoolParams)
+		plan.add(params.ConfigChange{
+			Action:       params.ConfigChangeActionUpdate,
+			ResourceType: params.ConfigResourcePool,
+			Name:         name,
+			ID:           poolID,
+			Fields:       poolFields,
+		}, func(ctx context.Context) error {
+			_, err := r.updateEntityPool(ctx, entity, poolID, updateParams)
+			return err
+		})
+	}
+
+	if !prune {
+		return nil
+	}
+	for _, pool := range current.Pools {
+		if _, ok := kept[pool.ID]; ok {
+			continue
+		}
+		r.planPoolRemoval(entity, desired.ResourceName(), pool.ID, plan)
+	}
+	return nil
+}
+
+// entityConfigUpdate returns the parameters needed to update the settings of an entity
+// to match the config, along with the names of the settings that change.
+func (r *Runner) entityConfigUpdate(entity params.GithubEntity, current, desired params.EntityConfig, plan *configPlan) (params.UpdateEntityParams, []string, error) {
+	var fields []string
+	update := params.UpdateEntityParams{
+		PoolBalancerType: desired.PoolBalancerType,
+	}
+	if update.PoolBalancerType == "" {
+		update.PoolBalancerType = params.PoolBalancerTypeRoundRobin
+	}
+	if update.PoolBalancerType != entity.GetPoolBalancerType() {
+		fields = append(fields, "pool_balancer_type")
+	}
+
+	if desired.CredentialsName != current.CredentialsName {
+		if _, ok := plan.credentials[desired.CredentialsName]; !ok {
+			return params.UpdateEntityParams{}, nil, runnerErrors.NewBadRequestError("credentials %s do not exist", desired.CredentialsName)
+		}
+		update.CredentialsName = desired.CredentialsName
+		fields = append(fields, "credentials_name")
+	}
+
+	// The placeholder keeps the current secret.
+	if desired.WebhookSecret != "" && desired.WebhookSecret != params.EntityConfigSecretPlaceholder && desired.WebhookSecret != entity.WebhookSecret {
+		update.WebhookSecret = desired.WebhookSecret
+		fields = append(fields, "webhook_secret")
+	}
+
+	if desired.MaxConcurrentJobs != current.MaxConcurrentJobs {
+		maxConcurrentJobs := desired.MaxConcurrentJobs
+		update.MaxConcurrentJobs = &maxConcurrentJobs
+		fields = append(fields, "max_concurrent_jobs")
+	}
+
+	rulesChanged, err := routingRulesChanged(current.RoutingRules, desired.RoutingRules)
+	if err != nil {
+		return params.UpdateEntityParams{}, nil, errors.Wrap(err, "comparing routing rules")
+	}
+	if rulesChanged {
+		if err := r.validateRoutingRules(desired.RoutingRules); err != nil {
+			return params.UpdateEntityParams{}, nil, errors.Wrap(err, "validating routing rules")
+		}
+		rules := append([]params.RoutingRule{}, desired.RoutingRules...)
+		update.RoutingRules = &rules
+		fields = append(fields, "routing_rules")
+	}
+	return update, fields, nil
+}
+
+func (r *Runner) planEntityRemoval(ctx context.Context, entity params.GithubEntity, plan *configPlan) error {
+	pools, err := r.store.ListEntityPools(ctx, entity)
+	if err != nil {
+		return errors.Wrap(err, "fetching pools")
+	}
+	name := entityResourceName(entity)
+	for _, pool := range pools {
+		r.planPoolRemoval(entity, name, pool.ID, plan)
+	}
+	plan.addDeletion(params.ConfigChange{
+		Action:       params.ConfigChangeActionDelete,
+		ResourceType: configResourceOfEntity(entity.EntityType),
+		Name:         name,
+		ID:           entity.ID,
+	}, func(ctx context.Context) error {
+		return r.deleteEntity(ctx, entity.EntityType, entity.ID)
+	})
+	return nil
+}
+
+func (r *Runner) planPoolRemoval(entity params.GithubEntity, entityName, poolID string, plan *configPlan) {
+	plan.addDeletion(params.ConfigChange{
+		Action:       params.ConfigChangeActionDelete,
+		ResourceType: params.ConfigResourcePool,
+		Name:         entityName,
+		ID:           poolID,
+	}, func(ctx context.Context) error {
+		return r.deleteEntityPool(ctx, entity, poolID)
+	})
+}
+
+// listConfigEntities returns all the repositories, organizations and enterprises of
+// the controller.
+func (r *Runner) listConfigEntities(ctx context.Context) ([]params.GithubEntity, error) {
+	var entities []params.GithubEntity
+
+	repos, err := r.store.ListRepositories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching repositories")
+	}
+	for _, repo := range repos {
+		entity, err := repo.GetEntity()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting entity")
+		}
+		entities = append(entities, entity)
+	}
+
+	orgs, err := r.store.ListOrganizations(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching organizations")
+	}
+	for _, org := range orgs {
+		entity, err := org.GetEntity()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting entity")
+		}
+		entities = append(entities, entity)
+	}
+
+	enterprises, err := r.store.ListEnterprises(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching enterprises")
+	}
+	for _, enterprise := range enterprises {
+		entity, err := enterprise.GetEntity()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting entity")
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// matchConfigPools returns the IDs of the current pools that match the pools in the
+// config, by position. Pools are matched by ID. Pools without an ID are matched with a
+// pool that has the same provider and tags, which keeps configs written by hand from
+// creating new pools each time they are applied. Pools with no match get an empty ID.
+func matchConfigPools(current, desired []params.EntityConfigPool) []string {
+	ids := make([]string, len(desired))
+	claimed := map[string]struct{}{}
+	for idx, pool := range desired {
+		for _, currentPool := range current {
+			if pool.ID != "" && pool.ID == currentPool.ID {
+				ids[idx] = currentPool.ID
+				claimed[currentPool.ID] = struct{}{}
+			}
+		}
+	}
+	for idx, pool := range desired {
+		if ids[idx] != "" || pool.ID != "" {
+			continue
+		}
+		for _, currentPool := range current {
+			if _, ok := claimed[currentPool.ID]; ok {
+				continue
+			}
+			if currentPool.ProviderName == pool.ProviderName && sameTags(currentPool.Tags, pool.Tags) {
+				ids[idx] = currentPool.ID
+				claimed[currentPool.ID] = struct{}{}
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func sameTags(a, b []string) bool {
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
+
+// changedPoolFields returns the names of the settings of a pool that differ between
+// the current and the desired config. Settings left out of the config are compared
+// with the defaults GARM sets when a pool is created.
+func changedPoolFields(current, desired params.CreatePoolParams) ([]string, error) {
+	currentFields, err := poolConfigFields(current)
+	if err != nil {
+		return nil, err
+	}
+	desiredFields, err := poolConfigFields(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for name, value := range desiredFields {
+		if !reflect.DeepEqual(value, currentFields[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range currentFields {
+		if _, ok := desiredFields[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func poolConfigFields(pool params.CreatePoolParams) (map[string]interface{}, error) {
+	pool.Prefix = pool.GetRunnerPrefix()
+	if pool.RunnerBootstrapTimeout == 0 {
+		pool.RunnerBootstrapTimeout = appdefaults.DefaultRunnerBootstrapTimeout
+	}
+	pool.Tags = append([]string{}, pool.Tags...)
+	sort.Strings(pool.Tags)
+	if spec := strings.TrimSpace(string(pool.ExtraSpecs)); spec == "{}" || spec == "null" {
+		pool.ExtraSpecs = nil
+	}
+
+	asJSON, err := json.Marshal(pool)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(asJSON, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// poolConfigToUpdateParams returns the update parameters that set all the settings of
+// a pool to the values in its config. Settings left out of the config are removed.
+func poolConfigToUpdateParams(pool params.CreatePoolParams) params.UpdatePoolParams {
+	extraSpecs := pool.ExtraSpecs
+	if len(extraSpecs) == 0 {
+		extraSpecs = json.RawMessage("{}")
+	}
+	cleanupPolicy := pool.CleanupPolicy
+	if cleanupPolicy == nil {
+		cleanupPolicy = &params.CleanupPolicy{}
+	}
+	networkSettings := pool.NetworkSettings
+	if networkSettings == nil {
+		networkSettings = &params.NetworkSettings{}
+	}
+	schedule := pool.Schedule
+	if schedule == nil {
+		schedule = &params.PoolSchedule{}
+	}
+	instanceMetadata := pool.InstanceMetadata
+	if instanceMetadata == nil {
+		instanceMetadata = params.InstanceMetadata{}
+	}
+	warmImage := pool.WarmImage
+	if warmImage == nil {
+		warmImage = &params.WarmImageSettings{}
+	}
+
+	return params.UpdatePoolParams{
+		RunnerPrefix:             pool.RunnerPrefix,
+		Tags:                     pool.Tags,
+		Enabled:                  &pool.Enabled,
+		MaxRunners:               &pool.MaxRunners,
+		MinIdleRunners:           &pool.MinIdleRunners,
+		RunnerBootstrapTimeout:   &pool.RunnerBootstrapTimeout,
+		Image:                    pool.Image,
+		Flavor:                   pool.Flavor,
+		OSType:                   pool.OSType,
+		OSArch:                   pool.OSArch,
+		ExtraSpecs:               extraSpecs,
+		GitHubRunnerGroup:        &pool.GitHubRunnerGroup,
+		Priority:                 &pool.Priority,
+		CleanupPolicy:            cleanupPolicy,
+		NetworkSettings:          networkSettings,
+		Schedule:                 schedule,
+		IdleDetectionWindow:      &pool.IdleDetectionWindow,
+		ScaleDownGracePeriod:     &pool.ScaleDownGracePeriod,
+		ScaleDownFactor:          &pool.ScaleDownFactor,
+		CapacityWarningThreshold: &pool.CapacityWarningThreshold,
+		AutoscaleMaxBurst:        &pool.AutoscaleMaxBurst,
+		AutoscaleCooldown:        &pool.AutoscaleCooldown,
+		MaxCreatesPerMinute:      &pool.MaxCreatesPerMinute,
+		CreateJitter:             &pool.CreateJitter,
+		RollingUpdateBatchSize:   &pool.RollingUpdateBatchSize,
+		RollingUpdatePause:       &pool.RollingUpdatePause,
+		URLSet:                   &pool.URLSet,
+		InstanceMetadata:         &instanceMetadata,
+		WarmImage:                warmImage,
+	}
+}
+
+func routingRulesChanged(current, desired []params.RoutingRule) (bool, error) {
+	if len(current) == 0 && len(desired) == 0 {
+		return false, nil
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	desiredJSON, err := json.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+	return string(currentJSON) != string(desiredJSON), nil
+}
+
+// configEntityKey identifies an entity in a config. Entities with the same name on
+// different endpoints are different entities.
+func configEntityKey(entityType params.GithubEntityType, endpoint, name string) string {
+	return strings.ToLower(string(entityType) + "/" + endpoint + "/" + name)
+}
+
+// configEntityEndpoint returns the endpoint of an entity in the config. Entities that
+// leave it out use the endpoint of their credentials.
+func configEntityEndpoint(desired params.EntityConfig, plan *configPlan) (string, error) {
+	creds, ok := plan.credentials[desired.CredentialsName]
+	if !ok {
+		// Missing credentials are reported when the entity is planned.
+		return desired.Endpoint, nil
+	}
+	if desired.Endpoint != "" && desired.Endpoint != creds.Endpoint.Name {
+		return "", runnerErrors.NewBadRequestError(
+			"the endpoint of %s %s does not match the endpoint of credentials %s",
+			desired.EntityType, desired.ResourceName(), creds.Name)
+	}
+	return creds.Endpoint.Name, nil
+}
+
+func entityResourceName(entity params.GithubEntity) string {
+	if entity.EntityType == params.GithubEntityTypeRepository {
+		return entity.Owner + "/" + entity.Name
+	}
+	return entity.Owner
+}
+
+func configPoolName(entityName string, idx int) string {
+	return fmt.Sprintf("%s, pool %d", entityName, idx+1)
+}
+
+func configResourceOfEntity(entityType params.GithubEntityType) params.ConfigResourceType {
+	switch entityType {
+	case params.GithubEntityTypeOrganization:
+		return params.ConfigResourceOrganization
+	case params.GithubEntityTypeEnterprise:
+		return params.ConfigResourceEnterprise
+	default:
+		return params.ConfigResourceRepository
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	runnerCommonMocks "github.com/cloudbase/garm/runner/common/mocks"
+	runnerMocks "github.com/cloudbase/garm/runner/mocks"
+)
+
+func TestExportApplyControllerConfig(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+	_, err = db.InitController()
+	require.Nil(t, err)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	org, err := db.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+
+	entity := params.GithubEntity{ID: org.ID, EntityType: params.GithubEntityTypeOrganization}
+	poolParams := params.CreatePoolParams{
+		ProviderName:           "test-provider",
+		MaxRunners:             4,
+		Image:                  "test-image",
+		Flavor:                 "test-flavor",
+		OSType:                 "linux",
+		OSArch:                 "amd64",
+		Tags:                   []string{"linux", "gpu"},
+		Enabled:                true,
+		RunnerBootstrapTimeout: 20,
+		ExtraSpecs:             []byte(`{"disk_size": 100}`),
+	}
+	kept, err := db.CreateEntityPool(adminCtx, entity, poolParams)
+	require.Nil(t, err)
+	poolParams.Tags = []string{"linux", "arm"}
+	removed, err := db.CreateEntityPool(adminCtx, entity, poolParams)
+	require.Nil(t, err)
+
+	provider := runnerCommonMocks.NewProvider(t)
+	provider.On("AsParams").Return(params.Provider{Name: "test-provider"}).Maybe()
+	poolMgr := runnerCommonMocks.NewPoolManager(t)
+	poolMgr.On("Status").Return(params.PoolManagerStatus{IsRunning: true}).Maybe()
+	poolMgrCtrl := runnerMocks.NewPoolManagerController(t)
+	poolMgrCtrl.On("GetOrgPoolManager", mock.AnythingOfType("params.Organization")).Return(poolMgr, nil).Maybe()
+	r := &Runner{
+		ctx:             adminCtx,
+		store:           db,
+		poolManagerCtrl: poolMgrCtrl,
+		providers: map[string]common.Provider{
+			"test-provider": provider,
+		},
+	}
+
+	_, err = r.ExportControllerConfig(context.Background())
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+
+	cfg, err := r.ExportControllerConfig(adminCtx)
+	require.Nil(t, err)
+	require.Equal(t, params.ControllerConfigVersion, cfg.Version)
+	require.Equal(t, []params.CredentialsConfig{{
+		Name:        creds.Name,
+		Description: creds.Description,
+		Endpoint:    endpoint.Name,
+		AuthType:    creds.AuthType,
+	}}, cfg.Credentials)
+	require.Len(t, cfg.Entities, 1)
+	require.Equal(t, "test-org", cfg.Entities[0].Name)
+	require.Equal(t, params.EntityConfigSecretPlaceholder, cfg.Entities[0].WebhookSecret)
+	require.Len(t, cfg.Entities[0].Pools, 2)
+
+	asYAML, err := yaml.Marshal(cfg)
+	require.Nil(t, err)
+	require.Contains(t, string(asYAML), "id: "+kept.ID)
+	var parsed params.ControllerConfig
+	require.Nil(t, yaml.Unmarshal(asYAML, &parsed))
+
+	// Applying an unchanged config is a no-op.
+	result, err := r.ApplyControllerConfig(adminCtx, parsed, params.ApplyControllerConfigParams{Prune: true})
+	require.Nil(t, err)
+	require.Empty(t, result.Changes)
+
+	orgCfg := &parsed.Entities[0]
+	orgCfg.MaxConcurrentJobs = 3
+	var keptPool params.EntityConfigPool
+	for _, pool := range orgCfg.Pools {
+		if pool.ID == kept.ID {
+			keptPool = pool
+		}
+	}
+	keptPool.MaxRunners = 8
+	newPool := keptPool
+	newPool.ID = ""
+	newPool.Tags = []string{"linux", "large"}
+	orgCfg.Pools = []params.EntityConfigPool{keptPool, newPool}
+
+	expected := []params.ConfigChange{
+		{
+			Action:       params.ConfigChangeActionUpdate,
+			ResourceType: params.ConfigResourceOrganization,
+			Name:         "test-org",
+			ID:           org.ID,
+			Fields:       []string{"max_concurrent_jobs"},
+		},
+		{
+			Action:       params.ConfigChangeActionUpdate,
+			ResourceType: params.ConfigResourcePool,
+			Name:         "test-org, pool 1",
+			ID:           kept.ID,
+			Fields:       []string{"max_runners"},
+		},
+		{
+			Action:       params.ConfigChangeActionCreate,
+			ResourceType: params.ConfigResourcePool,
+			Name:         "test-org, pool 2",
+		},
+		{
+			Action:       params.ConfigChangeActionDelete,
+			ResourceType: params.ConfigResourcePool,
+			Name:         "test-org",
+			ID:           removed.ID,
+		},
+	}
+
+	result, err = r.ApplyControllerConfig(adminCtx, parsed, params.ApplyControllerConfigParams{Prune: true, DryRun: true})
+	require.Nil(t, err)
+	require.True(t, result.DryRun)
+	require.Equal(t, expected, result.Changes)
+	pools, err := db.ListEntityPools(adminCtx, entity)
+	require.Nil(t, err)
+	require.Len(t, pools, 2)
+
+	// New entities need a webhook secret, which is not part of exported configs.
+	withNewRepo := parsed
+	withNewRepo.Entities = append([]params.EntityConfig{}, parsed.Entities...)
+	withNewRepo.Entities = append(withNewRepo.Entities, params.EntityConfig{
+		EntityType:      params.GithubEntityTypeRepository,
+		Owner:           "test-owner",
+		Name:            "test-repo",
+		CredentialsName: creds.Name,
+		WebhookSecret:   params.EntityConfigSecretPlaceholder,
+	})
+	_, err = r.ApplyControllerConfig(adminCtx, withNewRepo, params.ApplyControllerConfigParams{})
+	var missingSecret *runnerErrors.MissingSecretError
+	require.ErrorAs(t, err, &missingSecret)
+
+	// Credentials can't be created from a config.
+	withNewCreds := parsed
+	withNewCreds.Credentials = append(withNewCreds.Credentials, params.CredentialsConfig{Name: "missing-creds"})
+	_, err = r.ApplyControllerConfig(adminCtx, withNewCreds, params.ApplyControllerConfigParams{})
+	var badRequest *runnerErrors.BadRequestError
+	require.ErrorAs(t, err, &badRequest)
+
+	result, err = r.ApplyControllerConfig(adminCtx, parsed, params.ApplyControllerConfigParams{Prune: true})
+	require.Nil(t, err)
+	require.Equal(t, expected, result.Changes)
+
+	updatedOrg, err := db.GetOrganizationByID(adminCtx, org.ID)
+	require.Nil(t, err)
+	require.Equal(t, uint(3), updatedOrg.MaxConcurrentJobs)
+	pools, err = db.ListEntityPools(adminCtx, entity)
+	require.Nil(t, err)
+	require.Len(t, pools, 2)
+	for _, pool := range pools {
+		require.NotEqual(t, removed.ID, pool.ID)
+		require.Equal(t, uint(8), pool.MaxRunners)
+	}
+
+	// Pools without an ID are matched by provider and tags, so applying the config
+	// again is a no-op.
+	result, err = r.ApplyControllerConfig(adminCtx, parsed, params.ApplyControllerConfigParams{Prune: true})
+	require.Nil(t, err)
+	require.Empty(t, result.Changes)
+}
+
+func TestApplyControllerConfigMatchesEntitiesByEndpoint(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+	_, err = db.InitController()
+	require.Nil(t, err)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	ghes, err := db.CreateGithubEndpoint(adminCtx, params.CreateGithubEndpointParams{
+		Name:          "ghes",
+		APIBaseURL:    "https://ghes.example.com/api/v3/",
+		UploadBaseURL: "https://ghes.example.com/uploads/",
+		BaseURL:       "https://ghes.example.com",
+	})
+	require.Nil(t, err)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	ghesCreds := garmTesting.CreateTestGithubCredentials(adminCtx, "ghes-creds", db, t, ghes)
+	org, err := db.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+
+	r := &Runner{ctx: adminCtx, store: db}
+
+	cfg, err := r.ExportControllerConfig(adminCtx)
+	require.Nil(t, err)
+	require.Len(t, cfg.Entities, 1)
+	require.Equal(t, endpoint.Name, cfg.Entities[0].Endpoint)
+
+	// An organization with the same name on another endpoint is a different organization.
+	ghesOrg := cfg.Entities[0]
+	ghesOrg.CredentialsName = ghesCreds.Name
+	ghesOrg.Endpoint = ""
+	ghesOrg.WebhookSecret = "ghes-webhookSecret"
+	cfg.Entities = []params.EntityConfig{ghesOrg}
+
+	result, err := r.ApplyControllerConfig(adminCtx, cfg, params.ApplyControllerConfigParams{Prune: true, DryRun: true})
+	require.Nil(t, err)
+	require.Equal(t, []params.ConfigChange{
+		{
+			Action:       params.ConfigChangeActionCreate,
+			ResourceType: params.ConfigResourceOrganization,
+			Name:         "test-org",
+		},
+		{
+			Action:       params.ConfigChangeActionDelete,
+			ResourceType: params.ConfigResourceOrganization,
+			Name:         "test-org",
+			ID:           org.ID,
+		},
+	}, result.Changes)
+
+	// The endpoint must match the endpoint of the credentials.
+	ghesOrg.Endpoint = endpoint.Name
+	cfg.Entities = []params.EntityConfig{ghesOrg}
+	_, err = r.ApplyControllerConfig(adminCtx, cfg, params.ApplyControllerConfigParams{DryRun: true})
+	var badRequest *runnerErrors.BadRequestError
+	require.ErrorAs(t, err, &badRequest)
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util"
+)
+
+// controllerIDMigrationInterval is the interval at which a running controller ID
+// migration is advanced.
+const controllerIDMigrationInterval = 30 * time.Second
+
+// StartControllerIDMigration starts changing the ID of the controller. The migration
+// runs in the background and can be followed with GetControllerIDMigration.
+func (r *Runner) StartControllerIDMigration(ctx context.Context, param params.StartControllerIDMigrationParams) (params.ControllerIDMigration, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ControllerIDMigration{}, runnerErrors.ErrUnauthorized
+	}
+
+	newID := uuid.New()
+	if param.NewControllerID != nil {
+		newID = *param.NewControllerID
+	}
+	if newID == uuid.Nil {
+		return params.ControllerIDMigration{}, runnerErrors.NewBadRequestError("invalid controller ID")
+	}
+
+	migration, err := r.store.CreateControllerIDMigration(ctx, newID)
+	if err != nil {
+		return params.ControllerIDMigration{}, errors.Wrap(err, "creating controller ID migration")
+	}
+
+	go func() {
+		if err := r.advanceControllerIDMigration(r.ctx); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to advance controller ID migration")
+		}
+	}()
+	return migration, nil
+}
+
+// GetControllerIDMigration returns the most recent controller ID migration.
+func (r *Runner) GetControllerIDMigration(ctx context.Context) (params.ControllerIDMigration, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ControllerIDMigration{}, runnerErrors.ErrUnauthorized
+	}
+
+	migration, err := r.store.GetControllerIDMigration(ctx)
+	if err != nil {
+		return params.ControllerIDMigration{}, errors.Wrap(err, "fetching controller ID migration")
+	}
+	return migration, nil
+}
+
+// runControllerIDMigrations periodically advances the controller ID migration, if
+// one is running. A migration that was interrupted by a restart resumes from the
+// phase it was in.
+func (r *Runner) runControllerIDMigrations() {
+	ticker := time.NewTicker(controllerIDMigrationInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.advanceControllerIDMigration(r.ctx); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to advance controller ID migration")
+		}
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// advanceControllerIDMigration runs the phases of the current migration until it
+// completes, or until a phase needs to wait for GitHub or for runners to go away.
+func (r *Runner) advanceControllerIDMigration(ctx context.Context) error {
+	r.controllerIDMigrationMux.Lock()
+	defer r.controllerIDMigrationMux.Unlock()
+
+	migration, err := r.store.GetControllerIDMigration(ctx)
+	if err != nil {
+		if errors.Is(err, runnerErrors.ErrNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "fetching controller ID migration")
+	}
+
+	for migration.Phase != params.MigrationPhaseCompleted {
+		var update params.UpdateControllerIDMigrationParams
+		var done bool
+		switch migration.Phase {
+		case params.MigrationPhaseUpdateController:
+			update, done, err = r.switchControllerID(ctx, migration)
+		case params.MigrationPhaseReinstallWebhooks:
+			update, done, err = r.reinstallMigrationWebhooks(ctx, migration)
+		case params.MigrationPhaseDrainRunners:
+			update, done, err = r.drainMigrationRunners(ctx, migration)
+		default:
+			return fmt.Errorf("unknown controller ID migration phase %q", migration.Phase)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "running phase %s", migration.Phase)
+		}
+
+		migration, err = r.store.UpdateControllerIDMigration(ctx, migration.ID, update)
+		if err != nil {
+			return errors.Wrap(err, "updating controller ID migration")
+		}
+		if !done {
+			return nil
+		}
+		slog.InfoContext(ctx, "controller ID migration advanced", "migration_id", migration.ID, "phase", migration.Phase)
+	}
+	return nil
+}
+
+func migrationPhase(phase params.MigrationPhase) *params.MigrationPhase {
+	return &phase
+}
+
+// switchControllerID saves the new controller ID. The previous ID is kept until the
+// migration completes, so runners and webhooks using it are still handled.
+func (r *Runner) switchControllerID(ctx context.Context, migration params.ControllerIDMigration) (params.UpdateControllerIDMigrationParams, bool, error) {
+	if _, err := r.store.SetControllerID(ctx, migration.NewControllerID, &migration.PreviousControllerID); err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "setting controller ID")
+	}
+	now := time.Now().UTC()
+	return params.UpdateControllerIDMigrationParams{
+		Phase:      migrationPhase(params.MigrationPhaseReinstallWebhooks),
+		SwitchedAt: &now,
+	}, true, nil
+}
+
+// migrationEntities returns the repositories and organizations whose webhooks may be
+// managed by GARM. Webhooks are not managed for enterprises.
+func (r *Runner) migrationEntities(ctx context.Context) ([]params.GithubEntity, error) {
+	var entities []params.GithubEntity
+	repos, err := r.store.ListRepositories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing repositories")
+	}
+	for _, repo := range repos {
+		entity, err := repo.GetEntity()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting entity")
+		}
+		entities = append(entities, entity)
+	}
+
+	orgs, err := r.store.ListOrganizations(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing organizations")
+	}
+	for _, org := range orgs {
+		entity, err := org.GetEntity()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting entity")
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// reinstallMigrationWebhooks moves the webhooks GARM installed under the previous
+// controller webhook URL to the new one. Entities that were already handled are
+// skipped, and entities that failed are retried on the next run.
+func (r *Runner) reinstallMigrationWebhooks(ctx context.Context, migration params.ControllerIDMigration) (params.UpdateControllerIDMigrationParams, bool, error) {
+	info, err := r.store.ControllerInfo()
+	if err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "fetching controller info")
+	}
+	if info.WebhookURL == "" {
+		// Webhooks can't be managed by GARM without a webhook URL.
+		return params.UpdateControllerIDMigrationParams{
+			Phase:    migrationPhase(params.MigrationPhaseDrainRunners),
+			Webhooks: []params.MigrationWebhookStatus{},
+		}, true, nil
+	}
+	previousURL, err := url.JoinPath(info.WebhookURL, migration.PreviousControllerID.String())
+	if err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "joining webhook URL")
+	}
+
+	handled := map[string]params.MigrationWebhookStatus{}
+	for _, status := range migration.Webhooks {
+		if status.Error == "" {
+			handled[status.EntityID] = status
+		}
+	}
+
+	entities, err := r.migrationEntities(ctx)
+	if err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "listing entities")
+	}
+
+	done := true
+	statuses := make([]params.MigrationWebhookStatus, 0, len(entities))
+	for _, entity := range entities {
+		if status, ok := handled[entity.ID]; ok {
+			statuses = append(statuses, status)
+			continue
+		}
+		status := params.MigrationWebhookStatus{
+			EntityID:   entity.ID,
+			EntityType: entity.EntityType,
+			Name:       entity.String(),
+		}
+		ghCli, err := util.GithubClient(ctx, entity, entity.Credentials)
+		if err == nil {
+			status.Reinstalled, err = moveControllerWebhooks(ctx, ghCli, entity, previousURL, info.ControllerWebhookURL)
+		}
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				ctx, "failed to move webhook to the new controller URL", "entity", entity.String())
+			status.Error = err.Error()
+			done = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	update := params.UpdateControllerIDMigrationParams{
+		Webhooks: statuses,
+	}
+	if done {
+		update.Phase = migrationPhase(params.MigrationPhaseDrainRunners)
+	}
+	return update, done, nil
+}
+
+// moveControllerWebhooks replaces the webhooks of an entity that point to the previous
+// controller webhook URL, with webhooks pointing to the new URL. Both the controller
+// URL and the per-entity URL are moved. The returned bool is false if the entity had
+// no webhook managed by GARM.
+func moveControllerWebhooks(ctx context.Context, ghCli common.GithubEntityOperations, entity params.GithubEntity, previousURL, newURL string) (bool, error) {
+	opts := github.ListOptions{
+		PerPage: 100,
+	}
+	var allHooks []*github.Hook
+	for {
+		hooks, ghResp, err := ghCli.ListEntityHooks(ctx, &opts)
+		if err != nil {
+			return false, errors.Wrap(err, "listing hooks")
+		}
+		allHooks = append(allHooks, hooks...)
+		if ghResp == nil || ghResp.NextPage == 0 {
+			break
+		}
+		opts.Page = ghResp.NextPage
+	}
+
+	trimmedPrevious := strings.TrimRight(previousURL, "/")
+	trimmedNew := strings.TrimRight(newURL, "/")
+	existing := map[string]struct{}{}
+	for _, hook := range allHooks {
+		existing[strings.TrimRight(hookURL(hook), "/")] = struct{}{}
+	}
+
+	var moved bool
+	for _, hook := range allHooks {
+		var target string
+		switch strings.TrimRight(hookURL(hook), "/") {
+		case trimmedPrevious:
+			target = trimmedNew
+		case fmt.Sprintf("%s/%s", trimmedPrevious, entity.ID):
+			target = fmt.Sprintf("%s/%s", trimmedNew, entity.ID)
+		default:
+			continue
+		}
+
+		// The new webhook may already exist if an earlier attempt failed to remove
+		// the old one.
+		if _, ok := existing[target]; !ok {
+			insecureSSL, _ := hook.Config["insecure_ssl"].(string)
+			if insecureSSL == "" {
+				insecureSSL = "0"
+			}
+			req := &github.Hook{
+				Active: github.Bool(true),
+				Config: map[string]interface{}{
+					"url":          target,
+					"content_type": "json",
+					"insecure_ssl": insecureSSL,
+					"secret":       entity.WebhookSecret,
+				},
+				Events: hook.Events,
+			}
+			if _, err := ghCli.CreateEntityHook(ctx, req); err != nil {
+				return false, errors.Wrapf(err, "creating hook for %s", target)
+			}
+			existing[target] = struct{}{}
+		}
+
+		if _, err := ghCli.DeleteEntityHook(ctx, hook.GetID()); err != nil {
+			return false, errors.Wrapf(err, "deleting hook %d", hook.GetID())
+		}
+		moved = true
+	}
+	return moved, nil
+}
+
+func hookURL(hook *github.Hook) string {
+	if hook == nil || hook.Config == nil {
+		return ""
+	}
+	hookURL, _ := hook.Config["url"].(string)
+	return hookURL
+}
+
+// drainMigrationRunners removes the idle runners created before the controller ID
+// was switched. Busy runners are left to finish their jobs. Once no such runner is
+// left, the previous controller ID is dropped and the migration completes.
+func (r *Runner) drainMigrationRunners(ctx context.Context, migration params.ControllerIDMigration) (params.UpdateControllerIDMigrationParams, bool, error) {
+	if migration.SwitchedAt == nil {
+		return params.UpdateControllerIDMigrationParams{}, false, fmt.Errorf("controller ID migration has no switch time")
+	}
+
+	instances, err := r.store.ListAllInstances(ctx)
+	if err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "listing instances")
+	}
+
+	var pending uint
+	adminCtx := auth.GetAdminContext(ctx)
+	for _, instance := range instances {
+		if !instance.CreatedAt.Before(*migration.SwitchedAt) {
+			continue
+		}
+		pending++
+		if instance.Status != commonParams.InstanceRunning || instance.RunnerStatus != params.RunnerIdle {
+			continue
+		}
+		if err := r.DeleteRunner(adminCtx, instance.Name, false, false); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				ctx, "failed to remove runner labeled with the previous controller ID", "runner_name", instance.Name)
+		}
+	}
+
+	if pending > 0 {
+		return params.UpdateControllerIDMigrationParams{
+			PendingRunners: &pending,
+		}, false, nil
+	}
+
+	if _, err := r.store.SetControllerID(ctx, migration.NewControllerID, nil); err != nil {
+		return params.UpdateControllerIDMigrationParams{}, false, errors.Wrap(err, "clearing previous controller ID")
+	}
+	now := time.Now().UTC()
+	return params.UpdateControllerIDMigrationParams{
+		Phase:          migrationPhase(params.MigrationPhaseCompleted),
+		PendingRunners: &pending,
+		CompletedAt:    &now,
+	}, true, nil
+}
+
+// IsControllerWebhookID returns true if webhooks sent to a URL that includes the
+// given controller ID are meant for this controller. While the controller ID is
+// being migrated, the previous ID is accepted as well.
+func (r *Runner) IsControllerWebhookID(ctx context.Context, controllerID string) bool {
+	info, err := r.store.ControllerInfo()
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to fetch controller info")
+		return false
+	}
+	if controllerID == info.ControllerID.String() {
+		return true
+	}
+	return info.PreviousControllerID != nil && controllerID == info.PreviousControllerID.String()
+}
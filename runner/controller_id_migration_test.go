@@ -0,0 +1,162 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func newControllerIDMigrationRunner(t *testing.T) (*Runner, context.Context) {
+	db, err := database.NewDatabase(context.Background(), garmTesting.GetTestSqliteDBConfig(t))
+	require.NoError(t, err)
+	adminCtx := garmTesting.ImpersonateAdminContext(context.Background(), db, t)
+	_, err = db.InitController()
+	require.NoError(t, err)
+
+	return &Runner{ctx: adminCtx, store: db}, adminCtx
+}
+
+func TestControllerIDMigration(t *testing.T) {
+	r, adminCtx := newControllerIDMigrationRunner(t)
+	info, err := r.store.ControllerInfo()
+	require.NoError(t, err)
+	previousID := info.ControllerID
+	newID := uuid.New()
+
+	_, err = r.StartControllerIDMigration(context.Background(), params.StartControllerIDMigrationParams{NewControllerID: &newID})
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+
+	_, err = r.store.CreateControllerIDMigration(adminCtx, previousID)
+	var badRequest *runnerErrors.BadRequestError
+	require.True(t, errors.As(err, &badRequest), "expected bad request error, got %v", err)
+
+	migration, err := r.store.CreateControllerIDMigration(adminCtx, newID)
+	require.NoError(t, err)
+	require.Equal(t, params.MigrationPhaseUpdateController, migration.Phase)
+	require.Equal(t, previousID, migration.PreviousControllerID)
+
+	// Only one migration may run at a time.
+	_, err = r.store.CreateControllerIDMigration(adminCtx, uuid.New())
+	var conflict *runnerErrors.ConflictError
+	require.True(t, errors.As(err, &conflict), "expected conflict error, got %v", err)
+
+	require.NoError(t, r.advanceControllerIDMigration(adminCtx))
+
+	migration, err = r.GetControllerIDMigration(adminCtx)
+	require.NoError(t, err)
+	require.Equal(t, params.MigrationPhaseCompleted, migration.Phase)
+	require.NotNil(t, migration.SwitchedAt)
+	require.NotNil(t, migration.CompletedAt)
+
+	info, err = r.store.ControllerInfo()
+	require.NoError(t, err)
+	require.Equal(t, newID, info.ControllerID)
+	require.Nil(t, info.PreviousControllerID)
+	require.True(t, r.IsControllerWebhookID(adminCtx, newID.String()))
+	require.False(t, r.IsControllerWebhookID(adminCtx, previousID.String()))
+}
+
+func TestIsControllerWebhookIDDuringMigration(t *testing.T) {
+	r, adminCtx := newControllerIDMigrationRunner(t)
+	info, err := r.store.ControllerInfo()
+	require.NoError(t, err)
+	previousID := info.ControllerID
+	newID := uuid.New()
+
+	_, err = r.store.SetControllerID(adminCtx, newID, &previousID)
+	require.NoError(t, err)
+
+	require.True(t, r.IsControllerWebhookID(adminCtx, newID.String()))
+	require.True(t, r.IsControllerWebhookID(adminCtx, previousID.String()))
+	require.False(t, r.IsControllerWebhookID(adminCtx, uuid.New().String()))
+}
+
+func TestMoveControllerWebhooks(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:            "entity-id",
+		EntityType:    params.GithubEntityTypeRepository,
+		Owner:         "test-owner",
+		Name:          "test-repo",
+		WebhookSecret: "secret",
+	}
+	hooks := []*github.Hook{
+		{
+			ID:     github.Int64(1),
+			Events: []string{"workflow_job"},
+			Config: map[string]interface{}{"url": "https://garm.example.com/webhooks/old-id", "insecure_ssl": "1"},
+		},
+		{
+			ID:     github.Int64(2),
+			Events: []string{"workflow_job"},
+			Config: map[string]interface{}{"url": "https://garm.example.com/webhooks/old-id/entity-id"},
+		},
+		{
+			ID:     github.Int64(3),
+			Events: []string{"push"},
+			Config: map[string]interface{}{"url": "https://ci.example.com/hook"},
+		},
+	}
+
+	ghCli := mocks.NewGithubEntityOperations(t)
+	ghCli.On("ListEntityHooks", mock.Anything, mock.Anything).Return(hooks, &github.Response{}, nil)
+	ghCli.On("CreateEntityHook", mock.Anything, mock.MatchedBy(func(hook *github.Hook) bool {
+		return hook.Config["url"] == "https://garm.example.com/webhooks/new-id" &&
+			hook.Config["insecure_ssl"] == "1" && hook.Config["secret"] == "secret"
+	})).Return(&github.Hook{}, nil).Once()
+	ghCli.On("CreateEntityHook", mock.Anything, mock.MatchedBy(func(hook *github.Hook) bool {
+		return hook.Config["url"] == "https://garm.example.com/webhooks/new-id/entity-id" &&
+			hook.Config["insecure_ssl"] == "0"
+	})).Return(&github.Hook{}, nil).Once()
+	ghCli.On("DeleteEntityHook", mock.Anything, int64(1)).Return(&github.Response{}, nil).Once()
+	ghCli.On("DeleteEntityHook", mock.Anything, int64(2)).Return(&github.Response{}, nil).Once()
+
+	moved, err := moveControllerWebhooks(
+		context.Background(), ghCli, entity,
+		"https://garm.example.com/webhooks/old-id", "https://garm.example.com/webhooks/new-id")
+	require.NoError(t, err)
+	require.True(t, moved)
+}
+
+func TestMoveControllerWebhooksResumes(t *testing.T) {
+	entity := params.GithubEntity{ID: "entity-id"}
+	// The new webhook was created by an earlier attempt, which failed to remove the old one.
+	hooks := []*github.Hook{
+		{ID: github.Int64(1), Config: map[string]interface{}{"url": "https://garm.example.com/webhooks/old-id/"}},
+		{ID: github.Int64(2), Config: map[string]interface{}{"url": "https://garm.example.com/webhooks/new-id"}},
+	}
+
+	ghCli := mocks.NewGithubEntityOperations(t)
+	ghCli.On("ListEntityHooks", mock.Anything, mock.Anything).Return(hooks, &github.Response{}, nil)
+	ghCli.On("DeleteEntityHook", mock.Anything, int64(1)).Return(&github.Response{}, nil).Once()
+
+	moved, err := moveControllerWebhooks(
+		context.Background(), ghCli, entity,
+		"https://garm.example.com/webhooks/old-id", "https://garm.example.com/webhooks/new-id")
+	require.NoError(t, err)
+	require.True(t, moved)
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// ListControllerNodes returns the controllers that take part in the cluster, and the
+// entities each of them manages. The list is empty when clustering is disabled.
+func (r *Runner) ListControllerNodes(ctx context.Context) ([]params.ControllerNode, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	nodes, err := r.store.ListControllerNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching controller nodes")
+	}
+	return nodes, nil
+}
+
+// leaseIDs returns the IDs the coordinator takes leases on. Besides the entities, the
+// controller ID is leased by one of the nodes, which runs the tasks that must run on
+// a single node of the cluster, like archiving completed jobs.
+func (p *poolManagerCtrl) leaseIDs() []string {
+	ids := p.entityIDs()
+	info, err := p.store.ControllerInfo()
+	if err != nil {
+		slog.With(slog.Any("error", err)).Error("failed to fetch controller info")
+		return ids
+	}
+	return append(ids, info.ControllerID.String())
+}
+
+// runsClusterTasks returns true if this controller runs the tasks that must run on a
+// single node of the cluster. Controllers that are not part of a cluster always run them.
+func (r *Runner) runsClusterTasks() (bool, error) {
+	if r.coordinator == nil {
+		return true, nil
+	}
+	info, err := r.store.ControllerInfo()
+	if err != nil {
+		return false, errors.Wrap(err, "fetching controller info")
+	}
+	return r.coordinator.IsLeader(info.ControllerID.String()), nil
+}
+
+// entityIDs returns the IDs of the entities that have a pool manager.
+func (p *poolManagerCtrl) entityIDs() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	ret := make([]string, 0, len(p.repositories)+len(p.organizations)+len(p.enterprises))
+	for id := range p.repositories {
+		ret = append(ret, id)
+	}
+	for id := range p.organizations {
+		ret = append(ret, id)
+	}
+	for id := range p.enterprises {
+		ret = append(ret, id)
+	}
+	return ret
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+// Package coordination splits the entities managed by GARM between the controllers
+// of a cluster.
+//
+// Each controller registers itself as a node in the database and sends a heartbeat
+// every HeartbeatInterval. Every entity (repository, organization or enterprise) is
+// assigned to one of the nodes that are alive, using rendezvous hashing. The assigned
+// node takes a lease on the entity in the database, and renews it on every heartbeat.
+// Only the node that holds the lease on an entity is its leader, and runs the pool
+// manager workers of that entity.
+//
+// When a node joins or leaves the cluster, the entities are assigned again. A node
+// releases the leases on the entities it is no longer assigned, and the new node takes
+// them over. If a node dies, its leases expire after LeaseDuration and are taken over.
+//
+// Lease expiration relies on the clocks of the controllers being in sync.
+package coordination
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	dbCommon "github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+const (
+	// HeartbeatInterval is the interval at which a node refreshes its heartbeat and
+	// its leases.
+	HeartbeatInterval = 10 * time.Second
+	// NodeTimeout is the time after which a node that stopped sending heartbeats is
+	// no longer assigned entities.
+	NodeTimeout = 30 * time.Second
+	// LeaseDuration is the time a lease on an entity is valid for, if not renewed.
+	LeaseDuration = 30 * time.Second
+	// StaleNodeRetention is the time after which a node that stopped sending heartbeats
+	// is removed from the database.
+	StaleNodeRetention = 1 * time.Hour
+)
+
+var _ common.LeaderElector = &Coordinator{}
+
+// NewCoordinator returns a new coordinator for this controller. The entities function
+// returns the IDs of the entities that this controller has pool managers for. If name
+// is empty, the hostname is used.
+func NewCoordinator(ctx context.Context, store dbCommon.Store, name string, entities func() []string) (*Coordinator, error) {
+	if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "fetching hostname")
+		}
+		name = hostname
+	}
+
+	nodeID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, errors.Wrap(err, "generating node id")
+	}
+
+	return &Coordinator{
+		ctx:      ctx,
+		store:    store,
+		nodeID:   nodeID.String(),
+		name:     name,
+		entities: entities,
+		leases:   map[string]time.Time{},
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// Coordinator takes part in the election of the leaders of the entities, on behalf
+// of this controller.
+type Coordinator struct {
+	ctx      context.Context
+	store    dbCommon.Store
+	nodeID   string
+	name     string
+	entities func() []string
+
+	mux sync.Mutex
+	// leases holds the entities this node holds a lease on, and when the lease
+	// expires. The expiration is computed before the lease is acquired, so the
+	// local copy never outlives the one in the database.
+	leases map[string]time.Time
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NodeID returns the ID this controller registered with.
+func (c *Coordinator) NodeID() string {
+	return c.nodeID
+}
+
+// IsLeader returns true if this controller holds an unexpired lease on the entity.
+func (c *Coordinator) IsLeader(entityID string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	expiresAt, ok := c.leases[entityID]
+	return ok && time.Now().UTC().Before(expiresAt)
+}
+
+// Start registers this controller as a node, takes the leases on the entities
+// assigned to it, and keeps doing so in the background until stopped.
+func (c *Coordinator) Start() error {
+	if err := c.reconcile(); err != nil {
+		return errors.Wrap(err, "registering controller node")
+	}
+
+	c.wg.Add(1)
+	go c.loop()
+	return nil
+}
+
+// Stop removes this controller from the cluster. Its leases are released, so that
+// the other nodes can take over its entities right away. Stopping a coordinator
+// that was already stopped does nothing.
+func (c *Coordinator) Stop() error {
+	c.mux.Lock()
+	select {
+	case <-c.quit:
+		c.mux.Unlock()
+		return nil
+	default:
+	}
+	close(c.quit)
+	c.leases = map[string]time.Time{}
+	c.mux.Unlock()
+
+	c.wg.Wait()
+
+	if err := c.store.DeleteControllerNode(c.ctx, c.nodeID); err != nil {
+		return errors.Wrap(err, "removing controller node")
+	}
+	return nil
+}
+
+func (c *Coordinator) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.reconcile(); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(c.ctx, "failed to reconcile entity leases")
+			}
+		case <-c.ctx.Done():
+			return
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// reconcile refreshes the heartbeat of this node, takes or renews the leases on the
+// entities assigned to it, and releases the leases on the other entities.
+func (c *Coordinator) reconcile() error {
+	now := time.Now().UTC()
+	if _, err := c.store.UpdateControllerNodeHeartbeat(c.ctx, c.nodeID, c.name); err != nil {
+		return errors.Wrap(err, "updating heartbeat")
+	}
+
+	if err := c.store.DeleteStaleControllerNodes(c.ctx, now.Add(-StaleNodeRetention)); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(c.ctx, "failed to remove stale controller nodes")
+	}
+
+	nodes, err := c.store.ListControllerNodes(c.ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching controller nodes")
+	}
+	alive := []string{c.nodeID}
+	for _, node := range nodes {
+		if node.ID != c.nodeID && node.LastHeartbeat.After(now.Add(-NodeTimeout)) {
+			alive = append(alive, node.ID)
+		}
+	}
+
+	assigned := map[string]struct{}{}
+	for _, entityID := range c.entities() {
+		if assignedNode(alive, entityID) != c.nodeID {
+			continue
+		}
+		assigned[entityID] = struct{}{}
+
+		expiresAt := time.Now().UTC().Add(LeaseDuration)
+		acquired, err := c.store.AcquireEntityLease(c.ctx, entityID, c.nodeID, expiresAt)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(c.ctx, "failed to acquire entity lease", "entity_id", entityID)
+			continue
+		}
+
+		c.mux.Lock()
+		if acquired {
+			c.leases[entityID] = expiresAt
+		} else {
+			// The previous leader has not released the lease yet.
+			delete(c.leases, entityID)
+		}
+		c.mux.Unlock()
+	}
+
+	c.mux.Lock()
+	var released []string
+	for entityID := range c.leases {
+		if _, ok := assigned[entityID]; !ok {
+			released = append(released, entityID)
+			delete(c.leases, entityID)
+		}
+	}
+	c.mux.Unlock()
+
+	for _, entityID := range released {
+		slog.InfoContext(c.ctx, "releasing entity lease", "entity_id", entityID)
+		if err := c.store.ReleaseEntityLease(c.ctx, entityID, c.nodeID); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(c.ctx, "failed to release entity lease", "entity_id", entityID)
+		}
+	}
+	return nil
+}
+
+// assignedNode returns the node an entity is assigned to. All nodes get the same
+// result for the same list of nodes, and when a node joins or leaves, only the
+// entities assigned to that node move.
+func assignedNode(nodes []string, entityID string) string {
+	var ret string
+	var best uint64
+	for _, node := range nodes {
+		sum := sha256.Sum256([]byte(node + "/" + entityID))
+		score := binary.BigEndian.Uint64(sum[:8])
+		if ret == "" || score > best || (score == best && node < ret) {
+			ret = node
+			best = score
+		}
+	}
+	return ret
+}
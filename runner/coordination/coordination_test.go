@@ -0,0 +1,109 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package coordination
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/database"
+	"github.com/cloudbase/garm/database/watcher"
+	garmTesting "github.com/cloudbase/garm/internal/testing" //nolint:typecheck
+)
+
+func init() {
+	watcher.SetWatcher(&garmTesting.MockWatcher{})
+}
+
+func TestCoordinatorsSplitEntities(t *testing.T) {
+	ctx := context.Background()
+	store, err := database.NewDatabase(ctx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+
+	entities := make([]string, 20)
+	for idx := range entities {
+		entities[idx] = uuid.New().String()
+	}
+	listEntities := func() []string { return entities }
+
+	first, err := NewCoordinator(ctx, store, "first", listEntities)
+	require.Nil(t, err)
+	second, err := NewCoordinator(ctx, store, "second", listEntities)
+	require.Nil(t, err)
+
+	// The first node takes all entities, until it learns about the second one and
+	// hands over the entities assigned to it.
+	require.Nil(t, first.reconcile())
+	for _, entityID := range entities {
+		require.True(t, first.IsLeader(entityID))
+	}
+	require.Nil(t, second.reconcile())
+	require.Nil(t, first.reconcile())
+	require.Nil(t, second.reconcile())
+
+	var firstCount, secondCount int
+	for _, entityID := range entities {
+		require.NotEqual(t, first.IsLeader(entityID), second.IsLeader(entityID), entityID)
+		if first.IsLeader(entityID) {
+			firstCount++
+		} else {
+			secondCount++
+		}
+	}
+	require.NotZero(t, firstCount)
+	require.NotZero(t, secondCount)
+
+	nodes, err := store.ListControllerNodes(ctx)
+	require.Nil(t, err)
+	require.Len(t, nodes, 2)
+	require.Equal(t, "first", nodes[0].Name)
+	require.Len(t, nodes[0].Entities, firstCount)
+	require.Equal(t, "second", nodes[1].Name)
+	require.Len(t, nodes[1].Entities, secondCount)
+
+	// When the second node leaves, the first one takes over its entities.
+	require.Nil(t, second.Stop())
+	require.Nil(t, first.reconcile())
+	for _, entityID := range entities {
+		require.True(t, first.IsLeader(entityID))
+		require.False(t, second.IsLeader(entityID))
+	}
+
+	nodes, err = store.ListControllerNodes(ctx)
+	require.Nil(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Entities, len(entities))
+}
+
+func TestAssignedNodeIsStable(t *testing.T) {
+	nodes := []string{uuid.New().String(), uuid.New().String(), uuid.New().String()}
+	entityID := uuid.New().String()
+
+	owner := assignedNode(nodes, entityID)
+	require.Contains(t, nodes, owner)
+	require.Equal(t, owner, assignedNode([]string{nodes[2], nodes[0], nodes[1]}, entityID))
+
+	// Removing a node that doesn't own the entity doesn't move it.
+	var remaining []string
+	for _, node := range nodes {
+		if node == owner || len(remaining) == 0 {
+			remaining = append(remaining, node)
+		}
+	}
+	require.Equal(t, owner, assignedNode(remaining, entityID))
+}
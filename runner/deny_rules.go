@@ -0,0 +1,120 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// CreateDenyRule bans an image and flavor combination. The rule applies to new and
+// existing pools.
+func (r *Runner) CreateDenyRule(ctx context.Context, param params.CreateDenyRuleParams) (params.DenyRule, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.DenyRule{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.DenyRule{}, errors.Wrap(err, "validating params")
+	}
+
+	if param.ProviderName != "" {
+		if _, ok := r.providers[param.ProviderName]; !ok {
+			return params.DenyRule{}, runnerErrors.NewBadRequestError("no such provider %s", param.ProviderName)
+		}
+	}
+
+	rule, err := r.store.CreateDenyRule(ctx, param)
+	if err != nil {
+		return params.DenyRule{}, errors.Wrap(err, "creating deny rule")
+	}
+	return rule, nil
+}
+
+func (r *Runner) ListDenyRules(ctx context.Context) ([]params.DenyRule, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	rules, err := r.store.ListDenyRules(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching deny rules")
+	}
+	return rules, nil
+}
+
+func (r *Runner) DeleteDenyRule(ctx context.Context, ruleID string) error {
+	if !auth.IsAdmin(ctx) {
+		return runnerErrors.ErrUnauthorized
+	}
+
+	if err := r.store.DeleteDenyRule(ctx, ruleID); err != nil {
+		return errors.Wrap(err, "deleting deny rule")
+	}
+	return nil
+}
+
+// checkDenyRules returns an error if the image and flavor are banned on the provider. Every
+// attempt to use a banned combination is recorded in the audit log.
+func (r *Runner) checkDenyRules(ctx context.Context, providerName, image, flavor string) error {
+	rules, err := r.store.ListDenyRules(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching deny rules")
+	}
+
+	rule, ok := params.MatchingDenyRule(rules, providerName, image, flavor)
+	if !ok {
+		return nil
+	}
+
+	msg := fmt.Sprintf(
+		"image %q with flavor %q is denied on provider %s by rule %s",
+		image, flavor, providerName, rule.ID)
+	if rule.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, rule.Reason)
+	}
+
+	record := params.AuditRecord{
+		Action:   params.AuditActionDenyRuleMatched,
+		UserID:   auth.UserID(ctx),
+		Username: auth.Username(ctx),
+		Reason:   msg,
+	}
+	if _, err := r.store.CreateAuditRecord(ctx, record); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to create audit record")
+	}
+	return runnerErrors.NewBadRequestError("%s", msg)
+}
+
+// checkPoolUpdateDenyRules checks the image and flavor a pool will use after the update
+// against the deny rules.
+func (r *Runner) checkPoolUpdateDenyRules(ctx context.Context, pool params.Pool, param params.UpdatePoolParams) error {
+	image := pool.Image
+	if param.Image != "" {
+		image = param.Image
+	}
+	flavor := pool.Flavor
+	if param.Flavor != "" {
+		flavor = param.Flavor
+	}
+	return r.checkDenyRules(ctx, pool.ProviderName, image, flavor)
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// GetEnterpriseRunnerDistribution reports how the runners in the pools of an enterprise
+// were used by the organizations and repositories of that enterprise, in the given time
+// window. The report is aggregated from the recorded jobs, which are kept for the
+// configured retention after they complete.
+func (r *Runner) GetEnterpriseRunnerDistribution(ctx context.Context, enterpriseID string, window time.Duration) (params.EnterpriseRunnerDistribution, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.EnterpriseRunnerDistribution{}, runnerErrors.ErrUnauthorized
+	}
+
+	if retention := r.config.Jobs.CompletedJobsRetention(); window <= 0 || window > retention {
+		return params.EnterpriseRunnerDistribution{}, runnerErrors.NewBadRequestError(
+			"window must be a positive duration of at most %s", retention)
+	}
+
+	enterprise, err := r.store.GetEnterpriseByID(ctx, enterpriseID)
+	if err != nil {
+		return params.EnterpriseRunnerDistribution{}, errors.Wrap(err, "fetching enterprise")
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-window)
+	jobs, err := r.store.ListJobsServedByEnterprise(ctx, enterprise.ID, since)
+	if err != nil {
+		return params.EnterpriseRunnerDistribution{}, errors.Wrap(err, "fetching jobs")
+	}
+
+	orgs := distribution{}
+	repos := distribution{}
+	for _, job := range jobs {
+		orgs.add(job.RepositoryOwner, job, until)
+		repos.add(fmt.Sprintf("%s/%s", job.RepositoryOwner, job.RepositoryName), job, until)
+	}
+
+	return params.EnterpriseRunnerDistribution{
+		EnterpriseID:   enterprise.ID,
+		EnterpriseName: enterprise.Name,
+		Since:          since,
+		Until:          until,
+		Organizations:  orgs.entries(),
+		Repositories:   repos.entries(),
+	}, nil
+}
+
+type distributionEntry struct {
+	params.RunnerDistributionEntry
+	runners map[int64]struct{}
+}
+
+// distribution aggregates jobs by the name of an organization or repository.
+type distribution map[string]*distributionEntry
+
+func (d distribution) add(name string, job params.Job, now time.Time) {
+	entry, ok := d[name]
+	if !ok {
+		entry = &distributionEntry{
+			RunnerDistributionEntry: params.RunnerDistributionEntry{
+				Name: name,
+			},
+			runners: map[int64]struct{}{},
+		}
+		d[name] = entry
+	}
+
+	entry.Jobs++
+	end := now
+	switch params.JobStatus(job.Status) {
+	case params.JobStatusInProgress:
+		entry.InProgressJobs++
+	case params.JobStatusCompleted:
+		entry.CompletedJobs++
+		if !job.CompletedAt.IsZero() {
+			end = job.CompletedAt
+		}
+	}
+	if !job.StartedAt.IsZero() && end.After(job.StartedAt) {
+		entry.RunnerMinutes += end.Sub(job.StartedAt).Minutes()
+	}
+	if job.GithubRunnerID != 0 {
+		entry.runners[job.GithubRunnerID] = struct{}{}
+	}
+}
+
+// entries returns the aggregated entries, sorted by the number of jobs in descending order.
+func (d distribution) entries() []params.RunnerDistributionEntry {
+	ret := make([]params.RunnerDistributionEntry, 0, len(d))
+	for _, entry := range d {
+		entry.Runners = uint(len(entry.runners))
+		ret = append(ret, entry.RunnerDistributionEntry)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Jobs != ret[j].Jobs {
+			return ret[i].Jobs > ret[j].Jobs
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
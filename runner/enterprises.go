@@ -12,6 +12,7 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util"
 	"github.com/cloudbase/garm/util/appdefaults"
 )
 
@@ -39,6 +40,15 @@ func (r *Runner) CreateEnterprise(ctx context.Context, param params.CreateEnterp
 		return params.Enterprise{}, runnerErrors.NewConflictError("enterprise %s already exists", param.Name)
 	}
 
+	entity := params.GithubEntity{
+		Owner:       param.Name,
+		EntityType:  params.GithubEntityTypeEnterprise,
+		Credentials: creds,
+	}
+	if err := util.ValidateFineGrainedPATAccess(ctx, entity); err != nil {
+		return params.Enterprise{}, errors.Wrap(err, "validating credentials")
+	}
+
 	enterprise, err = r.store.CreateEnterprise(ctx, param.Name, creds.Name, param.WebhookSecret, param.PoolBalancerType)
 	if err != nil {
 		return params.Enterprise{}, errors.Wrap(err, "creating enterprise")
@@ -69,11 +79,14 @@ func (r *Runner) CreateEnterprise(ctx context.Context, param params.CreateEnterp
 		}
 		return params.Enterprise{}, errors.Wrap(err, "starting enterprise pool manager")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourceEnterprise, enterprise.ID, nil, enterprise)
 	return enterprise, nil
 }
 
+// ListEnterprises returns the enterprises the user in the context can view.
 func (r *Runner) ListEnterprises(ctx context.Context) ([]params.Enterprise, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 
@@ -85,6 +98,9 @@ func (r *Runner) ListEnterprises(ctx context.Context) ([]params.Enterprise, erro
 	var allEnterprises []params.Enterprise
 
 	for _, enterprise := range enterprises {
+		if !auth.CanViewEntity(ctx, params.GithubEntity{ID: enterprise.ID, EntityType: params.GithubEntityTypeEnterprise}) {
+			continue
+		}
 		poolMgr, err := r.poolManagerCtrl.GetEnterprisePoolManager(enterprise)
 		if err != nil {
 			enterprise.PoolManagerStatus.IsRunning = false
@@ -99,7 +115,7 @@ func (r *Runner) ListEnterprises(ctx context.Context) ([]params.Enterprise, erro
 }
 
 func (r *Runner) GetEnterpriseByID(ctx context.Context, enterpriseID string) (params.Enterprise, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return params.Enterprise{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -152,6 +168,8 @@ func (r *Runner) DeleteEnterprise(ctx context.Context, enterpriseID string) erro
 	if err := r.store.DeleteEnterprise(ctx, enterpriseID); err != nil {
 		return errors.Wrapf(err, "removing enterprise %s", enterpriseID)
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourceEnterprise, enterpriseID, enterprise, nil)
 	return nil
 }
 
@@ -163,12 +181,37 @@ func (r *Runner) UpdateEnterprise(ctx context.Context, enterpriseID string, para
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
+	if err := param.Validate(); err != nil {
+		return params.Enterprise{}, errors.Wrap(err, "validating params")
+	}
+
 	switch param.PoolBalancerType {
 	case params.PoolBalancerTypeRoundRobin, params.PoolBalancerTypePack, params.PoolBalancerTypeNone:
 	default:
 		return params.Enterprise{}, runnerErrors.NewBadRequestError("invalid pool balancer type: %s", param.PoolBalancerType)
 	}
 
+	if param.RoutingRules != nil {
+		if err := r.validateRoutingRules(*param.RoutingRules); err != nil {
+			return params.Enterprise{}, errors.Wrap(err, "validating routing rules")
+		}
+	}
+
+	before, err := r.store.GetEnterpriseByID(ctx, enterpriseID)
+	if err != nil {
+		return params.Enterprise{}, errors.Wrap(err, "fetching enterprise")
+	}
+
+	if param.CredentialsName != "" {
+		entity, err := before.GetEntity()
+		if err != nil {
+			return params.Enterprise{}, errors.Wrap(err, "getting entity")
+		}
+		if err := r.validateEntityCredentials(ctx, entity, param.CredentialsName); err != nil {
+			return params.Enterprise{}, errors.Wrap(err, "updating enterprise")
+		}
+	}
+
 	enterprise, err := r.store.UpdateEnterprise(ctx, enterpriseID, param)
 	if err != nil {
 		return params.Enterprise{}, errors.Wrap(err, "updating enterprise")
@@ -180,11 +223,13 @@ func (r *Runner) UpdateEnterprise(ctx context.Context, enterpriseID string, para
 	}
 
 	enterprise.PoolManagerStatus = poolMgr.Status()
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceEnterprise, enterprise.ID, before, enterprise)
 	return enterprise, nil
 }
 
 func (r *Runner) CreateEnterprisePool(ctx context.Context, enterpriseID string, param params.CreatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -193,6 +238,10 @@ func (r *Runner) CreateEnterprisePool(ctx context.Context, enterpriseID string,
 		return params.Pool{}, fmt.Errorf("failed to append tags to create pool params: %w", err)
 	}
 
+	if err := r.checkDenyRules(ctx, createPoolParams.ProviderName, createPoolParams.Image, createPoolParams.Flavor); err != nil {
+		return params.Pool{}, err
+	}
+
 	if param.RunnerBootstrapTimeout == 0 {
 		param.RunnerBootstrapTimeout = appdefaults.DefaultRunnerBootstrapTimeout
 	}
@@ -202,16 +251,29 @@ func (r *Runner) CreateEnterprisePool(ctx context.Context, enterpriseID string,
 		EntityType: params.GithubEntityTypeEnterprise,
 	}
 
+	if err := r.validatePoolRunnerGroup(ctx, entity, createPoolParams.GitHubRunnerGroup); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateNewPoolWithProvider(ctx, createPoolParams); err != nil {
+		return params.Pool{}, err
+	}
+
 	pool, err := r.store.CreateEntityPool(ctx, entity, createPoolParams)
 	if err != nil {
 		return params.Pool{}, fmt.Errorf("failed to create enterprise pool: %w", err)
 	}
 
+	if createPoolParams.DryRun {
+		return pool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourcePool, pool.ID, nil, pool)
 	return pool, nil
 }
 
 func (r *Runner) GetEnterprisePoolByID(ctx context.Context, enterpriseID, poolID string) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 	entity := params.GithubEntity{
@@ -222,11 +284,17 @@ func (r *Runner) GetEnterprisePoolByID(ctx context.Context, enterpriseID, poolID
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "fetching pool")
 	}
+
+	pool.ProviderPaused, err = r.isProviderPaused(ctx, pool.ProviderName)
+	if err != nil {
+		return params.Pool{}, err
+	}
+	pool.CapacityWarning = pool.CapacityWarningReached(uint(len(pool.Instances)))
 	return pool, nil
 }
 
 func (r *Runner) DeleteEnterprisePool(ctx context.Context, enterpriseID, poolID string) error {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return runnerErrors.ErrUnauthorized
 	}
 
@@ -253,11 +321,13 @@ func (r *Runner) DeleteEnterprisePool(ctx context.Context, enterpriseID, poolID
 	if err := r.store.DeleteEntityPool(ctx, entity, poolID); err != nil {
 		return errors.Wrap(err, "deleting pool")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourcePool, poolID, pool, nil)
 	return nil
 }
 
 func (r *Runner) ListEnterprisePools(ctx context.Context, enterpriseID string) ([]params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return []params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -269,11 +339,17 @@ func (r *Runner) ListEnterprisePools(ctx context.Context, enterpriseID string) (
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching pools")
 	}
+	if err := r.setPoolsProviderPaused(ctx, pools); err != nil {
+		return nil, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools); err != nil {
+		return nil, err
+	}
 	return pools, nil
 }
 
 func (r *Runner) UpdateEnterprisePool(ctx context.Context, enterpriseID, poolID string, param params.UpdatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -300,15 +376,77 @@ func (r *Runner) UpdateEnterprisePool(ctx context.Context, enterpriseID, poolID
 		return params.Pool{}, runnerErrors.NewBadRequestError("min_idle_runners cannot be larger than max_runners")
 	}
 
+	schedule := pool.Schedule
+	if param.Schedule != nil {
+		schedule = param.Schedule
+	}
+	if schedule != nil {
+		if err := schedule.Validate(maxRunners); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid schedule: %s", err)
+		}
+	}
+
+	if err := params.ValidateScaleDownSettings(param.IdleDetectionWindow, param.ScaleDownGracePeriod, param.ScaleDownFactor); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCapacityWarningThreshold(param.CapacityWarningThreshold); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCreateJitter(param.CreateJitter); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := r.validateRunnerPrefix(pool.ProviderName, param.Prefix); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.checkPoolUpdateDenyRules(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
+	if param.GitHubRunnerGroup != nil && *param.GitHubRunnerGroup != pool.GitHubRunnerGroup {
+		if err := r.validatePoolRunnerGroup(ctx, entity, *param.GitHubRunnerGroup); err != nil {
+			return params.Pool{}, err
+		}
+	}
+
+	if param.NetworkSettings != nil {
+		if err := param.NetworkSettings.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid network settings: %s", err)
+		}
+	}
+
+	if param.InstanceMetadata != nil {
+		if err := param.InstanceMetadata.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+		}
+	}
+
+	if err := r.validateWarmImage(pool.ProviderName, param.WarmImage); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateUpdatedPoolWithProvider(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
 	newPool, err := r.store.UpdateEntityPool(ctx, entity, poolID, param)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "updating pool")
 	}
+
+	if param.DryRun {
+		return newPool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourcePool, newPool.ID, pool, newPool)
 	return newPool, nil
 }
 
 func (r *Runner) ListEnterpriseInstances(ctx context.Context, enterpriseID string) ([]params.Instance, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: enterpriseID, EntityType: params.GithubEntityTypeEnterprise}) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 	entity := params.GithubEntity{
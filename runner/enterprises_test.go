@@ -18,7 +18,9 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -541,6 +543,67 @@ func (s *EnterpriseTestSuite) TestListEnterpriseInstancesErrUnauthorized() {
 	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
 }
 
+func (s *EnterpriseTestSuite) TestGetEnterpriseRunnerDistribution() {
+	enterprise := s.Fixtures.StoreEnterprises["test-enterprise-1"]
+	entity := params.GithubEntity{
+		ID:         enterprise.ID,
+		EntityType: params.GithubEntityTypeEnterprise,
+	}
+	pool, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams)
+	s.Require().Nil(err)
+
+	enterpriseID, err := uuid.Parse(enterprise.ID)
+	s.Require().Nil(err)
+	now := time.Now().UTC()
+	jobs := []params.Job{
+		{ID: 1, Status: string(params.JobStatusCompleted), RepositoryOwner: "org-a", RepositoryName: "repo-1", GithubRunnerID: 1, StartedAt: now.Add(-30 * time.Minute), CompletedAt: now.Add(-20 * time.Minute)},
+		{ID: 2, Status: string(params.JobStatusInProgress), RepositoryOwner: "org-a", RepositoryName: "repo-2", GithubRunnerID: 2, StartedAt: now.Add(-5 * time.Minute)},
+		{ID: 3, Status: string(params.JobStatusCompleted), RepositoryOwner: "org-b", RepositoryName: "repo-1", GithubRunnerID: 3, StartedAt: now.Add(-10 * time.Minute), CompletedAt: now.Add(-5 * time.Minute)},
+		// Started outside of the window.
+		{ID: 4, Status: string(params.JobStatusCompleted), RepositoryOwner: "org-b", RepositoryName: "repo-1", GithubRunnerID: 4, StartedAt: now.Add(-3 * time.Hour), CompletedAt: now.Add(-2 * time.Hour)},
+	}
+	for _, job := range jobs {
+		s.Fixtures.CreateInstanceParams.Name = fmt.Sprintf("test-enterprise-runner-%d", job.ID)
+		_, err := s.Fixtures.Store.CreateInstance(s.Fixtures.AdminContext, pool.ID, s.Fixtures.CreateInstanceParams)
+		s.Require().Nil(err)
+		job.RunnerName = s.Fixtures.CreateInstanceParams.Name
+		job.EnterpriseID = &enterpriseID
+		_, err = s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, job)
+		s.Require().Nil(err)
+	}
+	// A job received by the enterprise, that did not run on enterprise runners.
+	_, err = s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, params.Job{
+		ID: 5, Status: string(params.JobStatusCompleted), RepositoryOwner: "org-c", RepositoryName: "repo-1",
+		StartedAt: now.Add(-5 * time.Minute), EnterpriseID: &enterpriseID,
+	})
+	s.Require().Nil(err)
+
+	distribution, err := s.Runner.GetEnterpriseRunnerDistribution(s.Fixtures.AdminContext, enterprise.ID, time.Hour)
+	s.Require().Nil(err)
+	s.Require().Equal(enterprise.Name, distribution.EnterpriseName)
+	s.Require().Len(distribution.Organizations, 2)
+	s.Require().Equal("org-a", distribution.Organizations[0].Name)
+	s.Require().Equal(uint(2), distribution.Organizations[0].Jobs)
+	s.Require().Equal(uint(1), distribution.Organizations[0].InProgressJobs)
+	s.Require().Equal(uint(2), distribution.Organizations[0].Runners)
+	s.Require().Equal("org-b", distribution.Organizations[1].Name)
+	s.Require().Equal(uint(1), distribution.Organizations[1].Jobs)
+	s.Require().InDelta(5.0, distribution.Organizations[1].RunnerMinutes, 0.01)
+	s.Require().Len(distribution.Repositories, 3)
+}
+
+func (s *EnterpriseTestSuite) TestGetEnterpriseRunnerDistributionInvalidWindow() {
+	_, err := s.Runner.GetEnterpriseRunnerDistribution(s.Fixtures.AdminContext, s.Fixtures.StoreEnterprises["test-enterprise-1"].ID, 48*time.Hour)
+
+	s.Require().Regexp("window must be a positive duration", err.Error())
+}
+
+func (s *EnterpriseTestSuite) TestGetEnterpriseRunnerDistributionErrUnauthorized() {
+	_, err := s.Runner.GetEnterpriseRunnerDistribution(context.Background(), "dummy-enterprise-id", time.Hour)
+
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
 func (s *EnterpriseTestSuite) TestFindEnterprisePoolManager() {
 	s.Fixtures.PoolMgrCtrlMock.On("GetEnterprisePoolManager", mock.AnythingOfType("params.Enterprise")).Return(s.Fixtures.PoolMgrMock, nil)
 
@@ -0,0 +1,339 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// ExportEntityConfig returns the configuration of an entity and its pools. The webhook
+// secret is replaced with a placeholder.
+func (r *Runner) ExportEntityConfig(ctx context.Context, entityType params.GithubEntityType, entityID string) (params.EntityConfig, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.EntityConfig{}, runnerErrors.ErrUnauthorized
+	}
+
+	entity, err := r.getEntityOfType(ctx, entityType, entityID)
+	if err != nil {
+		return params.EntityConfig{}, err
+	}
+
+	pools, err := r.store.ListEntityPools(ctx, entity)
+	if err != nil {
+		return params.EntityConfig{}, errors.Wrap(err, "fetching pools")
+	}
+
+	cfg := params.EntityConfig{
+		Version:           params.EntityConfigVersion,
+		EntityType:        entity.EntityType,
+		Name:              entity.Name,
+		CredentialsName:   entity.Credentials.Name,
+		Endpoint:          entity.Credentials.Endpoint.Name,
+		WebhookSecret:     params.EntityConfigSecretPlaceholder,
+		PoolBalancerType:  entity.PoolBalancerType,
+		MaxConcurrentJobs: entity.MaxConcurrentJobs,
+		RoutingRules:      entity.RoutingRules,
+		Pools:             make([]params.EntityConfigPool, len(pools)),
+	}
+	if entity.EntityType == params.GithubEntityTypeRepository {
+		cfg.Owner = entity.Owner
+	} else {
+		cfg.Name = entity.Owner
+	}
+	for idx, pool := range pools {
+		// Listing pools leaves out their extra specs, so each pool is fetched again.
+		pool, err = r.store.GetEntityPool(ctx, entity, pool.ID)
+		if err != nil {
+			return params.EntityConfig{}, errors.Wrap(err, "fetching pool")
+		}
+		cfg.Pools[idx] = params.EntityConfigPool{
+			ID:               pool.ID,
+			CreatePoolParams: poolToCreateParams(pool),
+		}
+	}
+	return cfg, nil
+}
+
+// ImportEntityConfig creates the pools of an exported config in an existing entity. All
+// pools are validated before any of them is created, and the pools created so far are
+// removed if creating one of them fails. The entity settings in the config are ignored.
+func (r *Runner) ImportEntityConfig(ctx context.Context, entityType params.GithubEntityType, entityID string, cfg params.EntityConfig) ([]params.Pool, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validating config")
+	}
+
+	entity, err := r.getEntityOfType(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.validateConfigPools(ctx, cfg.Pools); err != nil {
+		return nil, err
+	}
+	return r.createConfigPools(ctx, entity, cfg.Pools)
+}
+
+// ImportEntity creates a new repository, organization or enterprise from an exported
+// config, along with its pools. The credentials and providers used in the config must
+// exist on this controller, and the webhook secret placeholder must be replaced.
+func (r *Runner) ImportEntity(ctx context.Context, cfg params.EntityConfig) (params.EntityConfigImport, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.EntityConfigImport{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := cfg.ValidateNewEntity(); err != nil {
+		return params.EntityConfigImport{}, errors.Wrap(err, "validating config")
+	}
+	if cfg.PoolBalancerType == "" {
+		cfg.PoolBalancerType = params.PoolBalancerTypeRoundRobin
+	}
+	if err := r.validateRoutingRules(cfg.RoutingRules); err != nil {
+		return params.EntityConfigImport{}, errors.Wrap(err, "validating routing rules")
+	}
+	if err := r.validateConfigPools(ctx, cfg.Pools); err != nil {
+		return params.EntityConfigImport{}, err
+	}
+
+	var entityID string
+	switch cfg.EntityType {
+	case params.GithubEntityTypeRepository:
+		repo, err := r.CreateRepository(ctx, params.CreateRepoParams{
+			Owner:            cfg.Owner,
+			Name:             cfg.Name,
+			CredentialsName:  cfg.CredentialsName,
+			WebhookSecret:    cfg.WebhookSecret,
+			PoolBalancerType: cfg.PoolBalancerType,
+		})
+		if err != nil {
+			return params.EntityConfigImport{}, errors.Wrap(err, "creating repository")
+		}
+		entityID = repo.ID
+	case params.GithubEntityTypeOrganization:
+		org, err := r.CreateOrganization(ctx, params.CreateOrgParams{
+			Name:             cfg.Name,
+			CredentialsName:  cfg.CredentialsName,
+			WebhookSecret:    cfg.WebhookSecret,
+			PoolBalancerType: cfg.PoolBalancerType,
+		})
+		if err != nil {
+			return params.EntityConfigImport{}, errors.Wrap(err, "creating organization")
+		}
+		entityID = org.ID
+	case params.GithubEntityTypeEnterprise:
+		enterprise, err := r.CreateEnterprise(ctx, params.CreateEnterpriseParams{
+			Name:             cfg.Name,
+			CredentialsName:  cfg.CredentialsName,
+			WebhookSecret:    cfg.WebhookSecret,
+			PoolBalancerType: cfg.PoolBalancerType,
+		})
+		if err != nil {
+			return params.EntityConfigImport{}, errors.Wrap(err, "creating enterprise")
+		}
+		entityID = enterprise.ID
+	}
+
+	entity, err := r.getEntityOfType(ctx, cfg.EntityType, entityID)
+	if err == nil && (cfg.MaxConcurrentJobs > 0 || len(cfg.RoutingRules) > 0) {
+		err = r.updateImportedEntity(ctx, entity, cfg)
+	}
+	var pools []params.Pool
+	if err == nil {
+		pools, err = r.createConfigPools(ctx, entity, cfg.Pools)
+	}
+	if err != nil {
+		if deleteErr := r.deleteEntity(ctx, cfg.EntityType, entityID); deleteErr != nil {
+			slog.With(slog.Any("error", deleteErr)).ErrorContext(
+				ctx, "failed to remove imported entity", "entity_id", entityID)
+		}
+		return params.EntityConfigImport{}, err
+	}
+
+	return params.EntityConfigImport{
+		EntityID:   entityID,
+		EntityType: cfg.EntityType,
+		Pools:      pools,
+	}, nil
+}
+
+// getEntityOfType returns the entity with the given ID, if it is of the given type.
+func (r *Runner) getEntityOfType(ctx context.Context, entityType params.GithubEntityType, entityID string) (params.GithubEntity, error) {
+	entity, err := r.getGithubEntityByID(ctx, entityID)
+	if err != nil {
+		return params.GithubEntity{}, errors.Wrap(err, "fetching entity")
+	}
+	if entity.EntityType != entityType {
+		return params.GithubEntity{}, runnerErrors.NewNotFoundError("%s %s not found", entityType, entityID)
+	}
+	return entity, nil
+}
+
+func (r *Runner) validateConfigPools(ctx context.Context, pools []params.EntityConfigPool) error {
+	for idx, pool := range pools {
+		if _, err := r.appendTagsToCreatePoolParams(pool.CreatePoolParams); err != nil {
+			return errors.Wrapf(err, "validating pool %d", idx+1)
+		}
+		if err := r.checkDenyRules(ctx, pool.ProviderName, pool.Image, pool.Flavor); err != nil {
+			return errors.Wrapf(err, "validating pool %d", idx+1)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) createConfigPools(ctx context.Context, entity params.GithubEntity, pools []params.EntityConfigPool) (ret []params.Pool, err error) {
+	ret = []params.Pool{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, pool := range ret {
+			if deleteErr := r.deleteEntityPool(ctx, entity, pool.ID); deleteErr != nil {
+				slog.With(slog.Any("error", deleteErr)).ErrorContext(
+					ctx, "failed to remove imported pool", "pool_id", pool.ID)
+			}
+		}
+	}()
+
+	for idx, param := range pools {
+		var pool params.Pool
+		pool, err = r.createEntityPool(ctx, entity, param.CreatePoolParams)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating pool %d", idx+1)
+		}
+		ret = append(ret, pool)
+	}
+	return ret, nil
+}
+
+func (r *Runner) createEntityPool(ctx context.Context, entity params.GithubEntity, param params.CreatePoolParams) (params.Pool, error) {
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		return r.CreateRepoPool(ctx, entity.ID, param)
+	case params.GithubEntityTypeOrganization:
+		return r.CreateOrgPool(ctx, entity.ID, param)
+	case params.GithubEntityTypeEnterprise:
+		return r.CreateEnterprisePool(ctx, entity.ID, param)
+	}
+	return params.Pool{}, runnerErrors.NewBadRequestError("invalid entity type %q", entity.EntityType)
+}
+
+func (r *Runner) updateEntityPool(ctx context.Context, entity params.GithubEntity, poolID string, param params.UpdatePoolParams) (params.Pool, error) {
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		return r.UpdateRepoPool(ctx, entity.ID, poolID, param)
+	case params.GithubEntityTypeOrganization:
+		return r.UpdateOrgPool(ctx, entity.ID, poolID, param)
+	case params.GithubEntityTypeEnterprise:
+		return r.UpdateEnterprisePool(ctx, entity.ID, poolID, param)
+	}
+	return params.Pool{}, runnerErrors.NewBadRequestError("invalid entity type %q", entity.EntityType)
+}
+
+func (r *Runner) deleteEntityPool(ctx context.Context, entity params.GithubEntity, poolID string) error {
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		return r.DeleteRepoPool(ctx, entity.ID, poolID)
+	case params.GithubEntityTypeOrganization:
+		return r.DeleteOrgPool(ctx, entity.ID, poolID)
+	case params.GithubEntityTypeEnterprise:
+		return r.DeleteEnterprisePool(ctx, entity.ID, poolID)
+	}
+	return nil
+}
+
+func (r *Runner) updateImportedEntity(ctx context.Context, entity params.GithubEntity, cfg params.EntityConfig) error {
+	return r.updateEntity(ctx, entity, params.UpdateEntityParams{
+		PoolBalancerType:  cfg.PoolBalancerType,
+		MaxConcurrentJobs: &cfg.MaxConcurrentJobs,
+		RoutingRules:      &cfg.RoutingRules,
+	})
+}
+
+func (r *Runner) updateEntity(ctx context.Context, entity params.GithubEntity, param params.UpdateEntityParams) error {
+	var err error
+	switch entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		_, err = r.UpdateRepository(ctx, entity.ID, param)
+	case params.GithubEntityTypeOrganization:
+		_, err = r.UpdateOrganization(ctx, entity.ID, param)
+	case params.GithubEntityTypeEnterprise:
+		_, err = r.UpdateEnterprise(ctx, entity.ID, param)
+	}
+	if err != nil {
+		return errors.Wrap(err, "updating entity")
+	}
+	return nil
+}
+
+func (r *Runner) deleteEntity(ctx context.Context, entityType params.GithubEntityType, entityID string) error {
+	switch entityType {
+	case params.GithubEntityTypeRepository:
+		return r.DeleteRepository(ctx, entityID, true)
+	case params.GithubEntityTypeOrganization:
+		return r.DeleteOrganization(ctx, entityID, true)
+	case params.GithubEntityTypeEnterprise:
+		return r.DeleteEnterprise(ctx, entityID)
+	}
+	return nil
+}
+
+func poolToCreateParams(pool params.Pool) params.CreatePoolParams {
+	tags := make([]string, len(pool.Tags))
+	for idx, tag := range pool.Tags {
+		tags[idx] = tag.Name
+	}
+	return params.CreatePoolParams{
+		RunnerPrefix:             pool.RunnerPrefix,
+		ProviderName:             pool.ProviderName,
+		MaxRunners:               pool.MaxRunners,
+		MinIdleRunners:           pool.MinIdleRunners,
+		Image:                    pool.Image,
+		Flavor:                   pool.Flavor,
+		OSType:                   pool.OSType,
+		OSArch:                   pool.OSArch,
+		Tags:                     tags,
+		Enabled:                  pool.Enabled,
+		RunnerBootstrapTimeout:   pool.RunnerBootstrapTimeout,
+		ExtraSpecs:               pool.ExtraSpecs,
+		GitHubRunnerGroup:        pool.GitHubRunnerGroup,
+		Priority:                 pool.Priority,
+		CleanupPolicy:            pool.CleanupPolicy,
+		NetworkSettings:          pool.NetworkSettings,
+		Schedule:                 pool.Schedule,
+		IdleDetectionWindow:      pool.IdleDetectionWindow,
+		ScaleDownGracePeriod:     pool.ScaleDownGracePeriod,
+		ScaleDownFactor:          pool.ScaleDownFactor,
+		CapacityWarningThreshold: pool.CapacityWarningThreshold,
+		AutoscaleMaxBurst:        pool.AutoscaleMaxBurst,
+		AutoscaleCooldown:        pool.AutoscaleCooldown,
+		MaxCreatesPerMinute:      pool.MaxCreatesPerMinute,
+		CreateJitter:             pool.CreateJitter,
+		RollingUpdateBatchSize:   pool.RollingUpdateBatchSize,
+		RollingUpdatePause:       pool.RollingUpdatePause,
+		URLSet:                   pool.URLSet,
+		InstanceMetadata:         pool.InstanceMetadata,
+		WarmImage:                pool.WarmImage,
+	}
+}
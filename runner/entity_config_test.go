@@ -0,0 +1,121 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestExportImportEntityConfig(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+	_, err = db.InitController()
+	require.Nil(t, err)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	source, err := db.CreateOrganization(adminCtx, "source-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypePack)
+	require.Nil(t, err)
+	target, err := db.CreateOrganization(adminCtx, "target-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+
+	entity := params.GithubEntity{ID: source.ID, EntityType: params.GithubEntityTypeOrganization}
+	_, err = db.CreateEntityPool(adminCtx, entity, params.CreatePoolParams{
+		ProviderName:   "test-provider",
+		MaxRunners:     4,
+		MinIdleRunners: 1,
+		Image:          "test-image",
+		Flavor:         "test-flavor",
+		OSType:         "linux",
+		OSArch:         "amd64",
+		Tags:           []string{"linux", "gpu"},
+		Enabled:        true,
+		ExtraSpecs:     []byte(`{"disk_size":100}`),
+	})
+	require.Nil(t, err)
+
+	provider := mocks.NewProvider(t)
+	provider.On("AsParams").Return(params.Provider{Name: "test-provider"}).Maybe()
+	r := &Runner{
+		ctx:   adminCtx,
+		store: db,
+		providers: map[string]common.Provider{
+			"test-provider": provider,
+		},
+	}
+
+	_, err = r.ExportEntityConfig(context.Background(), params.GithubEntityTypeOrganization, source.ID)
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+
+	_, err = r.ExportEntityConfig(adminCtx, params.GithubEntityTypeRepository, source.ID)
+	var notFound *runnerErrors.NotFoundError
+	require.ErrorAs(t, err, &notFound)
+
+	cfg, err := r.ExportEntityConfig(adminCtx, params.GithubEntityTypeOrganization, source.ID)
+	require.Nil(t, err)
+	require.Equal(t, params.EntityConfigVersion, cfg.Version)
+	require.Equal(t, "source-org", cfg.Name)
+	require.Equal(t, creds.Name, cfg.CredentialsName)
+	require.Equal(t, params.EntityConfigSecretPlaceholder, cfg.WebhookSecret)
+	require.Equal(t, params.PoolBalancerTypePack, cfg.PoolBalancerType)
+	require.Len(t, cfg.Pools, 1)
+	require.ElementsMatch(t, []string{"linux", "gpu"}, cfg.Pools[0].Tags)
+
+	asYAML, err := yaml.Marshal(cfg)
+	require.Nil(t, err)
+	require.Contains(t, string(asYAML), "credentials_name: test-creds")
+	var parsed params.EntityConfig
+	require.Nil(t, yaml.Unmarshal(asYAML, &parsed))
+	require.Equal(t, cfg.Pools[0].Image, parsed.Pools[0].Image)
+	require.JSONEq(t, `{"disk_size":100}`, string(parsed.Pools[0].ExtraSpecs))
+
+	// Pools using unknown providers are refused before anything is created.
+	invalid := parsed
+	invalid.Pools = append([]params.EntityConfigPool{}, parsed.Pools...)
+	invalid.Pools = append(invalid.Pools, parsed.Pools[0])
+	invalid.Pools[1].ProviderName = "missing-provider"
+	_, err = r.ImportEntityConfig(adminCtx, params.GithubEntityTypeOrganization, target.ID, invalid)
+	var badRequest *runnerErrors.BadRequestError
+	require.ErrorAs(t, err, &badRequest)
+	pools, err := db.ListEntityPools(adminCtx, params.GithubEntity{ID: target.ID, EntityType: params.GithubEntityTypeOrganization})
+	require.Nil(t, err)
+	require.Len(t, pools, 0)
+
+	imported, err := r.ImportEntityConfig(adminCtx, params.GithubEntityTypeOrganization, target.ID, parsed)
+	require.Nil(t, err)
+	require.Len(t, imported, 1)
+	require.Equal(t, target.ID, imported[0].OrgID)
+	require.Equal(t, "test-image", imported[0].Image)
+	require.True(t, imported[0].Enabled)
+
+	// New entities need the webhook secret placeholder to be replaced.
+	_, err = r.ImportEntity(adminCtx, parsed)
+	var missingSecret *runnerErrors.MissingSecretError
+	require.ErrorAs(t, err, &missingSecret)
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// getPoolManagerForEntityID returns the pool manager of the repository, organization or
+// enterprise with the given ID.
+func (r *Runner) getPoolManagerForEntityID(ctx context.Context, entityID string) (common.PoolManager, error) {
+	repo, err := r.store.GetRepositoryByID(ctx, entityID)
+	if err == nil {
+		return r.poolManagerCtrl.GetRepoPoolManager(repo)
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return nil, errors.Wrap(err, "fetching repository")
+	}
+
+	org, err := r.store.GetOrganizationByID(ctx, entityID)
+	if err == nil {
+		return r.poolManagerCtrl.GetOrgPoolManager(org)
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return nil, errors.Wrap(err, "fetching organization")
+	}
+
+	enterprise, err := r.store.GetEnterpriseByID(ctx, entityID)
+	if err == nil {
+		return r.poolManagerCtrl.GetEnterprisePoolManager(enterprise)
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return nil, errors.Wrap(err, "fetching enterprise")
+	}
+	return nil, runnerErrors.NewNotFoundError("entity %s not found", entityID)
+}
+
+// ListEntityForgeRunners lists the runners GitHub has registered for a repository,
+// organization or enterprise, correlated with the instances GARM has recorded for it.
+// This makes it easy to spot runners that GARM does not manage and instances that
+// GitHub does not know about.
+func (r *Runner) ListEntityForgeRunners(ctx context.Context, entityID string) ([]params.ForgeRunner, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entityID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool manager")
+	}
+
+	runners, err := poolMgr.ListForgeRunners(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing forge runners")
+	}
+	return runners, nil
+}
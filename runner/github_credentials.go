@@ -2,12 +2,18 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util"
+	garmGithub "github.com/cloudbase/garm/util/github"
+	"github.com/cloudbase/garm/util/ratelimit"
 )
 
 func (r *Runner) ListCredentials(ctx context.Context) ([]params.GithubCredentials, error) {
@@ -32,11 +38,24 @@ func (r *Runner) CreateGithubCredentials(ctx context.Context, param params.Creat
 		return params.GithubCredentials{}, errors.Wrap(err, "failed to validate github credentials params")
 	}
 
+	if param.AuthType == params.GithubAuthTypePAT && param.PAT.IsFineGrained() {
+		endpoint, err := r.store.GetGithubEndpoint(ctx, param.Endpoint)
+		if err != nil {
+			return params.GithubCredentials{}, errors.Wrap(err, "failed to fetch github endpoint")
+		}
+		summary, err := r.fineGrainedTokenSummary(ctx, endpoint, param.PAT)
+		if err != nil {
+			return params.GithubCredentials{}, err
+		}
+		param.TokenSummary = &summary
+	}
+
 	creds, err := r.store.CreateGithubCredentials(ctx, param)
 	if err != nil {
 		return params.GithubCredentials{}, errors.Wrap(err, "failed to create github credentials")
 	}
 
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourceGithubCredentials, fmt.Sprintf("%d", creds.ID), nil, creds)
 	return creds, nil
 }
 
@@ -58,10 +77,20 @@ func (r *Runner) DeleteGithubCredentials(ctx context.Context, id uint) error {
 		return runnerErrors.ErrUnauthorized
 	}
 
+	creds, err := r.store.GetGithubCredentials(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, runnerErrors.ErrNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get github credentials")
+	}
+
 	if err := r.store.DeleteGithubCredentials(ctx, id); err != nil {
 		return errors.Wrap(err, "failed to delete github credentials")
 	}
+	garmGithub.Default().Forget(id)
 
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourceGithubCredentials, fmt.Sprintf("%d", id), creds, nil)
 	return nil
 }
 
@@ -74,10 +103,72 @@ func (r *Runner) UpdateGithubCredentials(ctx context.Context, id uint, param par
 		return params.GithubCredentials{}, errors.Wrap(err, "failed to validate github credentials params")
 	}
 
+	creds, err := r.store.GetGithubCredentials(ctx, id, false)
+	if err != nil {
+		return params.GithubCredentials{}, errors.Wrap(err, "failed to get github credentials")
+	}
+
+	if param.PAT != nil && param.PAT.IsFineGrained() {
+		if creds.AuthType == params.GithubAuthTypePAT {
+			summary, err := r.fineGrainedTokenSummary(ctx, creds.Endpoint, *param.PAT)
+			if err != nil {
+				return params.GithubCredentials{}, err
+			}
+			param.TokenSummary = &summary
+		}
+	}
+
 	newCreds, err := r.store.UpdateGithubCredentials(ctx, id, param)
 	if err != nil {
 		return params.GithubCredentials{}, errors.Wrap(err, "failed to update github credentials")
 	}
 
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceGithubCredentials, fmt.Sprintf("%d", id), creds, newCreds)
 	return newCreds, nil
 }
+
+// fineGrainedTokenSummary probes a fine-grained PAT before it is saved, so that a token
+// GitHub rejects is refused right away.
+func (r *Runner) fineGrainedTokenSummary(ctx context.Context, endpoint params.GithubEndpoint, pat params.GithubPAT) (params.GithubTokenSummary, error) {
+	payload, err := json.Marshal(pat)
+	if err != nil {
+		return params.GithubTokenSummary{}, errors.Wrap(err, "failed to marshal PAT")
+	}
+	creds := params.GithubCredentials{
+		APIBaseURL:         endpoint.APIBaseURL,
+		UploadBaseURL:      endpoint.UploadBaseURL,
+		BaseURL:            endpoint.BaseURL,
+		CABundle:           endpoint.CACertBundle,
+		AuthType:           params.GithubAuthTypePAT,
+		CredentialsPayload: payload,
+	}
+	summary, err := util.GithubTokenSummary(ctx, creds)
+	if err != nil {
+		return params.GithubTokenSummary{}, errors.Wrap(err, "failed to probe fine-grained PAT")
+	}
+	return summary, nil
+}
+
+// validateEntityCredentials checks that the credentials with the given name can manage the
+// runners of the entity, before they are attached to it.
+func (r *Runner) validateEntityCredentials(ctx context.Context, entity params.GithubEntity, credsName string) error {
+	creds, err := r.store.GetGithubCredentialsByName(ctx, credsName, true)
+	if err != nil {
+		return errors.Wrap(err, "fetching credentials")
+	}
+	entity.Credentials = creds
+	if err := util.ValidateFineGrainedPATAccess(ctx, entity); err != nil {
+		return errors.Wrap(err, "validating credentials")
+	}
+	return nil
+}
+
+// ListRateLimitForecasts returns an estimate of when the rate limit of each set of
+// GitHub credentials will run out, based on the API responses GARM received recently.
+func (r *Runner) ListRateLimitForecasts(ctx context.Context) (params.RateLimitForecasts, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	return ratelimit.Default().Forecasts(time.Now()), nil
+}
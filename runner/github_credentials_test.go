@@ -0,0 +1,152 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+)
+
+const testFineGrainedPAT = "github_pat_test"
+
+// newFineGrainedPATRunner returns a runner and an admin context, backed by a database that
+// holds a GitHub endpoint served by the given handler.
+func newFineGrainedPATRunner(t *testing.T, handler http.Handler) (*Runner, context.Context, params.GithubEndpoint) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	db, err := database.NewDatabase(context.Background(), garmTesting.GetTestSqliteDBConfig(t))
+	require.NoError(t, err)
+	adminCtx := garmTesting.ImpersonateAdminContext(context.Background(), db, t)
+
+	endpoint, err := db.CreateGithubEndpoint(adminCtx, params.CreateGithubEndpointParams{
+		Name:          "test-endpoint",
+		APIBaseURL:    srv.URL + "/",
+		UploadBaseURL: srv.URL + "/",
+		BaseURL:       srv.URL,
+	})
+	require.NoError(t, err)
+
+	return &Runner{ctx: adminCtx, store: db}, adminCtx, endpoint
+}
+
+func TestCreateGithubCredentialsFineGrainedPAT(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/user/repos", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("GitHub-Authentication-Token-Expiration", "2030-01-02 03:04:05 UTC")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"full_name": "test-owner/test-repo"}]`))
+	})
+	r, adminCtx, endpoint := newFineGrainedPATRunner(t, mux)
+
+	creds, err := r.CreateGithubCredentials(adminCtx, params.CreateGithubCredentialsParams{
+		Name:     "fine-grained",
+		Endpoint: endpoint.Name,
+		AuthType: params.GithubAuthTypePAT,
+		PAT:      params.GithubPAT{OAuth2Token: testFineGrainedPAT},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, creds.TokenSummary)
+	require.Equal(t, []string{"test-owner/test-repo"}, creds.TokenSummary.Repositories)
+	require.NotNil(t, creds.TokenSummary.ExpiresAt)
+	require.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), *creds.TokenSummary.ExpiresAt)
+
+	// Replacing the token with a classic one drops the summary.
+	creds, err = r.UpdateGithubCredentials(adminCtx, creds.ID, params.UpdateGithubCredentialsParams{
+		PAT: &params.GithubPAT{OAuth2Token: "classic-token"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, creds.TokenSummary)
+}
+
+func TestCreateGithubCredentialsFineGrainedPATRejected(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/user/repos", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	r, adminCtx, endpoint := newFineGrainedPATRunner(t, mux)
+
+	_, err := r.CreateGithubCredentials(adminCtx, params.CreateGithubCredentialsParams{
+		Name:     "fine-grained",
+		Endpoint: endpoint.Name,
+		AuthType: params.GithubAuthTypePAT,
+		PAT:      params.GithubPAT{OAuth2Token: testFineGrainedPAT},
+	})
+	var badRequest *runnerErrors.BadRequestError
+	require.True(t, errors.As(err, &badRequest), "expected bad request error, got %v", err)
+
+	creds, err := r.ListCredentials(adminCtx)
+	require.NoError(t, err)
+	require.Empty(t, creds)
+}
+
+func TestCreateEntitiesWithFineGrainedPAT(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/test-owner/missing-repo/actions/runners", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/v3/repos/test-owner/read-only-repo/actions/runners", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Accepted-GitHub-Permissions", "administration=read")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	r, adminCtx, endpoint := newFineGrainedPATRunner(t, mux)
+
+	// Store the credentials directly, to skip probing the token.
+	creds, err := r.store.CreateGithubCredentials(adminCtx, params.CreateGithubCredentialsParams{
+		Name:     "fine-grained",
+		Endpoint: endpoint.Name,
+		AuthType: params.GithubAuthTypePAT,
+		PAT:      params.GithubPAT{OAuth2Token: testFineGrainedPAT},
+	})
+	require.NoError(t, err)
+
+	_, err = r.CreateRepository(adminCtx, params.CreateRepoParams{
+		Owner:           "test-owner",
+		Name:            "missing-repo",
+		CredentialsName: creds.Name,
+		WebhookSecret:   "test-webhook-secret",
+	})
+	require.ErrorContains(t, err, "does not have access to repository test-owner/missing-repo")
+
+	_, err = r.CreateRepository(adminCtx, params.CreateRepoParams{
+		Owner:           "test-owner",
+		Name:            "read-only-repo",
+		CredentialsName: creds.Name,
+		WebhookSecret:   "test-webhook-secret",
+	})
+	require.ErrorContains(t, err, "GitHub requires: administration=read")
+
+	_, err = r.CreateEnterprise(adminCtx, params.CreateEnterpriseParams{
+		Name:            "test-enterprise",
+		CredentialsName: creds.Name,
+		WebhookSecret:   "test-webhook-secret",
+	})
+	require.ErrorContains(t, err, "cannot manage enterprise runners")
+
+	repos, err := r.ListRepositories(adminCtx)
+	require.NoError(t, err)
+	require.Empty(t, repos)
+}
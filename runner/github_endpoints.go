@@ -24,6 +24,7 @@ func (r *Runner) CreateGithubEndpoint(ctx context.Context, param params.CreateGi
 		return params.GithubEndpoint{}, errors.Wrap(err, "failed to create github endpoint")
 	}
 
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourceGithubEndpoint, ep.Name, nil, ep)
 	return ep, nil
 }
 
@@ -44,11 +45,19 @@ func (r *Runner) DeleteGithubEndpoint(ctx context.Context, name string) error {
 		return runnerErrors.ErrUnauthorized
 	}
 
-	err := r.store.DeleteGithubEndpoint(ctx, name)
+	endpoint, err := r.store.GetGithubEndpoint(ctx, name)
 	if err != nil {
+		if errors.Is(err, runnerErrors.ErrNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get github endpoint")
+	}
+
+	if err := r.store.DeleteGithubEndpoint(ctx, name); err != nil {
 		return errors.Wrap(err, "failed to delete github endpoint")
 	}
 
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourceGithubEndpoint, name, endpoint, nil)
 	return nil
 }
 
@@ -61,10 +70,17 @@ func (r *Runner) UpdateGithubEndpoint(ctx context.Context, name string, param pa
 		return params.GithubEndpoint{}, errors.Wrap(err, "failed to validate github endpoint params")
 	}
 
+	endpoint, err := r.store.GetGithubEndpoint(ctx, name)
+	if err != nil {
+		return params.GithubEndpoint{}, errors.Wrap(err, "failed to get github endpoint")
+	}
+
 	newEp, err := r.store.UpdateGithubEndpoint(ctx, name, param)
 	if err != nil {
 		return params.GithubEndpoint{}, errors.Wrap(err, "failed to update github endpoint")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceGithubEndpoint, name, endpoint, newEp)
 	return newEp, nil
 }
 
@@ -0,0 +1,72 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// ListWorkerHealth returns the liveness information of the worker loops of all
+// pool managers.
+func (r *Runner) ListWorkerHealth(ctx context.Context) (params.WorkersHealth, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	managers, err := r.allPoolManagers()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := params.WorkersHealth{}
+	for _, manager := range managers {
+		ret = append(ret, manager.WorkerHealth()...)
+	}
+	return ret, nil
+}
+
+// allPoolManagers returns the pool managers of all repositories, organizations
+// and enterprises.
+func (r *Runner) allPoolManagers() ([]common.PoolManager, error) {
+	repos, err := r.poolManagerCtrl.GetRepoPoolManagers()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch repo pool managers")
+	}
+
+	orgs, err := r.poolManagerCtrl.GetOrgPoolManagers()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch org pool managers")
+	}
+
+	enterprises, err := r.poolManagerCtrl.GetEnterprisePoolManagers()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch enterprise pool managers")
+	}
+
+	var ret []common.PoolManager
+	for _, managers := range []map[string]common.PoolManager{repos, orgs, enterprises} {
+		for _, manager := range managers {
+			ret = append(ret, manager)
+		}
+	}
+	return ret, nil
+}
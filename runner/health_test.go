@@ -0,0 +1,58 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	runnerCommonMocks "github.com/cloudbase/garm/runner/common/mocks"
+	runnerMocks "github.com/cloudbase/garm/runner/mocks"
+)
+
+func TestListWorkerHealth(t *testing.T) {
+	ctx := auth.GetAdminContext(context.Background())
+
+	repoMgr := runnerCommonMocks.NewPoolManager(t)
+	repoMgr.On("WorkerHealth").Return([]params.WorkerHealth{
+		{Name: "scale_down", EntityID: "repo-id", Healthy: true},
+		{Name: "update_tools", EntityID: "repo-id", Healthy: false},
+	})
+
+	ctrl := runnerMocks.NewPoolManagerController(t)
+	ctrl.On("GetRepoPoolManagers").Return(map[string]common.PoolManager{"repo-id": repoMgr}, nil)
+	ctrl.On("GetOrgPoolManagers").Return(map[string]common.PoolManager{}, nil)
+	ctrl.On("GetEnterprisePoolManagers").Return(map[string]common.PoolManager{}, nil)
+
+	r := &Runner{ctx: ctx, poolManagerCtrl: ctrl}
+
+	workers, err := r.ListWorkerHealth(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 2)
+	require.False(t, workers[1].Healthy)
+}
+
+func TestListWorkerHealthUnauthorized(t *testing.T) {
+	r := &Runner{}
+
+	_, err := r.ListWorkerHealth(context.Background())
+	require.ErrorIs(t, err, runnerErrors.ErrUnauthorized)
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/config"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+func TestInstanceClockSkew(t *testing.T) {
+	r := &Runner{config: config.Config{JWTAuth: config.JWTAuth{InstanceClockSkewTolerance: time.Minute}}}
+
+	_, skewed := r.InstanceClockSkew(params.InstanceUpdateMessage{})
+	require.False(t, skewed)
+
+	now := time.Now()
+	_, skewed = r.InstanceClockSkew(params.InstanceUpdateMessage{Timestamp: &now})
+	require.False(t, skewed)
+
+	ahead := time.Now().Add(10 * time.Minute)
+	skew, skewed := r.InstanceClockSkew(params.InstanceUpdateMessage{Timestamp: &ahead})
+	require.True(t, skewed)
+	require.InDelta(t, (10 * time.Minute).Seconds(), skew.Seconds(), 1)
+
+	behind := time.Now().Add(-10 * time.Minute)
+	skew, skewed = r.InstanceClockSkew(params.InstanceUpdateMessage{Timestamp: &behind})
+	require.True(t, skewed)
+	require.Less(t, skew, time.Duration(0))
+}
+
+func TestAddInstanceStatusMessageRecordsClockSkewOnce(t *testing.T) {
+	ctx := auth.PopulateInstanceContext(context.Background(), params.Instance{Name: "runner-1"})
+
+	store := dbMocks.NewStore(t)
+	store.On("AddInstanceEvent", mock.Anything, "runner-1", params.StatusEvent, params.EventInfo, "installing", common.MaxInstanceEvents).Return(nil)
+	store.On("UpdateInstance", mock.Anything, "runner-1", mock.Anything).Return(params.Instance{}, nil)
+	store.On("ListInstanceEvents", mock.Anything, "runner-1", params.ClockSkewEvent).Return([]params.StatusMessage{}, nil).Once()
+	store.On("AddInstanceEvent", mock.Anything, "runner-1", params.ClockSkewEvent, params.EventWarning, mock.Anything, common.MaxInstanceEvents).Return(nil).Once()
+	store.On("ListInstanceEvents", mock.Anything, "runner-1", params.ClockSkewEvent).Return([]params.StatusMessage{{EventType: params.ClockSkewEvent}}, nil).Once()
+
+	r := &Runner{ctx: context.Background(), store: store}
+
+	ahead := time.Now().Add(time.Hour)
+	update := params.InstanceUpdateMessage{Status: params.RunnerInstalling, Message: "installing", Timestamp: &ahead}
+	require.NoError(t, r.AddInstanceStatusMessage(ctx, update))
+	// The warning is already recorded and is not added again.
+	require.NoError(t, r.AddInstanceStatusMessage(ctx, update))
+}
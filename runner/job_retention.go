@@ -0,0 +1,83 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/util/archive"
+)
+
+// jobRetentionInterval is the interval at which completed jobs that are past the
+// retention are archived and removed.
+const jobRetentionInterval = 5 * time.Minute
+
+// pruneCompletedJobs periodically removes the completed jobs that are older than the
+// configured retention, until the runner context is canceled.
+func (r *Runner) pruneCompletedJobs() {
+	ticker := time.NewTicker(jobRetentionInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.archiveCompletedJobs(r.ctx, time.Now()); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to prune completed jobs")
+		}
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// archiveCompletedJobs removes the jobs that completed before the retention, relative
+// to now. If archiving is enabled, the jobs are archived first, and are only removed
+// once the archive was stored. In a cluster, only one of the controllers does this.
+func (r *Runner) archiveCompletedJobs(ctx context.Context, now time.Time) error {
+	ok, err := r.runsClusterTasks()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	before := now.Add(-r.config.Jobs.CompletedJobsRetention())
+	if r.jobArchive != nil {
+		jobs, err := r.store.ListCompletedJobs(ctx, before)
+		if err != nil {
+			return errors.Wrap(err, "fetching completed jobs")
+		}
+		if len(jobs) > 0 {
+			data, err := archive.EncodeJobs(jobs)
+			if err != nil {
+				return errors.Wrap(err, "encoding completed jobs")
+			}
+			name := archive.Name(now)
+			if err := r.jobArchive.Write(ctx, name, data); err != nil {
+				return errors.Wrap(err, "archiving completed jobs")
+			}
+			slog.InfoContext(ctx, "archived completed jobs", "jobs", len(jobs), "archive", name)
+		}
+	}
+
+	if err := r.store.DeleteCompletedJobs(ctx, before); err != nil {
+		return errors.Wrap(err, "deleting completed jobs")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// ListInstanceLocks returns the locks held on instances by all pool managers.
+func (r *Runner) ListInstanceLocks(ctx context.Context) (params.InstanceLocks, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	managers, err := r.allPoolManagers()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := params.InstanceLocks{}
+	for _, manager := range managers {
+		ret = append(ret, manager.InstanceLocks()...)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].AcquiredAt.Before(ret[j].AcquiredAt)
+	})
+	return ret, nil
+}
+
+// ReleaseInstanceLock force releases the lock held on an instance. The worker that
+// held the lock is not stopped, so this should only be used for locks that were leaked.
+func (r *Runner) ReleaseInstanceLock(ctx context.Context, instanceName string) error {
+	if !auth.IsAdmin(ctx) {
+		return runnerErrors.ErrUnauthorized
+	}
+
+	managers, err := r.allPoolManagers()
+	if err != nil {
+		return err
+	}
+
+	for _, manager := range managers {
+		if manager.ReleaseInstanceLock(instanceName) {
+			slog.InfoContext(
+				ctx, "released instance lock",
+				"runner_name", instanceName,
+				"entity_id", manager.ID())
+			return nil
+		}
+	}
+	return errors.Wrapf(runnerErrors.ErrNotFound, "no lock held on instance %s", instanceName)
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	runnerCommonMocks "github.com/cloudbase/garm/runner/common/mocks"
+	runnerMocks "github.com/cloudbase/garm/runner/mocks"
+)
+
+func TestInstanceLocks(t *testing.T) {
+	ctx := auth.GetAdminContext(context.Background())
+	now := time.Now()
+
+	repoMgr := runnerCommonMocks.NewPoolManager(t)
+	repoMgr.On("ID").Return("repo-id").Maybe()
+	repoMgr.On("InstanceLocks").Return([]params.InstanceLock{
+		{InstanceName: "garm-repo-runner", EntityID: "repo-id", AcquiredAt: now},
+	}).Maybe()
+	repoMgr.On("ReleaseInstanceLock", "garm-org-runner").Return(false).Maybe()
+	repoMgr.On("ReleaseInstanceLock", "garm-missing").Return(false).Maybe()
+
+	orgMgr := runnerCommonMocks.NewPoolManager(t)
+	orgMgr.On("ID").Return("org-id").Maybe()
+	orgMgr.On("InstanceLocks").Return([]params.InstanceLock{
+		{InstanceName: "garm-org-runner", EntityID: "org-id", AcquiredAt: now.Add(-time.Hour), Stale: true},
+	}).Maybe()
+	orgMgr.On("ReleaseInstanceLock", "garm-org-runner").Return(true).Maybe()
+	orgMgr.On("ReleaseInstanceLock", "garm-missing").Return(false).Maybe()
+
+	ctrl := runnerMocks.NewPoolManagerController(t)
+	ctrl.On("GetRepoPoolManagers").Return(map[string]common.PoolManager{"repo-id": repoMgr}, nil)
+	ctrl.On("GetOrgPoolManagers").Return(map[string]common.PoolManager{"org-id": orgMgr}, nil)
+	ctrl.On("GetEnterprisePoolManagers").Return(map[string]common.PoolManager{}, nil)
+
+	r := &Runner{ctx: ctx, poolManagerCtrl: ctrl}
+
+	locks, err := r.ListInstanceLocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	// Oldest locks are listed first.
+	require.Equal(t, "garm-org-runner", locks[0].InstanceName)
+	require.True(t, locks[0].Stale)
+	require.Equal(t, "garm-repo-runner", locks[1].InstanceName)
+
+	require.NoError(t, r.ReleaseInstanceLock(ctx, "garm-org-runner"))
+	err = r.ReleaseInstanceLock(ctx, "garm-missing")
+	require.ErrorIs(t, err, runnerErrors.ErrNotFound)
+}
+
+func TestInstanceLocksUnauthorized(t *testing.T) {
+	r := &Runner{}
+
+	_, err := r.ListInstanceLocks(context.Background())
+	require.ErrorIs(t, err, runnerErrors.ErrUnauthorized)
+
+	err = r.ReleaseInstanceLock(context.Background(), "garm-runner")
+	require.ErrorIs(t, err, runnerErrors.ErrUnauthorized)
+}
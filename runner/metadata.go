@@ -13,8 +13,10 @@ import (
 
 	"github.com/cloudbase/garm-provider-common/defaults"
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
 )
 
 var systemdUnitTemplate = `[Unit]
@@ -28,6 +30,15 @@ WorkingDirectory=/home/{{.RunAsUser}}/actions-runner
 KillMode=process
 KillSignal=SIGTERM
 TimeoutStopSec=5min
+{{- if .HTTPProxy }}
+Environment="HTTP_PROXY={{.HTTPProxy}}" "http_proxy={{.HTTPProxy}}"
+{{- end }}
+{{- if .HTTPSProxy }}
+Environment="HTTPS_PROXY={{.HTTPSProxy}}" "https_proxy={{.HTTPSProxy}}"
+{{- end }}
+{{- if .NoProxy }}
+Environment="NO_PROXY={{.NoProxy}}" "no_proxy={{.NoProxy}}"
+{{- end }}
 
 [Install]
 WantedBy=multi-user.target
@@ -85,6 +96,11 @@ func (r *Runner) GenerateSystemdUnitFile(ctx context.Context, runAsUser string)
 		return nil, errors.Wrap(err, "fetching runner service name")
 	}
 
+	networkSettings, err := r.GetInstanceNetworkSettings(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching network settings")
+	}
+
 	unitTemplate, err := template.New("").Parse(systemdUnitTemplate)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing template")
@@ -97,9 +113,15 @@ func (r *Runner) GenerateSystemdUnitFile(ctx context.Context, runAsUser string)
 	data := struct {
 		ServiceName string
 		RunAsUser   string
+		HTTPProxy   string
+		HTTPSProxy  string
+		NoProxy     string
 	}{
 		ServiceName: serviceName,
 		RunAsUser:   runAsUser,
+		HTTPProxy:   networkSettings.HTTPProxy,
+		HTTPSProxy:  networkSettings.HTTPSProxy,
+		NoProxy:     networkSettings.NoProxy,
 	}
 
 	var unitFile bytes.Buffer
@@ -170,13 +192,91 @@ func (r *Runner) GetInstanceGithubRegistrationToken(ctx context.Context) (string
 		return "", errors.Wrap(err, "setting token_fetched for instance")
 	}
 
-	if err := r.store.AddInstanceEvent(ctx, instance.Name, params.FetchTokenEvent, params.EventInfo, "runner registration token was retrieved"); err != nil {
+	if err := r.store.AddInstanceEvent(ctx, instance.Name, params.FetchTokenEvent, params.EventInfo, "runner registration token was retrieved", common.MaxInstanceEvents); err != nil {
 		return "", errors.Wrap(err, "recording event")
 	}
 
 	return token, nil
 }
 
+// GetInstanceTools returns the runner tools available to the pool manager of the instance.
+// Runners can use this to download the runner archive in environments where the GitHub
+// API is not reachable.
+func (r *Runner) GetInstanceTools(ctx context.Context) ([]commonParams.RunnerApplicationDownload, error) {
+	status := auth.InstanceRunnerStatus(ctx)
+	if status != params.RunnerPending && status != params.RunnerInstalling {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	instance, err := auth.InstanceParams(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to get instance params")
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	poolMgr, err := r.getPoolManagerFromInstance(ctx, instance)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool manager for instance")
+	}
+
+	tools, err := poolMgr.Tools()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching tools")
+	}
+	return tools, nil
+}
+
+// GetInstanceNetworkSettings returns the network settings of the pool the instance belongs to.
+// An empty value is returned if the pool has no network settings.
+func (r *Runner) GetInstanceNetworkSettings(ctx context.Context) (params.NetworkSettings, error) {
+	status := auth.InstanceRunnerStatus(ctx)
+	if status != params.RunnerPending && status != params.RunnerInstalling {
+		return params.NetworkSettings{}, runnerErrors.ErrUnauthorized
+	}
+
+	instance, err := auth.InstanceParams(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to get instance params")
+		return params.NetworkSettings{}, runnerErrors.ErrUnauthorized
+	}
+
+	pool, err := r.store.GetPoolByID(r.ctx, instance.PoolID)
+	if err != nil {
+		return params.NetworkSettings{}, errors.Wrap(err, "fetching pool")
+	}
+	if pool.NetworkSettings == nil {
+		return params.NetworkSettings{}, nil
+	}
+	return *pool.NetworkSettings, nil
+}
+
+// GetInstanceMetadata returns the user defined metadata of the pool the instance belongs to.
+// An empty value is returned if the pool has no metadata.
+func (r *Runner) GetInstanceMetadata(ctx context.Context) (params.InstanceMetadata, error) {
+	status := auth.InstanceRunnerStatus(ctx)
+	if status != params.RunnerPending && status != params.RunnerInstalling {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	instance, err := auth.InstanceParams(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to get instance params")
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	pool, err := r.store.GetPoolByID(r.ctx, instance.PoolID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool")
+	}
+	if pool.InstanceMetadata == nil {
+		return params.InstanceMetadata{}, nil
+	}
+	return pool.InstanceMetadata, nil
+}
+
 func (r *Runner) GetRootCertificateBundle(ctx context.Context) (params.CertificateBundle, error) {
 	instance, err := auth.InstanceParams(ctx)
 	if err != nil {
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner"
+)
+
+const (
+	entityEventCategoryCredentials    = "credentials"
+	entityEventCategoryWebhookInstall = "webhook_install"
+	entityEventCategoryPoolManager    = "pool_manager"
+	entityEventCategoryOther          = "other"
+)
+
+// entityEventCategory classifies an entity event by its reason. Authentication
+// failures are reported as credentials errors, regardless of the operation that
+// failed, as they usually need the same fix.
+func entityEventCategory(event params.EntityEvent) string {
+	msg := strings.ToLower(event.Message)
+	for _, needle := range []string{"unauthorized", "bad credentials", "401"} {
+		if strings.Contains(msg, needle) {
+			return entityEventCategoryCredentials
+		}
+	}
+
+	switch event.EventType {
+	case params.WebhookInstallEvent:
+		return entityEventCategoryWebhookInstall
+	case params.PoolManagerEvent:
+		return entityEventCategoryPoolManager
+	default:
+		return entityEventCategoryOther
+	}
+}
+
+// CollectEntityEventMetric exposes the error events recorded for repositories,
+// organizations and enterprises as metrics, so alerts can be defined on them.
+// The metrics are computed from the events stored in the database, which means
+// they also cover events recorded before GARM was last restarted.
+func CollectEntityEventMetric(ctx context.Context, r *runner.Runner) error {
+	// reset metrics
+	metrics.EntityErrorEvents.Reset()
+	metrics.EntityLastErrorTimestamp.Reset()
+
+	events, err := r.ListEntityEvents(ctx, params.EventError)
+	if err != nil {
+		return err
+	}
+
+	type entityEvents struct {
+		count     int
+		lastError time.Time
+	}
+
+	byLabels := map[[4]string]*entityEvents{}
+	for _, event := range events {
+		key := [4]string{
+			string(event.EntityType),   // label: entity_type
+			event.EntityID,             // label: entity_id
+			event.EntityName,           // label: entity_name
+			entityEventCategory(event), // label: category
+		}
+		entry, ok := byLabels[key]
+		if !ok {
+			entry = &entityEvents{}
+			byLabels[key] = entry
+		}
+		entry.count++
+		if event.CreatedAt.After(entry.lastError) {
+			entry.lastError = event.CreatedAt
+		}
+	}
+
+	for labels, entry := range byLabels {
+		metrics.EntityErrorEvents.WithLabelValues(labels[:]...).Set(float64(entry.count))
+		metrics.EntityLastErrorTimestamp.WithLabelValues(labels[:]...).Set(float64(entry.lastError.Unix()))
+	}
+	return nil
+}
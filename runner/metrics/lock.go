@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/runner"
+)
+
+// CollectLockMetric collects the number and the age of the instance locks held by
+// all pool managers.
+func CollectLockMetric(ctx context.Context, r *runner.Runner) error {
+	locks, err := r.ListInstanceLocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.InstanceLocksHeld.Reset()
+	metrics.InstanceLocksStale.Reset()
+	metrics.InstanceLocksOldestAge.Reset()
+
+	now := time.Now()
+	oldest := map[string]float64{}
+	for _, lock := range locks {
+		metrics.InstanceLocksHeld.WithLabelValues(lock.EntityID).Inc()
+		if lock.Stale {
+			metrics.InstanceLocksStale.WithLabelValues(lock.EntityID).Inc()
+		}
+		oldest[lock.EntityID] = max(oldest[lock.EntityID], now.Sub(lock.AcquiredAt).Seconds())
+	}
+	for entityID, age := range oldest {
+		metrics.InstanceLocksOldestAge.WithLabelValues(entityID).Set(age)
+	}
+	return nil
+}
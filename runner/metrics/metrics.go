@@ -63,6 +63,12 @@ func collectMetrics(ctx context.Context, r *runner.Runner, controllerInfo params
 		return err
 	}
 
+	slog.DebugContext(ctx, "collecting entity event metrics")
+	err = CollectEntityEventMetric(ctx, r)
+	if err != nil {
+		return err
+	}
+
 	slog.DebugContext(ctx, "collecting provider metrics")
 	err = CollectProviderMetric(ctx, r)
 	if err != nil {
@@ -81,6 +87,24 @@ func collectMetrics(ctx context.Context, r *runner.Runner, controllerInfo params
 		return err
 	}
 
+	slog.DebugContext(ctx, "collecting usage metrics")
+	err = CollectUsageMetric(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "collecting worker metrics")
+	err = CollectWorkerMetric(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "collecting lock metrics")
+	err = CollectLockMetric(ctx, r)
+	if err != nil {
+		return err
+	}
+
 	slog.DebugContext(ctx, "collecting health metrics")
 	err = CollectHealthMetric(controllerInfo)
 	if err != nil {
@@ -17,6 +17,8 @@ func CollectPoolMetric(ctx context.Context, r *runner.Runner) error {
 	metrics.PoolMaxRunners.Reset()
 	metrics.PoolMinIdleRunners.Reset()
 	metrics.PoolBootstrapTimeout.Reset()
+	metrics.PoolCapacityWarningThreshold.Reset()
+	metrics.PoolCapacityWarning.Reset()
 
 	pools, err := r.ListAllPools(ctx)
 	if err != nil {
@@ -82,6 +84,14 @@ func CollectPoolMetric(ctx context.Context, r *runner.Runner) error {
 		metrics.PoolBootstrapTimeout.WithLabelValues(
 			pool.ID, // label: id
 		).Set(float64(pool.RunnerBootstrapTimeout))
+
+		metrics.PoolCapacityWarningThreshold.WithLabelValues(
+			pool.ID, // label: id
+		).Set(float64(pool.CapacityThreshold()))
+
+		metrics.PoolCapacityWarning.WithLabelValues(
+			pool.ID, // label: id
+		).Set(metrics.Bool2float64(pool.CapacityWarning))
 	}
 	return nil
 }
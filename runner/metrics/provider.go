@@ -10,6 +10,7 @@ import (
 func CollectProviderMetric(ctx context.Context, r *runner.Runner) error {
 	// reset metrics
 	metrics.ProviderInfo.Reset()
+	metrics.ProviderPaused.Reset()
 
 	providers, err := r.ListProviders(ctx)
 	if err != nil {
@@ -21,6 +22,10 @@ func CollectProviderMetric(ctx context.Context, r *runner.Runner) error {
 			string(provider.ProviderType), // label: type
 			provider.Description,          // label: description
 		).Set(1)
+
+		metrics.ProviderPaused.WithLabelValues(
+			provider.Name, // label: name
+		).Set(metrics.Bool2float64(provider.Paused))
 	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner"
+)
+
+func CollectUsageMetric(ctx context.Context, r *runner.Runner) error {
+	// reset metrics
+	metrics.UsageRunnerMinutes.Reset()
+	metrics.UsageRunners.Reset()
+
+	report, err := r.GetUsageReport(ctx, params.UsageReportParams{})
+	if err != nil {
+		return err
+	}
+	for _, pool := range report.Pools {
+		metrics.UsageRunnerMinutes.WithLabelValues(
+			pool.PoolID,             // label: pool_id
+			pool.ProviderName,       // label: provider
+			pool.EntityID,           // label: entity_id
+			string(pool.EntityType), // label: entity_type
+		).Set(pool.RuntimeMinutes)
+
+		metrics.UsageRunners.WithLabelValues(
+			pool.PoolID,             // label: pool_id
+			pool.ProviderName,       // label: provider
+			pool.EntityID,           // label: entity_id
+			string(pool.EntityType), // label: entity_type
+		).Set(float64(pool.Runners))
+	}
+
+	return collectEfficiencyMetric(ctx, r)
+}
+
+func collectEfficiencyMetric(ctx context.Context, r *runner.Runner) error {
+	metrics.UsageJobsServed.Reset()
+	metrics.UsageFirstJobLatency.Reset()
+	metrics.UsageIdleMinutes.Reset()
+	metrics.UsageBusyMinutes.Reset()
+
+	report, err := r.GetEfficiencyReport(ctx, params.UsageReportParams{})
+	if err != nil {
+		return err
+	}
+	for _, pool := range report.Pools {
+		labels := []string{
+			pool.PoolID,             // label: pool_id
+			pool.ProviderName,       // label: provider
+			pool.EntityID,           // label: entity_id
+			string(pool.EntityType), // label: entity_type
+		}
+		metrics.UsageJobsServed.WithLabelValues(labels...).Set(float64(pool.JobsServed))
+		metrics.UsageFirstJobLatency.WithLabelValues(labels...).Set(pool.AvgFirstJobLatencySeconds)
+		metrics.UsageIdleMinutes.WithLabelValues(labels...).Set(pool.IdleMinutes)
+		metrics.UsageBusyMinutes.WithLabelValues(labels...).Set(pool.BusyMinutes)
+	}
+	return nil
+}
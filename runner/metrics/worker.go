@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/runner"
+)
+
+// CollectWorkerMetric collects the health of the worker loops of all pool managers.
+func CollectWorkerMetric(ctx context.Context, r *runner.Runner) error {
+	workers, err := r.ListWorkerHealth(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.WorkerHealthy.Reset()
+	for _, worker := range workers {
+		metrics.WorkerHealthy.WithLabelValues(
+			worker.EntityID, // label: entity_id
+			worker.Name,     // label: worker
+		).Set(metrics.Bool2float64(worker.Healthy))
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util"
 	"github.com/cloudbase/garm/util/appdefaults"
 )
 
@@ -52,6 +53,15 @@ func (r *Runner) CreateOrganization(ctx context.Context, param params.CreateOrgP
 		return params.Organization{}, runnerErrors.NewConflictError("organization %s already exists", param.Name)
 	}
 
+	entity := params.GithubEntity{
+		Owner:       param.Name,
+		EntityType:  params.GithubEntityTypeOrganization,
+		Credentials: creds,
+	}
+	if err := util.ValidateFineGrainedPATAccess(ctx, entity); err != nil {
+		return params.Organization{}, errors.Wrap(err, "validating credentials")
+	}
+
 	org, err = r.store.CreateOrganization(ctx, param.Name, creds.Name, param.WebhookSecret, param.PoolBalancerType)
 	if err != nil {
 		return params.Organization{}, errors.Wrap(err, "creating organization")
@@ -81,11 +91,14 @@ func (r *Runner) CreateOrganization(ctx context.Context, param params.CreateOrgP
 		}
 		return params.Organization{}, errors.Wrap(err, "starting org pool manager")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourceOrganization, org.ID, nil, org)
 	return org, nil
 }
 
+// ListOrganizations returns the organizations the user in the context can view.
 func (r *Runner) ListOrganizations(ctx context.Context) ([]params.Organization, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 
@@ -97,6 +110,9 @@ func (r *Runner) ListOrganizations(ctx context.Context) ([]params.Organization,
 	var allOrgs []params.Organization
 
 	for _, org := range orgs {
+		if !auth.CanViewEntity(ctx, params.GithubEntity{ID: org.ID, EntityType: params.GithubEntityTypeOrganization}) {
+			continue
+		}
 		poolMgr, err := r.poolManagerCtrl.GetOrgPoolManager(org)
 		if err != nil {
 			org.PoolManagerStatus.IsRunning = false
@@ -112,7 +128,7 @@ func (r *Runner) ListOrganizations(ctx context.Context) ([]params.Organization,
 }
 
 func (r *Runner) GetOrganizationByID(ctx context.Context, orgID string) (params.Organization, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return params.Organization{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -181,6 +197,8 @@ func (r *Runner) DeleteOrganization(ctx context.Context, orgID string, keepWebho
 	if err := r.store.DeleteOrganization(ctx, orgID); err != nil {
 		return errors.Wrapf(err, "removing organization %s", orgID)
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourceOrganization, orgID, org, nil)
 	return nil
 }
 
@@ -192,12 +210,37 @@ func (r *Runner) UpdateOrganization(ctx context.Context, orgID string, param par
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
+	if err := param.Validate(); err != nil {
+		return params.Organization{}, errors.Wrap(err, "validating params")
+	}
+
 	switch param.PoolBalancerType {
 	case params.PoolBalancerTypeRoundRobin, params.PoolBalancerTypePack, params.PoolBalancerTypeNone:
 	default:
 		return params.Organization{}, runnerErrors.NewBadRequestError("invalid pool balancer type: %s", param.PoolBalancerType)
 	}
 
+	if param.RoutingRules != nil {
+		if err := r.validateRoutingRules(*param.RoutingRules); err != nil {
+			return params.Organization{}, errors.Wrap(err, "validating routing rules")
+		}
+	}
+
+	before, err := r.store.GetOrganizationByID(ctx, orgID)
+	if err != nil {
+		return params.Organization{}, errors.Wrap(err, "fetching org")
+	}
+
+	if param.CredentialsName != "" {
+		entity, err := before.GetEntity()
+		if err != nil {
+			return params.Organization{}, errors.Wrap(err, "getting entity")
+		}
+		if err := r.validateEntityCredentials(ctx, entity, param.CredentialsName); err != nil {
+			return params.Organization{}, errors.Wrap(err, "updating org")
+		}
+	}
+
 	org, err := r.store.UpdateOrganization(ctx, orgID, param)
 	if err != nil {
 		return params.Organization{}, errors.Wrap(err, "updating org")
@@ -209,11 +252,13 @@ func (r *Runner) UpdateOrganization(ctx context.Context, orgID string, param par
 	}
 
 	org.PoolManagerStatus = poolMgr.Status()
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceOrganization, org.ID, before, org)
 	return org, nil
 }
 
 func (r *Runner) CreateOrgPool(ctx context.Context, orgID string, param params.CreatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -222,6 +267,10 @@ func (r *Runner) CreateOrgPool(ctx context.Context, orgID string, param params.C
 		return params.Pool{}, errors.Wrap(err, "fetching pool params")
 	}
 
+	if err := r.checkDenyRules(ctx, createPoolParams.ProviderName, createPoolParams.Image, createPoolParams.Flavor); err != nil {
+		return params.Pool{}, err
+	}
+
 	if param.RunnerBootstrapTimeout == 0 {
 		param.RunnerBootstrapTimeout = appdefaults.DefaultRunnerBootstrapTimeout
 	}
@@ -231,16 +280,29 @@ func (r *Runner) CreateOrgPool(ctx context.Context, orgID string, param params.C
 		EntityType: params.GithubEntityTypeOrganization,
 	}
 
+	if err := r.validatePoolRunnerGroup(ctx, entity, createPoolParams.GitHubRunnerGroup); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateNewPoolWithProvider(ctx, createPoolParams); err != nil {
+		return params.Pool{}, err
+	}
+
 	pool, err := r.store.CreateEntityPool(ctx, entity, createPoolParams)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "creating pool")
 	}
 
+	if createPoolParams.DryRun {
+		return pool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourcePool, pool.ID, nil, pool)
 	return pool, nil
 }
 
 func (r *Runner) GetOrgPoolByID(ctx context.Context, orgID, poolID string) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -254,11 +316,16 @@ func (r *Runner) GetOrgPoolByID(ctx context.Context, orgID, poolID string) (para
 		return params.Pool{}, errors.Wrap(err, "fetching pool")
 	}
 
+	pool.ProviderPaused, err = r.isProviderPaused(ctx, pool.ProviderName)
+	if err != nil {
+		return params.Pool{}, err
+	}
+	pool.CapacityWarning = pool.CapacityWarningReached(uint(len(pool.Instances)))
 	return pool, nil
 }
 
 func (r *Runner) DeleteOrgPool(ctx context.Context, orgID, poolID string) error {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return runnerErrors.ErrUnauthorized
 	}
 
@@ -288,11 +355,13 @@ func (r *Runner) DeleteOrgPool(ctx context.Context, orgID, poolID string) error
 	if err := r.store.DeleteEntityPool(ctx, entity, poolID); err != nil {
 		return errors.Wrap(err, "deleting pool")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourcePool, poolID, pool, nil)
 	return nil
 }
 
 func (r *Runner) ListOrgPools(ctx context.Context, orgID string) ([]params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return []params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 	entity := params.GithubEntity{
@@ -303,11 +372,17 @@ func (r *Runner) ListOrgPools(ctx context.Context, orgID string) ([]params.Pool,
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching pools")
 	}
+	if err := r.setPoolsProviderPaused(ctx, pools); err != nil {
+		return nil, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools); err != nil {
+		return nil, err
+	}
 	return pools, nil
 }
 
 func (r *Runner) UpdateOrgPool(ctx context.Context, orgID, poolID string, param params.UpdatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -335,15 +410,77 @@ func (r *Runner) UpdateOrgPool(ctx context.Context, orgID, poolID string, param
 		return params.Pool{}, runnerErrors.NewBadRequestError("min_idle_runners cannot be larger than max_runners")
 	}
 
+	schedule := pool.Schedule
+	if param.Schedule != nil {
+		schedule = param.Schedule
+	}
+	if schedule != nil {
+		if err := schedule.Validate(maxRunners); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid schedule: %s", err)
+		}
+	}
+
+	if err := params.ValidateScaleDownSettings(param.IdleDetectionWindow, param.ScaleDownGracePeriod, param.ScaleDownFactor); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCapacityWarningThreshold(param.CapacityWarningThreshold); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCreateJitter(param.CreateJitter); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := r.validateRunnerPrefix(pool.ProviderName, param.Prefix); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.checkPoolUpdateDenyRules(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
+	if param.GitHubRunnerGroup != nil && *param.GitHubRunnerGroup != pool.GitHubRunnerGroup {
+		if err := r.validatePoolRunnerGroup(ctx, entity, *param.GitHubRunnerGroup); err != nil {
+			return params.Pool{}, err
+		}
+	}
+
+	if param.NetworkSettings != nil {
+		if err := param.NetworkSettings.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid network settings: %s", err)
+		}
+	}
+
+	if param.InstanceMetadata != nil {
+		if err := param.InstanceMetadata.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+		}
+	}
+
+	if err := r.validateWarmImage(pool.ProviderName, param.WarmImage); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateUpdatedPoolWithProvider(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
 	newPool, err := r.store.UpdateEntityPool(ctx, entity, poolID, param)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "updating pool")
 	}
+
+	if param.DryRun {
+		return newPool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourcePool, newPool.ID, pool, newPool)
 	return newPool, nil
 }
 
 func (r *Runner) ListOrgInstances(ctx context.Context, orgID string) ([]params.Instance, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: orgID, EntityType: params.GithubEntityTypeOrganization}) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 
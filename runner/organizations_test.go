@@ -18,12 +18,15 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/database"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	garmTesting "github.com/cloudbase/garm/internal/testing"
@@ -386,6 +389,137 @@ func (s *OrgTestSuite) TestCreateOrgPool() {
 	s.Require().Equal(s.Fixtures.CreatePoolParams.MinIdleRunners, org.Pools[0].MinIdleRunners)
 }
 
+func (s *OrgTestSuite) TestCreateOrgPoolDeniedImage() {
+	_, err := s.Runner.CreateDenyRule(s.Fixtures.AdminContext, params.CreateDenyRuleParams{
+		ProviderName: "test-provider",
+		ImagePattern: "te.*",
+		Reason:       "end of life",
+	})
+	s.Require().Nil(err)
+
+	_, err = s.Runner.CreateOrgPool(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID, s.Fixtures.CreatePoolParams)
+
+	s.Require().Regexp("image \"test\" with flavor \"test\" is denied on provider test-provider by rule .*: end of life", err.Error())
+	page, err := s.Fixtures.Store.ListAuditRecords(s.Fixtures.AdminContext, params.ListAuditRecordsParams{
+		Action:   params.AuditActionDenyRuleMatched,
+		Page:     1,
+		PageSize: 10,
+	})
+	s.Require().Nil(err)
+	s.Require().Len(page.Records, 1)
+}
+
+func (s *OrgTestSuite) TestCreateOrgPoolWithRunnerGroup() {
+	s.Fixtures.PoolMgrCtrlMock.On("GetOrgPoolManager", mock.AnythingOfType("params.Organization")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("GetRunnerGroup", s.Fixtures.AdminContext, "gpu-runners").Return(params.RunnerGroup{ID: 2, Name: "gpu-runners"}, nil)
+	s.Fixtures.CreatePoolParams.GitHubRunnerGroup = "gpu-runners"
+
+	pool, err := s.Runner.CreateOrgPool(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID, s.Fixtures.CreatePoolParams)
+
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+	s.Fixtures.PoolMgrCtrlMock.AssertExpectations(s.T())
+	s.Require().Nil(err)
+	s.Require().Equal("gpu-runners", pool.GitHubRunnerGroup)
+}
+
+func (s *OrgTestSuite) TestCreateOrgPoolMissingRunnerGroup() {
+	s.Fixtures.PoolMgrCtrlMock.On("GetOrgPoolManager", mock.AnythingOfType("params.Organization")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("GetRunnerGroup", s.Fixtures.AdminContext, "gpu-runners").Return(params.RunnerGroup{}, runnerErrors.NewNotFoundError("runner group not found"))
+	s.Fixtures.CreatePoolParams.GitHubRunnerGroup = "gpu-runners"
+
+	_, err := s.Runner.CreateOrgPool(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID, s.Fixtures.CreatePoolParams)
+
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+	s.Fixtures.PoolMgrCtrlMock.AssertExpectations(s.T())
+	var badRequest *runnerErrors.BadRequestError
+	s.Require().ErrorAs(err, &badRequest)
+	s.Require().Equal("runner group \"gpu-runners\" does not exist in GitHub; create it first", err.Error())
+
+	org, err := s.Fixtures.Store.GetOrganizationByID(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID)
+	s.Require().Nil(err)
+	s.Require().Len(org.Pools, 0)
+}
+
+func (s *OrgTestSuite) TestUpdateOrgPoolDeniedFlavor() {
+	entity := params.GithubEntity{
+		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	orgPool, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %s", err))
+	}
+	_, err = s.Runner.CreateDenyRule(s.Fixtures.AdminContext, params.CreateDenyRuleParams{
+		FlavorPattern: "large",
+	})
+	s.Require().Nil(err)
+
+	_, err = s.Runner.UpdateOrgPool(s.Fixtures.AdminContext, entity.ID, orgPool.ID, params.UpdatePoolParams{Flavor: "large"})
+	s.Require().Regexp("with flavor \"large\" is denied", err.Error())
+
+	// Patterns must match the whole flavor name.
+	_, err = s.Runner.UpdateOrgPool(s.Fixtures.AdminContext, entity.ID, orgPool.ID, params.UpdatePoolParams{Flavor: "x-large"})
+	s.Require().Nil(err)
+}
+
+func (s *OrgTestSuite) TestCreateDenyRuleInvalidPattern() {
+	_, err := s.Runner.CreateDenyRule(s.Fixtures.AdminContext, params.CreateDenyRuleParams{
+		ImagePattern: "ubuntu-(",
+	})
+
+	s.Require().Regexp("invalid image_pattern", err.Error())
+}
+
+func (s *OrgTestSuite) TestCreateCapacityReservationConflict() {
+	entity := params.GithubEntity{
+		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	orgPool, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %s", err))
+	}
+	reserve := func(extra uint, start, end time.Duration) error {
+		now := time.Now().UTC()
+		_, err := s.Runner.CreateCapacityReservation(s.Fixtures.AdminContext, params.CreateCapacityReservationParams{
+			PoolID:           orgPool.ID,
+			ExtraIdleRunners: extra,
+			StartTime:        now.Add(start),
+			EndTime:          now.Add(end),
+		})
+		return err
+	}
+
+	// The pool has 2 min idle runners and 4 max runners.
+	s.Require().Nil(reserve(1, time.Hour, 3*time.Hour))
+	s.Require().Nil(reserve(1, 2*time.Hour, 4*time.Hour))
+	err = reserve(1, 150*time.Minute, 160*time.Minute)
+	s.Require().Regexp("would raise min idle runners of pool .* to 5, which is above its max runners \\(4\\)", err.Error())
+	s.Require().Nil(reserve(2, 5*time.Hour, 6*time.Hour))
+
+	reservations, err := s.Runner.ListCapacityReservations(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(reservations, 3)
+}
+
+func (s *OrgTestSuite) TestCreateCapacityReservationInvalidWindow() {
+	now := time.Now().UTC()
+	_, err := s.Runner.CreateCapacityReservation(s.Fixtures.AdminContext, params.CreateCapacityReservationParams{
+		PoolID:           "dummy-pool-id",
+		ExtraIdleRunners: 1,
+		StartTime:        now.Add(time.Hour),
+		EndTime:          now,
+	})
+
+	s.Require().Regexp("end_time must be after start_time", err.Error())
+}
+
+func (s *OrgTestSuite) TestCreateCapacityReservationErrUnauthorized() {
+	_, err := s.Runner.CreateCapacityReservation(context.Background(), params.CreateCapacityReservationParams{})
+
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
 func (s *OrgTestSuite) TestCreateOrgPoolErrUnauthorized() {
 	_, err := s.Runner.CreateOrgPool(context.Background(), "dummy-org-id", s.Fixtures.CreatePoolParams)
 
@@ -401,6 +535,21 @@ func (s *OrgTestSuite) TestCreateOrgPoolFetchPoolParamsFailed() {
 	s.Require().Regexp("fetching pool params: no such provider", err.Error())
 }
 
+func (s *OrgTestSuite) TestCreateOrgPoolInvalidRunnerPrefix() {
+	providerMock := s.Fixtures.Providers["test-provider"].(*runnerCommonMocks.Provider)
+	providerMock.On("AsParams").Return(params.Provider{
+		Name: "test-provider",
+		NameConstraints: params.InstanceNameConstraints{
+			Charset: params.NameCharsetDNSLabel,
+		},
+	})
+	s.Fixtures.CreatePoolParams.Prefix = "___"
+	_, err := s.Runner.CreateOrgPool(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID, s.Fixtures.CreatePoolParams)
+
+	s.Require().NotNil(err)
+	s.Require().Regexp("invalid runner prefix for provider test-provider", err.Error())
+}
+
 func (s *OrgTestSuite) TestGetOrgPoolByID() {
 	entity := params.GithubEntity{
 		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
@@ -487,12 +636,262 @@ func (s *OrgTestSuite) TestListOrgPools() {
 	garmTesting.EqualDBEntityID(s.T(), orgPools, pools)
 }
 
+func (s *OrgTestSuite) TestPauseAndResumeProvider() {
+	providerMock := s.Fixtures.Providers["test-provider"].(*runnerCommonMocks.Provider)
+	providerMock.On("AsParams").Return(params.Provider{Name: "test-provider"})
+	entity := params.GithubEntity{
+		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	orgPool, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %v", err))
+	}
+
+	provider, err := s.Runner.PauseProvider(s.Fixtures.AdminContext, "test-provider", params.PauseProviderParams{Reason: "maintenance"})
+	s.Require().Nil(err)
+	s.Require().True(provider.Paused)
+	s.Require().Equal("maintenance", provider.PauseReason)
+	s.Require().NotNil(provider.PausedAt)
+
+	pool, err := s.Runner.GetOrgPoolByID(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID, orgPool.ID)
+	s.Require().Nil(err)
+	s.Require().True(pool.ProviderPaused)
+
+	providers, err := s.Runner.ListProviders(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(providers, 1)
+	s.Require().True(providers[0].Paused)
+
+	provider, err = s.Runner.ResumeProvider(s.Fixtures.AdminContext, "test-provider")
+	s.Require().Nil(err)
+	s.Require().False(provider.Paused)
+
+	pools, err := s.Runner.ListOrgPools(s.Fixtures.AdminContext, s.Fixtures.StoreOrgs["test-org-1"].ID)
+	s.Require().Nil(err)
+	s.Require().Len(pools, 1)
+	s.Require().False(pools[0].ProviderPaused)
+}
+
+type versionProviderMock struct {
+	*runnerCommonMocks.Provider
+	info params.ProviderVersionInfo
+}
+
+func (v versionProviderMock) GetVersionInfo(_ context.Context) params.ProviderVersionInfo {
+	return v.info
+}
+
+func (s *OrgTestSuite) TestListProvidersVersionInfo() {
+	providerMock := s.Fixtures.Providers["test-provider"].(*runnerCommonMocks.Provider)
+	providerMock.On("AsParams").Return(params.Provider{
+		Name:             "test-provider",
+		InterfaceVersion: "v0.1.1",
+		Capabilities:     []params.ProviderCapability{params.ProviderCapabilityJITConfig},
+	})
+	s.Runner.providers["test-provider"] = versionProviderMock{
+		Provider: providerMock,
+		info: params.ProviderVersionInfo{
+			Version:                    "v0.1.3",
+			SupportedInterfaceVersions: []string{"v0.1.0", "v0.1.1"},
+		},
+	}
+
+	providers, err := s.Runner.ListProviders(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(providers, 1)
+	s.Require().Equal("v0.1.1", providers[0].InterfaceVersion)
+	s.Require().Equal([]params.ProviderCapability{params.ProviderCapabilityJITConfig}, providers[0].Capabilities)
+	s.Require().NotNil(providers[0].VersionInfo)
+	s.Require().Equal("v0.1.3", providers[0].VersionInfo.Version)
+	s.Require().Equal([]string{"v0.1.0", "v0.1.1"}, providers[0].VersionInfo.SupportedInterfaceVersions)
+}
+
+func (s *OrgTestSuite) TestPauseProviderNotFound() {
+	_, err := s.Runner.PauseProvider(s.Fixtures.AdminContext, notExistingProviderName, params.PauseProviderParams{})
+
+	s.Require().Regexp("provider not-existent-provider-name not found", err.Error())
+}
+
+func (s *OrgTestSuite) TestUpdateProviderEnvironment() {
+	env, err := s.Runner.UpdateProviderEnvironment(s.Fixtures.AdminContext, "test-provider", params.UpdateProviderEnvironmentParams{
+		Set: map[string]string{
+			"OS_PASSWORD": "secret",
+			"OS_TOKEN":    "token",
+		},
+	})
+	s.Require().Nil(err)
+	s.Require().Equal([]string{"OS_PASSWORD", "OS_TOKEN"}, env.VariableNames)
+
+	env, err = s.Runner.UpdateProviderEnvironment(s.Fixtures.AdminContext, "test-provider", params.UpdateProviderEnvironmentParams{
+		Set:   map[string]string{"OS_PASSWORD": "rotated"},
+		Unset: []string{"OS_TOKEN"},
+	})
+	s.Require().Nil(err)
+	s.Require().Equal([]string{"OS_PASSWORD"}, env.VariableNames)
+
+	stored, err := s.Fixtures.Store.GetProviderEnvironment(s.Fixtures.AdminContext, "test-provider")
+	s.Require().Nil(err)
+	s.Require().Equal([]string{"OS_PASSWORD=rotated"}, stored.Environ())
+}
+
+func (s *OrgTestSuite) TestUpdateProviderEnvironmentReservedName() {
+	_, err := s.Runner.UpdateProviderEnvironment(s.Fixtures.AdminContext, "test-provider", params.UpdateProviderEnvironmentParams{
+		Set: map[string]string{"GARM_COMMAND": "DeleteInstance"},
+	})
+
+	s.Require().Regexp("environment variable GARM_COMMAND uses the reserved GARM prefix", err.Error())
+}
+
+func (s *OrgTestSuite) TestGetProviderEnvironmentNotFound() {
+	_, err := s.Runner.GetProviderEnvironment(s.Fixtures.AdminContext, notExistingProviderName)
+
+	s.Require().Regexp("provider not-existent-provider-name not found", err.Error())
+}
+
+func (s *OrgTestSuite) TestBulkUpdatePoolTags() {
+	entity := params.GithubEntity{
+		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	s.Fixtures.CreatePoolParams.Tags = []string{"linux", "gpu"}
+	gpuPool, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %v", err))
+	}
+	s.Fixtures.CreatePoolParams.Tags = []string{"linux"}
+	s.Fixtures.CreatePoolParams.Image = "test-cpu"
+	if _, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams); err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %v", err))
+	}
+	orgID, err := uuid.Parse(entity.ID)
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot parse org ID: %v", err))
+	}
+	_, err = s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, params.Job{
+		ID:     1,
+		Name:   "build",
+		Status: string(params.JobStatusQueued),
+		Labels: []string{"linux", "gpu"},
+		OrgID:  &orgID,
+	})
+	if err != nil {
+		s.FailNow(fmt.Sprintf("cannot create job: %v", err))
+	}
+
+	param := params.BulkUpdatePoolTagsParams{
+		Operation: params.PoolTagOperationRename,
+		Tag:       "GPU",
+		NewTag:    "nvidia-gpu",
+		DryRun:    true,
+	}
+	result, err := s.Runner.BulkUpdatePoolTags(s.Fixtures.AdminContext, entity.ID, param)
+	s.Require().Nil(err)
+	s.Require().True(result.DryRun)
+	s.Require().Len(result.Pools, 1)
+	s.Require().Equal(gpuPool.ID, result.Pools[0].PoolID)
+	s.Require().ElementsMatch([]string{"linux", "nvidia-gpu"}, result.Pools[0].NewTags)
+	s.Require().Len(result.Jobs, 1)
+	s.Require().Equal(int64(1), result.Jobs[0].JobID)
+	s.Require().True(result.Jobs[0].MatchedBefore)
+	s.Require().False(result.Jobs[0].MatchesAfter)
+
+	pool, err := s.Fixtures.Store.GetPoolByID(s.Fixtures.AdminContext, gpuPool.ID)
+	s.Require().Nil(err)
+	s.Require().ElementsMatch([]string{"linux", "gpu"}, []string{pool.Tags[0].Name, pool.Tags[1].Name})
+
+	param.DryRun = false
+	result, err = s.Runner.BulkUpdatePoolTags(s.Fixtures.AdminContext, entity.ID, param)
+	s.Require().Nil(err)
+	s.Require().False(result.DryRun)
+
+	pool, err = s.Fixtures.Store.GetPoolByID(s.Fixtures.AdminContext, gpuPool.ID)
+	s.Require().Nil(err)
+	s.Require().ElementsMatch([]string{"linux", "nvidia-gpu"}, []string{pool.Tags[0].Name, pool.Tags[1].Name})
+}
+
+func (s *OrgTestSuite) TestBulkUpdatePoolTagsLeavesPoolWithoutTags() {
+	entity := params.GithubEntity{
+		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	if _, err := s.Fixtures.Store.CreateEntityPool(s.Fixtures.AdminContext, entity, s.Fixtures.CreatePoolParams); err != nil {
+		s.FailNow(fmt.Sprintf("cannot create org pool: %v", err))
+	}
+
+	_, err := s.Runner.BulkUpdatePoolTags(s.Fixtures.AdminContext, entity.ID, params.BulkUpdatePoolTagsParams{
+		Operation: params.PoolTagOperationRemove,
+		Tag:       "arm64-linux-runner",
+	})
+
+	s.Require().Regexp("operation would leave pool .* without tags", err.Error())
+}
+
+func (s *OrgTestSuite) TestBulkUpdatePoolTagsAddRequiresFilter() {
+	_, err := s.Runner.BulkUpdatePoolTags(s.Fixtures.AdminContext, "", params.BulkUpdatePoolTagsParams{
+		Operation: params.PoolTagOperationAdd,
+		Tag:       "linux",
+	})
+
+	s.Require().Regexp("a filter is required when adding a tag to all pools", err.Error())
+}
+
 func (s *OrgTestSuite) TestListOrgPoolsErrUnauthorized() {
 	_, err := s.Runner.ListOrgPools(context.Background(), "dummy-org-id")
 
 	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
 }
 
+// grantedContext returns the context of a new user that has the given role on a single
+// organization.
+func (s *OrgTestSuite) grantedContext(username string, role params.UserRole, orgID string) context.Context {
+	user := garmTesting.CreateGARMTestUser(s.Fixtures.AdminContext, username, s.Fixtures.Store, s.T())
+	_, err := s.Fixtures.Store.CreateRoleGrant(s.Fixtures.AdminContext, user.Username, params.CreateRoleGrantParams{
+		Role:       role,
+		EntityType: params.GithubEntityTypeOrganization,
+		EntityID:   orgID,
+	})
+	s.Require().Nil(err)
+	user, err = s.Fixtures.Store.GetUser(s.Fixtures.AdminContext, user.Username)
+	s.Require().Nil(err)
+	return auth.PopulateContext(context.Background(), user, nil)
+}
+
+func (s *OrgTestSuite) TestOrgAccessWithViewerGrant() {
+	s.Fixtures.PoolMgrCtrlMock.On("GetOrgPoolManager", mock.AnythingOfType("params.Organization")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("Status").Return(params.PoolManagerStatus{IsRunning: true}, nil)
+	org := s.Fixtures.StoreOrgs["test-org-1"]
+	ctx := s.grantedContext("viewer", params.UserRoleViewer, org.ID)
+
+	orgs, err := s.Runner.ListOrganizations(ctx)
+	s.Require().Nil(err)
+	s.Require().Len(orgs, 1)
+	s.Require().Equal(org.ID, orgs[0].ID)
+
+	_, err = s.Runner.ListOrgPools(ctx, org.ID)
+	s.Require().Nil(err)
+	_, err = s.Runner.ListOrgPools(ctx, s.Fixtures.StoreOrgs["test-org-2"].ID)
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+	_, err = s.Runner.CreateOrgPool(ctx, org.ID, s.Fixtures.CreatePoolParams)
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+	_, err = s.Runner.UpdateOrganization(ctx, org.ID, params.UpdateEntityParams{})
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
+func (s *OrgTestSuite) TestOrgPoolsWithOperatorGrant() {
+	org := s.Fixtures.StoreOrgs["test-org-1"]
+	ctx := s.grantedContext("operator", params.UserRoleOperator, org.ID)
+
+	pool, err := s.Runner.CreateOrgPool(ctx, org.ID, s.Fixtures.CreatePoolParams)
+	s.Require().Nil(err)
+	_, err = s.Runner.UpdatePoolByID(ctx, pool.ID, s.Fixtures.UpdatePoolParams)
+	s.Require().Nil(err)
+	s.Require().Nil(s.Runner.DeletePoolByID(ctx, pool.ID))
+
+	_, err = s.Runner.CreateOrgPool(ctx, s.Fixtures.StoreOrgs["test-org-2"].ID, s.Fixtures.CreatePoolParams)
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
 func (s *OrgTestSuite) TestUpdateOrgPool() {
 	entity := params.GithubEntity{
 		ID:         s.Fixtures.StoreOrgs["test-org-1"].ID,
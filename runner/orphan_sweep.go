@@ -0,0 +1,175 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// ListOrphanedInstances asks a provider for all the instances created by this controller
+// and returns the ones GARM has no record of. Nothing is removed.
+func (r *Runner) ListOrphanedInstances(ctx context.Context, providerName string) (params.OrphanSweep, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.OrphanSweep{}, runnerErrors.ErrUnauthorized
+	}
+	return r.sweepOrphanedInstances(ctx, providerName, true)
+}
+
+// CleanupOrphanedInstances removes the instances of a provider that GARM has no record of.
+// Instances that belong to a pool of another provider are reported, but never removed.
+func (r *Runner) CleanupOrphanedInstances(ctx context.Context, providerName string, param params.CleanupOrphanedInstancesParams) (params.OrphanSweep, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.OrphanSweep{}, runnerErrors.ErrUnauthorized
+	}
+	return r.sweepOrphanedInstances(ctx, providerName, param.DryRun)
+}
+
+func (r *Runner) getInstanceSweepProvider(providerName string) (common.Provider, common.InstanceSweepProvider, error) {
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return nil, nil, runnerErrors.NewNotFoundError("provider %s not found", providerName)
+	}
+	sweeper, ok := provider.(common.InstanceSweepProvider)
+	if !ok || !sweeper.SupportsInstanceSweep() {
+		return nil, nil, runnerErrors.NewBadRequestError("provider %s does not support listing all controller instances", providerName)
+	}
+	return provider, sweeper, nil
+}
+
+func (r *Runner) sweepOrphanedInstances(ctx context.Context, providerName string, dryRun bool) (params.OrphanSweep, error) {
+	provider, sweeper, err := r.getInstanceSweepProvider(providerName)
+	if err != nil {
+		return params.OrphanSweep{}, err
+	}
+
+	// The provider is listed before the database. Instance records are created before
+	// the provider is asked to create an instance, and removed only after the provider
+	// removed it, so an instance that is being created or removed is never flagged.
+	providerInstances, err := sweeper.ListControllerInstances(ctx)
+	if err != nil {
+		return params.OrphanSweep{}, errors.Wrap(err, "listing provider instances")
+	}
+
+	pools, err := r.store.ListAllPools(ctx)
+	if err != nil {
+		return params.OrphanSweep{}, errors.Wrap(err, "fetching pools")
+	}
+	poolProviders := make(map[string]string, len(pools))
+	for _, pool := range pools {
+		poolProviders[pool.ID] = pool.ProviderName
+	}
+
+	instances, err := r.store.ListAllInstances(ctx)
+	if err != nil {
+		return params.OrphanSweep{}, errors.Wrap(err, "fetching instances")
+	}
+	instancePools := make(map[string]string, len(instances))
+	for _, instance := range instances {
+		instancePools[instance.Name] = instance.PoolID
+	}
+
+	controllerInfo, err := r.store.ControllerInfo()
+	if err != nil {
+		return params.OrphanSweep{}, errors.Wrap(err, "fetching controller info")
+	}
+
+	sweep := params.OrphanSweep{
+		ProviderName: providerName,
+		DryRun:       dryRun,
+		Instances:    []params.OrphanedInstance{},
+		SweptAt:      time.Now().UTC(),
+	}
+	for _, providerInstance := range providerInstances {
+		orphan := params.OrphanedInstance{
+			ProviderID: providerInstance.ProviderID,
+			Name:       providerInstance.Name,
+			Status:     providerInstance.Status,
+		}
+		poolID, ok := instancePools[providerInstance.Name]
+		switch {
+		case !ok:
+			orphan.Reason = params.OrphanReasonNoInstanceRecord
+		case poolProviders[poolID] != providerName:
+			orphan.Reason = params.OrphanReasonProviderMismatch
+		default:
+			continue
+		}
+
+		if !dryRun && orphan.Reason == params.OrphanReasonNoInstanceRecord {
+			identifier := orphan.ProviderID
+			if identifier == "" {
+				identifier = orphan.Name
+			}
+			deleteParams := common.DeleteInstanceParams{
+				DeleteInstanceV011: common.DeleteInstanceV011Params{
+					ProviderBaseParams: common.ProviderBaseParams{
+						ControllerInfo: controllerInfo,
+					},
+				},
+			}
+			if err := provider.DeleteInstance(ctx, identifier, deleteParams); err != nil {
+				orphan.Error = err.Error()
+			} else {
+				orphan.Removed = true
+			}
+		}
+		sweep.Instances = append(sweep.Instances, orphan)
+	}
+	sort.Slice(sweep.Instances, func(i, j int) bool {
+		return sweep.Instances[i].Name < sweep.Instances[j].Name
+	})
+	return sweep, nil
+}
+
+// runOrphanSweeps periodically looks for orphaned instances in all the providers that
+// support listing the instances of this controller. Orphaned instances are only logged,
+// unless cleanup is set.
+func (r *Runner) runOrphanSweeps(interval time.Duration, cleanup bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+		for name, provider := range r.providers {
+			if sweeper, ok := provider.(common.InstanceSweepProvider); !ok || !sweeper.SupportsInstanceSweep() {
+				continue
+			}
+			sweep, err := r.sweepOrphanedInstances(r.ctx, name, !cleanup)
+			if err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to look for orphaned instances", "provider", name)
+				continue
+			}
+			for _, orphan := range sweep.Instances {
+				slog.WarnContext(
+					r.ctx, "found orphaned instance",
+					"provider", name, "runner_name", orphan.Name, "provider_id", orphan.ProviderID,
+					"reason", orphan.Reason, "removed", orphan.Removed, "error", orphan.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+type sweepProvider struct {
+	*mocks.Provider
+	instances []commonParams.ProviderInstance
+}
+
+func (s *sweepProvider) SupportsInstanceSweep() bool {
+	return true
+}
+
+func (s *sweepProvider) ListControllerInstances(_ context.Context) ([]commonParams.ProviderInstance, error) {
+	return s.instances, nil
+}
+
+func TestOrphanedInstances(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+	_, err = db.InitController()
+	require.Nil(t, err)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	org, err := db.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+	entity := params.GithubEntity{ID: org.ID, EntityType: params.GithubEntityTypeOrganization}
+	for providerName, instanceName := range map[string]string{"test-provider": "known-instance", "other-provider": "other-instance"} {
+		pool, err := db.CreateEntityPool(adminCtx, entity, params.CreatePoolParams{
+			ProviderName: providerName,
+			MaxRunners:   4,
+			Image:        "test-image",
+			Flavor:       "test-flavor",
+			OSType:       "linux",
+			Tags:         []string{providerName},
+		})
+		require.Nil(t, err)
+		_, err = db.CreateInstance(adminCtx, pool.ID, params.CreateInstanceParams{Name: instanceName, OSType: "linux"})
+		require.Nil(t, err)
+	}
+
+	providerMock := mocks.NewProvider(t)
+	provider := &sweepProvider{
+		Provider: providerMock,
+		instances: []commonParams.ProviderInstance{
+			{ProviderID: "id-1", Name: "known-instance"},
+			{ProviderID: "id-2", Name: "other-instance"},
+			{ProviderID: "id-3", Name: "orphaned-instance"},
+			{Name: "failing-instance"},
+		},
+	}
+	r := &Runner{
+		ctx:   adminCtx,
+		store: db,
+		providers: map[string]common.Provider{
+			"test-provider":  provider,
+			"plain-provider": mocks.NewProvider(t),
+		},
+	}
+
+	_, err = r.ListOrphanedInstances(context.Background(), "test-provider")
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+
+	_, err = r.ListOrphanedInstances(adminCtx, "plain-provider")
+	var badRequest *runnerErrors.BadRequestError
+	require.True(t, errors.As(err, &badRequest), "expected bad request error, got %v", err)
+
+	sweep, err := r.ListOrphanedInstances(adminCtx, "test-provider")
+	require.Nil(t, err)
+	require.True(t, sweep.DryRun)
+	require.Len(t, sweep.Instances, 3)
+	require.Equal(t, "failing-instance", sweep.Instances[0].Name)
+	require.Equal(t, params.OrphanReasonNoInstanceRecord, sweep.Instances[0].Reason)
+	require.Equal(t, "orphaned-instance", sweep.Instances[1].Name)
+	require.Equal(t, params.OrphanReasonNoInstanceRecord, sweep.Instances[1].Reason)
+	require.Equal(t, "other-instance", sweep.Instances[2].Name)
+	require.Equal(t, params.OrphanReasonProviderMismatch, sweep.Instances[2].Reason)
+
+	providerMock.On("DeleteInstance", mock.Anything, "id-3", mock.Anything).Return(nil).Once()
+	providerMock.On("DeleteInstance", mock.Anything, "failing-instance", mock.Anything).Return(errors.New("boom")).Once()
+
+	sweep, err = r.CleanupOrphanedInstances(adminCtx, "test-provider", params.CleanupOrphanedInstancesParams{})
+	require.Nil(t, err)
+	require.False(t, sweep.DryRun)
+	require.False(t, sweep.Instances[0].Removed)
+	require.Equal(t, "boom", sweep.Instances[0].Error)
+	require.True(t, sweep.Instances[1].Removed)
+	require.False(t, sweep.Instances[2].Removed)
+}
@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// handledByAutoscaler returns true if runners for the job are created by the queue
+// depth autoscaler. This is the case for jobs queued within the autoscaler window,
+// if all the pools that match the job have the autoscaler enabled.
+func handledByAutoscaler(job params.Job, pools []params.Pool) bool {
+	if len(pools) == 0 || time.Since(job.CreatedAt) >= common.AutoscaleQueueWindow {
+		return false
+	}
+	for _, pool := range pools {
+		if !pool.AutoscaleEnabled() {
+			return false
+		}
+	}
+	return true
+}
+
+// autoscaleState is used to assign queued jobs to a pool during an autoscaler run.
+type autoscaleState struct {
+	pool params.Pool
+	// free is the number of idle or pending runners that can still take a job.
+	free int
+	// capacity is the number of runners that can still be created in the pool.
+	capacity int
+	// required is the number of new runners needed for the jobs assigned to the pool.
+	required int
+}
+
+func (r *basePoolManager) getAutoscaleState(pool params.Pool, states map[string]*autoscaleState) (*autoscaleState, error) {
+	if state, ok := states[pool.ID]; ok {
+		return state, nil
+	}
+
+	instances, err := r.store.ListPoolInstances(r.ctx, pool.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing pool instances")
+	}
+	state := &autoscaleState{
+		pool:     pool,
+		capacity: int(pool.MaxRunners) - len(instances),
+	}
+	for _, inst := range instances {
+		if inst.RunnerStatus != params.RunnerActive && inst.RunnerStatus != params.RunnerTerminated {
+			state.free++
+		}
+	}
+	states[pool.ID] = state
+	return state, nil
+}
+
+// autoscale scales up pools based on the number of queued jobs that match them. Each
+// job queued within the autoscaler window is assigned to the first pool, by priority,
+// that has an idle or pending runner for it or room for a new runner. The runners
+// a pool needs are then created at once, up to the max burst of the pool. A pool is
+// not scaled up again until its cooldown period has passed.
+func (r *basePoolManager) autoscale() error {
+	queued, err := r.store.ListEntityJobsByStatus(r.ctx, r.entity.EntityType, r.entity.ID, params.JobStatusQueued)
+	if err != nil {
+		return errors.Wrap(err, "listing queued jobs")
+	}
+
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+
+	poolsForLabels := map[string][]params.Pool{}
+	states := map[string]*autoscaleState{}
+	for _, job := range queued {
+		if job.LockedBy != uuid.Nil {
+			continue
+		}
+
+		key := strings.Join(job.Labels, "^")
+		pools, ok := poolsForLabels[key]
+		if !ok {
+			pools, err = r.findPoolsForJobLabels(job.Labels)
+			if err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(
+					r.ctx, "error finding pools matching labels",
+					"job_id", job.ID)
+				continue
+			}
+			sort.SliceStable(pools, func(i, j int) bool {
+				return pools[i].Priority > pools[j].Priority
+			})
+			poolsForLabels[key] = pools
+		}
+		if !handledByAutoscaler(job, pools) {
+			continue
+		}
+
+		for _, pool := range pools {
+			if _, ok := paused[pool.ProviderName]; ok || !pool.Enabled {
+				continue
+			}
+			state, err := r.getAutoscaleState(pool, states)
+			if err != nil {
+				return err
+			}
+			if state.free > 0 {
+				state.free--
+				break
+			}
+			if state.capacity > 0 {
+				state.capacity--
+				state.required++
+				break
+			}
+		}
+	}
+
+	if len(states) == 0 {
+		return nil
+	}
+
+	concurrency, err := r.getJobConcurrency(queued)
+	if err != nil {
+		return errors.Wrap(err, "counting active jobs")
+	}
+
+	toScale := make([]*autoscaleState, 0, len(states))
+	for _, state := range states {
+		toScale = append(toScale, state)
+	}
+	sort.SliceStable(toScale, func(i, j int) bool {
+		return toScale[i].pool.Priority > toScale[j].pool.Priority
+	})
+	for _, state := range toScale {
+		if err := r.autoscalePool(state, concurrency); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to scale up pool",
+				"pool_id", state.pool.ID)
+		}
+	}
+	return nil
+}
+
+func (r *basePoolManager) autoscalePool(state *autoscaleState, concurrency *jobConcurrency) error {
+	pool := state.pool
+	if state.required == 0 {
+		return nil
+	}
+
+	if r.autoscaleLastBurst == nil {
+		r.autoscaleLastBurst = map[string]time.Time{}
+	}
+	cooldown := time.Duration(pool.AutoscaleCooldownPeriod()) * time.Second
+	if lastBurst, ok := r.autoscaleLastBurst[pool.ID]; ok && time.Since(lastBurst) < cooldown {
+		slog.DebugContext(
+			r.ctx, "pool is in its autoscale cooldown period",
+			"pool_id", pool.ID,
+			"required_runners", state.required)
+		return nil
+	}
+
+	burst := min(state.required, int(pool.AutoscaleMaxBurst))
+	if remaining, limited := concurrency.remaining(); limited {
+		burst = min(burst, int(remaining))
+	}
+	if burst == 0 {
+		slog.DebugContext(
+			r.ctx, "job concurrency limit reached; not scaling up pool",
+			"pool_id", pool.ID)
+		return nil
+	}
+
+	slog.InfoContext(
+		r.ctx, "scaling up pool based on queue depth",
+		"pool_id", pool.ID,
+		"required_runners", state.required,
+		"burst", burst)
+	r.autoscaleLastBurst[pool.ID] = time.Now()
+	for i := 0; i < burst; i++ {
+		if err := r.addRunnerToPool(r.ctx, pool, nil); err != nil {
+			return fmt.Errorf("failed to scale up pool %s: %w", pool.ID, err)
+		}
+		concurrency.add()
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+func TestHandledByAutoscaler(t *testing.T) {
+	enabled := params.Pool{ID: "enabled", AutoscaleMaxBurst: 5}
+	disabled := params.Pool{ID: "disabled"}
+
+	tests := []struct {
+		name     string
+		job      params.Job
+		pools    []params.Pool
+		expected bool
+	}{
+		{
+			name:     "all pools have the autoscaler enabled",
+			job:      params.Job{CreatedAt: time.Now()},
+			pools:    []params.Pool{enabled, enabled},
+			expected: true,
+		},
+		{
+			name:  "one pool has the autoscaler disabled",
+			job:   params.Job{CreatedAt: time.Now()},
+			pools: []params.Pool{enabled, disabled},
+		},
+		{
+			name: "no matching pools",
+			job:  params.Job{CreatedAt: time.Now()},
+		},
+		{
+			name:  "job is older than the autoscaler window",
+			job:   params.Job{CreatedAt: time.Now().Add(-common.AutoscaleQueueWindow)},
+			pools: []params.Pool{enabled},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := handledByAutoscaler(tc.job, tc.pools); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetAutoscaleState(t *testing.T) {
+	pool := params.Pool{ID: "test-pool", MaxRunners: 5}
+	store := dbMocks.NewStore(t)
+	store.On("ListPoolInstances", mock.Anything, pool.ID).Return([]params.Instance{
+		{RunnerStatus: params.RunnerActive},
+		{RunnerStatus: params.RunnerIdle},
+		{RunnerStatus: params.RunnerPending},
+	}, nil).Once()
+
+	r := &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+	}
+	states := map[string]*autoscaleState{}
+	state, err := r.getAutoscaleState(pool, states)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if state.free != 2 || state.capacity != 2 {
+		t.Fatalf("unexpected autoscale state: %+v", state)
+	}
+
+	// The state is reused for the rest of the autoscaler run.
+	state.free--
+	state, err = r.getAutoscaleState(pool, states)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if state.free != 1 {
+		t.Fatalf("expected cached state, got %+v", state)
+	}
+}
+
+func TestAutoscalePoolSkipped(t *testing.T) {
+	pool := params.Pool{ID: "test-pool", MaxRunners: 10, AutoscaleMaxBurst: 5}
+
+	tests := []struct {
+		name        string
+		lastBurst   map[string]time.Time
+		concurrency *jobConcurrency
+	}{
+		{
+			name:        "pool is in its cooldown period",
+			lastBurst:   map[string]time.Time{pool.ID: time.Now()},
+			concurrency: &jobConcurrency{},
+		},
+		{
+			name:        "concurrency limit reached",
+			concurrency: &jobConcurrency{entityActive: 2, entityLimit: 2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// No runners must be created, so the store must not be used.
+			r := &basePoolManager{
+				ctx:                context.Background(),
+				store:              dbMocks.NewStore(t),
+				autoscaleLastBurst: tc.lastBurst,
+			}
+			state := &autoscaleState{pool: pool, capacity: 10, required: 3}
+			if err := r.autoscalePool(state, tc.concurrency); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.concurrency.entityActive != tc.concurrency.entityLimit {
+				t.Fatalf("unexpected runners recorded: %+v", tc.concurrency)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// applyCapacityReservations raises the min idle runners of the pools that have an active
+// capacity reservation. The min idle runners never go above the max runners of a pool.
+// Once a reservation ends, the pool goes back to its own min idle runners, and the extra
+// idle runners are removed by the scale down loop.
+func (r *basePoolManager) applyCapacityReservations(pools []params.Pool) ([]params.Pool, error) {
+	reservations, err := r.store.ListCapacityReservations(r.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capacity reservations: %w", err)
+	}
+
+	now := time.Now().UTC()
+	extra := map[string]uint{}
+	for _, reservation := range reservations {
+		if reservation.IsActive(now) {
+			extra[reservation.PoolID] += reservation.ExtraIdleRunners
+		}
+	}
+	if len(extra) == 0 {
+		return pools, nil
+	}
+
+	ret := make([]params.Pool, len(pools))
+	for idx, pool := range pools {
+		if reserved, ok := extra[pool.ID]; ok {
+			pool.MinIdleRunners = min(pool.MinIdleRunners+reserved, pool.MaxRunners)
+		}
+		ret[idx] = pool
+	}
+	return ret, nil
+}
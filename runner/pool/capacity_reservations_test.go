@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestApplyCapacityReservations(t *testing.T) {
+	now := time.Now().UTC()
+	pools := []params.Pool{
+		{ID: "first-pool", MinIdleRunners: 1, MaxRunners: 4},
+		{ID: "second-pool", MinIdleRunners: 1, MaxRunners: 4},
+		{ID: "third-pool", MinIdleRunners: 1, MaxRunners: 4},
+	}
+	reservations := []params.CapacityReservation{
+		{PoolID: "first-pool", ExtraIdleRunners: 1, StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour)},
+		{PoolID: "first-pool", ExtraIdleRunners: 1, StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Minute)},
+		// Reservations that ended or have not started yet are ignored.
+		{PoolID: "second-pool", ExtraIdleRunners: 2, StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour)},
+		{PoolID: "second-pool", ExtraIdleRunners: 2, StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)},
+		// Min idle runners never go above max runners.
+		{PoolID: "third-pool", ExtraIdleRunners: 10, StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour)},
+	}
+
+	store := dbMocks.NewStore(t)
+	store.On("ListCapacityReservations", mock.Anything).Return(reservations, nil).Once()
+
+	r := &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+	}
+
+	got, err := r.applyCapacityReservations(pools)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for idx, want := range []uint{3, 1, 4} {
+		if got[idx].MinIdleRunners != want {
+			t.Fatalf("expected %d min idle runners for %s, got %d", want, got[idx].ID, got[idx].MinIdleRunners)
+		}
+	}
+	if pools[0].MinIdleRunners != 1 {
+		t.Fatalf("expected the pools passed in to be left unchanged")
+	}
+}
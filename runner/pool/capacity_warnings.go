@@ -0,0 +1,34 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// checkPoolCapacity records an entity event when the number of runners of a pool goes
+// above its capacity warning threshold, and when it goes back below it. This gives
+// operators a chance to raise the max runners of the pool before jobs start queueing.
+func (r *basePoolManager) checkPoolCapacity(pool params.Pool, runners uint) {
+	if pool.CapacityWarningReached(runners) {
+		if _, loaded := r.capacityWarnings.LoadOrStore(pool.ID, struct{}{}); loaded {
+			return
+		}
+		slog.WarnContext(
+			r.ctx, "pool reached its capacity warning threshold",
+			"pool_id", pool.ID, "runners", runners, "max_runners", pool.MaxRunners)
+		msg := fmt.Sprintf(
+			"pool %s has %d runners, which is at or above %d%% of its max runners (%d)",
+			pool.ID, runners, pool.CapacityThreshold(), pool.MaxRunners)
+		r.addEntityEvent(r.ctx, params.CapacityWarningEvent, params.EventWarning, msg)
+		return
+	}
+
+	if _, loaded := r.capacityWarnings.LoadAndDelete(pool.ID); loaded {
+		msg := fmt.Sprintf(
+			"pool %s has %d runners, which is below %d%% of its max runners (%d)",
+			pool.ID, runners, pool.CapacityThreshold(), pool.MaxRunners)
+		r.addEntityEvent(r.ctx, params.CapacityWarningEvent, params.EventInfo, msg)
+	}
+}
@@ -0,0 +1,40 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+func TestCheckPoolCapacity(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pool := params.Pool{ID: "pool-id", MaxRunners: 10}
+
+	store := dbMocks.NewStore(t)
+	store.On("AddEntityEvent", mock.Anything, entity, params.CapacityWarningEvent, params.EventWarning,
+		"pool pool-id has 8 runners, which is at or above 80% of its max runners (10)",
+		common.MaxEntityEvents).Return(nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.CapacityWarningEvent, params.EventInfo,
+		"pool pool-id has 7 runners, which is below 80% of its max runners (10)",
+		common.MaxEntityEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+
+	// The warning is recorded once when the pool reaches the threshold, and once
+	// when it goes back below it.
+	for _, runners := range []uint{7, 8, 9, 10, 7, 5} {
+		r.checkPoolCapacity(pool, runners)
+	}
+}
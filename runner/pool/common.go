@@ -5,12 +5,14 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v57/github"
 	"github.com/pkg/errors"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
 )
 
 func validateHookRequest(controllerID, baseURL string, allHooks []*github.Hook, req *github.Hook) error {
@@ -65,6 +67,44 @@ func hookToParamsHookInfo(hook *github.Hook) params.HookInfo {
 	}
 }
 
+// webhookDeliveryStatsPageSize is the number of recent deliveries we take into
+// account when computing webhook delivery stats.
+const webhookDeliveryStatsPageSize = 100
+
+func hookDeliveriesToStats(deliveries []*github.HookDelivery) params.HookDeliveryStats {
+	stats := params.HookDeliveryStats{
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	var last *github.HookDelivery
+	for _, delivery := range deliveries {
+		if delivery == nil {
+			continue
+		}
+		stats.RecentDeliveries++
+		// A status code of 0 means GitHub was unable to connect to the
+		// webhook URL.
+		code := delivery.GetStatusCode()
+		if code < http.StatusOK || code >= http.StatusMultipleChoices {
+			stats.FailedDeliveries++
+		}
+		if last == nil || delivery.GetDeliveredAt().After(last.GetDeliveredAt().Time) {
+			last = delivery
+		}
+	}
+
+	if last != nil {
+		stats.LastDeliveryStatus = last.GetStatus()
+		stats.LastDeliveryStatusCode = last.GetStatusCode()
+		if last.DeliveredAt != nil {
+			deliveredAt := last.GetDeliveredAt().UTC()
+			stats.LastDeliveryAt = &deliveredAt
+		}
+	}
+
+	return stats
+}
+
 func (r *basePoolManager) listHooks(ctx context.Context) ([]*github.Hook, error) {
 	opts := github.ListOptions{
 		PerPage: 100,
@@ -86,3 +126,39 @@ func (r *basePoolManager) listHooks(ctx context.Context) ([]*github.Hook, error)
 	}
 	return allHooks, nil
 }
+
+// isHookAccessError returns true if listing hooks failed because the credentials
+// don't have access to the hooks of the entity. listHooks returns a bad request
+// error if GitHub answers with 404.
+func isHookAccessError(err error) bool {
+	var badRequestErr *runnerErrors.BadRequestError
+	if errors.As(err, &badRequestErr) {
+		return true
+	}
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusForbidden
+}
+
+// isRetryableWebhookInstallError returns false for errors that will not go away
+// by simply retrying the install, like an already existing webhook.
+func isRetryableWebhookInstallError(err error) bool {
+	var badRequestErr *runnerErrors.BadRequestError
+	var conflictErr *runnerErrors.ConflictError
+	if errors.As(err, &badRequestErr) || errors.As(err, &conflictErr) {
+		return false
+	}
+	return true
+}
+
+// webhookInstallRetryBackoff returns the time to wait after the given webhook install
+// attempt, before trying again.
+func webhookInstallRetryBackoff(attempt int) time.Duration {
+	backoff := common.PoolWebhookInstallRetryInterval
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= common.WebhookInstallRetryMaxBackoff {
+			return common.WebhookInstallRetryMaxBackoff
+		}
+	}
+	return backoff
+}
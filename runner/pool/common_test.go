@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestHookDeliveriesToStats(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	deliveries := []*github.HookDelivery{
+		{
+			Status:      github.String("OK"),
+			StatusCode:  github.Int(200),
+			DeliveredAt: &github.Timestamp{Time: now.Add(-2 * time.Minute)},
+		},
+		{
+			Status:      github.String("Invalid HTTP Response: 503"),
+			StatusCode:  github.Int(503),
+			DeliveredAt: &github.Timestamp{Time: now},
+		},
+		nil,
+		{
+			Status:      github.String("failed to connect to host"),
+			StatusCode:  github.Int(0),
+			DeliveredAt: &github.Timestamp{Time: now.Add(-1 * time.Minute)},
+		},
+	}
+
+	stats := hookDeliveriesToStats(deliveries)
+	if stats.RecentDeliveries != 3 {
+		t.Fatalf("expected 3 recent deliveries, got %d", stats.RecentDeliveries)
+	}
+	if stats.FailedDeliveries != 2 {
+		t.Fatalf("expected 2 failed deliveries, got %d", stats.FailedDeliveries)
+	}
+	if stats.LastDeliveryStatusCode != 503 {
+		t.Fatalf("expected last delivery status code 503, got %d", stats.LastDeliveryStatusCode)
+	}
+	if stats.LastDeliveryStatus != "Invalid HTTP Response: 503" {
+		t.Fatalf("unexpected last delivery status %q", stats.LastDeliveryStatus)
+	}
+	if stats.LastDeliveryAt == nil || !stats.LastDeliveryAt.Equal(now) {
+		t.Fatalf("expected last delivery at %v, got %v", now, stats.LastDeliveryAt)
+	}
+}
+
+func TestHookDeliveriesToStatsNoDeliveries(t *testing.T) {
+	stats := hookDeliveriesToStats(nil)
+	if stats.RecentDeliveries != 0 || stats.FailedDeliveries != 0 {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+	if stats.LastDeliveryAt != nil {
+		t.Fatalf("expected no last delivery, got %v", stats.LastDeliveryAt)
+	}
+}
+
+func TestWebhookInstallRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: 1 * time.Minute},
+		{attempt: 2, expected: 2 * time.Minute},
+		{attempt: 4, expected: 8 * time.Minute},
+		{attempt: 5, expected: 16 * time.Minute},
+		{attempt: 6, expected: 30 * time.Minute},
+		{attempt: 100, expected: 30 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		if got := webhookInstallRetryBackoff(tc.attempt); got != tc.expected {
+			t.Fatalf("attempt %d: expected backoff %s, got %s", tc.attempt, tc.expected, got)
+		}
+	}
+}
+
+func TestIsRetryableWebhookInstallError(t *testing.T) {
+	if isRetryableWebhookInstallError(errors.Wrap(runnerErrors.NewConflictError("hook already installed"), "validating hook request")) {
+		t.Fatalf("expected conflict error to not be retryable")
+	}
+	if isRetryableWebhookInstallError(errors.Wrap(runnerErrors.ErrBadRequest, "controller webhook url is empty")) {
+		t.Fatalf("expected bad request error to not be retryable")
+	}
+	if !isRetryableWebhookInstallError(errors.Wrap(fmt.Errorf("connection refused"), "listing hooks")) {
+		t.Fatalf("expected transient error to be retryable")
+	}
+}
+
+func TestUpdateWebhookDeliveryStatsWithoutHookAccess(t *testing.T) {
+	for _, statusCode := range []int{http.StatusForbidden, http.StatusNotFound} {
+		resp := &github.Response{Response: &http.Response{StatusCode: statusCode}}
+		cli := mocks.NewGithubClient(t)
+		cli.On("ListEntityHooks", mock.Anything, mock.Anything).Return(
+			nil, resp, &github.ErrorResponse{Response: resp.Response}).Once()
+
+		r := &basePoolManager{
+			ctx:                     context.Background(),
+			entity:                  params.GithubEntity{EntityType: params.GithubEntityTypeRepository},
+			ghcli:                   cli,
+			hookDeliveryStats:       &params.HookDeliveryStats{RecentDeliveries: 1},
+			hookDeliveryStatsHookID: 1,
+		}
+		if err := r.updateWebhookDeliveryStats(); err != nil {
+			t.Fatalf("unexpected error for status %d: %s", statusCode, err)
+		}
+		if r.hookDeliveryStats != nil || r.hookDeliveryStatsHookID != 0 {
+			t.Fatalf("expected hook delivery stats to be cleared for status %d", statusCode)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	concurrencyLimitEntity = "entity"
+	concurrencyLimitGlobal = "global"
+)
+
+// jobConcurrency tracks the number of jobs that count against the concurrency
+// limits of an entity and against the global limit. Jobs that are in progress and
+// queued jobs that are locked (a runner was created for them) are counted.
+type jobConcurrency struct {
+	entityActive uint
+	entityLimit  uint
+	globalActive uint
+	globalLimit  uint
+}
+
+// limitReached returns the name of the limit that prevents us from creating a runner
+// for another job, or an empty string if no limit was reached.
+func (j *jobConcurrency) limitReached() string {
+	if j.entityLimit > 0 && j.entityActive >= j.entityLimit {
+		return concurrencyLimitEntity
+	}
+	if j.globalLimit > 0 && j.globalActive >= j.globalLimit {
+		return concurrencyLimitGlobal
+	}
+	return ""
+}
+
+// remaining returns the number of jobs that can still get a runner before a limit is
+// reached. The second return value is false if no limit is set.
+func (j *jobConcurrency) remaining() (uint, bool) {
+	var ret uint
+	limited := false
+	for _, limit := range []struct{ active, max uint }{
+		{j.entityActive, j.entityLimit},
+		{j.globalActive, j.globalLimit},
+	} {
+		if limit.max == 0 {
+			continue
+		}
+		var left uint
+		if limit.active < limit.max {
+			left = limit.max - limit.active
+		}
+		if !limited || left < ret {
+			ret = left
+		}
+		limited = true
+	}
+	return ret, limited
+}
+
+// add records a new job for which a runner was created.
+func (j *jobConcurrency) add() {
+	j.entityActive++
+	j.globalActive++
+}
+
+func countActiveJobs(inProgress, queued []params.Job) uint {
+	count := uint(len(inProgress))
+	for _, job := range queued {
+		if job.LockedBy != uuid.Nil {
+			count++
+		}
+	}
+	return count
+}
+
+// getJobConcurrency counts the active jobs of the entity and the active jobs across
+// all entities. The counts are only fetched if the respective limit is set. The queued
+// jobs of the entity are passed in, as the caller already fetched them.
+func (r *basePoolManager) getJobConcurrency(entityQueued []params.Job) (*jobConcurrency, error) {
+	concurrency := &jobConcurrency{
+		entityLimit: r.entity.MaxConcurrentJobs,
+		globalLimit: r.maxConcurrentJobs,
+	}
+
+	if concurrency.entityLimit > 0 {
+		inProgress, err := r.store.ListEntityJobsByStatus(r.ctx, r.entity.EntityType, r.entity.ID, params.JobStatusInProgress)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing in progress jobs")
+		}
+		concurrency.entityActive = countActiveJobs(inProgress, entityQueued)
+	}
+
+	if concurrency.globalLimit > 0 {
+		inProgress, err := r.store.ListJobsByStatus(r.ctx, params.JobStatusInProgress)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing in progress jobs")
+		}
+		queued, err := r.store.ListJobsByStatus(r.ctx, params.JobStatusQueued)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing queued jobs")
+		}
+		concurrency.globalActive = countActiveJobs(inProgress, queued)
+	}
+	return concurrency, nil
+}
@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestJobConcurrencyLimitReached(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency jobConcurrency
+		expected    string
+	}{
+		{
+			name:        "no limits",
+			concurrency: jobConcurrency{entityActive: 100, globalActive: 100},
+		},
+		{
+			name:        "entity limit reached",
+			concurrency: jobConcurrency{entityActive: 2, entityLimit: 2, globalActive: 2, globalLimit: 10},
+			expected:    concurrencyLimitEntity,
+		},
+		{
+			name:        "global limit reached",
+			concurrency: jobConcurrency{entityActive: 1, entityLimit: 2, globalActive: 10, globalLimit: 10},
+			expected:    concurrencyLimitGlobal,
+		},
+		{
+			name:        "under limits",
+			concurrency: jobConcurrency{entityActive: 1, entityLimit: 2, globalActive: 9, globalLimit: 10},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.concurrency.limitReached(); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetJobConcurrency(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:                "test-repo-id",
+		EntityType:        params.GithubEntityTypeRepository,
+		MaxConcurrentJobs: 3,
+	}
+	locked := params.Job{ID: 1, Status: string(params.JobStatusQueued), LockedBy: uuid.New()}
+	unlocked := params.Job{ID: 2, Status: string(params.JobStatusQueued)}
+	running := params.Job{ID: 3, Status: string(params.JobStatusInProgress)}
+	otherRunning := params.Job{ID: 4, Status: string(params.JobStatusInProgress)}
+
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityJobsByStatus", mock.Anything, entity.EntityType, entity.ID, params.JobStatusInProgress).Return(
+		[]params.Job{running}, nil)
+	store.On("ListJobsByStatus", mock.Anything, params.JobStatusInProgress).Return(
+		[]params.Job{running, otherRunning}, nil)
+	store.On("ListJobsByStatus", mock.Anything, params.JobStatusQueued).Return(
+		[]params.Job{locked, unlocked}, nil)
+
+	r := &basePoolManager{
+		ctx:               context.Background(),
+		entity:            entity,
+		store:             store,
+		maxConcurrentJobs: 4,
+	}
+	concurrency, err := r.getJobConcurrency([]params.Job{locked, unlocked})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if concurrency.entityActive != 2 || concurrency.globalActive != 3 {
+		t.Fatalf("unexpected active job counts: %+v", concurrency)
+	}
+	if limit := concurrency.limitReached(); limit != "" {
+		t.Fatalf("expected no limit to be reached, got %q", limit)
+	}
+
+	concurrency.add()
+	if limit := concurrency.limitReached(); limit != concurrencyLimitEntity {
+		t.Fatalf("expected entity limit to be reached, got %q", limit)
+	}
+}
+
+func TestJobConcurrencyRemaining(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency jobConcurrency
+		expected    uint
+		limited     bool
+	}{
+		{
+			name:        "no limits",
+			concurrency: jobConcurrency{entityActive: 100, globalActive: 100},
+		},
+		{
+			name:        "entity limit",
+			concurrency: jobConcurrency{entityActive: 1, entityLimit: 3, globalActive: 1},
+			expected:    2,
+			limited:     true,
+		},
+		{
+			name:        "lowest limit wins",
+			concurrency: jobConcurrency{entityActive: 1, entityLimit: 5, globalActive: 9, globalLimit: 10},
+			expected:    1,
+			limited:     true,
+		},
+		{
+			name:        "over the limit",
+			concurrency: jobConcurrency{entityActive: 4, entityLimit: 3, globalActive: 4},
+			expected:    0,
+			limited:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, limited := tc.concurrency.remaining()
+			if got != tc.expected || limited != tc.limited {
+				t.Fatalf("expected (%d, %v), got (%d, %v)", tc.expected, tc.limited, got, limited)
+			}
+		})
+	}
+}
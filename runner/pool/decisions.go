@@ -0,0 +1,35 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// recordJobDecision stores the decision about creating a runner for a queued job on
+// the job. Queued jobs are evaluated every time the consume loop runs, so the decision
+// is only written if it differs from the last one recorded. Failures are logged, as
+// the decision is informational.
+func (r *basePoolManager) recordJobDecision(job params.Job, decision params.JobDecisionType, poolID, reason string, args ...interface{}) {
+	if len(args) > 0 {
+		reason = fmt.Sprintf(reason, args...)
+	}
+	jobDecision := params.JobDecision{
+		Decision:  decision,
+		PoolID:    poolID,
+		Reason:    reason,
+		Timestamp: time.Now().UTC(),
+	}
+	if job.Decision.Equals(&jobDecision) {
+		return
+	}
+
+	if err := r.store.SetJobDecision(r.ctx, job.ID, jobDecision); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			r.ctx, "failed to record job decision",
+			"job_id", job.ID,
+			"decision", decision)
+	}
+}
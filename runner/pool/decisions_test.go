@@ -0,0 +1,32 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestRecordJobDecision(t *testing.T) {
+	store := dbMocks.NewStore(t)
+	store.On("SetJobDecision", mock.Anything, int64(1), mock.MatchedBy(func(decision params.JobDecision) bool {
+		return decision.Decision == params.JobDecisionDeferred && decision.Reason == "entity job concurrency limit reached"
+	})).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+	}
+	job := params.Job{ID: 1}
+	r.recordJobDecision(job, params.JobDecisionDeferred, "", "%s job concurrency limit reached", concurrencyLimitEntity)
+
+	// The same decision is not recorded again.
+	job.Decision = &params.JobDecision{
+		Decision: params.JobDecisionDeferred,
+		Reason:   "entity job concurrency limit reached",
+	}
+	r.recordJobDecision(job, params.JobDecisionDeferred, "", "%s job concurrency limit reached", concurrencyLimitEntity)
+}
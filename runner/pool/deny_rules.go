@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// checkPoolDenyRules returns an error if the image and flavor of the pool are banned by
+// a deny rule. The first time a rule matches a pool, an audit record and an entity event
+// are recorded.
+func (r *basePoolManager) checkPoolDenyRules(pool params.Pool) error {
+	rules, err := r.store.ListDenyRules(r.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list deny rules: %w", err)
+	}
+
+	rule, ok := params.MatchingDenyRule(rules, pool.ProviderName, pool.Image, pool.Flavor)
+	if !ok {
+		r.deniedPools.Delete(pool.ID)
+		return nil
+	}
+
+	msg := fmt.Sprintf(
+		"image %q with flavor %q of pool %s is denied on provider %s by rule %s",
+		pool.Image, pool.Flavor, pool.ID, pool.ProviderName, rule.ID)
+	if rule.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, rule.Reason)
+	}
+
+	if previous, loaded := r.deniedPools.Swap(pool.ID, rule.ID); !loaded || previous != rule.ID {
+		slog.WarnContext(r.ctx, "pool uses a denied image and flavor", "pool_id", pool.ID, "deny_rule_id", rule.ID)
+		record := params.AuditRecord{
+			Action: params.AuditActionDenyRuleMatched,
+			Reason: msg,
+		}
+		if _, err := r.store.CreateAuditRecord(r.ctx, record); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to create audit record")
+		}
+		r.addEntityEvent(r.ctx, params.DenyRuleEvent, params.EventWarning, msg)
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+func TestCheckPoolDenyRules(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pool := params.Pool{ID: "pool-id", ProviderName: "test-provider", Image: "ubuntu-18.04", Flavor: "small"}
+	rule := params.DenyRule{ID: "rule-id", ImagePattern: "ubuntu-18\\.04.*"}
+
+	store := dbMocks.NewStore(t)
+	store.On("ListDenyRules", mock.Anything).Return([]params.DenyRule{rule}, nil).Twice()
+	store.On("CreateAuditRecord", mock.Anything, mock.MatchedBy(func(record params.AuditRecord) bool {
+		return record.Action == params.AuditActionDenyRuleMatched
+	})).Return(params.AuditRecord{}, nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.DenyRuleEvent, params.EventWarning,
+		"image \"ubuntu-18.04\" with flavor \"small\" of pool pool-id is denied on provider test-provider by rule rule-id",
+		common.MaxEntityEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+
+	// The pool is reported only the first time it is denied.
+	for i := 0; i < 2; i++ {
+		if err := r.checkPoolDenyRules(pool); err == nil {
+			t.Fatalf("expected pool to be denied")
+		}
+	}
+
+	store.On("ListDenyRules", mock.Anything).Return([]params.DenyRule{}, nil).Once()
+	if err := r.checkPoolDenyRules(pool); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
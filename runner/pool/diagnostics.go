@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+const (
+	// diagnosticsTimeout is the maximum amount of time we wait for a provider to
+	// return diagnostics for an instance.
+	diagnosticsTimeout = 2 * time.Minute
+	// maxDiagnosticsSize is the maximum size of the diagnostics we record for an
+	// instance. Console logs can be large, and the end of the log is usually the
+	// relevant part, so we only keep the tail of the output.
+	maxDiagnosticsSize = 16 * 1024
+)
+
+// truncateDiagnostics returns the last maxDiagnosticsSize bytes of the output.
+func truncateDiagnostics(output string) string {
+	if len(output) <= maxDiagnosticsSize {
+		return output
+	}
+	return "[truncated]\n" + output[len(output)-maxDiagnosticsSize:]
+}
+
+// captureInstanceDiagnostics asks the provider for diagnostic information about an
+// instance that failed to register as a runner, and records it as a provider operation
+// event of the instance. This is a best effort operation. Providers that don't support
+// diagnostics are skipped.
+func (r *basePoolManager) captureInstanceDiagnostics(instance params.Instance, pool params.Pool) {
+	provider, ok := r.providers[pool.ProviderName]
+	if !ok {
+		return
+	}
+	diagProvider, ok := provider.(common.DiagnosticsProvider)
+	if !ok || !diagProvider.SupportsDiagnostics() {
+		return
+	}
+
+	identifier := instance.ProviderID
+	if identifier == "" {
+		identifier = instance.Name
+	}
+
+	getInstanceParams := common.GetInstanceParams{
+		GetInstanceV011: common.GetInstanceV011Params{
+			ProviderBaseParams: r.getProviderBaseParams(pool),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, diagnosticsTimeout)
+	defer cancel()
+
+	output, err := diagProvider.GetInstanceDiagnostics(ctx, identifier, getInstanceParams)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			r.ctx, "failed to get instance diagnostics",
+			"runner_name", instance.Name)
+		r.recordProviderOperation(instance.Name, params.EventWarning, "failed to get instance diagnostics: %q", err)
+		return
+	}
+
+	output = truncateDiagnostics(output)
+	// Instance events are removed together with the instance, so we also log the
+	// diagnostics, to have a record of them after the instance is gone.
+	slog.WarnContext(
+		r.ctx, "captured diagnostics of timed out instance",
+		"runner_name", instance.Name,
+		"pool_id", pool.ID,
+		"diagnostics", output)
+	r.recordProviderOperation(instance.Name, params.EventWarning, "instance diagnostics:\n%s", output)
+}
@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+type diagnosticsProvider struct {
+	*mocks.Provider
+	supported bool
+	output    string
+}
+
+func (d *diagnosticsProvider) SupportsDiagnostics() bool {
+	return d.supported
+}
+
+func (d *diagnosticsProvider) GetInstanceDiagnostics(_ context.Context, _ string, _ common.GetInstanceParams) (string, error) {
+	return d.output, nil
+}
+
+func TestTruncateDiagnostics(t *testing.T) {
+	short := "boot failed"
+	if got := truncateDiagnostics(short); got != short {
+		t.Fatalf("expected %q, got %q", short, got)
+	}
+
+	long := strings.Repeat("a", maxDiagnosticsSize) + "end of log"
+	got := truncateDiagnostics(long)
+	if !strings.HasPrefix(got, "[truncated]\n") || !strings.HasSuffix(got, "end of log") {
+		t.Fatalf("expected the tail of the output to be kept, got %q", got[:32])
+	}
+	if len(got) != maxDiagnosticsSize+len("[truncated]\n") {
+		t.Fatalf("unexpected length of truncated output: %d", len(got))
+	}
+}
+
+func TestCaptureInstanceDiagnostics(t *testing.T) {
+	instance := params.Instance{Name: "garm-test", ProviderID: "provider-id"}
+	pool := params.Pool{ID: "pool-id", ProviderName: "test-provider"}
+
+	store := dbMocks.NewStore(t)
+	store.On("AddInstanceEvent", mock.Anything, instance.Name, params.ProviderOperationEvent, params.EventWarning, "instance diagnostics:\nkernel panic", common.MaxInstanceEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+		providers: map[string]common.Provider{
+			pool.ProviderName: &diagnosticsProvider{supported: true, output: "kernel panic"},
+		},
+	}
+	r.captureInstanceDiagnostics(instance, pool)
+}
+
+func TestCaptureInstanceDiagnosticsNotSupported(t *testing.T) {
+	instance := params.Instance{Name: "garm-test"}
+	pool := params.Pool{ID: "pool-id", ProviderName: "test-provider"}
+
+	// No events are expected to be recorded.
+	store := dbMocks.NewStore(t)
+	r := &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+		providers: map[string]common.Provider{
+			pool.ProviderName: &diagnosticsProvider{supported: false, output: "ignored"},
+		},
+	}
+	r.captureInstanceDiagnostics(instance, pool)
+}
@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+
+	"github.com/cloudbase/garm/params"
+	"golang.org/x/sync/errgroup"
+)
+
+// isDrainCandidate returns true if the instance can be removed from a draining pool.
+// Runners that are still executing a job are left alone until the job finishes.
+func isDrainCandidate(inst params.Instance) bool {
+	return inst.Status == commonParams.InstanceRunning && inst.RunnerStatus == params.RunnerIdle
+}
+
+// drainOnePool removes all idle runners of a draining pool. Unlike a regular scale
+// down, the idle detection window, the grace period and the min idle runners of the
+// pool are ignored.
+func (r *basePoolManager) drainOnePool(ctx context.Context, pool params.Pool) error {
+	existingInstances, err := r.store.ListPoolInstances(r.ctx, pool.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list instances for pool %s: %w", pool.ID, err)
+	}
+
+	g, _ := errgroup.WithContext(ctx)
+	for _, inst := range existingInstances {
+		if !isDrainCandidate(inst) {
+			continue
+		}
+		instanceToDelete := inst
+
+		fence, ok := r.keyMux.TryLock(instanceToDelete.Name)
+		if !ok {
+			slog.DebugContext(
+				ctx, "instance is locked, skipping drain",
+				"runner_name", instanceToDelete.Name)
+			continue
+		}
+		defer r.keyMux.Unlock(instanceToDelete.Name, fence)
+
+		g.Go(func() error {
+			slog.InfoContext(
+				ctx, "removing idle runner from draining pool",
+				"runner_name", instanceToDelete.Name,
+				"pool_id", pool.ID)
+			if err := r.DeleteRunner(instanceToDelete, false, false); err != nil {
+				return fmt.Errorf("failed to delete instance %s: %w", instanceToDelete.ID, err)
+			}
+			return nil
+		})
+	}
+
+	if err := r.waitForErrorGroupOrContextCancelled(g); err != nil {
+		return fmt.Errorf("failed to drain pool %s: %w", pool.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"testing"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestIsDrainCandidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance params.Instance
+		expected bool
+	}{
+		{
+			name:     "idle runner",
+			instance: params.Instance{Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle},
+			expected: true,
+		},
+		{
+			name:     "runner executing a job",
+			instance: params.Instance{Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive},
+		},
+		{
+			name:     "runner still installing",
+			instance: params.Instance{Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerInstalling},
+		},
+		{
+			name:     "instance pending delete",
+			instance: params.Instance{Status: commonParams.InstancePendingDelete, RunnerStatus: params.RunnerIdle},
+		},
+	}
+
+	for _, tc := range tests {
+		if got := isDrainCandidate(tc.instance); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
+)
+
+// ListForgeRunners returns the runners GitHub has registered for the entity, joined
+// with the instances GARM recorded for it.
+func (r *basePoolManager) ListForgeRunners(ctx context.Context) ([]params.ForgeRunner, error) {
+	runners, err := r.GetGithubRunners()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching github runners")
+	}
+
+	instances, err := r.store.ListEntityInstances(ctx, r.entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching instances")
+	}
+
+	return correlateForgeRunners(runners, instances, r.managedControllerIDs()...), nil
+}
+
+func setForgeRunnerInstance(runner *params.ForgeRunner, instance params.Instance) {
+	runner.InstanceID = instance.ID
+	runner.PoolID = instance.PoolID
+	runner.InstanceStatus = instance.Status
+	runner.RunnerStatus = instance.RunnerStatus
+}
+
+// correlateForgeRunners matches runners registered in GitHub with instances recorded
+// by GARM, by name. Runners without a matching instance are flagged depending on the
+// controller label they carry. Instances without a matching runner are flagged as
+// pending if they are still being set up and as missing otherwise. Instances that are
+// being removed, or whose runner already finished its job, are not expected to be in
+// GitHub and are left out.
+func correlateForgeRunners(ghRunners []*github.Runner, instances []params.Instance, controllerIDs ...string) []params.ForgeRunner {
+	instancesByName := make(map[string]params.Instance, len(instances))
+	for _, instance := range instances {
+		instancesByName[instance.Name] = instance
+	}
+
+	ret := []params.ForgeRunner{}
+	found := map[string]struct{}{}
+	for _, ghRunner := range ghRunners {
+		labels := make([]string, 0, len(ghRunner.Labels))
+		for _, label := range ghRunner.Labels {
+			labels = append(labels, label.GetName())
+		}
+		runner := params.ForgeRunner{
+			ForgeID:     ghRunner.GetID(),
+			Name:        ghRunner.GetName(),
+			Labels:      labels,
+			ForgeStatus: ghRunner.GetStatus(),
+			Busy:        ghRunner.GetBusy(),
+		}
+
+		runnerControllerID := controllerIDFromLabels(labels)
+		instance, ok := instancesByName[ghRunner.GetName()]
+		switch {
+		case ok:
+			found[instance.Name] = struct{}{}
+			runner.Correlation = params.ForgeRunnerManaged
+			setForgeRunnerInstance(&runner, instance)
+		case isManagedRunner(labels, controllerIDs...):
+			runner.Correlation = params.ForgeRunnerOrphaned
+		case runnerControllerID != "":
+			runner.Correlation = params.ForgeRunnerForeign
+		default:
+			runner.Correlation = params.ForgeRunnerUnmanaged
+		}
+		ret = append(ret, runner)
+	}
+
+	for _, instance := range instances {
+		if _, ok := found[instance.Name]; ok {
+			continue
+		}
+
+		switch instance.Status {
+		case commonParams.InstancePendingDelete, commonParams.InstancePendingForceDelete, commonParams.InstanceDeleting:
+			continue
+		}
+
+		runner := params.ForgeRunner{
+			Name:        instance.Name,
+			Correlation: params.ForgeRunnerMissing,
+		}
+		switch instance.RunnerStatus {
+		case params.RunnerTerminated:
+			continue
+		case params.RunnerPending, params.RunnerInstalling:
+			runner.Correlation = params.ForgeRunnerPending
+		}
+		setForgeRunnerInstance(&runner, instance)
+		ret = append(ret, runner)
+	}
+	return ret
+}
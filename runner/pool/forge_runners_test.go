@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
+)
+
+func ghRunner(id int64, name string, labels ...string) *github.Runner {
+	runner := &github.Runner{
+		ID:     github.Int64(id),
+		Name:   github.String(name),
+		Status: github.String("online"),
+		Busy:   github.Bool(false),
+	}
+	for _, label := range labels {
+		runner.Labels = append(runner.Labels, &github.RunnerLabels{Name: github.String(label)})
+	}
+	return runner
+}
+
+func TestCorrelateForgeRunners(t *testing.T) {
+	controllerID := "controller-id"
+	ghRunners := []*github.Runner{
+		ghRunner(1, "garm-managed", controllerLabelPrefix+controllerID),
+		ghRunner(2, "garm-orphaned", controllerLabelPrefix+controllerID),
+		ghRunner(3, "garm-foreign", controllerLabelPrefix+"another-controller"),
+		ghRunner(4, "self-hosted", "linux"),
+	}
+	instances := []params.Instance{
+		{ID: "1", Name: "garm-managed", PoolID: "pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle},
+		{ID: "2", Name: "garm-pending", PoolID: "pool", Status: commonParams.InstanceCreating, RunnerStatus: params.RunnerPending},
+		{ID: "3", Name: "garm-missing", PoolID: "pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle},
+		{ID: "4", Name: "garm-deleting", PoolID: "pool", Status: commonParams.InstanceDeleting, RunnerStatus: params.RunnerIdle},
+		{ID: "5", Name: "garm-terminated", PoolID: "pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerTerminated},
+	}
+
+	expected := map[string]params.ForgeRunnerCorrelation{
+		"garm-managed":  params.ForgeRunnerManaged,
+		"garm-orphaned": params.ForgeRunnerOrphaned,
+		"garm-foreign":  params.ForgeRunnerForeign,
+		"self-hosted":   params.ForgeRunnerUnmanaged,
+		"garm-pending":  params.ForgeRunnerPending,
+		"garm-missing":  params.ForgeRunnerMissing,
+	}
+
+	runners := correlateForgeRunners(ghRunners, instances, controllerID)
+	if len(runners) != len(expected) {
+		t.Fatalf("expected %d runners, got %d", len(expected), len(runners))
+	}
+	for _, runner := range runners {
+		correlation, ok := expected[runner.Name]
+		if !ok {
+			t.Fatalf("unexpected runner %s", runner.Name)
+		}
+		if runner.Correlation != correlation {
+			t.Fatalf("expected runner %s to be %s, got %s", runner.Name, correlation, runner.Correlation)
+		}
+	}
+
+	if runners[0].InstanceID != "1" || runners[0].ForgeID != 1 || runners[0].ForgeStatus != "online" {
+		t.Fatalf("managed runner was not joined with its instance: %+v", runners[0])
+	}
+}
@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// isLeakedJITRegistration returns true if the instance holds a GitHub runner registration
+// that will never be used. A registration is created in GitHub along with the JIT config
+// of an instance. If the provider fails to create the instance and we give up retrying,
+// the registration lingers in GitHub as an offline runner.
+func isLeakedJITRegistration(instance params.Instance) bool {
+	return instance.AgentID != 0 &&
+		!instance.JitRegistrationRemoved &&
+		instance.Status == commonParams.InstanceError &&
+		instance.CreateAttempt >= maxCreateAttempts
+}
+
+func (r *basePoolManager) removeLeakedJITRegistration(instance params.Instance) error {
+	resp, err := r.ghcli.RemoveEntityRunner(r.ctx, instance.AgentID)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return errors.Wrap(err, "removing runner")
+	}
+
+	removed := true
+	if _, err := r.store.UpdateInstance(r.ctx, instance.Name, params.UpdateInstanceParams{JitRegistrationRemoved: &removed}); err != nil {
+		return errors.Wrap(err, "updating instance")
+	}
+	if err := r.store.AddInstanceEvent(
+		r.ctx, instance.Name, params.StatusEvent, params.EventInfo,
+		"removed unused runner registration from GitHub", common.MaxInstanceEvents); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			r.ctx, "failed to add instance event",
+			"runner_name", instance.Name)
+	}
+	metrics.InstanceLeakedJITRegistrationsRemoved.WithLabelValues(
+		instance.PoolID, // label: pool_id
+		"create_failed", // label: reason
+	).Inc()
+	return nil
+}
+
+// cleanupLeakedJITRegistrations removes the GitHub runner registrations of instances
+// that failed to be created. The instances themselves are kept, so the provider fault
+// can still be inspected.
+func (r *basePoolManager) cleanupLeakedJITRegistrations() error {
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		return errors.Wrap(err, "listing pools")
+	}
+
+	for _, pool := range pools {
+		instances, err := r.store.ListPoolInstances(r.ctx, pool.ID)
+		if err != nil {
+			return errors.Wrapf(err, "listing instances for pool %s", pool.ID)
+		}
+
+		leaked := 0
+		for _, instance := range instances {
+			if !isLeakedJITRegistration(instance) {
+				continue
+			}
+
+			fence, ok := r.keyMux.TryLock(instance.Name)
+			if !ok {
+				leaked++
+				continue
+			}
+			err := r.removeLeakedJITRegistration(instance)
+			r.keyMux.Unlock(instance.Name, fence)
+			if err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(
+					r.ctx, "failed to remove leaked runner registration",
+					"runner_name", instance.Name,
+					"agent_id", instance.AgentID)
+				leaked++
+				continue
+			}
+			slog.InfoContext(
+				r.ctx, "removed leaked runner registration",
+				"runner_name", instance.Name,
+				"agent_id", instance.AgentID)
+		}
+		metrics.InstanceLeakedJITRegistrations.WithLabelValues(
+			pool.ID, // label: pool_id
+		).Set(float64(leaked))
+	}
+	return nil
+}
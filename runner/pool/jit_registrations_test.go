@@ -0,0 +1,81 @@
+package pool
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/stretchr/testify/mock"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestCleanupLeakedJITRegistrations(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pool := params.Pool{ID: "test-pool"}
+	leaked := params.Instance{
+		Name:          "leaked",
+		PoolID:        pool.ID,
+		AgentID:       1,
+		Status:        commonParams.InstanceError,
+		CreateAttempt: maxCreateAttempts,
+	}
+	alreadyRemoved := params.Instance{
+		Name:                   "already-removed",
+		PoolID:                 pool.ID,
+		AgentID:                2,
+		Status:                 commonParams.InstanceError,
+		CreateAttempt:          maxCreateAttempts,
+		JitRegistrationRemoved: true,
+	}
+	retrying := params.Instance{
+		Name:          "retrying",
+		PoolID:        pool.ID,
+		AgentID:       3,
+		Status:        commonParams.InstanceError,
+		CreateAttempt: 1,
+	}
+	goneFromGithub := params.Instance{
+		Name:          "gone-from-github",
+		PoolID:        pool.ID,
+		AgentID:       4,
+		Status:        commonParams.InstanceError,
+		CreateAttempt: maxCreateAttempts,
+	}
+
+	removed := true
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityPools", mock.Anything, entity).Return([]params.Pool{pool}, nil)
+	store.On("ListPoolInstances", mock.Anything, pool.ID).Return(
+		[]params.Instance{leaked, alreadyRemoved, retrying, goneFromGithub}, nil)
+	for _, name := range []string{leaked.Name, goneFromGithub.Name} {
+		store.On("UpdateInstance", mock.Anything, name, params.UpdateInstanceParams{JitRegistrationRemoved: &removed}).Return(
+			params.Instance{}, nil).Once()
+		store.On("AddInstanceEvent", mock.Anything, name, params.StatusEvent, params.EventInfo, mock.Anything, common.MaxInstanceEvents).Return(nil).Once()
+	}
+
+	cli := mocks.NewGithubClient(t)
+	cli.On("RemoveEntityRunner", mock.Anything, leaked.AgentID).Return(nil, nil).Once()
+	cli.On("RemoveEntityRunner", mock.Anything, goneFromGithub.AgentID).Return(
+		&github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		&github.ErrorResponse{}).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+		ghcli:  cli,
+		keyMux: &keyMutex{},
+	}
+	if err := r.cleanupLeakedJITRegistrations(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+)
+
+// SyncQueuedJobs fetches the jobs that are queued in GitHub for the repository, and records
+// the ones GARM has no record of, as if their queued webhook had been received. This recovers
+// jobs that were queued while GARM was offline. Only repositories are supported, as GitHub
+// does not list workflow runs for organizations or enterprises.
+func (r *basePoolManager) SyncQueuedJobs(ctx context.Context) ([]params.Job, error) {
+	if r.entity.EntityType != params.GithubEntityTypeRepository {
+		return nil, runnerErrors.NewBadRequestError("syncing jobs is only supported for repositories")
+	}
+
+	queued, err := r.listQueuedGithubJobs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching queued jobs")
+	}
+
+	ret := []params.Job{}
+	for _, ghJob := range queued {
+		if _, err := r.store.GetJobByID(ctx, ghJob.GetID()); err == nil {
+			continue
+		} else if !errors.Is(err, runnerErrors.ErrNotFound) {
+			return nil, errors.Wrap(err, "fetching job")
+		}
+
+		if err := r.HandleWorkflowJob(r.githubJobToWorkflowJob(ghJob)); err != nil {
+			return nil, errors.Wrap(err, "recording job")
+		}
+
+		// Jobs that no pool can run are not recorded.
+		job, err := r.store.GetJobByID(ctx, ghJob.GetID())
+		if err != nil {
+			if errors.Is(err, runnerErrors.ErrNotFound) {
+				continue
+			}
+			return nil, errors.Wrap(err, "fetching job")
+		}
+		slog.InfoContext(ctx, "recorded queued job missed while offline", "job_id", job.ID, "run_id", job.RunID)
+		ret = append(ret, job)
+	}
+	return ret, nil
+}
+
+// listQueuedGithubJobs returns the queued jobs of the workflow runs of the repository that
+// have not finished. A run that is in progress may still have queued jobs.
+func (r *basePoolManager) listQueuedGithubJobs(ctx context.Context) ([]*github.WorkflowJob, error) {
+	seen := map[int64]struct{}{}
+	var ret []*github.WorkflowJob
+	for _, status := range []string{string(params.JobStatusQueued), string(params.JobStatusInProgress)} {
+		opts := github.ListWorkflowRunsOptions{
+			Status:      status,
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+		for {
+			runs, ghResp, err := r.ghcli.ListRepositoryWorkflowRuns(ctx, r.entity.Owner, r.entity.Name, &opts)
+			if err != nil {
+				if ghResp != nil && ghResp.StatusCode == http.StatusUnauthorized {
+					return nil, errors.Wrap(runnerErrors.ErrUnauthorized, "fetching workflow runs")
+				}
+				return nil, errors.Wrap(err, "fetching workflow runs")
+			}
+			for _, run := range runs.WorkflowRuns {
+				if _, ok := seen[run.GetID()]; ok {
+					continue
+				}
+				seen[run.GetID()] = struct{}{}
+
+				jobs, err := r.listQueuedRunJobs(ctx, run.GetID())
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, jobs...)
+			}
+			if ghResp.NextPage == 0 {
+				break
+			}
+			opts.Page = ghResp.NextPage
+		}
+	}
+	return ret, nil
+}
+
+func (r *basePoolManager) listQueuedRunJobs(ctx context.Context, runID int64) ([]*github.WorkflowJob, error) {
+	opts := github.ListWorkflowJobsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var ret []*github.WorkflowJob
+	for {
+		jobs, ghResp, err := r.ghcli.ListWorkflowJobs(ctx, r.entity.Owner, r.entity.Name, runID, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching jobs of workflow run %d", runID)
+		}
+		for _, job := range jobs.Jobs {
+			if job.GetStatus() == string(params.JobStatusQueued) {
+				ret = append(ret, job)
+			}
+		}
+		if ghResp.NextPage == 0 {
+			break
+		}
+		opts.Page = ghResp.NextPage
+	}
+	return ret, nil
+}
+
+func (r *basePoolManager) githubJobToWorkflowJob(ghJob *github.WorkflowJob) params.WorkflowJob {
+	job := params.WorkflowJob{Action: "queued"}
+	job.WorkflowJob.ID = ghJob.GetID()
+	job.WorkflowJob.RunID = ghJob.GetRunID()
+	job.WorkflowJob.RunURL = ghJob.GetRunURL()
+	job.WorkflowJob.RunAttempt = ghJob.GetRunAttempt()
+	job.WorkflowJob.NodeID = ghJob.GetNodeID()
+	job.WorkflowJob.HeadSha = ghJob.GetHeadSHA()
+	job.WorkflowJob.URL = ghJob.GetURL()
+	job.WorkflowJob.HTMLURL = ghJob.GetHTMLURL()
+	job.WorkflowJob.Status = ghJob.GetStatus()
+	job.WorkflowJob.StartedAt = ghJob.GetStartedAt().Time
+	job.WorkflowJob.Name = ghJob.GetName()
+	job.WorkflowJob.CheckRunURL = ghJob.GetCheckRunURL()
+	job.WorkflowJob.Labels = ghJob.Labels
+	job.Repository.Name = r.entity.Name
+	job.Repository.Owner.Login = r.entity.Owner
+	return job
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestSyncQueuedJobs(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		Owner:      "test-owner",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	okResp := &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	run := &github.WorkflowRun{ID: github.Int64(10)}
+	known := &github.WorkflowJob{ID: github.Int64(1), RunID: github.Int64(10), Status: github.String("queued")}
+	// Jobs without labels are ignored by HandleWorkflowJob and never recorded.
+	unlabeled := &github.WorkflowJob{ID: github.Int64(2), RunID: github.Int64(10), Status: github.String("queued")}
+	running := &github.WorkflowJob{ID: github.Int64(3), RunID: github.Int64(10), Status: github.String("in_progress")}
+
+	cli := mocks.NewGithubClient(t)
+	// The run is listed both as queued and in progress, but its jobs are only fetched once.
+	cli.On("ListRepositoryWorkflowRuns", mock.Anything, entity.Owner, entity.Name, mock.Anything).Return(
+		&github.WorkflowRuns{WorkflowRuns: []*github.WorkflowRun{run}}, okResp, nil).Twice()
+	cli.On("ListWorkflowJobs", mock.Anything, entity.Owner, entity.Name, run.GetID(), mock.Anything).Return(
+		&github.Jobs{Jobs: []*github.WorkflowJob{known, unlabeled, running}}, okResp, nil).Once()
+
+	store := dbMocks.NewStore(t)
+	store.On("GetJobByID", mock.Anything, known.GetID()).Return(params.Job{ID: known.GetID()}, nil).Once()
+	store.On("GetJobByID", mock.Anything, unlabeled.GetID()).Return(params.Job{}, runnerErrors.ErrNotFound).Twice()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+		ghcli:  cli,
+	}
+	jobs, err := r.SyncQueuedJobs(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(jobs) != 0 {
+		t.Fatalf("expected no jobs to be recorded, got %d", len(jobs))
+	}
+}
+
+func TestSyncQueuedJobsOnlyForRepositories(t *testing.T) {
+	r := &basePoolManager{
+		ctx: context.Background(),
+		entity: params.GithubEntity{
+			ID:         "test-org-id",
+			EntityType: params.GithubEntityTypeOrganization,
+		},
+	}
+	_, err := r.SyncQueuedJobs(context.Background())
+	var badRequest *runnerErrors.BadRequestError
+	if !errors.As(err, &badRequest) {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package pool
+
+// isLeader returns true if this controller manages the entity of the pool manager.
+// Without a leader elector, the controller manages all entities.
+func (r *basePoolManager) isLeader() bool {
+	if r.leaderElector == nil {
+		return true
+	}
+	return r.leaderElector.IsLeader(r.entity.ID)
+}
+
+// leaderOnly wraps a worker function so that it only does something while this
+// controller is the leader of the entity.
+func (r *basePoolManager) leaderOnly(f func() error) func() error {
+	return func() error {
+		if !r.isLeader() {
+			return nil
+		}
+		return f()
+	}
+}
@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestLeaderOnly(t *testing.T) {
+	var calls int
+	f := func() error {
+		calls++
+		return nil
+	}
+
+	// Without a leader elector, the controller manages all entities.
+	r := &basePoolManager{entity: params.GithubEntity{ID: "test-repo-id"}}
+	if err := r.leaderOnly(f)(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+
+	elector := mocks.NewLeaderElector(t)
+	elector.On("IsLeader", "test-repo-id").Return(false).Once()
+	elector.On("IsLeader", "test-repo-id").Return(true).Once()
+	r.leaderElector = elector
+
+	for i := 0; i < 2; i++ {
+		if err := r.leaderOnly(f)(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestReconcileQueuedJobsOnLeadership(t *testing.T) {
+	entity := params.GithubEntity{ID: "test-repo-id", EntityType: params.GithubEntityTypeRepository}
+
+	// The queued jobs are listed once by the reconcile and once by each run of the
+	// consumer, while this controller is the leader.
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityJobsByStatus", mock.Anything, entity.EntityType, entity.ID, params.JobStatusQueued).Return([]params.Job{}, nil).Times(3)
+
+	elector := mocks.NewLeaderElector(t)
+	elector.On("IsLeader", entity.ID).Return(false).Once()
+	elector.On("IsLeader", entity.ID).Return(true).Twice()
+
+	r := &basePoolManager{
+		ctx:                    context.Background(),
+		entity:                 entity,
+		store:                  store,
+		reconcileJobsOnStartup: true,
+		leaderElector:          elector,
+	}
+
+	f := r.leaderOnly(r.reconcileAndConsumeQueuedJobs)
+	for i := 0; i < 3; i++ {
+		if err := f(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if i == 0 && r.queuedJobsReconciled {
+			t.Fatalf("queued jobs reconciled before the lease was acquired")
+		}
+	}
+	if !r.queuedJobsReconciled {
+		t.Fatalf("expected queued jobs to be reconciled")
+	}
+}
@@ -1,29 +1,180 @@
 package pool
 
-import "sync"
+import (
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// heldLock holds information about a lock taken on a key. The fence is unique for
+// every acquisition and must be passed back when unlocking. A holder whose lock
+// expired or was force released can't release a lock taken by someone else since.
+type heldLock struct {
+	key        string
+	fence      uint64
+	holder     string
+	acquiredAt time.Time
+	// staleReported is set once the lock was reported as stale.
+	staleReported bool
+}
 
 type keyMutex struct {
-	muxes sync.Map
+	mux       sync.Mutex
+	locks     map[string]*heldLock
+	lastFence uint64
 }
 
-func (k *keyMutex) TryLock(key string) bool {
-	mux, _ := k.muxes.LoadOrStore(key, &sync.Mutex{})
-	keyMux := mux.(*sync.Mutex)
-	return keyMux.TryLock()
+func newKeyMutex() *keyMutex {
+	return &keyMutex{
+		locks: map[string]*heldLock{},
+	}
 }
 
-func (k *keyMutex) Unlock(key string, remove bool) {
-	mux, ok := k.muxes.Load(key)
-	if !ok {
+// TryLock attempts to lock the key. It returns the fence of the new lock and true
+// if the lock was acquired.
+func (k *keyMutex) TryLock(key string) (uint64, bool) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if k.locks == nil {
+		k.locks = map[string]*heldLock{}
+	}
+	if _, ok := k.locks[key]; ok {
+		return 0, false
+	}
+
+	k.lastFence++
+	k.locks[key] = &heldLock{
+		key:        key,
+		fence:      k.lastFence,
+		holder:     lockHolder(),
+		acquiredAt: time.Now(),
+	}
+	return k.lastFence, true
+}
+
+// Unlock releases the lock on the key, if it is still held with the given fence.
+func (k *keyMutex) Unlock(key string, fence uint64) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	lock, ok := k.locks[key]
+	if !ok || lock.fence != fence {
+		slog.Warn(
+			"lock expired or was released before its holder unlocked it",
+			"key", key,
+			"fence", fence)
 		return
 	}
-	keyMux := mux.(*sync.Mutex)
-	if remove {
-		k.Delete(key)
+	delete(k.locks, key)
+}
+
+// ForceUnlock releases the lock on the key, regardless of who holds it. It returns
+// false if the key is not locked.
+func (k *keyMutex) ForceUnlock(key string) bool {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if _, ok := k.locks[key]; !ok {
+		return false
 	}
-	keyMux.Unlock()
+	delete(k.locks, key)
+	return true
 }
 
-func (k *keyMutex) Delete(key string) {
-	k.muxes.Delete(key)
+// NewStaleLocks returns the locks that became stale since the last call. Stale locks
+// are kept, as their holder may still be working on the key.
+func (k *keyMutex) NewStaleLocks(now time.Time, staleAfter time.Duration) []heldLock {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	var stale []heldLock
+	for _, lock := range k.locks {
+		if !lock.staleReported && now.Sub(lock.acquiredAt) >= staleAfter {
+			lock.staleReported = true
+			stale = append(stale, *lock)
+		}
+	}
+	return stale
+}
+
+// List returns the locks that are currently held, sorted by key.
+func (k *keyMutex) List() []heldLock {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	ret := make([]heldLock, 0, len(k.locks))
+	for _, lock := range k.locks {
+		ret = append(ret, *lock)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].key < ret[j].key
+	})
+	return ret
+}
+
+// lockHolder returns the name of the function that called TryLock.
+func lockHolder() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+func (r *basePoolManager) InstanceLocks() []params.InstanceLock {
+	now := time.Now()
+	locks := r.keyMux.List()
+	ret := make([]params.InstanceLock, 0, len(locks))
+	for _, lock := range locks {
+		ret = append(ret, params.InstanceLock{
+			InstanceName: lock.key,
+			EntityID:     r.entity.ID,
+			Holder:       lock.holder,
+			Fence:        lock.fence,
+			AcquiredAt:   lock.acquiredAt,
+			Stale:        now.Sub(lock.acquiredAt) >= common.InstanceLockStaleAfter,
+		})
+	}
+	return ret
+}
+
+func (r *basePoolManager) ReleaseInstanceLock(instanceName string) bool {
+	if !r.keyMux.ForceUnlock(instanceName) {
+		return false
+	}
+	slog.WarnContext(
+		r.ctx, "instance lock was force released",
+		"runner_name", instanceName)
+	metrics.InstanceLocksReleased.WithLabelValues(r.entity.ID, "forced").Inc()
+	return true
+}
+
+// reportStaleInstanceLocks logs the locks that were held for longer than the stale
+// threshold. The locks are not released, as a slow worker may still hold them, and
+// releasing them would let a second worker reconcile the same instance. An admin
+// can release a lock whose holder is known to be gone.
+func (r *basePoolManager) reportStaleInstanceLocks() {
+	for _, lock := range r.keyMux.NewStaleLocks(time.Now(), common.InstanceLockStaleAfter) {
+		slog.WarnContext(
+			r.ctx, "instance lock is stale; release it if its holder is gone",
+			"runner_name", lock.key,
+			"holder", lock.holder,
+			"acquired_at", lock.acquiredAt)
+	}
 }
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyMutexFencing(t *testing.T) {
+	k := newKeyMutex()
+
+	fence, ok := k.TryLock("runner-1")
+	if !ok {
+		t.Fatalf("expected lock to be acquired")
+	}
+	if _, ok := k.TryLock("runner-1"); ok {
+		t.Fatalf("expected lock to be held")
+	}
+
+	locks := k.List()
+	if len(locks) != 1 || locks[0].key != "runner-1" || locks[0].fence != fence {
+		t.Fatalf("unexpected locks: %+v", locks)
+	}
+	if locks[0].holder != "pool.TestKeyMutexFencing" {
+		t.Fatalf("unexpected lock holder %q", locks[0].holder)
+	}
+
+	// The lock is force released and taken by someone else. The previous holder
+	// must not be able to release the new lock.
+	if !k.ForceUnlock("runner-1") {
+		t.Fatalf("expected lock to be force released")
+	}
+	newFence, ok := k.TryLock("runner-1")
+	if !ok || newFence == fence {
+		t.Fatalf("expected lock to be acquired with a new fence")
+	}
+	k.Unlock("runner-1", fence)
+	if _, ok := k.TryLock("runner-1"); ok {
+		t.Fatalf("expected lock to still be held by the new holder")
+	}
+
+	k.Unlock("runner-1", newFence)
+	if _, ok := k.TryLock("runner-1"); !ok {
+		t.Fatalf("expected lock to be released")
+	}
+	if k.ForceUnlock("runner-2") {
+		t.Fatalf("expected force release of a key that is not locked to fail")
+	}
+}
+
+func TestKeyMutexNewStaleLocks(t *testing.T) {
+	k := newKeyMutex()
+	k.TryLock("runner-1")
+	k.TryLock("runner-2")
+	k.locks["runner-1"].acquiredAt = time.Now().Add(-2 * time.Hour)
+
+	stale := k.NewStaleLocks(time.Now(), time.Hour)
+	if len(stale) != 1 || stale[0].key != "runner-1" {
+		t.Fatalf("unexpected stale locks: %+v", stale)
+	}
+	// Stale locks are only reported once.
+	if stale := k.NewStaleLocks(time.Now(), time.Hour); len(stale) != 0 {
+		t.Fatalf("expected stale lock to be reported once, got: %+v", stale)
+	}
+	// Stale locks are not released, as their holder may still be working.
+	if _, ok := k.TryLock("runner-1"); ok {
+		t.Fatalf("expected stale lock to be held")
+	}
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// MatchPools returns the pools that would be tried, in order, to create a runner for a
+// queued job with the given labels. The pools are found and ordered the same way
+// consumeQueuedJobs does it: by priority, then by the routing rules of the entity, if
+// any. With the round robin balancer, this is the order used for the first matching
+// job of each pass. The jobs that follow start from the next pool.
+func (r *basePoolManager) MatchPools(_ context.Context, labels []string) (params.PoolMatchResult, error) {
+	// The pool cache sorts the labels in place.
+	labels = append([]string{}, labels...)
+	pools, err := r.findPoolsForJobLabels(labels)
+	if err != nil {
+		return params.PoolMatchResult{}, errors.Wrap(err, "finding pools")
+	}
+
+	poolsCache := poolsForTags{
+		poolCacheType: r.entity.GetPoolBalancerType(),
+	}
+	ordered := poolsCache.Add(labels, pools)
+	if len(r.entity.RoutingRules) > 0 {
+		router, err := r.newPoolRouter()
+		if err != nil {
+			return params.PoolMatchResult{}, errors.Wrap(err, "creating pool router")
+		}
+		ordered = router.Route(ordered.Pools())
+	}
+
+	result := params.PoolMatchResult{
+		Labels:           labels,
+		PoolBalancerType: r.entity.GetPoolBalancerType(),
+		RoutingRules:     r.entity.RoutingRules,
+		Autoscaled:       ordered.Len() > 0,
+		Pools:            []params.PoolMatch{},
+	}
+	for idx, pool := range ordered.Pools() {
+		match := params.PoolMatch{
+			Order:        idx + 1,
+			PoolID:       pool.ID,
+			ProviderName: pool.ProviderName,
+			Priority:     pool.Priority,
+			Tags:         pool.Tags,
+			Eligible:     true,
+		}
+		if err := r.checkPoolCanAddRunner(pool); err != nil {
+			match.Eligible = false
+			match.Reason = err.Error()
+		}
+		if !pool.AutoscaleEnabled() {
+			result.Autoscaled = false
+		}
+		result.Pools = append(result.Pools, match)
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestMatchPools(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:           "test-repo-id",
+		EntityType:   params.GithubEntityTypeRepository,
+		RoutingRules: []params.RoutingRule{{Type: params.RoutingRuleProviderOrder, Providers: []string{"lxd"}}},
+	}
+	openstack := params.Pool{ID: "openstack-pool", ProviderName: "openstack", Priority: 10, Enabled: true, MaxRunners: 2}
+	lxd := params.Pool{ID: "lxd-pool", ProviderName: "lxd", Priority: 5, Enabled: true, MaxRunners: 1}
+	disabled := params.Pool{ID: "disabled-pool", ProviderName: "openstack", Priority: 20}
+	labels := []string{"self-hosted", "linux"}
+
+	store := dbMocks.NewStore(t)
+	store.On("FindPoolsMatchingAllTags", mock.Anything, entity.EntityType, entity.ID, labels).
+		Return([]params.Pool{lxd, openstack, disabled}, nil)
+	store.On("ListEntityInstances", mock.Anything, entity).Return([]params.Instance{}, nil)
+	store.On("ListPausedProviders", mock.Anything).Return([]params.ProviderPause{}, nil)
+	store.On("ListDenyRules", mock.Anything).Return([]params.DenyRule{}, nil)
+	store.On("PoolInstanceCount", mock.Anything, openstack.ID).Return(int64(0), nil)
+	store.On("PoolInstanceCount", mock.Anything, lxd.ID).Return(int64(1), nil)
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+	result, err := r.MatchPools(context.Background(), labels)
+	require.NoError(t, err)
+	// The labels of the caller are not sorted in place.
+	require.Equal(t, []string{"self-hosted", "linux"}, labels)
+	require.Equal(t, params.PoolBalancerTypeRoundRobin, result.PoolBalancerType)
+	require.False(t, result.Autoscaled)
+
+	// Pools are sorted by priority, then by the routing rules.
+	require.Equal(t, []params.PoolMatch{
+		{Order: 1, PoolID: lxd.ID, ProviderName: "lxd", Priority: 5, Reason: "max workers (1) reached for pool lxd-pool"},
+		{Order: 2, PoolID: disabled.ID, ProviderName: "openstack", Priority: 20, Reason: "pool disabled-pool is disabled"},
+		{Order: 3, PoolID: openstack.ID, ProviderName: "openstack", Priority: 10, Eligible: true},
+	}, result.Pools)
+}
+
+func TestMatchPoolsNoMatch(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	store := dbMocks.NewStore(t)
+	store.On("FindPoolsMatchingAllTags", mock.Anything, entity.EntityType, entity.ID, []string{"gpu"}).
+		Return([]params.Pool{}, nil)
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+	result, err := r.MatchPools(context.Background(), []string{"gpu"})
+	require.NoError(t, err)
+	require.Empty(t, result.Pools)
+	require.False(t, result.Autoscaled)
+}
@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+)
+
+// maxRunnerNameCollisions is the number of times we try to register a runner after
+// its name was found to be taken in GitHub.
+const maxRunnerNameCollisions = 3
+
+// jitRegistration is the result of registering a new runner in GitHub.
+type jitRegistration struct {
+	// name is the name the runner was registered with. It differs from the requested
+	// name if that name was taken by a runner we could not remove.
+	name      string
+	jitConfig map[string]string
+	runner    *github.Runner
+	// collisions describes the name collisions that were resolved, so they can be
+	// recorded as events of the instance.
+	collisions []string
+}
+
+// registerJITRunner creates the JIT config of a new runner, which registers the runner in
+// GitHub. GitHub refuses to register a runner with the name of an existing runner. This
+// happens when an instance crashed before its runner could be removed, and the name is
+// reused. If the existing runner is offline and was created by this controller, it is
+// removed and the name is reused. Otherwise a new name is generated.
+func (r *basePoolManager) registerJITRunner(ctx context.Context, name string, pool params.Pool, labels []string, newName func() (string, error)) (jitRegistration, error) {
+	reg := jitRegistration{name: name}
+	for attempt := 0; ; attempt++ {
+		jitConfig, runner, err := r.ghcli.GetEntityJITConfig(ctx, reg.name, pool, labels)
+		var conflictErr *runnerErrors.ConflictError
+		if err == nil || !errors.As(err, &conflictErr) || attempt >= maxRunnerNameCollisions {
+			reg.jitConfig = jitConfig
+			reg.runner = runner
+			return reg, err
+		}
+
+		msg, err := r.resolveRunnerNameCollision(ctx, reg.name, pool)
+		if err != nil {
+			slog.With(slog.Any("error", err)).WarnContext(
+				ctx, "failed to remove stale runner, generating a new name",
+				"runner_name", reg.name)
+		}
+		if msg != "" {
+			reg.collisions = append(reg.collisions, msg)
+			continue
+		}
+
+		taken := reg.name
+		reg.name, err = newName()
+		if err != nil {
+			return reg, errors.Wrap(err, "generating instance name")
+		}
+		reg.collisions = append(reg.collisions, fmt.Sprintf(
+			"runner name %s is already taken in GitHub, registered the runner as %s instead", taken, reg.name))
+	}
+}
+
+// resolveRunnerNameCollision removes the GitHub runner that holds the given name, if it is
+// a stale runner created by this controller. It returns a description of what was done,
+// or an empty string if the name can't be reused.
+func (r *basePoolManager) resolveRunnerNameCollision(ctx context.Context, name string, pool params.Pool) (string, error) {
+	if _, err := r.store.GetInstanceByName(ctx, name); err == nil {
+		// The name belongs to an instance we know about. Its runner is not stale.
+		return "", nil
+	} else if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return "", errors.Wrap(err, "fetching instance")
+	}
+
+	runners, err := r.GetGithubRunners()
+	if err != nil {
+		return "", errors.Wrap(err, "fetching runners")
+	}
+
+	var existing *github.Runner
+	for _, run := range runners {
+		if run.GetName() == name {
+			existing = run
+			break
+		}
+	}
+	if existing == nil {
+		// The runner was removed in the meantime.
+		return fmt.Sprintf("runner name %s was taken in GitHub by a runner that no longer exists", name), nil
+	}
+	if existing.GetStatus() != "offline" || !isManagedRunner(labelsFromRunner(existing), r.managedControllerIDs()...) {
+		return "", nil
+	}
+
+	resp, err := r.ghcli.RemoveEntityRunner(ctx, existing.GetID())
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return "", errors.Wrap(err, "removing runner")
+	}
+	metrics.InstanceLeakedJITRegistrationsRemoved.WithLabelValues(
+		pool.ID,          // label: pool_id
+		"name_collision", // label: reason
+	).Inc()
+	slog.InfoContext(
+		ctx, "removed stale runner with the same name",
+		"runner_name", name, "gh_runner_id", existing.GetID())
+	return fmt.Sprintf("removed stale offline runner %d from GitHub, which held the runner name %s", existing.GetID(), name), nil
+}
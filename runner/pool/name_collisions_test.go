@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func newCollisionTestPoolManager(t *testing.T, store *dbMocks.Store, cli *mocks.GithubClient) *basePoolManager {
+	t.Helper()
+	return &basePoolManager{
+		ctx:   context.Background(),
+		store: store,
+		ghcli: cli,
+		controllerInfo: params.ControllerInfo{
+			ControllerID: uuid.New(),
+		},
+	}
+}
+
+func conflictErr(name string) error {
+	return fmt.Errorf("failed to get JIT config: %w", runnerErrors.NewConflictError("runner %s already exists", name))
+}
+
+func noNewName() (string, error) {
+	return "", errors.New("unexpected new name")
+}
+
+func TestRegisterJITRunnerRemovesStaleRunner(t *testing.T) {
+	pool := params.Pool{ID: "test-pool"}
+	store := dbMocks.NewStore(t)
+	cli := mocks.NewGithubClient(t)
+	r := newCollisionTestPoolManager(t, store, cli)
+
+	stale := &github.Runner{
+		ID:     github.Int64(10),
+		Name:   github.String("garm-taken"),
+		Status: github.String("offline"),
+		Labels: []*github.RunnerLabels{{Name: github.String(r.controllerLabel())}},
+	}
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-taken", pool, mock.Anything).Return(nil, nil, conflictErr("garm-taken")).Once()
+	store.On("GetInstanceByName", mock.Anything, "garm-taken").Return(params.Instance{}, runnerErrors.ErrNotFound).Once()
+	cli.On("ListEntityRunners", mock.Anything, mock.Anything).Return(
+		&github.Runners{Runners: []*github.Runner{stale}}, &github.Response{}, nil).Once()
+	cli.On("RemoveEntityRunner", mock.Anything, int64(10)).Return(nil, nil).Once()
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-taken", pool, mock.Anything).Return(
+		map[string]string{".runner": "config"}, &github.Runner{ID: github.Int64(11)}, nil).Once()
+
+	reg, err := r.registerJITRunner(context.Background(), "garm-taken", pool, nil, noNewName)
+	require.NoError(t, err)
+	require.Equal(t, "garm-taken", reg.name)
+	require.Equal(t, int64(11), reg.runner.GetID())
+	require.Len(t, reg.collisions, 1)
+	require.Contains(t, reg.collisions[0], "removed stale offline runner 10")
+}
+
+func TestRegisterJITRunnerRenamesWhenRunnerIsOnline(t *testing.T) {
+	pool := params.Pool{ID: "test-pool"}
+	store := dbMocks.NewStore(t)
+	cli := mocks.NewGithubClient(t)
+	r := newCollisionTestPoolManager(t, store, cli)
+
+	online := &github.Runner{
+		ID:     github.Int64(10),
+		Name:   github.String("garm-taken"),
+		Status: github.String("online"),
+		Labels: []*github.RunnerLabels{{Name: github.String(r.controllerLabel())}},
+	}
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-taken", pool, mock.Anything).Return(nil, nil, conflictErr("garm-taken")).Once()
+	store.On("GetInstanceByName", mock.Anything, "garm-taken").Return(params.Instance{}, runnerErrors.ErrNotFound).Once()
+	cli.On("ListEntityRunners", mock.Anything, mock.Anything).Return(
+		&github.Runners{Runners: []*github.Runner{online}}, &github.Response{}, nil).Once()
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-new", pool, mock.Anything).Return(
+		map[string]string{".runner": "config"}, &github.Runner{ID: github.Int64(11)}, nil).Once()
+
+	reg, err := r.registerJITRunner(context.Background(), "garm-taken", pool, nil, func() (string, error) {
+		return "garm-new", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "garm-new", reg.name)
+	require.Equal(t, []string{"runner name garm-taken is already taken in GitHub, registered the runner as garm-new instead"}, reg.collisions)
+}
+
+func TestRegisterJITRunnerKeepsRunnerOfKnownInstance(t *testing.T) {
+	pool := params.Pool{ID: "test-pool"}
+	store := dbMocks.NewStore(t)
+	cli := mocks.NewGithubClient(t)
+	r := newCollisionTestPoolManager(t, store, cli)
+
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-taken", pool, mock.Anything).Return(nil, nil, conflictErr("garm-taken")).Once()
+	store.On("GetInstanceByName", mock.Anything, "garm-taken").Return(params.Instance{Name: "garm-taken"}, nil).Once()
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-new", pool, mock.Anything).Return(
+		map[string]string{".runner": "config"}, &github.Runner{ID: github.Int64(11)}, nil).Once()
+
+	reg, err := r.registerJITRunner(context.Background(), "garm-taken", pool, nil, func() (string, error) {
+		return "garm-new", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "garm-new", reg.name)
+}
+
+func TestRegisterJITRunnerOtherErrors(t *testing.T) {
+	pool := params.Pool{ID: "test-pool"}
+	cli := mocks.NewGithubClient(t)
+	r := newCollisionTestPoolManager(t, dbMocks.NewStore(t), cli)
+
+	cli.On("GetEntityJITConfig", mock.Anything, "garm-runner", pool, mock.Anything).Return(
+		nil, nil, errors.New("failed to get JIT config: boom")).Once()
+
+	reg, err := r.registerJITRunner(context.Background(), "garm-runner", pool, nil, noNewName)
+	require.EqualError(t, err, "failed to get JIT config: boom")
+	require.Equal(t, "garm-runner", reg.name)
+	require.Empty(t, reg.collisions)
+}
@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+)
+
+// observeJobPlacement records the pool in which a runner would have been created for
+// a queued job. It is used in observer mode, instead of creating the runner. The pools
+// are tried in the same order and with the same checks used when creating runners.
+func (r *basePoolManager) observeJobPlacement(job params.Job, pools poolCacheStore) {
+	var reasons []string
+	for i := 0; i < pools.Len(); i++ {
+		pool, err := pools.Next()
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			break
+		}
+
+		if err := r.checkPoolCanAddRunner(pool); err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+
+		slog.InfoContext(
+			r.ctx, "observer mode: a runner would have been created for job",
+			"job_id", job.ID,
+			"pool_id", pool.ID,
+			"provider", pool.ProviderName)
+		r.addEntityEvent(r.ctx, params.ObserverEvent, params.EventInfo, fmt.Sprintf(
+			"job %d (%s) would have been handled by a runner in pool %s (provider %s)",
+			job.ID, job.Name, pool.ID, pool.ProviderName))
+		metrics.JobsObservedPlacements.WithLabelValues(
+			r.entity.String(), // label: entity
+			pool.ID,           // label: pool_id
+			pool.ProviderName, // label: provider
+		).Inc()
+		return
+	}
+
+	slog.WarnContext(
+		r.ctx, "observer mode: no pool could have created a runner for job",
+		"job_id", job.ID,
+		"reasons", strings.Join(reasons, "; "))
+	r.addEntityEvent(r.ctx, params.ObserverEvent, params.EventWarning, fmt.Sprintf(
+		"no pool could have handled job %d (%s): %s",
+		job.ID, job.Name, strings.Join(reasons, "; ")))
+	metrics.JobsObservedPlacements.WithLabelValues(
+		r.entity.String(), // label: entity
+		"",                // label: pool_id
+		"",                // label: provider
+	).Inc()
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestObserveJobPlacement(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	disabled := params.Pool{ID: "disabled-pool", ProviderName: "test-provider"}
+	full := params.Pool{ID: "full-pool", ProviderName: "test-provider", Enabled: true, MaxRunners: 1}
+	available := params.Pool{ID: "available-pool", ProviderName: "test-provider", Enabled: true, MaxRunners: 2}
+
+	store := dbMocks.NewStore(t)
+	store.On("ListPausedProviders", mock.Anything).Return([]params.ProviderPause{}, nil)
+	store.On("ListDenyRules", mock.Anything).Return([]params.DenyRule{}, nil)
+	store.On("PoolInstanceCount", mock.Anything, full.ID).Return(int64(1), nil)
+	store.On("PoolInstanceCount", mock.Anything, available.ID).Return(int64(1), nil)
+	store.On("AddEntityEvent", mock.Anything, entity, params.ObserverEvent, params.EventInfo,
+		"job 1 (build) would have been handled by a runner in pool available-pool (provider test-provider)",
+		mock.Anything).Return(nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.ObserverEvent, params.EventWarning,
+		"no pool could have handled job 2 (test): pool disabled-pool is disabled",
+		mock.Anything).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:          context.Background(),
+		entity:       entity,
+		store:        store,
+		observerMode: true,
+	}
+	r.observeJobPlacement(params.Job{ID: 1, Name: "build"}, &poolRoundRobin{
+		pools: []params.Pool{disabled, full, available},
+	})
+	r.observeJobPlacement(params.Job{ID: 2, Name: "test"}, &poolRoundRobin{
+		pools: []params.Pool{disabled},
+	})
+
+	if err := r.AddRunner(context.Background(), available.ID, nil); err == nil {
+		t.Fatalf("expected adding a runner to fail in observer mode")
+	}
+}
@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// creationPacingWindow is the window over which the runners created in a pool are
+// counted, for pools that limit the number of runners created each minute.
+const creationPacingWindow = 1 * time.Minute
+
+// creationPacer counts the runners created in each pool over a sliding window of one
+// minute. It is only used by the add_pending loop.
+type creationPacer struct {
+	created map[string][]time.Time
+}
+
+func newCreationPacer() *creationPacer {
+	return &creationPacer{
+		created: map[string][]time.Time{},
+	}
+}
+
+// allow returns true if a runner can be created in the pool, without going over the
+// max creates per minute of the pool.
+func (c *creationPacer) allow(pool params.Pool, now time.Time) bool {
+	if pool.MaxCreatesPerMinute == 0 {
+		return true
+	}
+	created := c.created[pool.ID]
+	for len(created) > 0 && now.Sub(created[0]) >= creationPacingWindow {
+		created = created[1:]
+	}
+	if len(created) == 0 {
+		delete(c.created, pool.ID)
+	} else {
+		c.created[pool.ID] = created
+	}
+	return uint(len(created)) < pool.MaxCreatesPerMinute
+}
+
+// record counts the creation of a runner in the pool.
+func (c *creationPacer) record(pool params.Pool, now time.Time) {
+	if pool.MaxCreatesPerMinute == 0 {
+		return
+	}
+	c.created[pool.ID] = append(c.created[pool.ID], now)
+}
+
+// creationJitter returns the random amount of time a runner of the pool waits before
+// it is created.
+func creationJitter(pool params.Pool) time.Duration {
+	if pool.CreateJitter == 0 {
+		return 0
+	}
+	return rand.N(time.Duration(pool.CreateJitter) * time.Second)
+}
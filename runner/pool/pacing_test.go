@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestCreationPacer(t *testing.T) {
+	paced := params.Pool{ID: "paced-pool", MaxCreatesPerMinute: 2}
+	unpaced := params.Pool{ID: "unpaced-pool"}
+	pacer := newCreationPacer()
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if !pacer.allow(paced, now) {
+			t.Fatalf("expected creation %d to be allowed", i+1)
+		}
+		pacer.record(paced, now)
+	}
+	if pacer.allow(paced, now.Add(30*time.Second)) {
+		t.Fatalf("expected creation over the limit to be refused")
+	}
+	if !pacer.allow(paced, now.Add(creationPacingWindow)) {
+		t.Fatalf("expected creation to be allowed once the window passed")
+	}
+
+	for i := 0; i < 10; i++ {
+		if !pacer.allow(unpaced, now) {
+			t.Fatalf("expected creations in pools with no limit to be allowed")
+		}
+		pacer.record(unpaced, now)
+	}
+	if _, ok := pacer.created[unpaced.ID]; ok {
+		t.Fatalf("expected creations in pools with no limit not to be recorded")
+	}
+}
+
+func TestCreationJitter(t *testing.T) {
+	if jitter := creationJitter(params.Pool{}); jitter != 0 {
+		t.Fatalf("expected no jitter, got %s", jitter)
+	}
+	pool := params.Pool{CreateJitter: 10}
+	for i := 0; i < 100; i++ {
+		jitter := creationJitter(pool)
+		if jitter < 0 || jitter >= 10*time.Second {
+			t.Fatalf("jitter %s out of range", jitter)
+		}
+	}
+}
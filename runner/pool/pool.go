@@ -20,6 +20,8 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,16 +30,21 @@ import (
 	"github.com/google/go-github/v57/github"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm-provider-common/util"
 	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/config"
 	dbCommon "github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/database/watcher"
+	"github.com/cloudbase/garm/metrics"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	garmTools "github.com/cloudbase/garm/runner/tools"
+	"github.com/cloudbase/garm/tracing"
 	garmUtil "github.com/cloudbase/garm/util"
 )
 
@@ -51,6 +58,14 @@ var (
 	// The job it picked up would already be transitioned to in_progress so it will be ignored by the
 	// consume loop.
 	jobLabelPrefix = "in_response_to_job:"
+	// jobPoolPinLabelPrefix is the prefix of the label that can be used in a workflow
+	// to pin a job to a particular pool. The label is only honored if job pool pinning
+	// is enabled in the config.
+	jobPoolPinLabelPrefix = "garm-pool="
+	// runnerStatusReconcileGracePeriod is the time we wait after the last update of
+	// an instance, before we correct its runner status based on the GitHub busy flag.
+	// This gives workflow job webhooks a chance to arrive.
+	runnerStatusReconcileGracePeriod = 2 * time.Minute
 )
 
 const (
@@ -62,7 +77,9 @@ const (
 	maxCreateAttempts = 5
 )
 
-func NewEntityPoolManager(ctx context.Context, entity params.GithubEntity, instanceTokenGetter auth.InstanceTokenGetter, providers map[string]common.Provider, store dbCommon.Store) (common.PoolManager, error) {
+// NewEntityPoolManager returns a pool manager for the entity. The options in the default
+// section of the config that change how pools behave are read from cfg.
+func NewEntityPoolManager(ctx context.Context, entity params.GithubEntity, instanceTokenGetter auth.InstanceTokenGetter, providers map[string]common.Provider, store dbCommon.Store, cfg config.Default, leaderElector common.LeaderElector, bootstrapTransformer common.BootstrapTransformer) (common.PoolManager, error) {
 	ctx = garmUtil.WithContext(ctx, slog.Any("pool_mgr", entity.String()), slog.Any("pool_type", entity.EntityType))
 	ghc, err := garmUtil.GithubClient(ctx, entity, entity.Credentials)
 	if err != nil {
@@ -89,7 +106,7 @@ func NewEntityPoolManager(ctx context.Context, entity params.GithubEntity, insta
 	}
 
 	wg := &sync.WaitGroup{}
-	keyMuxes := &keyMutex{}
+	keyMuxes := newKeyMutex()
 
 	repo := &basePoolManager{
 		ctx:                 ctx,
@@ -104,6 +121,18 @@ func NewEntityPoolManager(ctx context.Context, entity params.GithubEntity, insta
 		wg:        wg,
 		keyMux:    keyMuxes,
 		consumer:  consumer,
+
+		enableJobPoolPinning:   cfg.EnableJobPoolPinning,
+		verifyActionsPolicy:    cfg.VerifyActionsPolicy,
+		reconcileJobsOnStartup: cfg.ReconcileJobsOnStartup,
+		maxConcurrentJobs:      cfg.MaxConcurrentJobs,
+		observerMode:           cfg.ObserverMode,
+		gateEnvironmentJobs:    cfg.GateEnvironmentJobs,
+		rateLimitThreshold:     cfg.RateLimitThreshold(),
+		leaderElector:          leaderElector,
+		bootstrapTransformer:   bootstrapTransformer,
+
+		enableWebhookManagement: cfg.EnableWebhookManagement,
 	}
 	return repo, nil
 }
@@ -122,9 +151,84 @@ type basePoolManager struct {
 	tools     []commonParams.RunnerApplicationDownload
 	quit      chan struct{}
 
+	hookDeliveryStats       *params.HookDeliveryStats
+	hookDeliveryStatsHookID int64
+	// enableWebhookManagement is set if GARM may install webhooks. The delivery
+	// stats of the webhook are only fetched if it is set.
+	enableWebhookManagement bool
+
+	enableJobPoolPinning   bool
+	verifyActionsPolicy    bool
+	reconcileJobsOnStartup bool
+	// queuedJobsReconciled is set once the queued jobs were reconciled after this
+	// controller became the leader of the entity. It is only used by the job queue
+	// consumer loop.
+	queuedJobsReconciled bool
+	// maxConcurrentJobs is the global limit of jobs that can have runners
+	// at the same time. A value of 0 means no limit.
+	maxConcurrentJobs uint
+	// observerMode disables the creation and removal of runners. Queued jobs
+	// are only used to record where runners would have been created.
+	observerMode bool
+	// gateEnvironmentJobs records jobs that wait for the approval of a protected
+	// environment, so that no runner is created for them until they are approved.
+	gateEnvironmentJobs bool
+	// rateLimitThreshold is the number of remaining GitHub API requests below which
+	// non critical loops are deferred until the quota is reset.
+	rateLimitThreshold uint
+	// leaderElector tells us if this controller manages the entity. It is nil
+	// when clustering is disabled.
+	leaderElector common.LeaderElector
+	// bootstrapTransformer changes the bootstrap params of runners before they are
+	// sent to the provider. It is nil when no transformer is configured.
+	bootstrapTransformer common.BootstrapTransformer
+
+	webhookInstallAttempts    int
+	webhookInstallNextAttempt time.Time
+
+	// deniedPools holds the ID of the deny rule that matched each pool, so that
+	// a denied pool is only reported once.
+	deniedPools sync.Map
+	// capacityWarnings holds the IDs of the pools that are above their capacity
+	// warning threshold, so that the warning is only reported once.
+	capacityWarnings sync.Map
+	// autoscaleLastBurst holds the time the queue depth autoscaler last scaled up
+	// each pool. It is only used by the autoscaler loop.
+	autoscaleLastBurst map[string]time.Time
+	// rollingUpdateLastBatch holds the time the last batch of outdated runners was
+	// replaced in each pool. It is only used by the rolling update loop.
+	rollingUpdateLastBatch map[string]time.Time
+	// rateLimitWarned is set while the rate limit of the entity credentials is forecast
+	// to run out, so that the warning is only reported once. It is only used by the rate
+	// limit forecast loop.
+	rateLimitWarned bool
+	// rateLimitThrottledUntil is the reset time of the quota that non critical loops
+	// are waiting for, so that throttling is only reported once. Guarded by mux.
+	rateLimitThrottledUntil time.Time
+	// creationPacer limits the number of runners created in pools that set a max
+	// creates per minute. It is only used by the add_pending loop.
+	creationPacer *creationPacer
+	// poolReconciles holds the pools that are being reconciled after they were
+	// updated, and whether another update arrived in the meantime.
+	poolReconcilesMux sync.Mutex
+	poolReconciles    map[string]bool
+	// toolsRefreshRunning is set while the tools are being refreshed in the background,
+	// and toolsRefreshQueued if another refresh was requested in the meantime.
+	toolsRefreshMux     sync.Mutex
+	toolsRefreshRunning bool
+	toolsRefreshQueued  bool
+	// providerHealth holds the result of the health checks of the providers used by
+	// the pools of the entity, and degradedPools the pools that use an unhealthy
+	// provider. Both are updated by the provider health check loop, under mux.
+	providerHealth map[string]*providerHealthState
+	degradedPools  []params.DegradedPool
+
 	managerIsRunning   bool
 	managerErrorReason string
 
+	loopsMux sync.Mutex
+	loops    map[string]*loopState
+
 	mux    sync.Mutex
 	wg     *sync.WaitGroup
 	keyMux *keyMutex
@@ -141,6 +245,23 @@ func (r *basePoolManager) getProviderBaseParams(pool params.Pool) common.Provide
 }
 
 func (r *basePoolManager) HandleWorkflowJob(job params.WorkflowJob) error {
+	switch job.Action {
+	case "queued":
+		// Recording a queued job is the first step on the path to a runner, which is
+		// part of the trace started by the webhook of the job.
+		_, span := tracing.StartJobSpan(
+			r.ctx, job.WorkflowJob.ID, "pool.record_queued_job",
+			attribute.String("garm.entity", r.entity.String()))
+		err := r.handleWorkflowJob(job)
+		tracing.EndSpan(span, err)
+		return err
+	case "completed":
+		tracing.ForgetJob(job.WorkflowJob.ID)
+	}
+	return r.handleWorkflowJob(job)
+}
+
+func (r *basePoolManager) handleWorkflowJob(job params.WorkflowJob) error {
 	if err := r.ValidateOwner(job); err != nil {
 		return errors.Wrap(err, "validating owner")
 	}
@@ -173,7 +294,7 @@ func (r *basePoolManager) HandleWorkflowJob(job params.WorkflowJob) error {
 				return
 			}
 			// This job is new to us. Check if we have a pool that can handle it.
-			potentialPools, err := r.store.FindPoolsMatchingAllTags(r.ctx, r.entity.EntityType, r.entity.ID, jobParams.Labels)
+			potentialPools, err := r.findPoolsForJobLabels(jobParams.Labels)
 			if err != nil {
 				slog.With(slog.Any("error", err)).WarnContext(
 					r.ctx, "failed to find pools matching tags; not recording job",
@@ -214,6 +335,21 @@ func (r *basePoolManager) HandleWorkflowJob(job params.WorkflowJob) error {
 		if err != nil {
 			return errors.Wrap(err, "converting job to params")
 		}
+	case "waiting":
+		if !r.gateEnvironmentJobs {
+			return nil
+		}
+		// The job targets a protected environment and waits for approval. GitHub may have
+		// sent the queued event first, so we record the job as waiting, which stops us from
+		// creating a runner for it. The job is queued again once it is approved.
+		jobParams, err = r.paramsWorkflowJobToParamsJob(job)
+		if err != nil {
+			return errors.Wrap(err, "converting job to params")
+		}
+		jobParams.Status = string(params.JobStatusWaiting)
+		slog.InfoContext(
+			r.ctx, "job is waiting for environment approval",
+			"job_id", jobParams.ID)
 	case "completed":
 		jobParams, err = r.paramsWorkflowJobToParamsJob(job)
 		if err != nil {
@@ -312,6 +448,11 @@ func jobIDFromLabels(labels []string) int64 {
 }
 
 func (r *basePoolManager) startLoopForFunction(f func() error, interval time.Duration, name string, alwaysRun bool) {
+	generation := r.registerLoop(name, f, interval, alwaysRun)
+	r.runLoop(name, f, interval, alwaysRun, generation)
+}
+
+func (r *basePoolManager) runLoop(name string, f func() error, interval time.Duration, alwaysRun bool, generation uint64) {
 	slog.InfoContext(
 		r.ctx, "starting loop for entity",
 		"loop_name", name)
@@ -319,6 +460,16 @@ func (r *basePoolManager) startLoopForFunction(f func() error, interval time.Dur
 	r.wg.Add(1)
 
 	defer func() {
+		// Panics of the loop function are recovered in runLoopFunction. Anything
+		// else that brings the loop down is recorded, so the watchdog restarts it.
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(
+				r.ctx, "pool loop panicked",
+				"loop_name", name,
+				"panic", rec,
+				"stack", string(debug.Stack()))
+			r.loopExited(name, generation)
+		}
 		slog.InfoContext(
 			r.ctx, "pool loop exited",
 			"loop_name", name)
@@ -327,6 +478,10 @@ func (r *basePoolManager) startLoopForFunction(f func() error, interval time.Dur
 	}()
 
 	for {
+		if !r.loopHeartbeat(name, generation) {
+			// The pool manager was restarted and started a new instance of this loop.
+			return
+		}
 		shouldRun := r.managerIsRunning
 		if alwaysRun {
 			shouldRun = true
@@ -335,7 +490,7 @@ func (r *basePoolManager) startLoopForFunction(f func() error, interval time.Dur
 		case true:
 			select {
 			case <-ticker.C:
-				if err := f(); err != nil {
+				if err := r.runLoopFunction(name, f); err != nil {
 					slog.With(slog.Any("error", err)).ErrorContext(
 						r.ctx, "error in loop",
 						"loop_name", name)
@@ -379,6 +534,15 @@ func (r *basePoolManager) updateTools() error {
 	r.mux.Unlock()
 
 	slog.DebugContext(r.ctx, "successfully updated tools")
+
+	if r.verifyActionsPolicy {
+		if err := r.checkActionsPolicy(); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "actions policy does not allow runners for entity", "entity", r.entity.String())
+			r.setPoolRunningState(false, err.Error())
+			return fmt.Errorf("failed to verify actions policy for entity %s: %w", r.entity.String(), err)
+		}
+	}
 	r.setPoolRunningState(true, "")
 	return err
 }
@@ -398,7 +562,7 @@ func (r *basePoolManager) cleanupOrphanedProviderRunners(runners []*github.Runne
 
 	runnerNames := map[string]bool{}
 	for _, run := range runners {
-		if !isManagedRunner(labelsFromRunner(run), r.controllerInfo.ControllerID.String()) {
+		if !isManagedRunner(labelsFromRunner(run), r.managedControllerIDs()...) {
 			slog.DebugContext(
 				r.ctx, "runner is not managed by a pool we manage",
 				"runner_name", run.GetName())
@@ -408,14 +572,14 @@ func (r *basePoolManager) cleanupOrphanedProviderRunners(runners []*github.Runne
 	}
 
 	for _, instance := range dbInstances {
-		lockAcquired := r.keyMux.TryLock(instance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instance.Name)
 		if !lockAcquired {
 			slog.DebugContext(
 				r.ctx, "failed to acquire lock for instance",
 				"runner_name", instance.Name)
 			continue
 		}
-		defer r.keyMux.Unlock(instance.Name, false)
+		defer r.keyMux.Unlock(instance.Name, fence)
 
 		switch instance.Status {
 		case commonParams.InstancePendingCreate,
@@ -474,7 +638,7 @@ func (r *basePoolManager) reapTimedOutRunners(runners []*github.Runner) error {
 
 	runnersByName := map[string]*github.Runner{}
 	for _, run := range runners {
-		if !isManagedRunner(labelsFromRunner(run), r.controllerInfo.ControllerID.String()) {
+		if !isManagedRunner(labelsFromRunner(run), r.managedControllerIDs()...) {
 			slog.DebugContext(
 				r.ctx, "runner is not managed by a pool we manage",
 				"runner_name", run.GetName())
@@ -487,14 +651,14 @@ func (r *basePoolManager) reapTimedOutRunners(runners []*github.Runner) error {
 		slog.DebugContext(
 			r.ctx, "attempting to lock instance",
 			"runner_name", instance.Name)
-		lockAcquired := r.keyMux.TryLock(instance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instance.Name)
 		if !lockAcquired {
 			slog.DebugContext(
 				r.ctx, "failed to acquire lock for instance",
 				"runner_name", instance.Name)
 			continue
 		}
-		defer r.keyMux.Unlock(instance.Name, false)
+		defer r.keyMux.Unlock(instance.Name, fence)
 
 		pool, err := r.store.GetEntityPool(r.ctx, r.entity, instance.PoolID)
 		if err != nil {
@@ -513,6 +677,15 @@ func (r *basePoolManager) reapTimedOutRunners(runners []*github.Runner) error {
 			slog.InfoContext(
 				r.ctx, "reaping timed-out/failed runner",
 				"runner_name", instance.Name)
+			switch instance.Status {
+			case commonParams.InstancePendingDelete, commonParams.InstancePendingForceDelete, commonParams.InstanceDeleting:
+				// The instance was already reaped. Diagnostics are only captured once.
+			default:
+				r.recordProviderOperation(
+					instance.Name, params.EventWarning,
+					"runner did not register within the %d minute timeout of the pool", pool.RunnerTimeout())
+				r.captureInstanceDiagnostics(instance, pool)
+			}
 			if err := r.DeleteRunner(instance, false, false); err != nil {
 				slog.With(slog.Any("error", err)).ErrorContext(
 					r.ctx, "failed to update runner status",
@@ -532,7 +705,7 @@ func (r *basePoolManager) cleanupOrphanedGithubRunners(runners []*github.Runner)
 	poolInstanceCache := map[string][]commonParams.ProviderInstance{}
 	g, ctx := errgroup.WithContext(r.ctx)
 	for _, runner := range runners {
-		if !isManagedRunner(labelsFromRunner(runner), r.controllerInfo.ControllerID.String()) {
+		if !isManagedRunner(labelsFromRunner(runner), r.managedControllerIDs()...) {
 			slog.DebugContext(
 				r.ctx, "runner is not managed by a pool we manage",
 				"runner_name", runner.GetName())
@@ -611,14 +784,16 @@ func (r *basePoolManager) cleanupOrphanedGithubRunners(runners []*github.Runner)
 					ProviderBaseParams: r.getProviderBaseParams(pool),
 				},
 			}
+			started := time.Now()
 			poolInstances, err = provider.ListInstances(r.ctx, pool.ID, listInstancesParams)
+			observeProviderOperation("ListInstances", pool, started, err)
 			if err != nil {
 				return errors.Wrapf(err, "fetching instances for pool %s", pool.ID)
 			}
 			poolInstanceCache[pool.ID] = poolInstances
 		}
 
-		lockAcquired := r.keyMux.TryLock(dbInstance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(dbInstance.Name)
 		if !lockAcquired {
 			slog.DebugContext(
 				r.ctx, "failed to acquire lock for instance",
@@ -629,9 +804,8 @@ func (r *basePoolManager) cleanupOrphanedGithubRunners(runners []*github.Runner)
 		// See: https://golang.org/doc/faq#closures_and_goroutines
 		runner := runner
 		g.Go(func() error {
-			deleteMux := false
 			defer func() {
-				r.keyMux.Unlock(dbInstance.Name, deleteMux)
+				r.keyMux.Unlock(dbInstance.Name, fence)
 			}()
 			providerInstance, ok := instanceInList(dbInstance.Name, poolInstances)
 			if !ok {
@@ -658,7 +832,6 @@ func (r *basePoolManager) cleanupOrphanedGithubRunners(runners []*github.Runner)
 				if err := r.store.DeleteInstance(ctx, dbInstance.PoolID, dbInstance.Name); err != nil {
 					return errors.Wrap(err, "removing runner from database")
 				}
-				deleteMux = true
 				return nil
 			}
 
@@ -681,7 +854,10 @@ func (r *basePoolManager) cleanupOrphanedGithubRunners(runners []*github.Runner)
 					ProviderBaseParams: r.getProviderBaseParams(pool),
 				},
 			}
-			if err := provider.Start(r.ctx, dbInstance.ProviderID, startParams); err != nil {
+			started := time.Now()
+			err := provider.Start(r.ctx, dbInstance.ProviderID, startParams)
+			observeProviderOperation("Start", pool, started, err)
+			if err != nil {
 				return errors.Wrapf(err, "starting instance %s", dbInstance.ProviderID)
 			}
 			return nil
@@ -737,39 +913,71 @@ func (r *basePoolManager) setInstanceStatus(runnerName string, status commonPara
 }
 
 func (r *basePoolManager) AddRunner(ctx context.Context, poolID string, aditionalLabels []string) (err error) {
+	if r.observerMode {
+		return runnerErrors.NewBadRequestError("controller is in observer mode; runners are not created")
+	}
+
+	jobID := jobIDFromLabels(aditionalLabels)
+	ctx, span := tracing.StartJobSpan(ctx, jobID, "pool.add_runner", attribute.String("garm.pool.id", poolID))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
 	pool, err := r.store.GetEntityPool(r.ctx, r.entity, poolID)
 	if err != nil {
 		return errors.Wrap(err, "fetching pool")
 	}
 
+	if err := r.checkPoolDenyRules(pool); err != nil {
+		return runnerErrors.NewBadRequestError("%s", err)
+	}
+
 	provider, ok := r.providers[pool.ProviderName]
 	if !ok {
 		return fmt.Errorf("unknown provider %s for pool %s", pool.ProviderName, pool.ID)
 	}
 
-	name := fmt.Sprintf("%s-%s", pool.GetRunnerPrefix(), util.NewID())
+	nameConstraints := provider.AsParams().NameConstraints
+	name, err := nameConstraints.FormatName(pool.GetRunnerPrefix(), nameConstraints.NewInstanceID())
+	if err != nil {
+		return errors.Wrap(err, "generating instance name")
+	}
 	labels := r.getLabelsForInstance(pool)
+	span.SetAttributes(attribute.String("garm.runner.name", name))
 
 	jitConfig := make(map[string]string)
 	var runner *github.Runner
 
+	var nameCollisions []string
+
 	if !provider.DisableJITConfig() {
-		// Attempt to create JIT config
-		jitConfig, runner, err = r.ghcli.GetEntityJITConfig(ctx, name, pool, labels)
-		if err != nil {
-			slog.With(slog.Any("error", err)).ErrorContext(
+		// Attempt to create JIT config. This registers the runner in GitHub.
+		_, registerSpan := tracing.StartJobSpan(ctx, jobID, "github.register_runner", attribute.String("garm.runner.name", name))
+		reg, regErr := r.registerJITRunner(ctx, name, pool, labels, func() (string, error) {
+			return nameConstraints.FormatName(pool.GetRunnerPrefix(), nameConstraints.NewInstanceID())
+		})
+		tracing.EndSpan(registerSpan, regErr)
+		name = reg.name
+		nameCollisions = reg.collisions
+		if regErr != nil {
+			slog.With(slog.Any("error", regErr)).ErrorContext(
 				ctx, "failed to get JIT config, falling back to registration token")
+		} else {
+			jitConfig, runner = reg.jitConfig, reg.runner
 		}
 	}
 
+	// Pools may select a named URL set, pointing runners at a callback and metadata
+	// endpoint closer to where they are spawned.
+	metadataURL, callbackURL := r.controllerInfo.RunnerURLs(pool.URLSet)
 	createParams := params.CreateInstanceParams{
 		Name:              name,
 		Status:            commonParams.InstancePendingCreate,
 		RunnerStatus:      params.RunnerPending,
 		OSArch:            pool.OSArch,
 		OSType:            pool.OSType,
-		CallbackURL:       r.controllerInfo.CallbackURL,
-		MetadataURL:       r.controllerInfo.MetadataURL,
+		CallbackURL:       callbackURL,
+		MetadataURL:       metadataURL,
 		CreateAttempt:     1,
 		GitHubRunnerGroup: pool.GitHubRunnerGroup,
 		AditionalLabels:   aditionalLabels,
@@ -780,11 +988,10 @@ func (r *basePoolManager) AddRunner(ctx context.Context, poolID string, aditiona
 		createParams.AgentID = runner.GetID()
 	}
 
-	instance, err := r.store.CreateInstance(r.ctx, poolID, createParams)
-	if err != nil {
-		return errors.Wrap(err, "creating instance")
-	}
-
+	var instance params.Instance
+	// The cleanup must be registered before the instance is created. If we fail to record
+	// the instance, the runner registration created along with the JIT config would linger
+	// in GitHub, as nothing else references it.
 	defer func() {
 		if err != nil {
 			if instance.ID != "" {
@@ -797,15 +1004,33 @@ func (r *basePoolManager) AddRunner(ctx context.Context, poolID string, aditiona
 
 			if runner != nil {
 				_, runnerCleanupErr := r.ghcli.RemoveEntityRunner(r.ctx, runner.GetID())
-				if err != nil {
+				if runnerCleanupErr != nil {
 					slog.With(slog.Any("error", runnerCleanupErr)).ErrorContext(
 						ctx, "failed to remove runner",
 						"gh_runner_id", runner.GetID())
+				} else {
+					metrics.InstanceLeakedJITRegistrationsRemoved.WithLabelValues(
+						pool.ID,             // label: pool_id
+						"add_runner_failed", // label: reason
+					).Inc()
 				}
 			}
 		}
 	}()
 
+	instance, err = r.store.CreateInstance(r.ctx, poolID, createParams)
+	if err != nil {
+		return errors.Wrap(err, "creating instance")
+	}
+
+	for _, msg := range nameCollisions {
+		if err := r.store.AddInstanceEvent(r.ctx, instance.Name, params.StatusEvent, params.EventWarning, msg, common.MaxInstanceEvents); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				ctx, "failed to add instance event",
+				"runner_name", instance.Name)
+		}
+	}
+
 	return nil
 }
 
@@ -815,6 +1040,7 @@ func (r *basePoolManager) Status() params.PoolManagerStatus {
 	return params.PoolManagerStatus{
 		IsRunning:     r.managerIsRunning,
 		FailureReason: r.managerErrorReason,
+		DegradedPools: slices.Clone(r.degradedPools),
 	}
 }
 
@@ -832,9 +1058,21 @@ func (r *basePoolManager) waitForTimeoutOrCancelled(timeout time.Duration) {
 
 func (r *basePoolManager) setPoolRunningState(isRunning bool, failureReason string) {
 	r.mux.Lock()
+	changed := r.managerIsRunning != isRunning || r.managerErrorReason != failureReason
+	previousReason := r.managerErrorReason
 	r.managerErrorReason = failureReason
 	r.managerIsRunning = isRunning
 	r.mux.Unlock()
+
+	// Record state changes as entity events, so failures of the pool manager can be
+	// inspected after the fact and alerted on.
+	switch {
+	case !changed:
+	case !isRunning:
+		r.addEntityEvent(r.ctx, params.PoolManagerEvent, params.EventError, fmt.Sprintf("pool manager stopped: %s", failureReason))
+	case previousReason != "":
+		r.addEntityEvent(r.ctx, params.PoolManagerEvent, params.EventInfo, "pool manager recovered")
+	}
 }
 
 func (r *basePoolManager) getLabelsForInstance(pool params.Pool) []string {
@@ -844,6 +1082,9 @@ func (r *basePoolManager) getLabelsForInstance(pool params.Pool) []string {
 	}
 	labels = append(labels, r.controllerLabel())
 	labels = append(labels, r.poolLabel(pool.ID))
+	if r.enableJobPoolPinning {
+		labels = append(labels, poolPinLabel(pool.ID))
+	}
 	return labels
 }
 
@@ -861,12 +1102,13 @@ func (r *basePoolManager) addInstanceToProvider(instance params.Instance) error
 	jwtValidity := pool.RunnerTimeout()
 
 	entity := r.entity.String()
-	jwtToken, err := r.instanceTokenGetter.NewInstanceJWTToken(instance, entity, pool.PoolType(), jwtValidity)
+	jwtToken, err := r.instanceTokenGetter.NewInstanceJWTToken(instance, entity, pool, jwtValidity)
 	if err != nil {
 		return errors.Wrap(err, "fetching instance jwt token")
 	}
 
 	hasJITConfig := len(instance.JitConfiguration) > 0
+	image := pool.RunnerImage()
 
 	bootstrapArgs := commonParams.BootstrapInstance{
 		Name:              instance.Name,
@@ -878,7 +1120,7 @@ func (r *basePoolManager) addInstanceToProvider(instance params.Instance) error
 		OSArch:            pool.OSArch,
 		OSType:            pool.OSType,
 		Flavor:            pool.Flavor,
-		Image:             pool.Image,
+		Image:             image,
 		ExtraSpecs:        pool.ExtraSpecs,
 		PoolID:            instance.PoolID,
 		CACertBundle:      r.entity.Credentials.CABundle,
@@ -893,6 +1135,14 @@ func (r *basePoolManager) addInstanceToProvider(instance params.Instance) error
 		bootstrapArgs.Labels = r.getLabelsForInstance(pool)
 	}
 
+	if r.bootstrapTransformer != nil {
+		bootstrapArgs, err = r.bootstrapTransformer.Transform(r.ctx, r.entity, bootstrapArgs)
+		if err != nil {
+			r.recordProviderOperation(instance.Name, params.EventError, "failed to transform bootstrap params: %q", err)
+			return errors.Wrap(err, "transforming bootstrap params")
+		}
+	}
+
 	var instanceIDToDelete string
 
 	defer func() {
@@ -902,7 +1152,10 @@ func (r *basePoolManager) addInstanceToProvider(instance params.Instance) error
 					ProviderBaseParams: r.getProviderBaseParams(pool),
 				},
 			}
-			if err := provider.DeleteInstance(r.ctx, instanceIDToDelete, deleteInstanceParams); err != nil {
+			started := time.Now()
+			err := provider.DeleteInstance(r.ctx, instanceIDToDelete, deleteInstanceParams)
+			observeProviderOperation("DeleteInstance", pool, started, err)
+			if err != nil {
 				if !errors.Is(err, runnerErrors.ErrNotFound) {
 					slog.With(slog.Any("error", err)).ErrorContext(
 						r.ctx, "failed to cleanup instance",
@@ -917,20 +1170,45 @@ func (r *basePoolManager) addInstanceToProvider(instance params.Instance) error
 			ProviderBaseParams: r.getProviderBaseParams(pool),
 		},
 	}
+	r.recordProviderOperation(
+		instance.Name, params.EventInfo,
+		"creating instance using provider %s (attempt %d, image: %s, flavor: %s, os: %s/%s)",
+		pool.ProviderName, instance.CreateAttempt, image, pool.Flavor, pool.OSType, pool.OSArch)
+	_, span := tracing.StartJobSpan(
+		r.ctx, jobIDFromLabels(instance.AditionalLabels), "provider.create_instance",
+		attribute.String("garm.provider", pool.ProviderName),
+		attribute.String("garm.pool.id", pool.ID),
+		attribute.String("garm.runner.name", instance.Name),
+		attribute.Int("garm.runner.create_attempt", instance.CreateAttempt))
+	started := time.Now()
 	providerInstance, err := provider.CreateInstance(r.ctx, bootstrapArgs, createInstanceParams)
+	observeProviderOperation("CreateInstance", pool, started, err)
+	tracing.EndSpan(span, err)
 	if err != nil {
+		r.recordProviderOperation(instance.Name, params.EventError, "failed to create instance: %q", err)
 		instanceIDToDelete = instance.Name
 		return errors.Wrap(err, "creating instance")
 	}
 
 	if providerInstance.Status == commonParams.InstanceError {
+		r.recordProviderOperation(
+			instance.Name, params.EventError,
+			"provider returned instance %s in error state: %s", providerInstance.ProviderID, providerInstance.ProviderFault)
 		instanceIDToDelete = instance.ProviderID
 		if instanceIDToDelete == "" {
 			instanceIDToDelete = instance.Name
 		}
+	} else {
+		r.recordProviderOperation(
+			instance.Name, params.EventInfo,
+			"provider created instance %s with status %s", providerInstance.ProviderID, providerInstance.Status)
 	}
 
 	updateInstanceArgs := r.updateArgsFromProviderInstance(providerInstance)
+	// Record the spec of the pool the instance was created from, so we know when it
+	// needs to be replaced after the pool is updated.
+	updateInstanceArgs.Image = image
+	updateInstanceArgs.Flavor = pool.Flavor
 	if _, err := r.store.UpdateInstance(r.ctx, instance.Name, updateInstanceArgs); err != nil {
 		return errors.Wrap(err, "updating instance")
 	}
@@ -992,6 +1270,48 @@ func (r *basePoolManager) poolLabel(poolID string) string {
 	return fmt.Sprintf("%s%s", poolIDLabelprefix, poolID)
 }
 
+// findPoolsForJobLabels returns the pools that can handle a job with the given labels.
+// If job pool pinning is enabled and the job targets a pool using the garm-pool label,
+// only that pool is returned, provided it can satisfy all other labels of the job.
+func (r *basePoolManager) findPoolsForJobLabels(labels []string) ([]params.Pool, error) {
+	if !r.enableJobPoolPinning {
+		return r.store.FindPoolsMatchingAllTags(r.ctx, r.entity.EntityType, r.entity.ID, labels)
+	}
+
+	poolID, otherLabels, err := pinnedPoolFromLabels(labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing job labels")
+	}
+	if poolID == "" {
+		return r.store.FindPoolsMatchingAllTags(r.ctx, r.entity.EntityType, r.entity.ID, labels)
+	}
+
+	pool, err := r.store.GetEntityPool(r.ctx, r.entity, poolID)
+	if err != nil {
+		if errors.Is(err, runnerErrors.ErrNotFound) {
+			return nil, runnerErrors.NewBadRequestError("pinned pool %s does not exist for %s", poolID, r.entity.String())
+		}
+		return nil, errors.Wrap(err, "fetching pinned pool")
+	}
+
+	if !poolHasAllTags(pool, otherLabels) {
+		return nil, runnerErrors.NewBadRequestError("pinned pool %s does not have all the labels requested by the job", poolID)
+	}
+
+	return []params.Pool{pool}, nil
+}
+
+// managedControllerIDs returns the controller IDs runners of this controller may
+// be labeled with. The previous controller ID is only set while the controller ID
+// is being migrated.
+func (r *basePoolManager) managedControllerIDs() []string {
+	ids := []string{r.controllerInfo.ControllerID.String()}
+	if r.controllerInfo.PreviousControllerID != nil {
+		ids = append(ids, r.controllerInfo.PreviousControllerID.String())
+	}
+	return ids
+}
+
 func (r *basePoolManager) controllerLabel() string {
 	return fmt.Sprintf("%s%s", controllerLabelPrefix, r.controllerInfo.ControllerID.String())
 }
@@ -1011,6 +1331,9 @@ func (r *basePoolManager) scaleDownOnePool(ctx context.Context, pool params.Pool
 	slog.DebugContext(
 		ctx, "scaling down pool",
 		"pool_id", pool.ID)
+	if pool.Draining {
+		return r.drainOnePool(ctx, pool)
+	}
 	if !pool.Enabled {
 		slog.DebugContext(
 			ctx, "pool is disabled, skipping scale down",
@@ -1022,14 +1345,11 @@ func (r *basePoolManager) scaleDownOnePool(ctx context.Context, pool params.Pool
 	if err != nil {
 		return fmt.Errorf("failed to ensure minimum idle workers for pool %s: %w", pool.ID, err)
 	}
+	r.checkPoolCapacity(pool, uint(len(existingInstances)))
 
 	idleWorkers := []params.Instance{}
 	for _, inst := range existingInstances {
-		// Idle runners that have been spawned and are still idle after 5 minutes, are take into
-		// consideration for scale-down. The 5 minute grace period prevents a situation where a
-		// "queued" workflow triggers the creation of a new idle runner, and this routine reaps
-		// an idle runner before they have a chance to pick up a job.
-		if inst.RunnerStatus == params.RunnerIdle && inst.Status == commonParams.InstanceRunning && time.Since(inst.UpdatedAt).Minutes() > 2 {
+		if isScaleDownCandidate(inst, pool) {
 			idleWorkers = append(idleWorkers, inst)
 		}
 	}
@@ -1044,7 +1364,7 @@ func (r *basePoolManager) scaleDownOnePool(ctx context.Context, pool params.Pool
 		return nil
 	}
 
-	scaleDownFactor := 0.5 // could be configurable
+	scaleDownFactor := pool.ScaleDownFraction()
 	numScaleDown := int(math.Ceil(surplus * scaleDownFactor))
 
 	if numScaleDown <= 0 || numScaleDown > len(idleWorkers) {
@@ -1056,14 +1376,14 @@ func (r *basePoolManager) scaleDownOnePool(ctx context.Context, pool params.Pool
 	for _, instanceToDelete := range idleWorkers[:numScaleDown] {
 		instanceToDelete := instanceToDelete
 
-		lockAcquired := r.keyMux.TryLock(instanceToDelete.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instanceToDelete.Name)
 		if !lockAcquired {
 			slog.With(slog.Any("error", err)).ErrorContext(
 				ctx, "failed to acquire lock for instance",
 				"provider_id", instanceToDelete.Name)
 			continue
 		}
-		defer r.keyMux.Unlock(instanceToDelete.Name, false)
+		defer r.keyMux.Unlock(instanceToDelete.Name, fence)
 
 		g.Go(func() error {
 			slog.InfoContext(
@@ -1108,10 +1428,43 @@ func (r *basePoolManager) scaleDownOnePool(ctx context.Context, pool params.Pool
 	return nil
 }
 
-func (r *basePoolManager) addRunnerToPool(pool params.Pool, aditionalLabels []string) error {
+// getPausedProviders returns the names of the providers that are paused. Pools that
+// use a paused provider must not create new instances.
+func (r *basePoolManager) getPausedProviders() (map[string]struct{}, error) {
+	pauses, err := r.store.ListPausedProviders(r.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paused providers: %w", err)
+	}
+	ret := make(map[string]struct{}, len(pauses))
+	for _, pause := range pauses {
+		ret[pause.ProviderName] = struct{}{}
+	}
+	for _, name := range r.unhealthyPausedProviders() {
+		ret[name] = struct{}{}
+	}
+	return ret, nil
+}
+
+// checkPoolCanAddRunner returns an error if a new runner can't be added to the pool.
+func (r *basePoolManager) checkPoolCanAddRunner(pool params.Pool) error {
 	if !pool.Enabled {
 		return fmt.Errorf("pool %s is disabled", pool.ID)
 	}
+	if pool.Draining {
+		return fmt.Errorf("pool %s is draining", pool.ID)
+	}
+
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+	if _, ok := paused[pool.ProviderName]; ok {
+		return fmt.Errorf("provider %s of pool %s is paused", pool.ProviderName, pool.ID)
+	}
+
+	if err := r.checkPoolDenyRules(pool); err != nil {
+		return err
+	}
 
 	poolInstanceCount, err := r.store.PoolInstanceCount(r.ctx, pool.ID)
 	if err != nil {
@@ -1121,15 +1474,22 @@ func (r *basePoolManager) addRunnerToPool(pool params.Pool, aditionalLabels []st
 	if poolInstanceCount >= int64(pool.MaxRunners) {
 		return fmt.Errorf("max workers (%d) reached for pool %s", pool.MaxRunners, pool.ID)
 	}
+	return nil
+}
+
+func (r *basePoolManager) addRunnerToPool(ctx context.Context, pool params.Pool, aditionalLabels []string) error {
+	if err := r.checkPoolCanAddRunner(pool); err != nil {
+		return err
+	}
 
-	if err := r.AddRunner(r.ctx, pool.ID, aditionalLabels); err != nil {
+	if err := r.AddRunner(ctx, pool.ID, aditionalLabels); err != nil {
 		return fmt.Errorf("failed to add new instance for pool %s: %s", pool.ID, err)
 	}
 	return nil
 }
 
 func (r *basePoolManager) ensureIdleRunnersForOnePool(pool params.Pool) error {
-	if !pool.Enabled || pool.MinIdleRunners == 0 {
+	if !pool.Enabled || pool.Draining || pool.MinIdleRunners == 0 {
 		return nil
 	}
 
@@ -1178,7 +1538,7 @@ func (r *basePoolManager) ensureIdleRunnersForOnePool(pool params.Pool) error {
 }
 
 func (r *basePoolManager) retryFailedInstancesForOnePool(ctx context.Context, pool params.Pool) error {
-	if !pool.Enabled {
+	if !pool.Enabled || pool.Draining {
 		return nil
 	}
 	slog.DebugContext(
@@ -1204,7 +1564,7 @@ func (r *basePoolManager) retryFailedInstancesForOnePool(ctx context.Context, po
 		slog.DebugContext(
 			ctx, "attempting to retry failed instance",
 			"runner_name", instance.Name)
-		lockAcquired := r.keyMux.TryLock(instance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instance.Name)
 		if !lockAcquired {
 			slog.DebugContext(
 				ctx, "failed to acquire lock for instance",
@@ -1213,7 +1573,7 @@ func (r *basePoolManager) retryFailedInstancesForOnePool(ctx context.Context, po
 		}
 
 		g.Go(func() error {
-			defer r.keyMux.Unlock(instance.Name, false)
+			defer r.keyMux.Unlock(instance.Name, fence)
 			slog.DebugContext(
 				ctx, "attempting to clean up any previous instance",
 				"runner_name", instance.Name)
@@ -1271,9 +1631,17 @@ func (r *basePoolManager) retryFailedInstances() error {
 	if err != nil {
 		return fmt.Errorf("error listing pools: %w", err)
 	}
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
 	g, ctx := errgroup.WithContext(r.ctx)
 	for _, pool := range pools {
 		pool := pool
+		if _, ok := paused[pool.ProviderName]; ok {
+			// Retrying a failed instance creates it again.
+			continue
+		}
 		g.Go(func() error {
 			if err := r.retryFailedInstancesForOnePool(ctx, pool); err != nil {
 				return fmt.Errorf("retrying failed instances for pool %s: %w", pool.ID, err)
@@ -1294,6 +1662,11 @@ func (r *basePoolManager) scaleDown() error {
 	if err != nil {
 		return fmt.Errorf("error listing pools: %w", err)
 	}
+	pools = applyPoolSchedules(pools, time.Now())
+	pools, err = r.applyCapacityReservations(pools)
+	if err != nil {
+		return err
+	}
 	g, ctx := errgroup.WithContext(r.ctx)
 	for _, pool := range pools {
 		pool := pool
@@ -1315,10 +1688,26 @@ func (r *basePoolManager) ensureMinIdleRunners() error {
 	if err != nil {
 		return fmt.Errorf("error listing pools: %w", err)
 	}
+	pools = applyPoolSchedules(pools, time.Now())
+	pools, err = r.applyCapacityReservations(pools)
+	if err != nil {
+		return err
+	}
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
 
 	g, _ := errgroup.WithContext(r.ctx)
 	for _, pool := range pools {
 		pool := pool
+		if _, ok := paused[pool.ProviderName]; ok {
+			slog.DebugContext(
+				r.ctx, "provider is paused, skipping idle worker creation",
+				"provider", pool.ProviderName,
+				"pool_id", pool.ID)
+			continue
+		}
 		g.Go(func() error {
 			return r.ensureIdleRunnersForOnePool(pool)
 		})
@@ -1358,15 +1747,37 @@ func (r *basePoolManager) deleteInstanceFromProvider(ctx context.Context, instan
 			ProviderBaseParams: r.getProviderBaseParams(pool),
 		},
 	}
-	if err := provider.DeleteInstance(ctx, identifier, deleteInstanceParams); err != nil {
+	r.recordProviderOperation(instance.Name, params.EventInfo, "deleting instance %s using provider %s", identifier, pool.ProviderName)
+	started := time.Now()
+	err = provider.DeleteInstance(ctx, identifier, deleteInstanceParams)
+	observeProviderOperation("DeleteInstance", pool, started, err)
+	if err != nil {
+		r.recordProviderOperation(instance.Name, params.EventError, "failed to delete instance: %q", err)
 		return errors.Wrap(err, "removing instance")
 	}
 
 	return nil
 }
 
+// recordProviderOperation adds an event to the instance, describing an operation we
+// asked the provider to perform. These events allow users to find out why an instance
+// failed without access to the GARM logs.
+func (r *basePoolManager) recordProviderOperation(instanceName string, level params.EventLevel, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if err := r.store.AddInstanceEvent(r.ctx, instanceName, params.ProviderOperationEvent, level, msg, common.MaxInstanceEvents); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			r.ctx, "failed to record provider operation",
+			"runner_name", instanceName)
+	}
+}
+
 func (r *basePoolManager) deletePendingInstances() error {
-	instances, err := r.store.ListEntityInstances(r.ctx, r.entity)
+	instances, err := r.store.ListInstances(r.ctx, &r.entity, params.InstanceFilter{
+		Statuses: []commonParams.InstanceStatus{
+			commonParams.InstancePendingDelete,
+			commonParams.InstancePendingForceDelete,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to fetch instances from store: %w", err)
 	}
@@ -1374,16 +1785,11 @@ func (r *basePoolManager) deletePendingInstances() error {
 	slog.DebugContext(
 		r.ctx, "removing instances in pending_delete")
 	for _, instance := range instances {
-		if instance.Status != commonParams.InstancePendingDelete && instance.Status != commonParams.InstancePendingForceDelete {
-			// not in pending_delete status. Skip.
-			continue
-		}
-
 		slog.InfoContext(
 			r.ctx, "removing instance from pool",
 			"runner_name", instance.Name,
 			"pool_id", instance.PoolID)
-		lockAcquired := r.keyMux.TryLock(instance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instance.Name)
 		if !lockAcquired {
 			slog.InfoContext(
 				r.ctx, "failed to acquire lock for instance",
@@ -1398,14 +1804,13 @@ func (r *basePoolManager) deletePendingInstances() error {
 			slog.With(slog.Any("error", err)).ErrorContext(
 				r.ctx, "failed to update runner status",
 				"runner_name", instance.Name)
-			r.keyMux.Unlock(instance.Name, false)
+			r.keyMux.Unlock(instance.Name, fence)
 			continue
 		}
 
 		go func(instance params.Instance) (err error) {
-			deleteMux := false
 			defer func() {
-				r.keyMux.Unlock(instance.Name, deleteMux)
+				r.keyMux.Unlock(instance.Name, fence)
 			}()
 			defer func(instance params.Instance) {
 				if err != nil {
@@ -1440,7 +1845,6 @@ func (r *basePoolManager) deletePendingInstances() error {
 			if deleteErr := r.store.DeleteInstance(r.ctx, instance.PoolID, instance.Name); deleteErr != nil {
 				return fmt.Errorf("failed to delete instance from database: %w", deleteErr)
 			}
-			deleteMux = true
 			slog.InfoContext(
 				r.ctx, "instance was successfully removed",
 				"runner_name", instance.Name)
@@ -1452,15 +1856,49 @@ func (r *basePoolManager) deletePendingInstances() error {
 }
 
 func (r *basePoolManager) addPendingInstances() error {
-	// nolint:golangci-lint,godox
-	// TODO: filter instances by status.
-	instances, err := r.store.ListEntityInstances(r.ctx, r.entity)
+	instances, err := r.store.ListInstances(r.ctx, &r.entity, params.InstanceFilter{
+		Statuses: []commonParams.InstanceStatus{commonParams.InstancePendingCreate},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to fetch instances from store: %w", err)
 	}
+
+	// Instances in pools that use a paused provider are left in pending_create. They
+	// get created once the provider is resumed.
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		return fmt.Errorf("error listing pools: %w", err)
+	}
+	poolsByID := make(map[string]params.Pool, len(pools))
+	pausedPools := map[string]struct{}{}
+	for _, pool := range pools {
+		poolsByID[pool.ID] = pool
+		if _, ok := paused[pool.ProviderName]; ok {
+			pausedPools[pool.ID] = struct{}{}
+		}
+	}
+
+	if r.creationPacer == nil {
+		r.creationPacer = newCreationPacer()
+	}
+
 	for _, instance := range instances {
-		if instance.Status != commonParams.InstancePendingCreate {
-			// not in pending_create status. Skip.
+		if _, ok := pausedPools[instance.PoolID]; ok {
+			continue
+		}
+
+		// Instances over the max creates per minute of their pool are left in
+		// pending_create, and get created on a later run of this loop.
+		pool := poolsByID[instance.PoolID]
+		if !r.creationPacer.allow(pool, time.Now()) {
+			slog.DebugContext(
+				r.ctx, "pool reached its max creates per minute",
+				"runner_name", instance.Name,
+				"pool_id", instance.PoolID)
 			continue
 		}
 
@@ -1468,7 +1906,7 @@ func (r *basePoolManager) addPendingInstances() error {
 			r.ctx, "attempting to acquire lock for instance",
 			"runner_name", instance.Name,
 			"action", "create_pending")
-		lockAcquired := r.keyMux.TryLock(instance.Name)
+		fence, lockAcquired := r.keyMux.TryLock(instance.Name)
 		if !lockAcquired {
 			slog.DebugContext(
 				r.ctx, "failed to acquire lock for instance",
@@ -1482,14 +1920,31 @@ func (r *basePoolManager) addPendingInstances() error {
 			slog.With(slog.Any("error", err)).ErrorContext(
 				r.ctx, "failed to update runner status",
 				"runner_name", instance.Name)
-			r.keyMux.Unlock(instance.Name, false)
+			r.keyMux.Unlock(instance.Name, fence)
 			// We failed to transition the instance to Creating. This means that garm will retry to create this instance
 			// when the loop runs again and we end up with multiple instances.
 			continue
 		}
-
-		go func(instance params.Instance) {
-			defer r.keyMux.Unlock(instance.Name, false)
+		r.creationPacer.record(pool, time.Now())
+
+		go func(instance params.Instance, jitter time.Duration) {
+			defer r.keyMux.Unlock(instance.Name, fence)
+			if jitter > 0 {
+				timer := time.NewTimer(jitter)
+				select {
+				case <-timer.C:
+				case <-r.quit:
+					timer.Stop()
+					// Put the instance back in pending_create, so it gets created once
+					// the pool manager starts again.
+					if _, err := r.setInstanceStatus(instance.Name, commonParams.InstancePendingCreate, nil); err != nil {
+						slog.With(slog.Any("error", err)).ErrorContext(
+							r.ctx, "failed to update runner status",
+							"runner_name", instance.Name)
+					}
+					return
+				}
+			}
 			slog.InfoContext(
 				r.ctx, "creating instance in pool",
 				"runner_name", instance.Name,
@@ -1508,7 +1963,7 @@ func (r *basePoolManager) addPendingInstances() error {
 					r.ctx, "failed to create instance in provider",
 					"runner_name", instance.Name)
 			}
-		}(instance)
+		}(instance, creationJitter(pool))
 	}
 
 	return nil
@@ -1546,9 +2001,116 @@ func (r *basePoolManager) runnerCleanup() (err error) {
 		return fmt.Errorf("failed to cleanup orphaned runners: %w", err)
 	}
 
+	if err := r.reconcileRunnerStatus(runners); err != nil {
+		return fmt.Errorf("failed to reconcile runner status: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileRunnerStatus compares the busy flag of runners in GitHub with the runner
+// status we have recorded in the database and corrects any drift. Drift may happen
+// if we miss an "in_progress" or "completed" workflow job webhook. The runners are
+// the ones already fetched by the runner cleanup loop, so this costs no API calls.
+func (r *basePoolManager) reconcileRunnerStatus(runners []*github.Runner) error {
+	dbInstances, err := r.store.ListEntityInstances(r.ctx, r.entity)
+	if err != nil {
+		return errors.Wrap(err, "fetching instances from db")
+	}
+
+	runnersByName := map[string]*github.Runner{}
+	for _, run := range runners {
+		if !isManagedRunner(labelsFromRunner(run), r.managedControllerIDs()...) {
+			continue
+		}
+		runnersByName[run.GetName()] = run
+	}
+
+	for _, instance := range dbInstances {
+		runner, ok := runnersByName[instance.Name]
+		if !ok {
+			// Runners missing from GitHub are handled by the reaper.
+			continue
+		}
+
+		if _, ok := runnerStatusCorrection(instance, runner); !ok {
+			continue
+		}
+
+		fence, ok := r.keyMux.TryLock(instance.Name)
+		if !ok {
+			slog.DebugContext(
+				r.ctx, "failed to acquire lock for instance",
+				"runner_name", instance.Name)
+			continue
+		}
+
+		// A workflow job webhook may have updated the instance since we listed it.
+		// Check again with the current state, so we don't overwrite it.
+		current, err := r.store.GetInstanceByName(r.ctx, instance.Name)
+		if err != nil {
+			r.keyMux.Unlock(instance.Name, fence)
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to fetch instance",
+				"runner_name", instance.Name)
+			continue
+		}
+
+		newStatus, ok := runnerStatusCorrection(current, runner)
+		if !ok {
+			r.keyMux.Unlock(instance.Name, fence)
+			continue
+		}
+
+		slog.InfoContext(
+			r.ctx, "correcting runner status based on github busy flag",
+			"runner_name", current.Name,
+			"old_status", current.RunnerStatus,
+			"new_status", newStatus)
+		_, err = r.setInstanceRunnerStatus(current.Name, newStatus)
+		r.keyMux.Unlock(instance.Name, fence)
+		if err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to update runner status",
+				"runner_name", current.Name)
+			continue
+		}
+
+		metrics.InstanceRunnerStatusCorrectionCount.WithLabelValues(
+			current.PoolID,               // label: pool_id
+			string(current.RunnerStatus), // label: from
+			string(newStatus),            // label: to
+		).Inc()
+	}
 	return nil
 }
 
+// runnerStatusCorrection returns the runner status the instance should have, based on
+// the busy flag of its runner in GitHub, and whether it needs to be corrected.
+func runnerStatusCorrection(instance params.Instance, runner *github.Runner) (params.RunnerStatus, bool) {
+	if instance.Status != commonParams.InstanceRunning {
+		return "", false
+	}
+
+	// Give webhooks a chance to arrive before we consider the status stale.
+	if time.Since(instance.UpdatedAt) < runnerStatusReconcileGracePeriod {
+		return "", false
+	}
+
+	// Offline runners are handled by the reaper.
+	if runner.GetStatus() != "online" {
+		return "", false
+	}
+
+	switch {
+	case runner.GetBusy() && instance.RunnerStatus == params.RunnerIdle:
+		return params.RunnerActive, true
+	case !runner.GetBusy() && instance.RunnerStatus == params.RunnerActive:
+		return params.RunnerIdle, true
+	}
+	return "", false
+}
+
 func (r *basePoolManager) cleanupOrphanedRunners(runners []*github.Runner) error {
 	if err := r.cleanupOrphanedProviderRunners(runners); err != nil {
 		return errors.Wrap(err, "cleaning orphaned instances")
@@ -1572,6 +2134,7 @@ func (r *basePoolManager) Start() error {
 	}()
 
 	go r.runWatcher()
+	go r.runWatchdog()
 	go func() {
 		select {
 		case <-r.quit:
@@ -1581,15 +2144,32 @@ func (r *basePoolManager) Start() error {
 		case <-initialToolUpdate:
 		}
 		defer close(initialToolUpdate)
-		go r.startLoopForFunction(r.runnerCleanup, common.PoolReapTimeoutInterval, "timeout_reaper", false)
-		go r.startLoopForFunction(r.scaleDown, common.PoolScaleDownInterval, "scale_down", false)
-		// always run the delete pending instances routine. This way we can still remove existing runners, even if the pool is not running.
-		go r.startLoopForFunction(r.deletePendingInstances, common.PoolConsilitationInterval, "consolidate[delete_pending]", true)
-		go r.startLoopForFunction(r.addPendingInstances, common.PoolConsilitationInterval, "consolidate[add_pending]", false)
-		go r.startLoopForFunction(r.ensureMinIdleRunners, common.PoolConsilitationInterval, "consolidate[ensure_min_idle]", false)
-		go r.startLoopForFunction(r.retryFailedInstances, common.PoolConsilitationInterval, "consolidate[retry_failed]", false)
-		go r.startLoopForFunction(r.updateTools, common.PoolToolUpdateInterval, "update_tools", true)
-		go r.startLoopForFunction(r.consumeQueuedJobs, common.PoolConsilitationInterval, "job_queue_consumer", false)
+		// In observer mode we don't touch providers or runners. We only record jobs and
+		// where runners would have been created for them.
+		// When clustering is enabled, only the leader of the entity runs the workers.
+		// The other controllers only keep their tools cache up to date, which is used
+		// to serve runner metadata.
+		if !r.observerMode {
+			go r.startLoopForFunction(r.leaderOnly(r.deferredOnRateLimit("timeout_reaper", r.runnerCleanup)), common.PoolReapTimeoutInterval, "timeout_reaper", false)
+			go r.startLoopForFunction(r.leaderOnly(r.scaleDown), common.PoolScaleDownInterval, "scale_down", false)
+			// always run the delete pending instances routine. This way we can still remove existing runners, even if the pool is not running.
+			go r.startLoopForFunction(r.leaderOnly(r.deletePendingInstances), common.PoolConsilitationInterval, "consolidate[delete_pending]", true)
+			go r.startLoopForFunction(r.leaderOnly(r.addPendingInstances), common.PoolConsilitationInterval, "consolidate[add_pending]", false)
+			go r.startLoopForFunction(r.leaderOnly(r.ensureMinIdleRunners), common.PoolConsilitationInterval, "consolidate[ensure_min_idle]", false)
+			go r.startLoopForFunction(r.leaderOnly(r.autoscale), common.PoolAutoscaleInterval, "autoscale", false)
+			go r.startLoopForFunction(r.leaderOnly(r.rollingUpdate), common.PoolRollingUpdateInterval, "rolling_update", false)
+			go r.startLoopForFunction(r.leaderOnly(r.checkRateLimitForecast), common.PoolRateLimitForecastInterval, "rate_limit_forecast", false)
+			go r.startLoopForFunction(r.leaderOnly(r.retryFailedInstances), common.PoolConsilitationInterval, "consolidate[retry_failed]", false)
+			go r.startLoopForFunction(r.leaderOnly(r.deferredOnRateLimit("cleanup_leaked_jit_registrations", r.cleanupLeakedJITRegistrations)), common.PoolJITRegistrationCleanupInterval, "cleanup_leaked_jit_registrations", false)
+			go r.startLoopForFunction(r.leaderOnly(r.checkProviderHealth), common.PoolProviderHealthCheckInterval, "provider_health_check", false)
+			go r.startLoopForFunction(r.leaderOnly(r.buildWarmImages), common.PoolWarmImageInterval, "warm_image", false)
+		}
+		go r.startLoopForFunction(r.deferredOnRateLimit("update_tools", r.updateTools), common.PoolToolUpdateInterval, "update_tools", true)
+		go r.startLoopForFunction(r.leaderOnly(r.reconcileAndConsumeQueuedJobs), common.PoolConsilitationInterval, "job_queue_consumer", false)
+		if r.enableWebhookManagement {
+			go r.startLoopForFunction(r.leaderOnly(r.deferredOnRateLimit("webhook_delivery_stats", r.updateWebhookDeliveryStats)), common.PoolWebhookDeliveryStatsInterval, "webhook_delivery_stats", false)
+		}
+		go r.startLoopForFunction(r.leaderOnly(r.retryPendingWebhookInstall), common.PoolWebhookInstallRetryInterval, "webhook_install_retry", false)
 	}()
 	return nil
 }
@@ -1603,6 +2183,18 @@ func (r *basePoolManager) WebhookSecret() string {
 	return r.entity.WebhookSecret
 }
 
+func (r *basePoolManager) PreviousWebhookSecret() string {
+	expiresAt := r.entity.PreviousWebhookSecretExpiresAt
+	if expiresAt == nil || !time.Now().Before(*expiresAt) {
+		return ""
+	}
+	return r.entity.PreviousWebhookSecret
+}
+
+func (r *basePoolManager) EntityWebhookPath() bool {
+	return r.entity.EntityWebhookPath
+}
+
 func (r *basePoolManager) ID() string {
 	return r.entity.ID
 }
@@ -1613,7 +2205,8 @@ func (r *basePoolManager) DeleteRunner(runner params.Instance, forceRemove, bypa
 	if !r.managerIsRunning && !bypassGHUnauthorizedError {
 		return runnerErrors.NewConflictError("pool manager is not running for %s", r.entity.String())
 	}
-	if runner.AgentID != 0 {
+	// The registration of instances that failed to be created may have already been removed.
+	if runner.AgentID != 0 && !runner.JitRegistrationRemoved {
 		resp, err := r.ghcli.RemoveEntityRunner(r.ctx, runner.AgentID)
 		if err != nil {
 			if resp != nil {
@@ -1707,6 +2300,21 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 		poolCacheType: r.entity.GetPoolBalancerType(),
 	}
 
+	concurrency, err := r.getJobConcurrency(queued)
+	if err != nil {
+		return errors.Wrap(err, "counting active jobs")
+	}
+
+	// When the entity has routing rules, they decide the order in which pools are
+	// tried for each job, instead of the pool balancer.
+	var router *poolRouter
+	if len(r.entity.RoutingRules) > 0 {
+		router, err = r.newPoolRouter()
+		if err != nil {
+			return errors.Wrap(err, "creating pool router")
+		}
+	}
+
 	slog.DebugContext(
 		r.ctx, "found queued jobs",
 		"job_count", len(queued))
@@ -1728,7 +2336,9 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 			continue
 		}
 
-		if time.Since(job.UpdatedAt) >= time.Minute*10 {
+		// In observer mode, the placement of a job is only decided once. No runner
+		// was created that another job could have picked up.
+		if !r.observerMode && time.Since(job.UpdatedAt) >= time.Minute*10 {
 			// Job is still queued in our db, 10 minutes after a matching runner
 			// was spawned. Unlock it and try again. A different job may have picked up
 			// the runner.
@@ -1756,7 +2366,7 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 
 		poolRR, ok := poolsCache.Get(job.Labels)
 		if !ok {
-			potentialPools, err := r.store.FindPoolsMatchingAllTags(r.ctx, r.entity.EntityType, r.entity.ID, job.Labels)
+			potentialPools, err := r.findPoolsForJobLabels(job.Labels)
 			if err != nil {
 				slog.With(slog.Any("error", err)).ErrorContext(
 					r.ctx, "error finding pools matching labels")
@@ -1767,6 +2377,31 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 
 		if poolRR.Len() == 0 {
 			slog.DebugContext(r.ctx, "could not find pools with labels", "requested_labels", strings.Join(job.Labels, ","))
+			r.recordJobDecision(job, params.JobDecisionSkipped, "", "no pools match the job labels")
+			continue
+		}
+		if router != nil {
+			poolRR = router.Route(poolRR.Pools())
+		}
+		if !r.observerMode && handledByAutoscaler(job, poolRR.Pools()) {
+			slog.DebugContext(
+				r.ctx, "job is handled by the queue depth autoscaler",
+				"job_id", job.ID)
+			r.recordJobDecision(job, params.JobDecisionDeferred, "", "runners are created by the queue depth autoscaler")
+			continue
+		}
+
+		if limit := concurrency.limitReached(); limit != "" {
+			// The job stays queued and will be picked up once other jobs finish.
+			slog.DebugContext(
+				r.ctx, "job concurrency limit reached; holding back job",
+				"job_id", job.ID,
+				"limit", limit)
+			metrics.JobsThrottled.WithLabelValues(
+				r.entity.String(), // label: entity
+				limit,             // label: limit
+			).Inc()
+			r.recordJobDecision(job, params.JobDecisionDeferred, "", "%s job concurrency limit reached", limit)
 			continue
 		}
 
@@ -1778,15 +2413,28 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 			continue
 		}
 
+		if r.observerMode {
+			// The job stays locked, so the placement is recorded only once.
+			r.observeJobPlacement(job, poolRR)
+			concurrency.add()
+			continue
+		}
+
 		jobLabels := []string{
 			fmt.Sprintf("%s%d", jobLabelPrefix, job.ID),
 		}
+		jobCtx, span := tracing.StartJobSpan(
+			r.ctx, job.ID, "pool.select_pool",
+			attribute.String("garm.entity", r.entity.String()),
+			attribute.Int("garm.pool.candidates", poolRR.Len()))
+		var lastErr error
 		for i := 0; i < poolRR.Len(); i++ {
 			pool, err := poolRR.Next()
 			if err != nil {
 				slog.With(slog.Any("error", err)).ErrorContext(
 					r.ctx, "could not find a pool to create a runner for job",
 					"job_id", job.ID)
+				lastErr = err
 				break
 			}
 
@@ -1794,16 +2442,24 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 				r.ctx, "attempting to create a runner in pool",
 				"pool_id", pool.ID,
 				"job_id", job.ID)
-			if err := r.addRunnerToPool(pool, jobLabels); err != nil {
+			if err := r.addRunnerToPool(jobCtx, pool, jobLabels); err != nil {
 				slog.With(slog.Any("error", err)).ErrorContext(
 					r.ctx, "could not add runner to pool",
 					"pool_id", pool.ID)
+				lastErr = err
 				continue
 			}
 			slog.DebugContext(r.ctx, "a new runner was added as a response to queued job",
 				"pool_id", pool.ID,
 				"job_id", job.ID)
 			runnerCreated = true
+			span.SetAttributes(attribute.String("garm.pool.id", pool.ID))
+			span.End()
+			r.recordJobDecision(job, params.JobDecisionRunnerCreated, pool.ID, "")
+			concurrency.add()
+			if router != nil {
+				router.runnerAdded(pool.ID)
+			}
 			break
 		}
 
@@ -1811,6 +2467,12 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 			slog.WarnContext(
 				r.ctx, "could not create a runner for job; unlocking",
 				"job_id", job.ID)
+			reason := "could not create a runner in any of the matching pools"
+			if lastErr != nil {
+				reason = fmt.Sprintf("%s: %s", reason, lastErr)
+			}
+			tracing.EndSpan(span, errors.New(reason))
+			r.recordJobDecision(job, params.JobDecisionSkipped, "", reason)
 			if err := r.store.UnlockJob(r.ctx, job.ID, r.ID()); err != nil {
 				slog.With(slog.Any("error", err)).ErrorContext(
 					r.ctx, "failed to unlock job",
@@ -1819,10 +2481,91 @@ func (r *basePoolManager) consumeQueuedJobs() error {
 			}
 		}
 	}
+	return nil
+}
 
-	if err := r.store.DeleteCompletedJobs(r.ctx); err != nil {
-		slog.With(slog.Any("error", err)).ErrorContext(
-			r.ctx, "failed to delete completed jobs")
+// reconcileAndConsumeQueuedJobs consumes the queued jobs of the entity. If enabled, the
+// queued jobs are reconciled first, the first time it runs. It only runs while this
+// controller is the leader of the entity, so the jobs are reconciled once the lease of
+// the entity is acquired, and not by controllers that don't manage it.
+func (r *basePoolManager) reconcileAndConsumeQueuedJobs() error {
+	if r.reconcileJobsOnStartup && !r.queuedJobsReconciled {
+		r.queuedJobsReconciled = true
+		if err := r.reconcileQueuedJobs(); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to reconcile queued jobs")
+		}
+	}
+	return r.consumeQueuedJobs()
+}
+
+// reconcileQueuedJobs runs once, when this controller first manages the entity. If GARM was offline for a while,
+// jobs recorded as queued in the database may have been picked up by other runners, cancelled
+// or may have completed. Creating runners for them would be a waste, so we ask GitHub about the
+// current status of each of them. Jobs that are still queued get their stale locks removed, so
+// they can be handled right away. At most common.MaxStartupJobReconciliations jobs are checked,
+// to avoid exhausting the API rate limit of the credentials.
+func (r *basePoolManager) reconcileQueuedJobs() error {
+	queued, err := r.store.ListEntityJobsByStatus(r.ctx, r.entity.EntityType, r.entity.ID, params.JobStatusQueued)
+	if err != nil {
+		return errors.Wrap(err, "listing queued jobs")
+	}
+
+	if len(queued) > common.MaxStartupJobReconciliations {
+		slog.WarnContext(
+			r.ctx, "too many queued jobs; only reconciling some of them",
+			"job_count", len(queued), "max_reconciled", common.MaxStartupJobReconciliations)
+		queued = queued[:common.MaxStartupJobReconciliations]
+	}
+
+	for _, job := range queued {
+		if job.RepositoryOwner == "" || job.RepositoryName == "" {
+			continue
+		}
+
+		ghJob, ghResp, err := r.ghcli.GetWorkflowJobByID(r.ctx, job.RepositoryOwner, job.RepositoryName, job.ID)
+		if err != nil {
+			var rateLimitErr *github.RateLimitError
+			var abuseErr *github.AbuseRateLimitError
+			if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+				return errors.Wrap(err, "fetching workflow job")
+			}
+			if ghResp != nil && ghResp.StatusCode == http.StatusNotFound {
+				// The job, or the repository it belongs to, no longer exists.
+				slog.InfoContext(r.ctx, "queued job no longer exists in github; removing", "job_id", job.ID)
+				if err := r.store.DeleteJob(r.ctx, job.ID); err != nil {
+					slog.With(slog.Any("error", err)).ErrorContext(
+						r.ctx, "failed to delete job", "job_id", job.ID)
+				}
+				continue
+			}
+			slog.With(slog.Any("error", err)).WarnContext(
+				r.ctx, "failed to fetch workflow job", "job_id", job.ID)
+			continue
+		}
+
+		if ghJob.GetStatus() == string(params.JobStatusQueued) {
+			if job.LockedBy != uuid.Nil && time.Since(job.UpdatedAt) >= time.Minute*10 {
+				slog.InfoContext(r.ctx, "removing stale lock from queued job", "job_id", job.ID, "locked_by", job.LockedBy.String())
+				if err := r.store.BreakLockJobIsQueued(r.ctx, job.ID); err != nil {
+					slog.With(slog.Any("error", err)).ErrorContext(
+						r.ctx, "failed to unlock job", "job_id", job.ID)
+				}
+			}
+			continue
+		}
+
+		slog.InfoContext(
+			r.ctx, "queued job changed status while we were offline",
+			"job_id", job.ID, "status", ghJob.GetStatus(), "conclusion", ghJob.GetConclusion())
+		job.Status = ghJob.GetStatus()
+		job.Conclusion = ghJob.GetConclusion()
+		job.StartedAt = ghJob.GetStartedAt().Time
+		job.CompletedAt = ghJob.GetCompletedAt().Time
+		job.LockedBy = uuid.Nil
+		if _, err := r.store.CreateOrUpdateJob(r.ctx, job); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to update job", "job_id", job.ID)
+		}
 	}
 	return nil
 }
@@ -1832,6 +2575,9 @@ func (r *basePoolManager) UninstallWebhook(ctx context.Context) error {
 		return errors.Wrap(runnerErrors.ErrBadRequest, "controller webhook url is empty")
 	}
 
+	// The user no longer wants a webhook. Stop any pending install retries.
+	r.clearPendingWebhookInstall(ctx)
+
 	allHooks, err := r.listHooks(ctx)
 	if err != nil {
 		return errors.Wrap(err, "listing hooks")
@@ -1841,11 +2587,12 @@ func (r *basePoolManager) UninstallWebhook(ctx context.Context) error {
 	var baseHook string
 	trimmedBase := strings.TrimRight(r.controllerInfo.WebhookURL, "/")
 	trimmedController := strings.TrimRight(r.controllerInfo.ControllerWebhookURL, "/")
+	entityURL := r.entityWebhookURL()
 
 	for _, hook := range allHooks {
 		hookInfo := hookToParamsHookInfo(hook)
 		info := strings.TrimRight(hookInfo.URL, "/")
-		if strings.EqualFold(info, trimmedController) {
+		if strings.EqualFold(info, trimmedController) || strings.EqualFold(info, entityURL) {
 			controllerHookID = hook.GetID()
 		}
 
@@ -1859,6 +2606,11 @@ func (r *basePoolManager) UninstallWebhook(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("deleting hook: %w", err)
 		}
+		if r.EntityWebhookPath() {
+			if err := r.store.SetEntityWebhookPath(ctx, r.entity, false); err != nil {
+				return errors.Wrap(err, "recording webhook path")
+			}
+		}
 		return nil
 	}
 
@@ -1895,6 +2647,94 @@ func (r *basePoolManager) InstallHook(ctx context.Context, req *github.Hook) (pa
 }
 
 func (r *basePoolManager) InstallWebhook(ctx context.Context, param params.InstallWebhookParams) (params.HookInfo, error) {
+	info, err := r.installWebhook(ctx, param)
+	if err != nil {
+		if r.entity.EntityType != params.GithubEntityTypeEnterprise && isRetryableWebhookInstallError(err) {
+			r.addEntityEvent(ctx, params.WebhookInstallEvent, params.EventError, fmt.Sprintf("failed to install webhook: %q; install will be retried", err))
+			if err := r.store.SetEntityPendingWebhookInstall(ctx, r.entity, &param); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(
+					ctx, "failed to flag entity for webhook install retry")
+			}
+		}
+		return params.HookInfo{}, err
+	}
+
+	r.clearPendingWebhookInstall(ctx)
+	return info, nil
+}
+
+// retryPendingWebhookInstall attempts to install the webhook for entities where a previous
+// install attempt failed. Attempts are spaced using an exponential backoff.
+func (r *basePoolManager) retryPendingWebhookInstall() error {
+	r.mux.Lock()
+	pending := r.entity.PendingWebhookInstall
+	if pending == nil {
+		r.webhookInstallAttempts = 0
+		r.webhookInstallNextAttempt = time.Time{}
+		r.mux.Unlock()
+		return nil
+	}
+	if time.Now().UTC().Before(r.webhookInstallNextAttempt) {
+		r.mux.Unlock()
+		return nil
+	}
+	r.webhookInstallAttempts++
+	attempt := r.webhookInstallAttempts
+	backoff := webhookInstallRetryBackoff(attempt)
+	r.webhookInstallNextAttempt = time.Now().UTC().Add(backoff)
+	r.mux.Unlock()
+
+	slog.InfoContext(r.ctx, "retrying webhook install", "attempt", attempt)
+	_, err := r.installWebhook(r.ctx, *pending)
+	if err == nil {
+		r.addEntityEvent(r.ctx, params.WebhookInstallEvent, params.EventInfo, fmt.Sprintf("webhook installed after %d retries", attempt))
+		r.clearPendingWebhookInstall(r.ctx)
+		return nil
+	}
+
+	var conflictErr *runnerErrors.ConflictError
+	if errors.As(err, &conflictErr) {
+		// The webhook was installed by other means in the meantime.
+		r.addEntityEvent(r.ctx, params.WebhookInstallEvent, params.EventInfo, fmt.Sprintf("webhook install retry stopped: %q", err))
+		r.clearPendingWebhookInstall(r.ctx)
+		return nil
+	}
+
+	if !isRetryableWebhookInstallError(err) {
+		r.addEntityEvent(r.ctx, params.WebhookInstallEvent, params.EventError, fmt.Sprintf("webhook install retry %d failed with a permanent error: %q; giving up", attempt, err))
+		r.clearPendingWebhookInstall(r.ctx)
+		return nil
+	}
+
+	r.addEntityEvent(r.ctx, params.WebhookInstallEvent, params.EventWarning, fmt.Sprintf("webhook install retry %d failed: %q; next attempt in %s", attempt, err, backoff))
+	return nil
+}
+
+func (r *basePoolManager) clearPendingWebhookInstall(ctx context.Context) {
+	r.mux.Lock()
+	pending := r.entity.PendingWebhookInstall
+	r.entity.PendingWebhookInstall = nil
+	entity := r.entity
+	r.mux.Unlock()
+
+	if pending == nil {
+		return
+	}
+
+	if err := r.store.SetEntityPendingWebhookInstall(ctx, entity, nil); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to clear pending webhook install")
+	}
+}
+
+func (r *basePoolManager) addEntityEvent(ctx context.Context, event params.EventType, eventLevel params.EventLevel, msg string) {
+	if err := r.store.AddEntityEvent(ctx, r.entity, event, eventLevel, msg, common.MaxEntityEvents); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to add entity event")
+	}
+}
+
+func (r *basePoolManager) installWebhook(ctx context.Context, param params.InstallWebhookParams) (params.HookInfo, error) {
 	if r.controllerInfo.ControllerWebhookURL == "" {
 		return params.HookInfo{}, errors.Wrap(runnerErrors.ErrBadRequest, "controller webhook url is empty")
 	}
@@ -1903,10 +2743,14 @@ func (r *basePoolManager) InstallWebhook(ctx context.Context, param params.Insta
 	if param.InsecureSSL {
 		insecureSSL = "1"
 	}
+	hookURL := r.controllerInfo.ControllerWebhookURL
+	if param.EntityWebhookPath {
+		hookURL = r.entityWebhookURL()
+	}
 	req := &github.Hook{
 		Active: github.Bool(true),
 		Config: map[string]interface{}{
-			"url":          r.controllerInfo.ControllerWebhookURL,
+			"url":          hookURL,
 			"content_type": "json",
 			"insecure_ssl": insecureSSL,
 			"secret":       r.WebhookSecret(),
@@ -1916,7 +2760,21 @@ func (r *basePoolManager) InstallWebhook(ctx context.Context, param params.Insta
 		},
 	}
 
-	return r.InstallHook(ctx, req)
+	info, err := r.InstallHook(ctx, req)
+	if err != nil {
+		return params.HookInfo{}, err
+	}
+	if param.EntityWebhookPath != r.EntityWebhookPath() {
+		if err := r.store.SetEntityWebhookPath(ctx, r.entity, param.EntityWebhookPath); err != nil {
+			return params.HookInfo{}, errors.Wrap(err, "recording webhook path")
+		}
+	}
+	return info, nil
+}
+
+// entityWebhookURL returns the webhook URL unique to the entity of this pool manager.
+func (r *basePoolManager) entityWebhookURL() string {
+	return fmt.Sprintf("%s/%s", strings.TrimRight(r.controllerInfo.ControllerWebhookURL, "/"), r.entity.ID)
 }
 
 func (r *basePoolManager) ValidateOwner(job params.WorkflowJob) error {
@@ -1952,6 +2810,17 @@ func (r *basePoolManager) GithubRunnerRegistrationToken() (string, error) {
 }
 
 func (r *basePoolManager) FetchTools() ([]commonParams.RunnerApplicationDownload, error) {
+	r.mux.Lock()
+	endpoint := r.entity.Credentials.Endpoint
+	r.mux.Unlock()
+	if endpoint.UsesToolsMetadataURL() {
+		tools, err := garmTools.GetTools(r.ctx, endpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "fetching runner tools from metadata URL")
+		}
+		return tools, nil
+	}
+
 	tools, ghResp, err := r.ghcli.ListEntityRunnerApplicationDownloads(r.ctx)
 	if err != nil {
 		if ghResp != nil && ghResp.StatusCode == http.StatusUnauthorized {
@@ -1970,6 +2839,181 @@ func (r *basePoolManager) FetchTools() ([]commonParams.RunnerApplicationDownload
 	return ret, nil
 }
 
+// checkActionsPolicy verifies that the GitHub Actions policies allow workflows to run
+// for a repository. Both the repository policy and the policy of the organization that
+// owns the repository are checked. An error is returned only if we can determine that
+// the policy does not allow workflows to run. Failures to fetch the policy (for example,
+// if the credentials lack the permissions needed to read the organization policy) are
+// logged and ignored.
+func (r *basePoolManager) checkActionsPolicy() error {
+	if r.entity.EntityType != params.GithubEntityTypeRepository {
+		return nil
+	}
+
+	repoPerms, ghResp, err := r.ghcli.GetEntityActionsPermissions(r.ctx)
+	if err != nil {
+		if ghResp != nil && ghResp.StatusCode == http.StatusUnauthorized {
+			return errors.Wrap(runnerErrors.ErrUnauthorized, "fetching actions permissions")
+		}
+		slog.With(slog.Any("error", err)).WarnContext(
+			r.ctx, "failed to fetch actions permissions for repository")
+		return nil
+	}
+	if repoPerms != nil && !repoPerms.GetEnabled() {
+		return fmt.Errorf("github actions is disabled for repository %s", r.entity.String())
+	}
+
+	orgPerms, ghResp, err := r.ghcli.GetActionsPermissions(r.ctx, r.entity.Owner)
+	if err != nil {
+		if ghResp != nil && ghResp.StatusCode == http.StatusNotFound {
+			// The repository is most likely owned by a user, not an organization.
+			return nil
+		}
+		slog.With(slog.Any("error", err)).WarnContext(
+			r.ctx, "failed to fetch actions permissions for organization",
+			"organization", r.entity.Owner)
+		return nil
+	}
+
+	switch orgPerms.GetEnabledRepositories() {
+	case "none":
+		return fmt.Errorf("organization %s does not allow github actions for any repository", r.entity.Owner)
+	case "selected":
+		enabled, err := r.isRepoEnabledInOrg()
+		if err != nil {
+			slog.With(slog.Any("error", err)).WarnContext(
+				r.ctx, "failed to list repositories enabled for actions in organization",
+				"organization", r.entity.Owner)
+			return nil
+		}
+		if !enabled {
+			return fmt.Errorf("organization %s does not allow github actions for repository %s", r.entity.Owner, r.entity.Name)
+		}
+	}
+	return r.checkRunnerGroupsPolicy()
+}
+
+// checkRunnerGroupsPolicy verifies that the organization runner groups used by the pools
+// of the repository can be used by the repository. Runners in a group restricted to
+// selected repositories never pick up jobs from other repositories. As with the other
+// policy checks, failures to fetch the runner groups are logged and ignored.
+func (r *basePoolManager) checkRunnerGroupsPolicy() error {
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		slog.With(slog.Any("error", err)).WarnContext(
+			r.ctx, "failed to list pools of repository")
+		return nil
+	}
+
+	checked := map[string]struct{}{}
+	for _, pool := range pools {
+		if pool.GitHubRunnerGroup == "" {
+			continue
+		}
+		if _, ok := checked[pool.GitHubRunnerGroup]; ok {
+			continue
+		}
+		checked[pool.GitHubRunnerGroup] = struct{}{}
+
+		group, err := r.findOrgRunnerGroup(pool.GitHubRunnerGroup)
+		if err != nil {
+			slog.With(slog.Any("error", err)).WarnContext(
+				r.ctx, "failed to fetch runner group of organization",
+				"organization", r.entity.Owner, "runner_group", pool.GitHubRunnerGroup)
+			continue
+		}
+		if group.GetVisibility() != string(params.RunnerGroupVisibilitySelected) {
+			continue
+		}
+
+		allowed, err := r.isRepoInRunnerGroup(group.GetID())
+		if err != nil {
+			slog.With(slog.Any("error", err)).WarnContext(
+				r.ctx, "failed to list repositories that can use runner group",
+				"organization", r.entity.Owner, "runner_group", pool.GitHubRunnerGroup)
+			continue
+		}
+		if !allowed {
+			return fmt.Errorf("runner group %s of organization %s does not allow repository %s", pool.GitHubRunnerGroup, r.entity.Owner, r.entity.Name)
+		}
+	}
+	return nil
+}
+
+func (r *basePoolManager) findOrgRunnerGroup(name string) (*github.RunnerGroup, error) {
+	opts := github.ListOrgRunnerGroupOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	for {
+		groups, ghResp, err := r.ghcli.ListOrganizationRunnerGroups(r.ctx, r.entity.Owner, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing runner groups")
+		}
+		if groups != nil {
+			for _, group := range groups.RunnerGroups {
+				if group.GetName() == name {
+					return group, nil
+				}
+			}
+		}
+		if ghResp == nil || ghResp.NextPage == 0 {
+			break
+		}
+		opts.Page = ghResp.NextPage
+	}
+	return nil, runnerErrors.NewNotFoundError("runner group %q not found", name)
+}
+
+func (r *basePoolManager) isRepoInRunnerGroup(groupID int64) (bool, error) {
+	opts := github.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		repos, ghResp, err := r.ghcli.ListRepositoryAccessRunnerGroup(r.ctx, r.entity.Owner, groupID, &opts)
+		if err != nil {
+			return false, errors.Wrap(err, "listing runner group repositories")
+		}
+		if repos != nil {
+			for _, repo := range repos.Repositories {
+				if strings.EqualFold(repo.GetName(), r.entity.Name) {
+					return true, nil
+				}
+			}
+		}
+		if ghResp == nil || ghResp.NextPage == 0 {
+			break
+		}
+		opts.Page = ghResp.NextPage
+	}
+	return false, nil
+}
+
+func (r *basePoolManager) isRepoEnabledInOrg() (bool, error) {
+	opts := github.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		repos, ghResp, err := r.ghcli.ListEnabledReposInOrg(r.ctx, r.entity.Owner, &opts)
+		if err != nil {
+			return false, errors.Wrap(err, "listing enabled repositories")
+		}
+		if repos != nil {
+			for _, repo := range repos.Repositories {
+				if strings.EqualFold(repo.GetName(), r.entity.Name) {
+					return true, nil
+				}
+			}
+		}
+		if ghResp == nil || ghResp.NextPage == 0 {
+			break
+		}
+		opts.Page = ghResp.NextPage
+	}
+	return false, nil
+}
+
 func (r *basePoolManager) GetGithubRunners() ([]*github.Runner, error) {
 	opts := github.ListOptions{
 		PerPage: 100,
@@ -2006,13 +3050,12 @@ func (r *basePoolManager) GithubURL() string {
 	return ""
 }
 
-func (r *basePoolManager) GetWebhookInfo(ctx context.Context) (params.HookInfo, error) {
-	allHooks, err := r.listHooks(ctx)
-	if err != nil {
-		return params.HookInfo{}, errors.Wrap(err, "listing hooks")
-	}
+// findManagedHook returns the info of the webhook that points to this controller.
+// The controller specific webhook URL is preferred over the base webhook URL.
+func (r *basePoolManager) findManagedHook(allHooks []*github.Hook) (params.HookInfo, bool) {
 	trimmedBase := strings.TrimRight(r.controllerInfo.WebhookURL, "/")
 	trimmedController := strings.TrimRight(r.controllerInfo.ControllerWebhookURL, "/")
+	entityURL := r.entityWebhookURL()
 
 	var controllerHookInfo *params.HookInfo
 	var baseHookInfo *params.HookInfo
@@ -2020,7 +3063,7 @@ func (r *basePoolManager) GetWebhookInfo(ctx context.Context) (params.HookInfo,
 	for _, hook := range allHooks {
 		hookInfo := hookToParamsHookInfo(hook)
 		info := strings.TrimRight(hookInfo.URL, "/")
-		if strings.EqualFold(info, trimmedController) {
+		if strings.EqualFold(info, trimmedController) || strings.EqualFold(info, entityURL) {
 			controllerHookInfo = &hookInfo
 			break
 		}
@@ -2031,15 +3074,99 @@ func (r *basePoolManager) GetWebhookInfo(ctx context.Context) (params.HookInfo,
 
 	// Return the controller hook info if available.
 	if controllerHookInfo != nil {
-		return *controllerHookInfo, nil
+		return *controllerHookInfo, true
 	}
 
 	// Fall back to base hook info if defined.
 	if baseHookInfo != nil {
-		return *baseHookInfo, nil
+		return *baseHookInfo, true
+	}
+
+	return params.HookInfo{}, false
+}
+
+func (r *basePoolManager) GetWebhookInfo(ctx context.Context) (params.HookInfo, error) {
+	allHooks, err := r.listHooks(ctx)
+	if err != nil {
+		return params.HookInfo{}, errors.Wrap(err, "listing hooks")
+	}
+
+	hookInfo, ok := r.findManagedHook(allHooks)
+	if !ok {
+		return params.HookInfo{}, runnerErrors.NewNotFoundError("hook not found")
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.hookDeliveryStats != nil && r.hookDeliveryStatsHookID == hookInfo.ID {
+		stats := *r.hookDeliveryStats
+		hookInfo.DeliveryStats = &stats
+	}
+
+	return hookInfo, nil
+}
+
+// updateWebhookDeliveryStats fetches the most recent deliveries of the webhook
+// managed by this controller and caches a summary of them. The summary is
+// returned as part of the webhook info.
+func (r *basePoolManager) updateWebhookDeliveryStats() error {
+	if r.entity.EntityType == params.GithubEntityTypeEnterprise {
+		// Webhooks can not be managed for enterprises.
+		return nil
+	}
+
+	allHooks, err := r.listHooks(r.ctx)
+	if err != nil {
+		if !isHookAccessError(err) {
+			return errors.Wrap(err, "listing hooks")
+		}
+		// The credentials can't list hooks, so GARM can't have installed one either.
+		allHooks = nil
+	}
+
+	hookInfo, ok := r.findManagedHook(allHooks)
+	if !ok {
+		r.mux.Lock()
+		r.hookDeliveryStats = nil
+		r.hookDeliveryStatsHookID = 0
+		r.mux.Unlock()
+		return nil
+	}
+
+	opts := github.ListCursorOptions{
+		PerPage: webhookDeliveryStatsPageSize,
+	}
+	deliveries, _, err := r.ghcli.ListEntityHookDeliveries(r.ctx, hookInfo.ID, &opts)
+	if err != nil {
+		return errors.Wrap(err, "listing hook deliveries")
 	}
 
-	return params.HookInfo{}, runnerErrors.NewNotFoundError("hook not found")
+	stats := hookDeliveriesToStats(deliveries)
+
+	r.mux.Lock()
+	r.hookDeliveryStats = &stats
+	r.hookDeliveryStatsHookID = hookInfo.ID
+	r.mux.Unlock()
+
+	if stats.FailedDeliveries > 0 {
+		slog.WarnContext(
+			r.ctx, "webhook has failed deliveries",
+			"hook_id", hookInfo.ID,
+			"failed_deliveries", stats.FailedDeliveries,
+			"recent_deliveries", stats.RecentDeliveries,
+			"last_delivery_status", stats.LastDeliveryStatus)
+	}
+	return nil
+}
+
+func (r *basePoolManager) Tools() ([]commonParams.RunnerApplicationDownload, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if len(r.tools) == 0 {
+		return nil, errors.Wrap(runnerErrors.ErrNotFound, "tools not yet fetched")
+	}
+	return r.tools, nil
 }
 
 func (r *basePoolManager) RootCABundle() (params.CertificateBundle, error) {
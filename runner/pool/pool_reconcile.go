@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// schedulePoolReconcile reconciles a pool right after it was created or updated, so
+// changes made through the API take effect without waiting for the next run of the
+// scale down and consolidation loops. Updates received while the pool is being
+// reconciled are coalesced into one more run.
+func (r *basePoolManager) schedulePoolReconcile(poolID string) {
+	r.poolReconcilesMux.Lock()
+	defer r.poolReconcilesMux.Unlock()
+
+	if r.poolReconciles == nil {
+		r.poolReconciles = map[string]bool{}
+	}
+	if _, running := r.poolReconciles[poolID]; running {
+		r.poolReconciles[poolID] = true
+		return
+	}
+	r.poolReconciles[poolID] = false
+
+	go func() {
+		for {
+			if err := r.reconcilePool(poolID); err != nil {
+				slog.With(slog.Any("error", err)).ErrorContext(
+					r.ctx, "failed to reconcile pool",
+					"pool_id", poolID)
+			}
+
+			r.poolReconcilesMux.Lock()
+			if !r.poolReconciles[poolID] {
+				delete(r.poolReconciles, poolID)
+				r.poolReconcilesMux.Unlock()
+				return
+			}
+			r.poolReconciles[poolID] = false
+			r.poolReconcilesMux.Unlock()
+		}
+	}()
+}
+
+// reconcilePool scales down a single pool and makes sure it has the minimum number of
+// idle runners, like the scale down and consolidation loops do for all pools.
+func (r *basePoolManager) reconcilePool(poolID string) error {
+	r.mux.Lock()
+	isRunning := r.managerIsRunning
+	r.mux.Unlock()
+	if r.observerMode || !isRunning || !r.isLeader() {
+		return nil
+	}
+
+	pool, err := r.store.GetEntityPool(r.ctx, r.entity, poolID)
+	if err != nil {
+		return fmt.Errorf("error fetching pool: %w", err)
+	}
+	pools := applyPoolSchedules([]params.Pool{pool}, time.Now())
+	pools, err = r.applyCapacityReservations(pools)
+	if err != nil {
+		return err
+	}
+	pool = pools[0]
+
+	slog.DebugContext(
+		r.ctx, "reconciling updated pool",
+		"pool_id", pool.ID)
+	if err := r.scaleDownOnePool(r.ctx, pool); err != nil {
+		return fmt.Errorf("failed to scale down pool: %w", err)
+	}
+
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+	if _, ok := paused[pool.ProviderName]; ok {
+		return nil
+	}
+	if err := r.ensureIdleRunnersForOnePool(pool); err != nil {
+		return fmt.Errorf("failed to ensure minimum idle workers: %w", err)
+	}
+	return nil
+}
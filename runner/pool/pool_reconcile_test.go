@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestReconcilePoolSkippedWhenNotRunning(t *testing.T) {
+	// The store mock fails the test on any unexpected call.
+	store := dbMocks.NewStore(t)
+	for _, r := range []*basePoolManager{
+		{ctx: context.Background(), store: store},
+		{ctx: context.Background(), store: store, managerIsRunning: true, observerMode: true},
+	} {
+		require.NoError(t, r.reconcilePool("test-pool"))
+	}
+}
+
+func TestReconcilePoolSkipsIdleRunnersOfPausedProvider(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-org-id",
+		EntityType: params.GithubEntityTypeOrganization,
+	}
+	pool := params.Pool{
+		ID:             "test-pool",
+		ProviderName:   "paused-provider",
+		Enabled:        true,
+		MinIdleRunners: 2,
+		MaxRunners:     4,
+	}
+
+	store := dbMocks.NewStore(t)
+	store.On("GetEntityPool", mock.Anything, entity, pool.ID).Return(pool, nil).Once()
+	store.On("ListCapacityReservations", mock.Anything).Return([]params.CapacityReservation{}, nil).Once()
+	// Only scaling down lists the instances of the pool.
+	store.On("ListPoolInstances", mock.Anything, pool.ID).Return([]params.Instance{}, nil).Once()
+	store.On("ListPausedProviders", mock.Anything).Return(
+		[]params.ProviderPause{{ProviderName: "paused-provider"}}, nil).Once()
+
+	r := &basePoolManager{
+		ctx:              context.Background(),
+		entity:           entity,
+		store:            store,
+		managerIsRunning: true,
+	}
+	require.NoError(t, r.reconcilePool(pool.ID))
+}
+
+func TestSchedulePoolReconcileCoalescesUpdates(t *testing.T) {
+	r := &basePoolManager{
+		ctx:            context.Background(),
+		poolReconciles: map[string]bool{"test-pool": false},
+	}
+
+	// The pool is already being reconciled, so the update only asks for one more run.
+	r.schedulePoolReconcile("test-pool")
+	r.schedulePoolReconcile("test-pool")
+
+	r.poolReconcilesMux.Lock()
+	defer r.poolReconcilesMux.Unlock()
+	require.Equal(t, map[string]bool{"test-pool": true}, r.poolReconciles)
+}
@@ -0,0 +1,426 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestCheckActionsPolicy(t *testing.T) {
+	repoEntity := params.GithubEntity{
+		Owner:      "test-org",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+
+	tests := []struct {
+		name        string
+		entity      params.GithubEntity
+		pools       []params.Pool
+		setup       func(cli *mocks.GithubClient)
+		expectError bool
+	}{
+		{
+			name: "organization entities are not checked",
+			entity: params.GithubEntity{
+				Owner:      "test-org",
+				EntityType: params.GithubEntityTypeOrganization,
+			},
+			setup: func(_ *mocks.GithubClient) {},
+		},
+		{
+			name:   "actions disabled for repository",
+			entity: repoEntity,
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(false)}, nil, nil)
+			},
+			expectError: true,
+		},
+		{
+			name:   "repository owned by a user",
+			entity: repoEntity,
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, &github.ErrorResponse{})
+			},
+		},
+		{
+			name:   "organization policy allows no repositories",
+			entity: repoEntity,
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					&github.ActionsPermissions{EnabledRepositories: github.String("none")}, nil, nil)
+			},
+			expectError: true,
+		},
+		{
+			name:   "repository is selected in organization policy",
+			entity: repoEntity,
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					&github.ActionsPermissions{EnabledRepositories: github.String("selected")}, nil, nil)
+				cli.On("ListEnabledReposInOrg", mock.Anything, "test-org", mock.Anything).Return(
+					&github.ActionsEnabledOnOrgRepos{
+						Repositories: []*github.Repository{
+							{Name: github.String("other-repo")},
+							{Name: github.String("Test-Repo")},
+						},
+					}, &github.Response{}, nil)
+			},
+		},
+		{
+			name:   "repository is not selected in organization policy",
+			entity: repoEntity,
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					&github.ActionsPermissions{EnabledRepositories: github.String("selected")}, nil, nil)
+				cli.On("ListEnabledReposInOrg", mock.Anything, "test-org", mock.Anything).Return(
+					&github.ActionsEnabledOnOrgRepos{
+						Repositories: []*github.Repository{
+							{Name: github.String("other-repo")},
+						},
+					}, &github.Response{}, nil)
+			},
+			expectError: true,
+		},
+		{
+			name:   "repository can use runner group",
+			entity: repoEntity,
+			pools:  []params.Pool{{GitHubRunnerGroup: "linux"}, {GitHubRunnerGroup: "linux"}, {}},
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					&github.ActionsPermissions{EnabledRepositories: github.String("all")}, nil, nil)
+				cli.On("ListOrganizationRunnerGroups", mock.Anything, "test-org", mock.Anything).Return(
+					&github.RunnerGroups{
+						RunnerGroups: []*github.RunnerGroup{
+							{ID: github.Int64(2), Name: github.String("linux"), Visibility: github.String("selected")},
+						},
+					}, &github.Response{}, nil).Once()
+				cli.On("ListRepositoryAccessRunnerGroup", mock.Anything, "test-org", int64(2), mock.Anything).Return(
+					&github.ListRepositories{
+						Repositories: []*github.Repository{
+							{Name: github.String("test-repo")},
+						},
+					}, &github.Response{}, nil).Once()
+			},
+		},
+		{
+			name:   "runner group is restricted to other repositories",
+			entity: repoEntity,
+			pools:  []params.Pool{{GitHubRunnerGroup: "linux"}},
+			setup: func(cli *mocks.GithubClient) {
+				cli.On("GetEntityActionsPermissions", mock.Anything).Return(
+					&github.ActionsPermissionsRepository{Enabled: github.Bool(true)}, nil, nil)
+				cli.On("GetActionsPermissions", mock.Anything, "test-org").Return(
+					&github.ActionsPermissions{EnabledRepositories: github.String("all")}, nil, nil)
+				cli.On("ListOrganizationRunnerGroups", mock.Anything, "test-org", mock.Anything).Return(
+					&github.RunnerGroups{
+						RunnerGroups: []*github.RunnerGroup{
+							{ID: github.Int64(2), Name: github.String("linux"), Visibility: github.String("selected")},
+						},
+					}, &github.Response{}, nil)
+				cli.On("ListRepositoryAccessRunnerGroup", mock.Anything, "test-org", int64(2), mock.Anything).Return(
+					&github.ListRepositories{
+						Repositories: []*github.Repository{
+							{Name: github.String("other-repo")},
+						},
+					}, &github.Response{}, nil)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cli := mocks.NewGithubClient(t)
+			tc.setup(cli)
+			store := dbMocks.NewStore(t)
+			store.On("ListEntityPools", mock.Anything, tc.entity).Return(tc.pools, nil).Maybe()
+			r := &basePoolManager{
+				ctx:    context.Background(),
+				entity: tc.entity,
+				ghcli:  cli,
+				store:  store,
+			}
+			err := r.checkActionsPolicy()
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestInstallWebhookFailureFlagsEntityForRetry(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		Owner:      "test-org",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	installParams := params.InstallWebhookParams{
+		WebhookEndpointType: params.WebhookEndpointDirect,
+	}
+
+	cli := mocks.NewGithubClient(t)
+	cli.On("ListEntityHooks", mock.Anything, mock.Anything).Return(nil, nil, fmt.Errorf("connection refused"))
+
+	store := dbMocks.NewStore(t)
+	store.On("AddEntityEvent", mock.Anything, entity, params.WebhookInstallEvent, params.EventError, mock.Anything, common.MaxEntityEvents).Return(nil)
+	store.On("SetEntityPendingWebhookInstall", mock.Anything, entity, &installParams).Return(nil)
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		ghcli:  cli,
+		store:  store,
+		controllerInfo: params.ControllerInfo{
+			ControllerWebhookURL: "https://garm.example.com/webhooks/controller-id",
+		},
+	}
+
+	if _, err := r.InstallWebhook(context.Background(), installParams); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestInstallWebhookWithEntityPath(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:            "test-repo-id",
+		Owner:         "test-org",
+		Name:          "test-repo",
+		EntityType:    params.GithubEntityTypeRepository,
+		WebhookSecret: "secret",
+	}
+	entityURL := "https://garm.example.com/webhooks/controller-id/test-repo-id"
+	hook := &github.Hook{
+		ID:     github.Int64(1),
+		Active: github.Bool(true),
+		Config: map[string]interface{}{"url": entityURL},
+	}
+
+	cli := mocks.NewGithubClient(t)
+	cli.On("ListEntityHooks", mock.Anything, mock.Anything).Return([]*github.Hook{}, &github.Response{}, nil).Once()
+	cli.On("CreateEntityHook", mock.Anything, mock.MatchedBy(func(req *github.Hook) bool {
+		return req.Config["url"] == entityURL && req.Config["secret"] == "secret"
+	})).Return(hook, nil).Once()
+	cli.On("PingEntityHook", mock.Anything, int64(1)).Return(nil, nil).Once()
+	cli.On("ListEntityHooks", mock.Anything, mock.Anything).Return([]*github.Hook{hook}, &github.Response{}, nil).Once()
+
+	// The entity is flagged, so that webhooks for it are no longer accepted on the shared URL.
+	store := dbMocks.NewStore(t)
+	store.On("SetEntityWebhookPath", mock.Anything, entity, true).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		ghcli:  cli,
+		store:  store,
+		controllerInfo: params.ControllerInfo{
+			ControllerWebhookURL: "https://garm.example.com/webhooks/controller-id/",
+		},
+	}
+
+	info, err := r.InstallWebhook(context.Background(), params.InstallWebhookParams{EntityWebhookPath: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if info.URL != entityURL {
+		t.Fatalf("expected hook URL %s, got %s", entityURL, info.URL)
+	}
+
+	// The hook installed on the entity URL is recognized as managed by this controller.
+	info, err = r.GetWebhookInfo(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if info.ID != 1 {
+		t.Fatalf("expected hook ID 1, got %d", info.ID)
+	}
+}
+
+func TestRetryPendingWebhookInstallHonorsBackoff(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		Owner:      "test-org",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+		PendingWebhookInstall: &params.InstallWebhookParams{
+			WebhookEndpointType: params.WebhookEndpointDirect,
+		},
+	}
+
+	cli := mocks.NewGithubClient(t)
+	cli.On("ListEntityHooks", mock.Anything, mock.Anything).Return(nil, nil, fmt.Errorf("connection refused")).Once()
+
+	store := dbMocks.NewStore(t)
+	store.On("AddEntityEvent", mock.Anything, entity, params.WebhookInstallEvent, params.EventWarning, mock.Anything, common.MaxEntityEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		ghcli:  cli,
+		store:  store,
+		controllerInfo: params.ControllerInfo{
+			ControllerWebhookURL: "https://garm.example.com/webhooks/controller-id",
+		},
+	}
+
+	if err := r.retryPendingWebhookInstall(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The second call happens before the backoff expires and must not hit the API.
+	if err := r.retryPendingWebhookInstall(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r.webhookInstallAttempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", r.webhookInstallAttempts)
+	}
+}
+
+func TestReconcileQueuedJobs(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		Owner:      "test-org",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	lockedBy := uuid.New()
+	staleUpdate := time.Now().Add(-1 * time.Hour)
+
+	completedJob := params.Job{ID: 1, Status: "queued", RepositoryOwner: "test-org", RepositoryName: "test-repo"}
+	missingJob := params.Job{ID: 2, Status: "queued", RepositoryOwner: "test-org", RepositoryName: "test-repo"}
+	lockedJob := params.Job{ID: 3, Status: "queued", RepositoryOwner: "test-org", RepositoryName: "test-repo", LockedBy: lockedBy, UpdatedAt: staleUpdate}
+
+	cli := mocks.NewGithubClient(t)
+	cli.On("GetWorkflowJobByID", mock.Anything, "test-org", "test-repo", int64(1)).Return(
+		&github.WorkflowJob{Status: github.String("completed"), Conclusion: github.String("cancelled")}, nil, nil)
+	cli.On("GetWorkflowJobByID", mock.Anything, "test-org", "test-repo", int64(2)).Return(
+		nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, &github.ErrorResponse{})
+	cli.On("GetWorkflowJobByID", mock.Anything, "test-org", "test-repo", int64(3)).Return(
+		&github.WorkflowJob{Status: github.String("queued")}, nil, nil)
+
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityJobsByStatus", mock.Anything, entity.EntityType, entity.ID, params.JobStatusQueued).Return(
+		[]params.Job{completedJob, missingJob, lockedJob}, nil)
+	store.On("CreateOrUpdateJob", mock.Anything, mock.MatchedBy(func(job params.Job) bool {
+		return job.ID == 1 && job.Status == "completed" && job.Conclusion == "cancelled"
+	})).Return(params.Job{}, nil)
+	store.On("DeleteJob", mock.Anything, int64(2)).Return(nil)
+	store.On("BreakLockJobIsQueued", mock.Anything, int64(3)).Return(nil)
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		ghcli:  cli,
+		store:  store,
+	}
+
+	if err := r.reconcileQueuedJobs(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestReconcileRunnerStatusRechecksInstance(t *testing.T) {
+	controllerID := uuid.New()
+	entity := params.GithubEntity{ID: "test-repo-id", EntityType: params.GithubEntityTypeRepository}
+	stale := time.Now().Add(-2 * runnerStatusReconcileGracePeriod)
+
+	listed := params.Instance{
+		Name:         "runner-1",
+		Status:       "running",
+		RunnerStatus: params.RunnerActive,
+		UpdatedAt:    stale,
+	}
+	// The completed webhook arrived after the instances were listed.
+	current := listed
+	current.RunnerStatus = params.RunnerTerminated
+	current.UpdatedAt = time.Now()
+
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityInstances", mock.Anything, entity).Return([]params.Instance{listed}, nil)
+	store.On("GetInstanceByName", mock.Anything, "runner-1").Return(current, nil)
+
+	r := &basePoolManager{
+		ctx:            context.Background(),
+		entity:         entity,
+		store:          store,
+		keyMux:         newKeyMutex(),
+		controllerInfo: params.ControllerInfo{ControllerID: controllerID},
+	}
+
+	runners := []*github.Runner{
+		{
+			Name:   github.String("runner-1"),
+			Status: github.String("online"),
+			Busy:   github.Bool(false),
+			Labels: []*github.RunnerLabels{{Name: github.String(r.controllerLabel())}},
+		},
+	}
+
+	// The instance must not be set back to idle. The store mock fails the test on
+	// any call to UpdateInstance.
+	if err := r.reconcileRunnerStatus(runners); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestHandleWorkflowJobWaitingForEnvironment(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         uuid.New().String(),
+		Owner:      "test-org",
+		Name:       "test-repo",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	job := params.WorkflowJob{Action: "waiting"}
+	job.WorkflowJob.ID = 10
+	job.WorkflowJob.Status = "waiting"
+	job.WorkflowJob.Labels = []string{"self-hosted", "linux"}
+	job.Repository.Name = "test-repo"
+	job.Repository.Owner.Login = "test-org"
+
+	// Without gating, waiting jobs are ignored.
+	store := dbMocks.NewStore(t)
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+	if err := r.HandleWorkflowJob(job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// With gating, a job that was already recorded as queued is marked as waiting.
+	store.On("GetJobByID", mock.Anything, int64(10)).Return(params.Job{ID: 10, Status: "queued"}, nil)
+	store.On("CreateOrUpdateJob", mock.Anything, mock.MatchedBy(func(j params.Job) bool {
+		return j.ID == 10 && j.Status == string(params.JobStatusWaiting)
+	})).Return(params.Job{}, nil).Once()
+	r.gateEnvironmentJobs = true
+	if err := r.HandleWorkflowJob(job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,169 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// providerHealthState holds the result of the health checks of a provider.
+type providerHealthState struct {
+	// failures is the number of consecutive failed health checks.
+	failures  int
+	lastError string
+	// since is the time of the first failed health check.
+	since time.Time
+}
+
+func (p *providerHealthState) unhealthy() bool {
+	return p.failures >= common.ProviderUnhealthyThreshold
+}
+
+// checkProviderHealth lists the instances of one pool of each provider used by the
+// enabled pools of the entity. This is a cheap operation every provider implements,
+// and it fails if the provider can't reach its IaaS. Providers that fail a number of
+// consecutive checks are considered unhealthy, and the pools that use them are reported
+// as degraded in the status of the pool manager. Providers that set pause_when_unhealthy
+// are treated as paused until they pass a health check.
+func (r *basePoolManager) checkProviderHealth() error {
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		return fmt.Errorf("error listing pools: %w", err)
+	}
+
+	checked := map[string]params.Pool{}
+	for _, pool := range pools {
+		if !pool.Enabled {
+			continue
+		}
+		if _, ok := checked[pool.ProviderName]; !ok {
+			checked[pool.ProviderName] = pool
+		}
+	}
+
+	results := make(map[string]error, len(checked))
+	for name, pool := range checked {
+		provider, ok := r.providers[name]
+		if !ok {
+			continue
+		}
+		listInstancesParams := common.ListInstancesParams{
+			ListInstancesV011: common.ListInstancesV011Params{
+				ProviderBaseParams: r.getProviderBaseParams(pool),
+			},
+		}
+		started := time.Now()
+		_, err := provider.ListInstances(r.ctx, pool.ID, listInstancesParams)
+		observeProviderOperation("ListInstances", pool, started, err)
+		results[name] = err
+	}
+
+	r.recordProviderHealth(pools, results, time.Now())
+	return nil
+}
+
+// recordProviderHealth updates the health of the providers with the results of a health
+// check, and records an entity event when a provider becomes unhealthy or recovers.
+// Providers that are no longer used by an enabled pool are forgotten.
+func (r *basePoolManager) recordProviderHealth(pools []params.Pool, results map[string]error, now time.Time) {
+	type healthEvent struct {
+		level params.EventLevel
+		msg   string
+	}
+	var events []healthEvent
+
+	r.mux.Lock()
+	if r.providerHealth == nil {
+		r.providerHealth = map[string]*providerHealthState{}
+	}
+	for name, state := range r.providerHealth {
+		if _, ok := results[name]; !ok {
+			delete(r.providerHealth, name)
+			if state.unhealthy() {
+				events = append(events, healthEvent{params.EventInfo, fmt.Sprintf("provider %s is no longer used by an enabled pool", name)})
+			}
+		}
+	}
+	for name, err := range results {
+		state, ok := r.providerHealth[name]
+		if err == nil {
+			if ok && state.unhealthy() {
+				slog.InfoContext(
+					r.ctx, "provider recovered",
+					"provider", name)
+				events = append(events, healthEvent{params.EventInfo, fmt.Sprintf("provider %s passed its health check and is healthy again", name)})
+			}
+			delete(r.providerHealth, name)
+			continue
+		}
+
+		if !ok {
+			state = &providerHealthState{since: now}
+			r.providerHealth[name] = state
+		}
+		state.failures++
+		state.lastError = err.Error()
+		if state.failures == common.ProviderUnhealthyThreshold {
+			slog.With(slog.Any("error", err)).WarnContext(
+				r.ctx, "provider is unhealthy",
+				"provider", name,
+				"failures", state.failures)
+			events = append(events, healthEvent{params.EventWarning, fmt.Sprintf(
+				"provider %s failed %d consecutive health checks, pools that use it are degraded: %s",
+				name, state.failures, state.lastError)})
+		}
+	}
+
+	var degraded []params.DegradedPool
+	for _, pool := range pools {
+		state, ok := r.providerHealth[pool.ProviderName]
+		if !pool.Enabled || !ok || !state.unhealthy() {
+			continue
+		}
+		degraded = append(degraded, params.DegradedPool{
+			PoolID:         pool.ID,
+			ProviderName:   pool.ProviderName,
+			Reason:         state.lastError,
+			Since:          state.since,
+			CreationPaused: r.pauseWhenUnhealthy(pool.ProviderName),
+		})
+	}
+	sort.Slice(degraded, func(i, j int) bool {
+		return degraded[i].PoolID < degraded[j].PoolID
+	})
+	r.degradedPools = degraded
+	r.mux.Unlock()
+
+	for _, event := range events {
+		r.addEntityEvent(r.ctx, params.ProviderHealthEvent, event.level, event.msg)
+	}
+}
+
+// pauseWhenUnhealthy returns true if no new instances should be created in pools that
+// use the provider, while it is unhealthy.
+func (r *basePoolManager) pauseWhenUnhealthy(providerName string) bool {
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return false
+	}
+	return provider.AsParams().PauseWhenUnhealthy
+}
+
+// unhealthyPausedProviders returns the names of the unhealthy providers that set
+// pause_when_unhealthy.
+func (r *basePoolManager) unhealthyPausedProviders() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var ret []string
+	for name, state := range r.providerHealth {
+		if state.unhealthy() && r.pauseWhenUnhealthy(name) {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+func TestCheckProviderHealth(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pools := []params.Pool{
+		{ID: "pool-1", ProviderName: "openstack", Enabled: true},
+		{ID: "pool-2", ProviderName: "openstack", Enabled: true},
+		{ID: "pool-3", ProviderName: "openstack"},
+	}
+
+	provider := mocks.NewProvider(t)
+	provider.On("AsParams").Return(params.Provider{Name: "openstack", PauseWhenUnhealthy: true})
+	provider.On("ListInstances", mock.Anything, "pool-1", mock.Anything).Return(
+		nil, errors.New("connection refused")).Times(common.ProviderUnhealthyThreshold)
+	provider.On("ListInstances", mock.Anything, "pool-1", mock.Anything).Return(
+		[]commonParams.ProviderInstance{}, nil).Once()
+
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityPools", mock.Anything, entity).Return(pools, nil)
+	store.On("ListPausedProviders", mock.Anything).Return([]params.ProviderPause{}, nil)
+	store.On("AddEntityEvent", mock.Anything, entity, params.ProviderHealthEvent, params.EventWarning,
+		"provider openstack failed 3 consecutive health checks, pools that use it are degraded: connection refused",
+		common.MaxEntityEvents).Return(nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.ProviderHealthEvent, params.EventInfo,
+		"provider openstack passed its health check and is healthy again",
+		common.MaxEntityEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:       context.Background(),
+		entity:    entity,
+		store:     store,
+		providers: map[string]common.Provider{"openstack": provider},
+	}
+
+	// The provider is only unhealthy after a number of consecutive failures.
+	for i := 1; i < common.ProviderUnhealthyThreshold; i++ {
+		require.NoError(t, r.checkProviderHealth())
+		require.Empty(t, r.Status().DegradedPools)
+	}
+
+	require.NoError(t, r.checkProviderHealth())
+	degraded := r.Status().DegradedPools
+	require.Len(t, degraded, 2)
+	require.Equal(t, "pool-1", degraded[0].PoolID)
+	require.Equal(t, "pool-2", degraded[1].PoolID)
+	require.Equal(t, "connection refused", degraded[0].Reason)
+	require.True(t, degraded[0].CreationPaused)
+	paused, err := r.getPausedProviders()
+	require.NoError(t, err)
+	require.Contains(t, paused, "openstack")
+
+	require.NoError(t, r.checkProviderHealth())
+	require.Empty(t, r.Status().DegradedPools)
+	paused, err = r.getPausedProviders()
+	require.NoError(t, err)
+	require.NotContains(t, paused, "openstack")
+}
+
+func TestRecordProviderHealthForgetsUnusedProviders(t *testing.T) {
+	pool := params.Pool{ID: "pool-1", ProviderName: "openstack", Enabled: true}
+	r := &basePoolManager{
+		ctx:       context.Background(),
+		store:     dbMocks.NewStore(t),
+		providers: map[string]common.Provider{"openstack": mocks.NewProvider(t)},
+	}
+
+	now := time.Now()
+	for i := 1; i < common.ProviderUnhealthyThreshold; i++ {
+		r.recordProviderHealth([]params.Pool{pool}, map[string]error{"openstack": errors.New("boom")}, now)
+	}
+	require.Len(t, r.providerHealth, 1)
+	require.Empty(t, r.unhealthyPausedProviders())
+
+	// The pool was disabled, so the provider is no longer checked.
+	pool.Enabled = false
+	r.recordProviderHealth([]params.Pool{pool}, map[string]error{}, now)
+	require.Empty(t, r.providerHealth)
+	require.Empty(t, r.Status().DegradedPools)
+}
@@ -0,0 +1,30 @@
+package pool
+
+import (
+	"errors"
+	"time"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+)
+
+// observeProviderOperation records how long an operation we asked the provider to
+// perform for a pool took, and whether it failed. Deleting an instance that the
+// provider no longer knows about is not a failure.
+func observeProviderOperation(operation string, pool params.Pool, start time.Time, err error) {
+	metrics.ProviderOperationDuration.WithLabelValues(
+		pool.ProviderName, // label: provider
+		pool.ID,           // label: pool_id
+		operation,         // label: operation
+	).Observe(time.Since(start).Seconds())
+
+	if err != nil && !errors.Is(err, runnerErrors.ErrNotFound) {
+		metrics.ProviderOperationErrors.WithLabelValues(
+			pool.ProviderName, // label: provider
+			pool.ID,           // label: pool_id
+			operation,         // label: operation
+		).Inc()
+	}
+}
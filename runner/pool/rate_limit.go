@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util/ratelimit"
+)
+
+// rateLimitExhaustionImminent returns true if the quota is forecast to run out within
+// the warning threshold, before GitHub resets it.
+func rateLimitExhaustionImminent(forecast params.RateLimitForecast, now time.Time) bool {
+	if forecast.ExhaustsAt == nil || !forecast.ExhaustsBeforeReset {
+		return false
+	}
+	return forecast.ExhaustsAt.Sub(now) <= common.RateLimitWarningThreshold
+}
+
+// checkRateLimitForecast records an entity event when the rate limit of the entity
+// credentials is forecast to run out before it is reset, and when it no longer is.
+// Once the quota runs out, GARM can't create runners or react to jobs until the reset,
+// so this gives operators a chance to slow down other consumers of the credentials.
+func (r *basePoolManager) checkRateLimitForecast() error {
+	r.checkRateLimitForecastWith(ratelimit.Default(), time.Now())
+	return nil
+}
+
+func (r *basePoolManager) checkRateLimitForecastWith(tracker *ratelimit.Tracker, now time.Time) {
+	forecast, ok := tracker.Forecast(r.entity.Credentials.ID, now)
+	imminent := ok && rateLimitExhaustionImminent(forecast, now)
+
+	if imminent {
+		if r.rateLimitWarned {
+			return
+		}
+		r.rateLimitWarned = true
+
+		var share float64
+		for _, usage := range forecast.Entities {
+			if usage.EntityID == r.entity.ID {
+				share = usage.Share
+			}
+		}
+		slog.WarnContext(
+			r.ctx, "rate limit is forecast to run out before it is reset",
+			"credentials", forecast.CredentialsName,
+			"remaining", forecast.Remaining,
+			"exhausts_at", forecast.ExhaustsAt,
+			"reset_at", forecast.ResetAt)
+		msg := fmt.Sprintf(
+			"rate limit of credentials %s is forecast to run out at %s, before it is reset at %s (%d requests remaining, %.1f requests per minute, %.1f%% made by this entity)",
+			forecast.CredentialsName, forecast.ExhaustsAt.Format(time.RFC3339), forecast.ResetAt.Format(time.RFC3339),
+			forecast.Remaining, forecast.ConsumptionPerMinute, share)
+		r.addEntityEvent(r.ctx, params.RateLimitWarningEvent, params.EventWarning, msg)
+		return
+	}
+
+	if r.rateLimitWarned {
+		r.rateLimitWarned = false
+		msg := fmt.Sprintf("rate limit of credentials %s is no longer forecast to run out before it is reset", r.entity.Credentials.Name)
+		r.addEntityEvent(r.ctx, params.RateLimitWarningEvent, params.EventInfo, msg)
+	}
+}
+
+// deferredOnRateLimit wraps the function of a non critical loop, so that it is skipped
+// while the remaining rate limit of the entity credentials is below the threshold. This
+// leaves the remaining requests to the loops that create runners and consume jobs. The
+// loop runs again on its next tick after GitHub resets the quota.
+func (r *basePoolManager) deferredOnRateLimit(name string, f func() error) func() error {
+	return func() error {
+		if r.rateLimitThrottledWith(ratelimit.Default(), name, time.Now()) {
+			return nil
+		}
+		return f()
+	}
+}
+
+func (r *basePoolManager) rateLimitThrottledWith(tracker *ratelimit.Tracker, name string, now time.Time) bool {
+	creds := r.entity.Credentials
+	resetAt, throttled := tracker.Throttled(creds.ID, int(r.rateLimitThreshold), now)
+	metrics.GithubRateLimitThrottled.WithLabelValues(creds.Name).Set(metrics.Bool2float64(throttled))
+	if !throttled {
+		return false
+	}
+	metrics.GithubRateLimitDeferredCount.WithLabelValues(creds.Name, name).Inc()
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if !r.rateLimitThrottledUntil.Equal(resetAt) {
+		r.rateLimitThrottledUntil = resetAt
+		slog.WarnContext(
+			r.ctx, "rate limit is running low, deferring non critical loops until it is reset",
+			"credentials", creds.Name,
+			"threshold", r.rateLimitThreshold,
+			"reset_at", resetAt,
+			"loop_name", name)
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util/ratelimit"
+)
+
+func rateLimitResponse(remaining int, reset time.Time) *http.Response {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5000")
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return &http.Response{Header: header}
+}
+
+func TestCheckRateLimitForecast(t *testing.T) {
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	entity := params.GithubEntity{
+		ID:          "test-repo-id",
+		EntityType:  params.GithubEntityTypeRepository,
+		Credentials: creds,
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := start.Add(time.Hour)
+
+	store := dbMocks.NewStore(t)
+	store.On("AddEntityEvent", mock.Anything, entity, params.RateLimitWarningEvent, params.EventWarning,
+		"rate limit of credentials creds is forecast to run out at 2024-01-01T10:11:00Z, before it is reset at 2024-01-01T11:00:00Z (1000 requests remaining, 100.0 requests per minute, 100.0% made by this entity)",
+		common.MaxEntityEvents).Return(nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.RateLimitWarningEvent, params.EventInfo,
+		"rate limit of credentials creds is no longer forecast to run out before it is reset",
+		common.MaxEntityEvents).Return(nil).Once()
+
+	r := &basePoolManager{
+		ctx:    context.Background(),
+		entity: entity,
+		store:  store,
+	}
+	tracker := ratelimit.NewTracker(ratelimit.DefaultWindow)
+
+	// Consumption is slow enough for the quota to last until the reset.
+	tracker.Record(creds, entity.ID, rateLimitResponse(4000, reset), start)
+	tracker.Record(creds, entity.ID, rateLimitResponse(3990, reset), start.Add(time.Minute))
+	r.checkRateLimitForecastWith(tracker, start.Add(time.Minute))
+
+	// 100 requests per minute exhaust the quota in 10 minutes. The warning is only
+	// recorded once.
+	tracker = ratelimit.NewTracker(ratelimit.DefaultWindow)
+	tracker.Record(creds, entity.ID, rateLimitResponse(1100, reset), start)
+	tracker.Record(creds, entity.ID, rateLimitResponse(1000, reset), start.Add(time.Minute))
+	r.checkRateLimitForecastWith(tracker, start.Add(time.Minute))
+	r.checkRateLimitForecastWith(tracker, start.Add(time.Minute))
+
+	// Once the quota is reset, the warning is cleared.
+	tracker.Record(creds, entity.ID, rateLimitResponse(5000, reset.Add(time.Hour)), start.Add(2*time.Minute))
+	r.checkRateLimitForecastWith(tracker, start.Add(2*time.Minute))
+	r.checkRateLimitForecastWith(tracker, start.Add(2*time.Minute))
+}
+
+func TestDeferredOnRateLimit(t *testing.T) {
+	creds := params.GithubCredentials{ID: 2, Name: "low-creds"}
+	r := &basePoolManager{
+		ctx: context.Background(),
+		entity: params.GithubEntity{
+			ID:          "test-org-id",
+			EntityType:  params.GithubEntityTypeOrganization,
+			Credentials: creds,
+		},
+		rateLimitThreshold: 500,
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := start.Add(30 * time.Minute)
+	tracker := ratelimit.NewTracker(ratelimit.DefaultWindow)
+
+	tracker.Record(creds, r.entity.ID, rateLimitResponse(1000, reset), start)
+	require.False(t, r.rateLimitThrottledWith(tracker, "update_tools", start))
+
+	tracker.Record(creds, r.entity.ID, rateLimitResponse(100, reset), start.Add(time.Minute))
+	require.True(t, r.rateLimitThrottledWith(tracker, "update_tools", start.Add(time.Minute)))
+	require.True(t, r.rateLimitThrottledWith(tracker, "timeout_reaper", start.Add(2*time.Minute)))
+	require.Equal(t, reset, r.rateLimitThrottledUntil)
+
+	// Loops run again once the quota is reset.
+	require.False(t, r.rateLimitThrottledWith(tracker, "update_tools", reset))
+}
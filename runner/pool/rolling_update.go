@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// outdatedIdleRunners returns the idle runners of the pool that were created with an
+// image or flavor that is different from the current one of the pool.
+func outdatedIdleRunners(pool params.Pool, instances []params.Instance) []params.Instance {
+	var ret []params.Instance
+	for _, inst := range instances {
+		if inst.Status != commonParams.InstanceRunning || inst.RunnerStatus != params.RunnerIdle {
+			continue
+		}
+		if pool.IsOutdated(inst) {
+			ret = append(ret, inst)
+		}
+	}
+	return ret
+}
+
+// rollingUpdate replaces the idle runners of pools whose image or flavor changed since
+// the runners were created. Runners are removed in batches, and the ensure min idle
+// loop creates their replacements using the new spec of the pool. Runners that are
+// running a job are left alone, as they are removed once the job finishes.
+func (r *basePoolManager) rollingUpdate() error {
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		return fmt.Errorf("error listing pools: %w", err)
+	}
+
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+
+	if r.rollingUpdateLastBatch == nil {
+		r.rollingUpdateLastBatch = map[string]time.Time{}
+	}
+
+	for _, pool := range pools {
+		// Disabled pools and pools of paused providers can't create replacements, and
+		// draining pools remove all their idle runners anyway.
+		if _, ok := paused[pool.ProviderName]; ok || !pool.Enabled || pool.Draining {
+			continue
+		}
+		if err := r.rollingUpdateOnePool(pool); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to replace outdated runners",
+				"pool_id", pool.ID)
+		}
+	}
+	return nil
+}
+
+func (r *basePoolManager) rollingUpdateOnePool(pool params.Pool) error {
+	pause := time.Duration(pool.RollingUpdatePausePeriod()) * time.Second
+	if lastBatch, ok := r.rollingUpdateLastBatch[pool.ID]; ok && time.Since(lastBatch) < pause {
+		return nil
+	}
+
+	instances, err := r.store.ListPoolInstances(r.ctx, pool.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list instances for pool %s: %w", pool.ID, err)
+	}
+
+	outdated := outdatedIdleRunners(pool, instances)
+	if len(outdated) == 0 {
+		delete(r.rollingUpdateLastBatch, pool.ID)
+		return nil
+	}
+
+	batch := outdated[:min(len(outdated), int(pool.RollingUpdateBatch()))]
+	slog.InfoContext(
+		r.ctx, "replacing outdated runners",
+		"pool_id", pool.ID,
+		"image", pool.Image,
+		"flavor", pool.Flavor,
+		"outdated", len(outdated),
+		"batch", len(batch))
+	r.rollingUpdateLastBatch[pool.ID] = time.Now()
+
+	for _, inst := range batch {
+		fence, ok := r.keyMux.TryLock(inst.Name)
+		if !ok {
+			continue
+		}
+		err := r.DeleteRunner(inst, false, false)
+		r.keyMux.Unlock(inst.Name, fence)
+		if err != nil {
+			return fmt.Errorf("failed to delete instance %s: %w", inst.Name, err)
+		}
+	}
+	return nil
+}
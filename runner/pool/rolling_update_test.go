@@ -0,0 +1,29 @@
+package pool
+
+import (
+	"testing"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestOutdatedIdleRunners(t *testing.T) {
+	pool := params.Pool{Image: "ubuntu-24.04", Flavor: "large"}
+	instances := []params.Instance{
+		{Name: "current", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle, Image: "ubuntu-24.04", Flavor: "large"},
+		{Name: "old-image", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle, Image: "ubuntu-22.04", Flavor: "large"},
+		{Name: "old-flavor", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle, Image: "ubuntu-24.04", Flavor: "small"},
+		{Name: "old-active", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive, Image: "ubuntu-22.04", Flavor: "large"},
+		{Name: "old-creating", Status: commonParams.InstanceCreating, RunnerStatus: params.RunnerPending, Image: "ubuntu-22.04", Flavor: "large"},
+		{Name: "unknown-spec", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle},
+	}
+
+	outdated := outdatedIdleRunners(pool, instances)
+	if len(outdated) != 2 {
+		t.Fatalf("expected 2 outdated runners, got %d", len(outdated))
+	}
+	if outdated[0].Name != "old-image" || outdated[1].Name != "old-flavor" {
+		t.Fatalf("unexpected outdated runners: %s, %s", outdated[0].Name, outdated[1].Name)
+	}
+}
@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
+)
+
+type poolLoad struct {
+	runners uint
+	idle    uint
+}
+
+// poolRouter orders the pools that match a job, using the routing rules of the entity.
+// It keeps track of the runners it creates, so that the jobs handled in the same pass
+// are routed using up to date counts.
+type poolRouter struct {
+	rules []params.RoutingRule
+	loads map[string]*poolLoad
+}
+
+func (r *basePoolManager) newPoolRouter() (*poolRouter, error) {
+	instances, err := r.store.ListEntityInstances(r.ctx, r.entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing instances")
+	}
+
+	router := &poolRouter{
+		rules: r.entity.RoutingRules,
+		loads: map[string]*poolLoad{},
+	}
+	for _, inst := range instances {
+		load := router.load(inst.PoolID)
+		load.runners++
+		if inst.RunnerStatus == params.RunnerIdle && inst.Status == commonParams.InstanceRunning {
+			load.idle++
+		}
+	}
+	return router, nil
+}
+
+func (p *poolRouter) load(poolID string) *poolLoad {
+	load, ok := p.loads[poolID]
+	if !ok {
+		load = &poolLoad{}
+		p.loads[poolID] = load
+	}
+	return load
+}
+
+// runnerAdded records a runner that was created in the pool.
+func (p *poolRouter) runnerAdded(poolID string) {
+	p.load(poolID).runners++
+}
+
+// Route returns the pools in the order in which a runner should be created in them.
+// The pools are expected to be sorted by priority, which breaks the ties between
+// pools the routing rules consider equal.
+func (p *poolRouter) Route(pools []params.Pool) poolCacheStore {
+	ordered := make([]params.Pool, len(pools))
+	copy(ordered, pools)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		for _, rule := range p.rules {
+			if cmp := p.compare(rule, ordered[i], ordered[j]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	return &poolRoundRobin{pools: ordered}
+}
+
+// compare returns a negative number if pool a is preferred over pool b by the rule, a
+// positive number if b is preferred over a, and 0 if the rule does not prefer any of them.
+func (p *poolRouter) compare(rule params.RoutingRule, a, b params.Pool) int {
+	switch rule.Type {
+	case params.RoutingRulePreferIdle:
+		aIdle, bIdle := p.load(a.ID).idle > 0, p.load(b.ID).idle > 0
+		switch {
+		case aIdle && !bIdle:
+			return -1
+		case bIdle && !aIdle:
+			return 1
+		}
+	case params.RoutingRuleLeastLoaded:
+		// Compare runners/max runners without dividing. Pools that can't have
+		// runners are the most loaded.
+		aLoad := uint64(p.load(a.ID).runners) * uint64(b.MaxRunners)
+		bLoad := uint64(p.load(b.ID).runners) * uint64(a.MaxRunners)
+		switch {
+		case a.MaxRunners == 0 && b.MaxRunners == 0:
+			return 0
+		case a.MaxRunners == 0:
+			return 1
+		case b.MaxRunners == 0:
+			return -1
+		case aLoad < bLoad:
+			return -1
+		case aLoad > bLoad:
+			return 1
+		}
+	case params.RoutingRuleProviderOrder:
+		return providerRank(rule.Providers, a.ProviderName) - providerRank(rule.Providers, b.ProviderName)
+	}
+	return 0
+}
+
+func providerRank(providers []string, name string) int {
+	for idx, provider := range providers {
+		if provider == name {
+			return idx
+		}
+	}
+	return len(providers)
+}
@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+)
+
+func routedPoolIDs(pools poolCacheStore) []string {
+	ids := []string{}
+	for _, pool := range pools.Pools() {
+		ids = append(ids, pool.ID)
+	}
+	return ids
+}
+
+func TestPoolRouterRoute(t *testing.T) {
+	// Pools are passed in priority order, as returned by the pool cache.
+	pools := []params.Pool{
+		{ID: "openstack-pool", ProviderName: "openstack", MaxRunners: 10},
+		{ID: "lxd-pool", ProviderName: "lxd", MaxRunners: 4},
+		{ID: "azure-pool", ProviderName: "azure", MaxRunners: 2},
+	}
+	instances := []params.Instance{
+		{PoolID: "openstack-pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive},
+		{PoolID: "openstack-pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive},
+		{PoolID: "openstack-pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive},
+		{PoolID: "lxd-pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive},
+		{PoolID: "azure-pool", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []params.RoutingRule
+		expected []string
+	}{
+		{
+			name:     "prefer idle",
+			rules:    []params.RoutingRule{{Type: params.RoutingRulePreferIdle}},
+			expected: []string{"azure-pool", "openstack-pool", "lxd-pool"},
+		},
+		{
+			name:     "least loaded",
+			rules:    []params.RoutingRule{{Type: params.RoutingRuleLeastLoaded}},
+			expected: []string{"lxd-pool", "openstack-pool", "azure-pool"},
+		},
+		{
+			name:     "provider order",
+			rules:    []params.RoutingRule{{Type: params.RoutingRuleProviderOrder, Providers: []string{"lxd", "azure"}}},
+			expected: []string{"lxd-pool", "azure-pool", "openstack-pool"},
+		},
+		{
+			name: "ties fall through to the next rule",
+			rules: []params.RoutingRule{
+				{Type: params.RoutingRuleProviderOrder, Providers: []string{"azure"}},
+				{Type: params.RoutingRuleLeastLoaded},
+			},
+			expected: []string{"azure-pool", "lxd-pool", "openstack-pool"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entity := params.GithubEntity{
+				ID:           "test-repo-id",
+				EntityType:   params.GithubEntityTypeRepository,
+				RoutingRules: tc.rules,
+			}
+			store := dbMocks.NewStore(t)
+			store.On("ListEntityInstances", mock.Anything, entity).Return(instances, nil)
+
+			r := &basePoolManager{
+				ctx:    context.Background(),
+				entity: entity,
+				store:  store,
+			}
+			router, err := r.newPoolRouter()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			got := routedPoolIDs(router.Route(pools))
+			for idx := range tc.expected {
+				if got[idx] != tc.expected[idx] {
+					t.Fatalf("expected pools %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolRouterRunnerAdded(t *testing.T) {
+	pools := []params.Pool{
+		{ID: "pool-1", MaxRunners: 2},
+		{ID: "pool-2", MaxRunners: 2},
+	}
+	router := &poolRouter{
+		rules: []params.RoutingRule{{Type: params.RoutingRuleLeastLoaded}},
+		loads: map[string]*poolLoad{},
+	}
+
+	if got := routedPoolIDs(router.Route(pools)); got[0] != "pool-1" {
+		t.Fatalf("expected pool-1 to be tried first, got %v", got)
+	}
+	router.runnerAdded("pool-1")
+	if got := routedPoolIDs(router.Route(pools)); got[0] != "pool-2" {
+		t.Fatalf("expected pool-2 to be tried first, got %v", got)
+	}
+}
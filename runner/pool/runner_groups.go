@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func (r *basePoolManager) ListRunnerGroups(ctx context.Context) ([]params.RunnerGroup, error) {
+	groups, err := r.ghcli.ListEntityRunnerGroups(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing runner groups")
+	}
+	return groups, nil
+}
+
+func (r *basePoolManager) GetRunnerGroup(ctx context.Context, name string) (params.RunnerGroup, error) {
+	group, err := r.ghcli.GetEntityRunnerGroupByName(ctx, name)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "fetching runner group")
+	}
+	return group, nil
+}
+
+func (r *basePoolManager) CreateRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	group, err := r.ghcli.CreateEntityRunnerGroup(ctx, param)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "creating runner group")
+	}
+	return group, nil
+}
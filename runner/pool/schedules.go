@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// applyPoolSchedules sets the min idle runners of the pools that have an active schedule
+// window to the value of that window. Pools with no active window keep their own min idle
+// runners. Once a window ends, the extra idle runners are removed by the scale down loop.
+func applyPoolSchedules(pools []params.Pool, now time.Time) []params.Pool {
+	ret := make([]params.Pool, len(pools))
+	for idx, pool := range pools {
+		if pool.Schedule != nil {
+			if minIdleRunners, ok := pool.Schedule.MinIdleRunners(now); ok {
+				pool.MinIdleRunners = min(minIdleRunners, pool.MaxRunners)
+			}
+		}
+		ret[idx] = pool
+	}
+	return ret
+}
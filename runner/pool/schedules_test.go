@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestApplyPoolSchedules(t *testing.T) {
+	schedule := &params.PoolSchedule{
+		Timezone: "America/New_York",
+		Windows: []params.ScheduleWindow{
+			{Days: []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, Start: "08:00", End: "20:00", MinIdleRunners: 5},
+			// Windows that end before they start continue on the next day.
+			{Days: []string{"friday"}, Start: "22:00", End: "02:00", MinIdleRunners: 2},
+		},
+	}
+	pools := []params.Pool{
+		{ID: "scheduled-pool", MinIdleRunners: 0, MaxRunners: 10, Schedule: schedule},
+		{ID: "small-pool", MinIdleRunners: 0, MaxRunners: 3, Schedule: schedule},
+		{ID: "plain-pool", MinIdleRunners: 1, MaxRunners: 10},
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %s", err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want []uint
+	}{
+		{name: "weekday business hours", now: time.Date(2024, 6, 3, 9, 0, 0, 0, loc), want: []uint{5, 3, 1}},
+		{name: "weekday evening", now: time.Date(2024, 6, 3, 20, 0, 0, 0, loc), want: []uint{0, 0, 1}},
+		{name: "weekend", now: time.Date(2024, 6, 8, 9, 0, 0, 0, loc), want: []uint{0, 0, 1}},
+		{name: "window crossing midnight", now: time.Date(2024, 6, 8, 1, 0, 0, 0, loc), want: []uint{2, 2, 1}},
+		{name: "other time zone", now: time.Date(2024, 6, 3, 13, 0, 0, 0, time.UTC), want: []uint{5, 3, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := applyPoolSchedules(pools, tc.now)
+			for idx, want := range tc.want {
+				if got[idx].MinIdleRunners != want {
+					t.Fatalf("expected %d min idle runners for %s, got %d", want, got[idx].ID, got[idx].MinIdleRunners)
+				}
+			}
+		})
+	}
+	if pools[0].MinIdleRunners != 0 {
+		t.Fatalf("expected the pools passed in to be left unchanged")
+	}
+}
@@ -32,6 +32,10 @@ func (s *stubGithubClient) PingEntityHook(_ context.Context, _ int64) (*github.R
 	return nil, s.err
 }
 
+func (s *stubGithubClient) ListEntityHookDeliveries(_ context.Context, _ int64, _ *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+	return nil, nil, s.err
+}
+
 func (s *stubGithubClient) ListEntityRunners(_ context.Context, _ *github.ListOptions) (*github.Runners, *github.Response, error) {
 	return nil, nil, s.err
 }
@@ -52,6 +56,46 @@ func (s *stubGithubClient) GetEntityJITConfig(_ context.Context, _ string, _ par
 	return nil, nil, s.err
 }
 
+func (s *stubGithubClient) ListEntityRunnerGroups(_ context.Context) ([]params.RunnerGroup, error) {
+	return nil, s.err
+}
+
+func (s *stubGithubClient) GetEntityRunnerGroupByName(_ context.Context, _ string) (params.RunnerGroup, error) {
+	return params.RunnerGroup{}, s.err
+}
+
+func (s *stubGithubClient) CreateEntityRunnerGroup(_ context.Context, _ params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	return params.RunnerGroup{}, s.err
+}
+
+func (s *stubGithubClient) GetEntityActionsPermissions(_ context.Context) (*github.ActionsPermissionsRepository, *github.Response, error) {
+	return nil, nil, s.err
+}
+
+func (s *stubGithubClient) GetActionsPermissions(_ context.Context, _ string) (*github.ActionsPermissions, *github.Response, error) {
+	return nil, nil, s.err
+}
+
+func (s *stubGithubClient) ListEnabledReposInOrg(_ context.Context, _ string, _ *github.ListOptions) (*github.ActionsEnabledOnOrgRepos, *github.Response, error) {
+	return nil, nil, s.err
+}
+
+func (s *stubGithubClient) ListOrganizationRunnerGroups(_ context.Context, _ string, _ *github.ListOrgRunnerGroupOptions) (*github.RunnerGroups, *github.Response, error) {
+	return nil, nil, s.err
+}
+
+func (s *stubGithubClient) ListRepositoryAccessRunnerGroup(_ context.Context, _ string, _ int64, _ *github.ListOptions) (*github.ListRepositories, *github.Response, error) {
+	return nil, nil, s.err
+}
+
 func (s *stubGithubClient) GetWorkflowJobByID(_ context.Context, _, _ string, _ int64) (*github.WorkflowJob, *github.Response, error) {
 	return nil, nil, s.err
 }
+
+func (s *stubGithubClient) ListRepositoryWorkflowRuns(_ context.Context, _, _ string, _ *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	return nil, nil, s.err
+}
+
+func (s *stubGithubClient) ListWorkflowJobs(_ context.Context, _, _ string, _ int64, _ *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error) {
+	return nil, nil, s.err
+}
@@ -1,12 +1,16 @@
 package pool
 
 import (
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/google/uuid"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
@@ -19,6 +23,7 @@ type poolCacheStore interface {
 	Next() (params.Pool, error)
 	Reset()
 	Len() int
+	Pools() []params.Pool
 }
 
 type poolRoundRobin struct {
@@ -39,6 +44,10 @@ func (p *poolRoundRobin) Len() int {
 	return len(p.pools)
 }
 
+func (p *poolRoundRobin) Pools() []params.Pool {
+	return p.pools
+}
+
 func (p *poolRoundRobin) Reset() {
 	atomic.StoreUint32(&p.next, 0)
 }
@@ -97,6 +106,52 @@ func controllerIDFromLabels(labels []string) string {
 	return ""
 }
 
+func poolPinLabel(poolID string) string {
+	return fmt.Sprintf("%s%s", jobPoolPinLabelPrefix, poolID)
+}
+
+// pinnedPoolFromLabels returns the ID of the pool a job is pinned to, along with
+// the rest of the job labels. If the job is not pinned, the returned pool ID is empty.
+func pinnedPoolFromLabels(labels []string) (string, []string, error) {
+	var poolID string
+	otherLabels := []string{}
+	for _, lbl := range labels {
+		if len(lbl) < len(jobPoolPinLabelPrefix) || !strings.EqualFold(lbl[:len(jobPoolPinLabelPrefix)], jobPoolPinLabelPrefix) {
+			otherLabels = append(otherLabels, lbl)
+			continue
+		}
+		if poolID != "" {
+			return "", nil, runnerErrors.NewBadRequestError("job is pinned to more than one pool")
+		}
+		poolID = strings.TrimSpace(lbl[len(jobPoolPinLabelPrefix):])
+		if poolID == "" {
+			return "", nil, runnerErrors.NewBadRequestError("empty pool ID in label %s", lbl)
+		}
+		if _, err := uuid.Parse(poolID); err != nil {
+			return "", nil, runnerErrors.NewBadRequestError("invalid pool ID in label %s", lbl)
+		}
+	}
+	return poolID, otherLabels, nil
+}
+
+// poolHasAllTags returns true if the pool is tagged with all of the given labels.
+// Labels are case insensitive.
+func poolHasAllTags(pool params.Pool, labels []string) bool {
+	for _, lbl := range labels {
+		found := false
+		for _, tag := range pool.Tags {
+			if strings.EqualFold(tag.Name, lbl) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func labelsFromRunner(runner *github.Runner) []string {
 	if runner == nil || runner.Labels == nil {
 		return []string{}
@@ -113,15 +168,34 @@ func labelsFromRunner(runner *github.Runner) []string {
 }
 
 // isManagedRunner returns true if labels indicate the runner belongs to a pool
-// this manager is responsible for.
-func isManagedRunner(labels []string, controllerID string) bool {
+// this manager is responsible for. More than one controller ID may be passed
+// while the controller ID is being migrated.
+func isManagedRunner(labels []string, controllerIDs ...string) bool {
 	runnerControllerID := controllerIDFromLabels(labels)
-	return runnerControllerID == controllerID
+	if runnerControllerID == "" {
+		return false
+	}
+	return slices.Contains(controllerIDs, runnerControllerID)
+}
+
+// isScaleDownCandidate returns true if the instance is an idle runner that may be removed
+// when scaling down the pool. Runners that have been idle for longer than the idle detection
+// window of the pool are taken into consideration for scale-down. Runners that were created
+// less than the scale down grace period ago are skipped. The grace period prevents a situation
+// where a "queued" workflow triggers the creation of a new runner, and scale down reaps it
+// before it has a chance to pick up the job.
+func isScaleDownCandidate(inst params.Instance, pool params.Pool) bool {
+	if inst.RunnerStatus != params.RunnerIdle || inst.Status != commonParams.InstanceRunning {
+		return false
+	}
+	idleWindow := time.Duration(pool.IdleWindow()) * time.Minute
+	gracePeriod := time.Duration(pool.ScaleDownGrace()) * time.Minute
+	return time.Since(inst.UpdatedAt) >= idleWindow && time.Since(inst.CreatedAt) >= gracePeriod
 }
 
 func composeWatcherFilters(entity params.GithubEntity) dbCommon.PayloadFilterFunc {
-	// We want to watch for changes in either the controller or the
-	// entity itself.
+	// We want to watch for changes in either the controller, the
+	// entity itself or its pools.
 	return watcher.WithAny(
 		watcher.WithAll(
 			// Updates to the controller
@@ -130,7 +204,21 @@ func composeWatcherFilters(entity params.GithubEntity) dbCommon.PayloadFilterFun
 		),
 		// Any operation on the entity we're managing the pool for.
 		watcher.WithEntityFilter(entity),
+		// New and updated pools of the entity, which are reconciled right away.
+		watcher.WithAll(
+			watcher.WithEntityPoolFilter(entity),
+			watcher.WithAny(
+				watcher.WithOperationTypeFilter(dbCommon.CreateOperation),
+				watcher.WithOperationTypeFilter(dbCommon.UpdateOperation),
+			),
+		),
 		// Watch for changes to the github credentials
 		watcher.WithGithubCredentialsFilter(entity.Credentials),
+		// Watch for changes to the github endpoint, which may change where we
+		// fetch the tools from.
+		watcher.WithAll(
+			watcher.WithGithubEndpointFilter(entity.Credentials.Endpoint),
+			watcher.WithOperationTypeFilter(dbCommon.UpdateOperation),
+		),
 	)
 }
@@ -1,10 +1,13 @@
 package pool
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
 	"github.com/cloudbase/garm/params"
 )
 
@@ -226,3 +229,124 @@ func TestPoolsForTagsBalancerTypePack(t *testing.T) {
 		t.Fatalf("expected 0, got %d", poolCache.next)
 	}
 }
+
+func TestPinnedPoolFromLabels(t *testing.T) {
+	poolID := "5c2ab7a2-ff3f-4b11-9e05-3b5ba6a3d8ee"
+
+	pinned, other, err := pinnedPoolFromLabels([]string{"linux", "GARM-pool=" + poolID, "x64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if pinned != poolID {
+		t.Fatalf("expected pool ID %s, got %q", poolID, pinned)
+	}
+	if len(other) != 2 || other[0] != "linux" || other[1] != "x64" {
+		t.Fatalf("unexpected remaining labels: %v", other)
+	}
+
+	pinned, other, err = pinnedPoolFromLabels([]string{"linux", "x64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if pinned != "" || len(other) != 2 {
+		t.Fatalf("expected no pinned pool, got %q (%v)", pinned, other)
+	}
+}
+
+func TestPinnedPoolFromLabelsInvalid(t *testing.T) {
+	tests := [][]string{
+		{"garm-pool="},
+		{"garm-pool=not-a-uuid"},
+		{"garm-pool=5c2ab7a2-ff3f-4b11-9e05-3b5ba6a3d8ee", "garm-pool=0f4ea0a2-8a35-4a3c-9d5b-1f1b3aa1d2e4"},
+	}
+	for _, labels := range tests {
+		_, _, err := pinnedPoolFromLabels(labels)
+		if err == nil {
+			t.Fatalf("expected error for labels %v", labels)
+		}
+		var badReq *runnerErrors.BadRequestError
+		if !errors.As(err, &badReq) {
+			t.Fatalf("expected bad request error for labels %v, got %v", labels, err)
+		}
+	}
+}
+
+func TestPoolHasAllTags(t *testing.T) {
+	pool := params.Pool{
+		Tags: []params.Tag{
+			{Name: "linux"},
+			{Name: "X64"},
+		},
+	}
+	if !poolHasAllTags(pool, []string{"Linux", "x64"}) {
+		t.Fatalf("expected pool to have all tags")
+	}
+	if !poolHasAllTags(pool, nil) {
+		t.Fatalf("expected pool to match an empty list of tags")
+	}
+	if poolHasAllTags(pool, []string{"linux", "gpu"}) {
+		t.Fatalf("expected pool to not have all tags")
+	}
+}
+
+func TestIsScaleDownCandidate(t *testing.T) {
+	now := time.Now()
+	pool := params.Pool{IdleDetectionWindow: 10, ScaleDownGracePeriod: 30}
+
+	tests := []struct {
+		name     string
+		instance params.Instance
+		pool     params.Pool
+		expected bool
+	}{
+		{
+			name: "idle past both windows",
+			instance: params.Instance{
+				Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle,
+				CreatedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-11 * time.Minute),
+			},
+			pool:     pool,
+			expected: true,
+		},
+		{
+			name: "idle within detection window",
+			instance: params.Instance{
+				Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle,
+				CreatedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-5 * time.Minute),
+			},
+			pool: pool,
+		},
+		{
+			name: "idle within grace period",
+			instance: params.Instance{
+				Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle,
+				CreatedAt: now.Add(-20 * time.Minute), UpdatedAt: now.Add(-15 * time.Minute),
+			},
+			pool: pool,
+		},
+		{
+			name: "active runner",
+			instance: params.Instance{
+				Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive,
+				CreatedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-time.Hour),
+			},
+			pool: pool,
+		},
+		{
+			name: "defaults are used if not set",
+			instance: params.Instance{
+				Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle,
+				CreatedAt: now.Add(-4 * time.Minute), UpdatedAt: now.Add(-3 * time.Minute),
+			},
+			pool: params.Pool{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isScaleDownCandidate(tc.instance, tc.pool); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
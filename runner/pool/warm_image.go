@@ -0,0 +1,211 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+const (
+	// warmImageBuildTimeout is the maximum amount of time a provider may take to prepare
+	// and snapshot a runner. The runner is locked during the build, so this must stay
+	// below the max age of instance locks.
+	warmImageBuildTimeout = 45 * time.Minute
+	// warmImageRetryInterval is the amount of time we wait before retrying a failed
+	// warm image build.
+	warmImageRetryInterval = 1 * time.Hour
+)
+
+// warmImageBuildDue returns true if the warm image of the pool needs to be built. This
+// is the case if no image was built yet, if the image of the pool changed since the
+// last build, or if the refresh interval elapsed. Failed builds are retried at most
+// once every warmImageRetryInterval.
+func warmImageBuildDue(pool params.Pool, now time.Time) bool {
+	if pool.WarmImage == nil || pool.WarmImage.IsEmpty() {
+		return false
+	}
+	status := pool.WarmImageStatus
+	if status == nil || status.LastAttemptAt.IsZero() {
+		return true
+	}
+
+	refresh := pool.WarmImage.RefreshPeriod()
+	sinceLastAttempt := now.Sub(status.LastAttemptAt)
+	if status.LastError != "" {
+		return sinceLastAttempt >= min(warmImageRetryInterval, refresh)
+	}
+	if status.BaseImage != pool.Image {
+		return true
+	}
+	return sinceLastAttempt >= refresh
+}
+
+// warmImageCandidate returns an idle runner of the pool, created with the current spec
+// of the pool, which can be prepared and snapshotted.
+func warmImageCandidate(pool params.Pool, instances []params.Instance) (params.Instance, bool) {
+	for _, inst := range instances {
+		if inst.Status != commonParams.InstanceRunning || inst.RunnerStatus != params.RunnerIdle {
+			continue
+		}
+		if pool.IsOutdated(inst) {
+			continue
+		}
+		return inst, true
+	}
+	return params.Instance{}, false
+}
+
+// buildWarmImages builds the warm images of the pools that need one. Builds use an
+// idle runner of the pool, so pools without idle runners are skipped until one is
+// created, either by the min idle runners setting or by a job.
+func (r *basePoolManager) buildWarmImages() error {
+	pools, err := r.store.ListEntityPools(r.ctx, r.entity)
+	if err != nil {
+		return fmt.Errorf("error listing pools: %w", err)
+	}
+
+	paused, err := r.getPausedProviders()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, pool := range pools {
+		if _, ok := paused[pool.ProviderName]; ok || !pool.Enabled || pool.Draining {
+			continue
+		}
+		if !warmImageBuildDue(pool, now) {
+			continue
+		}
+		if err := r.buildWarmImage(pool); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to build warm image",
+				"pool_id", pool.ID)
+		}
+	}
+	return nil
+}
+
+func (r *basePoolManager) buildWarmImage(pool params.Pool) error {
+	provider, ok := r.providers[pool.ProviderName]
+	if !ok {
+		return fmt.Errorf("unknown provider %s for pool %s", pool.ProviderName, pool.ID)
+	}
+	snapshotProvider, ok := provider.(common.SnapshotProvider)
+	if !ok || !snapshotProvider.SupportsSnapshot() {
+		// Snapshots were disabled in the provider config after the pool was set up.
+		return nil
+	}
+
+	instances, err := r.store.ListPoolInstances(r.ctx, pool.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list instances for pool %s: %w", pool.ID, err)
+	}
+	candidate, ok := warmImageCandidate(pool, instances)
+	if !ok {
+		return nil
+	}
+
+	fence, ok := r.keyMux.TryLock(candidate.Name)
+	if !ok {
+		return nil
+	}
+	defer r.keyMux.Unlock(candidate.Name, fence)
+
+	// Take the runner out of rotation, so it doesn't pick up a job while it is being
+	// prepared. GitHub refuses to remove runners that are running a job.
+	if candidate.AgentID != 0 && !candidate.JitRegistrationRemoved {
+		resp, err := r.ghcli.RemoveEntityRunner(r.ctx, candidate.AgentID)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+				slog.DebugContext(
+					r.ctx, "runner picked up a job, skipping warm image build",
+					"runner_name", candidate.Name)
+				return nil
+			}
+			if resp == nil || resp.StatusCode != http.StatusNotFound {
+				return errors.Wrap(err, "removing runner")
+			}
+		}
+	}
+	removed := true
+	candidate, err = r.store.UpdateInstance(r.ctx, candidate.Name, params.UpdateInstanceParams{
+		RunnerStatus:           params.RunnerTerminated,
+		JitRegistrationRemoved: &removed,
+	})
+	if err != nil {
+		return errors.Wrap(err, "updating instance")
+	}
+
+	status := params.WarmImageStatus{}
+	if pool.WarmImageStatus != nil {
+		status = *pool.WarmImageStatus
+	}
+	status.LastAttemptAt = time.Now().UTC()
+	snapshotName := fmt.Sprintf("garm-warm-%s-%s", pool.ID, status.LastAttemptAt.Format("20060102150405"))
+
+	identifier := candidate.ProviderID
+	if identifier == "" {
+		identifier = candidate.Name
+	}
+	createSnapshotParams := common.CreateSnapshotParams{
+		CreateSnapshotV011: common.CreateSnapshotV011Params{
+			ProviderBaseParams: r.getProviderBaseParams(pool),
+			SnapshotName:       snapshotName,
+			PrepareScript:      pool.WarmImage.PrepareScript,
+		},
+	}
+
+	slog.InfoContext(
+		r.ctx, "building warm image",
+		"pool_id", pool.ID,
+		"runner_name", candidate.Name,
+		"snapshot_name", snapshotName)
+	r.recordProviderOperation(candidate.Name, params.EventInfo, "building warm image %s", snapshotName)
+
+	ctx, cancel := context.WithTimeout(r.ctx, warmImageBuildTimeout)
+	started := time.Now()
+	image, buildErr := snapshotProvider.CreateInstanceSnapshot(ctx, identifier, createSnapshotParams)
+	observeProviderOperation("CreateInstanceSnapshot", pool, started, buildErr)
+	cancel()
+
+	if buildErr != nil {
+		status.LastError = buildErr.Error()
+		r.recordProviderOperation(candidate.Name, params.EventError, "failed to build warm image: %q", buildErr)
+		r.addEntityEvent(
+			r.ctx, params.WarmImageEvent, params.EventWarning,
+			fmt.Sprintf("failed to build warm image for pool %s: %q", pool.ID, buildErr))
+	} else {
+		status.Image = image
+		status.BaseImage = pool.Image
+		status.BuiltAt = status.LastAttemptAt
+		status.LastError = ""
+		r.addEntityEvent(
+			r.ctx, params.WarmImageEvent, params.EventInfo,
+			fmt.Sprintf("built warm image %s for pool %s", image, pool.ID))
+	}
+
+	if _, err := r.store.UpdatePoolWarmImageStatus(r.ctx, pool.ID, status); err != nil {
+		return errors.Wrap(err, "updating warm image status")
+	}
+
+	// The runner was changed by the preparation script and is no longer registered in
+	// GitHub. The pool creates a replacement, using the new image if the build succeeded.
+	if err := r.DeleteRunner(candidate, false, false); err != nil {
+		return errors.Wrap(err, "removing prepared runner")
+	}
+
+	if buildErr != nil {
+		return errors.Wrap(buildErr, "creating snapshot")
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	dbMocks "github.com/cloudbase/garm/database/common/mocks"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/common/mocks"
+)
+
+type snapshotProvider struct {
+	*mocks.Provider
+	image    string
+	err      error
+	instance string
+	params   common.CreateSnapshotParams
+}
+
+func (s *snapshotProvider) SupportsSnapshot() bool {
+	return true
+}
+
+func (s *snapshotProvider) CreateInstanceSnapshot(_ context.Context, instance string, createSnapshotParams common.CreateSnapshotParams) (string, error) {
+	s.instance = instance
+	s.params = createSnapshotParams
+	return s.image, s.err
+}
+
+func TestWarmImageBuildDue(t *testing.T) {
+	now := time.Now()
+	settings := &params.WarmImageSettings{PrepareScript: "#!/bin/sh", RefreshInterval: 12}
+
+	tests := []struct {
+		name     string
+		pool     params.Pool
+		expected bool
+	}{
+		{
+			name:     "warm image disabled",
+			pool:     params.Pool{Image: "ubuntu"},
+			expected: false,
+		},
+		{
+			name:     "never built",
+			pool:     params.Pool{Image: "ubuntu", WarmImage: settings},
+			expected: true,
+		},
+		{
+			name: "recently built",
+			pool: params.Pool{Image: "ubuntu", WarmImage: settings, WarmImageStatus: &params.WarmImageStatus{
+				Image: "warm", BaseImage: "ubuntu", LastAttemptAt: now.Add(-time.Hour),
+			}},
+			expected: false,
+		},
+		{
+			name: "refresh interval elapsed",
+			pool: params.Pool{Image: "ubuntu", WarmImage: settings, WarmImageStatus: &params.WarmImageStatus{
+				Image: "warm", BaseImage: "ubuntu", LastAttemptAt: now.Add(-13 * time.Hour),
+			}},
+			expected: true,
+		},
+		{
+			name: "image of the pool changed",
+			pool: params.Pool{Image: "ubuntu-24.04", WarmImage: settings, WarmImageStatus: &params.WarmImageStatus{
+				Image: "warm", BaseImage: "ubuntu", LastAttemptAt: now.Add(-time.Hour),
+			}},
+			expected: true,
+		},
+		{
+			name: "failed build is not retried right away",
+			pool: params.Pool{Image: "ubuntu-24.04", WarmImage: settings, WarmImageStatus: &params.WarmImageStatus{
+				LastAttemptAt: now.Add(-time.Minute), LastError: "snapshot failed",
+			}},
+			expected: false,
+		},
+		{
+			name: "failed build is retried",
+			pool: params.Pool{Image: "ubuntu", WarmImage: settings, WarmImageStatus: &params.WarmImageStatus{
+				LastAttemptAt: now.Add(-warmImageRetryInterval), LastError: "snapshot failed",
+			}},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, warmImageBuildDue(tc.pool, now))
+		})
+	}
+}
+
+func TestWarmImageCandidate(t *testing.T) {
+	pool := params.Pool{Image: "ubuntu", Flavor: "small"}
+	instances := []params.Instance{
+		{Name: "busy", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerActive, Image: "ubuntu", Flavor: "small"},
+		{Name: "outdated", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle, Image: "debian", Flavor: "small"},
+		{Name: "idle", Status: commonParams.InstanceRunning, RunnerStatus: params.RunnerIdle, Image: "ubuntu", Flavor: "small"},
+	}
+
+	candidate, ok := warmImageCandidate(pool, instances)
+	require.True(t, ok)
+	require.Equal(t, "idle", candidate.Name)
+
+	_, ok = warmImageCandidate(pool, instances[:2])
+	require.False(t, ok)
+}
+
+func TestBuildWarmImage(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pool := params.Pool{
+		ID:           "pool-1",
+		ProviderName: "openstack",
+		Image:        "ubuntu",
+		Enabled:      true,
+		WarmImage:    &params.WarmImageSettings{PrepareScript: "#!/bin/sh\necho warm"},
+	}
+	instance := params.Instance{
+		Name:         "garm-runner",
+		ProviderID:   "provider-id",
+		PoolID:       pool.ID,
+		Status:       commonParams.InstanceRunning,
+		RunnerStatus: params.RunnerIdle,
+		Image:        "ubuntu",
+	}
+	removed := true
+	prepared := instance
+	prepared.RunnerStatus = params.RunnerTerminated
+	prepared.JitRegistrationRemoved = true
+
+	store := dbMocks.NewStore(t)
+	store.On("ListEntityPools", mock.Anything, entity).Return([]params.Pool{pool}, nil)
+	store.On("ListPausedProviders", mock.Anything).Return([]params.ProviderPause{}, nil)
+	store.On("ListPoolInstances", mock.Anything, pool.ID).Return([]params.Instance{instance}, nil)
+	store.On("UpdateInstance", mock.Anything, instance.Name, params.UpdateInstanceParams{
+		RunnerStatus:           params.RunnerTerminated,
+		JitRegistrationRemoved: &removed,
+	}).Return(prepared, nil).Once()
+	store.On("AddInstanceEvent", mock.Anything, instance.Name, params.ProviderOperationEvent, params.EventInfo, mock.Anything, common.MaxInstanceEvents).Return(nil).Once()
+	store.On("AddEntityEvent", mock.Anything, entity, params.WarmImageEvent, params.EventInfo,
+		"built warm image warm-image-id for pool pool-1", common.MaxEntityEvents).Return(nil).Once()
+	store.On("UpdatePoolWarmImageStatus", mock.Anything, pool.ID, mock.MatchedBy(func(status params.WarmImageStatus) bool {
+		return status.Image == "warm-image-id" && status.BaseImage == "ubuntu" &&
+			status.LastError == "" && !status.BuiltAt.IsZero()
+	})).Return(pool, nil).Once()
+	store.On("UpdateInstance", mock.Anything, instance.Name, params.UpdateInstanceParams{
+		Status: commonParams.InstancePendingDelete,
+	}).Return(prepared, nil).Once()
+
+	provider := &snapshotProvider{image: "warm-image-id"}
+	r := &basePoolManager{
+		ctx:              context.Background(),
+		entity:           entity,
+		store:            store,
+		keyMux:           &keyMutex{},
+		managerIsRunning: true,
+		providers: map[string]common.Provider{
+			pool.ProviderName: provider,
+		},
+	}
+
+	require.NoError(t, r.buildWarmImages())
+	require.Equal(t, "provider-id", provider.instance)
+	require.Equal(t, pool.WarmImage.PrepareScript, provider.params.CreateSnapshotV011.PrepareScript)
+	require.Contains(t, provider.params.CreateSnapshotV011.SnapshotName, "garm-warm-pool-1-")
+}
+
+func TestBuildWarmImageFailure(t *testing.T) {
+	entity := params.GithubEntity{
+		ID:         "test-repo-id",
+		EntityType: params.GithubEntityTypeRepository,
+	}
+	pool := params.Pool{
+		ID:           "pool-1",
+		ProviderName: "openstack",
+		Image:        "ubuntu",
+		WarmImage:    &params.WarmImageSettings{PrepareScript: "#!/bin/sh\nexit 1"},
+		WarmImageStatus: &params.WarmImageStatus{
+			Image:     "previous-warm-image",
+			BaseImage: "ubuntu",
+		},
+	}
+	instance := params.Instance{
+		Name:         "garm-runner",
+		PoolID:       pool.ID,
+		Status:       commonParams.InstanceRunning,
+		RunnerStatus: params.RunnerIdle,
+	}
+
+	store := dbMocks.NewStore(t)
+	store.On("ListPoolInstances", mock.Anything, pool.ID).Return([]params.Instance{instance}, nil)
+	store.On("UpdateInstance", mock.Anything, instance.Name, mock.Anything).Return(instance, nil).Twice()
+	store.On("AddInstanceEvent", mock.Anything, instance.Name, params.ProviderOperationEvent, mock.Anything, mock.Anything, common.MaxInstanceEvents).Return(nil).Twice()
+	store.On("AddEntityEvent", mock.Anything, entity, params.WarmImageEvent, params.EventWarning, mock.Anything, common.MaxEntityEvents).Return(nil).Once()
+	// The previous warm image is kept.
+	store.On("UpdatePoolWarmImageStatus", mock.Anything, pool.ID, mock.MatchedBy(func(status params.WarmImageStatus) bool {
+		return status.Image == "previous-warm-image" && status.LastError == "snapshot failed"
+	})).Return(pool, nil).Once()
+
+	r := &basePoolManager{
+		ctx:              context.Background(),
+		entity:           entity,
+		store:            store,
+		keyMux:           &keyMutex{},
+		managerIsRunning: true,
+		providers: map[string]common.Provider{
+			pool.ProviderName: &snapshotProvider{err: errors.New("snapshot failed")},
+		},
+	}
+
+	err := r.buildWarmImage(pool)
+	require.ErrorContains(t, err, "snapshot failed")
+	// The runner name is used if the provider ID is not known.
+	require.Equal(t, instance.Name, r.providers[pool.ProviderName].(*snapshotProvider).instance)
+}
+
+func TestPoolRunnerImage(t *testing.T) {
+	pool := params.Pool{Image: "ubuntu"}
+	require.Equal(t, "ubuntu", pool.RunnerImage())
+
+	pool.WarmImage = &params.WarmImageSettings{PrepareScript: "#!/bin/sh"}
+	pool.WarmImageStatus = &params.WarmImageStatus{Image: "warm", BaseImage: "ubuntu"}
+	require.Equal(t, "warm", pool.RunnerImage())
+	require.False(t, pool.IsOutdated(params.Instance{Image: "warm"}))
+	require.True(t, pool.IsOutdated(params.Instance{Image: "ubuntu"}))
+
+	// Warm images built from a previous image of the pool are not used.
+	pool.Image = "ubuntu-24.04"
+	require.Equal(t, "ubuntu-24.04", pool.RunnerImage())
+}
@@ -0,0 +1,192 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+// loopState holds the liveness information of one of the pool manager loops.
+// A loop is considered stale if it did not report a heartbeat in more than
+// staleAfter. A stale loop may just be slow, so it is only reported. The watchdog
+// only starts a new instance of a loop once the previous one exited unexpectedly,
+// so that two instances of the same loop never run at the same time.
+type loopState struct {
+	f         func() error
+	interval  time.Duration
+	alwaysRun bool
+
+	generation    uint64
+	lastHeartbeat time.Time
+	restarts      uint
+	// exited is set if the loop exited while the pool manager was still running.
+	exited bool
+	// staleReported is set once a stale loop was reported, and cleared by the
+	// next heartbeat.
+	staleReported bool
+}
+
+func (l *loopState) staleAfter() time.Duration {
+	// When the pool manager is not running, loops sleep for BackoffTimer between
+	// iterations instead of waiting for their own interval.
+	interval := l.interval
+	if interval < common.BackoffTimer {
+		interval = common.BackoffTimer
+	}
+	return interval + common.WorkerHeartbeatGracePeriod
+}
+
+func (l *loopState) isHealthy(now time.Time) bool {
+	return now.Sub(l.lastHeartbeat) <= l.staleAfter()
+}
+
+func (r *basePoolManager) registerLoop(name string, f func() error, interval time.Duration, alwaysRun bool) uint64 {
+	r.loopsMux.Lock()
+	defer r.loopsMux.Unlock()
+
+	if r.loops == nil {
+		r.loops = map[string]*loopState{}
+	}
+	state, ok := r.loops[name]
+	if !ok {
+		state = &loopState{}
+		r.loops[name] = state
+	} else {
+		// The pool manager was restarted. Any loop left over from the previous
+		// start must exit.
+		state.generation++
+	}
+	state.f = f
+	state.interval = interval
+	state.alwaysRun = alwaysRun
+	state.lastHeartbeat = time.Now()
+	state.exited = false
+	state.staleReported = false
+	return state.generation
+}
+
+// loopHeartbeat records a heartbeat for the loop. It returns false if the loop was
+// replaced, by a restart of the pool manager, and the caller should exit.
+func (r *basePoolManager) loopHeartbeat(name string, generation uint64) bool {
+	r.loopsMux.Lock()
+	defer r.loopsMux.Unlock()
+
+	state, ok := r.loops[name]
+	if !ok || state.generation != generation {
+		return false
+	}
+	state.lastHeartbeat = time.Now()
+	state.staleReported = false
+	return true
+}
+
+// loopExited records that the loop exited unexpectedly, so that the watchdog
+// starts a new instance of it.
+func (r *basePoolManager) loopExited(name string, generation uint64) {
+	r.loopsMux.Lock()
+	defer r.loopsMux.Unlock()
+
+	state, ok := r.loops[name]
+	if !ok || state.generation != generation {
+		return
+	}
+	state.exited = true
+}
+
+// runLoopFunction runs one iteration of a loop. A panic is converted to an error,
+// so that it does not bring down GARM or silently stop the loop.
+func (r *basePoolManager) runLoopFunction(name string, f func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(
+				r.ctx, "recovered from panic in loop",
+				"loop_name", name,
+				"panic", rec,
+				"stack", string(debug.Stack()))
+			r.loopsMux.Lock()
+			if state, ok := r.loops[name]; ok {
+				state.restarts++
+			}
+			r.loopsMux.Unlock()
+			metrics.WorkerRestarts.WithLabelValues(r.entity.ID, name, "panic").Inc()
+			err = fmt.Errorf("loop %s panicked: %v", name, rec)
+		}
+	}()
+	return f()
+}
+
+func (r *basePoolManager) runWatchdog() {
+	ticker := time.NewTicker(common.WatchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.restartWedgedLoops()
+			r.reportStaleInstanceLocks()
+		case <-r.ctx.Done():
+			return
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// restartWedgedLoops starts a new instance of every loop that exited unexpectedly.
+// Loops that stopped sending heartbeats are only reported. They may be stuck, but
+// they may also be busy creating runners or downloading tools, and starting a
+// second instance next to them could do the same work twice.
+func (r *basePoolManager) restartWedgedLoops() {
+	r.loopsMux.Lock()
+	defer r.loopsMux.Unlock()
+
+	now := time.Now()
+	for name, state := range r.loops {
+		if state.exited {
+			slog.WarnContext(
+				r.ctx, "loop exited unexpectedly, restarting",
+				"loop_name", name)
+			state.generation++
+			state.restarts++
+			state.exited = false
+			state.lastHeartbeat = now
+			metrics.WorkerRestarts.WithLabelValues(r.entity.ID, name, "exited").Inc()
+			go r.runLoop(name, state.f, state.interval, state.alwaysRun, state.generation)
+			continue
+		}
+		if !state.isHealthy(now) && !state.staleReported {
+			slog.WarnContext(
+				r.ctx, "loop did not report a heartbeat",
+				"loop_name", name,
+				"last_heartbeat", state.lastHeartbeat)
+			state.staleReported = true
+		}
+	}
+}
+
+func (r *basePoolManager) WorkerHealth() []params.WorkerHealth {
+	r.loopsMux.Lock()
+	defer r.loopsMux.Unlock()
+
+	now := time.Now()
+	ret := make([]params.WorkerHealth, 0, len(r.loops))
+	for name, state := range r.loops {
+		ret = append(ret, params.WorkerHealth{
+			Name:          name,
+			EntityID:      r.entity.ID,
+			LastHeartbeat: state.lastHeartbeat,
+			Restarts:      state.restarts,
+			Healthy:       state.isHealthy(now),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/common"
+)
+
+func newWatchdogTestManager() *basePoolManager {
+	return &basePoolManager{
+		ctx:    context.Background(),
+		entity: params.GithubEntity{ID: "test-repo-id"},
+		quit:   make(chan struct{}),
+		wg:     &sync.WaitGroup{},
+	}
+}
+
+func TestRunLoopFunctionRecoversFromPanic(t *testing.T) {
+	r := newWatchdogTestManager()
+	r.registerLoop("panics", nil, time.Minute, true)
+
+	err := r.runLoopFunction("panics", func() error {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	health := r.WorkerHealth()
+	if len(health) != 1 || health[0].Restarts != 1 {
+		t.Fatalf("expected one restart, got %+v", health)
+	}
+}
+
+func TestRestartWedgedLoopsOnlyReportsStaleLoops(t *testing.T) {
+	r := newWatchdogTestManager()
+	defer close(r.quit)
+
+	generation := r.registerLoop("slow", func() error { return nil }, 10*time.Millisecond, true)
+
+	r.loopsMux.Lock()
+	r.loops["slow"].lastHeartbeat = time.Now().Add(-1 * (common.BackoffTimer + common.WorkerHeartbeatGracePeriod + time.Minute))
+	r.loopsMux.Unlock()
+
+	health := r.WorkerHealth()
+	if len(health) != 1 || health[0].Healthy {
+		t.Fatalf("expected unhealthy worker, got %+v", health)
+	}
+
+	// The loop may just be slow. A second instance must not be started next to it.
+	r.restartWedgedLoops()
+
+	health = r.WorkerHealth()
+	if len(health) != 1 || health[0].Healthy || health[0].Restarts != 0 {
+		t.Fatalf("expected unhealthy worker without restarts, got %+v", health)
+	}
+	if !r.loopHeartbeat("slow", generation) {
+		t.Fatalf("expected the slow loop to keep running")
+	}
+	health = r.WorkerHealth()
+	if len(health) != 1 || !health[0].Healthy {
+		t.Fatalf("expected healthy worker after heartbeat, got %+v", health)
+	}
+}
+
+func TestRestartWedgedLoopsRestartsExitedLoops(t *testing.T) {
+	r := newWatchdogTestManager()
+	defer close(r.quit)
+
+	ran := make(chan struct{}, 1)
+	generation := r.registerLoop("exited", func() error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}, 10*time.Millisecond, true)
+
+	r.loopExited("exited", generation)
+	r.restartWedgedLoops()
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("restarted loop did not run")
+	}
+
+	health := r.WorkerHealth()
+	if len(health) != 1 || !health[0].Healthy || health[0].Restarts != 1 {
+		t.Fatalf("expected healthy worker with one restart, got %+v", health)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/cloudbase/garm/database/common"
 	"github.com/cloudbase/garm/params"
 	runnerCommon "github.com/cloudbase/garm/runner/common"
+	garmTools "github.com/cloudbase/garm/runner/tools"
 	garmUtil "github.com/cloudbase/garm/util"
 )
 
@@ -63,9 +64,7 @@ func (r *basePoolManager) handleEntityUpdate(entity params.GithubEntity, operati
 			return
 		}
 		slog.DebugContext(r.ctx, "updating tools", "entity", entity.ID)
-		if err := r.updateTools(); err != nil {
-			slog.ErrorContext(r.ctx, "failed to update tools", "error", err)
-		}
+		r.refreshTools()
 	}()
 
 	slog.DebugContext(r.ctx, "updating entity", "entity", entity.ID)
@@ -103,9 +102,7 @@ func (r *basePoolManager) handleCredentialsUpdate(credentials params.GithubCrede
 			return
 		}
 		slog.DebugContext(r.ctx, "deferred tools update", "credentials_id", credentials.ID)
-		if err := r.updateTools(); err != nil {
-			slog.ErrorContext(r.ctx, "failed to update tools", "error", err)
-		}
+		r.refreshTools()
 	}()
 
 	r.mux.Lock()
@@ -121,6 +118,54 @@ func (r *basePoolManager) handleCredentialsUpdate(credentials params.GithubCrede
 	r.mux.Unlock()
 }
 
+func (r *basePoolManager) handleEndpointUpdate(endpoint params.GithubEndpoint) {
+	r.mux.Lock()
+	if r.entity.Credentials.Endpoint.Name != endpoint.Name {
+		slog.InfoContext(r.ctx, "endpoint name mismatch; stale event?", "endpoint", endpoint.Name)
+		r.mux.Unlock()
+		return
+	}
+	slog.DebugContext(r.ctx, "updating endpoint", "endpoint", endpoint.Name)
+	r.entity.Credentials.Endpoint = endpoint
+	r.mux.Unlock()
+
+	// The CA bundle or the tools metadata may have changed. Make sure we don't
+	// serve stale tools.
+	garmTools.Invalidate(endpoint.Name)
+	r.refreshTools()
+}
+
+// refreshTools updates the tools in the background. Fetching the tools may involve
+// downloading and verifying the runner archives, which must not hold up the watcher.
+// Refreshes requested while one is running are coalesced into one more run.
+func (r *basePoolManager) refreshTools() {
+	r.toolsRefreshMux.Lock()
+	defer r.toolsRefreshMux.Unlock()
+
+	if r.toolsRefreshRunning {
+		r.toolsRefreshQueued = true
+		return
+	}
+	r.toolsRefreshRunning = true
+
+	go func() {
+		for {
+			if err := r.updateTools(); err != nil {
+				slog.ErrorContext(r.ctx, "failed to update tools", "error", err)
+			}
+
+			r.toolsRefreshMux.Lock()
+			if !r.toolsRefreshQueued {
+				r.toolsRefreshRunning = false
+				r.toolsRefreshMux.Unlock()
+				return
+			}
+			r.toolsRefreshQueued = false
+			r.toolsRefreshMux.Unlock()
+		}
+	}()
+}
+
 func (r *basePoolManager) handleWatcherEvent(event common.ChangePayload) {
 	dbEntityType := common.DatabaseEntityType(r.entity.EntityType)
 	switch event.EntityType {
@@ -131,6 +176,13 @@ func (r *basePoolManager) handleWatcherEvent(event common.ChangePayload) {
 			return
 		}
 		r.handleCredentialsUpdate(credentials)
+	case common.GithubEndpointEntityType:
+		endpoint, ok := event.Payload.(params.GithubEndpoint)
+		if !ok {
+			slog.ErrorContext(r.ctx, "failed to cast payload to github endpoint")
+			return
+		}
+		r.handleEndpointUpdate(endpoint)
 	case common.ControllerEntityType:
 		controllerInfo, ok := event.Payload.(params.ControllerInfo)
 		if !ok {
@@ -138,6 +190,13 @@ func (r *basePoolManager) handleWatcherEvent(event common.ChangePayload) {
 			return
 		}
 		r.handleControllerUpdateEvent(controllerInfo)
+	case common.PoolEntityType:
+		pool, ok := event.Payload.(params.Pool)
+		if !ok {
+			slog.ErrorContext(r.ctx, "failed to cast payload to pool")
+			return
+		}
+		r.schedulePoolReconcile(pool.ID)
 	case dbEntityType:
 		entity, ok := event.Payload.(entityGetter)
 		if !ok {
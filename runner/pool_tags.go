@@ -0,0 +1,262 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// BulkUpdatePoolTags adds, removes or renames a tag across the pools of an entity. If
+// entityID is empty, the operation applies to all pools of the controller. The changes
+// are applied in a single transaction. The result lists the pools that change, and the
+// queued jobs that would start or stop matching those pools.
+func (r *Runner) BulkUpdatePoolTags(ctx context.Context, entityID string, param params.BulkUpdatePoolTagsParams) (params.BulkUpdatePoolTagsResult, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.BulkUpdatePoolTagsResult{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "validating params")
+	}
+
+	var pools []params.Pool
+	var jobs []params.Job
+	if entityID == "" {
+		// Adding a tag to every pool of the controller is almost certainly a mistake.
+		if param.Operation == params.PoolTagOperationAdd && len(param.Filter) == 0 {
+			return params.BulkUpdatePoolTagsResult{}, runnerErrors.NewBadRequestError("a filter is required when adding a tag to all pools")
+		}
+
+		var err error
+		pools, err = r.store.ListAllPools(ctx)
+		if err != nil {
+			return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "fetching pools")
+		}
+		jobs, err = r.store.ListJobsByStatus(ctx, params.JobStatusQueued)
+		if err != nil {
+			return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "fetching jobs")
+		}
+	} else {
+		entity, err := r.getGithubEntityByID(ctx, entityID)
+		if err != nil {
+			return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "fetching entity")
+		}
+		pools, err = r.store.ListEntityPools(ctx, entity)
+		if err != nil {
+			return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "fetching pools")
+		}
+		jobs, err = r.store.ListEntityJobsByStatus(ctx, entity.EntityType, entity.ID, params.JobStatusQueued)
+		if err != nil {
+			return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "fetching jobs")
+		}
+	}
+
+	result := params.BulkUpdatePoolTagsResult{
+		DryRun: param.DryRun,
+		Pools:  []params.PoolTagsChange{},
+		Jobs:   []params.JobMatchChange{},
+	}
+	poolTags := map[string][]string{}
+	for _, pool := range pools {
+		if !hasAllTags(pool.Tags, param.Filter) {
+			continue
+		}
+		oldTags := tagNames(pool.Tags)
+		newTags, changed := applyPoolTagOperation(oldTags, param)
+		if !changed {
+			continue
+		}
+		if len(newTags) == 0 {
+			return params.BulkUpdatePoolTagsResult{}, runnerErrors.NewBadRequestError("operation would leave pool %s without tags", pool.ID)
+		}
+
+		poolTags[pool.ID] = newTags
+		result.Pools = append(result.Pools, params.PoolTagsChange{
+			PoolID:  pool.ID,
+			OldTags: oldTags,
+			NewTags: newTags,
+		})
+
+		updated := pool
+		updated.Tags = make([]params.Tag, len(newTags))
+		for idx, tag := range newTags {
+			updated.Tags[idx] = params.Tag{Name: tag}
+		}
+		for _, job := range jobs {
+			if !jobBelongsToPoolEntity(job, pool) {
+				continue
+			}
+			matchedBefore := pool.HasRequiredLabels(job.Labels)
+			matchesAfter := updated.HasRequiredLabels(job.Labels)
+			if matchedBefore == matchesAfter {
+				continue
+			}
+			result.Jobs = append(result.Jobs, params.JobMatchChange{
+				JobID:         job.ID,
+				Name:          job.Name,
+				Labels:        job.Labels,
+				PoolID:        pool.ID,
+				MatchedBefore: matchedBefore,
+				MatchesAfter:  matchesAfter,
+			})
+		}
+	}
+
+	if param.DryRun || len(poolTags) == 0 {
+		return result, nil
+	}
+
+	if _, err := r.store.ReplacePoolsTags(ctx, poolTags); err != nil {
+		return params.BulkUpdatePoolTagsResult{}, errors.Wrap(err, "updating pool tags")
+	}
+	return result, nil
+}
+
+// getGithubEntityByID returns the repository, organization or enterprise with the given ID.
+func (r *Runner) getGithubEntityByID(ctx context.Context, entityID string) (params.GithubEntity, error) {
+	repo, err := r.store.GetRepositoryByID(ctx, entityID)
+	if err == nil {
+		return repo.GetEntity()
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return params.GithubEntity{}, errors.Wrap(err, "fetching repository")
+	}
+
+	org, err := r.store.GetOrganizationByID(ctx, entityID)
+	if err == nil {
+		return org.GetEntity()
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return params.GithubEntity{}, errors.Wrap(err, "fetching organization")
+	}
+
+	enterprise, err := r.store.GetEnterpriseByID(ctx, entityID)
+	if err == nil {
+		return enterprise.GetEntity()
+	}
+	if !errors.Is(err, runnerErrors.ErrNotFound) {
+		return params.GithubEntity{}, errors.Wrap(err, "fetching enterprise")
+	}
+	return params.GithubEntity{}, runnerErrors.NewNotFoundError("entity %s not found", entityID)
+}
+
+// applyPoolTagOperation returns the tags that result from applying the operation to
+// the given tags, and whether they changed. Tags are compared case-insensitively.
+func applyPoolTagOperation(tags []string, param params.BulkUpdatePoolTagsParams) ([]string, bool) {
+	idx := -1
+	for i, tag := range tags {
+		if strings.EqualFold(tag, param.Tag) {
+			idx = i
+			break
+		}
+	}
+
+	switch param.Operation {
+	case params.PoolTagOperationAdd:
+		if idx != -1 {
+			return tags, false
+		}
+		return append(append([]string{}, tags...), param.Tag), true
+	case params.PoolTagOperationRemove:
+		if idx == -1 {
+			return tags, false
+		}
+		ret := append([]string{}, tags[:idx]...)
+		return append(ret, tags[idx+1:]...), true
+	case params.PoolTagOperationRename:
+		if idx == -1 {
+			return tags, false
+		}
+		ret := make([]string, 0, len(tags))
+		for i, tag := range tags {
+			if i != idx && strings.EqualFold(tag, param.NewTag) {
+				// The pool already has the new tag.
+				continue
+			}
+			if i == idx {
+				tag = param.NewTag
+			}
+			ret = append(ret, tag)
+		}
+		return ret, true
+	}
+	return tags, false
+}
+
+func tagNames(tags []params.Tag) []string {
+	ret := make([]string, len(tags))
+	for idx, tag := range tags {
+		ret[idx] = tag.Name
+	}
+	return ret
+}
+
+func hasAllTags(tags []params.Tag, filter []string) bool {
+	for _, f := range filter {
+		found := false
+		for _, tag := range tags {
+			if strings.EqualFold(tag.Name, f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func jobBelongsToPoolEntity(job params.Job, pool params.Pool) bool {
+	switch {
+	case pool.RepoID != "":
+		return job.RepoID != nil && job.RepoID.String() == pool.RepoID
+	case pool.OrgID != "":
+		return job.OrgID != nil && job.OrgID.String() == pool.OrgID
+	case pool.EnterpriseID != "":
+		return job.EnterpriseID != nil && job.EnterpriseID.String() == pool.EnterpriseID
+	}
+	return false
+}
+
+// MatchEntityPools returns the pools of an entity that would be tried, in order, to
+// create a runner for a queued job with the given labels. It can be used to find out
+// why no runner is created for a job.
+func (r *Runner) MatchEntityPools(ctx context.Context, entityID string, labels []string) (params.PoolMatchResult, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.PoolMatchResult{}, runnerErrors.ErrUnauthorized
+	}
+	if len(labels) == 0 {
+		return params.PoolMatchResult{}, runnerErrors.NewBadRequestError("missing labels")
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entityID)
+	if err != nil {
+		return params.PoolMatchResult{}, errors.Wrap(err, "fetching pool manager")
+	}
+
+	result, err := poolMgr.MatchPools(ctx, labels)
+	if err != nil {
+		return params.PoolMatchResult{}, errors.Wrap(err, "matching pools")
+	}
+	return result, nil
+}
@@ -16,6 +16,8 @@ package runner
 
 import (
 	"context"
+	"sort"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -33,11 +35,56 @@ func (r *Runner) ListAllPools(ctx context.Context) ([]params.Pool, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching pools")
 	}
+	if err := r.setPoolsProviderPaused(ctx, pools); err != nil {
+		return nil, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools); err != nil {
+		return nil, err
+	}
 	return pools, nil
 }
 
-func (r *Runner) GetPoolByID(ctx context.Context, poolID string) (params.Pool, error) {
+// ListAllPoolsPage returns one page of all pools.
+func (r *Runner) ListAllPoolsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
 	if !auth.IsAdmin(ctx) {
+		return params.PaginatedResult[params.Pool]{}, runnerErrors.ErrUnauthorized
+	}
+
+	pools, err := r.store.ListAllPoolsPage(ctx, opts)
+	if err != nil {
+		return params.PaginatedResult[params.Pool]{}, errors.Wrap(err, "fetching pools")
+	}
+	if err := r.setPoolsProviderPaused(ctx, pools.Items); err != nil {
+		return params.PaginatedResult[params.Pool]{}, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools.Items); err != nil {
+		return params.PaginatedResult[params.Pool]{}, err
+	}
+	return pools, nil
+}
+
+// ListEntityPoolsPage returns one page of the pools of a repository, organization or
+// enterprise.
+func (r *Runner) ListEntityPoolsPage(ctx context.Context, entity params.GithubEntity, opts params.ListOptions) (params.PaginatedResult[params.Pool], error) {
+	if !auth.CanViewEntity(ctx, entity) {
+		return params.PaginatedResult[params.Pool]{}, runnerErrors.ErrUnauthorized
+	}
+
+	pools, err := r.store.ListEntityPoolsPage(ctx, entity, opts)
+	if err != nil {
+		return params.PaginatedResult[params.Pool]{}, errors.Wrap(err, "fetching pools")
+	}
+	if err := r.setPoolsProviderPaused(ctx, pools.Items); err != nil {
+		return params.PaginatedResult[params.Pool]{}, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools.Items); err != nil {
+		return params.PaginatedResult[params.Pool]{}, err
+	}
+	return pools, nil
+}
+
+func (r *Runner) GetPoolByID(ctx context.Context, poolID string) (params.Pool, error) {
+	if !auth.HasRole(ctx) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -45,11 +92,20 @@ func (r *Runner) GetPoolByID(ctx context.Context, poolID string) (params.Pool, e
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "fetching pool")
 	}
+	if !auth.CanViewPool(ctx, pool) {
+		return params.Pool{}, runnerErrors.ErrUnauthorized
+	}
+
+	pool.ProviderPaused, err = r.isProviderPaused(ctx, pool.ProviderName)
+	if err != nil {
+		return params.Pool{}, err
+	}
+	pool.CapacityWarning = pool.CapacityWarningReached(uint(len(pool.Instances)))
 	return pool, nil
 }
 
 func (r *Runner) DeletePoolByID(ctx context.Context, poolID string) error {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
 		return runnerErrors.ErrUnauthorized
 	}
 
@@ -60,6 +116,9 @@ func (r *Runner) DeletePoolByID(ctx context.Context, poolID string) error {
 		}
 		return nil
 	}
+	if !auth.CanManagePool(ctx, pool) {
+		return runnerErrors.ErrUnauthorized
+	}
 
 	if len(pool.Instances) > 0 {
 		return runnerErrors.NewBadRequestError("pool has runners")
@@ -68,11 +127,13 @@ func (r *Runner) DeletePoolByID(ctx context.Context, poolID string) error {
 	if err := r.store.DeletePoolByID(ctx, poolID); err != nil {
 		return errors.Wrap(err, "deleting pool")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourcePool, poolID, pool, nil)
 	return nil
 }
 
 func (r *Runner) UpdatePoolByID(ctx context.Context, poolID string, param params.UpdatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -80,6 +141,9 @@ func (r *Runner) UpdatePoolByID(ctx context.Context, poolID string, param params
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "fetching pool")
 	}
+	if !auth.CanManagePool(ctx, pool) {
+		return params.Pool{}, runnerErrors.ErrUnauthorized
+	}
 
 	maxRunners := pool.MaxRunners
 	minIdleRunners := pool.MinIdleRunners
@@ -99,15 +163,77 @@ func (r *Runner) UpdatePoolByID(ctx context.Context, poolID string, param params
 		return params.Pool{}, runnerErrors.NewBadRequestError("min_idle_runners cannot be larger than max_runners")
 	}
 
+	schedule := pool.Schedule
+	if param.Schedule != nil {
+		schedule = param.Schedule
+	}
+	if schedule != nil {
+		if err := schedule.Validate(maxRunners); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid schedule: %s", err)
+		}
+	}
+
+	if err := params.ValidateScaleDownSettings(param.IdleDetectionWindow, param.ScaleDownGracePeriod, param.ScaleDownFactor); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCapacityWarningThreshold(param.CapacityWarningThreshold); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCreateJitter(param.CreateJitter); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := r.validateRunnerPrefix(pool.ProviderName, param.Prefix); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.checkPoolUpdateDenyRules(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
+	if param.NetworkSettings != nil {
+		if err := param.NetworkSettings.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid network settings: %s", err)
+		}
+	}
+
+	if param.InstanceMetadata != nil {
+		if err := param.InstanceMetadata.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+		}
+	}
+
+	if err := r.validateWarmImage(pool.ProviderName, param.WarmImage); err != nil {
+		return params.Pool{}, err
+	}
+
 	entity, err := pool.GithubEntity()
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "getting entity")
 	}
 
+	if param.GitHubRunnerGroup != nil && *param.GitHubRunnerGroup != pool.GitHubRunnerGroup {
+		if err := r.validatePoolRunnerGroup(ctx, entity, *param.GitHubRunnerGroup); err != nil {
+			return params.Pool{}, err
+		}
+	}
+
+	if err := r.validateUpdatedPoolWithProvider(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
 	newPool, err := r.store.UpdateEntityPool(ctx, entity, poolID, param)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "updating pool")
 	}
+
+	if param.DryRun {
+		return newPool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourcePool, newPool.ID, pool, newPool)
 	return newPool, nil
 }
 
@@ -122,3 +248,82 @@ func (r *Runner) ListAllJobs(ctx context.Context) ([]params.Job, error) {
 	}
 	return jobs, nil
 }
+
+// ListAllJobsPage returns one page of all jobs.
+func (r *Runner) ListAllJobsPage(ctx context.Context, opts params.ListOptions) (params.PaginatedResult[params.Job], error) {
+	if !auth.IsAdmin(ctx) {
+		return params.PaginatedResult[params.Job]{}, runnerErrors.ErrUnauthorized
+	}
+
+	jobs, err := r.store.ListAllJobsPage(ctx, opts)
+	if err != nil {
+		return params.PaginatedResult[params.Job]{}, errors.Wrap(err, "fetching jobs")
+	}
+	return jobs, nil
+}
+
+// UpdateJobMetadata sets metadata keys on a job, so external systems can correlate
+// the job with their own records.
+func (r *Runner) UpdateJobMetadata(ctx context.Context, jobID int64, param params.UpdateJobMetadataParams) (params.Job, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.Job{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.Job{}, errors.Wrap(err, "validating params")
+	}
+
+	job, err := r.store.GetJobByID(ctx, jobID)
+	if err != nil {
+		return params.Job{}, errors.Wrap(err, "fetching job")
+	}
+
+	newJob, err := r.store.UpdateJobMetadata(ctx, jobID, param)
+	if err != nil {
+		return params.Job{}, errors.Wrap(err, "updating job metadata")
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceJob, strconv.FormatInt(jobID, 10), job, newJob)
+	return newJob, nil
+}
+
+// setPoolsCapacityWarning flags the pools that have reached their capacity warning
+// threshold.
+func (r *Runner) setPoolsCapacityWarning(ctx context.Context, pools []params.Pool) error {
+	instances, err := r.store.ListAllInstances(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching instances")
+	}
+	runners := map[string]uint{}
+	for _, instance := range instances {
+		runners[instance.PoolID]++
+	}
+	for idx := range pools {
+		pools[idx].CapacityWarning = pools[idx].CapacityWarningReached(runners[pools[idx].ID])
+	}
+	return nil
+}
+
+// GetPoolSchema returns the JSON schemas of the parameters used to create and update
+// pools. Clients can use them to validate pool parameters before sending them.
+func (r *Runner) GetPoolSchema(ctx context.Context) (params.PoolSchema, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.PoolSchema{}, runnerErrors.ErrUnauthorized
+	}
+
+	providers := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	create := params.NewJSONSchema("CreatePoolParams", params.CreatePoolParams{})
+	create.SetRequired("provider_name", "max_runners", "image", "flavor", "tags")
+	create.Properties["provider_name"].Enum = providers
+
+	update := params.NewJSONSchema("UpdatePoolParams", params.UpdatePoolParams{})
+	return params.PoolSchema{
+		Create: create,
+		Update: update,
+	}, nil
+}
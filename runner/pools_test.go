@@ -16,8 +16,10 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -29,6 +31,8 @@ import (
 	garmTesting "github.com/cloudbase/garm/internal/testing"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	runnerCommonMocks "github.com/cloudbase/garm/runner/common/mocks"
+	"github.com/cloudbase/garm/util/archive"
 )
 
 type PoolTestFixtures struct {
@@ -156,6 +160,26 @@ func (s *PoolTestSuite) TestGetPoolByID() {
 	s.Require().Equal(s.Fixtures.Pools[0].ID, pool.ID)
 }
 
+func (s *PoolTestSuite) TestPoolCapacityWarning() {
+	// The pools have 4 max runners, and the default threshold is 80%.
+	for i := 0; i < 4; i++ {
+		createParams := s.Fixtures.CreateInstanceParams
+		createParams.Name = fmt.Sprintf("%s-%d", createParams.Name, i)
+		_, err := s.Fixtures.Store.CreateInstance(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, createParams)
+		s.Require().Nil(err)
+
+		pool, err := s.Runner.GetPoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID)
+		s.Require().Nil(err)
+		s.Require().Equal(i == 3, pool.CapacityWarning)
+	}
+
+	pools, err := s.Runner.ListAllPools(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	for _, pool := range pools {
+		s.Require().Equal(pool.ID == s.Fixtures.Pools[0].ID, pool.CapacityWarning)
+	}
+}
+
 func (s *PoolTestSuite) TestGetPoolByIDErrUnauthorized() {
 	_, err := s.Runner.GetPoolByID(context.Background(), "dummy-pool-id")
 
@@ -234,6 +258,109 @@ func (s *PoolTestSuite) TestTestUpdatePoolByIDRunnerBootstrapTimeoutFailed() {
 	s.Require().Equal(runnerErrors.NewBadRequestError("runner_bootstrap_timeout cannot be 0"), err)
 }
 
+func (s *PoolTestSuite) TestTestUpdatePoolByIDInvalidNetworkSettings() {
+	s.Fixtures.UpdatePoolParams.NetworkSettings = &params.NetworkSettings{
+		DNSServers: []string{"dns.example.com"},
+	}
+
+	_, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	s.Require().NotNil(err)
+	s.Require().Regexp("invalid network settings: invalid DNS server", err.Error())
+}
+
+func (s *PoolTestSuite) TestTestUpdatePoolByIDInvalidInstanceMetadata() {
+	s.Fixtures.UpdatePoolParams.InstanceMetadata = &params.InstanceMetadata{
+		"invalid key": "value",
+	}
+
+	_, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	var badRequest *runnerErrors.BadRequestError
+	s.Require().ErrorAs(err, &badRequest)
+	s.Require().Regexp("invalid instance metadata key", err.Error())
+}
+
+func (s *PoolTestSuite) TestTestUpdatePoolByIDWarmImageNotSupported() {
+	provider := runnerCommonMocks.NewProvider(s.T())
+	provider.On("AsParams").Return(params.Provider{
+		Name:         "test-provider",
+		Capabilities: []params.ProviderCapability{params.ProviderCapabilityJITConfig},
+	})
+	s.Runner.providers = map[string]common.Provider{
+		"test-provider": provider,
+	}
+	s.Fixtures.UpdatePoolParams.WarmImage = &params.WarmImageSettings{
+		PrepareScript: "#!/bin/sh",
+	}
+
+	_, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	var badRequest *runnerErrors.BadRequestError
+	s.Require().ErrorAs(err, &badRequest)
+	s.Require().Regexp("provider test-provider does not support snapshots", err.Error())
+}
+
+type poolValidationProvider struct {
+	*runnerCommonMocks.Provider
+	err  error
+	pool params.Pool
+}
+
+func (p *poolValidationProvider) SupportsPoolValidation() bool {
+	return true
+}
+
+func (p *poolValidationProvider) ValidatePool(_ context.Context, validatePoolParams common.ValidatePoolParams) error {
+	p.pool = validatePoolParams.ValidatePoolV011.PoolInfo
+	return p.err
+}
+
+func (s *PoolTestSuite) TestUpdatePoolByIDRejectedByProvider() {
+	_, err := s.Fixtures.Store.InitController()
+	s.Require().Nil(err)
+	provider := &poolValidationProvider{
+		err: runnerErrors.NewBadRequestError("pool rejected by provider test-provider: no such image"),
+	}
+	s.Runner.providers = map[string]common.Provider{
+		"test-provider": provider,
+	}
+
+	_, err = s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	var badRequest *runnerErrors.BadRequestError
+	s.Require().ErrorAs(err, &badRequest)
+	s.Require().Equal(s.Fixtures.UpdatePoolParams.Image, provider.pool.Image)
+	s.Require().Equal(s.Fixtures.UpdatePoolParams.Flavor, provider.pool.Flavor)
+	s.Require().Equal(s.Fixtures.Pools[0].OSType, provider.pool.OSType)
+
+	pool, err := s.Fixtures.Store.GetPoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID)
+	s.Require().Nil(err)
+	s.Require().Equal(s.Fixtures.Pools[0].Image, pool.Image)
+}
+
+func (s *PoolTestSuite) TestUpdatePoolByIDDryRun() {
+	_, err := s.Fixtures.Store.InitController()
+	s.Require().Nil(err)
+	provider := &poolValidationProvider{}
+	s.Runner.providers = map[string]common.Provider{
+		"test-provider": provider,
+	}
+	s.Fixtures.UpdatePoolParams.DryRun = true
+
+	pool, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	s.Require().Nil(err)
+	s.Require().Equal(s.Fixtures.UpdatePoolParams.Image, pool.Image)
+	s.Require().Equal(*s.Fixtures.UpdatePoolParams.MaxRunners, pool.MaxRunners)
+	s.Require().Equal(s.Fixtures.UpdatePoolParams.Image, provider.pool.Image)
+
+	pool, err = s.Fixtures.Store.GetPoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID)
+	s.Require().Nil(err)
+	s.Require().Equal(s.Fixtures.Pools[0].Image, pool.Image)
+	s.Require().Equal(s.Fixtures.Pools[0].MaxRunners, pool.MaxRunners)
+}
+
 func (s *PoolTestSuite) TestTestUpdatePoolByIDMinIdleGreaterThanMax() {
 	var maxRunners uint = 10
 	var minIdleRunners uint = 11
@@ -246,6 +373,154 @@ func (s *PoolTestSuite) TestTestUpdatePoolByIDMinIdleGreaterThanMax() {
 	s.Require().Equal(runnerErrors.NewBadRequestError("min_idle_runners cannot be larger than max_runners"), err)
 }
 
+func (s *PoolTestSuite) TestUpdatePoolInvalidScaleDownFactor() {
+	factor := 1.5
+	s.Fixtures.UpdatePoolParams.ScaleDownFactor = &factor
+
+	_, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	s.Require().NotNil(err)
+	s.Require().Regexp("scale_down_factor must be between 0 and 1", err.Error())
+}
+
+func (s *PoolTestSuite) TestUpdatePoolInvalidCapacityWarningThreshold() {
+	threshold := uint(120)
+	s.Fixtures.UpdatePoolParams.CapacityWarningThreshold = &threshold
+
+	_, err := s.Runner.UpdatePoolByID(s.Fixtures.AdminContext, s.Fixtures.Pools[0].ID, s.Fixtures.UpdatePoolParams)
+
+	s.Require().NotNil(err)
+	s.Require().Regexp("capacity_warning_threshold cannot be larger than 100", err.Error())
+}
+
+func (s *PoolTestSuite) TestGetPoolSchema() {
+	s.Runner.providers = map[string]common.Provider{
+		"lxd":       nil,
+		"openstack": nil,
+	}
+
+	schema, err := s.Runner.GetPoolSchema(s.Fixtures.AdminContext)
+
+	s.Require().Nil(err)
+	s.Require().Equal([]string{"flavor", "image", "max_runners", "provider_name", "tags"}, schema.Create.Required)
+	s.Require().Equal([]string{"lxd", "openstack"}, schema.Create.Properties["provider_name"].Enum)
+	s.Require().Equal("object", schema.Create.Properties["extra_specs"].Type)
+	s.Require().Equal("array", schema.Create.Properties["tags"].Type)
+	s.Require().Equal("string", schema.Create.Properties["tags"].Items.Type)
+	s.Require().Equal([]string{"linux", "windows"}, schema.Create.Properties["os_type"].Enum)
+	// Fields of embedded structs are flattened.
+	s.Require().Contains(schema.Create.Properties, "runner_prefix")
+	s.Require().Empty(schema.Update.Required)
+	s.Require().Equal("integer", schema.Update.Properties["max_runners"].Type)
+	s.Require().Equal("boolean", schema.Update.Properties["enabled"].Type)
+}
+
+func (s *PoolTestSuite) TestGetPoolSchemaErrUnauthorized() {
+	_, err := s.Runner.GetPoolSchema(context.Background())
+
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
+func (s *PoolTestSuite) TestUpdateJobMetadata() {
+	job, err := s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, params.Job{
+		ID:     1,
+		Name:   "build",
+		Status: string(params.JobStatusQueued),
+		Action: string(params.JobStatusQueued),
+	})
+	s.Require().Nil(err)
+
+	_, err = s.Runner.UpdateJobMetadata(s.Fixtures.AdminContext, job.ID, params.UpdateJobMetadataParams{
+		Metadata: map[string]json.RawMessage{
+			"ticket":   json.RawMessage(`"OPS-123"`),
+			"pipeline": json.RawMessage(`{"url": "https://ci.example.com/1"}`),
+		},
+	})
+	s.Require().Nil(err)
+
+	// Keys are merged and keys set to null are removed.
+	job, err = s.Runner.UpdateJobMetadata(s.Fixtures.AdminContext, job.ID, params.UpdateJobMetadataParams{
+		Metadata: map[string]json.RawMessage{
+			"ticket": json.RawMessage(`null`),
+			"deploy": json.RawMessage(`42`),
+		},
+	})
+	s.Require().Nil(err)
+	s.Require().JSONEq(`{"pipeline": {"url": "https://ci.example.com/1"}, "deploy": 42}`, string(job.Metadata))
+
+	// Webhooks for the job don't remove the metadata.
+	job.Status = string(params.JobStatusInProgress)
+	_, err = s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, job)
+	s.Require().Nil(err)
+	jobs, err := s.Runner.ListAllJobs(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(jobs, 1)
+	s.Require().JSONEq(`{"pipeline": {"url": "https://ci.example.com/1"}, "deploy": 42}`, string(jobs[0].Metadata))
+}
+
+func (s *PoolTestSuite) TestUpdateJobMetadataNotFound() {
+	_, err := s.Runner.UpdateJobMetadata(s.Fixtures.AdminContext, 1, params.UpdateJobMetadataParams{
+		Metadata: map[string]json.RawMessage{"ticket": json.RawMessage(`"OPS-123"`)},
+	})
+
+	s.Require().ErrorIs(err, runnerErrors.ErrNotFound)
+}
+
+func (s *PoolTestSuite) TestUpdateJobMetadataErrUnauthorized() {
+	_, err := s.Runner.UpdateJobMetadata(context.Background(), 1, params.UpdateJobMetadataParams{})
+
+	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
+}
+
+type archiveWriterMock struct {
+	archives map[string][]byte
+	err      error
+}
+
+func (a *archiveWriterMock) Write(_ context.Context, name string, data []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.archives[name] = data
+	return nil
+}
+
+func (s *PoolTestSuite) TestArchiveCompletedJobs() {
+	for _, job := range []params.Job{
+		{ID: 1, Name: "build", Status: string(params.JobStatusCompleted), Action: string(params.JobStatusCompleted)},
+		{ID: 2, Name: "test", Status: string(params.JobStatusInProgress), Action: string(params.JobStatusInProgress)},
+	} {
+		_, err := s.Fixtures.Store.CreateOrUpdateJob(s.Fixtures.AdminContext, job)
+		s.Require().Nil(err)
+	}
+	writer := &archiveWriterMock{archives: map[string][]byte{}, err: fmt.Errorf("bucket unavailable")}
+	s.Runner.jobArchive = writer
+
+	// Jobs within the retention are kept.
+	err := s.Runner.archiveCompletedJobs(s.Fixtures.AdminContext, time.Now())
+	s.Require().Nil(err)
+	s.Require().Empty(writer.archives)
+
+	// Jobs are not removed if they could not be archived.
+	now := time.Now().Add(48 * time.Hour)
+	err = s.Runner.archiveCompletedJobs(s.Fixtures.AdminContext, now)
+	s.Require().ErrorContains(err, "bucket unavailable")
+	jobs, err := s.Runner.ListAllJobs(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(jobs, 2)
+
+	writer.err = nil
+	err = s.Runner.archiveCompletedJobs(s.Fixtures.AdminContext, now)
+	s.Require().Nil(err)
+	s.Require().Len(writer.archives, 1)
+	s.Require().Contains(writer.archives, archive.Name(now))
+
+	jobs, err = s.Runner.ListAllJobs(s.Fixtures.AdminContext)
+	s.Require().Nil(err)
+	s.Require().Len(jobs, 1)
+	s.Require().Equal(int64(2), jobs[0].ID)
+}
+
 func TestPoolTestSuite(t *testing.T) {
 	suite.Run(t, new(PoolTestSuite))
 }
@@ -0,0 +1,65 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// GetProviderEnvironment returns the names of the environment variables stored for a
+// provider. The values are never returned.
+func (r *Runner) GetProviderEnvironment(ctx context.Context, providerName string) (params.ProviderEnvironment, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ProviderEnvironment{}, runnerErrors.ErrUnauthorized
+	}
+
+	if _, ok := r.providers[providerName]; !ok {
+		return params.ProviderEnvironment{}, runnerErrors.NewNotFoundError("provider %s not found", providerName)
+	}
+
+	env, err := r.store.GetProviderEnvironment(ctx, providerName)
+	if err != nil {
+		return params.ProviderEnvironment{}, errors.Wrap(err, "fetching provider environment")
+	}
+	return env, nil
+}
+
+// UpdateProviderEnvironment sets or unsets the environment variables passed to a
+// provider. The new values are used the next time the provider binary is executed.
+func (r *Runner) UpdateProviderEnvironment(ctx context.Context, providerName string, param params.UpdateProviderEnvironmentParams) (params.ProviderEnvironment, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.ProviderEnvironment{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.ProviderEnvironment{}, errors.Wrap(err, "validating params")
+	}
+
+	if _, ok := r.providers[providerName]; !ok {
+		return params.ProviderEnvironment{}, runnerErrors.NewNotFoundError("provider %s not found", providerName)
+	}
+
+	env, err := r.store.UpdateProviderEnvironment(ctx, providerName, param)
+	if err != nil {
+		return params.ProviderEnvironment{}, errors.Wrap(err, "updating provider environment")
+	}
+	return env, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// PauseProvider pauses a provider. Pools that use a paused provider stop creating new
+// instances, but existing instances can still be deleted. This is useful during the
+// maintenance window of a cloud, without having to disable every pool that uses it.
+func (r *Runner) PauseProvider(ctx context.Context, providerName string, param params.PauseProviderParams) (params.Provider, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.Provider{}, runnerErrors.ErrUnauthorized
+	}
+
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return params.Provider{}, runnerErrors.NewNotFoundError("provider %s not found", providerName)
+	}
+
+	pause, err := r.store.PauseProvider(ctx, providerName, param.Reason)
+	if err != nil {
+		return params.Provider{}, errors.Wrap(err, "pausing provider")
+	}
+
+	ret := provider.AsParams()
+	setProviderPause(&ret, pause)
+	return ret, nil
+}
+
+// ResumeProvider resumes a paused provider. Pools that use the provider pick up
+// creating instances on their next reconciliation loop.
+func (r *Runner) ResumeProvider(ctx context.Context, providerName string) (params.Provider, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.Provider{}, runnerErrors.ErrUnauthorized
+	}
+
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return params.Provider{}, runnerErrors.NewNotFoundError("provider %s not found", providerName)
+	}
+
+	if err := r.store.ResumeProvider(ctx, providerName); err != nil {
+		return params.Provider{}, errors.Wrap(err, "resuming provider")
+	}
+	return provider.AsParams(), nil
+}
+
+func setProviderPause(provider *params.Provider, pause params.ProviderPause) {
+	pausedAt := pause.PausedAt
+	provider.Paused = true
+	provider.PauseReason = pause.Reason
+	provider.PausedAt = &pausedAt
+}
+
+func (r *Runner) getPausedProviders(ctx context.Context) (map[string]params.ProviderPause, error) {
+	pauses, err := r.store.ListPausedProviders(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching paused providers")
+	}
+
+	ret := make(map[string]params.ProviderPause, len(pauses))
+	for _, pause := range pauses {
+		ret[pause.ProviderName] = pause
+	}
+	return ret, nil
+}
+
+func (r *Runner) isProviderPaused(ctx context.Context, providerName string) (bool, error) {
+	paused, err := r.getPausedProviders(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := paused[providerName]
+	return ok, nil
+}
+
+// setPoolsProviderPaused flags the pools that use a paused provider.
+func (r *Runner) setPoolsProviderPaused(ctx context.Context, pools []params.Pool) error {
+	paused, err := r.getPausedProviders(ctx)
+	if err != nil {
+		return err
+	}
+	for idx := range pools {
+		_, pools[idx].ProviderPaused = paused[pools[idx].ProviderName]
+	}
+	return nil
+}
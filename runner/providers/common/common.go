@@ -1,11 +1,39 @@
 package common
 
 import (
+	"context"
+	"log/slog"
+
 	garmErrors "github.com/cloudbase/garm-provider-common/errors"
 	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/providers/util"
 )
 
+// EnvironmentGetter fetches the environment variables stored in GARM for a provider.
+type EnvironmentGetter interface {
+	GetProviderEnvironment(ctx context.Context, providerName string) (params.ProviderEnvironment, error)
+}
+
+// Environment returns the environment variables passed to the provider binary. The
+// variables stored in GARM are fetched on every call, so that rotated credentials are
+// picked up without restarting GARM. They are appended after the variables from the
+// config file and take precedence over them.
+func Environment(ctx context.Context, getter EnvironmentGetter, providerName string, configured []string) []string {
+	ret := append([]string{}, configured...)
+	if getter == nil {
+		return ret
+	}
+	env, err := getter.GetProviderEnvironment(ctx, providerName)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to fetch provider environment",
+			"provider", providerName)
+		return ret
+	}
+	return append(ret, env.Environ()...)
+}
+
 func ValidateResult(inst commonParams.ProviderInstance) error {
 	if inst.ProviderID == "" {
 		return garmErrors.NewProviderError("missing provider ID")
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	// versionInfoTTL is the amount of time for which the version information of a
+	// provider is cached. Provider binaries may be upgraded without restarting GARM.
+	versionInfoTTL = 10 * time.Minute
+	// versionInfoErrorTTL is the amount of time after which fetching the version
+	// information is retried, if it failed.
+	versionInfoErrorTTL = 1 * time.Minute
+	// versionInfoTimeout limits the time a provider binary has to report its version,
+	// so a binary that hangs doesn't block listing the providers.
+	versionInfoTimeout = 10 * time.Second
+)
+
+// VersionCache caches the version information reported by a provider binary, so the
+// binary is not executed every time the providers are listed. The zero value is
+// ready to use.
+type VersionCache struct {
+	mux  sync.Mutex
+	info *params.ProviderVersionInfo
+}
+
+// Get returns the cached version information, or calls fetch if the cached
+// information expired.
+func (c *VersionCache) Get(ctx context.Context, fetch func(ctx context.Context) (params.ProviderVersionInfo, error)) params.ProviderVersionInfo {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.info != nil {
+		ttl := versionInfoTTL
+		if c.info.Error != "" {
+			ttl = versionInfoErrorTTL
+		}
+		if time.Since(c.info.CheckedAt) < ttl {
+			return *c.info
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, versionInfoTimeout)
+	defer cancel()
+	info, err := fetch(ctx)
+	if err != nil {
+		info.Error = err.Error()
+	}
+	info.CheckedAt = time.Now().UTC()
+	c.info = &info
+	return info
+}
+
+// ParseVersion returns the version printed by the GetVersion command of a provider.
+func ParseVersion(out []byte) (string, error) {
+	version := strings.TrimSpace(string(out))
+	if version == "" {
+		return "", errors.New("provider returned an empty version")
+	}
+	return version, nil
+}
+
+// ParseSupportedInterfaceVersions decodes the JSON list of versions printed by the
+// GetSupportedInterfaceVersions command of a provider.
+func ParseSupportedInterfaceVersions(out []byte) ([]string, error) {
+	var versions []string
+	if err := json.Unmarshal(out, &versions); err != nil {
+		return nil, errors.Wrap(err, "decoding supported interface versions")
+	}
+	return versions, nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestVersionCache(t *testing.T) {
+	var cache VersionCache
+	calls := 0
+	fetch := func(_ context.Context) (params.ProviderVersionInfo, error) {
+		calls++
+		return params.ProviderVersionInfo{Version: "v0.1.3"}, nil
+	}
+
+	info := cache.Get(context.Background(), fetch)
+	require.Equal(t, "v0.1.3", info.Version)
+	require.Empty(t, info.Error)
+	require.False(t, info.CheckedAt.IsZero())
+
+	cache.Get(context.Background(), fetch)
+	require.Equal(t, 1, calls)
+
+	// Expired information is fetched again.
+	cache.info.CheckedAt = time.Now().Add(-versionInfoTTL)
+	cache.Get(context.Background(), fetch)
+	require.Equal(t, 2, calls)
+}
+
+func TestVersionCacheRetriesErrorsSooner(t *testing.T) {
+	var cache VersionCache
+	info := cache.Get(context.Background(), func(_ context.Context) (params.ProviderVersionInfo, error) {
+		return params.ProviderVersionInfo{}, errors.New("unknown command")
+	})
+	require.Equal(t, "unknown command", info.Error)
+
+	cache.info.CheckedAt = time.Now().Add(-versionInfoErrorTTL)
+	info = cache.Get(context.Background(), func(_ context.Context) (params.ProviderVersionInfo, error) {
+		return params.ProviderVersionInfo{Version: "v0.1.3"}, nil
+	})
+	require.Equal(t, "v0.1.3", info.Version)
+	require.Empty(t, info.Error)
+}
+
+func TestParseSupportedInterfaceVersions(t *testing.T) {
+	versions, err := ParseSupportedInterfaceVersions([]byte(`["v0.1.0", "v0.1.1"]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"v0.1.0", "v0.1.1"}, versions)
+
+	_, err = ParseSupportedInterfaceVersions([]byte("v0.1.1"))
+	require.Error(t, err)
+
+	_, err = ParseVersion([]byte("\n"))
+	require.Error(t, err)
+}
@@ -23,12 +23,14 @@ import (
 	"github.com/cloudbase/garm/config"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	commonExternal "github.com/cloudbase/garm/runner/providers/common"
 	"github.com/cloudbase/garm/runner/providers/external"
 )
 
 // LoadProvidersFromConfig loads all providers from the config and populates
-// a map with them.
-func LoadProvidersFromConfig(ctx context.Context, cfg config.Config, controllerID string) (map[string]common.Provider, error) {
+// a map with them. The environment variables stored for each provider are fetched
+// using envGetter.
+func LoadProvidersFromConfig(ctx context.Context, cfg config.Config, controllerID string, envGetter commonExternal.EnvironmentGetter) (map[string]common.Provider, error) {
 	providers := make(map[string]common.Provider, len(cfg.Providers))
 	for _, providerCfg := range cfg.Providers {
 		slog.InfoContext(
@@ -37,7 +39,7 @@ func LoadProvidersFromConfig(ctx context.Context, cfg config.Config, controllerI
 		switch providerCfg.ProviderType {
 		case params.ExternalProvider:
 			conf := providerCfg
-			provider, err := external.NewProvider(ctx, &conf, controllerID)
+			provider, err := external.NewProvider(ctx, &conf, controllerID, envGetter)
 			if err != nil {
 				return nil, errors.Wrap(err, "creating provider")
 			}
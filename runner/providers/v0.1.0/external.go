@@ -20,10 +20,13 @@ import (
 	commonExternal "github.com/cloudbase/garm/runner/providers/common"
 )
 
-var _ common.Provider = (*external)(nil)
+var (
+	_ common.Provider        = (*external)(nil)
+	_ common.VersionProvider = (*external)(nil)
+)
 
 // NewProvider creates a legacy external provider.
-func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string) (common.Provider, error) {
+func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string, envGetter commonExternal.EnvironmentGetter) (common.Provider, error) {
 	if cfg.ProviderType != params.ExternalProvider {
 		return nil, garmErrors.NewBadRequestError("invalid provider config")
 	}
@@ -46,6 +49,7 @@ func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string)
 		cfg:                  cfg,
 		execPath:             execPath,
 		environmentVariables: envVars,
+		envGetter:            envGetter,
 	}, nil
 }
 
@@ -55,6 +59,12 @@ type external struct {
 	cfg                  *config.Provider
 	execPath             string
 	environmentVariables []string
+	envGetter            commonExternal.EnvironmentGetter
+	versionCache         commonExternal.VersionCache
+}
+
+func (e *external) environment(ctx context.Context) []string {
+	return commonExternal.Environment(ctx, e.envGetter, e.cfg.Name, e.environmentVariables)
 }
 
 // CreateInstance creates a new compute instance in the provider.
@@ -65,7 +75,7 @@ func (e *external) CreateInstance(ctx context.Context, bootstrapParams commonPar
 		fmt.Sprintf("GARM_POOL_ID=%s", bootstrapParams.PoolID),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	asJs, err := json.Marshal(bootstrapParams)
 	if err != nil {
@@ -118,7 +128,7 @@ func (e *external) DeleteInstance(ctx context.Context, instance string, _ common
 		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"DeleteInstance", // label: operation
@@ -146,7 +156,7 @@ func (e *external) GetInstance(ctx context.Context, instance string, _ common.Ge
 		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	// nolint:golangci-lint,godox
 	// TODO(gabriel-samfira): handle error types. Of particular interest is to
@@ -192,7 +202,7 @@ func (e *external) ListInstances(ctx context.Context, poolID string, _ common.Li
 		fmt.Sprintf("GARM_POOL_ID=%s", poolID),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"ListInstances", // label: operation
@@ -238,7 +248,7 @@ func (e *external) RemoveAllInstances(ctx context.Context, _ common.RemoveAllIns
 		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"RemoveAllInstances", // label: operation
@@ -264,7 +274,7 @@ func (e *external) Stop(ctx context.Context, instance string, _ common.StopParam
 		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"Stop",     // label: operation
@@ -289,7 +299,7 @@ func (e *external) Start(ctx context.Context, instance string, _ common.StartPar
 		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"Start",    // label: operation
@@ -307,11 +317,54 @@ func (e *external) Start(ctx context.Context, instance string, _ common.StartPar
 	return nil
 }
 
+// GetVersionInfo returns the version of the provider binary. The information is
+// cached. Providers that implement interface version v0.1.0 can't report the
+// interface versions they support.
+func (e *external) GetVersionInfo(ctx context.Context) params.ProviderVersionInfo {
+	return e.versionCache.Get(ctx, e.fetchVersionInfo)
+}
+
+func (e *external) fetchVersionInfo(ctx context.Context) (params.ProviderVersionInfo, error) {
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", commonExecution.GetVersionCommand),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		"GetVersion", // label: operation
+		e.cfg.Name,   // label: provider
+	).Inc()
+	out, err := garmExec.Exec(ctx, e.execPath, nil, asEnv)
+	if err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			"GetVersion", // label: operation
+			e.cfg.Name,   // label: provider
+		).Inc()
+		return params.ProviderVersionInfo{}, garmErrors.NewProviderError("provider binary %s returned error: %s", e.execPath, err)
+	}
+
+	version, err := commonExternal.ParseVersion(out)
+	if err != nil {
+		return params.ProviderVersionInfo{}, err
+	}
+	return params.ProviderVersionInfo{Version: version}, nil
+}
+
 func (e *external) AsParams() params.Provider {
+	capabilities := []params.ProviderCapability{}
+	if !e.DisableJITConfig() {
+		capabilities = append(capabilities, params.ProviderCapabilityJITConfig)
+	}
 	return params.Provider{
-		Name:         e.cfg.Name,
-		Description:  e.cfg.Description,
-		ProviderType: e.cfg.ProviderType,
+		Name:               e.cfg.Name,
+		Description:        e.cfg.Description,
+		ProviderType:       e.cfg.ProviderType,
+		NameConstraints:    e.cfg.NameConstraints.AsParams(),
+		InterfaceVersion:   common.Version010,
+		Capabilities:       capabilities,
+		PauseWhenUnhealthy: e.cfg.PauseWhenUnhealthy,
 	}
 }
 
@@ -21,9 +21,47 @@ import (
 	commonExternal "github.com/cloudbase/garm/runner/providers/common"
 )
 
-var _ common.Provider = (*external)(nil)
+var (
+	_ common.Provider               = (*external)(nil)
+	_ common.DiagnosticsProvider    = (*external)(nil)
+	_ common.InstanceSweepProvider  = (*external)(nil)
+	_ common.VersionProvider        = (*external)(nil)
+	_ common.SnapshotProvider       = (*external)(nil)
+	_ common.PoolValidationProvider = (*external)(nil)
+)
+
+// GetInstanceDiagnosticsCommand is the command sent to providers that declare support
+// for diagnostics. It is not part of the provider interface of garm-provider-common,
+// which is why providers need to explicitly opt in.
+const GetInstanceDiagnosticsCommand = "GetInstanceDiagnostics"
+
+// ListControllerInstancesCommand is the command sent to providers that declare support
+// for listing all the instances of a controller, regardless of pool. Like diagnostics,
+// providers need to explicitly opt in.
+const ListControllerInstancesCommand = "ListControllerInstances"
+
+// CreateInstanceSnapshotCommand is the command sent to providers that declare support
+// for snapshots. Like diagnostics, providers need to explicitly opt in.
+const CreateInstanceSnapshotCommand = "CreateInstanceSnapshot"
+
+// ValidatePoolCommand is the command sent to providers that declare support for pool
+// validation. Like diagnostics, providers need to explicitly opt in.
+const ValidatePoolCommand = "ValidatePool"
+
+// snapshotResult is the output expected from the CreateInstanceSnapshot command.
+type snapshotResult struct {
+	Image string `json:"image"`
+}
+
+// validatePoolInput is the input sent to providers by the ValidatePool command.
+type validatePoolInput struct {
+	Image  string              `json:"image"`
+	Flavor string              `json:"flavor"`
+	OSType commonParams.OSType `json:"os_type"`
+	OSArch commonParams.OSArch `json:"os_arch"`
+}
 
-func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string) (common.Provider, error) {
+func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string, envGetter commonExternal.EnvironmentGetter) (common.Provider, error) {
 	if cfg.ProviderType != params.ExternalProvider {
 		return nil, garmErrors.NewBadRequestError("invalid provider config")
 	}
@@ -45,6 +83,7 @@ func NewProvider(ctx context.Context, cfg *config.Provider, controllerID string)
 		cfg:                  cfg,
 		execPath:             execPath,
 		environmentVariables: envVars,
+		envGetter:            envGetter,
 	}, nil
 }
 
@@ -54,10 +93,46 @@ type external struct {
 	controllerID         string
 	execPath             string
 	environmentVariables []string
+	envGetter            commonExternal.EnvironmentGetter
+	versionCache         commonExternal.VersionCache
+}
+
+func (e *external) environment(ctx context.Context) []string {
+	return commonExternal.Environment(ctx, e.envGetter, e.cfg.Name, e.environmentVariables)
+}
+
+// cleanupPolicyEnv returns the environment variable used to pass the pool cleanup
+// policy to the provider. No variable is set if the pool has no cleanup policy.
+func cleanupPolicyEnv(pool params.Pool) ([]string, error) {
+	if pool.CleanupPolicy == nil || pool.CleanupPolicy.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(pool.CleanupPolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing cleanup policy")
+	}
+	return []string{
+		fmt.Sprintf("GARM_POOL_CLEANUP_POLICY=%s", base64.StdEncoding.EncodeToString(asJs)),
+	}, nil
+}
+
+// networkSettingsEnv returns the environment variable used to pass the pool network
+// settings to the provider. No variable is set if the pool has no network settings.
+func networkSettingsEnv(pool params.Pool) ([]string, error) {
+	if pool.NetworkSettings == nil || pool.NetworkSettings.IsEmpty() {
+		return nil, nil
+	}
+	asJs, err := json.Marshal(pool.NetworkSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing network settings")
+	}
+	return []string{
+		fmt.Sprintf("GARM_POOL_NETWORK_SETTINGS=%s", base64.StdEncoding.EncodeToString(asJs)),
+	}, nil
 }
 
 // CreateInstance creates a new compute instance in the provider.
-func (e *external) CreateInstance(ctx context.Context, bootstrapParams commonParams.BootstrapInstance, _ common.CreateInstanceParams) (commonParams.ProviderInstance, error) {
+func (e *external) CreateInstance(ctx context.Context, bootstrapParams commonParams.BootstrapInstance, createInstanceParams common.CreateInstanceParams) (commonParams.ProviderInstance, error) {
 	extraspecs := bootstrapParams.ExtraSpecs
 	extraspecsValue, err := json.Marshal(extraspecs)
 	if err != nil {
@@ -72,7 +147,17 @@ func (e *external) CreateInstance(ctx context.Context, bootstrapParams commonPar
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	cleanupEnv, err := cleanupPolicyEnv(createInstanceParams.CreateInstanceV011.PoolInfo)
+	if err != nil {
+		return commonParams.ProviderInstance{}, err
+	}
+	asEnv = append(asEnv, cleanupEnv...)
+	networkEnv, err := networkSettingsEnv(createInstanceParams.CreateInstanceV011.PoolInfo)
+	if err != nil {
+		return commonParams.ProviderInstance{}, err
+	}
+	asEnv = append(asEnv, networkEnv...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	asJs, err := json.Marshal(bootstrapParams)
 	if err != nil {
@@ -134,7 +219,12 @@ func (e *external) DeleteInstance(ctx context.Context, instance string, deleteIn
 		fmt.Sprintf("GARM_POOL_ID=%s", deleteInstanceParams.DeleteInstanceV011.PoolInfo.ID),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	cleanupEnv, err := cleanupPolicyEnv(deleteInstanceParams.DeleteInstanceV011.PoolInfo)
+	if err != nil {
+		return err
+	}
+	asEnv = append(asEnv, cleanupEnv...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"DeleteInstance", // label: operation
@@ -171,7 +261,7 @@ func (e *external) GetInstance(ctx context.Context, instance string, getInstance
 		fmt.Sprintf("GARM_POOL_ID=%s", getInstanceParams.GetInstanceV011.PoolInfo.ID),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	// nolint:golangci-lint,godox
 	// TODO(gabriel-samfira): handle error types. Of particular interest is to
@@ -225,7 +315,7 @@ func (e *external) ListInstances(ctx context.Context, poolID string, listInstanc
 		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"ListInstances", // label: operation
@@ -280,7 +370,7 @@ func (e *external) RemoveAllInstances(ctx context.Context, removeAllInstances co
 		fmt.Sprintf("GARM_POOL_ID=%s", removeAllInstances.RemoveAllInstancesV011.PoolInfo.ID),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"RemoveAllInstances", // label: operation
@@ -315,7 +405,7 @@ func (e *external) Stop(ctx context.Context, instance string, stopParams common.
 		fmt.Sprintf("GARM_POOL_ID=%s", stopParams.StopV011.PoolInfo.ID),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"Stop",     // label: operation
@@ -349,7 +439,7 @@ func (e *external) Start(ctx context.Context, instance string, startParams commo
 		fmt.Sprintf("GARM_POOL_ID=%s", startParams.StartV011.PoolInfo.ID),
 		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
 	}
-	asEnv = append(asEnv, e.environmentVariables...)
+	asEnv = append(asEnv, e.environment(ctx)...)
 
 	metrics.InstanceOperationCount.WithLabelValues(
 		"Start",    // label: operation
@@ -367,11 +457,300 @@ func (e *external) Start(ctx context.Context, instance string, startParams commo
 	return nil
 }
 
+// SupportsDiagnostics returns true if the provider was configured as implementing
+// the GetInstanceDiagnostics command.
+func (e *external) SupportsDiagnostics() bool {
+	if e.cfg == nil {
+		return false
+	}
+	return e.cfg.SupportsDiagnostics
+}
+
+// GetInstanceDiagnostics returns diagnostic information about an instance, as
+// returned by the provider. The output is free form text.
+func (e *external) GetInstanceDiagnostics(ctx context.Context, instance string, getInstanceParams common.GetInstanceParams) (string, error) {
+	if !e.SupportsDiagnostics() {
+		return "", garmErrors.NewBadRequestError("provider %s does not support diagnostics", e.cfg.Name)
+	}
+	extraspecs := getInstanceParams.GetInstanceV011.PoolInfo.ExtraSpecs
+	extraspecsValue, err := json.Marshal(extraspecs)
+	if err != nil {
+		return "", errors.Wrap(err, "serializing extraspecs")
+	}
+	// Encode the extraspecs as base64 to avoid issues with special characters.
+	base64EncodedExtraSpecs := base64.StdEncoding.EncodeToString(extraspecsValue)
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", GetInstanceDiagnosticsCommand),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+		fmt.Sprintf("GARM_POOL_ID=%s", getInstanceParams.GetInstanceV011.PoolInfo.ID),
+		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		"GetInstanceDiagnostics", // label: operation
+		e.cfg.Name,               // label: provider
+	).Inc()
+	out, err := garmExec.Exec(ctx, e.execPath, nil, asEnv)
+	if err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			"GetInstanceDiagnostics", // label: operation
+			e.cfg.Name,               // label: provider
+		).Inc()
+		return "", garmErrors.NewProviderError("provider binary %s returned error: %s", e.execPath, err)
+	}
+	return string(out), nil
+}
+
+// SupportsInstanceSweep returns true if the provider declared that it implements
+// the ListControllerInstances command.
+func (e *external) SupportsInstanceSweep() bool {
+	if e.cfg == nil {
+		return false
+	}
+	return e.cfg.SupportsInstanceSweep
+}
+
+// ListControllerInstances lists all instances tagged with the ID of this controller,
+// across all pools.
+func (e *external) ListControllerInstances(ctx context.Context) ([]commonParams.ProviderInstance, error) {
+	if !e.SupportsInstanceSweep() {
+		return nil, garmErrors.NewBadRequestError("provider %s does not support listing all controller instances", e.cfg.Name)
+	}
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", ListControllerInstancesCommand),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		"ListControllerInstances", // label: operation
+		e.cfg.Name,                // label: provider
+	).Inc()
+
+	out, err := garmExec.Exec(ctx, e.execPath, nil, asEnv)
+	if err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			"ListControllerInstances", // label: operation
+			e.cfg.Name,                // label: provider
+		).Inc()
+		return nil, garmErrors.NewProviderError("provider binary %s returned error: %s", e.execPath, err)
+	}
+
+	var param []commonParams.ProviderInstance
+	if err := json.Unmarshal(out, &param); err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			"ListControllerInstances", // label: operation
+			e.cfg.Name,                // label: provider
+		).Inc()
+		return nil, garmErrors.NewProviderError("failed to decode response from binary: %s", err)
+	}
+
+	for _, inst := range param {
+		if err := commonExternal.ValidateResult(inst); err != nil {
+			metrics.InstanceOperationFailedCount.WithLabelValues(
+				"ListControllerInstances", // label: operation
+				e.cfg.Name,                // label: provider
+			).Inc()
+			return nil, garmErrors.NewProviderError("failed to validate result: %s", err)
+		}
+	}
+	return param, nil
+}
+
+// SupportsSnapshot returns true if the provider declared that it implements the
+// CreateInstanceSnapshot command.
+func (e *external) SupportsSnapshot() bool {
+	if e.cfg == nil {
+		return false
+	}
+	return e.cfg.SupportsSnapshot
+}
+
+// CreateInstanceSnapshot runs the preparation script on an instance and snapshots it
+// into a new image. The script is sent to the provider on standard input.
+func (e *external) CreateInstanceSnapshot(ctx context.Context, instance string, createSnapshotParams common.CreateSnapshotParams) (string, error) {
+	if !e.SupportsSnapshot() {
+		return "", garmErrors.NewBadRequestError("provider %s does not support snapshots", e.cfg.Name)
+	}
+	snapshotParams := createSnapshotParams.CreateSnapshotV011
+	extraspecs := snapshotParams.PoolInfo.ExtraSpecs
+	extraspecsValue, err := json.Marshal(extraspecs)
+	if err != nil {
+		return "", errors.Wrap(err, "serializing extraspecs")
+	}
+	// Encode the extraspecs as base64 to avoid issues with special characters.
+	base64EncodedExtraSpecs := base64.StdEncoding.EncodeToString(extraspecsValue)
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", CreateInstanceSnapshotCommand),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_INSTANCE_ID=%s", instance),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+		fmt.Sprintf("GARM_POOL_ID=%s", snapshotParams.PoolInfo.ID),
+		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
+		fmt.Sprintf("GARM_SNAPSHOT_NAME=%s", snapshotParams.SnapshotName),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		CreateInstanceSnapshotCommand, // label: operation
+		e.cfg.Name,                    // label: provider
+	).Inc()
+	out, err := garmExec.Exec(ctx, e.execPath, []byte(snapshotParams.PrepareScript), asEnv)
+	if err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			CreateInstanceSnapshotCommand, // label: operation
+			e.cfg.Name,                    // label: provider
+		).Inc()
+		return "", garmErrors.NewProviderError("provider binary %s returned error: %s", e.execPath, err)
+	}
+
+	var result snapshotResult
+	if err := json.Unmarshal(out, &result); err != nil || result.Image == "" {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			CreateInstanceSnapshotCommand, // label: operation
+			e.cfg.Name,                    // label: provider
+		).Inc()
+		return "", garmErrors.NewProviderError("failed to decode response from binary: missing image in %q", string(out))
+	}
+	return result.Image, nil
+}
+
+// SupportsPoolValidation returns true if the provider declared that it implements the
+// ValidatePool command.
+func (e *external) SupportsPoolValidation() bool {
+	if e.cfg == nil {
+		return false
+	}
+	return e.cfg.SupportsPoolValidation
+}
+
+// ValidatePool asks the provider to check that it can create runners for a pool. The
+// image, flavor, OS type and OS arch of the pool are sent on standard input. The pool
+// ID is empty for pools that are not created yet.
+func (e *external) ValidatePool(ctx context.Context, validatePoolParams common.ValidatePoolParams) error {
+	if !e.SupportsPoolValidation() {
+		return garmErrors.NewBadRequestError("provider %s does not support pool validation", e.cfg.Name)
+	}
+	pool := validatePoolParams.ValidatePoolV011.PoolInfo
+	extraspecsValue, err := json.Marshal(pool.ExtraSpecs)
+	if err != nil {
+		return errors.Wrap(err, "serializing extraspecs")
+	}
+	// Encode the extraspecs as base64 to avoid issues with special characters.
+	base64EncodedExtraSpecs := base64.StdEncoding.EncodeToString(extraspecsValue)
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", ValidatePoolCommand),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+		fmt.Sprintf("GARM_POOL_ID=%s", pool.ID),
+		fmt.Sprintf("GARM_POOL_EXTRASPECS=%s", base64EncodedExtraSpecs),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	asJs, err := json.Marshal(validatePoolInput{
+		Image:  pool.Image,
+		Flavor: pool.Flavor,
+		OSType: pool.OSType,
+		OSArch: pool.OSArch,
+	})
+	if err != nil {
+		return errors.Wrap(err, "serializing pool")
+	}
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		ValidatePoolCommand, // label: operation
+		e.cfg.Name,          // label: provider
+	).Inc()
+	if _, err := garmExec.Exec(ctx, e.execPath, asJs, asEnv); err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			ValidatePoolCommand, // label: operation
+			e.cfg.Name,          // label: provider
+		).Inc()
+		return garmErrors.NewBadRequestError("pool rejected by provider %s: %s", e.cfg.Name, err)
+	}
+	return nil
+}
+
+// GetVersionInfo returns the version of the provider binary and the interface
+// versions it supports. The information is cached.
+func (e *external) GetVersionInfo(ctx context.Context) params.ProviderVersionInfo {
+	return e.versionCache.Get(ctx, e.fetchVersionInfo)
+}
+
+func (e *external) fetchVersionInfo(ctx context.Context) (params.ProviderVersionInfo, error) {
+	var info params.ProviderVersionInfo
+	out, err := e.execInfoCommand(ctx, commonExecution.GetVersionCommand)
+	if err != nil {
+		return info, err
+	}
+	if info.Version, err = commonExternal.ParseVersion(out); err != nil {
+		return info, err
+	}
+
+	out, err = e.execInfoCommand(ctx, commonExecution.GetSupportedInterfaceVersionsCommand)
+	if err != nil {
+		return info, err
+	}
+	if info.SupportedInterfaceVersions, err = commonExternal.ParseSupportedInterfaceVersions(out); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// execInfoCommand runs a command that returns information about the provider itself,
+// rather than about an instance.
+func (e *external) execInfoCommand(ctx context.Context, command commonExecution.ExecutionCommand) ([]byte, error) {
+	asEnv := []string{
+		fmt.Sprintf("GARM_COMMAND=%s", command),
+		fmt.Sprintf("GARM_CONTROLLER_ID=%s", e.controllerID),
+		fmt.Sprintf("GARM_PROVIDER_CONFIG_FILE=%s", e.cfg.External.ConfigFile),
+	}
+	asEnv = append(asEnv, e.environment(ctx)...)
+
+	metrics.InstanceOperationCount.WithLabelValues(
+		string(command), // label: operation
+		e.cfg.Name,      // label: provider
+	).Inc()
+	out, err := garmExec.Exec(ctx, e.execPath, nil, asEnv)
+	if err != nil {
+		metrics.InstanceOperationFailedCount.WithLabelValues(
+			string(command), // label: operation
+			e.cfg.Name,      // label: provider
+		).Inc()
+		return nil, garmErrors.NewProviderError("provider binary %s returned error: %s", e.execPath, err)
+	}
+	return out, nil
+}
+
 func (e *external) AsParams() params.Provider {
+	capabilities := []params.ProviderCapability{}
+	if !e.DisableJITConfig() {
+		capabilities = append(capabilities, params.ProviderCapabilityJITConfig)
+	}
+	if e.SupportsDiagnostics() {
+		capabilities = append(capabilities, params.ProviderCapabilityDiagnostics)
+	}
+	if e.SupportsInstanceSweep() {
+		capabilities = append(capabilities, params.ProviderCapabilityInstanceSweep)
+	}
+	if e.SupportsSnapshot() {
+		capabilities = append(capabilities, params.ProviderCapabilitySnapshot)
+	}
+	if e.SupportsPoolValidation() {
+		capabilities = append(capabilities, params.ProviderCapabilityPoolValidation)
+	}
 	return params.Provider{
-		Name:         e.cfg.Name,
-		Description:  e.cfg.Description,
-		ProviderType: e.cfg.ProviderType,
+		Name:               e.cfg.Name,
+		Description:        e.cfg.Description,
+		ProviderType:       e.cfg.ProviderType,
+		NameConstraints:    e.cfg.NameConstraints.AsParams(),
+		InterfaceVersion:   common.Version011,
+		Capabilities:       capabilities,
+		PauseWhenUnhealthy: e.cfg.PauseWhenUnhealthy,
 	}
 }
 
@@ -26,6 +26,7 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/util"
 	"github.com/cloudbase/garm/util/appdefaults"
 )
 
@@ -52,6 +53,16 @@ func (r *Runner) CreateRepository(ctx context.Context, param params.CreateRepoPa
 		return params.Repository{}, runnerErrors.NewConflictError("repository %s/%s already exists", param.Owner, param.Name)
 	}
 
+	entity := params.GithubEntity{
+		Owner:       param.Owner,
+		Name:        param.Name,
+		EntityType:  params.GithubEntityTypeRepository,
+		Credentials: creds,
+	}
+	if err := util.ValidateFineGrainedPATAccess(ctx, entity); err != nil {
+		return params.Repository{}, errors.Wrap(err, "validating credentials")
+	}
+
 	repo, err = r.store.CreateRepository(ctx, param.Owner, param.Name, creds.Name, param.WebhookSecret, param.PoolBalancerType)
 	if err != nil {
 		return params.Repository{}, errors.Wrap(err, "creating repository")
@@ -81,11 +92,14 @@ func (r *Runner) CreateRepository(ctx context.Context, param params.CreateRepoPa
 		}
 		return params.Repository{}, errors.Wrap(err, "starting repo pool manager")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourceRepository, repo.ID, nil, repo)
 	return repo, nil
 }
 
+// ListRepositories returns the repositories the user in the context can view.
 func (r *Runner) ListRepositories(ctx context.Context) ([]params.Repository, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 
@@ -97,6 +111,9 @@ func (r *Runner) ListRepositories(ctx context.Context) ([]params.Repository, err
 	var allRepos []params.Repository
 
 	for _, repo := range repos {
+		if !auth.CanViewEntity(ctx, params.GithubEntity{ID: repo.ID, EntityType: params.GithubEntityTypeRepository}) {
+			continue
+		}
 		poolMgr, err := r.poolManagerCtrl.GetRepoPoolManager(repo)
 		if err != nil {
 			repo.PoolManagerStatus.IsRunning = false
@@ -111,7 +128,7 @@ func (r *Runner) ListRepositories(ctx context.Context) ([]params.Repository, err
 }
 
 func (r *Runner) GetRepositoryByID(ctx context.Context, repoID string) (params.Repository, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return params.Repository{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -180,6 +197,8 @@ func (r *Runner) DeleteRepository(ctx context.Context, repoID string, keepWebhoo
 	if err := r.store.DeleteRepository(ctx, repoID); err != nil {
 		return errors.Wrap(err, "removing repository")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourceRepository, repoID, repo, nil)
 	return nil
 }
 
@@ -191,12 +210,37 @@ func (r *Runner) UpdateRepository(ctx context.Context, repoID string, param para
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
+	if err := param.Validate(); err != nil {
+		return params.Repository{}, errors.Wrap(err, "validating params")
+	}
+
 	switch param.PoolBalancerType {
 	case params.PoolBalancerTypeRoundRobin, params.PoolBalancerTypePack, params.PoolBalancerTypeNone:
 	default:
 		return params.Repository{}, runnerErrors.NewBadRequestError("invalid pool balancer type: %s", param.PoolBalancerType)
 	}
 
+	if param.RoutingRules != nil {
+		if err := r.validateRoutingRules(*param.RoutingRules); err != nil {
+			return params.Repository{}, errors.Wrap(err, "validating routing rules")
+		}
+	}
+
+	before, err := r.store.GetRepositoryByID(ctx, repoID)
+	if err != nil {
+		return params.Repository{}, errors.Wrap(err, "fetching repo")
+	}
+
+	if param.CredentialsName != "" {
+		entity, err := before.GetEntity()
+		if err != nil {
+			return params.Repository{}, errors.Wrap(err, "getting entity")
+		}
+		if err := r.validateEntityCredentials(ctx, entity, param.CredentialsName); err != nil {
+			return params.Repository{}, errors.Wrap(err, "updating repo")
+		}
+	}
+
 	slog.InfoContext(ctx, "updating repository", "repo_id", repoID, "param", param)
 	repo, err := r.store.UpdateRepository(ctx, repoID, param)
 	if err != nil {
@@ -209,11 +253,13 @@ func (r *Runner) UpdateRepository(ctx context.Context, repoID string, param para
 	}
 
 	repo.PoolManagerStatus = poolMgr.Status()
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourceRepository, repo.ID, before, repo)
 	return repo, nil
 }
 
 func (r *Runner) CreateRepoPool(ctx context.Context, repoID string, param params.CreatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -222,6 +268,10 @@ func (r *Runner) CreateRepoPool(ctx context.Context, repoID string, param params
 		return params.Pool{}, errors.Wrap(err, "appending tags to create pool params")
 	}
 
+	if err := r.checkDenyRules(ctx, createPoolParams.ProviderName, createPoolParams.Image, createPoolParams.Flavor); err != nil {
+		return params.Pool{}, err
+	}
+
 	if createPoolParams.RunnerBootstrapTimeout == 0 {
 		createPoolParams.RunnerBootstrapTimeout = appdefaults.DefaultRunnerBootstrapTimeout
 	}
@@ -231,16 +281,29 @@ func (r *Runner) CreateRepoPool(ctx context.Context, repoID string, param params
 		EntityType: params.GithubEntityTypeRepository,
 	}
 
+	if err := r.validatePoolRunnerGroup(ctx, entity, createPoolParams.GitHubRunnerGroup); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateNewPoolWithProvider(ctx, createPoolParams); err != nil {
+		return params.Pool{}, err
+	}
+
 	pool, err := r.store.CreateEntityPool(ctx, entity, createPoolParams)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "creating pool")
 	}
 
+	if createPoolParams.DryRun {
+		return pool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceCreated, params.AuditResourcePool, pool.ID, nil, pool)
 	return pool, nil
 }
 
 func (r *Runner) GetRepoPoolByID(ctx context.Context, repoID, poolID string) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -254,11 +317,16 @@ func (r *Runner) GetRepoPoolByID(ctx context.Context, repoID, poolID string) (pa
 		return params.Pool{}, errors.Wrap(err, "fetching pool")
 	}
 
+	pool.ProviderPaused, err = r.isProviderPaused(ctx, pool.ProviderName)
+	if err != nil {
+		return params.Pool{}, err
+	}
+	pool.CapacityWarning = pool.CapacityWarningReached(uint(len(pool.Instances)))
 	return pool, nil
 }
 
 func (r *Runner) DeleteRepoPool(ctx context.Context, repoID, poolID string) error {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return runnerErrors.ErrUnauthorized
 	}
 
@@ -284,11 +352,13 @@ func (r *Runner) DeleteRepoPool(ctx context.Context, repoID, poolID string) erro
 	if err := r.store.DeleteEntityPool(ctx, entity, poolID); err != nil {
 		return errors.Wrap(err, "deleting pool")
 	}
+
+	r.recordMutation(ctx, params.AuditActionResourceDeleted, params.AuditResourcePool, poolID, pool, nil)
 	return nil
 }
 
 func (r *Runner) ListRepoPools(ctx context.Context, repoID string) ([]params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return []params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 	entity := params.GithubEntity{
@@ -299,11 +369,25 @@ func (r *Runner) ListRepoPools(ctx context.Context, repoID string) ([]params.Poo
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching pools")
 	}
+	if err := r.setPoolsProviderPaused(ctx, pools); err != nil {
+		return nil, err
+	}
+	if err := r.setPoolsCapacityWarning(ctx, pools); err != nil {
+		return nil, err
+	}
 	return pools, nil
 }
 
 func (r *Runner) ListPoolInstances(ctx context.Context, poolID string) ([]params.Instance, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.HasRole(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	pool, err := r.store.GetPoolByID(ctx, poolID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool")
+	}
+	if !auth.CanViewPool(ctx, pool) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 
@@ -315,7 +399,7 @@ func (r *Runner) ListPoolInstances(ctx context.Context, poolID string) ([]params
 }
 
 func (r *Runner) UpdateRepoPool(ctx context.Context, repoID, poolID string, param params.UpdatePoolParams) (params.Pool, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanManageEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return params.Pool{}, runnerErrors.ErrUnauthorized
 	}
 
@@ -342,15 +426,77 @@ func (r *Runner) UpdateRepoPool(ctx context.Context, repoID, poolID string, para
 		return params.Pool{}, runnerErrors.NewBadRequestError("min_idle_runners cannot be larger than max_runners")
 	}
 
+	schedule := pool.Schedule
+	if param.Schedule != nil {
+		schedule = param.Schedule
+	}
+	if schedule != nil {
+		if err := schedule.Validate(maxRunners); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid schedule: %s", err)
+		}
+	}
+
+	if err := params.ValidateScaleDownSettings(param.IdleDetectionWindow, param.ScaleDownGracePeriod, param.ScaleDownFactor); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCapacityWarningThreshold(param.CapacityWarningThreshold); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := params.ValidateCreateJitter(param.CreateJitter); err != nil {
+		return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+	}
+
+	if err := r.validateRunnerPrefix(pool.ProviderName, param.Prefix); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.checkPoolUpdateDenyRules(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
+	if param.GitHubRunnerGroup != nil && *param.GitHubRunnerGroup != pool.GitHubRunnerGroup {
+		if err := r.validatePoolRunnerGroup(ctx, entity, *param.GitHubRunnerGroup); err != nil {
+			return params.Pool{}, err
+		}
+	}
+
+	if param.NetworkSettings != nil {
+		if err := param.NetworkSettings.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("invalid network settings: %s", err)
+		}
+	}
+
+	if param.InstanceMetadata != nil {
+		if err := param.InstanceMetadata.Validate(); err != nil {
+			return params.Pool{}, runnerErrors.NewBadRequestError("%s", err)
+		}
+	}
+
+	if err := r.validateWarmImage(pool.ProviderName, param.WarmImage); err != nil {
+		return params.Pool{}, err
+	}
+
+	if err := r.validateUpdatedPoolWithProvider(ctx, pool, param); err != nil {
+		return params.Pool{}, err
+	}
+
 	newPool, err := r.store.UpdateEntityPool(ctx, entity, poolID, param)
 	if err != nil {
 		return params.Pool{}, errors.Wrap(err, "updating pool")
 	}
+
+	if param.DryRun {
+		return newPool, nil
+	}
+
+	r.recordMutation(ctx, params.AuditActionResourceUpdated, params.AuditResourcePool, newPool.ID, pool, newPool)
 	return newPool, nil
 }
 
 func (r *Runner) ListRepoInstances(ctx context.Context, repoID string) ([]params.Instance, error) {
-	if !auth.IsAdmin(ctx) {
+	if !auth.CanViewEntity(ctx, params.GithubEntity{ID: repoID, EntityType: params.GithubEntityTypeRepository}) {
 		return nil, runnerErrors.ErrUnauthorized
 	}
 	entity := params.GithubEntity{
@@ -402,6 +548,30 @@ func (r *Runner) InstallRepoWebhook(ctx context.Context, repoID string, param pa
 	return info, nil
 }
 
+// SyncRepoJobs records the jobs queued in GitHub for the repository that GARM has
+// no record of. These are jobs that were queued while GARM was not receiving webhooks.
+func (r *Runner) SyncRepoJobs(ctx context.Context, repoID string) ([]params.Job, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	repo, err := r.store.GetRepositoryByID(ctx, repoID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching repo")
+	}
+
+	poolManager, err := r.poolManagerCtrl.GetRepoPoolManager(repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool manager for repo")
+	}
+
+	jobs, err := poolManager.SyncQueuedJobs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "syncing queued jobs")
+	}
+	return jobs, nil
+}
+
 func (r *Runner) UninstallRepoWebhook(ctx context.Context, repoID string) error {
 	if !auth.IsAdmin(ctx) {
 		return runnerErrors.ErrUnauthorized
@@ -16,8 +16,14 @@ package runner
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
@@ -336,6 +342,35 @@ func (s *RepoTestSuite) TestUpdateRepository() {
 	s.Require().Equal(params.PoolBalancerTypeRoundRobin, repo.PoolBalancerType)
 }
 
+func (s *RepoTestSuite) TestUpdateRepositoryRecordsAudit() {
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("Status").Return(params.PoolManagerStatus{IsRunning: true}, nil)
+
+	updateRepoParams := s.Fixtures.UpdateRepoParams
+	updateRepoParams.PoolBalancerType = params.PoolBalancerTypePack
+	repo, err := s.Runner.UpdateRepository(s.Fixtures.AdminContext, s.Fixtures.StoreRepos["test-repo-1"].ID, updateRepoParams)
+	s.Require().Nil(err)
+
+	page, err := s.Runner.ListAuditRecords(s.Fixtures.AdminContext, params.ListAuditRecordsParams{
+		ResourceType: params.AuditResourceRepository,
+		Page:         1,
+		PageSize:     10,
+	})
+	s.Require().Nil(err)
+	s.Require().Len(page.Records, 1)
+	record := page.Records[0]
+	s.Require().Equal(params.AuditActionResourceUpdated, record.Action)
+	s.Require().Equal(repo.ID, record.ResourceID)
+	s.Require().Contains(record.Changes, params.AuditChange{
+		Field:  "pool_balancing_type",
+		Before: json.RawMessage(`"roundrobin"`),
+		After:  json.RawMessage(`"pack"`),
+	})
+	for _, change := range record.Changes {
+		s.Require().NotEqual("updated_at", change.Field)
+	}
+}
+
 func (s *RepoTestSuite) TestUpdateRepositoryBalancingType() {
 	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
 	s.Fixtures.PoolMgrMock.On("Status").Return(params.PoolManagerStatus{IsRunning: true}, nil)
@@ -352,6 +387,45 @@ func (s *RepoTestSuite) TestUpdateRepositoryBalancingType() {
 	s.Require().Equal(params.PoolBalancerTypePack, repo.PoolBalancerType)
 }
 
+func (s *RepoTestSuite) TestUpdateRepositoryRoutingRules() {
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("Status").Return(params.PoolManagerStatus{IsRunning: true}, nil)
+
+	rules := []params.RoutingRule{
+		{Type: params.RoutingRuleProviderOrder, Providers: []string{"test-provider"}},
+		{Type: params.RoutingRuleLeastLoaded},
+	}
+	updateRepoParams := s.Fixtures.UpdateRepoParams
+	updateRepoParams.RoutingRules = &rules
+	repo, err := s.Runner.UpdateRepository(s.Fixtures.AdminContext, s.Fixtures.StoreRepos["test-repo-1"].ID, updateRepoParams)
+
+	s.Require().Nil(err)
+	s.Require().Equal(rules, repo.RoutingRules)
+
+	// An empty list removes the rules.
+	updateRepoParams.RoutingRules = &[]params.RoutingRule{}
+	repo, err = s.Runner.UpdateRepository(s.Fixtures.AdminContext, s.Fixtures.StoreRepos["test-repo-1"].ID, updateRepoParams)
+
+	s.Require().Nil(err)
+	s.Require().Empty(repo.RoutingRules)
+}
+
+func (s *RepoTestSuite) TestUpdateRepositoryInvalidRoutingRules() {
+	tests := map[string][]params.RoutingRule{
+		"unknown provider": {{Type: params.RoutingRuleProviderOrder, Providers: []string{"missing-provider"}}},
+		"duplicate rule":   {{Type: params.RoutingRulePreferIdle}, {Type: params.RoutingRulePreferIdle}},
+		"unknown rule":     {{Type: "cheapest"}},
+	}
+	for name, rules := range tests {
+		updateRepoParams := s.Fixtures.UpdateRepoParams
+		updateRepoParams.RoutingRules = &rules
+		_, err := s.Runner.UpdateRepository(s.Fixtures.AdminContext, s.Fixtures.StoreRepos["test-repo-1"].ID, updateRepoParams)
+
+		var badRequest *runnerErrors.BadRequestError
+		s.Require().True(errors.As(err, &badRequest), "%s: expected bad request error, got %v", name, err)
+	}
+}
+
 func (s *RepoTestSuite) TestUpdateRepositoryErrUnauthorized() {
 	_, err := s.Runner.UpdateRepository(context.Background(), "dummy-repo-id", s.Fixtures.UpdateRepoParams)
 	s.Require().Equal(runnerErrors.ErrUnauthorized, err)
@@ -635,6 +709,207 @@ func (s *RepoTestSuite) TestFindRepoPoolManagerFetchPoolMgrFailed() {
 	s.Require().Regexp("fetching pool manager for repo", err.Error())
 }
 
+func (s *RepoTestSuite) TestDispatchWorkflowJobEntityMismatch() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	jobData := fmt.Sprintf(
+		`{"action":"queued","workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}}}`,
+		repo.Owner, repo.Name, repo.Name, repo.Owner)
+
+	err := s.Runner.DispatchWorkflowJob(s.Fixtures.StoreRepos["test-repo-2"].ID, string(RepoHook), "", []byte(jobData))
+
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+	s.Require().Regexp("job not meant for entity", err.Error())
+}
+
+func (s *RepoTestSuite) TestDispatchWorkflowJobQueuedAfterWaiting() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Runner.jobEventDedup = newJobEventDeduplicator(time.Minute)
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("secret")
+	s.Fixtures.PoolMgrMock.On("PreviousWebhookSecret").Return("")
+	s.Fixtures.PoolMgrMock.On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Times(3)
+
+	jobData := func(action string) []byte {
+		return []byte(fmt.Sprintf(
+			`{"action":%q,"workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}}}`,
+			action, repo.Owner, repo.Name, repo.Name, repo.Owner))
+	}
+
+	// The job is queued, then waits for the approval of a protected environment and
+	// is queued again once approved. The second queued event must be handled.
+	for _, action := range []string{"queued", "waiting", "queued"} {
+		data := jobData(action)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(data)
+		err := s.Runner.DispatchWorkflowJob(repo.ID, string(RepoHook), fmt.Sprintf("sha256=%x", mac.Sum(nil)), data)
+		s.Require().Nil(err)
+	}
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+}
+
+func (s *RepoTestSuite) TestDispatchWorkflowJobFanOutRequiresEntitySecret() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Runner.jobEventDedup = newJobEventDeduplicator(time.Minute)
+	trustedOrg, err := s.Fixtures.Store.CreateOrganization(s.Fixtures.AdminContext, "trusted-org", s.testCreds.Name, "secret", params.PoolBalancerTypeRoundRobin)
+	s.Require().Nil(err)
+	otherOrg, err := s.Fixtures.Store.CreateOrganization(s.Fixtures.AdminContext, "other-org", s.testCreds.Name, "other-secret", params.PoolBalancerTypeRoundRobin)
+	s.Require().Nil(err)
+
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	s.Fixtures.PoolMgrMock.On("EntityWebhookPath").Return(false)
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("secret")
+	s.Fixtures.PoolMgrMock.On("PreviousWebhookSecret").Return("")
+	s.Fixtures.PoolMgrMock.On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Twice()
+
+	orgPoolMgrs := map[string]*runnerCommonMocks.PoolManager{}
+	for _, org := range []params.Organization{trustedOrg, otherOrg} {
+		poolMgr := runnerCommonMocks.NewPoolManager(s.T())
+		poolMgr.On("ID").Return(org.ID).Maybe()
+		poolMgr.On("EntityWebhookPath").Return(false)
+		poolMgr.On("WebhookSecret").Return(org.WebhookSecret)
+		poolMgr.On("PreviousWebhookSecret").Return("")
+		orgPoolMgrs[org.Name] = poolMgr
+		s.Fixtures.PoolMgrCtrlMock.On("GetOrgPoolManager", mock.MatchedBy(func(o params.Organization) bool { return o.ID == org.ID })).Return(poolMgr, nil)
+	}
+	// Only the organization sharing the secret of the repository trusts the event.
+	orgPoolMgrs["trusted-org"].On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Once()
+
+	for idx, orgName := range []string{"trusted-org", "other-org"} {
+		jobData := []byte(fmt.Sprintf(
+			`{"action":"queued","workflow_job":{"id":%d,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}},"organization":{"login":%q}}`,
+			idx+1, repo.Owner, repo.Name, repo.Name, repo.Owner, orgName))
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(jobData)
+		err := s.Runner.DispatchWorkflowJob("", string(RepoHook), fmt.Sprintf("sha256=%x", mac.Sum(nil)), jobData)
+		s.Require().Nil(err)
+	}
+
+	// The delivery of the organization webhook is a duplicate for the trusted organization.
+	jobData := []byte(fmt.Sprintf(
+		`{"action":"queued","workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"organization":{"login":"trusted-org"}}`,
+		repo.Owner, repo.Name))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(jobData)
+	err = s.Runner.DispatchWorkflowJob("", string(OrganizationHook), fmt.Sprintf("sha256=%x", mac.Sum(nil)), jobData)
+	s.Require().Nil(err)
+
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+}
+
+func (s *RepoTestSuite) TestDispatchWorkflowJobEntityWebhookPath() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Runner.jobEventDedup = newJobEventDeduplicator(time.Minute)
+	org, err := s.Fixtures.Store.CreateOrganization(s.Fixtures.AdminContext, "test-org", s.testCreds.Name, "secret", params.PoolBalancerTypeRoundRobin)
+	s.Require().Nil(err)
+
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	s.Fixtures.PoolMgrMock.On("EntityWebhookPath").Return(true)
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("secret")
+	s.Fixtures.PoolMgrMock.On("PreviousWebhookSecret").Return("")
+	s.Fixtures.PoolMgrMock.On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Once()
+	// The organization shares the secret of the repository, but must not receive events
+	// delivered on the URL of the repository.
+	orgPoolMgr := runnerCommonMocks.NewPoolManager(s.T())
+	s.Fixtures.PoolMgrCtrlMock.On("GetOrgPoolManager", mock.AnythingOfType("params.Organization")).Return(orgPoolMgr, nil).Maybe()
+
+	jobData := []byte(fmt.Sprintf(
+		`{"action":"queued","workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}},"organization":{"login":%q}}`,
+		repo.Owner, repo.Name, repo.Name, repo.Owner, org.Name))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(jobData)
+	signature := fmt.Sprintf("sha256=%x", mac.Sum(nil))
+
+	// The webhook of the repository was installed on its own URL, so the shared URL is rejected.
+	err = s.Runner.DispatchWorkflowJob("", string(RepoHook), signature, jobData)
+	s.Require().Regexp("only accepts webhooks on its own URL", err.Error())
+
+	err = s.Runner.DispatchWorkflowJob(repo.ID, string(RepoHook), signature, jobData)
+	s.Require().Nil(err)
+
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+	orgPoolMgr.AssertNotCalled(s.T(), "HandleWorkflowJob", mock.Anything)
+}
+
+func (s *RepoTestSuite) TestHandleWebhookDeliveryAndRedeliver() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Runner.jobEventDedup = newJobEventDeduplicator(time.Minute)
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	s.Fixtures.PoolMgrMock.On("EntityWebhookPath").Return(false)
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("wrong-secret").Once()
+	s.Fixtures.PoolMgrMock.On("PreviousWebhookSecret").Return("")
+	jobData := []byte(fmt.Sprintf(
+		`{"action":"queued","workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}}}`,
+		repo.Owner, repo.Name, repo.Name, repo.Owner))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(jobData)
+	headers := http.Header{}
+	headers.Set("X-GitHub-Delivery", "delivery-1")
+	headers.Set("X-GitHub-Hook-Installation-Target-Type", string(RepoHook))
+	headers.Set("X-Hub-Signature-256", fmt.Sprintf("sha256=%x", mac.Sum(nil)))
+
+	err := s.Runner.HandleWebhookDelivery(s.Fixtures.AdminContext, "", headers, jobData)
+	s.Require().NotNil(err)
+
+	page, err := s.Runner.ListWebhookDeliveries(s.Fixtures.AdminContext, params.ListWebhookDeliveriesParams{
+		EntityID: repo.ID,
+		Page:     1,
+		PageSize: 10,
+	})
+	s.Require().Nil(err)
+	s.Require().Len(page.Deliveries, 1)
+	delivery := page.Deliveries[0]
+	s.Require().Equal("delivery-1", delivery.DeliveryID)
+	s.Require().Equal(params.WebhookDeliveryFailed, delivery.Status)
+	s.Require().Equal(fmt.Sprintf("%x", sha256.Sum256(jobData)), delivery.PayloadHash)
+	s.Require().Equal(string(RepoHook), delivery.Headers["X-Github-Hook-Installation-Target-Type"])
+	s.Require().Empty(delivery.Payload)
+
+	// Once the secret is fixed, the delivery can be processed again.
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("secret").Once()
+	s.Fixtures.PoolMgrMock.On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Once()
+	delivery, err = s.Runner.RedeliverWebhook(s.Fixtures.AdminContext, delivery.ID)
+	s.Require().Nil(err)
+	s.Require().Equal(params.WebhookDeliveryProcessed, delivery.Status)
+	s.Require().Empty(delivery.Error)
+	s.Require().Equal(uint(2), delivery.Attempts)
+
+	_, err = s.Runner.RedeliverWebhook(s.Fixtures.AdminContext, delivery.ID)
+	s.Require().Regexp("only failed deliveries", err.Error())
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+}
+
+func (s *RepoTestSuite) TestHandleWebhookDeliveryPreviousSecret() {
+	repo := s.Fixtures.StoreRepos["test-repo-1"]
+	s.Runner.jobEventDedup = newJobEventDeduplicator(time.Minute)
+	s.Fixtures.PoolMgrCtrlMock.On("GetRepoPoolManager", mock.AnythingOfType("params.Repository")).Return(s.Fixtures.PoolMgrMock, nil)
+	s.Fixtures.PoolMgrMock.On("ID").Return(repo.ID)
+	s.Fixtures.PoolMgrMock.On("EntityWebhookPath").Return(false)
+	s.Fixtures.PoolMgrMock.On("WebhookSecret").Return("new-secret")
+	s.Fixtures.PoolMgrMock.On("PreviousWebhookSecret").Return("old-secret")
+	s.Fixtures.PoolMgrMock.On("HandleWorkflowJob", mock.AnythingOfType("params.WorkflowJob")).Return(nil).Once()
+	jobData := []byte(fmt.Sprintf(
+		`{"action":"queued","workflow_job":{"id":1,"html_url":"https://github.com/%s/%s/actions/runs/1/job/1"},"repository":{"name":%q,"owner":{"login":%q}}}`,
+		repo.Owner, repo.Name, repo.Name, repo.Owner))
+
+	// The webhook in GitHub still uses the previous secret, and only sends a sha1 signature.
+	mac := hmac.New(sha1.New, []byte("old-secret"))
+	mac.Write(jobData)
+	headers := http.Header{}
+	headers.Set("X-GitHub-Delivery", "delivery-1")
+	headers.Set("X-GitHub-Hook-Installation-Target-Type", string(RepoHook))
+	headers.Set("X-Hub-Signature", fmt.Sprintf("sha1=%x", mac.Sum(nil)))
+
+	err := s.Runner.HandleWebhookDelivery(s.Fixtures.AdminContext, "", headers, jobData)
+	s.Require().Nil(err)
+	s.Fixtures.PoolMgrMock.AssertExpectations(s.T())
+}
+
 func TestRepoTestSuite(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, new(RepoTestSuite))
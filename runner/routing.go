@@ -0,0 +1,36 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/params"
+)
+
+// validateRoutingRules checks the routing rules of an entity, and makes sure the
+// providers they refer to are configured.
+func (r *Runner) validateRoutingRules(rules []params.RoutingRule) error {
+	if err := params.ValidateRoutingRules(rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		for _, name := range rule.Providers {
+			if _, ok := r.providers[name]; !ok {
+				return runnerErrors.NewBadRequestError("routing rule %s refers to unknown provider %s", rule.Type, name)
+			}
+		}
+	}
+	return nil
+}
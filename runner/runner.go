@@ -26,6 +26,7 @@ import (
 	"log/slog"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +34,8 @@ import (
 	"github.com/juju/clock"
 	"github.com/juju/retry"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
@@ -41,10 +44,15 @@ import (
 	"github.com/cloudbase/garm/auth"
 	"github.com/cloudbase/garm/config"
 	dbCommon "github.com/cloudbase/garm/database/common"
+	"github.com/cloudbase/garm/metrics"
 	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/runner/bootstrap"
 	"github.com/cloudbase/garm/runner/common"
+	"github.com/cloudbase/garm/runner/coordination"
 	"github.com/cloudbase/garm/runner/pool"
 	"github.com/cloudbase/garm/runner/providers"
+	"github.com/cloudbase/garm/tracing"
+	"github.com/cloudbase/garm/util/archive"
 )
 
 func NewRunner(ctx context.Context, cfg config.Config, db dbCommon.Store) (*Runner, error) {
@@ -53,7 +61,7 @@ func NewRunner(ctx context.Context, cfg config.Config, db dbCommon.Store) (*Runn
 		return nil, errors.Wrap(err, "fetching controller info")
 	}
 
-	providers, err := providers.LoadProvidersFromConfig(ctx, cfg, ctrlID.ControllerID.String())
+	providers, err := providers.LoadProvidersFromConfig(ctx, cfg, ctrlID.ControllerID.String(), db)
 	if err != nil {
 		return nil, errors.Wrap(err, "loading providers")
 	}
@@ -77,6 +85,25 @@ func NewRunner(ctx context.Context, cfg config.Config, db dbCommon.Store) (*Runn
 		store:           db,
 		poolManagerCtrl: poolManagerCtrl,
 		providers:       providers,
+		jobEventDedup:   newJobEventDeduplicator(jobEventDeduplicationTTL),
+	}
+
+	if cfg.BootstrapTransformer.Enabled() {
+		poolManagerCtrl.bootstrapTransformer = bootstrap.NewTransformer(cfg.BootstrapTransformer)
+	}
+
+	runner.jobArchive, err = archive.NewWriter(cfg.Jobs.Archive)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating job archive")
+	}
+
+	if cfg.Cluster.Enabled {
+		coordinator, err := coordination.NewCoordinator(ctx, db, cfg.Cluster.NodeName, poolManagerCtrl.leaseIDs)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating coordinator")
+		}
+		poolManagerCtrl.leaderElector = coordinator
+		runner.coordinator = coordinator
 	}
 
 	if err := runner.loadReposOrgsAndEnterprises(); err != nil {
@@ -95,6 +122,13 @@ type poolManagerCtrl struct {
 	repositories  map[string]common.PoolManager
 	organizations map[string]common.PoolManager
 	enterprises   map[string]common.PoolManager
+
+	// leaderElector is passed to the pool managers. It is nil when clustering
+	// is disabled.
+	leaderElector common.LeaderElector
+	// bootstrapTransformer is passed to the pool managers. It is nil when no
+	// bootstrap transformer is configured.
+	bootstrapTransformer common.BootstrapTransformer
 }
 
 func (p *poolManagerCtrl) CreateRepoPoolManager(ctx context.Context, repo params.Repository, providers map[string]common.Provider, store dbCommon.Store) (common.PoolManager, error) {
@@ -110,7 +144,7 @@ func (p *poolManagerCtrl) CreateRepoPoolManager(ctx context.Context, repo params
 	if err != nil {
 		return nil, errors.Wrap(err, "creating instance token getter")
 	}
-	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store)
+	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store, p.config.Default, p.leaderElector, p.bootstrapTransformer)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating repo pool manager")
 	}
@@ -156,7 +190,7 @@ func (p *poolManagerCtrl) CreateOrgPoolManager(ctx context.Context, org params.O
 	if err != nil {
 		return nil, errors.Wrap(err, "creating instance token getter")
 	}
-	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store)
+	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store, p.config.Default, p.leaderElector, p.bootstrapTransformer)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating org pool manager")
 	}
@@ -202,7 +236,7 @@ func (p *poolManagerCtrl) CreateEnterprisePoolManager(ctx context.Context, enter
 	if err != nil {
 		return nil, errors.Wrap(err, "creating instance token getter")
 	}
-	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store)
+	poolManager, err := pool.NewEntityPoolManager(ctx, entity, instanceTokenGetter, providers, store, p.config.Default, p.leaderElector, p.bootstrapTransformer)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating enterprise pool manager")
 	}
@@ -245,6 +279,20 @@ type Runner struct {
 	poolManagerCtrl PoolManagerController
 
 	providers map[string]common.Provider
+
+	jobEventDedup *jobEventDeduplicator
+
+	// coordinator splits the entities between the controllers of a cluster. It is
+	// nil when clustering is disabled.
+	coordinator *coordination.Coordinator
+
+	// controllerIDMigrationMux makes sure a single goroutine advances the controller
+	// ID migration at any given time.
+	controllerIDMigrationMux sync.Mutex
+
+	// jobArchive stores the completed jobs before they are removed. It is nil when
+	// archiving is disabled.
+	jobArchive archive.Writer
 }
 
 // UpdateController will update the controller settings.
@@ -306,6 +354,7 @@ func (r *Runner) GetControllerInfo(ctx context.Context) (params.ControllerInfo,
 	// object. As a single controller will be made up of multiple nodes, we will need to model
 	// that aspect of GARM.
 	info.Hostname = hostname
+	info.ObserverMode = r.config.Default.ObserverMode
 	return info, nil
 }
 
@@ -315,12 +364,39 @@ func (r *Runner) ListProviders(ctx context.Context) ([]params.Provider, error) {
 	}
 	ret := []params.Provider{}
 
+	paused, err := r.getPausedProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, val := range r.providers {
-		ret = append(ret, val.AsParams())
+		provider := val.AsParams()
+		if pause, ok := paused[provider.Name]; ok {
+			setProviderPause(&provider, pause)
+		}
+		if versionProvider, ok := val.(common.VersionProvider); ok {
+			info := versionProvider.GetVersionInfo(ctx)
+			provider.VersionInfo = &info
+		}
+		ret = append(ret, provider)
 	}
 	return ret, nil
 }
 
+// ListEntityEvents returns the events with the given level, recorded for all
+// repositories, organizations and enterprises.
+func (r *Runner) ListEntityEvents(ctx context.Context, eventLevel params.EventLevel) ([]params.EntityEvent, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	events, err := r.store.ListEntityEvents(ctx, eventLevel)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching entity events")
+	}
+	return events, nil
+}
+
 func (r *Runner) loadReposOrgsAndEnterprises() error {
 	r.mux.Lock()
 	defer r.mux.Unlock()
@@ -380,6 +456,24 @@ func (r *Runner) Start() error {
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
+	// Take the leases on our entities before starting the pool managers, so they
+	// don't wait for the next heartbeat to start working.
+	if r.coordinator != nil {
+		if err := r.coordinator.Start(); err != nil {
+			return errors.Wrap(err, "starting coordinator")
+		}
+	}
+
+	if retention := r.config.Default.AuditLogRetention; retention > 0 {
+		go r.pruneAuditRecords(retention)
+	}
+	go r.runControllerIDMigrations()
+	go r.pruneWebhookDeliveries()
+	go r.pruneCompletedJobs()
+	if interval := r.config.Default.OrphanSweepInterval; interval > 0 {
+		go r.runOrphanSweeps(interval, r.config.Default.OrphanSweepCleanup)
+	}
+
 	repositories, err := r.poolManagerCtrl.GetRepoPoolManagers()
 	if err != nil {
 		return errors.Wrap(err, "fetch repo pool managers")
@@ -499,6 +593,12 @@ func (r *Runner) Stop() error {
 	if err := r.waitForErrorGroupOrTimeout(g); err != nil {
 		return fmt.Errorf("failed to stop pool managers: %w", err)
 	}
+
+	if r.coordinator != nil {
+		if err := r.coordinator.Stop(); err != nil {
+			return errors.Wrap(err, "stopping coordinator")
+		}
+	}
 	return nil
 }
 
@@ -554,11 +654,20 @@ func (r *Runner) Wait() error {
 	}
 
 	wg.Wait()
+
+	if r.coordinator != nil {
+		if err := r.coordinator.Stop(); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to stop coordinator")
+		}
+	}
 	return nil
 }
 
-func (r *Runner) validateHookBody(signature, secret string, body []byte) error {
-	if secret == "" {
+// validateHookBody checks the signature of a webhook against the given secrets. The first
+// secret is the current webhook secret of the entity. During a secret rotation, the
+// previous secret is also accepted.
+func (r *Runner) validateHookBody(signature string, secrets []string, body []byte) error {
+	if len(secrets) == 0 || secrets[0] == "" {
 		return runnerErrors.NewMissingSecretError("missing secret to validate webhook signature")
 	}
 
@@ -586,18 +695,23 @@ func (r *Runner) validateHookBody(signature, secret string, body []byte) error {
 		return runnerErrors.NewBadRequestError("unknown signature type")
 	}
 
-	mac := hmac.New(hashFunc, []byte(secret))
-	_, err := mac.Write(body)
-	if err != nil {
-		return errors.Wrap(err, "failed to compute sha256")
-	}
-	expectedMAC := hex.EncodeToString(mac.Sum(nil))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(hashFunc, []byte(secret))
+		_, err := mac.Write(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to compute sha256")
+		}
+		expectedMAC := hex.EncodeToString(mac.Sum(nil))
 
-	if !hmac.Equal([]byte(sigParts[1]), []byte(expectedMAC)) {
-		return runnerErrors.NewUnauthorizedError("signature missmatch")
+		if hmac.Equal([]byte(sigParts[1]), []byte(expectedMAC)) {
+			return nil
+		}
 	}
 
-	return nil
+	return runnerErrors.NewUnauthorizedError("signature missmatch")
 }
 
 func (r *Runner) findEndpointForJob(job params.WorkflowJob) (params.GithubEndpoint, error) {
@@ -625,19 +739,47 @@ func (r *Runner) findEndpointForJob(job params.WorkflowJob) (params.GithubEndpoi
 	return params.GithubEndpoint{}, runnerErrors.NewNotFoundError("no endpoint found for job")
 }
 
-func (r *Runner) DispatchWorkflowJob(hookTargetType, signature string, jobData []byte) error {
+// DispatchWorkflowJob hands a workflow job received via webhook to the pool manager of the
+// entity it is meant for. If entityID is set, the webhook was received on the URL of that
+// entity, and the job is rejected if it belongs to any other entity.
+func (r *Runner) DispatchWorkflowJob(entityID, hookTargetType, signature string, jobData []byte) error {
+	_, err := r.dispatchWorkflowJob(r.ctx, entityID, hookTargetType, signature, jobData)
+	return err
+}
+
+// dispatchWorkflowJob does the work of DispatchWorkflowJob, and returns the ID of the
+// entity the job was handed to. The ID is returned even if handling the job failed,
+// as long as the entity was found.
+func (r *Runner) dispatchWorkflowJob(ctx context.Context, entityID, hookTargetType, signature string, jobData []byte) (_ string, err error) {
 	if len(jobData) == 0 {
-		return runnerErrors.NewBadRequestError("missing job data")
+		return "", runnerErrors.NewBadRequestError("missing job data")
 	}
 
 	var job params.WorkflowJob
 	if err := json.Unmarshal(jobData, &job); err != nil {
-		return errors.Wrapf(runnerErrors.ErrBadRequest, "invalid job data: %s", err)
+		return "", errors.Wrapf(runnerErrors.ErrBadRequest, "invalid job data: %s", err)
+	}
+
+	_, span := tracing.Tracer().Start(
+		ctx, "dispatch_workflow_job",
+		trace.WithAttributes(
+			attribute.Int64("garm.job.id", job.WorkflowJob.ID),
+			attribute.String("garm.job.action", job.Action),
+			attribute.Int64("garm.job.run_id", job.WorkflowJob.RunID),
+			attribute.StringSlice("garm.job.labels", job.WorkflowJob.Labels),
+		))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+	// Runners for queued jobs are created later, by the pool managers. They look up the
+	// span of the job, so the creation of the runner is part of the same trace.
+	if job.Action == "queued" {
+		tracing.RememberJob(job.WorkflowJob.ID, span.SpanContext())
 	}
 
 	endpoint, err := r.findEndpointForJob(job)
 	if err != nil {
-		return errors.Wrap(err, "finding endpoint for job")
+		return "", errors.Wrap(err, "finding endpoint for job")
 	}
 
 	var poolManager common.PoolManager
@@ -660,29 +802,122 @@ func (r *Runner) DispatchWorkflowJob(hookTargetType, signature string, jobData [
 			"enterprise", util.SanitizeLogEntry(job.Enterprise.Slug))
 		poolManager, err = r.findEnterprisePoolManager(job.Enterprise.Slug, endpoint.Name)
 	default:
-		return runnerErrors.NewBadRequestError("cannot handle hook target type %s", hookTargetType)
+		return "", runnerErrors.NewBadRequestError("cannot handle hook target type %s", hookTargetType)
 	}
 
 	if err != nil {
 		// We don't have a repository or organization configured that
 		// can handle this workflow job.
-		return errors.Wrap(err, "fetching poolManager")
+		return "", errors.Wrap(err, "fetching poolManager")
+	}
+
+	if entityID != "" && poolManager.ID() != entityID {
+		return "", runnerErrors.NewBadRequestError("job not meant for entity %s", entityID)
+	}
+	if entityID == "" && poolManager.EntityWebhookPath() {
+		// The webhook of this entity was installed on its own URL. Accepting the event on the
+		// shared URL would defeat the isolation the entity URL is meant to provide.
+		return "", runnerErrors.NewBadRequestError("entity %s only accepts webhooks on its own URL", poolManager.ID())
 	}
 
 	// We found a pool. Validate the webhook job. If a secret is configured,
 	// we make sure that the source of this workflow job is valid.
-	secret := poolManager.WebhookSecret()
-	if err := r.validateHookBody(signature, secret, jobData); err != nil {
-		return errors.Wrap(err, "validating webhook data")
+	if err := r.validateHookBody(signature, webhookSecrets(poolManager), jobData); err != nil {
+		return poolManager.ID(), errors.Wrap(err, "validating webhook data")
 	}
 
-	if err := poolManager.HandleWorkflowJob(job); err != nil {
-		return errors.Wrap(err, "handling workflow job")
+	if err := r.handleWorkflowJobOnce(poolManager, endpoint.Name, job); err != nil {
+		return poolManager.ID(), errors.Wrap(err, "handling workflow job")
 	}
 
+	// Events received on the URL of an entity are only ever handled by that entity.
+	if entityID != "" {
+		return poolManager.ID(), nil
+	}
+
+	// When GARM manages more than one level of the hierarchy (repo, org, enterprise), GitHub
+	// delivers the same event to each of them. The event is handed over to the other pool managers
+	// interested in it, but only if it is signed with their own secret. The organization and the
+	// enterprise in the payload are not otherwise trusted, as the signature only proves that the
+	// event was sent by the webhook of the entity it was delivered for. Entities with their own
+	// webhook URL only accept the events received on it.
+	for _, otherPoolMgr := range r.findOtherPoolManagersForJob(job, HookTargetType(hookTargetType), endpoint.Name) {
+		if otherPoolMgr.EntityWebhookPath() {
+			continue
+		}
+		if err := r.validateHookBody(signature, webhookSecrets(otherPoolMgr), jobData); err != nil {
+			continue
+		}
+		if err := r.handleWorkflowJobOnce(otherPoolMgr, endpoint.Name, job); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				r.ctx, "failed to handle workflow job",
+				"job_id", job.WorkflowJob.ID, "pool_manager", otherPoolMgr.ID())
+		}
+	}
+
+	return poolManager.ID(), nil
+}
+
+// webhookSecrets returns the secrets that webhooks meant for the entity of the pool manager
+// may be signed with.
+func webhookSecrets(poolMgr common.PoolManager) []string {
+	secrets := []string{poolMgr.WebhookSecret()}
+	if previous := poolMgr.PreviousWebhookSecret(); previous != "" {
+		secrets = append(secrets, previous)
+	}
+	return secrets
+}
+
+// handleWorkflowJobOnce hands a workflow job event to a pool manager, unless the same event was
+// already handed to it, either through the webhook of its own entity or through the webhook of
+// another entity in the same hierarchy.
+func (r *Runner) handleWorkflowJobOnce(poolMgr common.PoolManager, endpointName string, job params.WorkflowJob) error {
+	key := jobEventKey(endpointName, poolMgr.ID(), job)
+	if !r.jobEventDedup.claim(key) {
+		slog.DebugContext(
+			r.ctx, "ignoring duplicate workflow job event",
+			"job_id", job.WorkflowJob.ID, "action", util.SanitizeLogEntry(job.Action),
+			"pool_manager", poolMgr.ID())
+		metrics.WebhooksDeduplicated.Inc()
+		return nil
+	}
+
+	if err := poolMgr.HandleWorkflowJob(job); err != nil {
+		r.jobEventDedup.release(key)
+		return err
+	}
+	if job.Action == "waiting" {
+		// A job waiting for the approval of a protected environment is queued again once
+		// it is approved. That event must not be mistaken for a duplicate.
+		queued := job
+		queued.Action = "queued"
+		r.jobEventDedup.release(jobEventKey(endpointName, poolMgr.ID(), queued))
+	}
 	return nil
 }
 
+// findOtherPoolManagersForJob returns the pool managers of the entities in the hierarchy of
+// a workflow job, other than the one the webhook was meant for.
+func (r *Runner) findOtherPoolManagersForJob(job params.WorkflowJob, hookTarget HookTargetType, endpointName string) []common.PoolManager {
+	var ret []common.PoolManager
+	if hookTarget != RepoHook && job.Repository.Name != "" {
+		if poolMgr, err := r.findRepoPoolManager(job.Repository.Owner.Login, job.Repository.Name, endpointName); err == nil {
+			ret = append(ret, poolMgr)
+		}
+	}
+	if hookTarget != OrganizationHook && job.Organization.Login != "" {
+		if poolMgr, err := r.findOrgPoolManager(job.Organization.Login, endpointName); err == nil {
+			ret = append(ret, poolMgr)
+		}
+	}
+	if hookTarget != EnterpriseHook && job.Enterprise.Slug != "" {
+		if poolMgr, err := r.findEnterprisePoolManager(job.Enterprise.Slug, endpointName); err == nil {
+			ret = append(ret, poolMgr)
+		}
+	}
+	return ret
+}
+
 func (r *Runner) appendTagsToCreatePoolParams(param params.CreatePoolParams) (params.CreatePoolParams, error) {
 	if err := param.Validate(); err != nil {
 		return params.CreatePoolParams{}, fmt.Errorf("failed to validate params (%q): %w", err, runnerErrors.ErrBadRequest)
@@ -702,9 +937,135 @@ func (r *Runner) appendTagsToCreatePoolParams(param params.CreatePoolParams) (pa
 		return params.CreatePoolParams{}, runnerErrors.NewBadRequestError("no such provider %s", param.ProviderName)
 	}
 
+	if err := r.validateRunnerPrefix(param.ProviderName, param.Prefix); err != nil {
+		return params.CreatePoolParams{}, err
+	}
+
+	if err := r.validateWarmImage(param.ProviderName, param.WarmImage); err != nil {
+		return params.CreatePoolParams{}, err
+	}
+
 	return param, nil
 }
 
+// validateWarmImage makes sure that warm images are only enabled on pools whose provider
+// is able to snapshot instances. Empty settings disable warm images and are always valid.
+func (r *Runner) validateWarmImage(providerName string, settings *params.WarmImageSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if err := settings.Validate(); err != nil {
+		return runnerErrors.NewBadRequestError("invalid warm image settings: %s", err)
+	}
+	if settings.IsEmpty() {
+		return nil
+	}
+
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return runnerErrors.NewBadRequestError("no such provider %s", providerName)
+	}
+	if !slices.Contains(provider.AsParams().Capabilities, params.ProviderCapabilitySnapshot) {
+		return runnerErrors.NewBadRequestError("provider %s does not support snapshots, which are needed to build warm images", providerName)
+	}
+	return nil
+}
+
+// validateRunnerPrefix makes sure that instance names using the given prefix can
+// satisfy the name constraints of the provider. An empty prefix means the default
+// prefix will be used, which is validated when the provider config is loaded.
+func (r *Runner) validateRunnerPrefix(providerName, prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+
+	provider, ok := r.providers[providerName]
+	if !ok {
+		return runnerErrors.NewBadRequestError("no such provider %s", providerName)
+	}
+
+	if err := provider.AsParams().NameConstraints.ValidatePrefix(prefix); err != nil {
+		return runnerErrors.NewBadRequestError("invalid runner prefix for provider %s: %s", providerName, err)
+	}
+	return nil
+}
+
+// validatePoolWithProvider asks the provider of the pool to check that it can create
+// runners for it, for example that the image and flavor exist. Providers that don't
+// support pool validation accept any pool. The provider of new pools is checked by
+// appendTagsToCreatePoolParams, while existing pools may still be updated after their
+// provider was removed from the config.
+func (r *Runner) validatePoolWithProvider(ctx context.Context, pool params.Pool) error {
+	provider, ok := r.providers[pool.ProviderName]
+	if !ok {
+		return nil
+	}
+	validator, ok := provider.(common.PoolValidationProvider)
+	if !ok || !validator.SupportsPoolValidation() {
+		return nil
+	}
+
+	controllerInfo, err := r.store.ControllerInfo()
+	if err != nil {
+		return errors.Wrap(err, "fetching controller info")
+	}
+	validateParams := common.ValidatePoolParams{
+		ValidatePoolV011: common.ValidatePoolV011Params{
+			ProviderBaseParams: common.ProviderBaseParams{
+				PoolInfo:       pool,
+				ControllerInfo: controllerInfo,
+			},
+		},
+	}
+	if err := validator.ValidatePool(ctx, validateParams); err != nil {
+		return errors.Wrap(err, "validating pool")
+	}
+	return nil
+}
+
+// validateNewPoolWithProvider validates a pool that is about to be created.
+func (r *Runner) validateNewPoolWithProvider(ctx context.Context, param params.CreatePoolParams) error {
+	return r.validatePoolWithProvider(ctx, params.Pool{
+		ProviderName: param.ProviderName,
+		Image:        param.Image,
+		Flavor:       param.Flavor,
+		OSType:       param.OSType,
+		OSArch:       param.OSArch,
+		ExtraSpecs:   param.ExtraSpecs,
+	})
+}
+
+// validateUpdatedPoolWithProvider validates the pool that results from an update. The
+// provider is only asked if the update changes the image, flavor, OS or extra specs of
+// the pool, or if this is a dry run.
+func (r *Runner) validateUpdatedPoolWithProvider(ctx context.Context, pool params.Pool, param params.UpdatePoolParams) error {
+	changed := false
+	if param.Image != "" && param.Image != pool.Image {
+		pool.Image = param.Image
+		changed = true
+	}
+	if param.Flavor != "" && param.Flavor != pool.Flavor {
+		pool.Flavor = param.Flavor
+		changed = true
+	}
+	if param.OSType != "" && param.OSType != pool.OSType {
+		pool.OSType = param.OSType
+		changed = true
+	}
+	if param.OSArch != "" && param.OSArch != pool.OSArch {
+		pool.OSArch = param.OSArch
+		changed = true
+	}
+	if param.ExtraSpecs != nil {
+		pool.ExtraSpecs = param.ExtraSpecs
+		changed = true
+	}
+	if !changed && !param.DryRun {
+		return nil
+	}
+	return r.validatePoolWithProvider(ctx, pool)
+}
+
 func (r *Runner) GetInstance(ctx context.Context, instanceName string) (params.Instance, error) {
 	if !auth.IsAdmin(ctx) {
 		return params.Instance{}, runnerErrors.ErrUnauthorized
@@ -717,6 +1078,38 @@ func (r *Runner) GetInstance(ctx context.Context, instanceName string) (params.I
 	return instance, nil
 }
 
+// GetInstanceProviderLogs returns the operations GARM performed through the provider
+// for the given instance, oldest first.
+func (r *Runner) GetInstanceProviderLogs(ctx context.Context, instanceName string) ([]params.StatusMessage, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	events, err := r.store.ListInstanceEvents(ctx, instanceName, params.ProviderOperationEvent)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching instance events")
+	}
+	return events, nil
+}
+
+// ListInstanceStatusMessages returns one page of the status messages of an instance,
+// newest first.
+func (r *Runner) ListInstanceStatusMessages(ctx context.Context, instanceName string, param params.ListStatusMessagesParams) (params.StatusMessagesPage, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.StatusMessagesPage{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "validating params")
+	}
+
+	messages, err := r.store.ListInstanceStatusMessages(ctx, instanceName, param)
+	if err != nil {
+		return params.StatusMessagesPage{}, errors.Wrap(err, "fetching status messages")
+	}
+	return messages, nil
+}
+
 func (r *Runner) ListAllInstances(ctx context.Context) ([]params.Instance, error) {
 	if !auth.IsAdmin(ctx) {
 		return nil, runnerErrors.ErrUnauthorized
@@ -729,16 +1122,96 @@ func (r *Runner) ListAllInstances(ctx context.Context) ([]params.Instance, error
 	return instances, nil
 }
 
+// ListInstances returns the instances that match the filter. If entity is set, only
+// instances in the pools of that repository, organization or enterprise are returned.
+func (r *Runner) ListInstances(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter) ([]params.Instance, error) {
+	if !canListInstances(ctx, entity) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	instances, err := r.store.ListInstances(ctx, entity, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching instances")
+	}
+	return instances, nil
+}
+
+// canListInstances returns true if the user in the context can list the instances
+// of an entity. Only admins can list the instances of all entities.
+func canListInstances(ctx context.Context, entity *params.GithubEntity) bool {
+	if entity == nil {
+		return auth.IsAdmin(ctx)
+	}
+	return auth.CanViewEntity(ctx, *entity)
+}
+
+// ListInstancesPage returns one page of the instances that match the filter. If entity
+// is set, only instances in the pools of that repository, organization or enterprise
+// are returned.
+func (r *Runner) ListInstancesPage(ctx context.Context, entity *params.GithubEntity, filter params.InstanceFilter, opts params.ListOptions) (params.PaginatedResult[params.Instance], error) {
+	if !canListInstances(ctx, entity) {
+		return params.PaginatedResult[params.Instance]{}, runnerErrors.ErrUnauthorized
+	}
+
+	instances, err := r.store.ListInstancesPage(ctx, entity, filter, opts)
+	if err != nil {
+		return params.PaginatedResult[params.Instance]{}, errors.Wrap(err, "fetching instances")
+	}
+	return instances, nil
+}
+
+// InstanceClockSkew returns the difference between the clock of the instance, as
+// reported in the timestamp of a status update, and the clock of GARM. The second
+// value is true if the skew exceeds the configured tolerance. Updates without a
+// timestamp are not checked.
+func (r *Runner) InstanceClockSkew(param params.InstanceUpdateMessage) (time.Duration, bool) {
+	if param.Timestamp == nil {
+		return 0, false
+	}
+	skew := time.Until(*param.Timestamp).Round(time.Second)
+	tolerance := r.config.JWTAuth.InstanceClockSkew()
+	return skew, skew > tolerance || skew < -tolerance
+}
+
+// recordClockSkew adds a warning to the status messages of the instance. The warning
+// is only recorded once per instance, to avoid flooding the status messages with the
+// same warning on every status update.
+func (r *Runner) recordClockSkew(ctx context.Context, instanceName string, skew time.Duration) {
+	events, err := r.store.ListInstanceEvents(ctx, instanceName, params.ClockSkewEvent)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to list clock skew events",
+			"runner_name", instanceName)
+		return
+	}
+	if len(events) > 0 {
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"clock of the instance differs from the clock of GARM by %s (tolerance is %s); check the time synchronization on the instance",
+		skew, r.config.JWTAuth.InstanceClockSkew())
+	if err := r.store.AddInstanceEvent(ctx, instanceName, params.ClockSkewEvent, params.EventWarning, msg, common.MaxInstanceEvents); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(
+			ctx, "failed to record clock skew event",
+			"runner_name", instanceName)
+	}
+}
+
 func (r *Runner) AddInstanceStatusMessage(ctx context.Context, param params.InstanceUpdateMessage) error {
 	instanceName := auth.InstanceName(ctx)
 	if instanceName == "" {
 		return runnerErrors.ErrUnauthorized
 	}
 
-	if err := r.store.AddInstanceEvent(ctx, instanceName, params.StatusEvent, params.EventInfo, param.Message); err != nil {
+	if err := r.store.AddInstanceEvent(ctx, instanceName, params.StatusEvent, params.EventInfo, param.Message, common.MaxInstanceEvents); err != nil {
 		return errors.Wrap(err, "adding status update")
 	}
 
+	if skew, skewed := r.InstanceClockSkew(param); skewed {
+		r.recordClockSkew(ctx, instanceName, skew)
+	}
+
 	updateParams := params.UpdateInstanceParams{
 		RunnerStatus: param.Status,
 	}
@@ -761,7 +1234,7 @@ func (r *Runner) UpdateSystemInfo(ctx context.Context, param params.UpdateSystem
 		return runnerErrors.ErrUnauthorized
 	}
 
-	if param.OSName == "" && param.OSVersion == "" && param.AgentID == nil {
+	if param.OSName == "" && param.OSVersion == "" && param.AgentID == nil && param.DiskUsage == nil {
 		// Nothing to update
 		return nil
 	}
@@ -775,6 +1248,15 @@ func (r *Runner) UpdateSystemInfo(ctx context.Context, param params.UpdateSystem
 		updateParams.AgentID = *param.AgentID
 	}
 
+	if param.DiskUsage != nil {
+		if param.DiskUsage.TotalBytes > 0 && param.DiskUsage.UsedBytes > param.DiskUsage.TotalBytes {
+			return runnerErrors.NewBadRequestError("used disk space cannot be larger than total disk space")
+		}
+		diskUsage := *param.DiskUsage
+		diskUsage.UpdatedAt = time.Now().UTC()
+		updateParams.DiskUsage = &diskUsage
+	}
+
 	if _, err := r.store.UpdateInstance(r.ctx, instanceName, updateParams); err != nil {
 		return errors.Wrap(err, "updating runner system info")
 	}
@@ -826,6 +1308,37 @@ func (r *Runner) getPoolManagerFromInstance(ctx context.Context, instance params
 // DeleteRunner removes a runner from a pool. If forceDelete is true, GARM will ignore any provider errors
 // that may occur, and attempt to remove the runner from GitHub and then the database, regardless of provider
 // errors.
+// BulkDeleteRunners marks all the runners matching the filters in param for deletion. The
+// runners are removed from the provider by the pool managers, and their GitHub runner
+// registrations are cleaned up once they appear offline.
+func (r *Runner) BulkDeleteRunners(ctx context.Context, param params.BulkDeleteInstancesParams) (params.BulkDeleteInstancesResult, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.BulkDeleteInstancesResult{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.BulkDeleteInstancesResult{}, errors.Wrap(err, "validating params")
+	}
+
+	if param.PoolID != "" {
+		if _, err := r.store.GetPoolByID(ctx, param.PoolID); err != nil {
+			return params.BulkDeleteInstancesResult{}, errors.Wrap(err, "fetching pool")
+		}
+	}
+
+	names, err := r.store.MarkInstancesForDeletion(ctx, param)
+	if err != nil {
+		return params.BulkDeleteInstancesResult{}, errors.Wrap(err, "marking runners for deletion")
+	}
+	if !param.DryRun && len(names) > 0 {
+		slog.InfoContext(ctx, "marked runners for deletion", "count", len(names), "force_remove", param.ForceRemove)
+	}
+	return params.BulkDeleteInstancesResult{
+		DryRun:    param.DryRun,
+		Instances: names,
+	}, nil
+}
+
 func (r *Runner) DeleteRunner(ctx context.Context, instanceName string, forceDelete, bypassGithubUnauthorized bool) error {
 	if !auth.IsAdmin(ctx) {
 		return runnerErrors.ErrUnauthorized
@@ -0,0 +1,111 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// ListEntityRunnerGroups lists the runner groups GitHub has for an organization or
+// enterprise.
+func (r *Runner) ListEntityRunnerGroups(ctx context.Context, entityID string) ([]params.RunnerGroup, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, runnerErrors.ErrUnauthorized
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entityID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching pool manager")
+	}
+
+	groups, err := poolMgr.ListRunnerGroups(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing runner groups")
+	}
+	return groups, nil
+}
+
+// GetEntityRunnerGroup returns a runner group of an organization or enterprise by name.
+// It can be used to check that a group exists before it is set on a pool.
+func (r *Runner) GetEntityRunnerGroup(ctx context.Context, entityID, name string) (params.RunnerGroup, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.RunnerGroup{}, runnerErrors.ErrUnauthorized
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entityID)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "fetching pool manager")
+	}
+
+	group, err := poolMgr.GetRunnerGroup(ctx, name)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "fetching runner group")
+	}
+	return group, nil
+}
+
+// CreateEntityRunnerGroup creates a runner group in an organization or enterprise.
+func (r *Runner) CreateEntityRunnerGroup(ctx context.Context, entityID string, param params.CreateRunnerGroupParams) (params.RunnerGroup, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.RunnerGroup{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "validating params")
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entityID)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "fetching pool manager")
+	}
+
+	group, err := poolMgr.CreateRunnerGroup(ctx, param)
+	if err != nil {
+		return params.RunnerGroup{}, errors.Wrap(err, "creating runner group")
+	}
+	return group, nil
+}
+
+// validatePoolRunnerGroup checks that the runner group set on a pool exists in GitHub.
+// Runners of a pool with a missing group would otherwise fail to register, long after
+// the pool was created.
+func (r *Runner) validatePoolRunnerGroup(ctx context.Context, entity params.GithubEntity, name string) error {
+	if name == "" {
+		return nil
+	}
+	if entity.EntityType == params.GithubEntityTypeRepository {
+		return runnerErrors.NewBadRequestError("runner groups are only available for organizations and enterprises")
+	}
+
+	poolMgr, err := r.getPoolManagerForEntityID(ctx, entity.ID)
+	if err != nil {
+		return errors.Wrap(err, "fetching pool manager")
+	}
+
+	if _, err := poolMgr.GetRunnerGroup(ctx, name); err != nil {
+		var notFound *runnerErrors.NotFoundError
+		if errors.As(err, &notFound) {
+			return runnerErrors.NewBadRequestError("runner group %q does not exist in GitHub; create it first", name)
+		}
+		return errors.Wrap(err, "validating runner group")
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+// Package tools fetches the runner tools metadata from the tools metadata URL
+// configured on a github endpoint, verifies the checksums of the archives it
+// references and caches the result per endpoint.
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	// DefaultCacheTTL is the amount of time the tools metadata of an endpoint
+	// is cached before it is fetched again.
+	DefaultCacheTTL = 1 * time.Hour
+	// metadataFetchTimeout is the timeout for fetching the tools metadata document.
+	metadataFetchTimeout = 1 * time.Minute
+	// archiveFetchTimeout is the timeout for downloading one tools archive when
+	// verifying its checksum.
+	archiveFetchTimeout = 30 * time.Minute
+	// maxMetadataSize is the maximum size of the tools metadata document.
+	maxMetadataSize = 1 << 20
+)
+
+var defaultCache = NewCache(DefaultCacheTTL)
+
+// GetTools returns the tools for an endpoint from the default cache.
+func GetTools(ctx context.Context, endpoint params.GithubEndpoint) ([]commonParams.RunnerApplicationDownload, error) {
+	return defaultCache.GetTools(ctx, endpoint)
+}
+
+// Invalidate removes the tools of an endpoint from the default cache.
+func Invalidate(endpointName string) {
+	defaultCache.Invalidate(endpointName)
+}
+
+type cacheEntry struct {
+	metadataURL string
+	tools       []commonParams.RunnerApplicationDownload
+	updatedAt   time.Time
+}
+
+// Cache holds the verified tools of each github endpoint that has a tools
+// metadata URL set.
+type Cache struct {
+	mux sync.Mutex
+	ttl time.Duration
+
+	entries map[string]cacheEntry
+	// verified maps the download URL of an archive to the checksum we computed
+	// for it. Archives are only downloaded once, unless their URL or checksum
+	// changes.
+	verified map[string]string
+	// fetchLocks serializes the fetching of the tools of each endpoint. Fetching
+	// may take a long time, so it is done without holding the cache lock, and
+	// endpoints don't wait for each other.
+	fetchLocks map[string]*sync.Mutex
+	// generations is incremented each time the tools of an endpoint are invalidated,
+	// so that a fetch that started before is not saved in the cache.
+	generations map[string]uint64
+}
+
+// NewCache returns a new tools cache that keeps the tools of an endpoint for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:         ttl,
+		entries:     map[string]cacheEntry{},
+		verified:    map[string]string{},
+		fetchLocks:  map[string]*sync.Mutex{},
+		generations: map[string]uint64{},
+	}
+}
+
+// Invalidate removes the tools of an endpoint from the cache.
+func (c *Cache) Invalidate(endpointName string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	delete(c.entries, endpointName)
+	c.generations[endpointName]++
+}
+
+// cached returns the tools of an endpoint, if they are in the cache and still valid.
+// It also returns the generation of the tools of the endpoint.
+func (c *Cache) cached(endpoint params.GithubEndpoint) ([]commonParams.RunnerApplicationDownload, uint64, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry, ok := c.entries[endpoint.Name]
+	if ok && entry.metadataURL == endpoint.ToolsMetadataURL && time.Since(entry.updatedAt) < c.ttl {
+		return entry.tools, c.generations[endpoint.Name], true
+	}
+	return nil, c.generations[endpoint.Name], false
+}
+
+func (c *Cache) fetchLock(endpointName string) *sync.Mutex {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	lock, ok := c.fetchLocks[endpointName]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.fetchLocks[endpointName] = lock
+	}
+	return lock
+}
+
+// GetTools returns the verified tools of an endpoint. The tools metadata is fetched
+// if it is not in the cache, if it expired or if the tools metadata URL of the
+// endpoint changed. Concurrent calls for the same endpoint wait for a single fetch.
+func (c *Cache) GetTools(ctx context.Context, endpoint params.GithubEndpoint) ([]commonParams.RunnerApplicationDownload, error) {
+	if endpoint.ToolsMetadataURL == "" {
+		return nil, runnerErrors.NewBadRequestError("endpoint %s has no tools metadata URL", endpoint.Name)
+	}
+
+	if tools, _, ok := c.cached(endpoint); ok {
+		return tools, nil
+	}
+
+	lock := c.fetchLock(endpoint.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The tools may have been fetched while we were waiting.
+	tools, generation, ok := c.cached(endpoint)
+	if ok {
+		return tools, nil
+	}
+
+	httpClient, err := newHTTPClient(endpoint.CACertBundle)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating http client")
+	}
+
+	tools, err = fetchMetadata(ctx, httpClient, endpoint.ToolsMetadataURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching tools metadata")
+	}
+
+	for _, tool := range tools {
+		if err := c.verifyArchive(ctx, httpClient, tool); err != nil {
+			return nil, errors.Wrapf(err, "verifying %s", tool.GetFilename())
+		}
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	// The endpoint was updated while we were fetching. The tools we got may
+	// be stale, so we return them without caching them.
+	if c.generations[endpoint.Name] == generation {
+		c.entries[endpoint.Name] = cacheEntry{
+			metadataURL: endpoint.ToolsMetadataURL,
+			tools:       tools,
+			updatedAt:   time.Now().UTC(),
+		}
+	}
+	return tools, nil
+}
+
+// verifyArchive downloads a tools archive and compares its sha256 checksum with the
+// one advertised in the tools metadata. The cache lock is only held while looking up
+// and recording the checksum, not while downloading the archive.
+func (c *Cache) verifyArchive(ctx context.Context, httpClient *http.Client, tool commonParams.RunnerApplicationDownload) error {
+	downloadURL := tool.GetDownloadURL()
+	expected := strings.ToLower(tool.GetSHA256Checksum())
+	if downloadURL == "" {
+		return fmt.Errorf("missing download_url")
+	}
+	if len(expected) != sha256.Size*2 {
+		return fmt.Errorf("missing or invalid sha256_checksum")
+	}
+
+	c.mux.Lock()
+	checksum, ok := c.verified[downloadURL]
+	c.mux.Unlock()
+	if ok && checksum == expected {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, archiveFetchTimeout)
+	defer cancel()
+
+	resp, err := doGet(ctx, httpClient, downloadURL)
+	if err != nil {
+		return errors.Wrap(err, "downloading archive")
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return errors.Wrap(err, "reading archive")
+	}
+	checksum = hex.EncodeToString(hash.Sum(nil))
+	if checksum != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, checksum)
+	}
+
+	slog.DebugContext(ctx, "verified tools archive", "download_url", downloadURL, "sha256", checksum)
+	c.mux.Lock()
+	c.verified[downloadURL] = checksum
+	c.mux.Unlock()
+	return nil
+}
+
+func fetchMetadata(ctx context.Context, httpClient *http.Client, metadataURL string) ([]commonParams.RunnerApplicationDownload, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataFetchTimeout)
+	defer cancel()
+
+	resp, err := doGet(ctx, httpClient, metadataURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tools []commonParams.RunnerApplicationDownload
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&tools); err != nil {
+		return nil, errors.Wrap(err, "decoding tools metadata")
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("tools metadata is empty")
+	}
+	return tools, nil
+}
+
+func doGet(ctx context.Context, httpClient *http.Client, getURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, getURL)
+	}
+	return resp, nil
+}
+
+func newHTTPClient(caCertBundle []byte) (*http.Client, error) {
+	var roots *x509.CertPool
+	if caCertBundle != nil {
+		roots = x509.NewCertPool()
+		if ok := roots.AppendCertsFromPEM(caCertBundle); !ok {
+			return nil, fmt.Errorf("failed to parse CA cert")
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs:    roots,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
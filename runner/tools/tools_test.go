@@ -0,0 +1,176 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	commonParams "github.com/cloudbase/garm-provider-common/params"
+	"github.com/cloudbase/garm/params"
+)
+
+type toolsServer struct {
+	*httptest.Server
+
+	checksum         string
+	metadataRequests atomic.Int32
+	archiveRequests  atomic.Int32
+	// archiveGate, if set, holds archive downloads until it is closed.
+	archiveGate chan struct{}
+}
+
+func newToolsServer(t *testing.T, archive []byte, checksum string) *toolsServer {
+	srv := &toolsServer{checksum: checksum}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tools.json", func(w http.ResponseWriter, _ *http.Request) {
+		srv.metadataRequests.Add(1)
+		downloadURL := srv.URL + "/actions-runner-linux-x64.tar.gz"
+		tools := []commonParams.RunnerApplicationDownload{
+			{
+				OS:             stringPtr("linux"),
+				Architecture:   stringPtr("x64"),
+				Filename:       stringPtr("actions-runner-linux-x64.tar.gz"),
+				DownloadURL:    &downloadURL,
+				SHA256Checksum: stringPtr(srv.checksum),
+			},
+		}
+		_ = json.NewEncoder(w).Encode(tools)
+	})
+	mux.HandleFunc("/actions-runner-linux-x64.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		srv.archiveRequests.Add(1)
+		if srv.archiveGate != nil {
+			<-srv.archiveGate
+		}
+		_, _ = w.Write(archive)
+	})
+	srv.Server = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestGetToolsVerifiesAndCaches(t *testing.T) {
+	archive := []byte("runner archive contents")
+	srv := newToolsServer(t, archive, checksumOf(archive))
+	endpoint := params.GithubEndpoint{
+		Name:             "internal",
+		ToolsMetadataURL: srv.URL + "/tools.json",
+	}
+
+	cache := NewCache(time.Hour)
+	tools, err := cache.GetTools(context.Background(), endpoint)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Equal(t, "linux", tools[0].GetOS())
+
+	_, err = cache.GetTools(context.Background(), endpoint)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), srv.metadataRequests.Load())
+	require.Equal(t, int32(1), srv.archiveRequests.Load())
+
+	// Once invalidated, the metadata is fetched again, but the archive was already verified.
+	cache.Invalidate(endpoint.Name)
+	_, err = cache.GetTools(context.Background(), endpoint)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), srv.metadataRequests.Load())
+	require.Equal(t, int32(1), srv.archiveRequests.Load())
+}
+
+func TestGetToolsDoesNotBlockOtherEndpoints(t *testing.T) {
+	archive := []byte("runner archive contents")
+	slowSrv := newToolsServer(t, archive, checksumOf(archive))
+	slowSrv.archiveGate = make(chan struct{})
+	fastSrv := newToolsServer(t, archive, checksumOf(archive))
+
+	cache := NewCache(time.Hour)
+	slowEndpoint := params.GithubEndpoint{
+		Name:             "slow",
+		ToolsMetadataURL: slowSrv.URL + "/tools.json",
+	}
+	slowDone := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cache.GetTools(context.Background(), slowEndpoint)
+			slowDone <- err
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return slowSrv.archiveRequests.Load() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The download of the slow endpoint does not hold back other endpoints.
+	_, err := cache.GetTools(context.Background(), params.GithubEndpoint{
+		Name:             "fast",
+		ToolsMetadataURL: fastSrv.URL + "/tools.json",
+	})
+	require.NoError(t, err)
+
+	close(slowSrv.archiveGate)
+	require.NoError(t, <-slowDone)
+	require.NoError(t, <-slowDone)
+	// Concurrent calls for the same endpoint wait for a single fetch.
+	require.Equal(t, int32(1), slowSrv.metadataRequests.Load())
+	require.Equal(t, int32(1), slowSrv.archiveRequests.Load())
+}
+
+func TestGetToolsChecksumMismatch(t *testing.T) {
+	archive := []byte("runner archive contents")
+	srv := newToolsServer(t, archive, checksumOf([]byte("something else")))
+	endpoint := params.GithubEndpoint{
+		Name:             "internal",
+		ToolsMetadataURL: srv.URL + "/tools.json",
+	}
+
+	cache := NewCache(time.Hour)
+	_, err := cache.GetTools(context.Background(), endpoint)
+	require.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestGetToolsMissingChecksum(t *testing.T) {
+	srv := newToolsServer(t, []byte("runner archive contents"), "")
+	endpoint := params.GithubEndpoint{
+		Name:             "internal",
+		ToolsMetadataURL: srv.URL + "/tools.json",
+	}
+
+	cache := NewCache(time.Hour)
+	_, err := cache.GetTools(context.Background(), endpoint)
+	require.ErrorContains(t, err, "missing or invalid sha256_checksum")
+	require.Equal(t, int32(0), srv.archiveRequests.Load())
+}
+
+func TestGetToolsNoMetadataURL(t *testing.T) {
+	cache := NewCache(time.Hour)
+	_, err := cache.GetTools(context.Background(), params.GithubEndpoint{Name: "github.com"})
+	require.Error(t, err)
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+)
+
+// GetUsageReport aggregates the runtime of runners per pool, provider and entity, over
+// the period set in param.
+func (r *Runner) GetUsageReport(ctx context.Context, param params.UsageReportParams) (params.UsageReport, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.UsageReport{}, runnerErrors.ErrUnauthorized
+	}
+	if err := param.Validate(); err != nil {
+		return params.UsageReport{}, errors.Wrap(err, "validating params")
+	}
+
+	until := param.Until
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	records, err := r.store.ListRunnerUsage(ctx, param.Since, until)
+	if err != nil {
+		return params.UsageReport{}, errors.Wrap(err, "fetching runner usage")
+	}
+
+	report := AggregateRunnerUsage(records, param.Since, until)
+	names := r.entityNames(ctx)
+	for idx := range report.Pools {
+		report.Pools[idx].EntityName = names[report.Pools[idx].EntityID]
+	}
+	for idx := range report.Entities {
+		report.Entities[idx].EntityName = names[report.Entities[idx].EntityID]
+	}
+	return report, nil
+}
+
+// AggregateRunnerUsage sums up the runtime of the given usage records between since
+// and until, per pool, provider and entity.
+func AggregateRunnerUsage(records []params.RunnerUsage, since, until time.Time) params.UsageReport {
+	pools := map[string]*params.UsageSummary{}
+	providers := map[string]*params.UsageSummary{}
+	entities := map[string]*params.UsageSummary{}
+	add := func(summaries map[string]*params.UsageSummary, key string, summary params.UsageSummary, runtime time.Duration) {
+		if _, ok := summaries[key]; !ok {
+			summaries[key] = &summary
+		}
+		summaries[key].Runners++
+		summaries[key].RuntimeMinutes += runtime.Minutes()
+	}
+
+	for _, record := range records {
+		runtime := record.Runtime(since, until)
+		add(pools, record.PoolID, params.UsageSummary{
+			PoolID:       record.PoolID,
+			ProviderName: record.ProviderName,
+			EntityID:     record.EntityID,
+			EntityType:   record.EntityType,
+		}, runtime)
+		add(providers, record.ProviderName, params.UsageSummary{
+			ProviderName: record.ProviderName,
+		}, runtime)
+		if record.EntityID != "" {
+			add(entities, record.EntityID, params.UsageSummary{
+				EntityID:   record.EntityID,
+				EntityType: record.EntityType,
+			}, runtime)
+		}
+	}
+
+	report := params.UsageReport{
+		Until:     until,
+		Pools:     sortedUsageSummaries(pools),
+		Providers: sortedUsageSummaries(providers),
+		Entities:  sortedUsageSummaries(entities),
+	}
+	if !since.IsZero() {
+		report.Since = since
+	}
+	return report
+}
+
+// GetEfficiencyReport returns, for each pool, how many jobs its runners served, how long
+// they took to pick up their first job, and how much of their runtime they were idle.
+func (r *Runner) GetEfficiencyReport(ctx context.Context, param params.UsageReportParams) (params.EfficiencyReport, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.EfficiencyReport{}, runnerErrors.ErrUnauthorized
+	}
+	if err := param.Validate(); err != nil {
+		return params.EfficiencyReport{}, errors.Wrap(err, "validating params")
+	}
+
+	until := param.Until
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	records, err := r.store.ListRunnerUsage(ctx, param.Since, until)
+	if err != nil {
+		return params.EfficiencyReport{}, errors.Wrap(err, "fetching runner usage")
+	}
+
+	report := AggregateRunnerEfficiency(records, param.Since, until)
+	names := r.entityNames(ctx)
+	for idx := range report.Pools {
+		report.Pools[idx].EntityName = names[report.Pools[idx].EntityID]
+	}
+	return report, nil
+}
+
+// AggregateRunnerEfficiency computes the efficiency of the runners of each pool, from
+// the given usage records, between since and until.
+func AggregateRunnerEfficiency(records []params.RunnerUsage, since, until time.Time) params.EfficiencyReport {
+	type poolTotals struct {
+		efficiency   params.PoolEfficiency
+		runtime      time.Duration
+		busy         time.Duration
+		firstJob     time.Duration
+		firstJobRuns uint
+	}
+	pools := map[string]*poolTotals{}
+	for _, record := range records {
+		totals, ok := pools[record.PoolID]
+		if !ok {
+			totals = &poolTotals{
+				efficiency: params.PoolEfficiency{
+					PoolID:       record.PoolID,
+					ProviderName: record.ProviderName,
+					EntityID:     record.EntityID,
+					EntityType:   record.EntityType,
+				},
+			}
+			pools[record.PoolID] = totals
+		}
+
+		runtime := record.Runtime(since, until)
+		totals.efficiency.Runners++
+		totals.efficiency.JobsServed += record.JobsServed
+		if record.JobsServed == 0 && record.RemovedAt != nil {
+			totals.efficiency.UnusedRunners++
+		}
+		totals.runtime += runtime
+		totals.busy += min(record.BusyTime(until), runtime)
+		if latency, ok := record.FirstJobLatency(); ok {
+			totals.firstJob += latency
+			totals.firstJobRuns++
+		}
+	}
+
+	ids := make([]string, 0, len(pools))
+	for id := range pools {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	report := params.EfficiencyReport{
+		Until: until,
+		Pools: make([]params.PoolEfficiency, 0, len(ids)),
+	}
+	if !since.IsZero() {
+		report.Since = since
+	}
+	for _, id := range ids {
+		totals := pools[id]
+		efficiency := totals.efficiency
+		efficiency.JobsPerRunner = float64(efficiency.JobsServed) / float64(efficiency.Runners)
+		if totals.firstJobRuns > 0 {
+			efficiency.AvgFirstJobLatencySeconds = totals.firstJob.Seconds() / float64(totals.firstJobRuns)
+		}
+		efficiency.BusyMinutes = totals.busy.Minutes()
+		efficiency.IdleMinutes = (totals.runtime - totals.busy).Minutes()
+		if totals.runtime > 0 {
+			efficiency.IdlePercent = efficiency.IdleMinutes * 100 / totals.runtime.Minutes()
+		}
+		report.Pools = append(report.Pools, efficiency)
+	}
+	return report
+}
+
+func sortedUsageSummaries(summaries map[string]*params.UsageSummary) []params.UsageSummary {
+	keys := make([]string, 0, len(summaries))
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ret := make([]params.UsageSummary, len(keys))
+	for idx, key := range keys {
+		ret[idx] = *summaries[key]
+	}
+	return ret
+}
+
+// entityNames returns the names of all entities, indexed by ID. Entities that were
+// removed are missing from the result.
+func (r *Runner) entityNames(ctx context.Context) map[string]string {
+	names := map[string]string{}
+	repos, err := r.store.ListRepositories(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to list repositories")
+	}
+	for _, repo := range repos {
+		names[repo.ID] = repo.String()
+	}
+	orgs, err := r.store.ListOrganizations(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to list organizations")
+	}
+	for _, org := range orgs {
+		names[org.ID] = org.Name
+	}
+	enterprises, err := r.store.ListEnterprises(ctx)
+	if err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to list enterprises")
+	}
+	for _, enterprise := range enterprises {
+		names[enterprise.ID] = enterprise.Name
+	}
+	return names
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/database"
+	garmTesting "github.com/cloudbase/garm/internal/testing"
+	"github.com/cloudbase/garm/params"
+)
+
+func TestAggregateRunnerUsage(t *testing.T) {
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(10 * time.Hour)
+	removedAt := since.Add(1 * time.Hour)
+
+	records := []params.RunnerUsage{
+		// Created before the period, removed during it.
+		{PoolID: "pool-1", ProviderName: "lxd", EntityID: "org-1", EntityType: params.GithubEntityTypeOrganization, CreatedAt: since.Add(-1 * time.Hour), RemovedAt: &removedAt},
+		// Still running at the end of the period.
+		{PoolID: "pool-1", ProviderName: "lxd", EntityID: "org-1", EntityType: params.GithubEntityTypeOrganization, CreatedAt: since.Add(8 * time.Hour)},
+		{PoolID: "pool-2", ProviderName: "openstack", EntityID: "org-1", EntityType: params.GithubEntityTypeOrganization, CreatedAt: since.Add(9 * time.Hour)},
+		{PoolID: "pool-3", ProviderName: "lxd", EntityID: "repo-1", EntityType: params.GithubEntityTypeRepository, CreatedAt: since.Add(9*time.Hour + 30*time.Minute)},
+	}
+
+	report := AggregateRunnerUsage(records, since, until)
+	require.Equal(t, since, report.Since)
+	require.Equal(t, until, report.Until)
+
+	require.Len(t, report.Pools, 3)
+	require.Equal(t, "pool-1", report.Pools[0].PoolID)
+	require.Equal(t, uint(2), report.Pools[0].Runners)
+	require.Equal(t, float64(180), report.Pools[0].RuntimeMinutes)
+
+	require.Len(t, report.Providers, 2)
+	require.Equal(t, "lxd", report.Providers[0].ProviderName)
+	require.Equal(t, uint(3), report.Providers[0].Runners)
+	require.Equal(t, float64(210), report.Providers[0].RuntimeMinutes)
+	require.Equal(t, float64(60), report.Providers[1].RuntimeMinutes)
+
+	require.Len(t, report.Entities, 2)
+	require.Equal(t, "org-1", report.Entities[0].EntityID)
+	require.Equal(t, float64(240), report.Entities[0].RuntimeMinutes)
+	require.Equal(t, float64(30), report.Entities[1].RuntimeMinutes)
+}
+
+func TestAggregateRunnerEfficiency(t *testing.T) {
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(10 * time.Hour)
+	removedAt := since.Add(1 * time.Hour)
+	firstJobAt := since.Add(2 * time.Minute)
+	busySince := since.Add(9*time.Hour + 30*time.Minute)
+
+	records := []params.RunnerUsage{
+		// Ran two jobs for a total of 30 minutes during its hour of runtime.
+		{PoolID: "pool-1", ProviderName: "lxd", CreatedAt: since, RemovedAt: &removedAt, JobsServed: 2, FirstJobAt: &firstJobAt, BusySeconds: 1800},
+		// Removed without running a job.
+		{PoolID: "pool-1", ProviderName: "lxd", CreatedAt: since, RemovedAt: &removedAt},
+		// Created 4 minutes before its first job, which it is still running.
+		{PoolID: "pool-2", ProviderName: "lxd", CreatedAt: since.Add(9*time.Hour + 26*time.Minute), JobsServed: 1, FirstJobAt: &busySince, BusySince: &busySince},
+	}
+
+	report := AggregateRunnerEfficiency(records, since, until)
+	require.Equal(t, since, report.Since)
+	require.Len(t, report.Pools, 2)
+
+	pool1 := report.Pools[0]
+	require.Equal(t, "pool-1", pool1.PoolID)
+	require.Equal(t, uint(2), pool1.Runners)
+	require.Equal(t, uint(2), pool1.JobsServed)
+	require.Equal(t, float64(1), pool1.JobsPerRunner)
+	require.Equal(t, uint(1), pool1.UnusedRunners)
+	require.Equal(t, float64(120), pool1.AvgFirstJobLatencySeconds)
+	require.Equal(t, float64(30), pool1.BusyMinutes)
+	require.Equal(t, float64(90), pool1.IdleMinutes)
+	require.Equal(t, float64(75), pool1.IdlePercent)
+
+	pool2 := report.Pools[1]
+	require.Equal(t, uint(0), pool2.UnusedRunners)
+	require.Equal(t, float64(240), pool2.AvgFirstJobLatencySeconds)
+	require.Equal(t, float64(30), pool2.BusyMinutes)
+	require.Equal(t, float64(4), pool2.IdleMinutes)
+}
+
+func TestGetUsageReport(t *testing.T) {
+	adminCtx := auth.GetAdminContext(context.Background())
+	db, err := database.NewDatabase(adminCtx, garmTesting.GetTestSqliteDBConfig(t))
+	require.Nil(t, err)
+	adminCtx = garmTesting.ImpersonateAdminContext(adminCtx, db, t)
+	_, err = db.InitController()
+	require.Nil(t, err)
+
+	endpoint := garmTesting.CreateDefaultGithubEndpoint(adminCtx, db, t)
+	creds := garmTesting.CreateTestGithubCredentials(adminCtx, "test-creds", db, t, endpoint)
+	org, err := db.CreateOrganization(adminCtx, "test-org", creds.Name, "test-webhookSecret", params.PoolBalancerTypeRoundRobin)
+	require.Nil(t, err)
+	entity := params.GithubEntity{ID: org.ID, EntityType: params.GithubEntityTypeOrganization}
+	pool, err := db.CreateEntityPool(adminCtx, entity, params.CreatePoolParams{
+		ProviderName: "test-provider",
+		MaxRunners:   4,
+		Image:        "test-image",
+		Flavor:       "test-flavor",
+		OSType:       "linux",
+		Tags:         []string{"test"},
+	})
+	require.Nil(t, err)
+	for _, name := range []string{"runner-1", "runner-2"} {
+		_, err = db.CreateInstance(adminCtx, pool.ID, params.CreateInstanceParams{Name: name, OSType: "linux"})
+		require.Nil(t, err)
+	}
+	require.Nil(t, db.DeleteInstance(adminCtx, pool.ID, "runner-1"))
+
+	r := &Runner{
+		ctx:   adminCtx,
+		store: db,
+	}
+
+	_, err = r.GetUsageReport(context.Background(), params.UsageReportParams{})
+	require.Equal(t, runnerErrors.ErrUnauthorized, err)
+
+	now := time.Now().UTC()
+	_, err = r.GetUsageReport(adminCtx, params.UsageReportParams{Since: now, Until: now.Add(-1 * time.Hour)})
+	require.NotNil(t, err)
+
+	report, err := r.GetUsageReport(adminCtx, params.UsageReportParams{})
+	require.Nil(t, err)
+	require.True(t, report.Since.IsZero())
+	require.Len(t, report.Pools, 1)
+	require.Equal(t, pool.ID, report.Pools[0].PoolID)
+	require.Equal(t, "test-provider", report.Pools[0].ProviderName)
+	require.Equal(t, org.ID, report.Pools[0].EntityID)
+	require.Equal(t, "test-org", report.Pools[0].EntityName)
+	require.Equal(t, uint(2), report.Pools[0].Runners)
+	require.Len(t, report.Entities, 1)
+	require.Equal(t, "test-org", report.Entities[0].EntityName)
+
+	// Runners created after the end of the period are left out.
+	report, err = r.GetUsageReport(adminCtx, params.UsageReportParams{Until: now.Add(-1 * time.Hour)})
+	require.Nil(t, err)
+	require.Len(t, report.Pools, 0)
+}
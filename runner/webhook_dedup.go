@@ -0,0 +1,85 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// jobEventDeduplicationTTL is the amount of time we remember a workflow job event
+// after it was dispatched. GitHub sends the same event to the webhooks of the repo,
+// the org and the enterprise, usually within a few seconds of each other.
+const jobEventDeduplicationTTL = 5 * time.Minute
+
+// jobEventDeduplicator keeps track of the workflow job events that were already
+// dispatched to the pool managers, so that the same event delivered by multiple
+// webhooks is only processed once by each pool manager.
+type jobEventDeduplicator struct {
+	mux       sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newJobEventDeduplicator(ttl time.Duration) *jobEventDeduplicator {
+	return &jobEventDeduplicator{
+		ttl:  ttl,
+		seen: map[string]time.Time{},
+	}
+}
+
+// jobEventKey returns the key used to identify a workflow job event handed to the pool
+// manager of an entity. The delivery GUID is different for each webhook, so we use the
+// job ID and the action instead. Job IDs are only unique within a github endpoint.
+func jobEventKey(endpointName, entityID string, job params.WorkflowJob) string {
+	return fmt.Sprintf("%s/%s/%d/%s", endpointName, entityID, job.WorkflowJob.ID, job.Action)
+}
+
+// claim records the event identified by key and returns true if it was not seen
+// in the last ttl. A false return value means the event is a duplicate and should
+// be ignored.
+func (d *jobEventDeduplicator) claim(key string) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(d.lastPrune) > d.ttl {
+		for k, seenAt := range d.seen {
+			if now.Sub(seenAt) > d.ttl {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) <= d.ttl {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// release forgets an event, allowing it to be processed again. This is used when
+// processing the event failed.
+func (d *jobEventDeduplicator) release(key string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	delete(d.seen, key)
+}
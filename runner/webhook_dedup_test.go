@@ -0,0 +1,57 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func TestJobEventDeduplicator(t *testing.T) {
+	dedup := newJobEventDeduplicator(time.Minute)
+
+	job := params.WorkflowJob{Action: "queued"}
+	job.WorkflowJob.ID = 1234
+	key := jobEventKey("github.com", "entity-1", job)
+
+	require.True(t, dedup.claim(key))
+	require.False(t, dedup.claim(key))
+
+	// The same event is handed once to each entity.
+	require.True(t, dedup.claim(jobEventKey("github.com", "entity-2", job)))
+
+	// Job IDs of different endpoints may collide.
+	require.True(t, dedup.claim(jobEventKey("ghes", "entity-1", job)))
+
+	// A different action for the same job is a different event.
+	job.Action = "in_progress"
+	require.True(t, dedup.claim(jobEventKey("github.com", "entity-1", job)))
+
+	// Released events can be processed again.
+	dedup.release(key)
+	require.True(t, dedup.claim(key))
+}
+
+func TestJobEventDeduplicatorExpires(t *testing.T) {
+	dedup := newJobEventDeduplicator(10 * time.Millisecond)
+
+	require.True(t, dedup.claim("1/queued"))
+	time.Sleep(20 * time.Millisecond)
+	require.True(t, dedup.claim("1/queued"))
+}
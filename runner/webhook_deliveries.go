@@ -0,0 +1,179 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/auth"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/tracing"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const (
+	webhookSignatureHeader = "X-Hub-Signature-256"
+	// webhookSHA1SignatureHeader holds the sha1 signature of the payload. GitHub sends
+	// it along with the sha256 signature. It is only used if the sha256 signature is
+	// missing, like in deliveries from older GHES versions.
+	webhookSHA1SignatureHeader = "X-Hub-Signature"
+	webhookTargetTypeHeader    = "X-Github-Hook-Installation-Target-Type"
+	webhookDeliveryIDHeader    = "X-Github-Delivery"
+
+	// webhookDeliveryPruneInterval is the interval at which old webhook deliveries
+	// are removed.
+	webhookDeliveryPruneInterval = 1 * time.Hour
+)
+
+// webhookDeliveryHeaders are the headers recorded along with a webhook delivery.
+// They are needed to process the delivery again.
+var webhookDeliveryHeaders = []string{
+	webhookDeliveryIDHeader,
+	"X-Github-Event",
+	"X-Github-Hook-Id",
+	webhookTargetTypeHeader,
+	"X-Github-Hook-Installation-Target-Id",
+	webhookSignatureHeader,
+	webhookSHA1SignatureHeader,
+	"User-Agent",
+}
+
+// webhookSignature returns the signature of a webhook. The sha256 signature is preferred
+// over the sha1 signature.
+func webhookSignature(header func(name string) string) string {
+	if signature := header(webhookSignatureHeader); signature != "" {
+		return signature
+	}
+	return header(webhookSHA1SignatureHeader)
+}
+
+// HandleWebhookDelivery dispatches a workflow job webhook and records the delivery,
+// along with the result of processing it. The error returned is the one returned
+// when dispatching the job.
+func (r *Runner) HandleWebhookDelivery(ctx context.Context, targetEntityID string, headers http.Header, body []byte) error {
+	ctx, span := tracing.Tracer().Start(
+		ctx, "webhook.workflow_job",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("garm.webhook.delivery_id", headers.Get(webhookDeliveryIDHeader))))
+	entityID, dispatchErr := r.dispatchWorkflowJob(ctx, targetEntityID, headers.Get(webhookTargetTypeHeader), webhookSignature(headers.Get), body)
+	defer func() {
+		tracing.EndSpan(span, dispatchErr)
+	}()
+
+	delivery := params.WebhookDelivery{
+		DeliveryID:     headers.Get(webhookDeliveryIDHeader),
+		EntityID:       entityID,
+		TargetEntityID: targetEntityID,
+		Headers:        map[string]string{},
+		PayloadHash:    fmt.Sprintf("%x", sha256.Sum256(body)),
+		Payload:        body,
+		Status:         params.WebhookDeliveryProcessed,
+	}
+	for _, name := range webhookDeliveryHeaders {
+		if val := headers.Get(name); val != "" {
+			delivery.Headers[name] = val
+		}
+	}
+	if dispatchErr != nil {
+		delivery.Status = params.WebhookDeliveryFailed
+		delivery.Error = dispatchErr.Error()
+	}
+
+	if _, err := r.store.CreateWebhookDelivery(ctx, delivery); err != nil {
+		slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to record webhook delivery")
+	}
+	return dispatchErr
+}
+
+// ListWebhookDeliveries returns one page of the recorded webhook deliveries.
+func (r *Runner) ListWebhookDeliveries(ctx context.Context, param params.ListWebhookDeliveriesParams) (params.WebhookDeliveriesPage, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.WebhookDeliveriesPage{}, runnerErrors.ErrUnauthorized
+	}
+
+	if err := param.Validate(); err != nil {
+		return params.WebhookDeliveriesPage{}, errors.Wrap(err, "validating params")
+	}
+
+	deliveries, err := r.store.ListWebhookDeliveries(ctx, param)
+	if err != nil {
+		return params.WebhookDeliveriesPage{}, errors.Wrap(err, "listing webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook processes a failed webhook delivery again. This is useful once the
+// cause of the failure was fixed, like a wrong webhook secret or a missing entity.
+func (r *Runner) RedeliverWebhook(ctx context.Context, deliveryID string) (params.WebhookDelivery, error) {
+	if !auth.IsAdmin(ctx) {
+		return params.WebhookDelivery{}, runnerErrors.ErrUnauthorized
+	}
+
+	delivery, err := r.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return params.WebhookDelivery{}, errors.Wrap(err, "fetching webhook delivery")
+	}
+	if delivery.Status != params.WebhookDeliveryFailed {
+		return params.WebhookDelivery{}, runnerErrors.NewBadRequestError("only failed deliveries can be processed again")
+	}
+
+	status := params.WebhookDeliveryProcessed
+	var deliveryErr string
+	dispatchCtx, span := tracing.Tracer().Start(
+		ctx, "webhook.redeliver_workflow_job",
+		trace.WithAttributes(attribute.String("garm.webhook.delivery_id", deliveryID)))
+	entityID, err := r.dispatchWorkflowJob(
+		dispatchCtx, delivery.TargetEntityID, delivery.Headers[webhookTargetTypeHeader],
+		webhookSignature(func(name string) string { return delivery.Headers[name] }), delivery.Payload)
+	if err != nil {
+		status = params.WebhookDeliveryFailed
+		deliveryErr = err.Error()
+	}
+	tracing.EndSpan(span, err)
+
+	delivery, err = r.store.UpdateWebhookDeliveryResult(ctx, deliveryID, entityID, status, deliveryErr)
+	if err != nil {
+		return params.WebhookDelivery{}, errors.Wrap(err, "updating webhook delivery")
+	}
+	return delivery, nil
+}
+
+// pruneWebhookDeliveries periodically removes the webhook deliveries that are older
+// than the retention period.
+func (r *Runner) pruneWebhookDeliveries() {
+	ticker := time.NewTicker(webhookDeliveryPruneInterval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().UTC().Add(-appdefaults.WebhookDeliveryRetention)
+		if err := r.store.DeleteWebhookDeliveriesBefore(r.ctx, before); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(r.ctx, "failed to prune webhook deliveries")
+		}
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
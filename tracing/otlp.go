@@ -0,0 +1,365 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const (
+	exportTimeout  = 30 * time.Second
+	exportInterval = 5 * time.Second
+	// exportBatchSize is the number of queued spans that trigger an export before
+	// the export interval elapses.
+	exportBatchSize = 512
+	// maxQueueSize is the maximum number of spans waiting to be exported. Spans that
+	// end while the queue is full are dropped, so an unreachable collector can't make
+	// GARM run out of memory.
+	maxQueueSize = 4096
+	scopeName    = "github.com/cloudbase/garm"
+)
+
+// exporter batches finished spans and sends them to a collector using the JSON
+// encoding of OTLP over HTTP.
+type exporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	mux     sync.Mutex
+	queue   []spanData
+	dropped int
+
+	flush chan struct{}
+	quit  chan struct{}
+	done  chan struct{}
+}
+
+func newExporter(cfg config.Tracing) *exporter {
+	return &exporter{
+		url:         strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/traces",
+		headers:     cfg.Headers,
+		serviceName: cfg.Service(),
+		client:      &http.Client{Timeout: exportTimeout},
+		flush:       make(chan struct{}, 1),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (e *exporter) enqueue(data spanData) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if len(e.queue) >= maxQueueSize {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, data)
+	if len(e.queue) >= exportBatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) take() ([]spanData, int) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	queue, dropped := e.queue, e.dropped
+	e.queue = nil
+	e.dropped = 0
+	return queue, dropped
+}
+
+// start exports the queued spans every export interval, or sooner if a full batch
+// is queued, until the exporter is shut down.
+func (e *exporter) start(ctx context.Context) {
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(exportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.quit:
+				e.exportQueued(ctx)
+				return
+			case <-ticker.C:
+			case <-e.flush:
+			}
+			e.exportQueued(ctx)
+		}
+	}()
+}
+
+// shutdown exports the spans that are still queued and stops the exporter.
+func (e *exporter) shutdown(ctx context.Context) error {
+	close(e.quit)
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error flushing spans: %w", ctx.Err())
+	}
+}
+
+func (e *exporter) exportQueued(ctx context.Context) {
+	queue, dropped := e.take()
+	if dropped > 0 {
+		slog.WarnContext(ctx, "span export queue is full; spans were dropped", "dropped", dropped)
+	}
+	for len(queue) > 0 {
+		batch := queue[:min(len(queue), exportBatchSize)]
+		queue = queue[len(batch):]
+		if err := e.export(ctx, batch); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(ctx, "failed to export spans", "spans", len(batch))
+		}
+	}
+}
+
+func (e *exporter) export(ctx context.Context, spans []spanData) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("error encoding spans: %w", err)
+	}
+
+	// The context of the exporter may already be canceled while GARM shuts down,
+	// and the last batch must still be sent.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("garm/%s", appdefaults.GetVersion()))
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// The types below follow the JSON encoding of the OTLP ExportTraceServiceRequest.
+// Trace and span IDs are hex encoded, and 64 bit integers are encoded as strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpValue `json:"values"`
+}
+
+func (e *exporter) encode(spans []spanData) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.spanContext.TraceID().String(),
+			SpanID:            s.spanContext.SpanID().String(),
+			Name:              s.name,
+			Kind:              int(s.kind),
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Attributes:        encodeAttributes(s.attributes),
+			Status:            encodeStatus(s.status, s.statusMsg),
+		}
+		if s.parent.IsValid() {
+			span.ParentSpanID = s.parent.SpanID().String()
+		}
+		for _, ev := range s.events {
+			span.Events = append(span.Events, otlpEvent{
+				TimeUnixNano: unixNano(ev.at),
+				Name:         ev.name,
+				Attributes:   encodeAttributes(ev.attributes),
+			})
+		}
+		for _, link := range s.links {
+			span.Links = append(span.Links, otlpLink{
+				TraceID:    link.SpanContext.TraceID().String(),
+				SpanID:     link.SpanContext.SpanID().String(),
+				Attributes: encodeAttributes(link.Attributes),
+			})
+		}
+		encoded = append(encoded, span)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: encodeAttributes([]attribute.KeyValue{
+						attribute.String("service.name", e.serviceName),
+						attribute.String("service.version", appdefaults.GetVersion()),
+					}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: scopeName},
+						Spans: encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// encodeStatus converts the status code of the API to the OTLP status code, which
+// uses a different numbering.
+func encodeStatus(code codes.Code, msg string) otlpStatus {
+	switch code {
+	case codes.Ok:
+		return otlpStatus{Code: 1}
+	case codes.Error:
+		return otlpStatus{Code: 2, Message: msg}
+	default:
+		return otlpStatus{}
+	}
+}
+
+func encodeAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	ret := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		ret = append(ret, otlpKeyValue{
+			Key:   string(kv.Key),
+			Value: encodeValue(kv.Value),
+		})
+	}
+	return ret
+}
+
+func encodeValue(v attribute.Value) otlpValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		values := []otlpValue{}
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, encodeValue(attribute.BoolValue(b)))
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		values := []otlpValue{}
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, encodeValue(attribute.Int64Value(i)))
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := []otlpValue{}
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, encodeValue(attribute.Float64Value(f)))
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		values := []otlpValue{}
+		for _, s := range v.AsStringSlice() {
+			values = append(values, encodeValue(attribute.StringValue(s)))
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return otlpValue{StringValue: &s}
+	}
+}
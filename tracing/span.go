@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// spanData is the snapshot of a finished span that is handed to the exporter.
+type spanData struct {
+	spanContext trace.SpanContext
+	parent      trace.SpanContext
+	name        string
+	kind        trace.SpanKind
+	start       time.Time
+	end         time.Time
+	attributes  []attribute.KeyValue
+	events      []eventData
+	links       []trace.Link
+	status      codes.Code
+	statusMsg   string
+}
+
+type eventData struct {
+	name       string
+	at         time.Time
+	attributes []attribute.KeyValue
+}
+
+// tracerProvider records every span and hands it to the exporter once it ends.
+type tracerProvider struct {
+	embedded.TracerProvider
+
+	exporter *exporter
+}
+
+func (p *tracerProvider) Tracer(_ string, _ ...trace.TracerOption) trace.Tracer {
+	return &tracer{provider: p}
+}
+
+type tracer struct {
+	embedded.Tracer
+
+	provider *tracerProvider
+}
+
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	var parent trace.SpanContext
+	if !cfg.NewRoot() {
+		parent = trace.SpanContextFromContext(ctx)
+	}
+
+	traceID := parent.TraceID()
+	if !parent.IsValid() {
+		traceID = newTraceID()
+	}
+	s := &span{
+		provider: t.provider,
+		data: spanData{
+			spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     newSpanID(),
+				TraceFlags: trace.FlagsSampled,
+			}),
+			parent:     parent,
+			name:       name,
+			kind:       cfg.SpanKind(),
+			start:      cfg.Timestamp(),
+			attributes: cfg.Attributes(),
+			links:      cfg.Links(),
+		},
+	}
+	if s.data.start.IsZero() {
+		s.data.start = time.Now()
+	}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type span struct {
+	embedded.Span
+
+	mux      sync.Mutex
+	provider *tracerProvider
+	data     spanData
+	ended    bool
+}
+
+func (s *span) End(opts ...trace.SpanEndOption) {
+	cfg := trace.NewSpanEndConfig(opts...)
+
+	s.mux.Lock()
+	if s.ended {
+		s.mux.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.end = cfg.Timestamp()
+	if s.data.end.IsZero() {
+		s.data.end = time.Now()
+	}
+	data := s.data
+	s.mux.Unlock()
+
+	s.provider.exporter.enqueue(data)
+}
+
+func (s *span) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	at := cfg.Timestamp()
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.events = append(s.data.events, eventData{
+		name:       name,
+		at:         at,
+		attributes: cfg.Attributes(),
+	})
+}
+
+func (s *span) AddLink(link trace.Link) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.links = append(s.data.links, link)
+}
+
+func (s *span) IsRecording() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return !s.ended
+}
+
+func (s *span) RecordError(err error, opts ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	opts = append(opts, trace.WithAttributes(
+		attribute.String("exception.type", fmt.Sprintf("%T", err)),
+		attribute.String("exception.message", err.Error()),
+	))
+	s.AddEvent("exception", opts...)
+}
+
+func (s *span) SpanContext() trace.SpanContext {
+	return s.data.spanContext
+}
+
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	// An Ok status is final, and Unset never overrides a status that was set.
+	if s.ended || s.data.status == codes.Ok || code == codes.Unset {
+		return
+	}
+	s.data.status = code
+	s.data.statusMsg = ""
+	if code == codes.Error {
+		s.data.statusMsg = description
+	}
+}
+
+func (s *span) SetName(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.ended {
+		s.data.name = name
+	}
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.ended {
+		s.data.attributes = append(s.data.attributes, kv...)
+	}
+}
+
+func (s *span) TracerProvider() trace.TracerProvider {
+	return s.provider
+}
+
+func newTraceID() trace.TraceID {
+	var id trace.TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() trace.SpanID {
+	var id trace.SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
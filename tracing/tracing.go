@@ -0,0 +1,139 @@
+// Package tracing exports traces of the path a workflow job takes through GARM, from
+// the webhook that announces the job, to the runner created for it in a provider.
+// Spans are sent to an OpenTelemetry collector using OTLP over HTTP.
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/cloudbase/garm/config"
+)
+
+const (
+	tracerName = "github.com/cloudbase/garm"
+	// jobTraceTTL is the amount of time for which the trace of a job is remembered.
+	// Runners for queued jobs are created within a few minutes, unless no pool has
+	// room for them.
+	jobTraceTTL = 1 * time.Hour
+)
+
+var (
+	providerMux sync.RWMutex
+	provider    trace.TracerProvider = noop.NewTracerProvider()
+)
+
+func setProvider(p trace.TracerProvider) {
+	providerMux.Lock()
+	defer providerMux.Unlock()
+	provider = p
+}
+
+// Setup starts exporting spans to the collector configured in the tracing section of
+// the config. It returns a function that flushes the remaining spans, which must be
+// called before GARM exits. If tracing is disabled, spans are not recorded.
+func Setup(ctx context.Context, cfg config.Tracing) func(context.Context) error {
+	if !cfg.Enable {
+		return func(context.Context) error { return nil }
+	}
+
+	exp := newExporter(cfg)
+	exp.start(ctx)
+	setProvider(&tracerProvider{exporter: exp})
+	return func(ctx context.Context) error {
+		setProvider(noop.NewTracerProvider())
+		return exp.shutdown(ctx)
+	}
+}
+
+// Tracer returns the tracer used by GARM.
+func Tracer() trace.Tracer {
+	providerMux.RLock()
+	defer providerMux.RUnlock()
+	return provider.Tracer(tracerName)
+}
+
+// EndSpan records the error, if any, on the span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type jobTrace struct {
+	spanContext trace.SpanContext
+	at          time.Time
+}
+
+var jobTraces = struct {
+	mux    sync.Mutex
+	traces map[int64]jobTrace
+}{
+	traces: map[int64]jobTrace{},
+}
+
+// RememberJob saves the span that handled the webhook of a workflow job. The steps
+// that run later in the loops of the pool manager, like creating a runner for the
+// job, are recorded as children of this span, so they end up in the same trace.
+func RememberJob(jobID int64, sc trace.SpanContext) {
+	if jobID == 0 || !sc.IsValid() {
+		return
+	}
+
+	now := time.Now()
+	jobTraces.mux.Lock()
+	defer jobTraces.mux.Unlock()
+	for id, jt := range jobTraces.traces {
+		if now.Sub(jt.at) > jobTraceTTL {
+			delete(jobTraces.traces, id)
+		}
+	}
+	// Only the first webhook of a job starts the trace. Later events, like the job
+	// starting or completing, are part of their own traces.
+	if _, ok := jobTraces.traces[jobID]; !ok {
+		jobTraces.traces[jobID] = jobTrace{spanContext: sc, at: now}
+	}
+}
+
+// ForgetJob removes the saved trace of a job.
+func ForgetJob(jobID int64) {
+	jobTraces.mux.Lock()
+	defer jobTraces.mux.Unlock()
+	delete(jobTraces.traces, jobID)
+}
+
+func jobSpanContext(jobID int64) (trace.SpanContext, bool) {
+	jobTraces.mux.Lock()
+	defer jobTraces.mux.Unlock()
+	jt, ok := jobTraces.traces[jobID]
+	if !ok || time.Since(jt.at) > jobTraceTTL {
+		return trace.SpanContext{}, false
+	}
+	return jt.spanContext, true
+}
+
+// StartJobSpan starts a span for a step in the handling of a workflow job. The span is
+// a child of the span in ctx, if any, or of the span that handled the webhook of the
+// job. If neither exists, the step is not recorded, and a span that records nothing
+// is returned.
+func StartJobSpan(ctx context.Context, jobID int64, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		sc, ok := jobSpanContext(jobID)
+		if !ok {
+			return ctx, trace.SpanFromContext(context.Background())
+		}
+		ctx = trace.ContextWithSpanContext(ctx, sc)
+	}
+	if jobID != 0 {
+		attrs = append(attrs, attribute.Int64("garm.job.id", jobID))
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
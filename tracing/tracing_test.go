@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudbase/garm/config"
+)
+
+type collector struct {
+	mux      sync.Mutex
+	headers  []http.Header
+	requests []otlpRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.headers = append(c.headers, r.Header)
+	c.requests = append(c.requests, req)
+}
+
+func (c *collector) spans() map[string]otlpSpan {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	ret := map[string]otlpSpan{}
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					ret[s.Name] = s
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func TestJobSpansAreExportedInOneTrace(t *testing.T) {
+	coll := &collector{}
+	srv := httptest.NewServer(coll)
+	defer srv.Close()
+
+	shutdown := Setup(context.Background(), config.Tracing{
+		Enable:   true,
+		Endpoint: srv.URL + "/",
+		Headers:  map[string]string{"X-Api-Key": "secret"},
+	})
+
+	_, webhook := Tracer().Start(context.Background(), "webhook.workflow_job", trace.WithSpanKind(trace.SpanKindServer))
+	RememberJob(42, webhook.SpanContext())
+	webhook.End()
+
+	// The runner is created by a loop of the pool manager, after the webhook was handled.
+	ctx, addRunner := StartJobSpan(context.Background(), 42, "pool.add_runner")
+	_, register := StartJobSpan(ctx, 42, "github.register_runner")
+	EndSpan(register, errors.New("JIT config not supported"))
+	EndSpan(addRunner, nil)
+
+	_, unrelated := StartJobSpan(context.Background(), 43, "pool.add_runner")
+	require.False(t, unrelated.IsRecording())
+	unrelated.End()
+
+	require.NoError(t, shutdown(context.Background()))
+	ForgetJob(42)
+
+	spans := coll.spans()
+	require.Len(t, spans, 3)
+	root := spans["webhook.workflow_job"]
+	require.Empty(t, root.ParentSpanID)
+	require.Equal(t, int(trace.SpanKindServer), root.Kind)
+
+	require.Equal(t, root.TraceID, spans["pool.add_runner"].TraceID)
+	require.Equal(t, root.SpanID, spans["pool.add_runner"].ParentSpanID)
+	require.Equal(t, root.TraceID, spans["github.register_runner"].TraceID)
+	require.Equal(t, spans["pool.add_runner"].SpanID, spans["github.register_runner"].ParentSpanID)
+
+	require.Equal(t, 2, spans["github.register_runner"].Status.Code)
+	require.Equal(t, "JIT config not supported", spans["github.register_runner"].Status.Message)
+	require.Len(t, spans["github.register_runner"].Events, 1)
+	require.Equal(t, "exception", spans["github.register_runner"].Events[0].Name)
+
+	jobID := spans["pool.add_runner"].Attributes[0]
+	require.Equal(t, "garm.job.id", jobID.Key)
+	require.Equal(t, "42", *jobID.Value.IntValue)
+
+	resource := coll.requests[0].ResourceSpans[0].Resource.Attributes
+	require.Equal(t, "service.name", resource[0].Key)
+	require.Equal(t, "garm", *resource[0].Value.StringValue)
+	require.Equal(t, "secret", coll.headers[0].Get("X-Api-Key"))
+}
+
+func TestSpansAreNotRecordedWhenDisabled(t *testing.T) {
+	shutdown := Setup(context.Background(), config.Tracing{})
+	defer shutdown(context.Background()) //nolint:errcheck
+
+	_, span := Tracer().Start(context.Background(), "webhook.workflow_job")
+	require.False(t, span.IsRecording())
+	require.False(t, span.SpanContext().IsValid())
+
+	// Invalid span contexts are not remembered.
+	RememberJob(42, span.SpanContext())
+	_, ok := jobSpanContext(42)
+	require.False(t, ok)
+}
@@ -12,6 +12,66 @@ const (
 	// of time and no new updates have been made to it's state, it will be removed.
 	DefaultRunnerBootstrapTimeout = 20
 
+	// DefaultIdleDetectionWindow is the default amount of time in minutes a runner
+	// must be idle, before it is considered for scale down.
+	DefaultIdleDetectionWindow = 2
+
+	// DefaultScaleDownGracePeriod is the default amount of time in minutes since a runner
+	// was created, during which the runner will not be scaled down. This gives new runners
+	// a chance to pick up the job that may have triggered their creation.
+	DefaultScaleDownGracePeriod = 5
+
+	// DefaultScaleDownFactor is the default fraction of the surplus of idle runners
+	// of a pool that is removed on each scale down run.
+	DefaultScaleDownFactor = 0.5
+
+	// DefaultCapacityWarningThreshold is the default percentage of the max runners of a
+	// pool, above which a warning is emitted.
+	DefaultCapacityWarningThreshold = 80
+
+	// DefaultAutoscaleCooldown is the default amount of time in seconds the queue depth
+	// autoscaler waits between two scale ups of a pool.
+	DefaultAutoscaleCooldown = 60
+
+	// DefaultRollingUpdateBatchSize is the default number of outdated idle runners
+	// of a pool that are replaced at once after the image or flavor of the pool changed.
+	DefaultRollingUpdateBatchSize = 1
+
+	// DefaultRollingUpdatePause is the default amount of time in seconds waited between
+	// two batches of a rolling update.
+	DefaultRollingUpdatePause = 60
+
+	// MaxCreateJitter is the maximum value in seconds of the random delay added before
+	// the runners of a pool are created.
+	MaxCreateJitter = 5 * 60
+
+	// MaxScaleDownWindow is the maximum value in minutes of the idle detection window
+	// and of the scale down grace period.
+	MaxScaleDownWindow = 24 * 60
+
+	// DefaultIdempotencyKeyTTL is the amount of time the response of a request made
+	// with an idempotency key is kept, and returned to retries of that request.
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+	// DefaultInstanceClockSkewTolerance is the default clock skew above which GARM
+	// warns about instances with a badly set clock.
+	DefaultInstanceClockSkewTolerance = 5 * time.Minute
+
+	// MaxInstanceClockSkewTolerance is the maximum value of the clock skew tolerance
+	// for instances.
+	MaxInstanceClockSkewTolerance = time.Hour
+
+	// DefaultGithubRateLimitThreshold is the default number of remaining GitHub API
+	// requests below which non critical loops are deferred until the quota is reset.
+	DefaultGithubRateLimitThreshold = 500
+
+	// DefaultLoginRateLimit is the default number of login attempts allowed each minute,
+	// for each username and source address pair and for each source address.
+	DefaultLoginRateLimit = 10
+
+	// DefaultLoginLockoutDuration is the default amount of time a user is locked out for.
+	DefaultLoginLockoutDuration = 15 * time.Minute
+
 	// DefaultGithubURL is the default URL where Github or Github Enterprise can be accessed.
 	DefaultGithubURL = "https://github.com"
 
@@ -30,6 +90,81 @@ const (
 
 	// metrics data update interval
 	DefaultMetricsUpdateInterval = 60 * time.Second
+
+	// DefaultRemoteWriteInterval is the default interval at which metrics are pushed
+	// to the remote write endpoint.
+	DefaultRemoteWriteInterval = 60 * time.Second
+
+	// DefaultCompletedJobsRetention is the default amount of time completed jobs are
+	// kept in the database.
+	DefaultCompletedJobsRetention = 24 * time.Hour
+
+	// DefaultTracingServiceName is the service name reported with exported spans.
+	DefaultTracingServiceName = "garm"
+
+	// DefaultBootstrapTransformerTimeout is the default time the bootstrap transformer
+	// has to respond.
+	DefaultBootstrapTransformerTimeout = 10 * time.Second
+
+	// DefaultStatusMessagesPageSize is the default number of instance status messages
+	// returned in one page.
+	DefaultStatusMessagesPageSize = 50
+
+	// MaxStatusMessagesPageSize is the maximum number of instance status messages
+	// that can be requested in one page.
+	MaxStatusMessagesPageSize = 500
+
+	// DefaultAuditRecordsPageSize is the default number of audit records returned
+	// in one page.
+	DefaultAuditRecordsPageSize = 50
+
+	// MaxAuditRecordsPageSize is the maximum number of audit records that can be
+	// requested in one page.
+	MaxAuditRecordsPageSize = 500
+
+	// DefaultWebhookDeliveriesPageSize is the default number of webhook deliveries
+	// returned in one page.
+	DefaultWebhookDeliveriesPageSize = 50
+
+	// MaxWebhookDeliveriesPageSize is the maximum number of webhook deliveries that
+	// can be requested in one page.
+	MaxWebhookDeliveriesPageSize = 500
+
+	// DefaultListPageSize is the default number of instances, pools or jobs returned
+	// in one page, when a list operation is paginated.
+	DefaultListPageSize = 100
+
+	// MaxListPageSize is the maximum number of instances, pools or jobs that can be
+	// requested in one page.
+	MaxListPageSize = 1000
+
+	// WebhookDeliveryRetention is how long webhook deliveries are kept.
+	WebhookDeliveryRetention = 72 * time.Hour
+
+	// MaxJobMetadataSize is the maximum size, in bytes, of the JSON encoded metadata
+	// of a job.
+	MaxJobMetadataSize = 16 * 1024
+
+	// MaxWebhookSecretGracePeriod is the maximum value in minutes of the period during
+	// which the previous webhook secret of an entity is still accepted.
+	MaxWebhookSecretGracePeriod = 7 * 24 * 60
+
+	// MaxAPITokenTTLDays is the maximum number of days an API token can be valid for.
+	MaxAPITokenTTLDays = 5 * 365
+
+	// MaxInstanceMetadataSize is the maximum size, in bytes, of the JSON encoded
+	// instance metadata of a pool.
+	MaxInstanceMetadataSize = 16 * 1024
+
+	// DefaultWarmImageRefreshInterval is the default interval, in hours, at which the
+	// warm image of a pool is rebuilt.
+	DefaultWarmImageRefreshInterval = 24
+	// MaxWarmImageRefreshInterval is the maximum interval, in hours, at which the warm
+	// image of a pool is rebuilt.
+	MaxWarmImageRefreshInterval = 90 * 24
+	// MaxWarmImageScriptSize is the maximum size, in bytes, of the preparation script
+	// used to build the warm image of a pool.
+	MaxWarmImageScriptSize = 64 * 1024
 )
 
 var Version string
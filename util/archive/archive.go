@@ -0,0 +1,95 @@
+// Package archive exports completed jobs to a local directory or to an S3 compatible
+// bucket, before they are removed from the database.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/params"
+)
+
+// Writer stores archives.
+type Writer interface {
+	// Write stores an archive with the given name. Archives are never overwritten,
+	// as each archive gets a unique name.
+	Write(ctx context.Context, name string, data []byte) error
+}
+
+// NewWriter returns the writer for the configured archive type. It returns nil if
+// archiving is disabled.
+func NewWriter(cfg config.JobArchive) (Writer, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case config.JobArchiveTypeFile:
+		return &fileWriter{dir: cfg.Directory}, nil
+	case config.JobArchiveTypeS3:
+		return newS3Writer(cfg.S3), nil
+	default:
+		return nil, fmt.Errorf("invalid archive type %q", cfg.Type)
+	}
+}
+
+// Name returns the name of the archive of jobs removed at the given time.
+func Name(at time.Time) string {
+	return fmt.Sprintf("garm-jobs-%s.jsonl.gz", at.UTC().Format("20060102T150405.000000000Z"))
+}
+
+// EncodeJobs encodes the jobs as JSON, one job per line, and compresses the result
+// using gzip.
+func EncodeJobs(jobs []params.Job) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, job := range jobs {
+		if err := enc.Encode(job); err != nil {
+			return nil, fmt.Errorf("error encoding job %d: %w", job.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("error compressing jobs: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type fileWriter struct {
+	dir string
+}
+
+// Write writes the archive to a temporary file first, so that a partially written
+// archive is never mistaken for a complete one.
+func (f *fileWriter) Write(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(f.dir, 0o750); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(f.dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("error creating archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(f.dir, name)); err != nil {
+		return fmt.Errorf("error renaming archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/params"
+)
+
+func decodeJobs(t *testing.T, data []byte) []params.Job {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	dec := json.NewDecoder(gz)
+	var jobs []params.Job
+	for {
+		var job params.Job
+		if err := dec.Decode(&job); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestFileWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	writer, err := NewWriter(config.JobArchive{Type: config.JobArchiveTypeFile, Directory: dir})
+	require.NoError(t, err)
+
+	data, err := EncodeJobs([]params.Job{{ID: 1, Name: "build"}, {ID: 2, Name: "test"}})
+	require.NoError(t, err)
+	name := Name(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	require.Equal(t, "garm-jobs-20240501T100000.000000000Z.jsonl.gz", name)
+	require.NoError(t, writer.Write(context.Background(), name, data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, name, entries[0].Name())
+
+	written, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	jobs := decodeJobs(t, written)
+	require.Len(t, jobs, 2)
+	require.Equal(t, "test", jobs[1].Name)
+}
+
+func TestNewWriterDisabled(t *testing.T) {
+	writer, err := NewWriter(config.JobArchive{})
+	require.NoError(t, err)
+	require.Nil(t, writer)
+}
+
+func TestS3Writer(t *testing.T) {
+	var path, auth string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	writer, err := NewWriter(config.JobArchive{
+		Type: config.JobArchiveTypeS3,
+		S3: config.S3Bucket{
+			Endpoint:        srv.URL,
+			Region:          "us-east-1",
+			Bucket:          "garm",
+			Prefix:          "jobs/",
+			AccessKeyID:     "access",
+			SecretAccessKey: "secret",
+		},
+	})
+	require.NoError(t, err)
+
+	data, err := EncodeJobs([]params.Job{{ID: 1}})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(context.Background(), "garm-jobs.jsonl.gz", data))
+
+	require.Equal(t, "/garm/jobs/garm-jobs.jsonl.gz", path)
+	require.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/"))
+	require.Equal(t, data, body)
+}
+
+func TestS3WriterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("AccessDenied")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	writer := newS3Writer(config.S3Bucket{Endpoint: srv.URL, Region: "us-east-1", Bucket: "garm"})
+	err := writer.Write(context.Background(), "garm-jobs.jsonl.gz", []byte("data"))
+	require.EqualError(t, err, "bucket returned 403 Forbidden: AccessDenied")
+}
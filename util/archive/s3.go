@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudbase/garm/config"
+	"github.com/cloudbase/garm/util/sigv4"
+)
+
+const s3UploadTimeout = 5 * time.Minute
+
+// s3Writer uploads archives to an S3 compatible bucket. Requests are signed using
+// AWS Signature Version 4.
+type s3Writer struct {
+	cfg    config.S3Bucket
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3Writer(cfg config.S3Bucket) *s3Writer {
+	return &s3Writer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: s3UploadTimeout},
+		now:    time.Now,
+	}
+}
+
+func (s *s3Writer) objectURL(name string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing endpoint: %w", err)
+	}
+	u.Path = strings.Join([]string{u.Path, s.cfg.Bucket, s.cfg.Prefix + name}, "/")
+	return u, nil
+}
+
+func (s *s3Writer) Write(ctx context.Context, name string, data []byte) error {
+	u, err := s.objectURL(name)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	sigv4.SignRequest(req, data, sigv4.Credentials{
+		AccessKeyID:     s.cfg.AccessKeyID,
+		SecretAccessKey: s.cfg.SecretAccessKey,
+	}, s.cfg.Region, "s3", s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading archive: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("bucket returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
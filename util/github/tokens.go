@@ -0,0 +1,265 @@
+// Package github holds the helpers shared by the GitHub clients created by GARM.
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+	"github.com/cloudbase/garm/util/appdefaults"
+)
+
+const (
+	// DefaultRefreshBefore is how long before they expire installation tokens are
+	// refreshed. Installation tokens are valid for one hour.
+	DefaultRefreshBefore = 10 * time.Minute
+	// refreshInterval is how often the cached tokens are checked for expiry.
+	refreshInterval = time.Minute
+	// tokenRequestTimeout is the timeout for requesting a new installation token.
+	tokenRequestTimeout = 30 * time.Second
+)
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// appToken caches the installation token of one set of github app credentials.
+type appToken struct {
+	mux sync.Mutex
+
+	// fingerprint identifies the version of the credentials the token was created
+	// for. Updating the credentials invalidates the cached token.
+	fingerprint string
+	credsName   string
+	tokenURL    string
+	transport   *http.Transport
+	apps        *ghinstallation.AppsTransport
+	token       *installationToken
+}
+
+// get returns the cached token, requesting a new one if it expires within refreshBefore.
+func (a *appToken) get(ctx context.Context, refreshBefore time.Duration, now time.Time) (string, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if a.token == nil || !now.Before(a.token.ExpiresAt.Add(-refreshBefore)) {
+		if err := a.refresh(ctx); err != nil {
+			metrics.GithubAppTokenRefreshCount.WithLabelValues(a.credsName, strconv.FormatBool(false)).Inc()
+			return "", err
+		}
+		metrics.GithubAppTokenRefreshCount.WithLabelValues(a.credsName, strconv.FormatBool(true)).Inc()
+		metrics.GithubAppTokenExpiry.WithLabelValues(a.credsName).Set(float64(a.token.ExpiresAt.Unix()))
+	}
+	return a.token.Token, nil
+}
+
+func (a *appToken) refresh(ctx context.Context) error {
+	// The token is shared by all the clients using the credentials, so it must not be
+	// tied to the lifetime of the request that triggered the refresh.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := a.apps.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("github returned %s when requesting installation token: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var token installationToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode installation token: %w", err)
+	}
+	if token.Token == "" {
+		return fmt.Errorf("github returned an empty installation token")
+	}
+	a.token = &token
+	return nil
+}
+
+// credentialsFingerprint changes whenever the details used to request tokens change.
+func credentialsFingerprint(creds params.GithubCredentials) string {
+	hash := sha256.New()
+	hash.Write([]byte(creds.APIBaseURL))
+	hash.Write(creds.CABundle)
+	hash.Write(creds.CredentialsPayload)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// TokenManager caches the installation tokens of github app credentials, so all the
+// clients using the same credentials share a single token instead of each requesting
+// their own. Tokens are refreshed before they expire.
+type TokenManager struct {
+	mux           sync.Mutex
+	refreshBefore time.Duration
+	tokens        map[uint]*appToken
+	now           func() time.Time
+}
+
+func NewTokenManager(refreshBefore time.Duration) *TokenManager {
+	return &TokenManager{
+		refreshBefore: refreshBefore,
+		tokens:        map[uint]*appToken{},
+		now:           time.Now,
+	}
+}
+
+var defaultManager = NewTokenManager(DefaultRefreshBefore)
+
+// Default returns the token manager used by the GitHub clients created by GARM.
+func Default() *TokenManager {
+	return defaultManager
+}
+
+func (m *TokenManager) appToken(creds params.GithubCredentials) (*appToken, error) {
+	fingerprint := credentialsFingerprint(creds)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if tok, ok := m.tokens[creds.ID]; ok && tok.fingerprint == fingerprint {
+		return tok, nil
+	}
+
+	app, err := creds.GithubApp()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := creds.HTTPTransport()
+	if err != nil {
+		return nil, err
+	}
+	apps, err := ghinstallation.NewAppsTransport(transport, app.AppID, app.PrivateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create github app transport: %w", err)
+	}
+
+	baseURL := creds.APIBaseURL
+	if baseURL == "" {
+		baseURL = appdefaults.GithubDefaultBaseURL
+	}
+	tok := &appToken{
+		fingerprint: fingerprint,
+		credsName:   creds.Name,
+		tokenURL:    fmt.Sprintf("%s/app/installations/%d/access_tokens", strings.TrimSuffix(baseURL, "/"), app.InstallationID),
+		transport:   transport,
+		apps:        apps,
+	}
+	m.tokens[creds.ID] = tok
+	return tok, nil
+}
+
+// Token returns an installation token for the github app credentials.
+func (m *TokenManager) Token(ctx context.Context, creds params.GithubCredentials) (string, error) {
+	tok, err := m.appToken(creds)
+	if err != nil {
+		return "", err
+	}
+	return tok.get(ctx, m.refreshBefore, m.now())
+}
+
+// HTTPClient returns a client authenticated using the credentials. Clients for github
+// app credentials use the cached installation token.
+func (m *TokenManager) HTTPClient(ctx context.Context, creds params.GithubCredentials) (*http.Client, error) {
+	if creds.AuthType != params.GithubAuthTypeApp {
+		return creds.GetHTTPClient(ctx)
+	}
+	tok, err := m.appToken(creds)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &tokenTransport{
+			manager: m,
+			token:   tok,
+		},
+	}, nil
+}
+
+// Forget removes the cached token of the credentials.
+func (m *TokenManager) Forget(credsID uint) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if tok, ok := m.tokens[credsID]; ok {
+		metrics.GithubAppTokenExpiry.DeleteLabelValues(tok.credsName)
+		delete(m.tokens, credsID)
+	}
+}
+
+// RefreshExpiring refreshes the cached tokens that expire within the refresh period,
+// so requests don't have to wait for a new token.
+func (m *TokenManager) RefreshExpiring(ctx context.Context) {
+	m.mux.Lock()
+	tokens := make([]*appToken, 0, len(m.tokens))
+	for _, tok := range m.tokens {
+		tokens = append(tokens, tok)
+	}
+	m.mux.Unlock()
+
+	now := m.now()
+	for _, tok := range tokens {
+		if _, err := tok.get(ctx, m.refreshBefore, now); err != nil {
+			slog.With(slog.Any("error", err)).ErrorContext(
+				ctx, "failed to refresh installation token", "credentials", tok.credsName)
+		}
+	}
+}
+
+// Start refreshes the expiring tokens in the background, until the context is canceled.
+func (m *TokenManager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.RefreshExpiring(ctx)
+			}
+		}
+	}()
+}
+
+// tokenTransport authenticates requests using the cached installation token.
+type tokenTransport struct {
+	manager *TokenManager
+	token   *appToken
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token.get(req.Context(), t.manager.refreshBefore, t.manager.now())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	creq := req.Clone(req.Context())
+	creq.Header.Set("Authorization", "token "+token)
+	return t.token.transport.RoundTrip(creq)
+}
@@ -0,0 +1,137 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/params"
+)
+
+// fakeGithub issues installation tokens that expire after ttl and records the
+// tokens used to call the API.
+type fakeGithub struct {
+	mux      sync.Mutex
+	ttl      time.Duration
+	issued   int
+	used     []string
+	failWith int
+}
+
+func (f *fakeGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if r.Method == http.MethodPost && r.URL.Path == "/app/installations/2/access_tokens" {
+		if f.failWith != 0 {
+			w.WriteHeader(f.failWith)
+			w.Write([]byte(`{"message":"Bad credentials"}`)) //nolint:errcheck
+			return
+		}
+		f.issued++
+		json.NewEncoder(w).Encode(installationToken{ //nolint:errcheck
+			Token:     fmt.Sprintf("token-%d", f.issued),
+			ExpiresAt: time.Now().Add(f.ttl),
+		})
+		return
+	}
+	f.used = append(f.used, r.Header.Get("Authorization"))
+}
+
+func testCredentials(t *testing.T, apiURL string) params.GithubCredentials {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	payload, err := json.Marshal(params.GithubApp{AppID: 1, InstallationID: 2, PrivateKeyBytes: privateKey})
+	require.NoError(t, err)
+	return params.GithubCredentials{
+		ID:                 1,
+		Name:               "test-app",
+		APIBaseURL:         apiURL + "/",
+		AuthType:           params.GithubAuthTypeApp,
+		CredentialsPayload: payload,
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestTokenManagerSharesTokens(t *testing.T) {
+	fake := &fakeGithub{ttl: time.Hour}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	creds := testCredentials(t, srv.URL)
+	manager := NewTokenManager(DefaultRefreshBefore)
+	for i := 0; i < 3; i++ {
+		client, err := manager.HTTPClient(context.Background(), creds)
+		require.NoError(t, err)
+		get(t, client, srv.URL+"/repos/owner/repo")
+	}
+
+	require.Equal(t, 1, fake.issued)
+	require.Equal(t, []string{"token token-1", "token token-1", "token token-1"}, fake.used)
+
+	// Updating the credentials invalidates the cached token.
+	creds = testCredentials(t, srv.URL)
+	token, err := manager.Token(context.Background(), creds)
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+}
+
+func TestTokenManagerRefreshesBeforeExpiry(t *testing.T) {
+	fake := &fakeGithub{ttl: time.Hour}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	creds := testCredentials(t, srv.URL)
+	manager := NewTokenManager(DefaultRefreshBefore)
+	token, err := manager.Token(context.Background(), creds)
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+
+	// Still valid, nothing to refresh.
+	manager.RefreshExpiring(context.Background())
+	require.Equal(t, 1, fake.issued)
+
+	// The token expires within the refresh period.
+	manager.now = func() time.Time { return time.Now().Add(time.Hour - DefaultRefreshBefore/2) }
+	manager.RefreshExpiring(context.Background())
+	require.Equal(t, 2, fake.issued)
+
+	manager.now = time.Now
+	token, err = manager.Token(context.Background(), creds)
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+
+	manager.Forget(creds.ID)
+	token, err = manager.Token(context.Background(), creds)
+	require.NoError(t, err)
+	require.Equal(t, "token-3", token)
+}
+
+func TestTokenManagerError(t *testing.T) {
+	fake := &fakeGithub{ttl: time.Hour, failWith: http.StatusUnauthorized}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	manager := NewTokenManager(DefaultRefreshBefore)
+	client, err := manager.HTTPClient(context.Background(), testCredentials(t, srv.URL))
+	require.NoError(t, err)
+	_, err = client.Get(srv.URL + "/repos/owner/repo")
+	require.ErrorContains(t, err, `github returned 401 Unauthorized when requesting installation token: {"message":"Bad credentials"}`)
+	require.Empty(t, fake.used)
+}
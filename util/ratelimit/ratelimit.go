@@ -0,0 +1,248 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudbase/garm/params"
+)
+
+const (
+	// DefaultWindow is the amount of time for which rate limit samples are kept.
+	DefaultWindow = 15 * time.Minute
+	// minForecastPeriod is the minimum amount of time samples need to span before
+	// we estimate the consumption rate. Shorter periods give wildly inaccurate rates.
+	minForecastPeriod = 30 * time.Second
+	// coreResource is the rate limit resource used by the REST API. The search and
+	// GraphQL APIs have separate quotas, which GARM does not use.
+	coreResource = "core"
+)
+
+type sample struct {
+	at        time.Time
+	entityID  string
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+type credentialsHistory struct {
+	name    string
+	samples []sample
+}
+
+// Tracker records the rate limit headers of the responses returned by the GitHub API
+// and uses them to forecast when the quota of each set of credentials runs out.
+type Tracker struct {
+	mux     sync.Mutex
+	window  time.Duration
+	history map[uint]*credentialsHistory
+}
+
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{
+		window:  window,
+		history: map[uint]*credentialsHistory{},
+	}
+}
+
+var defaultTracker = NewTracker(DefaultWindow)
+
+// Default returns the tracker used by the GitHub clients created by GARM.
+func Default() *Tracker {
+	return defaultTracker
+}
+
+// Record saves the rate limit information of a response. Responses without rate
+// limit headers, or for a resource other than the REST API, are ignored.
+func (t *Tracker) Record(creds params.GithubCredentials, entityID string, resp *http.Response, now time.Time) {
+	if resp == nil {
+		return
+	}
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != coreResource {
+		return
+	}
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	hist, ok := t.history[creds.ID]
+	if !ok {
+		hist = &credentialsHistory{}
+		t.history[creds.ID] = hist
+	}
+	hist.name = creds.Name
+	hist.samples = append(hist.samples, sample{
+		at:        now,
+		entityID:  entityID,
+		limit:     limit,
+		remaining: remaining,
+		reset:     time.Unix(reset, 0).UTC(),
+	})
+	hist.samples = t.prune(hist.samples, now)
+}
+
+// prune removes the samples that are older than the window.
+func (t *Tracker) prune(samples []sample, now time.Time) []sample {
+	idx := sort.Search(len(samples), func(i int) bool {
+		return now.Sub(samples[i].at) < t.window
+	})
+	return samples[idx:]
+}
+
+// Forecast returns the rate limit forecast of a set of credentials. It returns false
+// if no samples were recorded for the credentials within the window.
+func (t *Tracker) Forecast(credentialsID uint, now time.Time) (params.RateLimitForecast, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	hist, ok := t.history[credentialsID]
+	if !ok {
+		return params.RateLimitForecast{}, false
+	}
+	hist.samples = t.prune(hist.samples, now)
+	if len(hist.samples) == 0 {
+		delete(t.history, credentialsID)
+		return params.RateLimitForecast{}, false
+	}
+	return forecast(credentialsID, hist.name, hist.samples), true
+}
+
+// Throttled returns true if fewer than threshold requests remain for the credentials,
+// according to the latest sample, along with the time GitHub resets the quota. It
+// returns false once the reset time has passed, even if no new sample was recorded.
+func (t *Tracker) Throttled(credentialsID uint, threshold int, now time.Time) (time.Time, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	hist, ok := t.history[credentialsID]
+	if !ok || len(hist.samples) == 0 {
+		return time.Time{}, false
+	}
+	latest := hist.samples[len(hist.samples)-1]
+	if latest.remaining >= threshold || !now.Before(latest.reset) {
+		return time.Time{}, false
+	}
+	return latest.reset, true
+}
+
+// Forecasts returns the rate limit forecasts of all credentials that were used within
+// the window, sorted by credentials ID.
+func (t *Tracker) Forecasts(now time.Time) []params.RateLimitForecast {
+	t.mux.Lock()
+	ids := make([]uint, 0, len(t.history))
+	for id := range t.history {
+		ids = append(ids, id)
+	}
+	t.mux.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	ret := []params.RateLimitForecast{}
+	for _, id := range ids {
+		if f, ok := t.Forecast(id, now); ok {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+func forecast(credentialsID uint, name string, samples []sample) params.RateLimitForecast {
+	latest := samples[len(samples)-1]
+	ret := params.RateLimitForecast{
+		CredentialsID:   credentialsID,
+		CredentialsName: name,
+		Limit:           latest.limit,
+		Remaining:       latest.remaining,
+		ResetAt:         latest.reset,
+		SampledAt:       latest.at,
+	}
+
+	// The consumption rate is computed from the remaining quota, so requests made with
+	// the same credentials by other applications are accounted for. Only samples taken
+	// since the last reset of the quota are relevant.
+	first := latest
+	for i := len(samples) - 1; i >= 0; i-- {
+		if !samples[i].reset.Equal(latest.reset) {
+			break
+		}
+		first = samples[i]
+	}
+	period := latest.at.Sub(first.at)
+	consumed := first.remaining - latest.remaining
+	if period >= minForecastPeriod && consumed > 0 {
+		ret.ConsumptionPerMinute = float64(consumed) / period.Minutes()
+		left := time.Duration(float64(latest.remaining) / ret.ConsumptionPerMinute * float64(time.Minute))
+		exhaustsAt := latest.at.Add(left)
+		ret.ExhaustsAt = &exhaustsAt
+		ret.ExhaustsBeforeReset = exhaustsAt.Before(latest.reset)
+	}
+
+	// Requests made by each entity are counted over all the samples in the window.
+	observed := max(latest.at.Sub(samples[0].at), time.Minute)
+	requests := map[string]int{}
+	for _, s := range samples {
+		requests[s.entityID]++
+	}
+	for entityID, count := range requests {
+		ret.Entities = append(ret.Entities, params.EntityRateLimitUsage{
+			EntityID:          entityID,
+			Requests:          count,
+			RequestsPerMinute: float64(count) / observed.Minutes(),
+			Share:             float64(count) * 100 / float64(len(samples)),
+		})
+	}
+	sort.Slice(ret.Entities, func(i, j int) bool {
+		if ret.Entities[i].Requests == ret.Entities[j].Requests {
+			return ret.Entities[i].EntityID < ret.Entities[j].EntityID
+		}
+		return ret.Entities[i].Requests > ret.Entities[j].Requests
+	})
+	return ret
+}
+
+type transport struct {
+	base     http.RoundTripper
+	tracker  *Tracker
+	creds    params.GithubCredentials
+	entityID string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.tracker.Record(t.creds, t.entityID, resp, time.Now())
+	}
+	return resp, err
+}
+
+// NewTransport returns a round tripper that records the rate limit of every response
+// returned by the base round tripper.
+func NewTransport(base http.RoundTripper, tracker *Tracker, creds params.GithubCredentials, entityID string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{
+		base:     base,
+		tracker:  tracker,
+		creds:    creds,
+		entityID: entityID,
+	}
+}
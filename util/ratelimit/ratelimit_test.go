@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudbase/garm/params"
+)
+
+func rateLimitResponse(limit, remaining int, reset time.Time) *http.Response {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	header.Set("X-RateLimit-Resource", "core")
+	return &http.Response{Header: header}
+}
+
+func TestForecastExhaustsBeforeReset(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := start.Add(45 * time.Minute)
+
+	// 100 requests per minute, with 1000 requests remaining after 4 minutes.
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 1400, reset), start)
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 1200, reset), start.Add(2*time.Minute))
+	tracker.Record(creds, "org", rateLimitResponse(5000, 1000, reset), start.Add(4*time.Minute))
+
+	forecast, ok := tracker.Forecast(1, start.Add(4*time.Minute))
+	require.True(t, ok)
+	require.Equal(t, "creds", forecast.CredentialsName)
+	require.Equal(t, 5000, forecast.Limit)
+	require.Equal(t, 1000, forecast.Remaining)
+	require.Equal(t, reset, forecast.ResetAt)
+	require.InDelta(t, 100, forecast.ConsumptionPerMinute, 0.001)
+	require.NotNil(t, forecast.ExhaustsAt)
+	require.Equal(t, start.Add(14*time.Minute), *forecast.ExhaustsAt)
+	require.True(t, forecast.ExhaustsBeforeReset)
+
+	require.Len(t, forecast.Entities, 2)
+	require.Equal(t, "repo", forecast.Entities[0].EntityID)
+	require.Equal(t, 2, forecast.Entities[0].Requests)
+	require.InDelta(t, 0.5, forecast.Entities[0].RequestsPerMinute, 0.001)
+	require.InDelta(t, 66.666, forecast.Entities[0].Share, 0.001)
+	require.Equal(t, "org", forecast.Entities[1].EntityID)
+}
+
+func TestForecastDoesNotExhaustBeforeReset(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := start.Add(10 * time.Minute)
+
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 4000, reset), start)
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 3990, reset), start.Add(time.Minute))
+
+	forecast, ok := tracker.Forecast(1, start.Add(time.Minute))
+	require.True(t, ok)
+	require.NotNil(t, forecast.ExhaustsAt)
+	require.False(t, forecast.ExhaustsBeforeReset)
+}
+
+func TestForecastIgnoresSamplesBeforeReset(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// The quota was reset between the two samples, so there is no consumption trend yet.
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 10, start.Add(time.Minute)), start)
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 4999, start.Add(61*time.Minute)), start.Add(2*time.Minute))
+
+	forecast, ok := tracker.Forecast(1, start.Add(2*time.Minute))
+	require.True(t, ok)
+	require.Equal(t, 4999, forecast.Remaining)
+	require.Zero(t, forecast.ConsumptionPerMinute)
+	require.Nil(t, forecast.ExhaustsAt)
+	require.False(t, forecast.ExhaustsBeforeReset)
+}
+
+func TestForecastExpiresOldSamples(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 4000, start.Add(time.Hour)), start)
+	_, ok := tracker.Forecast(1, start.Add(DefaultWindow))
+	require.False(t, ok)
+	require.Empty(t, tracker.Forecasts(start.Add(DefaultWindow)))
+}
+
+func TestRecordIgnoresOtherResources(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	now := time.Now()
+
+	resp := rateLimitResponse(30, 10, now.Add(time.Minute))
+	resp.Header.Set("X-RateLimit-Resource", "search")
+	tracker.Record(creds, "repo", resp, now)
+	tracker.Record(creds, "repo", &http.Response{Header: http.Header{}}, now)
+
+	_, ok := tracker.Forecast(1, now)
+	require.False(t, ok)
+}
+
+func TestThrottled(t *testing.T) {
+	tracker := NewTracker(DefaultWindow)
+	creds := params.GithubCredentials{ID: 1, Name: "creds"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := start.Add(30 * time.Minute)
+
+	_, throttled := tracker.Throttled(1, 500, start)
+	require.False(t, throttled)
+
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 600, reset), start)
+	_, throttled = tracker.Throttled(1, 500, start)
+	require.False(t, throttled)
+
+	tracker.Record(creds, "repo", rateLimitResponse(5000, 400, reset), start.Add(time.Minute))
+	resetAt, throttled := tracker.Throttled(1, 500, start.Add(time.Minute))
+	require.True(t, throttled)
+	require.Equal(t, reset, resetAt)
+
+	// The quota was reset, even if no request was made since.
+	_, throttled = tracker.Throttled(1, 500, reset)
+	require.False(t, throttled)
+}
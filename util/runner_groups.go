@@ -0,0 +1,182 @@
+// Copyright 2024 Cloudbase Solutions SRL
+//
+//    Licensed under the Apache License, Version 2.0 (the "License"); you may
+//    not use this file except in compliance with the License. You may obtain
+//    a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+//    WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+//    License for the specific language governing permissions and limitations
+//    under the License.
+
+package util
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/pkg/errors"
+
+	runnerErrors "github.com/cloudbase/garm-provider-common/errors"
+	"github.com/cloudbase/garm/metrics"
+	"github.com/cloudbase/garm/params"
+)
+
+var errRunnerGroupsNotSupported = runnerErrors.NewBadRequestError("runner groups are only available for organizations and enterprises")
+
+func orgRunnerGroupToParams(group *github.RunnerGroup) params.RunnerGroup {
+	return params.RunnerGroup{
+		ID:                       group.GetID(),
+		Name:                     group.GetName(),
+		Visibility:               params.RunnerGroupVisibility(group.GetVisibility()),
+		Default:                  group.GetDefault(),
+		Inherited:                group.GetInherited(),
+		AllowsPublicRepositories: group.GetAllowsPublicRepositories(),
+		RestrictedToWorkflows:    group.GetRestrictedToWorkflows(),
+		SelectedWorkflows:        group.SelectedWorkflows,
+	}
+}
+
+func enterpriseRunnerGroupToParams(group *github.EnterpriseRunnerGroup) params.RunnerGroup {
+	return params.RunnerGroup{
+		ID:                       group.GetID(),
+		Name:                     group.GetName(),
+		Visibility:               params.RunnerGroupVisibility(group.GetVisibility()),
+		Default:                  group.GetDefault(),
+		Inherited:                group.GetInherited(),
+		AllowsPublicRepositories: group.GetAllowsPublicRepositories(),
+		RestrictedToWorkflows:    group.GetRestrictedToWorkflows(),
+		SelectedWorkflows:        group.SelectedWorkflows,
+	}
+}
+
+func wrapRunnerGroupsError(err error, response *github.Response, msg string) error {
+	if response != nil && response.StatusCode == http.StatusUnauthorized {
+		return errors.Wrap(runnerErrors.ErrUnauthorized, msg)
+	}
+	return errors.Wrap(err, msg)
+}
+
+// ListEntityRunnerGroups returns all the runner groups of the organization or enterprise.
+func (g *githubClient) ListEntityRunnerGroups(ctx context.Context) (ret []params.RunnerGroup, err error) {
+	metrics.GithubOperationCount.WithLabelValues(
+		"ListEntityRunnerGroups", // label: operation
+		g.entity.LabelScope(),    // label: scope
+	).Inc()
+	defer func() {
+		if err != nil {
+			metrics.GithubOperationFailedCount.WithLabelValues(
+				"ListEntityRunnerGroups", // label: operation
+				g.entity.LabelScope(),    // label: scope
+			).Inc()
+		}
+	}()
+
+	listOpts := github.ListOptions{PerPage: 100}
+	ret = []params.RunnerGroup{}
+	for {
+		var response *github.Response
+		switch g.entity.EntityType {
+		case params.GithubEntityTypeOrganization:
+			var groups *github.RunnerGroups
+			groups, response, err = g.ListOrganizationRunnerGroups(ctx, g.entity.Owner, &github.ListOrgRunnerGroupOptions{ListOptions: listOpts})
+			if err != nil {
+				return nil, wrapRunnerGroupsError(err, response, "fetching runner groups")
+			}
+			for _, group := range groups.RunnerGroups {
+				ret = append(ret, orgRunnerGroupToParams(group))
+			}
+		case params.GithubEntityTypeEnterprise:
+			var groups *github.EnterpriseRunnerGroups
+			groups, response, err = g.enterprise.ListRunnerGroups(ctx, g.entity.Owner, &github.ListEnterpriseRunnerGroupOptions{ListOptions: listOpts})
+			if err != nil {
+				return nil, wrapRunnerGroupsError(err, response, "fetching runner groups")
+			}
+			for _, group := range groups.RunnerGroups {
+				ret = append(ret, enterpriseRunnerGroupToParams(group))
+			}
+		default:
+			return nil, errRunnerGroupsNotSupported
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		listOpts.Page = response.NextPage
+	}
+	return ret, nil
+}
+
+// GetEntityRunnerGroupByName returns the runner group of the organization or enterprise
+// with the given name. A not found error is returned if no such group exists.
+func (g *githubClient) GetEntityRunnerGroupByName(ctx context.Context, name string) (params.RunnerGroup, error) {
+	groups, err := g.ListEntityRunnerGroups(ctx)
+	if err != nil {
+		return params.RunnerGroup{}, err
+	}
+	for _, group := range groups {
+		if group.Name == name {
+			return group, nil
+		}
+	}
+	return params.RunnerGroup{}, runnerErrors.NewNotFoundError("runner group %q does not exist in %s", name, g.entity.String())
+}
+
+// CreateEntityRunnerGroup creates a runner group in the organization or enterprise.
+func (g *githubClient) CreateEntityRunnerGroup(ctx context.Context, param params.CreateRunnerGroupParams) (ret params.RunnerGroup, err error) {
+	visibility := param.Visibility
+	if visibility == "" {
+		visibility = params.RunnerGroupVisibilityAll
+	}
+
+	metrics.GithubOperationCount.WithLabelValues(
+		"CreateEntityRunnerGroup", // label: operation
+		g.entity.LabelScope(),     // label: scope
+	).Inc()
+	defer func() {
+		if err != nil {
+			metrics.GithubOperationFailedCount.WithLabelValues(
+				"CreateEntityRunnerGroup", // label: operation
+				g.entity.LabelScope(),     // label: scope
+			).Inc()
+		}
+	}()
+
+	var response *github.Response
+	switch g.entity.EntityType {
+	case params.GithubEntityTypeOrganization:
+		var group *github.RunnerGroup
+		group, response, err = g.CreateOrganizationRunnerGroup(ctx, g.entity.Owner, github.CreateRunnerGroupRequest{
+			Name:                     github.String(param.Name),
+			Visibility:               github.String(string(visibility)),
+			AllowsPublicRepositories: github.Bool(param.AllowsPublicRepositories),
+			RestrictedToWorkflows:    github.Bool(param.RestrictedToWorkflows),
+			SelectedWorkflows:        param.SelectedWorkflows,
+		})
+		if err != nil {
+			return params.RunnerGroup{}, wrapRunnerGroupsError(err, response, "creating runner group")
+		}
+		return orgRunnerGroupToParams(group), nil
+	case params.GithubEntityTypeEnterprise:
+		if visibility == params.RunnerGroupVisibilityPrivate {
+			return params.RunnerGroup{}, runnerErrors.NewBadRequestError("visibility %q is not available for enterprises", visibility)
+		}
+		var group *github.EnterpriseRunnerGroup
+		group, response, err = g.enterprise.CreateEnterpriseRunnerGroup(ctx, g.entity.Owner, github.CreateEnterpriseRunnerGroupRequest{
+			Name:                     github.String(param.Name),
+			Visibility:               github.String(string(visibility)),
+			AllowsPublicRepositories: github.Bool(param.AllowsPublicRepositories),
+			RestrictedToWorkflows:    github.Bool(param.RestrictedToWorkflows),
+			SelectedWorkflows:        param.SelectedWorkflows,
+		})
+		if err != nil {
+			return params.RunnerGroup{}, wrapRunnerGroupsError(err, response, "creating runner group")
+		}
+		return enterpriseRunnerGroupToParams(group), nil
+	default:
+		return params.RunnerGroup{}, errRunnerGroupsNotSupported
+	}
+}
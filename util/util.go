@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/google/go-github/v57/github"
 	"github.com/pkg/errors"
@@ -29,6 +30,8 @@ import (
 	"github.com/cloudbase/garm/metrics"
 	"github.com/cloudbase/garm/params"
 	"github.com/cloudbase/garm/runner/common"
+	garmGithub "github.com/cloudbase/garm/util/github"
+	"github.com/cloudbase/garm/util/ratelimit"
 )
 
 type githubClient struct {
@@ -160,6 +163,52 @@ func (g *githubClient) PingEntityHook(ctx context.Context, id int64) (ret *githu
 	return ret, err
 }
 
+func (g *githubClient) ListEntityHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) (ret []*github.HookDelivery, response *github.Response, err error) {
+	metrics.GithubOperationCount.WithLabelValues(
+		"ListHookDeliveries",  // label: operation
+		g.entity.LabelScope(), // label: scope
+	).Inc()
+	defer func() {
+		if err != nil {
+			metrics.GithubOperationFailedCount.WithLabelValues(
+				"ListHookDeliveries",  // label: operation
+				g.entity.LabelScope(), // label: scope
+			).Inc()
+		}
+	}()
+	switch g.entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		ret, response, err = g.repo.ListHookDeliveries(ctx, g.entity.Owner, g.entity.Name, id, opts)
+	case params.GithubEntityTypeOrganization:
+		ret, response, err = g.org.ListHookDeliveries(ctx, g.entity.Owner, id, opts)
+	default:
+		return nil, nil, fmt.Errorf("invalid entity type: %s", g.entity.EntityType)
+	}
+	return ret, response, err
+}
+
+func (g *githubClient) GetEntityActionsPermissions(ctx context.Context) (ret *github.ActionsPermissionsRepository, response *github.Response, err error) {
+	metrics.GithubOperationCount.WithLabelValues(
+		"GetActionsPermissions", // label: operation
+		g.entity.LabelScope(),   // label: scope
+	).Inc()
+	defer func() {
+		if err != nil {
+			metrics.GithubOperationFailedCount.WithLabelValues(
+				"GetActionsPermissions", // label: operation
+				g.entity.LabelScope(),   // label: scope
+			).Inc()
+		}
+	}()
+	switch g.entity.EntityType {
+	case params.GithubEntityTypeRepository:
+		ret, response, err = g.repo.GetActionsPermissions(ctx, g.entity.Owner, g.entity.Name)
+	default:
+		return nil, nil, fmt.Errorf("invalid entity type: %s", g.entity.EntityType)
+	}
+	return ret, response, err
+}
+
 func (g *githubClient) ListEntityRunners(ctx context.Context, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
 	var ret *github.Runners
 	var response *github.Response
@@ -410,6 +459,10 @@ func (g *githubClient) GetEntityJITConfig(ctx context.Context, instance string,
 		if response != nil && response.StatusCode == http.StatusUnauthorized {
 			return nil, nil, fmt.Errorf("failed to get JIT config: %w", err)
 		}
+		if response != nil && response.StatusCode == http.StatusConflict {
+			// GitHub refuses to register a runner with the name of an existing runner.
+			return nil, nil, fmt.Errorf("failed to get JIT config: %w", runnerErrors.NewConflictError("runner %s already exists: %s", instance, err))
+		}
 		return nil, nil, fmt.Errorf("failed to get JIT config: %w", err)
 	}
 
@@ -436,10 +489,11 @@ func (g *githubClient) GetEntityJITConfig(ctx context.Context, instance string,
 }
 
 func GithubClient(ctx context.Context, entity params.GithubEntity, credsDetails params.GithubCredentials) (common.GithubClient, error) {
-	httpClient, err := credsDetails.GetHTTPClient(ctx)
+	httpClient, err := garmGithub.Default().HTTPClient(ctx, credsDetails)
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching http client")
 	}
+	httpClient.Transport = ratelimit.NewTransport(httpClient.Transport, ratelimit.Default(), credsDetails, entity.ID)
 
 	ghClient, err := github.NewClient(httpClient).WithEnterpriseURLs(credsDetails.APIBaseURL, credsDetails.UploadBaseURL)
 	if err != nil {
@@ -455,3 +509,93 @@ func GithubClient(ctx context.Context, entity params.GithubEntity, credsDetails
 	}
 	return cli, nil
 }
+
+// tokenExpirationLayout is the layout of the GitHub-Authentication-Token-Expiration header.
+const tokenExpirationLayout = "2006-01-02 15:04:05 MST"
+
+// GithubTokenSummary lists the repositories a fine-grained PAT can access.
+func GithubTokenSummary(ctx context.Context, credsDetails params.GithubCredentials) (params.GithubTokenSummary, error) {
+	httpClient, err := credsDetails.GetHTTPClient(ctx)
+	if err != nil {
+		return params.GithubTokenSummary{}, errors.Wrap(err, "fetching http client")
+	}
+
+	ghClient, err := github.NewClient(httpClient).WithEnterpriseURLs(credsDetails.APIBaseURL, credsDetails.UploadBaseURL)
+	if err != nil {
+		return params.GithubTokenSummary{}, errors.Wrap(err, "fetching github client")
+	}
+
+	summary := params.GithubTokenSummary{
+		CheckedAt: time.Now().UTC(),
+	}
+	opts := &github.RepositoryListByAuthenticatedUserOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		repos, response, err := ghClient.Repositories.ListByAuthenticatedUser(ctx, opts)
+		if err != nil {
+			if response != nil && response.StatusCode == http.StatusUnauthorized {
+				return params.GithubTokenSummary{}, runnerErrors.NewBadRequestError("the token was rejected by GitHub")
+			}
+			return params.GithubTokenSummary{}, errors.Wrap(err, "listing repositories")
+		}
+		if summary.ExpiresAt == nil {
+			if expiration, err := time.Parse(tokenExpirationLayout, response.Header.Get("GitHub-Authentication-Token-Expiration")); err == nil {
+				expiration = expiration.UTC()
+				summary.ExpiresAt = &expiration
+			}
+		}
+		for _, repo := range repos {
+			summary.Repositories = append(summary.Repositories, repo.GetFullName())
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+	return summary, nil
+}
+
+// ValidateFineGrainedPATAccess checks that the fine-grained PAT of the entity can manage
+// the runners of the entity. Fine-grained tokens only cover the repositories and permissions
+// selected when they were created, so without this check a missing grant would only show
+// up later, as 404 or 403 errors from the pool manager.
+func ValidateFineGrainedPATAccess(ctx context.Context, entity params.GithubEntity) error {
+	if !entity.Credentials.IsFineGrainedPAT() {
+		return nil
+	}
+	if entity.EntityType == params.GithubEntityTypeEnterprise {
+		return runnerErrors.NewBadRequestError(
+			"credentials %s hold a fine-grained personal access token, which cannot manage enterprise runners",
+			entity.Credentials.Name)
+	}
+
+	cli, err := GithubClient(ctx, entity, entity.Credentials)
+	if err != nil {
+		return errors.Wrap(err, "fetching github client")
+	}
+
+	_, response, err := cli.ListEntityRunners(ctx, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if response != nil {
+			switch response.StatusCode {
+			case http.StatusUnauthorized:
+				return runnerErrors.NewBadRequestError("the token of credentials %s was rejected by GitHub", entity.Credentials.Name)
+			case http.StatusNotFound:
+				return runnerErrors.NewBadRequestError(
+					"the token of credentials %s does not have access to %s %s",
+					entity.Credentials.Name, entity.EntityType, entity.String())
+			case http.StatusForbidden:
+				msg := fmt.Sprintf(
+					"the token of credentials %s is not allowed to manage the runners of %s %s",
+					entity.Credentials.Name, entity.EntityType, entity.String())
+				if accepted := response.Header.Get("X-Accepted-GitHub-Permissions"); accepted != "" {
+					msg = fmt.Sprintf("%s (GitHub requires: %s)", msg, accepted)
+				}
+				return runnerErrors.NewBadRequestError("%s", msg)
+			}
+		}
+		return errors.Wrap(err, "listing runners")
+	}
+	return nil
+}